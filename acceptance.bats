@@ -1,12 +1,24 @@
 #!/usr/bin/env bats
 
+@test "[CHECK] Using --exists flag reports missing images" {
+  run ./sinker check --exists --manifest test/push/dryrun-images.yaml
+  [ "$status" -eq 1 ]
+  [[ "$output" =~ "Image plexsystems/busybox:1.32.0 is missing from the target" ]]
+}
+
+@test "[CHECK] Using --exists and --images flags all images exist" {
+  run ./sinker check --exists --images busybox:1.30.0 --target plexsystems
+  [ "$status" -eq 0 ]
+  [[ "$output" =~ "All images exist at the target!" ]]
+}
+
 @test "[CHECK] Using manifest returns newer image" {
   run ./sinker check --manifest example
   [[ "$output" =~ "New versions for" ]]
 }
 
 @test "[CHECK] Using --images flag returns newer versions" {
-  run ./sinker check --images plexsystems/busybox:1.30.0 
+  run ./sinker check --images plexsystems/busybox:1.30.0
   [[ "$output" =~ "New versions for" ]]
 }
 
@@ -22,16 +34,18 @@
 
 @test "[LIST] List of source images matches example source list" {
   run ./sinker list source --manifest example --output example/source.txt
+  [ "$status" -eq 0 ]
   git diff --quiet -- example/source.txt
 }
 
 @test "[LIST] List of target images matches example target list" {
   run ./sinker list target --manifest example --output example/target.txt
+  [ "$status" -eq 0 ]
   git diff --quiet -- example/target.txt
 }
 
-@test "[PUSH] Using --dryrun flag lists missing images" {
-  run ./sinker push --dryrun --manifest test/push/dryrun-images.yaml
+@test "[PUSH] Using --dry-run flag lists missing images" {
+  run ./sinker push --dry-run --manifest test/push/dryrun-images.yaml
   [[ "$output" =~ "Image busybox:1.32.0 would be pushed as plexsystems/busybox:1.32.0" ]]
 }
 
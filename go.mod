@@ -6,7 +6,9 @@ require (
 	github.com/avast/retry-go v2.6.0+incompatible
 	github.com/containerd/containerd v1.3.6 // indirect
 	github.com/coreos/prometheus-operator v0.40.0
+	github.com/docker/distribution v2.7.1+incompatible
 	github.com/docker/docker v1.4.2-0.20190924003213-a8608b5b67c7
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/ghodss/yaml v1.0.0
 	github.com/google/go-containerregistry v0.1.1
 	github.com/hashicorp/go-version v1.2.1
@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The operations that are recorded in the audit log.
+const (
+	auditOperationPush   = "push"
+	auditOperationDelete = "delete"
+)
+
+// auditEntry is an operation on an image at a target registry.
+type auditEntry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Source    string    `json:"source,omitempty"`
+
+	// Digest is the digest of the source image that was pushed, or the digest of the image that was deleted.
+	Digest string `json:"digest,omitempty"`
+
+	Target string `json:"target"`
+	User   string `json:"user"`
+	Error  string `json:"error,omitempty"`
+}
+
+// auditLog is an append-only log of the operations on images at target registries, with one JSON
+// entry per line. Entries are only ever appended, so that the log can be kept as an audit trail.
+type auditLog struct {
+	path  string
+	user  string
+	mutex sync.Mutex
+}
+
+func newAuditLog(path string) *auditLog {
+	return &auditLog{
+		path: path,
+		user: getAuditUser(),
+	}
+}
+
+// record appends the entry to the log, with the current time and the user that is running sinker. Each entry
+// is synced to disk as soon as it is written, so that no operation is missing from the log if sinker is stopped.
+func (a *auditLog) record(entry auditEntry) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	entry.Time = time.Now().UTC()
+	entry.User = a.user
+
+	contents, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+
+	if _, err := f.Write(append(contents, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	return nil
+}
+
+// recordPush records the push of the source, which failed with the given error unless it is nil. The digest of
+// the source is looked up when the source is not pinned to a digest, and is left out when it cannot be found.
+func recordPush(ctx context.Context, audit *auditLog, client docker.Client, source manifest.Source, sourceAuth string, pushErr error) error {
+	entry := auditEntry{
+		Operation: auditOperationPush,
+		Source:    source.Image(),
+		Digest:    source.Digest,
+		Target:    source.TargetImage(),
+	}
+
+	if entry.Digest == "" {
+		digest, err := client.GetDigest(ctx, source.PullImage(), sourceAuth)
+		if err != nil {
+			log.Debugf("Unable to get the digest of %s for the audit log: %v", source.Image(), err)
+		}
+
+		entry.Digest = digest
+	}
+
+	if pushErr != nil {
+		entry.Error = pushErr.Error()
+	}
+
+	return audit.record(entry)
+}
+
+func readAuditLog(path string) ([]auditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	var entries []auditEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("unmarshal line %v: %w", line, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return entries, nil
+}
+
+// getAuditUser returns the name of the user that is running sinker. The SINKER_AUDIT_USER environment variable
+// takes precedence, so that pipelines can record the user that triggered them rather than the user of the runner.
+func getAuditUser() string {
+	if auditUser := os.Getenv("SINKER_AUDIT_USER"); auditUser != "" {
+		return auditUser
+	}
+
+	if currentUser, err := user.Current(); err == nil && currentUser.Username != "" {
+		return currentUser.Username
+	}
+
+	if auditUser := os.Getenv("USER"); auditUser != "" {
+		return auditUser
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.jsonl")
+	audit := &auditLog{path: path, user: "alice"}
+
+	pushed := auditEntry{Operation: auditOperationPush, Source: "busybox:1.32.0", Digest: "sha256:abc", Target: "mycompany.com/myteam/busybox:1.32.0"}
+	deleted := auditEntry{Operation: auditOperationDelete, Digest: "sha256:def", Target: "mycompany.com/myteam/busybox:1.31.0", Error: "unauthorized"}
+
+	for _, entry := range []auditEntry{pushed, deleted} {
+		if err := audit.record(entry); err != nil {
+			t.Fatal("record:", err)
+		}
+	}
+
+	entries, err := readAuditLog(path)
+	if err != nil {
+		t.Fatal("read audit log:", err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, actual %v", len(entries))
+	}
+
+	for _, entry := range entries {
+		if entry.User != "alice" || entry.Time.IsZero() {
+			t.Errorf("expected entry of alice with a time, actual %+v", entry)
+		}
+	}
+
+	if entries[0].Target != pushed.Target || entries[1].Error != deleted.Error {
+		t.Errorf("expected entries in the order they were recorded, actual %+v", entries)
+	}
+
+	failed := historyFilter{failed: true}
+	if failed.matches(entries[0]) || !failed.matches(entries[1]) {
+		t.Error("expected only the failed entry to match the failed filter")
+	}
+
+	recent := historyFilter{since: time.Now().Add(time.Hour)}
+	if recent.matches(entries[0]) {
+		t.Error("expected entry before since not to match")
+	}
+
+	var table bytes.Buffer
+	if err := writeHistoryTable(&table, entries); err != nil {
+		t.Fatal("write history table:", err)
+	}
+
+	if !strings.Contains(table.String(), "failed: unauthorized") || !strings.Contains(table.String(), "succeeded") {
+		t.Errorf("expected statuses in table, actual\n%s", table.String())
+	}
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/viper"
+)
+
+// getSourceAuth returns the encoded auth for the source registry. Credentials that are passed in explicitly take
+// precedence over the auth defined in the manifest, which takes precedence over the secrets of the registry-secrets flag.
+func getSourceAuth(source manifest.Source) (string, error) {
+	if viper.GetString("source-username") != "" {
+		auth, err := docker.GetEncodedBasicAuth(viper.GetString("source-username"), viper.GetString("source-password"))
+		if err != nil {
+			return "", fmt.Errorf("get encoded basic auth: %w", err)
+		}
+
+		return auth, nil
+	}
+
+	if source.Auth == (manifest.Auth{}) && len(viper.GetStringSlice("registry-secrets")) > 0 {
+		reference, err := name.ParseReference(source.PullImage(), name.WeakValidation)
+		if err != nil {
+			return "", fmt.Errorf("parse reference: %w", err)
+		}
+
+		auth, found, err := getRegistrySecretAuth(reference.Context().RegistryStr())
+		if err != nil {
+			return "", fmt.Errorf("get registry secret auth: %w", err)
+		}
+
+		if found {
+			return auth, nil
+		}
+	}
+
+	auth, err := source.EncodedAuth()
+	if err != nil {
+		return "", fmt.Errorf("get source auth: %w", err)
+	}
+
+	return auth, nil
+}
+
+// getTargetAuth returns the encoded auth for the target registry. Credentials that are passed in explicitly take
+// precedence over the auth defined in the manifest, which takes precedence over the secrets of the registry-secrets flag.
+func getTargetAuth(target manifest.Target) (string, error) {
+	if viper.GetString("target-username") != "" {
+		auth, err := docker.GetEncodedBasicAuth(viper.GetString("target-username"), viper.GetString("target-password"))
+		if err != nil {
+			return "", fmt.Errorf("get encoded basic auth: %w", err)
+		}
+
+		return auth, nil
+	}
+
+	if target.Auth == (manifest.Auth{}) {
+		auth, found, err := getRegistrySecretAuth(target.Host)
+		if err != nil {
+			return "", fmt.Errorf("get registry secret auth: %w", err)
+		}
+
+		if found {
+			return auth, nil
+		}
+	}
+
+	auth, err := target.EncodedAuth()
+	if err != nil {
+		return "", fmt.Errorf("get target auth: %w", err)
+	}
+
+	return auth, nil
+}
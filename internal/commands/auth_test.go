@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetSourceAuth_ExplicitCredentials(t *testing.T) {
+	viper.Set("source-username", "user")
+	viper.Set("source-password", "pass")
+	defer viper.Set("source-username", "")
+	defer viper.Set("source-password", "")
+
+	source := manifest.Source{
+		Auth: manifest.Auth{
+			Username: "ENV_USER_KEY",
+			Password: "ENV_PASS_KEY",
+		},
+	}
+
+	actual, err := getSourceAuth(source)
+	if err != nil {
+		t.Fatal("get source auth:", err)
+	}
+
+	expectedAuthJSON := []byte(`{"Username":"user","Password":"pass"}`)
+	expected := base64.URLEncoding.EncodeToString(expectedAuthJSON)
+
+	if actual != expected {
+		t.Errorf("expected source auth %s, actual %s", expected, actual)
+	}
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+)
+
+// targetCache records the images that have been confirmed to exist at the target so that
+// repeated runs do not need to ask the target registry about images that have not changed.
+//
+// Images that are pinned to a digest are keyed by the digest of the source and never expire, as the
+// image that the digest refers to cannot change. Images that are only referenced by a tag can be moved
+// to a different image at the source, so they expire once they have been in the cache for the TTL.
+type targetCache struct {
+	path    string
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]targetCacheEntry
+	changed bool
+}
+
+type targetCacheEntry struct {
+	Confirmed time.Time `json:"confirmed"`
+	Pinned    bool      `json:"pinned,omitempty"`
+}
+
+// readTargetCache reads the cache at the given path. A cache that does not exist yet is empty.
+func readTargetCache(path string, ttl time.Duration) (*targetCache, error) {
+	cache := targetCache{
+		path:    path,
+		ttl:     ttl,
+		entries: make(map[string]targetCacheEntry),
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	if err := json.Unmarshal(contents, &cache.entries); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return &cache, nil
+}
+
+// getTargetCacheKey returns the key of the source in the cache. When the source is pinned
+// to a digest, the key includes the digest so that changing the digest invalidates the entry.
+func getTargetCacheKey(source manifest.Source) string {
+	if source.Digest != "" {
+		return source.TargetImage() + "#" + source.Digest
+	}
+
+	return source.TargetImage()
+}
+
+// exists returns true when the source has been confirmed to exist at the target and the entry has not expired.
+func (c *targetCache) exists(source manifest.Source) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[getTargetCacheKey(source)]
+	if !ok {
+		return false
+	}
+
+	return entry.Pinned || time.Since(entry.Confirmed) < c.ttl
+}
+
+// confirm records that the source exists at the target. Images that use the latest tag (or no tag)
+// are never cached, as they are always pushed again so that they are kept up to date.
+func (c *targetCache) confirm(source manifest.Source) {
+	if source.Digest == "" && (source.Tag == "" || source.Tag == "latest") {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[getTargetCacheKey(source)] = targetCacheEntry{
+		Confirmed: time.Now().UTC(),
+		Pinned:    source.Digest != "",
+	}
+	c.changed = true
+}
+
+// save writes the cache, without the expired entries, when it has changed. The cache is written
+// atomically so that an interrupted write does not leave a corrupt cache behind.
+func (c *targetCache) save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.changed {
+		return nil
+	}
+
+	for key, entry := range c.entries {
+		if !entry.Pinned && time.Since(entry.Confirmed) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+
+	contents, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := writeFileAtomic(c.path, contents); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	c.changed = false
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+)
+
+func TestTargetCache(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cachePath := filepath.Join(tempDir, "cache.json")
+
+	cache, err := readTargetCache(cachePath, time.Hour)
+	if err != nil {
+		t.Fatal("read target cache:", err)
+	}
+
+	tagged := manifest.Source{Repository: "busybox", Tag: "1.32.0", Target: manifest.Target{Host: "host.com"}}
+	pinned := manifest.Source{Repository: "busybox", Digest: "sha256:abc", Target: manifest.Target{Host: "host.com"}}
+	latest := manifest.Source{Repository: "busybox", Tag: "latest", Target: manifest.Target{Host: "host.com"}}
+
+	cache.confirm(tagged)
+	cache.confirm(pinned)
+	cache.confirm(latest)
+
+	if err := cache.save(); err != nil {
+		t.Fatal("save:", err)
+	}
+
+	cachedImages, err := readTargetCache(cachePath, time.Hour)
+	if err != nil {
+		t.Fatal("read saved target cache:", err)
+	}
+
+	if !cachedImages.exists(tagged) || !cachedImages.exists(pinned) {
+		t.Error("expected confirmed images to exist")
+	}
+
+	if cachedImages.exists(latest) {
+		t.Error("expected latest image to not be cached")
+	}
+
+	repinned := pinned
+	repinned.Digest = "sha256:def"
+	if cachedImages.exists(repinned) {
+		t.Error("expected image with a different digest to not be cached")
+	}
+
+	expiredImages, err := readTargetCache(cachePath, 0)
+	if err != nil {
+		t.Fatal("read expired target cache:", err)
+	}
+
+	if expiredImages.exists(tagged) {
+		t.Error("expected tagged image to expire")
+	}
+
+	if !expiredImages.exists(pinned) {
+		t.Error("expected pinned image to never expire")
+	}
+}
@@ -2,12 +2,14 @@ package commands
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/plexsystems/sinker/internal/docker"
 	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/images"
 
 	"github.com/hashicorp/go-version"
 	log "github.com/sirupsen/logrus"
@@ -16,17 +18,160 @@ import (
 )
 
 func newCheckCommand() *cobra.Command {
+	var deprecateErr error
 	cmd := cobra.Command{
 		Use:   "check",
-		Short: "Check for newer images",
+		Short: "Check for newer images, or that the images in the manifest exist at the target registry",
 
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if deprecateErr != nil {
+				return fmt.Errorf("deprecate updates flag: %w", deprecateErr)
+			}
+
 			if err := viper.BindPFlag("images", cmd.Flags().Lookup("images")); err != nil {
 				return fmt.Errorf("bind images flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("images-file", cmd.Flags().Lookup("images-file")); err != nil {
+				return fmt.Errorf("bind images-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exists", cmd.Flags().Lookup("exists")); err != nil {
+				return fmt.Errorf("bind exists flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("updates", cmd.Flags().Lookup("updates")); err != nil {
+				return fmt.Errorf("bind updates flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude", cmd.Flags().Lookup("exclude")); err != nil {
+				return fmt.Errorf("bind exclude flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-regex", cmd.Flags().Lookup("exclude-regex")); err != nil {
+				return fmt.Errorf("bind exclude-regex flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("source-filter", cmd.Flags().Lookup("source-filter")); err != nil {
+				return fmt.Errorf("bind source-filter flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("cache-file", cmd.Flags().Lookup("cache-file")); err != nil {
+				return fmt.Errorf("bind cache-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("cache-ttl", cmd.Flags().Lookup("cache-ttl")); err != nil {
+				return fmt.Errorf("bind cache-ttl flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("fail-on", cmd.Flags().Lookup("fail-on")); err != nil {
+				return fmt.Errorf("bind fail-on flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("fail-threshold", cmd.Flags().Lookup("fail-threshold")); err != nil {
+				return fmt.Errorf("bind fail-threshold flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("cluster", cmd.Flags().Lookup("cluster")); err != nil {
+				return fmt.Errorf("bind cluster flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("manifest-key", cmd.Flags().Lookup("manifest-key")); err != nil {
+				return fmt.Errorf("bind manifest-key flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("manifest-signature", cmd.Flags().Lookup("manifest-signature")); err != nil {
+				return fmt.Errorf("bind manifest-signature flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("summary-file", cmd.Flags().Lookup("summary-file")); err != nil {
+				return fmt.Errorf("bind summary-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("jobs", cmd.Flags().Lookup("jobs")); err != nil {
+				return fmt.Errorf("bind jobs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("rate-limit", cmd.Flags().Lookup("rate-limit")); err != nil {
+				return fmt.Errorf("bind rate-limit flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platform", cmd.Flags().Lookup("platform")); err != nil {
+				return fmt.Errorf("bind platform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-image-size", cmd.Flags().Lookup("max-image-size")); err != nil {
+				return fmt.Errorf("bind max-image-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("offline", cmd.Flags().Lookup("offline")); err != nil {
+				return fmt.Errorf("bind offline flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("snapshot", cmd.Flags().Lookup("snapshot")); err != nil {
+				return fmt.Errorf("bind snapshot flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("report", cmd.Flags().Lookup("report")); err != nil {
+				return fmt.Errorf("bind report flag: %w", err)
+			}
+
+			if viper.GetBool("cluster") {
+				if err := runCheckClusterCommand(cmd.Context()); err != nil {
+					return fmt.Errorf("check cluster: %w", err)
+				}
+
+				return nil
+			}
+
+			if viper.GetBool("exists") && viper.GetBool("updates") {
+				return errors.New("exists and updates flags cannot be used together")
+			}
+
 			manifestPath := viper.GetString("manifest")
-			if err := runCheckCommand(manifestPath); err != nil {
+			if !viper.GetBool("exists") {
+				for _, flag := range existsOnlyFlags {
+					if cmd.Flags().Changed(flag) {
+						return fmt.Errorf("%s flag requires the exists flag", flag)
+					}
+				}
+
+				if err := runCheckUpdatesCommand(cmd.Context(), manifestPath); err != nil {
+					return fmt.Errorf("check updates: %w", err)
+				}
+
+				return nil
+			}
+
+			if viper.GetBool("offline") && viper.GetString("snapshot") == "" {
+				return errors.New("snapshot must be specified when using the offline flag")
+			}
+
+			if viper.GetBool("offline") && len(viper.GetStringSlice("platform")) > 0 {
+				return errors.New("platforms cannot be checked when using the offline flag, as the snapshot does not record them")
+			}
+
+			if viper.GetBool("offline") && viper.GetString("max-image-size") != "" {
+				return errors.New("image sizes cannot be checked when using the offline flag, as the snapshot does not record them")
+			}
+
+			if _, err := docker.ParseSize(viper.GetString("max-image-size")); viper.GetString("max-image-size") != "" && err != nil {
+				return fmt.Errorf("max image size: %w", err)
+			}
+
+			if hasInputImages() && viper.GetString("target") == "" {
+				return errors.New("target must be specified when using the images or images-file flag")
+			}
+
+			if err := runCheckCommand(cmd.Context(), manifestPath); err != nil {
 				return fmt.Errorf("check: %w", err)
 			}
 
@@ -35,48 +180,445 @@ func newCheckCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringSliceP("images", "i", []string{}, "List of images to check (e.g. host.com/repo:v1.0.0)")
+	cmd.Flags().String("images-file", "", "Path to a file that lists the images to check, one per line (e.g. the output of list), or - for stdin")
+	cmd.Flags().StringP("target", "t", "", "Registry to check the images against when using the images flag")
+	cmd.Flags().Bool("exists", false, "Check that the images exist at the target registry instead of checking for newer versions")
+	cmd.Flags().Bool("updates", false, "Check the source images for newer versions, which is the default")
+	cmd.Flags().StringSlice("exclude", []string{}, "Glob patterns of images to exclude (e.g. busybox:*)")
+	cmd.Flags().StringSlice("exclude-regex", []string{}, "Regular expressions of images to exclude")
+	cmd.Flags().StringSlice("source-filter", []string{}, "Only include images from the given source registries (e.g. quay.io,gcr.io)")
+	cmd.Flags().String("cache-file", "", "Path to a file that caches the images that exist at the target so that they are not checked again")
+	cmd.Flags().Duration("cache-ttl", 24*time.Hour, "How long images that are not pinned to a digest are cached for")
+	cmd.Flags().String("manifest-key", "", "Public key to verify the signature of the manifest with before acting on it (requires cosign)")
+	cmd.Flags().String("manifest-signature", "", "Path to the signature of the manifest (defaults to the path of the manifest with a .sig extension)")
+	cmd.Flags().String("summary-file", "", "Path to write a JSON summary of the images that were found to")
+	cmd.Flags().String("report", "", "Path to write a JSON report of the status, digests and timings of each image to")
+	cmd.Flags().IntP("jobs", "j", 10, "Number of images to check at the same time")
+	cmd.Flags().Int("rate-limit", 0, "Maximum number of images to check at each target registry per minute (defaults to no limit)")
+	cmd.Flags().StringSlice("platform", []string{}, "Platforms that every image must provide (e.g. linux/arm64)")
+	cmd.Flags().String("max-image-size", "", "Maximum compressed size of an image at the target (e.g. 5GiB), defaults to the maxImageSize of the policy of the manifest")
+	cmd.Flags().Bool("offline", false, "Check the images against a snapshot of the target instead of the target registry")
+	cmd.Flags().String("snapshot", "", "Path to the snapshot created by the snapshot command when using the offline flag")
+	cmd.Flags().Bool("cluster", false, "Check that the workloads running in a Kubernetes cluster can pull their images with their image pull secrets instead (requires kubectl)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Namespaces to check the workloads of when using the cluster flag (defaults to all namespaces)")
+	cmd.Flags().StringSlice("fail-on", []string{failOnMissing, failOnPlatform, failOnOversized}, "Kinds of images that cause the check to fail (missing, untagged, latest-tag, platform, oversized or none)")
+	cmd.Flags().Int("fail-threshold", 0, "Number of images of each kind in fail-on that are allowed before the check fails")
+
+	deprecateErr = cmd.Flags().MarkDeprecated("updates", "newer versions are checked for by default")
 
 	return &cmd
 }
 
-func runCheckCommand(manifestPath string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// existsOnlyFlags are the flags of the check command that only apply when checking
+// that the images exist at the target, rather than when checking for newer versions.
+var existsOnlyFlags = []string{
+	"target",
+	"cache-file",
+	"cache-ttl",
+	"summary-file",
+	"report",
+	"jobs",
+	"rate-limit",
+	"platform",
+	"max-image-size",
+	"offline",
+	"snapshot",
+	"fail-on",
+	"fail-threshold",
+}
+
+func runCheckCommand(ctx context.Context, manifestPath string) error {
+	started := time.Now()
+
+	var summary runSummary
+	report := newRunReport("check")
+	err := checkImages(ctx, manifestPath, &summary, report)
+	sendNotifications(ctx, "check", started, summary, err)
+
+	return writeRunReport(report, err)
+}
+
+// checkImages checks the images and records the counts of the run in the summary and the outcome of each image in
+// the report, which are filled in as far as the check got, so that they can be sent or written even on failure.
+func checkImages(ctx context.Context, manifestPath string, summary *runSummary, report *runReport) error {
+	ctx, cancel := withCommandTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	client, err := docker.NewClient(log.Infof)
+	policy, err := getFailurePolicy(failOnMissing, failOnUntagged, failOnLatestTag, failOnPlatform, failOnOversized)
+	if err != nil {
+		return fmt.Errorf("get failure policy: %w", err)
+	}
+
+	sources, sourcesSummary, err := getImagesOrManifestSources(ctx, manifestPath)
+	if err != nil {
+		return fmt.Errorf("get sources: %w", err)
+	}
+	*summary = sourcesSummary
+
+	missingImages, err := findMissingImages(ctx, sources)
+	if err != nil {
+		return fmt.Errorf("find missing images: %w", err)
+	}
+
+	summary.MissingAtTarget = len(missingImages)
+	if err := writeSummary(*summary); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	var mismatchedImages []string
+	if len(viper.GetStringSlice("platform")) > 0 {
+		mismatchedImages, err = findImagesWithoutPlatforms(ctx, sources, missingImages, viper.GetStringSlice("platform"))
+		if err != nil {
+			return fmt.Errorf("find images without platforms: %w", err)
+		}
+	}
+
+	// The snapshot of an offline check does not record the sizes of the images.
+	var oversizedImages []string
+	if !viper.GetBool("offline") {
+		maxImageSize, maxImageSizeSetting, err := getMaxImageSize(manifestPath)
+		if err != nil {
+			return fmt.Errorf("get max image size: %w", err)
+		}
+
+		if maxImageSize > 0 {
+			oversizedImages, err = findOversizedImages(ctx, sources, missingImages, maxImageSize, maxImageSizeSetting)
+			if err != nil {
+				return fmt.Errorf("find oversized images: %w", err)
+			}
+		}
+	}
+
+	if viper.GetString("report") != "" {
+		if err := reportCheckedImages(ctx, report, sources, missingImages, mismatchedImages, oversizedImages); err != nil {
+			return fmt.Errorf("report checked images: %w", err)
+		}
+	}
+
+	counts := countSourceKinds(sources)
+	counts[failOnMissing] = len(missingImages)
+	counts[failOnPlatform] = len(mismatchedImages)
+	counts[failOnOversized] = len(oversizedImages)
+	if err := policy.check(counts); err != nil {
+		return err
+	}
+
+	if len(missingImages) == 0 {
+		log.Infof("All images exist at the target!")
+	}
+
+	return nil
+}
+
+// findMissingImages returns the target images of the sources that do not exist at the target.
+// When a cache file is set, images that were recently confirmed to exist are not checked again.
+func findMissingImages(ctx context.Context, sources []manifest.Source) ([]string, error) {
+	if viper.GetBool("offline") {
+		return findMissingImagesInSnapshot(viper.GetString("snapshot"), sources)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+
+	var cache *targetCache
+	if viper.GetString("cache-file") != "" {
+		cache, err = readTargetCache(viper.GetString("cache-file"), viper.GetDuration("cache-ttl"))
+		if err != nil {
+			return nil, fmt.Errorf("read target cache: %w", err)
+		}
+
+		defer func() {
+			if err := cache.save(); err != nil {
+				log.Warnf("Unable to save the target cache: %v", err)
+			}
+		}()
+	}
+
+	var rateLimiter *docker.RateLimiter
+	if viper.GetInt("rate-limit") > 0 {
+		rateLimiter = docker.NewRateLimiter(viper.GetInt("rate-limit"))
+	}
+
+	log.Infof("Checking that images exist at the target ...")
+
+	// The images are checked at the same time, so the missing images are collected
+	// by the index of their source to keep them in the order of the manifest.
+	missing := make([]bool, len(sources))
+	check := func(i int) error {
+		source := sources[i]
+		if cache != nil && cache.exists(source) {
+			return nil
+		}
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		if rateLimiter != nil {
+			if err := rateLimiter.Wait(ctx, source.TargetImage()); err != nil {
+				return fmt.Errorf("wait for rate limit: %w", err)
+			}
+		}
+
+		exists, err := client.ManifestExistsAtRemote(ctx, source.TargetImage(), targetAuth)
+		if err != nil {
+			return fmt.Errorf("manifest exists at remote: %w", err)
+		}
+
+		if !exists {
+			logImageStatus(statusFailed, "Image %s is missing from the target%s", source.TargetImage(), getDefinedAt(source))
+			printQuiet(source.TargetImage())
+			missing[i] = true
+		} else if cache != nil {
+			cache.confirm(source)
+		}
+
+		return nil
+	}
+
+	if err := runJobs(ctx, viper.GetInt("jobs"), len(sources), check); err != nil {
+		return nil, err
+	}
+
+	var missingImages []string
+	for i, source := range sources {
+		if missing[i] {
+			missingImages = append(missingImages, source.TargetImage())
+		}
+	}
+
+	return missingImages, nil
+}
+
+// findImagesWithoutPlatforms returns the target images of the sources that do not provide all of the given platforms,
+// such as images that would fail to schedule on nodes with a different architecture. Missing images are not checked.
+func findImagesWithoutPlatforms(ctx context.Context, sources []manifest.Source, missingImages []string, platforms []string) ([]string, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+
+	for _, platform := range platforms {
+		if _, err := docker.ParsePlatform(platform); err != nil {
+			return nil, fmt.Errorf("parse platform: %w", err)
+		}
+	}
+
+	log.Infof("Checking that images provide the platforms %v ...", platforms)
+
+	mismatched := make([]bool, len(sources))
+	check := func(i int) error {
+		source := sources[i]
+		if containsString(missingImages, source.TargetImage()) || source.IsArtifact() {
+			return nil
+		}
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		missingPlatforms, err := client.GetMissingPlatforms(ctx, source.TargetImage(), targetAuth, platforms)
+		if err != nil {
+			return fmt.Errorf("get missing platforms of %s: %w", source.TargetImage(), err)
+		}
+
+		if len(missingPlatforms) > 0 {
+			logImageStatus(statusFailed, "Image %s does not provide the platforms %v%s", source.TargetImage(), missingPlatforms, getDefinedAt(source))
+			printQuiet(source.TargetImage())
+			mismatched[i] = true
+		}
+
+		return nil
+	}
+
+	if err := runJobs(ctx, viper.GetInt("jobs"), len(sources), check); err != nil {
+		return nil, err
+	}
+
+	var mismatchedImages []string
+	for i, source := range sources {
+		if mismatched[i] {
+			mismatchedImages = append(mismatchedImages, source.TargetImage())
+		}
+	}
+
+	return mismatchedImages, nil
+}
+
+// findOversizedImages returns the target images of the sources whose compressed size exceeds the maximum image size,
+// such as images that were pushed before the maximum size was set. Missing images are not checked.
+func findOversizedImages(ctx context.Context, sources []manifest.Source, missingImages []string, maxImageSize int64, maxImageSizeSetting string) ([]string, error) {
+	client, err := newClient()
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+
+	log.Infof("Checking that images are at most %s ...", maxImageSizeSetting)
+
+	oversized := make([]bool, len(sources))
+	check := func(i int) error {
+		source := sources[i]
+		if containsString(missingImages, source.TargetImage()) {
+			return nil
+		}
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		size, err := getImageSize(ctx, client, source.TargetImage(), targetAuth, nil)
+		if err != nil {
+			return fmt.Errorf("get size of %s: %w", source.TargetImage(), err)
+		}
+
+		if size > maxImageSize {
+			logImageStatus(statusFailed, "Image %s has a size of %s, which exceeds the maximum image size of %s%s", source.TargetImage(), formatBytes(uint64(size)), maxImageSizeSetting, getDefinedAt(source))
+			printQuiet(source.TargetImage())
+			oversized[i] = true
+		}
+
+		return nil
+	}
+
+	if err := runJobs(ctx, viper.GetInt("jobs"), len(sources), check); err != nil {
+		return nil, err
+	}
+
+	var oversizedImages []string
+	for i, source := range sources {
+		if oversized[i] {
+			oversizedImages = append(oversizedImages, source.TargetImage())
+		}
+	}
+
+	return oversizedImages, nil
+}
+
+// reportCheckedImages records the status of each of the checked images in the report, along with the issues that were
+// found with the images that exist at the target. The duration of each image is how long its digest at the target took
+// to resolve, as the images are checked at the same time.
+func reportCheckedImages(ctx context.Context, report *runReport, sources []manifest.Source, missingImages []string, mismatchedImages []string, oversizedImages []string) error {
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	reportImage := func(i int) error {
+		source := sources[i]
+		if containsString(missingImages, source.TargetImage()) {
+			report.add(source, reportStatusMissing, time.Time{}, nil)
+			return nil
+		}
+
+		started := time.Now()
+
+		var digest string
+		if !viper.GetBool("offline") {
+			targetAuth, err := getTargetAuth(source.Target)
+			if err != nil {
+				return fmt.Errorf("get target auth: %w", err)
+			}
+
+			digest, err = client.GetDigest(ctx, source.TargetImage(), targetAuth)
+			if err != nil {
+				return fmt.Errorf("get digest of %s: %w", source.TargetImage(), err)
+			}
+		}
+
+		reportedImage := newReportedImage(source, reportStatusExists, started, nil)
+		reportedImage.TargetDigest = digest
+		if containsString(mismatchedImages, source.TargetImage()) {
+			reportedImage.Issues = append(reportedImage.Issues, failOnPlatform)
+		}
+		if containsString(oversizedImages, source.TargetImage()) {
+			reportedImage.Issues = append(reportedImage.Issues, failOnOversized)
+		}
+
+		report.addImage(reportedImage)
+		return nil
+	}
+
+	if err := runJobs(ctx, viper.GetInt("jobs"), len(sources), reportImage); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// findMissingImagesInSnapshot returns the target images of the sources that were not at the target
+// when the snapshot was taken, without accessing the target registry.
+func findMissingImagesInSnapshot(snapshotPath string, sources []manifest.Source) ([]string, error) {
+	snapshot, err := readSnapshot(snapshotPath)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+
+	log.Infof("Checking that images exist in the snapshot of the target taken at %s ...", snapshot.Created.Format(time.RFC3339))
+
+	var missingImages []string
+	for _, source := range sources {
+		if !snapshot.exists(source) {
+			logImageStatus(statusFailed, "Image %s is missing from the target%s", source.TargetImage(), getDefinedAt(source))
+			printQuiet(source.TargetImage())
+			missingImages = append(missingImages, source.TargetImage())
+		}
+	}
+
+	return missingImages, nil
+}
+
+func runCheckUpdatesCommand(ctx context.Context, manifestPath string) error {
+	ctx, cancel := withCommandTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	client, err := newClient()
 	if err != nil {
 		return fmt.Errorf("new client: %w", err)
 	}
 
-	imagesToCheck := viper.GetStringSlice("images")
+	imagesToCheck, err := getInputImages()
+	if err != nil {
+		return fmt.Errorf("get input images: %w", err)
+	}
+
 	if len(imagesToCheck) == 0 {
-		imageManifest, err := manifest.Get(manifestPath)
+		sources, err := getManifestSources(ctx, manifestPath)
 		if err != nil {
-			return fmt.Errorf("get manifest: %w", err)
+			return fmt.Errorf("get manifest sources: %w", err)
 		}
 
-		for _, source := range imageManifest.Sources {
+		for _, source := range sources {
 			imagesToCheck = append(imagesToCheck, source.Image())
 		}
 	}
 
-	var images []docker.RegistryPath
+	var parsedImages []images.Image
 	for _, image := range imagesToCheck {
-		images = append(images, docker.RegistryPath(image))
+		parsedImage, err := images.ParseReference(image, getRegistryHostsOption())
+		if err != nil {
+			return fmt.Errorf("parse image %s: %w", image, err)
+		}
+
+		parsedImages = append(parsedImages, parsedImage)
 	}
 
-	for _, image := range images {
-		if image.Tag() == "" {
+	for _, image := range parsedImages {
+		if image.Tag == "" {
 			continue
 		}
 
-		imageVersion, err := version.NewVersion(image.Tag())
+		imageVersion, err := version.NewVersion(image.Tag)
 		if err != nil {
-			log.Infof("Image %s has an invalid version. Skipping ...", image)
+			log.Warnf("Image %s has an invalid version. Skipping ...", image)
 			continue
 		}
 
-		tags, err := client.GetTagsForRepository(ctx, image.Host(), image.Repository())
+		tags, err := client.GetTagsForRepository(ctx, image.Host, image.Repository)
 		if err != nil {
 			return fmt.Errorf("get tags: %w", err)
 		}
@@ -155,3 +697,13 @@ func containsSubstring(items []string, item string) bool {
 
 	return false
 }
+
+// getDefinedAt returns where the source is defined in the manifest (e.g. " (defined at .images.yaml:12)"),
+// or nothing when the source was not read from the manifest.
+func getDefinedAt(source manifest.Source) string {
+	if source.Line() == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" (defined at %s:%v)", manifest.Location(viper.GetString("manifest")), source.Line())
+}
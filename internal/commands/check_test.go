@@ -1,12 +1,66 @@
 package commands
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/plexsystems/sinker/internal/manifest"
+
 	"github.com/hashicorp/go-version"
 )
 
+func TestFindMissingImages(t *testing.T) {
+	existing := map[string]bool{
+		"/v2/mirror/busybox/manifests/1.32.0": true,
+		"/v2/mirror/nginx/manifests/1.25":     true,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+
+		case existing[r.URL.Path]:
+			w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+			w.Write([]byte("{}"))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	target := manifest.Target{Host: strings.TrimPrefix(server.URL, "http://"), Repository: "mirror"}
+
+	var sources []manifest.Source
+	var expected []string
+	for i := 0; i < 10; i++ {
+		source := manifest.Source{Target: target, Repository: "alpine", Tag: fmt.Sprintf("3.%v", i)}
+		sources = append(sources, source)
+		expected = append(expected, source.TargetImage())
+
+		if i == 5 {
+			sources = append(sources, manifest.Source{Target: target, Repository: "busybox", Tag: "1.32.0"})
+		}
+	}
+	sources = append(sources, manifest.Source{Target: target, Repository: "nginx", Tag: "1.25"})
+
+	actual, err := findMissingImages(context.Background(), sources)
+	if err != nil {
+		t.Fatal("find missing images:", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected missing images %v, actual %v", expected, actual)
+	}
+}
+
 func TestFilterTags(t *testing.T) {
 	tags := []string{
 		"noperiods",
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// runCheckClusterCommand checks that the nodes of a cluster are able to pull the images of the workloads
+// running in it, using the image pull secrets of each workload and its service account, so that missing
+// images and misconfigured pull secrets are caught before the workloads are rolled out.
+func runCheckClusterCommand(ctx context.Context) error {
+	ctx, cancel := withCommandTimeout(ctx, 0)
+	defer cancel()
+
+	policy, err := getFailurePolicy(failOnMissing)
+	if err != nil {
+		return fmt.Errorf("get failure policy: %w", err)
+	}
+
+	cluster := images.Cluster{
+		Kubeconfig: viper.GetString("kubeconfig"),
+		Context:    viper.GetString("context"),
+		Namespaces: viper.GetStringSlice("namespaces"),
+	}
+
+	workloads, err := images.FindWorkloadsInCluster(cluster, images.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("find workloads in cluster: %w", err)
+	}
+
+	pullSecrets, err := images.FindPullSecretsInCluster(cluster, images.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("find pull secrets in cluster: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	log.Infof("Checking that the images of %v workload(s) can be pulled ...", len(workloads))
+
+	// The same image is often used by several workloads with the same pull secret, so
+	// the result of pulling an image with an auth is only checked once.
+	pullErrors := make(map[string]error)
+
+	var failedPulls int
+	for _, workload := range workloads {
+		for _, image := range workload.Images {
+			auth, secret, err := getPullAuth(workload, pullSecrets, image)
+			if err != nil {
+				return fmt.Errorf("get pull auth: %w", err)
+			}
+
+			key := image + "\n" + auth
+			pullErr, checked := pullErrors[key]
+			if !checked {
+				pullErr = checkPull(ctx, client, image, auth)
+				pullErrors[key] = pullErr
+			}
+
+			if pullErr == nil {
+				continue
+			}
+
+			failedPulls++
+
+			if secret == "" {
+				log.Errorf("%s %s/%s cannot pull %s without a pull secret: %v", workload.Kind, workload.Namespace, workload.Name, image, pullErr)
+			} else {
+				log.Errorf("%s %s/%s cannot pull %s with pull secret %s: %v", workload.Kind, workload.Namespace, workload.Name, image, secret, pullErr)
+			}
+		}
+	}
+
+	if err := policy.check(map[string]int{failOnMissing: failedPulls}); err != nil {
+		return err
+	}
+
+	if failedPulls == 0 {
+		log.Infof("All images can be pulled!")
+	}
+
+	return nil
+}
+
+// getPullAuth returns the auth that the node uses to pull the image of the workload, which is the auth of the first
+// pull secret of the workload that has credentials for the registry of the image, and the name of that secret. When
+// none of the pull secrets have credentials for the registry, the image is pulled anonymously.
+func getPullAuth(workload images.Workload, pullSecrets map[string][]byte, image string) (string, string, error) {
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return "", "", fmt.Errorf("parse ref: %w", err)
+	}
+
+	for _, secret := range workload.PullSecrets {
+		dockerConfig, ok := pullSecrets[workload.Namespace+"/"+secret]
+		if !ok {
+			log.Warnf("%s %s/%s uses pull secret %s, which does not exist", workload.Kind, workload.Namespace, workload.Name, secret)
+			continue
+		}
+
+		auth, found, err := docker.GetEncodedAuthFromDockerConfig(dockerConfig, reference.Context().RegistryStr())
+		if err != nil {
+			return "", "", fmt.Errorf("get auth from pull secret %s: %w", secret, err)
+		}
+
+		if found {
+			return auth, secret, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+// checkPull returns an error when the image cannot be pulled with the auth.
+func checkPull(ctx context.Context, client docker.Client, image string, auth string) error {
+	exists, err := client.ManifestExistsAtRemote(ctx, image, auth)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return errors.New("image does not exist")
+	}
+
+	return nil
+}
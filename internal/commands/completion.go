@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newCompletionCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:       "completion <bash|zsh|fish|powershell>",
+		Short:     "Generate the shell completion script for sinker",
+		Long:      "Generate the shell completion script for sinker. For example, to load the completions in the current bash session:\n\n  source <(sinker completion bash)",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			switch args[0] {
+			case "bash":
+				err = cmd.Root().GenBashCompletion(os.Stdout)
+			case "zsh":
+				err = cmd.Root().GenZshCompletion(os.Stdout)
+			case "fish":
+				err = cmd.Root().GenFishCompletion(os.Stdout, true)
+			case "powershell":
+				err = cmd.Root().GenPowerShellCompletion(os.Stdout)
+			}
+			if err != nil {
+				return fmt.Errorf("generate %s completion: %w", args[0], err)
+			}
+
+			return nil
+		},
+	}
+
+	return &cmd
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/viper"
+)
+
+// configFiles are the names of the config files that are loaded from the working
+// directory when a config file is not given explicitly, in order of precedence.
+var configFiles = []string{"sinker.yaml", ".sinker.yaml", "sinker.toml", ".sinker.toml"}
+
+// loadConfig reads the defaults of the flags from the config file at the given path.
+// When the path is empty, the first config file found in the directory is used, if any.
+//
+// Flags that are set explicitly and environment variables take precedence over the config file.
+// References to environment variables in the config file (e.g. ${MIRROR_REGISTRY}) are replaced with their values.
+func loadConfig(path string, dir string) error {
+	if path == "" {
+		path = findConfigFile(dir)
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+
+	// The config file can reference environment variables in the same way as the manifest.
+	contents, err = manifest.ExpandVariables(contents, nil)
+	if err != nil {
+		return fmt.Errorf("expand variables in config %s: %w", path, err)
+	}
+
+	viper.SetConfigFile(path)
+	if err := viper.ReadConfig(bytes.NewReader(contents)); err != nil {
+		return fmt.Errorf("read config %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func findConfigFile(dir string) string {
+	for _, configFile := range configFiles {
+		path := filepath.Join(dir, configFile)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadConfig(t *testing.T) {
+	defer viper.Reset()
+
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	contents := []byte(`target: mycompany.com/myrepo
+jobs: 4
+exclude:
+- busybox:*
+`)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, ".sinker.yaml"), contents, os.ModePerm); err != nil {
+		t.Fatal("write config:", err)
+	}
+
+	if err := loadConfig("", dir); err != nil {
+		t.Fatal("load config:", err)
+	}
+
+	if viper.GetString("target") != "mycompany.com/myrepo" {
+		t.Errorf("expected target mycompany.com/myrepo, actual %s", viper.GetString("target"))
+	}
+
+	if viper.GetInt("jobs") != 4 {
+		t.Errorf("expected 4 jobs, actual %v", viper.GetInt("jobs"))
+	}
+
+	expectedExclude := []string{"busybox:*"}
+	if !reflect.DeepEqual(viper.GetStringSlice("exclude"), expectedExclude) {
+		t.Errorf("expected exclude %v, actual %v", expectedExclude, viper.GetStringSlice("exclude"))
+	}
+}
+
+func TestLoadConfig_NoConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := loadConfig("", dir); err != nil {
+		t.Error("expected no error when there is no config file, actual", err)
+	}
+
+	if err := loadConfig(filepath.Join(dir, "missing.yaml"), dir); err == nil {
+		t.Error("expected error when the given config file does not exist")
+	}
+}
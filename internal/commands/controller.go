@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/controller"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const defaultControllerInterval = 5 * time.Minute
+
+func newControllerCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "controller",
+		Short: "Run in a cluster and continuously mirror the images of ImageSync resources",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("namespace", cmd.Flags().Lookup("namespace")); err != nil {
+				return fmt.Errorf("bind namespace flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("interval", cmd.Flags().Lookup("interval")); err != nil {
+				return fmt.Errorf("bind interval flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("metrics-address", cmd.Flags().Lookup("metrics-address")); err != nil {
+				return fmt.Errorf("bind metrics-address flag: %w", err)
+			}
+
+			if viper.GetDuration("interval") <= 0 {
+				return errors.New("interval must be greater than zero")
+			}
+
+			if err := runControllerCommand(cmd.Context()); err != nil {
+				return fmt.Errorf("controller: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("namespace", "", "Namespace to watch for ImageSync resources (defaults to all namespaces)")
+	cmd.Flags().Duration("interval", defaultControllerInterval, "How often to reconcile every ImageSync resource")
+	cmd.Flags().String("metrics-address", "", "Address to serve Prometheus metrics on (e.g. :9090)")
+
+	return &cmd
+}
+
+func runControllerCommand(ctx context.Context) error {
+	serveMetrics(viper.GetString("metrics-address"))
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	config := controller.Config{
+		Namespace:  viper.GetString("namespace"),
+		Interval:   viper.GetDuration("interval"),
+		SourceAuth: getSourceAuth,
+		TargetAuth: getTargetAuth,
+		Logger:     log.Infof,
+	}
+
+	imageSyncController, err := controller.New(client, config)
+	if err != nil {
+		return fmt.Errorf("new controller: %w", err)
+	}
+
+	log.Infof("Reconciling ImageSync resources every %v ...", config.Interval)
+	if err := imageSyncController.Run(ctx); err != nil {
+		return fmt.Errorf("run: %w", err)
+	}
+
+	return nil
+}
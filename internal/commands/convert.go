@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newConvertCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "convert <path>",
+		Short: "Convert a list of images of another mirroring tool to a manifest, or a manifest to the list of another tool",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("from", cmd.Flags().Lookup("from")); err != nil {
+				return fmt.Errorf("bind from flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("to", cmd.Flags().Lookup("to")); err != nil {
+				return fmt.Errorf("bind to flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			if err := runConvertCommand(args[0]); err != nil {
+				return fmt.Errorf("convert: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	formats := strings.Join(manifest.ConvertFormats, ", ")
+	cmd.Flags().String("from", "", "Format of the list to convert, one of "+formats+" (detected from the contents by default)")
+	cmd.Flags().String("to", manifest.FormatManifest, "Format to convert the list to, one of "+formats)
+	cmd.Flags().StringP("target", "t", "", "Registry the images are pushed to when the list does not record it (e.g. mycompany.com/mirror)")
+	cmd.Flags().StringP("output", "o", "", "Path where the converted list will be written to (defaults to stdout)")
+
+	return &cmd
+}
+
+func runConvertCommand(path string) error {
+	reader := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open: %w", err)
+		}
+		defer f.Close()
+
+		reader = f
+	}
+
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	from := viper.GetString("from")
+	if from == "" {
+		from = manifest.DetectFormat(contents)
+		log.Infof("Converting %s as %s", path, from)
+	}
+
+	var target manifest.Target
+	if viper.GetString("target") != "" {
+		targetPath := docker.RegistryPath(viper.GetString("target"))
+		target = manifest.Target{
+			Host:       targetPath.Host(getRegistryHostsOption()),
+			Repository: targetPath.Repository(getRegistryHostsOption()),
+		}
+	}
+
+	to := viper.GetString("to")
+	if target.Host == "" && to == manifest.FormatManifest && (from == manifest.FormatSkopeo || from == manifest.FormatKubeImageKeeper) {
+		log.Warnf("The %s format does not record where the images are pushed to, set the target of the manifest with --target", from)
+	}
+
+	if (from == manifest.FormatManifest || from == manifest.FormatText) && (to == manifest.FormatSkopeo || to == manifest.FormatKubeImageKeeper) {
+		log.Warnf("The %s format does not record where the images are pushed to, so the targets of the images are not converted", to)
+	}
+
+	converted, err := manifest.Convert(contents, from, to, target)
+	if err != nil {
+		return fmt.Errorf("convert %s to %s: %w", from, to, err)
+	}
+
+	if viper.GetString("output") == "" {
+		if _, err := os.Stdout.Write(converted); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := ioutil.WriteFile(viper.GetString("output"), converted, os.ModePerm); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
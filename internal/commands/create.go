@@ -1,20 +1,29 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
 
 	"github.com/plexsystems/sinker/internal/docker"
 	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/images"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func newCreateCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "create <source>",
 		Short: "Create a new a manifest",
+		Args:  cobra.MaximumNArgs(1),
 
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
@@ -25,6 +34,106 @@ func newCreateCommand() *cobra.Command {
 				return fmt.Errorf("bind output flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("resolve-digests", cmd.Flags().Lookup("resolve-digests")); err != nil {
+				return fmt.Errorf("bind resolve-digests flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm", cmd.Flags().Lookup("helm")); err != nil {
+				return fmt.Errorf("bind helm flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm-values", cmd.Flags().Lookup("helm-values")); err != nil {
+				return fmt.Errorf("bind helm-values flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kustomize", cmd.Flags().Lookup("kustomize")); err != nil {
+				return fmt.Errorf("bind kustomize flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images", cmd.Flags().Lookup("env-images")); err != nil {
+				return fmt.Errorf("bind env-images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images-pattern", cmd.Flags().Lookup("env-images-pattern")); err != nil {
+				return fmt.Errorf("bind env-images-pattern flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("crd-config", cmd.Flags().Lookup("crd-config")); err != nil {
+				return fmt.Errorf("bind crd-config flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("deep-scan", cmd.Flags().Lookup("deep-scan")); err != nil {
+				return fmt.Errorf("bind deep-scan flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dockerfiles", cmd.Flags().Lookup("dockerfiles")); err != nil {
+				return fmt.Errorf("bind dockerfiles flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("ci-files", cmd.Flags().Lookup("ci-files")); err != nil {
+				return fmt.Errorf("bind ci-files flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("terraform", cmd.Flags().Lookup("terraform")); err != nil {
+				return fmt.Errorf("bind terraform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-sources", cmd.Flags().Lookup("follow-sources")); err != nil {
+				return fmt.Errorf("bind follow-sources flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("skip-dirs", cmd.Flags().Lookup("skip-dirs")); err != nil {
+				return fmt.Errorf("bind skip-dirs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-gitignore", cmd.Flags().Lookup("no-gitignore")); err != nil {
+				return fmt.Errorf("bind no-gitignore flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-symlinks", cmd.Flags().Lookup("follow-symlinks")); err != nil {
+				return fmt.Errorf("bind follow-symlinks flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kinds", cmd.Flags().Lookup("kinds")); err != nil {
+				return fmt.Errorf("bind kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-kinds", cmd.Flags().Lookup("exclude-kinds")); err != nil {
+				return fmt.Errorf("bind exclude-kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("selector", cmd.Flags().Lookup("selector")); err != nil {
+				return fmt.Errorf("bind selector flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-document-size", cmd.Flags().Lookup("max-document-size")); err != nil {
+				return fmt.Errorf("bind max-document-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("warn-on-parse-error", cmd.Flags().Lookup("warn-on-parse-error")); err != nil {
+				return fmt.Errorf("bind warn-on-parse-error flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("template-defaults", cmd.Flags().Lookup("template-defaults")); err != nil {
+				return fmt.Errorf("bind template-defaults flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("strict", cmd.Flags().Lookup("strict")); err != nil {
+				return fmt.Errorf("bind strict flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("group-by", cmd.Flags().Lookup("group-by")); err != nil {
+				return fmt.Errorf("bind group-by flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("group-label", cmd.Flags().Lookup("group-label")); err != nil {
+				return fmt.Errorf("bind group-label flag: %w", err)
+			}
+
 			var resourcePath string
 			if len(args) > 0 {
 				resourcePath = args[0]
@@ -35,7 +144,24 @@ func newCreateCommand() *cobra.Command {
 				manifestPath = viper.GetString("output")
 			}
 
-			if err := runCreateCommand(resourcePath, manifestPath); err != nil {
+			if viper.GetString("group-by") != "" {
+				if resourcePath == "" {
+					return errors.New("a source must be specified when using the group-by flag")
+				}
+
+				// The manifest path is a directory unless it is the path of a YAML file.
+				if manifest.Location(manifestPath) != filepath.Join(manifestPath, ".images.yaml") {
+					return errors.New("output must be a directory when using the group-by flag")
+				}
+
+				if err := runCreateGroupsCommand(cmd.Context(), resourcePath, manifestPath); err != nil {
+					return fmt.Errorf("create groups: %w", err)
+				}
+
+				return nil
+			}
+
+			if err := runCreateCommand(cmd.Context(), resourcePath, manifestPath); err != nil {
 				return fmt.Errorf("create: %w", err)
 			}
 
@@ -47,31 +173,284 @@ func newCreateCommand() *cobra.Command {
 	cmd.MarkFlagRequired("target")
 
 	cmd.Flags().StringP("output", "o", "", "Path where the manifest file will be written to")
+	cmd.Flags().Bool("resolve-digests", false, "Record the current digest of each image in the manifest")
+	cmd.Flags().Bool("helm", false, "Render Helm charts found at the source before finding images (requires helm)")
+	cmd.Flags().StringSlice("helm-values", []string{}, "Values files to use when rendering Helm charts")
+	cmd.Flags().Bool("kustomize", false, "Build kustomizations found at the source before finding images (requires kustomize)")
+	cmd.Flags().Bool("env-images", false, "Find images in the values of container environment variables")
+	cmd.Flags().String("env-images-pattern", "IMAGE", "Regular expression that environment variable names must match to be searched for images")
+	cmd.Flags().String("crd-config", "", "Path to a file that declares where images are located in custom resources")
+	cmd.Flags().Bool("deep-scan", false, "Also find image references in any value of any resource (e.g. ConfigMap data), which may include false positives")
+	cmd.Flags().Bool("dockerfiles", false, "Also find the base images in the FROM instructions of Dockerfiles")
+	cmd.Flags().Bool("ci-files", false, "Also find the images of the jobs in GitHub Actions workflows and GitLab CI files")
+	cmd.Flags().Bool("terraform", false, "Also find the images set in Terraform files by the Kubernetes and Helm providers")
+	cmd.Flags().Bool("follow-sources", false, "Also find the images deployed by Argo CD Applications and Flux HelmReleases and Kustomizations by fetching their sources (requires git and helm)")
+	cmd.Flags().StringSlice("skip-dirs", []string{}, "Directories to skip when finding images, by name or by path relative to the source (e.g. testdata,charts/*/tests)")
+	cmd.Flags().Bool("no-gitignore", false, "Also find images in files and directories that are ignored by .gitignore files")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symbolic links to directories when finding images")
+	cmd.Flags().StringSlice("kinds", []string{}, "Only find images in the resources of the given kinds (e.g. Deployment,CronJob)")
+	cmd.Flags().StringSlice("exclude-kinds", []string{}, "Skip the resources of the given kinds when finding images (e.g. ConfigMap)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Only find images in the resources in the given namespaces")
+	cmd.Flags().StringP("selector", "l", "", "Only find images in the resources whose labels match the selector (e.g. app.kubernetes.io/part-of=platform)")
+	cmd.Flags().String("max-document-size", "16MiB", "Maximum size of a YAML document (e.g. 64MiB), larger documents are skipped (0 does not limit the size)")
+	cmd.Flags().Bool("warn-on-parse-error", true, "Warn about YAML files that cannot be parsed, which are otherwise skipped")
+	cmd.Flags().Bool("template-defaults", false, "Find the images of unrendered Helm templates on a best-effort basis with the default values of their chart")
+	cmd.Flags().Bool("strict", false, "Fail when an image does not have a tag or digest instead of using the latest tag, or when a YAML file cannot be parsed")
+	cmd.Flags().String("group-by", "", "Split the images into a manifest for each namespace, directory or label of their resources, written to the output directory (namespace, directory or label)")
+	cmd.Flags().String("group-label", "", "Label of the resources to group the images by when grouping by label (e.g. team)")
 
 	return &cmd
 }
 
-func runCreateCommand(resourcePath string, manifestPath string) error {
-	if _, err := manifest.Get(manifestPath); err == nil {
+func runCreateCommand(ctx context.Context, resourcePath string, manifestPath string) error {
+	if _, err := manifest.GetTemplate(manifestPath); err == nil {
 		return errors.New("manifest file already exists")
 	}
 
 	targetPath := docker.RegistryPath(viper.GetString("target"))
 
-	var err error
+	opts, err := getAutodetectOptions(ctx)
+	if err != nil {
+		return fmt.Errorf("get autodetect options: %w", err)
+	}
+
 	var imageManifest manifest.Manifest
 	if resourcePath == "" {
-		imageManifest = manifest.New(targetPath.Host(), targetPath.Repository())
+		imageManifest = manifest.New(targetPath.Host(getRegistryHostsOption()), targetPath.Repository(getRegistryHostsOption()))
 	} else {
-		imageManifest, err = manifest.NewWithAutodetect(targetPath.Host(), targetPath.Repository(), resourcePath)
+		imageManifest, err = manifest.NewWithAutodetect(targetPath.Host(getRegistryHostsOption()), targetPath.Repository(getRegistryHostsOption()), resourcePath, opts...)
 		if err != nil {
 			return fmt.Errorf("new manifest with autodetect: %w", err)
 		}
 	}
 
+	if viper.GetBool("resolve-digests") {
+		if err := pinSourceDigests(ctx, imageManifest.Sources); err != nil {
+			return fmt.Errorf("pin source digests: %w", err)
+		}
+	}
+
 	if err := imageManifest.Write(manifestPath); err != nil {
 		return fmt.Errorf("write manifest: %w", err)
 	}
 
 	return nil
 }
+
+// runCreateGroupsCommand creates a manifest for each group of the images found at the source, named after the
+// group (e.g. team-a.images.yaml), so that each group can be synced and owned independently.
+func runCreateGroupsCommand(ctx context.Context, resourcePath string, manifestDir string) error {
+	targetPath := docker.RegistryPath(viper.GetString("target"))
+
+	getGroup, err := getGroupFunc(viper.GetString("group-by"), viper.GetString("group-label"), resourcePath)
+	if err != nil {
+		return fmt.Errorf("get group func: %w", err)
+	}
+
+	opts, err := getAutodetectOptions(ctx)
+	if err != nil {
+		return fmt.Errorf("get autodetect options: %w", err)
+	}
+
+	foundImages, err := images.FindImages(resourcePath, opts...)
+	if err != nil {
+		return fmt.Errorf("find images: %w", err)
+	}
+
+	groups := groupImages(foundImages, getGroup)
+
+	groupNames := sortedGroupNames(groups)
+	for _, group := range groupNames {
+		if _, err := os.Stat(getGroupManifestPath(manifestDir, group)); err == nil {
+			return fmt.Errorf("manifest file %s already exists", getGroupManifestPath(manifestDir, group))
+		}
+	}
+
+	if manifestDir != "" {
+		if err := os.MkdirAll(manifestDir, os.ModePerm); err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+	}
+
+	for _, group := range groupNames {
+		imageManifest := manifest.NewWithImages(targetPath.Host(getRegistryHostsOption()), targetPath.Repository(getRegistryHostsOption()), groups[group])
+		if viper.GetBool("resolve-digests") {
+			if err := pinSourceDigests(ctx, imageManifest.Sources); err != nil {
+				return fmt.Errorf("pin source digests of %s: %w", group, err)
+			}
+		}
+
+		if err := imageManifest.Write(getGroupManifestPath(manifestDir, group)); err != nil {
+			return fmt.Errorf("write manifest of %s: %w", group, err)
+		}
+
+		log.Infof("Created %s with %v image(s)", getGroupManifestPath(manifestDir, group), len(imageManifest.Sources))
+	}
+
+	return nil
+}
+
+// getGroupManifestPath returns the path of the manifest of the group in the directory.
+func getGroupManifestPath(manifestDir string, group string) string {
+	return filepath.Join(manifestDir, group+".images.yaml")
+}
+
+func getAutodetectOptions(ctx context.Context) ([]images.Option, error) {
+	opts := []images.Option{images.WithContext(ctx), getRegistryHostsOption()}
+	if viper.GetBool("helm") {
+		opts = append(opts, images.WithHelm(viper.GetStringSlice("helm-values")...))
+	}
+
+	if viper.GetBool("kustomize") {
+		opts = append(opts, images.WithKustomize())
+	}
+
+	if viper.GetBool("env-images") {
+		namePattern, err := regexp.Compile(viper.GetString("env-images-pattern"))
+		if err != nil {
+			return nil, fmt.Errorf("compile env images pattern: %w", err)
+		}
+
+		opts = append(opts, images.WithEnvImages(namePattern))
+	}
+
+	if viper.GetString("crd-config") != "" {
+		crdImagePaths, err := images.GetCRDImagePaths(viper.GetString("crd-config"))
+		if err != nil {
+			return nil, fmt.Errorf("get crd image paths: %w", err)
+		}
+
+		opts = append(opts, images.WithCRDImagePaths(crdImagePaths))
+	}
+
+	if viper.GetBool("strict") {
+		opts = append(opts, images.WithStrict())
+	}
+
+	if viper.GetBool("deep-scan") {
+		opts = append(opts, images.WithDeepScan())
+	}
+
+	if viper.GetBool("dockerfiles") {
+		opts = append(opts, images.WithDockerfiles())
+	}
+
+	if viper.GetBool("ci-files") {
+		opts = append(opts, images.WithCIFiles())
+	}
+
+	if viper.GetBool("terraform") {
+		opts = append(opts, images.WithTerraform())
+	}
+
+	if viper.GetBool("follow-sources") {
+		opts = append(opts, images.WithFollowSources())
+	}
+
+	if len(viper.GetStringSlice("skip-dirs")) > 0 {
+		opts = append(opts, images.WithSkipDirs(viper.GetStringSlice("skip-dirs")...))
+	}
+
+	if viper.GetBool("no-gitignore") {
+		opts = append(opts, images.WithoutGitignore())
+	}
+
+	if viper.GetBool("follow-symlinks") {
+		opts = append(opts, images.WithFollowSymlinks())
+	}
+
+	if viper.GetBool("template-defaults") {
+		opts = append(opts, images.WithTemplateDefaults())
+	}
+
+	if len(viper.GetStringSlice("kinds")) > 0 {
+		opts = append(opts, images.WithKinds(viper.GetStringSlice("kinds")...))
+	}
+
+	if len(viper.GetStringSlice("exclude-kinds")) > 0 {
+		opts = append(opts, images.WithExcludeKinds(viper.GetStringSlice("exclude-kinds")...))
+	}
+
+	if len(viper.GetStringSlice("namespaces")) > 0 {
+		opts = append(opts, images.WithNamespaces(viper.GetStringSlice("namespaces")...))
+	}
+
+	if viper.GetString("selector") != "" {
+		selector, err := labels.Parse(viper.GetString("selector"))
+		if err != nil {
+			return nil, fmt.Errorf("parse selector: %w", err)
+		}
+
+		opts = append(opts, images.WithLabelSelector(selector))
+	}
+
+	if viper.GetString("max-document-size") == "0" {
+		opts = append(opts, images.WithMaxDocumentSize(0))
+	} else if viper.GetString("max-document-size") != "" {
+		maxDocumentSize, err := docker.ParseSize(viper.GetString("max-document-size"))
+		if err != nil {
+			return nil, fmt.Errorf("parse max document size: %w", err)
+		}
+
+		opts = append(opts, images.WithMaxDocumentSize(int(maxDocumentSize)))
+	}
+
+	if viper.GetBool("warn-on-parse-error") {
+		opts = append(opts, images.WithParseErrorHandler(func(path string, err error) {
+			if errors.Is(err, images.ErrUnrenderedTemplate) {
+				log.Warnf("Found Go templates in %s, so its images may be missing or incomplete. Render its chart with --helm, or use --template-defaults to find its images with the default values of the chart: %v", path, err)
+				return
+			}
+
+			if errors.Is(err, images.ErrDocumentTooLarge) {
+				log.Warnf("Skipping a large document of %s and the rest of the file, which may contain images. Raise its limit with --max-document-size: %v", path, err)
+				return
+			}
+
+			log.Warnf("Unable to parse a document of %s, skipping the document: %v", path, err)
+		}))
+	}
+
+	// The Git repositories and Helm charts that are fetched are cached in the cache directory
+	// of the user (e.g. ~/.cache/sinker), so that repeated runs do not fetch them again.
+	if !viper.GetBool("no-cache") {
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			log.Debugf("Unable to find the cache directory, fetched sources are not cached: %v", err)
+		} else {
+			opts = append(opts, images.WithCacheDir(filepath.Join(cacheDir, "sinker")))
+		}
+	}
+
+	return opts, nil
+}
+
+func pinSourceDigests(ctx context.Context, sources []manifest.Source) error {
+	ctx, cancel := withCommandTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	for s := range sources {
+		if sources[s].Tag == "" {
+			continue
+		}
+
+		auth, err := getSourceAuth(sources[s])
+		if err != nil {
+			return fmt.Errorf("get source auth: %w", err)
+		}
+
+		digest, err := client.GetDigest(ctx, sources[s].Image(), auth)
+		if err != nil {
+			return fmt.Errorf("get digest: %w", err)
+		}
+
+		sources[s].Digest = digest
+	}
+
+	return nil
+}
@@ -0,0 +1,375 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultDaemonInterval = 6 * time.Hour
+	defaultDaemonJitter   = 15 * time.Minute
+
+	// daemonLivenessGrace is how long a run can start after it was scheduled
+	// before the daemon is no longer considered to be alive.
+	daemonLivenessGrace = time.Minute
+)
+
+// daemonUnsupportedFlags are the flags of push that do not apply to the runs of the daemon.
+var daemonUnsupportedFlags = []string{"watch", "interactive", "metrics-address", "dryrun"}
+
+func newDaemonCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "daemon [<source>]",
+		Short: "Periodically update the manifest from the source and push its images, to run as a service",
+		Args:  cobra.MaximumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The daemon has the flags of both update and push, which are bound all at once.
+			if err := viper.BindPFlags(cmd.Flags()); err != nil {
+				return fmt.Errorf("bind flags: %w", err)
+			}
+
+			for _, flag := range daemonUnsupportedFlags {
+				if cmd.Flags().Changed(flag) {
+					return fmt.Errorf("%s is not supported by the daemon", flag)
+				}
+			}
+
+			if viper.GetString("images-file") == "-" {
+				return errors.New("images-file cannot be read from stdin by the daemon, as it is read again on each run")
+			}
+
+			if viper.GetDuration("interval") <= 0 {
+				return errors.New("interval must be greater than zero")
+			}
+
+			if viper.GetDuration("jitter") < 0 {
+				return errors.New("jitter cannot be negative")
+			}
+
+			var sourcePath string
+			if len(args) > 0 {
+				sourcePath = args[0]
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runDaemonCommand(cmd.Context(), sourcePath, manifestPath); err != nil {
+				return fmt.Errorf("daemon: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("interval", defaultDaemonInterval, "How often to update the manifest and push its images")
+	cmd.Flags().Duration("jitter", defaultDaemonJitter, "Maximum random delay that is added to the interval, so that daemons that start at the same time do not run at the same time")
+	cmd.Flags().String("lock-file", "", "Path to a lock file that prevents runs from overlapping with the runs of other daemons (defaults to the path of the manifest with a .lock extension)")
+	cmd.Flags().String("address", ":8080", "Address to serve the health (/healthz) and liveness (/livez) endpoints and Prometheus metrics (/metrics) on")
+
+	cmd.Flags().AddFlagSet(newUpdateCommand().Flags())
+	cmd.Flags().AddFlagSet(newPushCommand().Flags())
+	for _, flag := range daemonUnsupportedFlags {
+		cmd.Flags().MarkHidden(flag)
+	}
+
+	return &cmd
+}
+
+func runDaemonCommand(ctx context.Context, sourcePath string, manifestPath string) error {
+	interval := viper.GetDuration("interval")
+	jitter := viper.GetDuration("jitter")
+
+	lockPath := viper.GetString("lock-file")
+	if lockPath == "" {
+		lockPath = manifest.Location(manifestPath) + ".lock"
+	}
+
+	status := &daemonStatus{}
+	if viper.GetString("address") != "" {
+		serveDaemon(ctx, viper.GetString("address"), status)
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for {
+		status.startRun()
+
+		skipped, err := runDaemonOnce(ctx, sourcePath, manifestPath, lockPath, interval)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err != nil {
+			log.Errorf("Run failed: %v", err)
+		}
+
+		now := time.Now()
+		runJitter := time.Duration(random.Int63n(int64(jitter) + 1))
+		throttledUntil, throttledHost := docker.ThrottledUntil()
+		delay := getDaemonDelay(interval, runJitter, now, throttledUntil)
+		if delay > interval+runJitter {
+			log.Warnf("%s asked to not be sent requests until %v, so the next run is delayed until then", throttledHost, throttledUntil.Format(time.RFC3339))
+		}
+
+		status.finishRun(now, err, skipped, now.Add(delay))
+		log.Infof("Next run at %v", now.Add(delay).Format(time.RFC3339))
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// runDaemonOnce updates the manifest from the source, when there is one, and pushes the images of the manifest.
+// The run is skipped when the lock file is held by another run, which returns true.
+func runDaemonOnce(ctx context.Context, sourcePath string, manifestPath string, lockPath string, interval time.Duration) (bool, error) {
+	release, acquired, err := acquireLock(lockPath, interval)
+	if err != nil {
+		return false, fmt.Errorf("acquire lock: %w", err)
+	}
+
+	if !acquired {
+		log.Warnf("Skipping the run, as another run holds the lock %s", lockPath)
+		return true, nil
+	}
+	defer release()
+
+	pushPath := manifestPath
+	if sourcePath != "" {
+		if viper.GetString("output") != "" {
+			pushPath = viper.GetString("output")
+		}
+
+		log.Infof("Updating the manifest from %s ...", sourcePath)
+		if err := runUpdateCommand(ctx, sourcePath, manifestPath, pushPath); err != nil {
+			return false, fmt.Errorf("update: %w", err)
+		}
+	}
+
+	if err := runPushCommand(ctx, pushPath); err != nil {
+		return false, fmt.Errorf("push: %w", err)
+	}
+
+	return false, nil
+}
+
+// getDaemonDelay returns how long to wait before the next run, which is the interval with the random jitter added
+// to it. When a registry asked to not be sent requests until after that (e.g. because its pull quota is exhausted),
+// the next run waits until then, as the images of the registry would otherwise fail to be pushed again.
+func getDaemonDelay(interval time.Duration, jitter time.Duration, now time.Time, throttledUntil time.Time) time.Duration {
+	delay := interval + jitter
+	if wait := throttledUntil.Sub(now); wait > delay {
+		delay = wait
+	}
+
+	return delay
+}
+
+// acquireLock creates the lock file, and returns the function that removes it again along with true when it was
+// created. When the lock file already exists, another run holds the lock and false is returned, unless the lock
+// file is older than the given age, which means that it was left behind by a run that was killed. Runs are
+// cancelled after their timeout, which is usually much shorter than the interval of the daemon.
+func acquireLock(path string, staleAfter time.Duration) (func(), bool, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if os.IsExist(err) {
+		fileInfo, statErr := os.Stat(path)
+		if os.IsNotExist(statErr) {
+			return acquireLock(path, staleAfter)
+		}
+		if statErr != nil {
+			return nil, false, fmt.Errorf("stat lock: %w", statErr)
+		}
+
+		if time.Since(fileInfo.ModTime()) < staleAfter {
+			return nil, false, nil
+		}
+
+		log.Warnf("Removing the lock %s, which was left behind by a run at %v", path, fileInfo.ModTime().Format(time.RFC3339))
+		if removeErr := os.Remove(path); removeErr != nil && !os.IsNotExist(removeErr) {
+			return nil, false, fmt.Errorf("remove stale lock: %w", removeErr)
+		}
+
+		file, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if os.IsExist(err) {
+			return nil, false, nil
+		}
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("create lock: %w", err)
+	}
+
+	_, writeErr := fmt.Fprintf(file, "%v\n", os.Getpid())
+	if err := file.Close(); err != nil && writeErr == nil {
+		writeErr = err
+	}
+	if writeErr != nil {
+		os.Remove(path)
+		return nil, false, fmt.Errorf("write lock: %w", writeErr)
+	}
+
+	release := func() {
+		if err := os.Remove(path); err != nil {
+			log.Warnf("Unable to remove the lock %s: %v", path, err)
+		}
+	}
+
+	return release, true, nil
+}
+
+// daemonStatus is the status of the runs of the daemon, which is reported by its health and liveness endpoints.
+type daemonStatus struct {
+	mutex sync.Mutex
+
+	running     bool
+	lastRun     time.Time
+	lastSuccess time.Time
+	lastError   string
+	nextRun     time.Time
+}
+
+// daemonHealth is the status of the daemon that is written by its health and liveness endpoints.
+type daemonHealth struct {
+	Healthy     bool   `json:"healthy"`
+	Running     bool   `json:"running"`
+	LastRun     string `json:"lastRun,omitempty"`
+	LastSuccess string `json:"lastSuccess,omitempty"`
+	LastError   string `json:"lastError,omitempty"`
+	NextRun     string `json:"nextRun,omitempty"`
+}
+
+func (s *daemonStatus) startRun() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.running = true
+}
+
+// finishRun records the result of a run. A run that was skipped because another run held the
+// lock does not change the result of the last run, as the other run does the work instead.
+func (s *daemonStatus) finishRun(now time.Time, err error, skipped bool, nextRun time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.running = false
+	s.nextRun = nextRun
+	if skipped {
+		return
+	}
+
+	s.lastRun = now
+	s.lastError = ""
+	if err != nil {
+		s.lastError = err.Error()
+	} else {
+		s.lastSuccess = now
+	}
+}
+
+// health returns the status of the daemon, which is healthy unless its last run failed.
+func (s *daemonStatus) health() daemonHealth {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return daemonHealth{
+		Healthy:     s.lastError == "",
+		Running:     s.running,
+		LastRun:     formatDaemonTime(s.lastRun),
+		LastSuccess: formatDaemonTime(s.lastSuccess),
+		LastError:   s.lastError,
+		NextRun:     formatDaemonTime(s.nextRun),
+	}
+}
+
+// isAlive returns false when the next run did not start at the time it was scheduled for, which means
+// that the daemon is stuck. Runs cannot get stuck themselves, as they are cancelled after their timeout.
+func (s *daemonStatus) isAlive(now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.running || s.nextRun.IsZero() || now.Before(s.nextRun.Add(daemonLivenessGrace))
+}
+
+func (s *daemonStatus) handleHealth(w http.ResponseWriter, r *http.Request) {
+	health := s.health()
+
+	statusCode := http.StatusOK
+	if !health.Healthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	writeDaemonHealth(w, statusCode, health)
+}
+
+func (s *daemonStatus) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	statusCode := http.StatusOK
+	if !s.isAlive(time.Now()) {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	writeDaemonHealth(w, statusCode, s.health())
+}
+
+func writeDaemonHealth(w http.ResponseWriter, statusCode int, health daemonHealth) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		log.Debugf("Unable to write health: %v", err)
+	}
+}
+
+// serveDaemon serves the health and liveness endpoints of the daemon along with its metrics
+// in the background, until the context is cancelled.
+func serveDaemon(ctx context.Context, address string, status *daemonStatus) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", status.handleHealth)
+	mux.HandleFunc("/livez", status.handleLiveness)
+
+	server := http.Server{
+		Addr:    address,
+		Handler: mux,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("Unable to shut down: %v", err)
+		}
+	}()
+
+	go func() {
+		log.Infof("Serving health, liveness and metrics on %s", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Unable to serve health: %v", err)
+		}
+	}()
+}
+
+func formatDaemonTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	lockPath := filepath.Join(tempDir, ".images.yaml.lock")
+
+	release, acquired, err := acquireLock(lockPath, time.Hour)
+	if err != nil {
+		t.Fatal("acquire lock:", err)
+	}
+
+	if !acquired {
+		t.Fatal("expected lock to be acquired")
+	}
+
+	if _, acquired, err := acquireLock(lockPath, time.Hour); err != nil || acquired {
+		t.Errorf("expected lock to be held by the first run, acquired %v (%v)", acquired, err)
+	}
+
+	release()
+
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("expected lock to be removed when released, actual %v", err)
+	}
+
+	// A lock that is older than its stale age was left behind by a run that was killed.
+	staleTime := time.Now().Add(-2 * time.Hour)
+	if err := ioutil.WriteFile(lockPath, []byte("1\n"), 0644); err != nil {
+		t.Fatal("write lock:", err)
+	}
+
+	if err := os.Chtimes(lockPath, staleTime, staleTime); err != nil {
+		t.Fatal("change lock times:", err)
+	}
+
+	release, acquired, err = acquireLock(lockPath, time.Hour)
+	if err != nil {
+		t.Fatal("acquire stale lock:", err)
+	}
+
+	if !acquired {
+		t.Fatal("expected stale lock to be acquired")
+	}
+
+	release()
+}
+
+func TestGetDaemonDelay(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		throttledUntil time.Time
+		expected       time.Duration
+	}{
+		{throttledUntil: time.Time{}, expected: 6*time.Hour + 5*time.Minute},
+		{throttledUntil: now.Add(time.Hour), expected: 6*time.Hour + 5*time.Minute},
+		{throttledUntil: now.Add(8 * time.Hour), expected: 8 * time.Hour},
+	}
+
+	for _, testCase := range testCases {
+		actual := getDaemonDelay(6*time.Hour, 5*time.Minute, now, testCase.throttledUntil)
+		if actual != testCase.expected {
+			t.Errorf("expected delay %v when throttled until %v, actual %v", testCase.expected, testCase.throttledUntil, actual)
+		}
+	}
+}
+
+func TestDaemonStatus(t *testing.T) {
+	status := &daemonStatus{}
+
+	assertStatusCode := func(handler http.HandlerFunc, expected int) {
+		t.Helper()
+
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		if recorder.Code != expected {
+			t.Errorf("expected status %v, actual %v: %s", expected, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	assertStatusCode(status.handleHealth, http.StatusOK)
+	assertStatusCode(status.handleLiveness, http.StatusOK)
+
+	now := time.Now()
+	status.startRun()
+	status.finishRun(now, errors.New("push: unauthorized"), false, now.Add(time.Hour))
+	assertStatusCode(status.handleHealth, http.StatusServiceUnavailable)
+	assertStatusCode(status.handleLiveness, http.StatusOK)
+
+	// A skipped run does not change the result of the last run.
+	status.startRun()
+	status.finishRun(now, nil, true, now.Add(time.Hour))
+	assertStatusCode(status.handleHealth, http.StatusServiceUnavailable)
+
+	status.startRun()
+	status.finishRun(now, nil, false, now.Add(-time.Hour))
+	assertStatusCode(status.handleHealth, http.StatusOK)
+	assertStatusCode(status.handleLiveness, http.StatusServiceUnavailable)
+
+	if status.health().LastSuccess == "" {
+		t.Error("expected the time of the last success to be reported")
+	}
+}
@@ -1,8 +1,12 @@
 package commands
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"strings"
+
+	"github.com/plexsystems/sinker/pkg/images"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -14,21 +18,133 @@ func NewDefaultCommand() *cobra.Command {
 		Use:     path.Base(os.Args[0]),
 		Short:   "sinker",
 		Long:    "A tool to sync container images to another container registry",
-		Version: "0.10.1",
+		Version: buildVersion,
 	}
 
 	cmd.PersistentFlags().StringP("manifest", "m", "", "Path where the manifest file is (defaults to .images.yaml in the current directory)")
 	viper.BindPFlag("manifest", cmd.PersistentFlags().Lookup("manifest"))
 
+	cmd.PersistentFlags().StringSlice("manifest-values", []string{}, "Values files with the variables that are referenced in the manifest (e.g. ${MIRROR_REGISTRY})")
+	viper.BindPFlag("manifest-values", cmd.PersistentFlags().Lookup("manifest-values"))
+
+	cmd.PersistentFlags().String("source-username", "", "Username to authenticate to the source registry with")
+	viper.BindPFlag("source-username", cmd.PersistentFlags().Lookup("source-username"))
+
+	cmd.PersistentFlags().String("source-password", "", "Password to authenticate to the source registry with")
+	viper.BindPFlag("source-password", cmd.PersistentFlags().Lookup("source-password"))
+
+	cmd.PersistentFlags().String("target-username", "", "Username to authenticate to the target registry with")
+	viper.BindPFlag("target-username", cmd.PersistentFlags().Lookup("target-username"))
+
+	cmd.PersistentFlags().String("target-password", "", "Password to authenticate to the target registry with")
+	viper.BindPFlag("target-password", cmd.PersistentFlags().Lookup("target-password"))
+
+	cmd.PersistentFlags().StringToString("source-mirror", map[string]string{}, "Registry mirrors to pull the images of source registries through (e.g. docker.io=mirror.internal/docker.io)")
+	viper.BindPFlag("source-mirror", cmd.PersistentFlags().Lookup("source-mirror"))
+
+	cmd.PersistentFlags().String("ca-cert", "", "Path to a CA certificate to trust when connecting to registries (e.g. a private CA)")
+	viper.BindPFlag("ca-cert", cmd.PersistentFlags().Lookup("ca-cert"))
+
+	cmd.PersistentFlags().Bool("insecure-skip-tls-verify", false, "Skip verifying the certificates of registries")
+	viper.BindPFlag("insecure-skip-tls-verify", cmd.PersistentFlags().Lookup("insecure-skip-tls-verify"))
+
+	cmd.PersistentFlags().Duration("timeout", 0, "Maximum time that the command runs for before it is cancelled (defaults to the timeout of the command, e.g. 30m for push)")
+	viper.BindPFlag("timeout", cmd.PersistentFlags().Lookup("timeout"))
+
+	cmd.PersistentFlags().Duration("registry-timeout", 0, "How long to wait for a registry to respond to each request before the request fails (defaults to no timeout)")
+	viper.BindPFlag("registry-timeout", cmd.PersistentFlags().Lookup("registry-timeout"))
+
+	cmd.PersistentFlags().StringSlice("insecure", []string{}, "Registries to connect to over plain HTTP (e.g. localhost:5000)")
+	viper.BindPFlag("insecure", cmd.PersistentFlags().Lookup("insecure"))
+
+	cmd.PersistentFlags().StringSlice("registry-hosts", []string{}, "Hosts of registries that do not look like a host, such as hosts on an internal network without a domain (e.g. registry)")
+	viper.BindPFlag("registry-hosts", cmd.PersistentFlags().Lookup("registry-hosts"))
+
+	cmd.PersistentFlags().Bool("no-cache", false, "Fetch remote Git repositories and Helm charts again instead of using the copies cached in ~/.cache/sinker")
+	viper.BindPFlag("no-cache", cmd.PersistentFlags().Lookup("no-cache"))
+
+	cmd.PersistentFlags().StringSlice("registry-secrets", []string{}, "Kubernetes Secrets of type kubernetes.io/dockerconfigjson to read the credentials of registries from, as namespace/name (e.g. mirroring/quay-credentials)")
+	viper.BindPFlag("registry-secrets", cmd.PersistentFlags().Lookup("registry-secrets"))
+
+	cmd.PersistentFlags().String("kubeconfig", "", "Path to the kubeconfig file of the cluster used by the cluster and registry-secrets flags (defaults to the kubeconfig of kubectl)")
+	viper.BindPFlag("kubeconfig", cmd.PersistentFlags().Lookup("kubeconfig"))
+
+	cmd.PersistentFlags().String("context", "", "Kubeconfig context of the cluster used by the cluster and registry-secrets flags (defaults to the current context)")
+	viper.BindPFlag("context", cmd.PersistentFlags().Lookup("context"))
+
+	cmd.PersistentFlags().String("config", "", "Path to a config file with defaults for the flags (defaults to sinker.yaml, .sinker.yaml, sinker.toml or .sinker.toml in the current directory)")
+	viper.BindPFlag("config", cmd.PersistentFlags().Lookup("config"))
+
+	cmd.PersistentFlags().String("log-level", "info", "Minimum level of the log messages (debug, info, warn or error)")
+	viper.BindPFlag("log-level", cmd.PersistentFlags().Lookup("log-level"))
+
+	cmd.PersistentFlags().String("log-format", "text", "Format of the log messages (text or json)")
+	viper.BindPFlag("log-format", cmd.PersistentFlags().Lookup("log-format"))
+
+	cmd.PersistentFlags().BoolP("quiet", "q", false, "Only print the references of the images that the command reports on, without any log messages")
+	viper.BindPFlag("quiet", cmd.PersistentFlags().Lookup("quiet"))
+
+	cmd.PersistentFlags().Bool("no-color", false, "Disable colored output, which is otherwise enabled when writing to a terminal")
+	viper.BindPFlag("no-color", cmd.PersistentFlags().Lookup("no-color"))
+
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := loadConfig(viper.GetString("config"), "."); err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+
+		if err := configureLogging(viper.GetString("log-level"), viper.GetString("log-format")); err != nil {
+			return fmt.Errorf("configure logging: %w", err)
+		}
+
+		configureOutput(viper.GetBool("quiet"), viper.GetBool("no-color"))
+
+		return nil
+	}
+
 	viper.SetEnvPrefix("SINKER")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv()
 
 	cmd.AddCommand(newCreateCommand())
 	cmd.AddCommand(newUpdateCommand())
+	cmd.AddCommand(newUpdateManifestsCommand())
+	cmd.AddCommand(newFindCommand())
+	cmd.AddCommand(newReportCommand())
+	cmd.AddCommand(newWhereCommand())
+	cmd.AddCommand(newLintCommand())
+	cmd.AddCommand(newDiffCommand())
+	cmd.AddCommand(newDriftCommand())
+	cmd.AddCommand(newMergeCommand())
 	cmd.AddCommand(newListCommand())
+	cmd.AddCommand(newInspectCommand())
+	cmd.AddCommand(newExportCommand())
+	cmd.AddCommand(newConvertCommand())
+	cmd.AddCommand(newGenerateCommand())
 	cmd.AddCommand(newPullCommand())
+	cmd.AddCommand(newValidateCommand())
 	cmd.AddCommand(newPushCommand())
+	cmd.AddCommand(newSyncCommand())
+	cmd.AddCommand(newPlanCommand())
+	cmd.AddCommand(newDoctorCommand())
 	cmd.AddCommand(newCheckCommand())
+	cmd.AddCommand(newSignCommand())
+	cmd.AddCommand(newOutdatedCommand())
+	cmd.AddCommand(newPruneCommand())
+	cmd.AddCommand(newHistoryCommand())
+	cmd.AddCommand(newSnapshotCommand())
+	cmd.AddCommand(newSaveCommand())
+	cmd.AddCommand(newLoadCommand())
+	cmd.AddCommand(newControllerCommand())
+	cmd.AddCommand(newWebhookCommand())
+	cmd.AddCommand(newDaemonCommand())
+	cmd.AddCommand(newVersionCommand())
+	cmd.AddCommand(newCompletionCommand())
 
 	return &cmd
 }
+
+// getRegistryHostsOption returns the option that finds the hosts of images with the hosts of the registry-hosts flag,
+// which do not look like a host (e.g. registry/team/app, where registry is a host on an internal network).
+func getRegistryHostsOption() images.Option {
+	return images.WithRegistryHosts(viper.GetStringSlice("registry-hosts")...)
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"github.com/spf13/viper"
+)
+
+// getRegistryOnlyFlag returns the first of the flags that is set which only applies when the images are pushed
+// to a registry, or an empty string when none of them are set. The images written to a destination are
+// saved as they are in the source registry, and the destination has no API to create repositories
+// or to add labels with, so these flags would otherwise be silently ignored.
+func getRegistryOnlyFlag() string {
+	flags := []struct {
+		name  string
+		isSet bool
+	}{
+		{name: "platforms", isSet: len(viper.GetStringSlice("platforms")) > 0},
+		{name: "annotate", isSet: viper.GetBool("annotate")},
+		{name: "expires-after", isSet: viper.GetString("expires-after") != ""},
+		{name: "harbor-labels", isSet: len(viper.GetStringSlice("harbor-labels")) > 0},
+		{name: "copy-signatures", isSet: viper.GetBool("copy-signatures")},
+		{name: "copy-sboms", isSet: viper.GetBool("copy-sboms")},
+		{name: "verify-digests", isSet: isVerifyingDigests()},
+		{name: "create-repos", isSet: viper.GetBool("create-repos")},
+		{name: "create-harbor-projects", isSet: viper.GetBool("create-harbor-projects")},
+	}
+
+	for _, flag := range flags {
+		if flag.isSet {
+			return flag.name
+		}
+	}
+
+	return ""
+}
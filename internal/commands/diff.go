@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newDiffCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "diff <path> [path]",
+		Short: "Compare the images found at a path against another path or the target registry",
+		Args:  cobra.RangeArgs(1, 2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			var err error
+			if len(args) == 2 {
+				err = runDiffCommand(cmd.Context(), args[0], args[1])
+			} else {
+				err = runDiffTargetCommand(cmd.Context(), args[0])
+			}
+			if err != nil {
+				return fmt.Errorf("diff: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("target", "t", "", "Registry to compare the images against when a path to Kubernetes manifests is given")
+
+	return &cmd
+}
+
+func runDiffCommand(ctx context.Context, fromPath string, toPath string) error {
+	fromSources, err := getDiffSources(ctx, fromPath, manifest.Target{})
+	if err != nil {
+		return fmt.Errorf("get sources from %s: %w", fromPath, err)
+	}
+
+	toSources, err := getDiffSources(ctx, toPath, manifest.Target{})
+	if err != nil {
+		return fmt.Errorf("get sources from %s: %w", toPath, err)
+	}
+
+	if err := writeDiff(os.Stdout, fromSources, toSources); err != nil {
+		return fmt.Errorf("write diff: %w", err)
+	}
+
+	return nil
+}
+
+// runDiffTargetCommand compares the images found at the path against the target registry.
+// Images that are missing from the target are reported as added.
+func runDiffTargetCommand(ctx context.Context, path string) error {
+	ctx, cancel := withCommandTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	targetPath := docker.RegistryPath(viper.GetString("target"))
+	target := manifest.Target{
+		Host:       targetPath.Host(getRegistryHostsOption()),
+		Repository: targetPath.Repository(getRegistryHostsOption()),
+	}
+
+	sources, err := getDiffSources(ctx, path, target)
+	if err != nil {
+		return fmt.Errorf("get sources: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	var existingSources []manifest.Source
+	for _, source := range sources {
+		if source.Target.Host == "" {
+			return errors.New("target must be specified when comparing Kubernetes manifests against the target")
+		}
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		exists, err := client.ManifestExistsAtRemote(ctx, source.TargetImage(), targetAuth)
+		if err != nil {
+			return fmt.Errorf("manifest exists at remote: %w", err)
+		}
+
+		if exists {
+			existingSources = append(existingSources, source)
+		}
+	}
+
+	if err := writeDiff(os.Stdout, existingSources, sources); err != nil {
+		return fmt.Errorf("write diff: %w", err)
+	}
+
+	return nil
+}
+
+// getDiffSources returns the sources in the image manifest at the path, or when the
+// path is not an image manifest, the sources found in the Kubernetes manifests at the path.
+func getDiffSources(ctx context.Context, path string, target manifest.Target) ([]manifest.Source, error) {
+	if isManifestFile(path) {
+		imageManifest, err := getManifest(path)
+		if err != nil {
+			return nil, fmt.Errorf("get manifest: %w", err)
+		}
+
+		return imageManifest.Sources, nil
+	}
+
+	opts, err := getAutodetectOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get autodetect options: %w", err)
+	}
+
+	sources, err := manifest.GetImagesFromKubernetesManifests(path, target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("get images from kubernetes manifests: %w", err)
+	}
+
+	return sources, nil
+}
+
+func isManifestFile(path string) bool {
+	fileInfo, err := os.Stat(path)
+	if err != nil || fileInfo.IsDir() {
+		return false
+	}
+
+	imageManifest, err := getManifest(path)
+	if err != nil {
+		return false
+	}
+
+	return imageManifest.Target.Host != ""
+}
+
+// writeDiff writes the repositories that were added or removed, and the
+// repositories whose tags changed, between the two sets of sources.
+func writeDiff(w io.Writer, fromSources []manifest.Source, toSources []manifest.Source) error {
+	from := getRepositoryVersions(fromSources)
+	to := getRepositoryVersions(toSources)
+
+	repositories := make(map[string]bool)
+	for repository := range from {
+		repositories[repository] = true
+	}
+	for repository := range to {
+		repositories[repository] = true
+	}
+
+	var sortedRepositories []string
+	for repository := range repositories {
+		sortedRepositories = append(sortedRepositories, repository)
+	}
+	sort.Strings(sortedRepositories)
+
+	for _, repository := range sortedRepositories {
+		fromVersions := from[repository]
+		toVersions := to[repository]
+
+		var line string
+		switch {
+		case len(fromVersions) == 0:
+			line = fmt.Sprintf("+ %s:%s", repository, strings.Join(toVersions, ", "))
+		case len(toVersions) == 0:
+			line = fmt.Sprintf("- %s:%s", repository, strings.Join(fromVersions, ", "))
+		case strings.Join(fromVersions, ",") != strings.Join(toVersions, ","):
+			line = fmt.Sprintf("~ %s: %s -> %s", repository, strings.Join(fromVersions, ", "), strings.Join(toVersions, ", "))
+		default:
+			continue
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("write line: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getRepositoryVersions returns the sorted tags (or digests) of each source repository.
+func getRepositoryVersions(sources []manifest.Source) map[string][]string {
+	versions := make(map[string][]string)
+	for _, source := range sources {
+		repository := source.Repository
+		if source.Host != "" {
+			repository = source.Host + "/" + repository
+		}
+
+		version := source.Tag
+		if version == "" {
+			version = source.Digest
+		}
+
+		versions[repository] = append(versions[repository], version)
+	}
+
+	for repository := range versions {
+		sort.Strings(versions[repository])
+	}
+
+	return versions
+}
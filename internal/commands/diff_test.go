@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+)
+
+func TestWriteDiff(t *testing.T) {
+	from := []manifest.Source{
+		{Repository: "busybox", Tag: "1.31.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Repository: "jimmidyson/configmap-reload", Tag: "v0.3.0"},
+	}
+
+	to := []manifest.Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-config-reloader", Tag: "v0.40.0"},
+	}
+
+	var actual bytes.Buffer
+	if err := writeDiff(&actual, from, to); err != nil {
+		t.Fatal("write diff:", err)
+	}
+
+	expected := `~ busybox: 1.31.0 -> 1.32.0
+- jimmidyson/configmap-reload:v0.3.0
++ quay.io/coreos/prometheus-config-reloader:v0.40.0
+`
+
+	if actual.String() != expected {
+		t.Errorf("expected diff\n%s\nactual\n%s", expected, actual.String())
+	}
+}
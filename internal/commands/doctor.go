@@ -0,0 +1,359 @@
+package commands
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newDoctorCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "doctor",
+		Short: "Check the connection and credentials to each registry in the manifest, and print the registry settings of the environment",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			manifestPath := viper.GetString("manifest")
+			if err := runDoctorCommand(cmd.Context(), os.Stdout, manifestPath); err != nil {
+				return fmt.Errorf("doctor: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	return &cmd
+}
+
+// registryToCheck is a registry that the images in the manifest are pulled from or pushed to, along with
+// a repository of the registry that the credentials are checked against.
+type registryToCheck struct {
+	role       string
+	repository string
+	auth       string
+	push       bool
+}
+
+func runDoctorCommand(ctx context.Context, w io.Writer, manifestPath string) error {
+	ctx, cancel := withCommandTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if err := writeEnvironment(w); err != nil {
+		return fmt.Errorf("write environment: %w", err)
+	}
+
+	location := manifest.Location(manifestPath)
+	if _, err := os.Stat(location); os.IsNotExist(err) {
+		fmt.Fprintf(w, "\nNo manifest found at %s, so no registries were checked.\n", location)
+		return nil
+	}
+
+	registries, err := getRegistriesToCheck(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get registries to check: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+	client = client.WithRetries(0)
+
+	var checks []docker.RegistryCheck
+	for _, registry := range registries {
+		checks = append(checks, client.CheckRegistry(ctx, registry.repository, registry.auth, registry.push))
+	}
+
+	if err := writeRegistryChecks(w, registries, checks); err != nil {
+		return fmt.Errorf("write registry checks: %w", err)
+	}
+
+	var failed int
+	for _, check := range checks {
+		if check.Err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v registry check(s) failed", failed, len(checks))
+	}
+
+	return nil
+}
+
+// getRegistriesToCheck returns the registries that the sources in the manifest are pulled from, including
+// their mirrors and fallbacks, and the registries that they are pushed to. Each registry is checked once for
+// pulling and once for pushing, against the first repository of the registry in the manifest.
+func getRegistriesToCheck(manifestPath string) ([]registryToCheck, error) {
+	imageManifest, err := getManifest(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("get manifest: %w", err)
+	}
+
+	sources, err := manifest.WithSourceMirrors(imageManifest.Sources, viper.GetStringMapString("source-mirror"))
+	if err != nil {
+		return nil, fmt.Errorf("with source mirrors: %w", err)
+	}
+
+	seen := make(map[string]bool)
+
+	var registries []registryToCheck
+	add := func(role string, image string, auth string, push bool) error {
+		reference, err := name.ParseReference(image, name.WeakValidation)
+		if err != nil {
+			return fmt.Errorf("parse reference: %w", err)
+		}
+
+		key := fmt.Sprintf("%s %v", reference.Context().RegistryStr(), push)
+		if seen[key] {
+			return nil
+		}
+
+		seen[key] = true
+		registries = append(registries, registryToCheck{
+			role:       role,
+			repository: reference.Context().Name(),
+			auth:       auth,
+			push:       push,
+		})
+
+		return nil
+	}
+
+	for _, source := range sources {
+		pulledSources := append([]manifest.Source{source}, source.FallbackSources()...)
+		for _, pulledSource := range pulledSources {
+			sourceAuth, err := getSourceAuth(pulledSource)
+			if err != nil {
+				return nil, fmt.Errorf("get source auth: %w", err)
+			}
+
+			if err := add("source", pulledSource.PullImage(), sourceAuth, false); err != nil {
+				return nil, fmt.Errorf("source %s: %w", pulledSource.PullImage(), err)
+			}
+		}
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return nil, fmt.Errorf("get target auth: %w", err)
+		}
+
+		if err := add("target", source.TargetImage(), targetAuth, true); err != nil {
+			return nil, fmt.Errorf("target %s: %w", source.TargetImage(), err)
+		}
+	}
+
+	return registries, nil
+}
+
+func writeRegistryChecks(w io.Writer, registries []registryToCheck, checks []docker.RegistryCheck) error {
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, "REGISTRY\tROLE\tREPOSITORY\tCREDENTIALS\tSTATUS\tRATE LIMIT"); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for i, check := range checks {
+		credentials := "configured"
+		if check.Anonymous {
+			credentials = "anonymous"
+		}
+
+		status := "ok"
+		switch {
+		case !check.Reachable:
+			status = fmt.Sprintf("unreachable: %v", check.Err)
+		case !check.Authorized:
+			status = fmt.Sprintf("unauthorized: %v", check.Err)
+		}
+
+		rateLimit := check.RateLimit
+		if rateLimit == "" {
+			rateLimit = "-"
+		}
+
+		if _, err := fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", check.Registry, registries[i].role, check.Repository, credentials, status, rateLimit); err != nil {
+			return fmt.Errorf("write check: %w", err)
+		}
+	}
+
+	return table.Flush()
+}
+
+// writeEnvironment writes the settings of the environment that affect how registries are accessed, which
+// are the Docker config and its credential helpers, the proxy settings and the certificates that are trusted.
+func writeEnvironment(w io.Writer) error {
+	configPath := getDockerConfigPath()
+	fmt.Fprintf(w, "Docker config: %s\n", configPath)
+
+	config, err := readDockerConfig(configPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		fmt.Fprintln(w, "  not found, so only anonymous access and the credentials in the manifest, flags and cloud providers are used")
+	case err != nil:
+		fmt.Fprintf(w, "  unable to read: %v\n", err)
+	default:
+		var hosts []string
+		for host := range config.Auths {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+
+		fmt.Fprintf(w, "  credentials: %s\n", formatList(hosts))
+
+		if config.CredsStore != "" {
+			fmt.Fprintf(w, "  credential store: %s (%s)\n", config.CredsStore, findCredentialHelper(config.CredsStore))
+		}
+
+		var helperHosts []string
+		for host := range config.CredHelpers {
+			helperHosts = append(helperHosts, host)
+		}
+		sort.Strings(helperHosts)
+
+		for _, host := range helperHosts {
+			helper := config.CredHelpers[host]
+			fmt.Fprintf(w, "  credential helper for %s: %s (%s)\n", host, helper, findCredentialHelper(helper))
+		}
+	}
+
+	fmt.Fprintln(w, "Proxy:")
+	for _, variable := range []string{"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY"} {
+		value := os.Getenv(variable)
+		if value == "" {
+			value = os.Getenv(strings.ToLower(variable))
+		}
+
+		if value == "" {
+			value = "not set"
+		}
+
+		fmt.Fprintf(w, "  %s: %s\n", variable, redactProxy(value))
+	}
+
+	fmt.Fprintln(w, "Certificates:")
+	if caCert := viper.GetString("ca-cert"); caCert != "" {
+		fmt.Fprintf(w, "  --ca-cert: %s (%s)\n", caCert, checkCACert(caCert))
+	}
+
+	for _, variable := range []string{"SSL_CERT_FILE", "SSL_CERT_DIR"} {
+		if value := os.Getenv(variable); value != "" {
+			fmt.Fprintf(w, "  %s: %s\n", variable, value)
+		}
+	}
+
+	if viper.GetBool("insecure-skip-tls-verify") {
+		fmt.Fprintln(w, "  the certificates of registries are not verified (--insecure-skip-tls-verify)")
+	} else {
+		fmt.Fprintln(w, "  the certificates of registries are verified against the system certificates")
+	}
+
+	if insecure := viper.GetStringSlice("insecure"); len(insecure) > 0 {
+		fmt.Fprintf(w, "  plain HTTP registries: %s\n", strings.Join(insecure, ", "))
+	}
+
+	return nil
+}
+
+// dockerConfig is the part of the Docker config that determines the credentials of registries.
+type dockerConfig struct {
+	Auths       map[string]json.RawMessage `json:"auths"`
+	CredsStore  string                     `json:"credsStore"`
+	CredHelpers map[string]string          `json:"credHelpers"`
+}
+
+// getDockerConfigPath returns the path of the Docker config, which is in the directory set by the
+// DOCKER_CONFIG environment variable, or in the .docker directory of the home directory.
+func getDockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".docker", "config.json")
+	}
+
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+func readDockerConfig(path string) (dockerConfig, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return dockerConfig{}, fmt.Errorf("read file: %w", err)
+	}
+
+	var config dockerConfig
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return dockerConfig{}, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return config, nil
+}
+
+// findCredentialHelper returns where the binary of the credential helper is, or that it is not installed.
+func findCredentialHelper(helper string) string {
+	path, err := exec.LookPath("docker-credential-" + helper)
+	if err != nil {
+		return "docker-credential-" + helper + " not found in PATH"
+	}
+
+	return path
+}
+
+// checkCACert returns whether the file contains certificates that can be trusted.
+func checkCACert(path string) string {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Sprintf("unable to read: %v", err)
+	}
+
+	if !x509.NewCertPool().AppendCertsFromPEM(contents) {
+		return "no PEM certificates found"
+	}
+
+	return "ok"
+}
+
+// redactProxy returns the proxy without the password of its URL, if any.
+func redactProxy(proxy string) string {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.User == nil {
+		return proxy
+	}
+
+	if _, hasPassword := proxyURL.User.Password(); hasPassword {
+		proxyURL.User = url.UserPassword(proxyURL.User.Username(), "xxxxx")
+	}
+
+	return proxyURL.String()
+}
+
+func formatList(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+
+	return strings.Join(values, ", ")
+}
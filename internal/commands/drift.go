@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newDriftCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "drift <environment>=<path>...",
+		Short: "Report the images whose versions differ between environments",
+		Args:  cobra.MinimumNArgs(2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("all", cmd.Flags().Lookup("all")); err != nil {
+				return fmt.Errorf("bind all flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("format", cmd.Flags().Lookup("format")); err != nil {
+				return fmt.Errorf("bind format flag: %w", err)
+			}
+
+			if err := runDriftCommand(cmd.Context(), args); err != nil {
+				return fmt.Errorf("drift: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("all", false, "Report every repository, including the repositories that are the same in each environment")
+	cmd.Flags().String("format", "table", "Format to output the report in (table or json)")
+
+	return &cmd
+}
+
+// driftEnvironment is an environment whose images are found at the path, which is
+// either an image manifest or a path to Kubernetes manifests (see diff).
+type driftEnvironment struct {
+	name string
+	path string
+}
+
+// repositoryDrift is the versions of a repository in each of the environments that it is used in.
+type repositoryDrift struct {
+	Repository string              `json:"repository"`
+	Versions   map[string][]string `json:"versions"`
+	Drift      bool                `json:"drift"`
+}
+
+func runDriftCommand(ctx context.Context, args []string) error {
+	format := viper.GetString("format")
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %s", format)
+	}
+
+	environments, err := parseDriftEnvironments(args)
+	if err != nil {
+		return fmt.Errorf("parse environments: %w", err)
+	}
+
+	environmentVersions := make(map[string]map[string][]string)
+	for _, environment := range environments {
+		sources, err := getDiffSources(ctx, environment.path, manifest.Target{})
+		if err != nil {
+			return fmt.Errorf("get sources of %s: %w", environment.name, err)
+		}
+
+		environmentVersions[environment.name] = getRepositoryVersions(sources)
+	}
+
+	drifts := getRepositoryDrifts(environments, environmentVersions)
+
+	var driftCount int
+	var reportedDrifts []repositoryDrift
+	for _, drift := range drifts {
+		if drift.Drift {
+			driftCount++
+		}
+
+		if drift.Drift || viper.GetBool("all") {
+			reportedDrifts = append(reportedDrifts, drift)
+		}
+	}
+
+	if format == "json" {
+		if err := writeDriftJSON(os.Stdout, reportedDrifts); err != nil {
+			return fmt.Errorf("write json: %w", err)
+		}
+	} else if err := writeDriftTable(os.Stdout, environments, reportedDrifts); err != nil {
+		return fmt.Errorf("write table: %w", err)
+	}
+
+	log.Infof("%v of %v repositories drift between the environments", driftCount, len(drifts))
+
+	return nil
+}
+
+// parseDriftEnvironments parses the environments from arguments in the form of <environment>=<path>.
+func parseDriftEnvironments(args []string) ([]driftEnvironment, error) {
+	var environments []driftEnvironment
+	names := make(map[string]bool)
+	for _, arg := range args {
+		tokens := strings.SplitN(arg, "=", 2)
+		if len(tokens) != 2 || tokens[0] == "" || tokens[1] == "" {
+			return nil, fmt.Errorf("invalid environment %s, expected <environment>=<path>", arg)
+		}
+
+		if names[tokens[0]] {
+			return nil, fmt.Errorf("environment %s is specified more than once", tokens[0])
+		}
+		names[tokens[0]] = true
+
+		environments = append(environments, driftEnvironment{name: tokens[0], path: tokens[1]})
+	}
+
+	return environments, nil
+}
+
+// getRepositoryDrifts returns the versions of each repository in each environment, sorted by the repository.
+// A repository drifts when it is used in more than one environment and its versions are not the same in each
+// of them. Repositories that are missing from some environments do not drift, as they may not be deployed there yet.
+func getRepositoryDrifts(environments []driftEnvironment, environmentVersions map[string]map[string][]string) []repositoryDrift {
+	driftsByRepository := make(map[string]*repositoryDrift)
+	for _, environment := range environments {
+		for repository, versions := range environmentVersions[environment.name] {
+			drift, ok := driftsByRepository[repository]
+			if !ok {
+				drift = &repositoryDrift{Repository: repository, Versions: make(map[string][]string)}
+				driftsByRepository[repository] = drift
+			}
+
+			drift.Versions[environment.name] = versions
+		}
+	}
+
+	var drifts []repositoryDrift
+	for _, drift := range driftsByRepository {
+		versionSets := make(map[string]bool)
+		for _, versions := range drift.Versions {
+			versionSets[strings.Join(versions, ",")] = true
+		}
+		drift.Drift = len(versionSets) > 1
+
+		drifts = append(drifts, *drift)
+	}
+
+	sort.Slice(drifts, func(i, j int) bool {
+		return drifts[i].Repository < drifts[j].Repository
+	})
+
+	return drifts
+}
+
+func writeDriftJSON(w io.Writer, drifts []repositoryDrift) error {
+	if drifts == nil {
+		drifts = []repositoryDrift{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(drifts); err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+
+	return nil
+}
+
+// writeDriftTable writes a row for each repository with a column for each environment, in the order of the
+// environments. A repository that is not used in an environment has a dash in the column of the environment.
+func writeDriftTable(w io.Writer, environments []driftEnvironment, drifts []repositoryDrift) error {
+	header := []string{"REPOSITORY"}
+	for _, environment := range environments {
+		header = append(header, strings.ToUpper(environment.name))
+	}
+	header = append(header, "STATUS")
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, strings.Join(header, "\t")); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, drift := range drifts {
+		row := []string{drift.Repository}
+		for _, environment := range environments {
+			row = append(row, valueOrDash(strings.Join(drift.Versions[environment.name], ", ")))
+		}
+
+		status := "same"
+		if drift.Drift {
+			status = "drift"
+		}
+		row = append(row, status)
+
+		if _, err := fmt.Fprintln(table, strings.Join(row, "\t")); err != nil {
+			return fmt.Errorf("write repository: %w", err)
+		}
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
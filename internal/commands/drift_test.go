@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+)
+
+func TestParseDriftEnvironments(t *testing.T) {
+	environments, err := parseDriftEnvironments([]string{"dev=envs/dev", "prod=.images.yaml"})
+	if err != nil {
+		t.Fatal("parse drift environments:", err)
+	}
+
+	expected := []driftEnvironment{{name: "dev", path: "envs/dev"}, {name: "prod", path: ".images.yaml"}}
+	if len(environments) != len(expected) || environments[0] != expected[0] || environments[1] != expected[1] {
+		t.Errorf("expected environments %v, actual %v", expected, environments)
+	}
+
+	invalidArgs := [][]string{
+		{"envs/dev", "prod=.images.yaml"},
+		{"=envs/dev", "prod=.images.yaml"},
+		{"dev=", "prod=.images.yaml"},
+		{"dev=envs/dev", "dev=envs/dev2"},
+	}
+
+	for _, args := range invalidArgs {
+		if _, err := parseDriftEnvironments(args); err == nil {
+			t.Errorf("expected error when parsing %v", args)
+		}
+	}
+}
+
+func TestWriteDriftTable(t *testing.T) {
+	environments := []driftEnvironment{{name: "dev"}, {name: "stage"}, {name: "prod"}}
+
+	environmentVersions := map[string]map[string][]string{
+		"dev": getRepositoryVersions([]manifest.Source{
+			{Repository: "busybox", Tag: "1.32.0"},
+			{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.41.0"},
+			{Repository: "jimmidyson/configmap-reload", Tag: "v0.3.0"},
+		}),
+		"stage": getRepositoryVersions([]manifest.Source{
+			{Repository: "busybox", Tag: "1.32.0"},
+			{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		}),
+		"prod": getRepositoryVersions([]manifest.Source{
+			{Repository: "busybox", Tag: "1.32.0"},
+			{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		}),
+	}
+
+	drifts := getRepositoryDrifts(environments, environmentVersions)
+	if len(drifts) != 3 {
+		t.Fatalf("expected 3 repositories, actual %v", len(drifts))
+	}
+
+	var actual bytes.Buffer
+	if err := writeDriftTable(&actual, environments, drifts); err != nil {
+		t.Fatal("write drift table:", err)
+	}
+
+	expected := `REPOSITORY                          DEV      STAGE    PROD     STATUS
+busybox                             1.32.0   1.32.0   1.32.0   same
+jimmidyson/configmap-reload         v0.3.0   -        -        same
+quay.io/coreos/prometheus-operator  v0.41.0  v0.40.0  v0.40.0  drift
+`
+
+	if actual.String() != expected {
+		t.Errorf("expected table\n%s\nactual\n%s", expected, actual.String())
+	}
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newExportCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:       "export <skopeo|oc-mirror|harbor|cyclonedx|backstage>",
+		Short:     "Export the images in the manifest as a configuration for other mirroring tools or as an inventory for other systems",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"skopeo", "oc-mirror", "harbor", "cyclonedx", "backstage"},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("owner", cmd.Flags().Lookup("owner")); err != nil {
+				return fmt.Errorf("bind owner flag: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runExportCommand(cmd.Context(), args[0], manifestPath); err != nil {
+				return fmt.Errorf("export: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Path where the configuration will be written to (defaults to stdout)")
+	cmd.Flags().String("owner", "unknown", "Owner of the exported Backstage entities (e.g. group:platform)")
+
+	return &cmd
+}
+
+func runExportCommand(ctx context.Context, format string, manifestPath string) error {
+	sources, err := getManifestSources(ctx, manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest sources: %w", err)
+	}
+
+	var contents []byte
+	switch format {
+	case "oc-mirror":
+		contents, err = manifest.ToImageSetConfig(sources)
+	case "harbor":
+		contents, err = manifest.ToHarborReplication(sources)
+	case "cyclonedx":
+		contents, err = manifest.ToCycloneDX(sources, buildVersion)
+	case "backstage":
+		contents, err = manifest.ToBackstageEntities(sources, viper.GetString("owner"))
+	default:
+		contents, err = manifest.ToSkopeoSync(sources)
+	}
+	if err != nil {
+		return fmt.Errorf("convert %s: %w", format, err)
+	}
+
+	if viper.GetString("output") == "" {
+		if _, err := os.Stdout.Write(contents); err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := ioutil.WriteFile(viper.GetString("output"), contents, os.ModePerm); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
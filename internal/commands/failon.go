@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/spf13/viper"
+)
+
+// The kinds of images that can cause a command to fail.
+const (
+	failOnMissing    = "missing"
+	failOnUntagged   = "untagged"
+	failOnLatestTag  = "latest-tag"
+	failOnPlatform   = "platform"
+	failOnOversized  = "oversized"
+	failOnViolations = "violations"
+	failOnNone       = "none"
+)
+
+var failureMessages = map[string]string{
+	failOnMissing:    "%v image(s) missing from the target",
+	failOnUntagged:   "%v image(s) without a tag or digest",
+	failOnLatestTag:  "%v image(s) using the latest tag",
+	failOnPlatform:   "%v image(s) without the required platforms",
+	failOnOversized:  "%v image(s) larger than the maximum image size",
+	failOnViolations: "found %v policy violation(s)",
+}
+
+// failurePolicy decides whether a command fails based on the number of images of each kind that it found,
+// so that pipelines can enforce their mirroring policies at different levels of strictness.
+type failurePolicy struct {
+	kinds     []string
+	threshold int
+}
+
+// getFailurePolicy returns the failure policy set by the fail-on and fail-threshold flags. The
+// command only supports the given kinds, as not every kind of image can be found by every command.
+func getFailurePolicy(supportedKinds ...string) (failurePolicy, error) {
+	kinds := viper.GetStringSlice("fail-on")
+	for _, kind := range kinds {
+		if kind == failOnNone {
+			if len(kinds) > 1 {
+				return failurePolicy{}, errors.New("fail-on none cannot be combined with other kinds")
+			}
+
+			return failurePolicy{}, nil
+		}
+
+		if !containsString(supportedKinds, kind) {
+			return failurePolicy{}, fmt.Errorf("unsupported fail-on kind %s (must be one of %s or none)", kind, strings.Join(supportedKinds, ", "))
+		}
+	}
+
+	threshold := viper.GetInt("fail-threshold")
+	if threshold < 0 {
+		return failurePolicy{}, errors.New("fail-threshold must not be negative")
+	}
+
+	policy := failurePolicy{
+		kinds:     kinds,
+		threshold: threshold,
+	}
+
+	return policy, nil
+}
+
+// enabled returns true when the policy fails on the given kind of image.
+func (p failurePolicy) enabled(kind string) bool {
+	return containsString(p.kinds, kind)
+}
+
+// check returns an error when more images of any kind that the policy fails on were found than the threshold allows.
+func (p failurePolicy) check(counts map[string]int) error {
+	var failures []string
+	for _, kind := range p.kinds {
+		if counts[kind] <= p.threshold {
+			continue
+		}
+
+		failure := fmt.Sprintf(failureMessages[kind], counts[kind])
+		if p.threshold > 0 {
+			failure += fmt.Sprintf(" (%v allowed)", p.threshold)
+		}
+
+		failures = append(failures, failure)
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, ", "))
+	}
+
+	return nil
+}
+
+// countSourceKinds returns the number of sources without a tag or digest and the number of sources that
+// use the latest tag. Sources without a tag default to the latest tag, so they are counted as both.
+func countSourceKinds(sources []manifest.Source) map[string]int {
+	counts := make(map[string]int)
+	for _, source := range sources {
+		if source.Tag == "" && source.Digest == "" {
+			counts[failOnUntagged]++
+			counts[failOnLatestTag]++
+		} else if source.Tag == "latest" {
+			counts[failOnLatestTag]++
+		}
+	}
+
+	return counts
+}
+
+// countImageKinds returns the number of images without a tag or digest and the number of images that
+// use the latest tag. Images without a tag default to the latest tag, so they are counted as both.
+func countImageKinds(foundImages []images.Image) map[string]int {
+	counts := make(map[string]int)
+	for _, image := range foundImages {
+		if image.Tag == "latest" && image.Digest == "" && !strings.HasSuffix(image.Reference, ":latest") {
+			counts[failOnUntagged]++
+		}
+
+		if image.Tag == "latest" {
+			counts[failOnLatestTag]++
+		}
+	}
+
+	return counts
+}
+
+func containsString(items []string, item string) bool {
+	for _, currentItem := range items {
+		if currentItem == item {
+			return true
+		}
+	}
+
+	return false
+}
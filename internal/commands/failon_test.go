@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/viper"
+)
+
+func TestFailurePolicy_Check(t *testing.T) {
+	viper.Set("fail-on", []string{failOnMissing, failOnLatestTag})
+	viper.Set("fail-threshold", 1)
+	defer viper.Reset()
+
+	policy, err := getFailurePolicy(failOnMissing, failOnUntagged, failOnLatestTag)
+	if err != nil {
+		t.Fatal("get failure policy:", err)
+	}
+
+	sources := []manifest.Source{
+		{Repository: "busybox", Tag: "latest"},
+		{Repository: "nginx"},
+		{Repository: "alpine", Tag: "3.12"},
+	}
+
+	counts := countSourceKinds(sources)
+	if counts[failOnUntagged] != 1 || counts[failOnLatestTag] != 2 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+
+	counts[failOnMissing] = 1
+	err = policy.check(counts)
+	if err == nil {
+		t.Fatal("expected more latest tags than the threshold to fail")
+	}
+
+	expected := "2 image(s) using the latest tag (1 allowed)"
+	if err.Error() != expected {
+		t.Errorf("expected error %q, actual %q", expected, err.Error())
+	}
+}
+
+func TestGetFailurePolicy(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("fail-on", []string{failOnNone})
+	policy, err := getFailurePolicy(failOnMissing)
+	if err != nil {
+		t.Fatal("get failure policy:", err)
+	}
+
+	if err := policy.check(map[string]int{failOnMissing: 10}); err != nil {
+		t.Errorf("expected none to never fail, actual %v", err)
+	}
+
+	viper.Set("fail-on", []string{failOnViolations})
+	if _, err := getFailurePolicy(failOnMissing); err == nil {
+		t.Error("expected unsupported kind to return an error")
+	}
+
+	viper.Set("fail-on", []string{failOnNone, failOnMissing})
+	if _, err := getFailurePolicy(failOnMissing); err == nil {
+		t.Error("expected none combined with other kinds to return an error")
+	}
+}
@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// The exit codes of a push that failed to push some or all of the images, so that pipelines can tell
+// them apart from each other and from other errors (such as an invalid manifest), which exit with 1.
+const (
+	exitCodePartialFailure = 2
+	exitCodeAllFailed      = 3
+)
+
+// ExitError is an error that the command exits with the given exit code for.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e ExitError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error that caused the command to exit.
+func (e ExitError) Unwrap() error {
+	return e.Err
+}
+
+// pushFailures are the images that could not be pushed, which can be pushed again with the from-failures flag.
+type pushFailures struct {
+	Failed []pushFailure `yaml:"failed"`
+}
+
+type pushFailure struct {
+	Source string `yaml:"source"`
+	Target string `yaml:"target"`
+	Error  string `yaml:"error"`
+}
+
+// newPushFailures returns the sources that failed to be pushed, where the error of each
+// source is at the same index as the source and sources that were pushed have no error.
+func newPushFailures(sources []manifest.Source, errs []error) pushFailures {
+	var failures pushFailures
+	for s, source := range sources {
+		if errs[s] == nil {
+			continue
+		}
+
+		failures.Failed = append(failures.Failed, pushFailure{
+			Source: source.Image(),
+			Target: source.TargetImage(),
+			Error:  errs[s].Error(),
+		})
+	}
+
+	return failures
+}
+
+func readPushFailures(path string) (pushFailures, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return pushFailures{}, fmt.Errorf("read file: %w", err)
+	}
+
+	var failures pushFailures
+	if err := yaml.Unmarshal(contents, &failures); err != nil {
+		return pushFailures{}, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return failures, nil
+}
+
+// write writes the failures to the path. When no image failed, the file is removed
+// instead, so that a later run does not push the failures of an earlier run again.
+func (f pushFailures) write(path string) error {
+	if len(f.Failed) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove: %w", err)
+		}
+
+		return nil
+	}
+
+	contents, err := yaml.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := writeFileAtomic(path, contents); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
+
+// filterFailedSources returns the sources that failed to be pushed to their target.
+func filterFailedSources(sources []manifest.Source, failures pushFailures) []manifest.Source {
+	failedTargets := make(map[string]bool)
+	for _, failure := range failures.Failed {
+		failedTargets[failure.Target] = true
+	}
+
+	var failedSources []manifest.Source
+	for _, source := range sources {
+		if failedTargets[source.TargetImage()] {
+			failedSources = append(failedSources, source)
+			delete(failedTargets, source.TargetImage())
+		}
+	}
+
+	for _, failure := range failures.Failed {
+		if failedTargets[failure.Target] {
+			log.Warnf("Image %s failed to be pushed as %s, but is no longer in the manifest", failure.Source, failure.Target)
+		}
+	}
+
+	return failedSources
+}
+
+// getPushExitError returns the error of a push where the given number of images out of the
+// total failed, with the exit code of a push where either some or all of the images failed.
+func getPushExitError(err error, failed int, total int) error {
+	code := exitCodePartialFailure
+	if failed == total {
+		code = exitCodeAllFailed
+	}
+
+	return ExitError{Code: code, Err: err}
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+)
+
+func TestPushFailures(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	target := manifest.Target{Host: "mycompany.com", Repository: "mirror"}
+	sources := []manifest.Source{
+		{Repository: "busybox", Tag: "1.32.0", Target: target},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: target},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.0", Target: target},
+	}
+
+	failures := newPushFailures(sources, []error{nil, errors.New("copy image: unauthorized"), nil})
+
+	path := filepath.Join(tempDir, "failed.yaml")
+	if err := failures.write(path); err != nil {
+		t.Fatal("write failures:", err)
+	}
+
+	readFailures, err := readPushFailures(path)
+	if err != nil {
+		t.Fatal("read failures:", err)
+	}
+
+	if !reflect.DeepEqual(readFailures, failures) {
+		t.Errorf("expected failures %+v, actual %+v", failures, readFailures)
+	}
+
+	failedSources := filterFailedSources(sources, readFailures)
+	if len(failedSources) != 1 || failedSources[0].Image() != "quay.io/coreos/prometheus-operator:v0.40.0" {
+		t.Errorf("expected only the failed source, actual %v", failedSources)
+	}
+
+	// A push without failures removes the failures of an earlier push.
+	if err := newPushFailures(sources, make([]error, len(sources))).write(path); err != nil {
+		t.Fatal("write failures:", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected failures file to be removed")
+	}
+}
+
+func TestGetPushExitError(t *testing.T) {
+	var exitErr ExitError
+	if !errors.As(getPushExitError(errors.New("push"), 1, 3), &exitErr) || exitErr.Code != exitCodePartialFailure {
+		t.Errorf("expected exit code %v for a partial failure, actual %v", exitCodePartialFailure, exitErr.Code)
+	}
+
+	if !errors.As(getPushExitError(errors.New("push"), 3, 3), &exitErr) || exitErr.Code != exitCodeAllFailed {
+		t.Errorf("expected exit code %v when all images failed, actual %v", exitCodeAllFailed, exitErr.Code)
+	}
+}
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// copySourceWithFallbacks copies the source to the target. When the image of the source cannot be copied, it is copied
+// from each of the fallbacks of the source in order, until one of them is copied. The source that was copied is returned
+// along with its auth, so that the steps after the copy (e.g. verifying the digest) use the registry it was copied from.
+func copySourceWithFallbacks(ctx context.Context, target docker.Target, source manifest.Source, sourceAuth string, targetAuth string) (manifest.Source, string, error) {
+	copyErr := copySource(ctx, target, source, sourceAuth, targetAuth)
+	if copyErr == nil {
+		return source, sourceAuth, nil
+	}
+
+	failedImage := source.PullImage()
+	for _, fallback := range source.FallbackSources() {
+		if ctx.Err() != nil {
+			break
+		}
+
+		log.Warnf("Unable to pull %s: %v. Pulling %s instead", failedImage, copyErr, fallback.PullImage())
+
+		fallbackAuth, err := getSourceAuth(fallback)
+		if err != nil {
+			return source, sourceAuth, fmt.Errorf("get fallback auth: %w", err)
+		}
+
+		copyErr = copySource(ctx, target, fallback, fallbackAuth, targetAuth)
+		if copyErr == nil {
+			return fallback, fallbackAuth, nil
+		}
+
+		failedImage = fallback.PullImage()
+	}
+
+	return source, sourceAuth, copyErr
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestCopySourceWithFallbacks(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	// The image only exists at the second fallback, as if the project moved there.
+	reference, err := name.ParseReference(host+"/moved/app:1.0", name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(reference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	source := manifest.Source{
+		Host:       host,
+		Repository: "org/app",
+		Tag:        "1.0",
+		Target:     manifest.Target{Host: host, Repository: "target"},
+		Fallbacks: []manifest.Fallback{
+			{Repository: "outage/app"},
+			{Repository: "moved/app"},
+		},
+	}
+
+	client, err := docker.NewClient(t.Logf)
+	if err != nil {
+		t.Fatal("new client:", err)
+	}
+	client = client.WithRetries(0)
+
+	target, err := docker.NewTarget(client, "")
+	if err != nil {
+		t.Fatal("new target:", err)
+	}
+
+	copiedSource, _, err := copySourceWithFallbacks(context.Background(), target, source, "", "")
+	if err != nil {
+		t.Fatal("copy source with fallbacks:", err)
+	}
+
+	expected := host + "/moved/app:1.0"
+	if copiedSource.PullImage() != expected {
+		t.Errorf("expected source to be copied from %s, actual %s", expected, copiedSource.PullImage())
+	}
+
+	targetDigest, err := client.GetDigest(context.Background(), source.TargetImage(), "")
+	if err != nil {
+		t.Fatal("get target digest:", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	if targetDigest != digest.String() {
+		t.Errorf("expected target digest %s, actual %s", digest, targetDigest)
+	}
+
+	source.Fallbacks = source.Fallbacks[:1]
+	if _, _, err := copySourceWithFallbacks(context.Background(), target, source, "", ""); err == nil {
+		t.Error("expected error when none of the fallbacks can be copied")
+	}
+}
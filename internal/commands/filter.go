@@ -0,0 +1,263 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// getManifestSources returns the sources in the manifest found at the specified path
+// without the sources that are ignored by the manifest, the ignore file or the exclude flags.
+// Sources that select tags with a version constraint or pattern are expanded into a source for each tag.
+func getManifestSources(ctx context.Context, manifestPath string) ([]manifest.Source, error) {
+	sources, _, err := getManifestSourcesWithSummary(ctx, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// getManifestSourcesWithSummary returns the same sources as getManifestSources along with a summary
+// of the files that were read, the sources in the manifest and the images that were skipped.
+// When the since flag is set, only the sources that were added or changed since the Git ref are returned.
+func getManifestSourcesWithSummary(ctx context.Context, manifestPath string) ([]manifest.Source, runSummary, error) {
+	imageManifest, err := getManifest(manifestPath)
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("get manifest: %w", err)
+	}
+
+	resources := len(imageManifest.Sources)
+
+	// The sources are compared before their tags are expanded, so that the
+	// new tags of a source that did not change are not part of the delta.
+	if ref := viper.GetString("since"); ref != "" {
+		imageManifest.Sources, err = filterChangedSources(ctx, manifestPath, imageManifest.Sources, ref)
+		if err != nil {
+			return nil, runSummary{}, fmt.Errorf("filter changed sources: %w", err)
+		}
+	}
+
+	imageManifest.Sources, err = expandTagSelectors(ctx, imageManifest.Sources)
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("expand tag selectors: %w", err)
+	}
+
+	ignoreFilePatterns, err := manifest.GetIgnoreFile(manifestPath)
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("get ignore file: %w", err)
+	}
+
+	var globs []string
+	globs = append(globs, imageManifest.Ignore...)
+	globs = append(globs, ignoreFilePatterns...)
+
+	sources, err := filterSources(imageManifest.Sources, globs)
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("filter sources: %w", err)
+	}
+
+	sources, err = manifest.WithSourceMirrors(sources, viper.GetStringMapString("source-mirror"))
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("with source mirrors: %w", err)
+	}
+
+	summary := newRunSummary(len(imageManifest.Sources), sources)
+	summary.FilesScanned = 1 + len(viper.GetStringSlice("manifest-values"))
+	summary.ResourcesParsed = resources
+
+	return sources, summary, nil
+}
+
+// getImagesOrManifestSources returns the sources of the images passed in with the images or images-file flags,
+// or the sources in the manifest found at the specified path when no images were passed in.
+func getImagesOrManifestSources(ctx context.Context, manifestPath string) ([]manifest.Source, runSummary, error) {
+	inputImages, err := getInputImages()
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("get input images: %w", err)
+	}
+
+	sources, err := manifest.GetSourcesFromImages(inputImages, viper.GetString("target"), getRegistryHostsOption())
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("get sources from images: %w", err)
+	}
+
+	if len(sources) == 0 {
+		if err := verifyManifestSignature(ctx, manifestPath); err != nil {
+			return nil, runSummary{}, fmt.Errorf("verify manifest signature: %w", err)
+		}
+
+		return getManifestSourcesWithSummary(ctx, manifestPath)
+	}
+
+	filteredSources, err := filterSources(sources, nil)
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("filter sources: %w", err)
+	}
+
+	filteredSources, err = manifest.WithSourceMirrors(filteredSources, viper.GetStringMapString("source-mirror"))
+	if err != nil {
+		return nil, runSummary{}, fmt.Errorf("with source mirrors: %w", err)
+	}
+
+	return filteredSources, newRunSummary(len(sources), filteredSources), nil
+}
+
+// getInputImages returns the images passed in with the images flag, followed by
+// the images listed in the file passed in with the images-file flag, if any.
+func getInputImages() ([]string, error) {
+	inputImages := viper.GetStringSlice("images")
+	if viper.GetString("images-file") == "" {
+		return inputImages, nil
+	}
+
+	fileImages, err := readImageList(viper.GetString("images-file"))
+	if err != nil {
+		return nil, fmt.Errorf("read image list: %w", err)
+	}
+
+	return append(inputImages, fileImages...), nil
+}
+
+// hasInputImages returns true when images were passed in with the images or images-file flags.
+func hasInputImages() bool {
+	return len(viper.GetStringSlice("images")) > 0 || viper.GetString("images-file") != ""
+}
+
+// readImageList returns the images listed in the file, such as the output of the list command, or
+// in stdin when the path is -. Empty lines and comments are skipped.
+func readImageList(path string) ([]string, error) {
+	reader := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open: %w", err)
+		}
+		defer f.Close()
+
+		reader = f
+	}
+
+	var images []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		images = append(images, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return images, nil
+}
+
+// expandTagSelectors replaces the sources that select tags with a source for each of the selected
+// tags, which are listed from the source registry. Other sources are returned as is.
+func expandTagSelectors(ctx context.Context, sources []manifest.Source) ([]manifest.Source, error) {
+	var client *docker.Client
+	var expandedSources []manifest.Source
+	for _, source := range sources {
+		if !source.HasTagSelector() {
+			expandedSources = append(expandedSources, source)
+			continue
+		}
+
+		if client == nil {
+			tagClient, err := newClient()
+			if err != nil {
+				return nil, fmt.Errorf("new client: %w", err)
+			}
+
+			client = &tagClient
+		}
+
+		tags, err := client.GetTagsForRepository(ctx, source.Host, source.Repository)
+		if err != nil {
+			return nil, fmt.Errorf("get tags for %s: %w", source.Image(), err)
+		}
+
+		taggedSources, err := source.ExpandTags(tags)
+		if err != nil {
+			return nil, fmt.Errorf("expand tags of %s: %w", source.Image(), err)
+		}
+
+		if len(taggedSources) == 0 {
+			log.Warnf("No tags of %s match the tag selector", source.Image())
+		}
+
+		expandedSources = append(expandedSources, taggedSources...)
+	}
+
+	return expandedSources, nil
+}
+
+// filterSources removes the sources that match any of the given glob patterns
+// or any of the patterns passed in with the exclude flags. When the source filter
+// flag is set, only the sources from the given registries are kept.
+func filterSources(sources []manifest.Source, globs []string) ([]manifest.Source, error) {
+	sources = filterSourceRegistries(sources, viper.GetStringSlice("source-filter"))
+
+	globs = append(globs, viper.GetStringSlice("exclude")...)
+
+	var patterns []*regexp.Regexp
+	for _, glob := range globs {
+		pattern, err := manifest.CompileGlob(glob)
+		if err != nil {
+			return nil, fmt.Errorf("compile glob: %w", err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	for _, expression := range viper.GetStringSlice("exclude-regex") {
+		pattern, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("compile exclude regex: %w", err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return manifest.Exclude(sources, patterns), nil
+}
+
+func filterSourceRegistries(sources []manifest.Source, registries []string) []manifest.Source {
+	if len(registries) == 0 {
+		return sources
+	}
+
+	var filtered []manifest.Source
+	for _, source := range sources {
+		for _, registry := range registries {
+
+			// Sources without a host are on Docker Hub, which can be
+			// referred to as either docker.io or index.docker.io.
+			if source.Host == "" && (registry == "docker.io" || registry == "index.docker.io") {
+				filtered = append(filtered, source)
+				break
+			}
+
+			if strings.EqualFold(source.Host, registry) {
+				filtered = append(filtered, source)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
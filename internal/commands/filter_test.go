@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/spf13/viper"
+)
+
+func TestFilterSourceRegistries(t *testing.T) {
+	sources := []manifest.Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "gcr.io", Repository: "google-containers/pause", Tag: "3.2"},
+		{Host: "internal.example.com", Repository: "team/app", Tag: "v1.0.0"},
+	}
+
+	actual := filterSourceRegistries(sources, []string{"quay.io", "docker.io"})
+
+	expected := []manifest.Source{sources[0], sources[1]}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected sources %+v, actual %+v", expected, actual)
+	}
+}
+
+func TestGetInputImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "images.txt")
+	if err := ioutil.WriteFile(path, []byte("# mirrored images\nnginx:1.25\n\n  quay.io/coreos/prometheus-operator:v0.40.0\n"), os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	viper.Set("images", []string{"busybox:1.32.0"})
+	viper.Set("images-file", path)
+	defer viper.Set("images", []string{})
+	defer viper.Set("images-file", "")
+
+	actual, err := getInputImages()
+	if err != nil {
+		t.Fatal("get input images:", err)
+	}
+
+	expected := []string{"busybox:1.32.0", "nginx:1.25", "quay.io/coreos/prometheus-operator:v0.40.0"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,314 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newGenerateCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "generate",
+		Short: "Generate Kubernetes resources from the images in the manifest",
+	}
+
+	cmd.AddCommand(newGeneratePrepullCommand())
+	cmd.AddCommand(newGeneratePolicyCommand())
+	cmd.AddCommand(newGenerateMirrorsCommand())
+
+	return &cmd
+}
+
+func newGeneratePrepullCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:       "prepull <source|target>",
+		Short:     "Generate a DaemonSet or OpenKruise ImagePullJobs that pre-pull the images onto the nodes of a cluster",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"source", "target"},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := []string{"kind", "name", "namespace", "pull-secret", "helper-image", "pause-image", "output"}
+			for _, flag := range flags {
+				if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+					return fmt.Errorf("bind %s flag: %w", flag, err)
+				}
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runGeneratePrepullCommand(cmd.Context(), args[0], manifestPath); err != nil {
+				return fmt.Errorf("generate prepull: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("kind", "daemonset", "Kind of the resources to generate (daemonset or imagepulljob)")
+	cmd.Flags().String("name", "sinker-prepull", "Name of the DaemonSet, or the prefix of the names of the ImagePullJobs")
+	cmd.Flags().String("namespace", "", "Namespace of the generated resources")
+	cmd.Flags().StringSlice("pull-secret", []string{}, "Name of an image pull secret to pull the images with (can be repeated)")
+	cmd.Flags().String("helper-image", "busybox:1.36", "Image of the static busybox binary that the DaemonSet runs in each image")
+	cmd.Flags().String("pause-image", "registry.k8s.io/pause:3.9", "Image of the container that keeps the DaemonSet running")
+	cmd.Flags().StringP("output", "o", "", "Path where the resources will be written to (defaults to stdout)")
+
+	return &cmd
+}
+
+func runGeneratePrepullCommand(ctx context.Context, origin string, manifestPath string) error {
+	kind := viper.GetString("kind")
+	if kind != "daemonset" && kind != "imagepulljob" {
+		return fmt.Errorf("unknown kind %s", kind)
+	}
+
+	sources, err := getManifestSources(ctx, manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest sources: %w", err)
+	}
+
+	var images []string
+	for _, source := range sources {
+		if origin == "source" {
+			images = append(images, source.Image())
+		} else {
+			images = append(images, source.TargetImage())
+		}
+	}
+	images = dedupeImages(images, images)
+
+	options := manifest.PrepullOptions{
+		Name:        viper.GetString("name"),
+		Namespace:   viper.GetString("namespace"),
+		PullSecrets: viper.GetStringSlice("pull-secret"),
+		HelperImage: viper.GetString("helper-image"),
+		PauseImage:  viper.GetString("pause-image"),
+	}
+
+	var contents []byte
+	if kind == "imagepulljob" {
+		contents, err = manifest.ToImagePullJobs(images, options)
+	} else {
+		contents, err = manifest.ToPrepullDaemonSet(images, options)
+	}
+	if err != nil {
+		return fmt.Errorf("generate %s: %w", kind, err)
+	}
+
+	if err := writeGenerated(contents); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+func newGeneratePolicyCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "policy",
+		Short: "Generate an admission policy that only allows the images pushed to the target to run",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := []string{"format", "scope", "name", "output"}
+			for _, flag := range flags {
+				if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+					return fmt.Errorf("bind %s flag: %w", flag, err)
+				}
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runGeneratePolicyCommand(cmd.Context(), manifestPath); err != nil {
+				return fmt.Errorf("generate policy: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("format", "kyverno", "Format of the policy (kyverno, gatekeeper or opa)")
+	cmd.Flags().String("scope", manifest.AdmissionScopeImage, "Scope of the allowed images (image, repository or registry)")
+	cmd.Flags().String("name", "sinker-allowed-images", "Name of the policy")
+	cmd.Flags().StringP("output", "o", "", "Path where the policy will be written to (defaults to stdout)")
+
+	return &cmd
+}
+
+func runGeneratePolicyCommand(ctx context.Context, manifestPath string) error {
+	scope := viper.GetString("scope")
+	if scope != manifest.AdmissionScopeImage && scope != manifest.AdmissionScopeRepository && scope != manifest.AdmissionScopeRegistry {
+		return fmt.Errorf("unknown scope %s", scope)
+	}
+
+	sources, err := getManifestSources(ctx, manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest sources: %w", err)
+	}
+
+	options := manifest.AdmissionOptions{
+		Name:  viper.GetString("name"),
+		Scope: scope,
+	}
+
+	var contents []byte
+	switch format := viper.GetString("format"); format {
+	case "kyverno":
+		contents, err = manifest.ToKyvernoPolicy(sources, options)
+	case "gatekeeper":
+		contents, err = manifest.ToGatekeeperConstraint(sources, options)
+	case "opa":
+		contents, err = manifest.ToOPAData(sources)
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("generate policy: %w", err)
+	}
+
+	if err := writeGenerated(contents); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+func newGenerateMirrorsCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "mirrors",
+		Short: "Generate the containerd registry configuration that pulls the images of their source registries from the target",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runGenerateMirrorsCommand(cmd.Context(), manifestPath); err != nil {
+				return fmt.Errorf("generate mirrors: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Config path of containerd where the hosts.toml of each registry will be written to (defaults to stdout)")
+
+	return &cmd
+}
+
+func runGenerateMirrorsCommand(ctx context.Context, manifestPath string) error {
+	sources, err := getManifestSources(ctx, manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest sources: %w", err)
+	}
+
+	hosts, unmirrored, err := manifest.ToContainerdHosts(sources)
+	if err != nil {
+		return fmt.Errorf("generate hosts: %w", err)
+	}
+
+	for _, source := range unmirrored {
+		log.Warnf("Image %s is pulled from its source registry, as it is pushed to %s, which containerd cannot pull it from", source.Image(), source.TargetImage())
+	}
+
+	outputPath := viper.GetString("output")
+	if outputPath == "" {
+		for h, host := range hosts {
+			header := fmt.Sprintf("# %s/hosts.toml\n", host.Registry)
+			if h > 0 {
+				header = "\n" + header
+			}
+
+			if _, err := os.Stdout.Write(append([]byte(header), host.Contents...)); err != nil {
+				return fmt.Errorf("write stdout: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	if err := writeContainerdHosts(outputPath, hosts); err != nil {
+		return fmt.Errorf("write hosts: %w", err)
+	}
+
+	return nil
+}
+
+// writeContainerdHosts writes the hosts.toml of each registry to the directory of the registry in the config path,
+// and removes the hosts.toml files that sinker generated for registries that no longer have images in the manifest,
+// so that the configuration of containerd stays in sync with the manifest. Files that were not generated are kept.
+func writeContainerdHosts(configPath string, hosts []manifest.ContainerdHosts) error {
+	registries := make(map[string]bool)
+	for _, host := range hosts {
+		registries[host.Registry] = true
+
+		if err := os.MkdirAll(filepath.Join(configPath, host.Registry), os.ModePerm); err != nil {
+			return fmt.Errorf("create registry directory: %w", err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(configPath, host.Registry, "hosts.toml"), host.Contents, os.ModePerm); err != nil {
+			return fmt.Errorf("write file: %w", err)
+		}
+
+		log.Infof("Wrote mirrors of %s", host.Registry)
+	}
+
+	entries, err := ioutil.ReadDir(configPath)
+	if err != nil {
+		return fmt.Errorf("read config path: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || registries[entry.Name()] {
+			continue
+		}
+
+		hostsFile := filepath.Join(configPath, entry.Name(), "hosts.toml")
+		contents, err := ioutil.ReadFile(hostsFile)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read file: %w", err)
+		}
+
+		if !strings.HasPrefix(string(contents), manifest.ContainerdHostsHeader) {
+			continue
+		}
+
+		if err := os.Remove(hostsFile); err != nil {
+			return fmt.Errorf("remove file: %w", err)
+		}
+
+		// The directory of the registry may still have certificates in it.
+		os.Remove(filepath.Dir(hostsFile))
+
+		log.Infof("Removed mirrors of %s", entry.Name())
+	}
+
+	return nil
+}
+
+// writeGenerated writes the generated contents to the path of the output flag, or to stdout when it is not set.
+func writeGenerated(contents []byte) error {
+	if viper.GetString("output") == "" {
+		if _, err := os.Stdout.Write(contents); err != nil {
+			return fmt.Errorf("write stdout: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := ioutil.WriteFile(viper.GetString("output"), contents, os.ModePerm); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+// The ways that the images found at a source can be grouped into several manifests.
+const (
+	groupByNamespace = "namespace"
+	groupByDirectory = "directory"
+	groupByLabel     = "label"
+)
+
+// defaultGroup is the group of the resources that do not have a namespace or the label that is grouped by.
+const defaultGroup = "default"
+
+// groupNamePattern matches the characters that are replaced in the names of groups, which name their manifest files.
+var groupNamePattern = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// getGroupFunc returns the function that returns the group of a resource: its namespace, the directory below the
+// source that it was found in, or the value of the given label of the resource.
+func getGroupFunc(groupBy string, label string, sourcePath string) (func(resource images.Resource) string, error) {
+	switch groupBy {
+	case groupByNamespace:
+		return func(resource images.Resource) string {
+			return resource.Namespace
+		}, nil
+
+	case groupByDirectory:
+		return func(resource images.Resource) string {
+			return getResourceDirectory(resource.Path, sourcePath)
+		}, nil
+
+	case groupByLabel:
+		if label == "" {
+			return nil, fmt.Errorf("group-label must be specified when grouping by %s", groupByLabel)
+		}
+
+		return func(resource images.Resource) string {
+			return resource.Labels[label]
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown group-by %s (must be %s, %s or %s)", groupBy, groupByNamespace, groupByDirectory, groupByLabel)
+}
+
+// groupImages returns the images grouped by the groups of their resources. An image that is referenced by
+// resources of several groups is in each of those groups, with only the resources of the group.
+func groupImages(foundImages []images.Image, getGroup func(resource images.Resource) string) map[string][]images.Image {
+	groups := make(map[string][]images.Image)
+	for _, image := range foundImages {
+		var groupNames []string
+		groupResources := make(map[string][]images.Resource)
+		for _, resource := range image.Resources {
+			group := getGroupName(getGroup(resource))
+			if _, ok := groupResources[group]; !ok {
+				groupNames = append(groupNames, group)
+			}
+
+			groupResources[group] = append(groupResources[group], resource)
+		}
+
+		for _, group := range groupNames {
+			groupImage := image
+			groupImage.Resources = groupResources[group]
+			groups[group] = append(groups[group], groupImage)
+		}
+	}
+
+	return groups
+}
+
+// getResourceDirectory returns the first directory below the source that the file at the path is in, such as
+// the directory of a team, or the name of the source itself for files that are directly in the source.
+func getResourceDirectory(path string, sourcePath string) string {
+	relativePath, err := filepath.Rel(sourcePath, path)
+	if err != nil || strings.HasPrefix(relativePath, "..") {
+		return filepath.Base(filepath.Dir(path))
+	}
+
+	directory := strings.Split(filepath.ToSlash(relativePath), "/")[0]
+	if directory == relativePath || directory == "." {
+		absolutePath, err := filepath.Abs(sourcePath)
+		if err != nil {
+			return filepath.Base(sourcePath)
+		}
+
+		return filepath.Base(absolutePath)
+	}
+
+	return directory
+}
+
+// getGroupName returns the group as it can be used in the name of a file, or the default group when it is empty.
+func getGroupName(group string) string {
+	name := strings.Trim(groupNamePattern.ReplaceAllString(strings.ToLower(group), "-"), "-.")
+	if name == "" {
+		return defaultGroup
+	}
+
+	return name
+}
+
+// sortedGroupNames returns the names of the groups in alphabetical order.
+func sortedGroupNames(groups map[string][]images.Image) []string {
+	var names []string
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+func TestGroupImages(t *testing.T) {
+	foundImages := []images.Image{
+		{
+			Reference: "nginx:1.19",
+			Resources: []images.Resource{
+				{Path: filepath.Join("apps", "team-a", "web.yaml"), Kind: "Deployment", Name: "web", Namespace: "frontend", Labels: map[string]string{"team": "Team A"}},
+				{Path: filepath.Join("apps", "team-b", "proxy.yaml"), Kind: "DaemonSet", Name: "proxy", Namespace: "edge"},
+			},
+		},
+		{
+			Reference: "quay.io/org/api:v1",
+			Resources: []images.Resource{
+				{Path: filepath.Join("apps", "team-b", "api", "api.yaml"), Kind: "Deployment", Name: "api"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		groupBy  string
+		expected map[string][]string
+	}{
+		{
+			groupBy:  groupByNamespace,
+			expected: map[string][]string{"frontend": {"nginx:1.19"}, "edge": {"nginx:1.19"}, "default": {"quay.io/org/api:v1"}},
+		},
+		{
+			groupBy:  groupByDirectory,
+			expected: map[string][]string{"team-a": {"nginx:1.19"}, "team-b": {"nginx:1.19", "quay.io/org/api:v1"}},
+		},
+		{
+			groupBy:  groupByLabel,
+			expected: map[string][]string{"team-a": {"nginx:1.19"}, "default": {"nginx:1.19", "quay.io/org/api:v1"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		getGroup, err := getGroupFunc(testCase.groupBy, "team", "apps")
+		if err != nil {
+			t.Fatal("get group func:", err)
+		}
+
+		actual := make(map[string][]string)
+		for group, groupImages := range groupImages(foundImages, getGroup) {
+			for _, image := range groupImages {
+				actual[group] = append(actual[group], image.Reference)
+
+				if len(image.Resources) != 1 {
+					t.Errorf("expected only the resource of group %s of image %s when grouping by %s, actual %v", group, image.Reference, testCase.groupBy, image.Resources)
+				}
+			}
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected groups %v when grouping by %s, actual %v", testCase.expected, testCase.groupBy, actual)
+		}
+	}
+
+	if _, err := getGroupFunc(groupByLabel, "", "apps"); err == nil {
+		t.Error("expected error when grouping by label without a label")
+	}
+}
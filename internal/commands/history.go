@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newHistoryCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "history",
+		Short: "Print the images that were pushed and deleted, as recorded in the audit log",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flags := []string{"audit-log", "image", "operation", "user", "since", "failed", "format"}
+			for _, flag := range flags {
+				if err := viper.BindPFlag(flag, cmd.Flags().Lookup(flag)); err != nil {
+					return fmt.Errorf("bind %s flag: %w", flag, err)
+				}
+			}
+
+			if viper.GetString("audit-log") == "" {
+				return errors.New("audit-log must be specified")
+			}
+
+			if err := runHistoryCommand(); err != nil {
+				return fmt.Errorf("history: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("audit-log", "", "Path to the audit log written by the push and prune commands")
+	cmd.Flags().String("image", "", "Glob pattern that the source or target image must match (e.g. *busybox:*)")
+	cmd.Flags().String("operation", "", "Only print the given operation (push or delete)")
+	cmd.Flags().String("user", "", "Only print the operations of the given user")
+	cmd.Flags().Duration("since", 0, "Only print the operations of the given duration before now (e.g. 168h)")
+	cmd.Flags().Bool("failed", false, "Only print the operations that failed")
+	cmd.Flags().String("format", "table", "Format to output the operations in (table or json)")
+
+	return &cmd
+}
+
+// historyFilter selects the entries of the audit log that are printed.
+type historyFilter struct {
+	image     *regexp.Regexp
+	operation string
+	user      string
+	since     time.Time
+	failed    bool
+}
+
+func (f historyFilter) matches(entry auditEntry) bool {
+	if f.image != nil && !f.image.MatchString(entry.Source) && !f.image.MatchString(entry.Target) {
+		return false
+	}
+
+	if f.operation != "" && entry.Operation != f.operation {
+		return false
+	}
+
+	if f.user != "" && entry.User != f.user {
+		return false
+	}
+
+	if !f.since.IsZero() && entry.Time.Before(f.since) {
+		return false
+	}
+
+	return !f.failed || entry.Error != ""
+}
+
+func runHistoryCommand() error {
+	format := viper.GetString("format")
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %s", format)
+	}
+
+	filter := historyFilter{
+		operation: viper.GetString("operation"),
+		user:      viper.GetString("user"),
+		failed:    viper.GetBool("failed"),
+	}
+
+	if viper.GetString("image") != "" {
+		pattern, err := manifest.CompileGlob(viper.GetString("image"))
+		if err != nil {
+			return fmt.Errorf("compile image glob: %w", err)
+		}
+
+		filter.image = pattern
+	}
+
+	if viper.GetDuration("since") > 0 {
+		filter.since = time.Now().Add(-viper.GetDuration("since"))
+	}
+
+	entries, err := readAuditLog(viper.GetString("audit-log"))
+	if err != nil {
+		return fmt.Errorf("read audit log: %w", err)
+	}
+
+	var matchingEntries []auditEntry
+	for _, entry := range entries {
+		if filter.matches(entry) {
+			matchingEntries = append(matchingEntries, entry)
+		}
+	}
+
+	if format == "json" {
+		return writeHistoryJSON(os.Stdout, matchingEntries)
+	}
+
+	return writeHistoryTable(os.Stdout, matchingEntries)
+}
+
+// writeHistoryJSON writes one object per line, in the same format as the audit log.
+func writeHistoryJSON(w io.Writer, entries []auditEntry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("encode entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func writeHistoryTable(w io.Writer, entries []auditEntry) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, "TIME\tOPERATION\tTARGET\tSOURCE\tDIGEST\tUSER\tSTATUS"); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, entry := range entries {
+		status := "succeeded"
+		if entry.Error != "" {
+			status = "failed: " + entry.Error
+		}
+
+		row := []string{
+			entry.Time.UTC().Format(time.RFC3339),
+			entry.Operation,
+			entry.Target,
+			valueOrDash(entry.Source),
+			valueOrDash(entry.Digest),
+			entry.User,
+			status,
+		}
+
+		if _, err := fmt.Fprintln(table, strings.Join(row, "\t")); err != nil {
+			return fmt.Errorf("write entry: %w", err)
+		}
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
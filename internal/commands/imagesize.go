@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/viper"
+)
+
+// getMaxImageSize returns the maximum compressed size of an image in bytes, and the size as it was set (e.g. 5GiB),
+// which is set by the max-image-size flag, or by the maxImageSize of the policy of the manifest when the flag
+// is not set. A maximum size of zero allows images of any size.
+func getMaxImageSize(manifestPath string) (int64, string, error) {
+	maxImageSize := viper.GetString("max-image-size")
+	if maxImageSize == "" && !hasInputImages() {
+		imageManifest, err := getManifest(manifestPath)
+		if err != nil {
+			return 0, "", fmt.Errorf("get manifest: %w", err)
+		}
+
+		maxImageSize = imageManifest.Policy.MaxImageSize
+	}
+
+	if maxImageSize == "" {
+		return 0, "", nil
+	}
+
+	size, err := docker.ParseSize(maxImageSize)
+	if err != nil {
+		return 0, "", fmt.Errorf("parse max image size: %w", err)
+	}
+
+	return size, maxImageSize, nil
+}
+
+// getImageSize returns the compressed size of the image, which is the sum of the sizes of its manifests, configs and
+// layers. The size of a multi-arch image is restricted to the given platforms in the same way as copying the image is.
+func getImageSize(ctx context.Context, client docker.Client, image string, auth string, platforms []string) (int64, error) {
+	blobs, err := client.GetBlobs(ctx, image, auth, platforms)
+	if err != nil {
+		return 0, fmt.Errorf("get blobs: %w", err)
+	}
+
+	var size int64
+	for _, blob := range blobs {
+		size += blob.Size
+	}
+
+	return size, nil
+}
+
+// getSourceSize returns the compressed size of the image of the source as it would be pushed to the target.
+// The size of an assembled source is the sum of the sizes of the images of its platforms.
+func getSourceSize(ctx context.Context, client docker.Client, source manifest.Source, sourceAuth string, platforms []string) (int64, error) {
+	// The platforms of artifacts are not filtered when they are pushed.
+	if source.IsArtifact() {
+		platforms = nil
+	}
+
+	if !source.IsAssembled() {
+		return getImageSize(ctx, client, source.PullImage(), sourceAuth, platforms)
+	}
+
+	var size int64
+	for _, platformImage := range source.PlatformImages {
+		platformSource := source.PlatformSource(platformImage)
+		platformSize, err := getImageSize(ctx, client, platformSource.PullImage(), sourceAuth, []string{platformImage.Platform})
+		if err != nil {
+			return 0, fmt.Errorf("get size of %s: %w", platformSource.PullImage(), err)
+		}
+
+		size += platformSize
+	}
+
+	return size, nil
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestGetSourceSize(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	image, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	reference, err := name.ParseReference(host+"/source/app:1.0", name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(reference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	imageManifest, err := image.Manifest()
+	if err != nil {
+		t.Fatal("manifest:", err)
+	}
+
+	rawManifest, err := image.RawManifest()
+	if err != nil {
+		t.Fatal("raw manifest:", err)
+	}
+
+	expected := int64(len(rawManifest)) + imageManifest.Config.Size
+	for _, layer := range imageManifest.Layers {
+		expected += layer.Size
+	}
+
+	client, err := docker.NewClient(t.Logf)
+	if err != nil {
+		t.Fatal("new client:", err)
+	}
+
+	source := manifest.Source{Host: host, Repository: "source/app", Tag: "1.0"}
+	actual, err := getSourceSize(context.Background(), client, source, "", nil)
+	if err != nil {
+		t.Fatal("get source size:", err)
+	}
+
+	if actual != expected {
+		t.Errorf("expected size %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,213 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/internal/scan"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newInspectCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "inspect <image>...",
+		Short: "Print the labels, entrypoint, exposed ports and layers of images without pulling them",
+		Args:  cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("format", cmd.Flags().Lookup("format")); err != nil {
+				return fmt.Errorf("bind format flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platform", cmd.Flags().Lookup("platform")); err != nil {
+				return fmt.Errorf("bind platform flag: %w", err)
+			}
+
+			if err := runInspectCommand(cmd.Context(), args); err != nil {
+				return fmt.Errorf("inspect: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("format", "table", "Format to output the metadata of the images in (table or json)")
+	cmd.Flags().String("platform", "linux/amd64", "Platform to inspect of multi-arch images")
+
+	return &cmd
+}
+
+func runInspectCommand(ctx context.Context, images []string) error {
+	ctx, cancel := withCommandTimeout(ctx, 0)
+	defer cancel()
+
+	sources, err := manifest.GetSourcesFromImages(images, "", getRegistryHostsOption())
+	if err != nil {
+		return fmt.Errorf("get sources from images: %w", err)
+	}
+
+	var auths []string
+	for _, source := range sources {
+		auth, err := getSourceAuth(source)
+		if err != nil {
+			return fmt.Errorf("get source auth: %w", err)
+		}
+
+		auths = append(auths, auth)
+	}
+
+	if err := inspectImages(ctx, images, auths, nil); err != nil {
+		return fmt.Errorf("inspect images: %w", err)
+	}
+
+	return nil
+}
+
+// inspectedImage is the metadata of an inspected image, with the number of its known vulnerabilities when it was scanned.
+type inspectedImage struct {
+	docker.ImageMetadata
+	Vulnerabilities *vulnerabilityCounts `json:"vulnerabilities,omitempty"`
+}
+
+// vulnerabilityCounts are the numbers of the most severe known vulnerabilities of an image.
+type vulnerabilityCounts struct {
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+}
+
+// inspectImages writes the metadata of the images, which are accessed with the auth at the same index, to stdout
+// in the format of the format flag. The images are inspected at the same time, but written in the given order.
+// When scan options are given, each image is also scanned for vulnerabilities, which are counted in its metadata.
+func inspectImages(ctx context.Context, images []string, auths []string, scanOptions *scan.Options) error {
+	format := viper.GetString("format")
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unknown format %s", format)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	metadata := make([]inspectedImage, len(images))
+	inspect := func(i int) error {
+		imageMetadata, err := client.InspectImage(ctx, images[i], auths[i], viper.GetString("platform"))
+		if err != nil {
+			return fmt.Errorf("inspect %s: %w", images[i], err)
+		}
+
+		metadata[i] = inspectedImage{ImageMetadata: imageMetadata}
+		if scanOptions == nil {
+			return nil
+		}
+
+		vulnerabilities, err := scan.ScanImage(ctx, images[i], *scanOptions)
+		if err != nil {
+			return fmt.Errorf("scan %s: %w", images[i], err)
+		}
+
+		counts := scan.CountBySeverity(vulnerabilities)
+		metadata[i].Vulnerabilities = &vulnerabilityCounts{Critical: counts["CRITICAL"], High: counts["HIGH"]}
+		return nil
+	}
+
+	if err := runJobs(ctx, 10, len(images), inspect); err != nil {
+		return err
+	}
+
+	if format == "json" {
+		return writeImageMetadataJSON(os.Stdout, metadata)
+	}
+
+	return writeImageMetadataTable(os.Stdout, metadata)
+}
+
+// writeImageMetadataJSON writes one object per line so that the output can be streamed into tools such as jq.
+func writeImageMetadataJSON(w io.Writer, metadata []inspectedImage) error {
+	encoder := json.NewEncoder(w)
+	for _, imageMetadata := range metadata {
+		if err := encoder.Encode(imageMetadata); err != nil {
+			return fmt.Errorf("encode metadata: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeImageMetadataTable writes the metadata as a table, which includes the CRITICAL and HIGH vulnerabilities
+// of the images when they were scanned.
+func writeImageMetadataTable(w io.Writer, metadata []inspectedImage) error {
+	var scanned bool
+	for _, imageMetadata := range metadata {
+		if imageMetadata.Vulnerabilities != nil {
+			scanned = true
+		}
+	}
+
+	header := []string{"IMAGE", "CREATED", "LAYERS", "ENTRYPOINT", "PORTS"}
+	if scanned {
+		header = append(header, "CRITICAL", "HIGH")
+	}
+	header = append(header, "LABELS")
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, strings.Join(header, "\t")); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, imageMetadata := range metadata {
+		created := "-"
+		if !imageMetadata.Created.IsZero() {
+			created = imageMetadata.Created.UTC().Format("2006-01-02")
+		}
+
+		var labels []string
+		for key, value := range imageMetadata.Labels {
+			labels = append(labels, key+"="+value)
+		}
+		sort.Strings(labels)
+
+		row := []string{
+			imageMetadata.Image,
+			created,
+			fmt.Sprint(imageMetadata.Layers),
+			valueOrDash(strings.Join(imageMetadata.Entrypoint, " ")),
+			valueOrDash(strings.Join(imageMetadata.ExposedPorts, ",")),
+		}
+
+		if scanned && imageMetadata.Vulnerabilities != nil {
+			row = append(row, fmt.Sprint(imageMetadata.Vulnerabilities.Critical), fmt.Sprint(imageMetadata.Vulnerabilities.High))
+		} else if scanned {
+			row = append(row, "-", "-")
+		}
+		row = append(row, valueOrDash(strings.Join(labels, ",")))
+
+		if _, err := fmt.Fprintln(table, strings.Join(row, "\t")); err != nil {
+			return fmt.Errorf("write image: %w", err)
+		}
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
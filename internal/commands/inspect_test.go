@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+)
+
+func TestWriteImageMetadataTable(t *testing.T) {
+	metadata := []inspectedImage{
+		{
+			ImageMetadata: docker.ImageMetadata{
+				Image:        "quay.io/coreos/prometheus-operator:v0.40.0",
+				Created:      time.Date(2020, 6, 30, 12, 0, 0, 0, time.UTC),
+				Labels:       map[string]string{"b": "2", "a": "1"},
+				Entrypoint:   []string{"/bin/operator", "--help"},
+				ExposedPorts: []string{"8080/tcp"},
+				Layers:       4,
+			},
+		},
+		{
+			ImageMetadata: docker.ImageMetadata{
+				Image:  "busybox:1.32.0",
+				Layers: 1,
+			},
+		},
+	}
+
+	var output bytes.Buffer
+	if err := writeImageMetadataTable(&output, metadata); err != nil {
+		t.Fatal("write image metadata table:", err)
+	}
+
+	expected := `IMAGE                                       CREATED     LAYERS  ENTRYPOINT            PORTS     LABELS
+quay.io/coreos/prometheus-operator:v0.40.0  2020-06-30  4       /bin/operator --help  8080/tcp  a=1,b=2
+busybox:1.32.0                              -           1       -                     -         -
+`
+
+	if output.String() != expected {
+		t.Errorf("expected table\n%s\nactual\n%s", expected, output.String())
+	}
+}
+
+func TestWriteImageMetadata_Vulnerabilities(t *testing.T) {
+	metadata := []inspectedImage{
+		{
+			ImageMetadata:   docker.ImageMetadata{Image: "busybox:1.32.0", Layers: 1},
+			Vulnerabilities: &vulnerabilityCounts{Critical: 1, High: 3},
+		},
+	}
+
+	var table bytes.Buffer
+	if err := writeImageMetadataTable(&table, metadata); err != nil {
+		t.Fatal("write image metadata table:", err)
+	}
+
+	expectedTable := `IMAGE           CREATED  LAYERS  ENTRYPOINT  PORTS  CRITICAL  HIGH  LABELS
+busybox:1.32.0  -        1       -           -      1         3     -
+`
+
+	if table.String() != expectedTable {
+		t.Errorf("expected table\n%s\nactual\n%s", expectedTable, table.String())
+	}
+
+	var output bytes.Buffer
+	if err := writeImageMetadataJSON(&output, metadata); err != nil {
+		t.Fatal("write image metadata json:", err)
+	}
+
+	expectedJSON := `{"image":"busybox:1.32.0","digest":"","created":"0001-01-01T00:00:00Z","layers":1,"vulnerabilities":{"critical":1,"high":3}}` + "\n"
+	if output.String() != expectedJSON {
+		t.Errorf("expected json %s, actual %s", expectedJSON, output.String())
+	}
+}
@@ -0,0 +1,328 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/docker/docker/pkg/term"
+	log "github.com/sirupsen/logrus"
+)
+
+// interactiveSizeJobs is the number of images whose sizes are queried at the same time before they are selected.
+const interactiveSizeJobs = 10
+
+// selectableImage is an image that can be selected to be pushed. Images that already exist
+// at the target can be selected as well, which pushes them again.
+type selectableImage struct {
+	source   manifest.Source
+	exists   bool
+	size     int64
+	selected bool
+}
+
+// selectionKey is a key that is pressed while selecting the images.
+type selectionKey int
+
+const (
+	keyUnknown selectionKey = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyToggle
+	keyAll
+	keyNone
+	keyMissing
+	keyConfirm
+	keyCancel
+)
+
+// imageSelection is the state of the list of images that are being selected. Only the images
+// that fit in the height of the terminal are shown, scrolling to keep the cursor in view.
+type imageSelection struct {
+	images []selectableImage
+	cursor int
+	offset int
+	height int
+}
+
+// selectImages shows the images in the terminal and returns the sources of the images that were selected.
+// The images that are missing from the target are selected to begin with. The second return value is
+// false when the selection was canceled.
+func selectImages(ctx context.Context, client docker.Client, images []selectableImage, platforms []string) ([]manifest.Source, bool, error) {
+	log.Infof("Querying the sizes of %v image(s) ...", len(images))
+	getImageSizes(ctx, client, images, platforms)
+
+	fd, _ := term.GetFdInfo(os.Stdin)
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, false, fmt.Errorf("make raw terminal: %w", err)
+	}
+	defer term.RestoreTerminal(fd, state)
+
+	// The header and the footer of the list take up four lines.
+	height := 20
+	if size, err := term.GetWinsize(fd); err == nil && size.Height > 5 {
+		height = int(size.Height) - 4
+	}
+
+	selection := newImageSelection(images, height)
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		if err := selection.render(newRawWriter(os.Stderr)); err != nil {
+			return nil, false, fmt.Errorf("render: %w", err)
+		}
+
+		key, err := readSelectionKey(reader)
+		if err != nil {
+			return nil, false, fmt.Errorf("read key: %w", err)
+		}
+
+		switch key {
+		case keyConfirm:
+			fmt.Fprint(os.Stderr, "\033[H\033[2J")
+			return selection.selectedSources(), true, nil
+
+		case keyCancel:
+			fmt.Fprint(os.Stderr, "\033[H\033[2J")
+			return nil, false, nil
+		}
+
+		selection.handleKey(key)
+	}
+}
+
+// getImageSizes sets the size of each image to the size of the blobs of its source, or to -1
+// when its size cannot be queried, so that an image whose registry is unreachable can be deselected.
+func getImageSizes(ctx context.Context, client docker.Client, images []selectableImage, platforms []string) {
+	query := func(i int) error {
+		images[i].size = -1
+
+		source := images[i].source
+		sourceAuth, err := getSourceAuth(source)
+		if err != nil {
+			log.Debugf("Unable to get the auth of %s: %v", source.Image(), err)
+			return nil
+		}
+
+		size, err := getSourceSize(ctx, client, source, sourceAuth, platforms)
+		if err != nil {
+			log.Debugf("Unable to get the size of %s: %v", source.Image(), err)
+			return nil
+		}
+
+		images[i].size = size
+		return nil
+	}
+
+	runJobs(ctx, interactiveSizeJobs, len(images), query)
+}
+
+func newImageSelection(images []selectableImage, height int) *imageSelection {
+	if height < 1 {
+		height = 1
+	}
+
+	return &imageSelection{images: images, height: height}
+}
+
+// handleKey moves the cursor or changes which images are selected.
+func (s *imageSelection) handleKey(key selectionKey) {
+	switch key {
+	case keyUp:
+		s.moveCursor(-1)
+	case keyDown:
+		s.moveCursor(1)
+	case keyPageUp:
+		s.moveCursor(-s.height)
+	case keyPageDown:
+		s.moveCursor(s.height)
+	case keyToggle:
+		if len(s.images) > 0 {
+			s.images[s.cursor].selected = !s.images[s.cursor].selected
+		}
+	case keyAll, keyNone, keyMissing:
+		for i := range s.images {
+			s.images[i].selected = key == keyAll || (key == keyMissing && !s.images[i].exists)
+		}
+	}
+}
+
+func (s *imageSelection) moveCursor(rows int) {
+	s.cursor += rows
+	if s.cursor >= len(s.images) {
+		s.cursor = len(s.images) - 1
+	}
+	if s.cursor < 0 {
+		s.cursor = 0
+	}
+
+	if s.cursor < s.offset {
+		s.offset = s.cursor
+	}
+	if s.cursor >= s.offset+s.height {
+		s.offset = s.cursor - s.height + 1
+	}
+}
+
+// selectedSources returns the sources of the selected images, in the order of the manifest.
+func (s *imageSelection) selectedSources() []manifest.Source {
+	var sources []manifest.Source
+	for _, image := range s.images {
+		if image.selected {
+			sources = append(sources, image.source)
+		}
+	}
+
+	return sources
+}
+
+// render writes the visible images along with how many images are selected. The columns are aligned
+// across every image rather than only the visible ones, so that they do not move while scrolling.
+func (s *imageSelection) render(w io.Writer) error {
+	var rows bytes.Buffer
+	table := tabwriter.NewWriter(&rows, 0, 0, 2, ' ', 0)
+
+	var selectedCount int
+	var selectedSize int64
+	for i, image := range s.images {
+		cursor := " "
+		if i == s.cursor {
+			cursor = ">"
+		}
+
+		checkbox := "[ ]"
+		if image.selected {
+			checkbox = "[x]"
+			selectedCount++
+			if image.size > 0 {
+				selectedSize += image.size
+			}
+		}
+
+		status := "missing"
+		if image.exists {
+			status = "exists"
+		}
+
+		size := "-"
+		if image.size >= 0 {
+			size = formatBytes(uint64(image.size))
+		}
+
+		fmt.Fprintf(table, "%s %s %s\t%s\t%s\t%s\n", cursor, checkbox, image.source.Image(), image.source.TargetImage(), status, size)
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	lines := strings.SplitAfter(rows.String(), "\n")
+	end := s.offset + s.height
+	if end > len(s.images) {
+		end = len(s.images)
+	}
+
+	var screen strings.Builder
+	screen.WriteString("\033[H\033[2J")
+	screen.WriteString("Select the images to push (up/down to move, space to select, a for all, n for none, m for missing, enter to push, q to cancel)\n\n")
+	screen.WriteString(strings.Join(lines[s.offset:end], ""))
+	screen.WriteString(fmt.Sprintf("\n%v of %v image(s) selected, %s\n", selectedCount, len(s.images), formatBytes(uint64(selectedSize))))
+
+	if _, err := io.WriteString(w, screen.String()); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	return nil
+}
+
+// readSelectionKey reads the next key that is pressed. The arrow and page keys are
+// read from their escape sequences, while the other keys are single characters.
+func readSelectionKey(reader *bufio.Reader) (selectionKey, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return keyUnknown, err
+	}
+
+	switch b {
+	case 'k':
+		return keyUp, nil
+	case 'j':
+		return keyDown, nil
+	case ' ', 'x':
+		return keyToggle, nil
+	case 'a':
+		return keyAll, nil
+	case 'n':
+		return keyNone, nil
+	case 'm':
+		return keyMissing, nil
+	case '\r', '\n':
+		return keyConfirm, nil
+	case 'q', 3:
+		return keyCancel, nil
+	case 27:
+		// A single escape without a sequence following it is the escape key itself.
+		if reader.Buffered() == 0 {
+			return keyCancel, nil
+		}
+	default:
+		return keyUnknown, nil
+	}
+
+	sequence, err := reader.ReadByte()
+	if err != nil || sequence != '[' {
+		return keyUnknown, err
+	}
+
+	code, err := reader.ReadByte()
+	if err != nil {
+		return keyUnknown, err
+	}
+
+	switch code {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case '5', '6':
+		if _, err := reader.ReadByte(); err != nil {
+			return keyUnknown, err
+		}
+
+		if code == '5' {
+			return keyPageUp, nil
+		}
+
+		return keyPageDown, nil
+	}
+
+	return keyUnknown, nil
+}
+
+// rawWriter writes to a terminal in raw mode, which does not return the
+// cursor to the start of the line when the line ends.
+type rawWriter struct {
+	w io.Writer
+}
+
+func newRawWriter(w io.Writer) rawWriter {
+	return rawWriter{w: w}
+}
+
+func (r rawWriter) Write(p []byte) (int, error) {
+	if _, err := r.w.Write(bytes.Replace(p, []byte("\n"), []byte("\r\n"), -1)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+)
+
+func TestImageSelection(t *testing.T) {
+	target := manifest.Target{Host: "mycr.azurecr.io"}
+	images := []selectableImage{
+		{source: manifest.Source{Repository: "busybox", Tag: "1.32.0", Target: target}, size: 764619, selected: true},
+		{source: manifest.Source{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: target}, exists: true, size: 14000000},
+		{source: manifest.Source{Repository: "jimmidyson/configmap-reload", Tag: "v0.3.0", Target: target}, size: -1, selected: true},
+	}
+
+	selection := newImageSelection(images, 2)
+
+	// The cursor moves to the last image and scrolls the first image out of view.
+	selection.handleKey(keyPageDown)
+	selection.handleKey(keyToggle)
+	selection.handleKey(keyUp)
+	selection.handleKey(keyToggle)
+
+	var actual bytes.Buffer
+	if err := selection.render(&actual); err != nil {
+		t.Fatal("render:", err)
+	}
+
+	expected := "\033[H\033[2J" + `Select the images to push (up/down to move, space to select, a for all, n for none, m for missing, enter to push, q to cancel)
+
+> [x] quay.io/coreos/prometheus-operator:v0.40.0  mycr.azurecr.io/coreos/prometheus-operator:v0.40.0  exists   14.0 MB
+  [ ] jimmidyson/configmap-reload:v0.3.0          mycr.azurecr.io/jimmidyson/configmap-reload:v0.3.0  missing  -
+
+2 of 3 image(s) selected, 14.8 MB
+`
+
+	if actual.String() != expected {
+		t.Errorf("expected screen\n%s\nactual\n%s", expected, actual.String())
+	}
+
+	selection.handleKey(keyMissing)
+
+	sources := selection.selectedSources()
+	if len(sources) != 2 || sources[0].Repository != "busybox" || sources[1].Repository != "jimmidyson/configmap-reload" {
+		t.Errorf("expected the missing images to be selected, actual %v", sources)
+	}
+
+	selection.handleKey(keyNone)
+	if len(selection.selectedSources()) != 0 {
+		t.Error("expected no images to be selected")
+	}
+}
+
+func TestReadSelectionKey(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("\033[A\033[Bj \033[6~a\rq"))
+
+	expected := []selectionKey{keyUp, keyDown, keyDown, keyToggle, keyPageDown, keyAll, keyConfirm, keyCancel}
+	for _, expectedKey := range expected {
+		key, err := readSelectionKey(reader)
+		if err != nil {
+			t.Fatal("read selection key:", err)
+		}
+
+		if key != expectedKey {
+			t.Errorf("expected key %v, actual %v", expectedKey, key)
+		}
+	}
+}
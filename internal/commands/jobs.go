@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// jobErrors is a collection of errors that occurred while running jobs.
+type jobErrors []error
+
+func (e jobErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("%v job(s) failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Is reports whether any of the errors matches the target.
+func (e jobErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runJobs calls the job function once for every index from zero to count, running at most
+// the given number of jobs at the same time. A failing job does not stop any of the other
+// jobs from running, all of the errors are collected and returned once every job has completed.
+// When the context is cancelled, no more jobs are started and the jobs that are running are waited for.
+func runJobs(ctx context.Context, jobs int, count int, job func(i int) error) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+
+		for i := 0; i < count; i++ {
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mutex sync.Mutex
+	var errs jobErrors
+
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				if err := job(i); err != nil {
+					mutex.Lock()
+					errs = append(errs, err)
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		errs = append(errs, ctx.Err())
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
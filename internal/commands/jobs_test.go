@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunJobs(t *testing.T) {
+	var completed int32
+	job := func(i int) error {
+		atomic.AddInt32(&completed, 1)
+
+		if i%2 == 0 {
+			return errors.New("failed")
+		}
+
+		return nil
+	}
+
+	err := runJobs(context.Background(), 3, 10, job)
+
+	if completed != 10 {
+		t.Errorf("expected 10 jobs to complete, actual %v", completed)
+	}
+
+	var errs jobErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected job errors, actual %v", err)
+	}
+
+	if len(errs) != 5 {
+		t.Errorf("expected 5 errors, actual %v", len(errs))
+	}
+}
+
+func TestRunJobs_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var completed int32
+	job := func(i int) error {
+		atomic.AddInt32(&completed, 1)
+		cancel()
+
+		return nil
+	}
+
+	err := runJobs(ctx, 1, 10, job)
+
+	if completed != 1 {
+		t.Errorf("expected 1 job to complete, actual %v", completed)
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled error, actual %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newLintCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "lint <source>...",
+		Short: "Check that the images referenced by the Kubernetes resources at the sources follow the policy",
+		Args:  cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("forbid-latest", cmd.Flags().Lookup("forbid-latest")); err != nil {
+				return fmt.Errorf("bind forbid-latest flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("require-digest", cmd.Flags().Lookup("require-digest")); err != nil {
+				return fmt.Errorf("bind require-digest flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("allowed-registries", cmd.Flags().Lookup("allowed-registries")); err != nil {
+				return fmt.Errorf("bind allowed-registries flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("tag-pattern", cmd.Flags().Lookup("tag-pattern")); err != nil {
+				return fmt.Errorf("bind tag-pattern flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm", cmd.Flags().Lookup("helm")); err != nil {
+				return fmt.Errorf("bind helm flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm-values", cmd.Flags().Lookup("helm-values")); err != nil {
+				return fmt.Errorf("bind helm-values flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kustomize", cmd.Flags().Lookup("kustomize")); err != nil {
+				return fmt.Errorf("bind kustomize flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images", cmd.Flags().Lookup("env-images")); err != nil {
+				return fmt.Errorf("bind env-images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images-pattern", cmd.Flags().Lookup("env-images-pattern")); err != nil {
+				return fmt.Errorf("bind env-images-pattern flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("crd-config", cmd.Flags().Lookup("crd-config")); err != nil {
+				return fmt.Errorf("bind crd-config flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("deep-scan", cmd.Flags().Lookup("deep-scan")); err != nil {
+				return fmt.Errorf("bind deep-scan flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dockerfiles", cmd.Flags().Lookup("dockerfiles")); err != nil {
+				return fmt.Errorf("bind dockerfiles flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("ci-files", cmd.Flags().Lookup("ci-files")); err != nil {
+				return fmt.Errorf("bind ci-files flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("terraform", cmd.Flags().Lookup("terraform")); err != nil {
+				return fmt.Errorf("bind terraform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-sources", cmd.Flags().Lookup("follow-sources")); err != nil {
+				return fmt.Errorf("bind follow-sources flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("skip-dirs", cmd.Flags().Lookup("skip-dirs")); err != nil {
+				return fmt.Errorf("bind skip-dirs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-gitignore", cmd.Flags().Lookup("no-gitignore")); err != nil {
+				return fmt.Errorf("bind no-gitignore flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-symlinks", cmd.Flags().Lookup("follow-symlinks")); err != nil {
+				return fmt.Errorf("bind follow-symlinks flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kinds", cmd.Flags().Lookup("kinds")); err != nil {
+				return fmt.Errorf("bind kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-kinds", cmd.Flags().Lookup("exclude-kinds")); err != nil {
+				return fmt.Errorf("bind exclude-kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("selector", cmd.Flags().Lookup("selector")); err != nil {
+				return fmt.Errorf("bind selector flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-document-size", cmd.Flags().Lookup("max-document-size")); err != nil {
+				return fmt.Errorf("bind max-document-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("warn-on-parse-error", cmd.Flags().Lookup("warn-on-parse-error")); err != nil {
+				return fmt.Errorf("bind warn-on-parse-error flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("template-defaults", cmd.Flags().Lookup("template-defaults")); err != nil {
+				return fmt.Errorf("bind template-defaults flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("fail-on", cmd.Flags().Lookup("fail-on")); err != nil {
+				return fmt.Errorf("bind fail-on flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("fail-threshold", cmd.Flags().Lookup("fail-threshold")); err != nil {
+				return fmt.Errorf("bind fail-threshold flag: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runLintCommand(cmd.Context(), args, manifestPath); err != nil {
+				return fmt.Errorf("lint: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("forbid-latest", false, "Forbid images that use the latest tag, including images without a tag")
+	cmd.Flags().Bool("require-digest", false, "Require every image to be referenced by its digest")
+	cmd.Flags().StringSlice("allowed-registries", []string{}, "Registries that images may be sourced from (e.g. quay.io,mycompany.com/myteam)")
+	cmd.Flags().String("tag-pattern", "", "Regular expression that the tag of every image must match (e.g. v?\\d+\\.\\d+\\.\\d+)")
+	cmd.Flags().Bool("helm", false, "Render Helm charts found at the source before finding images (requires helm)")
+	cmd.Flags().StringSlice("helm-values", []string{}, "Values files to use when rendering Helm charts")
+	cmd.Flags().Bool("kustomize", false, "Build kustomizations found at the source before finding images (requires kustomize)")
+	cmd.Flags().Bool("env-images", false, "Find images in the values of container environment variables")
+	cmd.Flags().String("env-images-pattern", "IMAGE", "Regular expression that environment variable names must match to be searched for images")
+	cmd.Flags().String("crd-config", "", "Path to a file that declares where images are located in custom resources")
+	cmd.Flags().Bool("deep-scan", false, "Also find image references in any value of any resource (e.g. ConfigMap data), which may include false positives")
+	cmd.Flags().Bool("dockerfiles", false, "Also find the base images in the FROM instructions of Dockerfiles")
+	cmd.Flags().Bool("ci-files", false, "Also find the images of the jobs in GitHub Actions workflows and GitLab CI files")
+	cmd.Flags().Bool("terraform", false, "Also find the images set in Terraform files by the Kubernetes and Helm providers")
+	cmd.Flags().Bool("follow-sources", false, "Also find the images deployed by Argo CD Applications and Flux HelmReleases and Kustomizations by fetching their sources (requires git and helm)")
+	cmd.Flags().StringSlice("skip-dirs", []string{}, "Directories to skip when finding images, by name or by path relative to the source (e.g. testdata,charts/*/tests)")
+	cmd.Flags().Bool("no-gitignore", false, "Also find images in files and directories that are ignored by .gitignore files")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symbolic links to directories when finding images")
+	cmd.Flags().StringSlice("kinds", []string{}, "Only find images in the resources of the given kinds (e.g. Deployment,CronJob)")
+	cmd.Flags().StringSlice("exclude-kinds", []string{}, "Skip the resources of the given kinds when finding images (e.g. ConfigMap)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Only find images in the resources in the given namespaces")
+	cmd.Flags().StringP("selector", "l", "", "Only find images in the resources whose labels match the selector (e.g. app.kubernetes.io/part-of=platform)")
+	cmd.Flags().String("max-document-size", "16MiB", "Maximum size of a YAML document (e.g. 64MiB), larger documents are skipped (0 does not limit the size)")
+	cmd.Flags().Bool("warn-on-parse-error", true, "Warn about YAML files that cannot be parsed, which are otherwise skipped")
+	cmd.Flags().Bool("template-defaults", false, "Find the images of unrendered Helm templates on a best-effort basis with the default values of their chart")
+	cmd.Flags().StringSlice("fail-on", []string{failOnViolations}, "Kinds of images that cause the command to fail (violations, untagged, latest-tag or none)")
+	cmd.Flags().Int("fail-threshold", 0, "Number of images (or violations) of each kind in fail-on that are allowed before the command fails")
+
+	return &cmd
+}
+
+func runLintCommand(ctx context.Context, paths []string, manifestPath string) error {
+	failurePolicy, err := getFailurePolicy(failOnViolations, failOnUntagged, failOnLatestTag)
+	if err != nil {
+		return fmt.Errorf("get failure policy: %w", err)
+	}
+
+	policy, err := getPolicy(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get policy: %w", err)
+	}
+
+	opts, err := getAutodetectOptions(ctx)
+	if err != nil {
+		return fmt.Errorf("get autodetect options: %w", err)
+	}
+
+	foundImages, err := images.FindImagesInPaths(paths, opts...)
+	if err != nil {
+		return fmt.Errorf("find images: %w", err)
+	}
+
+	violations, err := writeViolations(os.Stdout, foundImages, policy)
+	if err != nil {
+		return fmt.Errorf("write violations: %w", err)
+	}
+
+	counts := countImageKinds(foundImages)
+	counts[failOnViolations] = violations
+	if err := failurePolicy.check(counts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getPolicy returns the policy in the manifest, if any, with the rules
+// that were passed in as flags (or set in the config file) added to it.
+func getPolicy(manifestPath string) (manifest.Policy, error) {
+	imageManifest, err := getManifest(manifestPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return manifest.Policy{}, fmt.Errorf("get manifest: %w", err)
+	}
+
+	policy := imageManifest.Policy
+
+	if viper.GetBool("forbid-latest") {
+		policy.ForbidLatest = true
+	}
+
+	if viper.GetBool("require-digest") {
+		policy.RequireDigest = true
+	}
+
+	if len(viper.GetStringSlice("allowed-registries")) > 0 {
+		policy.AllowedRegistries = viper.GetStringSlice("allowed-registries")
+	}
+
+	if viper.GetString("tag-pattern") != "" {
+		policy.TagPattern = viper.GetString("tag-pattern")
+	}
+
+	return policy, nil
+}
+
+// writeViolations writes a line for every resource that references an image that violates
+// the policy, in the form of path:line: image: message. Returns the number of violations.
+func writeViolations(w io.Writer, foundImages []images.Image, policy manifest.Policy) (int, error) {
+	var count int
+	for _, image := range foundImages {
+		violations, err := policy.Check(image)
+		if err != nil {
+			return 0, fmt.Errorf("check %s: %w", image, err)
+		}
+
+		for _, resource := range image.Resources {
+			for _, violation := range violations {
+				if _, err := fmt.Fprintf(w, "%s: %s (%s/%s): %s [%s]\n", resource.Location(), image, resource.Kind, resource.Name, violation.Message, violation.Rule); err != nil {
+					return 0, fmt.Errorf("write: %w", err)
+				}
+
+				count++
+			}
+		}
+	}
+
+	return count, nil
+}
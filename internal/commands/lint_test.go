@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+func TestWriteViolations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "pod.yaml")
+	contents := []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  containers:
+  - name: app
+    image: quay.io/coreos/prometheus-operator:v0.40.0
+  - name: busybox
+    image: busybox
+`)
+
+	if err := ioutil.WriteFile(path, contents, os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	foundImages, err := images.FindImages(path)
+	if err != nil {
+		t.Fatal("find images:", err)
+	}
+
+	policy := manifest.Policy{
+		ForbidLatest: true,
+	}
+
+	var actual bytes.Buffer
+	count, err := writeViolations(&actual, foundImages, policy)
+	if err != nil {
+		t.Fatal("write violations:", err)
+	}
+
+	expected := path + ":10: busybox (Pod/app): image uses the latest tag [forbid-latest]\n"
+	if count != 1 || actual.String() != expected {
+		t.Errorf("expected 1 violation %q, actual %v violation(s) %q", expected, count, actual.String())
+	}
+}
@@ -1,30 +1,169 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/internal/metrics"
+	"github.com/plexsystems/sinker/internal/scan"
+	"github.com/plexsystems/sinker/pkg/images"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 func newListCommand() *cobra.Command {
+	var deprecateErr error
 	cmd := cobra.Command{
 		Use:       "list <source|target>",
 		Short:     "List the images found in the manifest",
-		Args:      cobra.ExactValidArgs(1),
 		ValidArgs: []string{"source", "target"},
 
+		Args: func(cmd *cobra.Command, args []string) error {
+			if cluster, _ := cmd.Flags().GetBool("cluster"); cluster {
+				return cobra.NoArgs(cmd, args)
+			}
+
+			return cobra.ExactValidArgs(1)(cmd, args)
+		},
+
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if deprecateErr != nil {
+				return fmt.Errorf("deprecate force flag: %w", deprecateErr)
+			}
+
 			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
 				return fmt.Errorf("bind output flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("append", cmd.Flags().Lookup("append")); err != nil {
+				return fmt.Errorf("bind append flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-clobber", cmd.Flags().Lookup("no-clobber")); err != nil {
+				return fmt.Errorf("bind no-clobber flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("resolve-digests", cmd.Flags().Lookup("resolve-digests")); err != nil {
+				return fmt.Errorf("bind resolve-digests flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dedupe-digests", cmd.Flags().Lookup("dedupe-digests")); err != nil {
+				return fmt.Errorf("bind dedupe-digests flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude", cmd.Flags().Lookup("exclude")); err != nil {
+				return fmt.Errorf("bind exclude flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-regex", cmd.Flags().Lookup("exclude-regex")); err != nil {
+				return fmt.Errorf("bind exclude-regex flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("source-filter", cmd.Flags().Lookup("source-filter")); err != nil {
+				return fmt.Errorf("bind source-filter flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("template", cmd.Flags().Lookup("template")); err != nil {
+				return fmt.Errorf("bind template flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("template-file", cmd.Flags().Lookup("template-file")); err != nil {
+				return fmt.Errorf("bind template-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("cluster", cmd.Flags().Lookup("cluster")); err != nil {
+				return fmt.Errorf("bind cluster flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("watch", cmd.Flags().Lookup("watch")); err != nil {
+				return fmt.Errorf("bind watch flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("metrics-address", cmd.Flags().Lookup("metrics-address")); err != nil {
+				return fmt.Errorf("bind metrics-address flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("fail-on", cmd.Flags().Lookup("fail-on")); err != nil {
+				return fmt.Errorf("bind fail-on flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("fail-threshold", cmd.Flags().Lookup("fail-threshold")); err != nil {
+				return fmt.Errorf("bind fail-threshold flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("summary-file", cmd.Flags().Lookup("summary-file")); err != nil {
+				return fmt.Errorf("bind summary-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("sort", cmd.Flags().Lookup("sort")); err != nil {
+				return fmt.Errorf("bind sort flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("inspect", cmd.Flags().Lookup("inspect")); err != nil {
+				return fmt.Errorf("bind inspect flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("format", cmd.Flags().Lookup("format")); err != nil {
+				return fmt.Errorf("bind format flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platform", cmd.Flags().Lookup("platform")); err != nil {
+				return fmt.Errorf("bind platform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("since", cmd.Flags().Lookup("since")); err != nil {
+				return fmt.Errorf("bind since flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("vulnerabilities", cmd.Flags().Lookup("vulnerabilities")); err != nil {
+				return fmt.Errorf("bind vulnerabilities flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("scan-server", cmd.Flags().Lookup("scan-server")); err != nil {
+				return fmt.Errorf("bind scan-server flag: %w", err)
+			}
+
+			if viper.GetBool("vulnerabilities") && !viper.GetBool("inspect") {
+				return errors.New("vulnerabilities can only be used together with the inspect flag")
+			}
+
+			if viper.GetBool("inspect") && (viper.GetString("output") != "" || viper.GetString("template") != "" || viper.GetString("template-file") != "") {
+				return errors.New("inspect cannot be used together with the output, template or template-file flags")
+			}
+
+			if viper.GetBool("cluster") {
+				if err := runListClusterCommand(cmd.Context()); err != nil {
+					return fmt.Errorf("list cluster: %w", err)
+				}
+
+				return nil
+			}
+
 			origin := args[0]
 			manifestPath := viper.GetString("manifest")
-			if err := runListCommand(origin, manifestPath); err != nil {
+			if viper.GetBool("watch") {
+				serveMetrics(viper.GetString("metrics-address"))
+
+				run := func() error { return runListCommand(cmd.Context(), origin, manifestPath) }
+				if err := watch(cmd.Context(), getWatchPath(manifestPath), run); err != nil {
+					return fmt.Errorf("watch: %w", err)
+				}
+
+				return nil
+			}
+
+			if err := runListCommand(cmd.Context(), origin, manifestPath); err != nil {
 				return fmt.Errorf("list: %w", err)
 			}
 
@@ -33,25 +172,244 @@ func newListCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("output", "o", "", "Output the images in the manifest to a file")
+	cmd.Flags().Bool("append", false, "Append the images to the output file when it already exists")
+	cmd.Flags().Bool("no-clobber", false, "Fail instead of replacing the output file when it already exists")
+	cmd.Flags().Bool("force", false, "Replace the output file when it already exists, which is the default")
+	deprecateErr = cmd.Flags().MarkDeprecated("force", "the output file is replaced by default")
+	cmd.Flags().String("template", "", "Go template to format each image with (e.g. '{{.Repository}}:{{.Version}}')")
+	cmd.Flags().String("template-file", "", "Path to a Go template file to format all of the images with, which are available as .Images")
+	cmd.Flags().Bool("resolve-digests", false, "Output the images referenced by their digest (e.g. host.com/repo@sha256:...)")
+	cmd.Flags().Bool("dedupe-digests", false, "Only list the first of the images that resolve to the same digest")
+	cmd.Flags().String("sort", sortRegistry, "Order to list the images in (registry, name or file)")
+	cmd.Flags().Bool("inspect", false, "Print the labels, entrypoint, exposed ports and layers of each image instead of its reference")
+	cmd.Flags().String("format", "table", "Format to output the metadata of the images in when using the inspect flag (table or json)")
+	cmd.Flags().String("platform", "linux/amd64", "Platform to inspect of multi-arch images when using the inspect flag")
+	cmd.Flags().Bool("vulnerabilities", false, "Scan each image when using the inspect flag and include the number of its known CRITICAL and HIGH vulnerabilities (requires trivy)")
+	cmd.Flags().String("scan-server", "", "Address of a Trivy server to scan images with when using the vulnerabilities flag")
+	cmd.Flags().String("since", "", "Only list the images of the sources that were added or changed in the manifest since the Git ref (e.g. origin/main)")
+	cmd.Flags().StringSlice("exclude", []string{}, "Glob patterns of images to exclude (e.g. busybox:*)")
+	cmd.Flags().StringSlice("exclude-regex", []string{}, "Regular expressions of images to exclude")
+	cmd.Flags().StringSlice("source-filter", []string{}, "Only include images from the given source registries (e.g. quay.io,gcr.io)")
+	cmd.Flags().String("summary-file", "", "Path to write a JSON summary of the images that were found to")
+	cmd.Flags().Bool("watch", false, "Watch the manifest for changes and list the images again when it changes")
+	cmd.Flags().String("metrics-address", "", "Address to serve Prometheus metrics on when watching (e.g. :9090)")
+	cmd.Flags().Bool("cluster", false, "List the images used by the workloads running in a Kubernetes cluster instead (requires kubectl)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Namespaces to list images from when using the cluster flag (defaults to all namespaces)")
+	cmd.Flags().StringSlice("fail-on", []string{failOnNone}, "Kinds of images in the manifest that cause the command to fail (missing, untagged, latest-tag or none)")
+	cmd.Flags().Int("fail-threshold", 0, "Number of images of each kind in fail-on that are allowed before the command fails")
 
 	return &cmd
 }
 
-func runListCommand(origin string, manifestPath string) error {
-	imageManifest, err := manifest.Get(manifestPath)
+func runListCommand(ctx context.Context, origin string, manifestPath string) error {
+	policy, err := getFailurePolicy(failOnMissing, failOnUntagged, failOnLatestTag)
+	if err != nil {
+		return fmt.Errorf("get failure policy: %w", err)
+	}
+
+	sources, summary, err := getManifestSourcesWithSummary(ctx, manifestPath)
 	if err != nil {
-		return fmt.Errorf("get manifest: %w", err)
+		return fmt.Errorf("get manifest sources: %w", err)
 	}
 
+	metrics.ImagesDiscovered.Add(uint64(len(sources)))
+
 	var images []string
-	for _, source := range imageManifest.Sources {
+	var imageKeys []string
+	for _, source := range sources {
+		image := source.Image()
+		if origin == "target" {
+			image = source.TargetImage()
+		}
+
+		images = append(images, image)
+		imageKeys = append(imageKeys, getImageKey(image))
+	}
+
+	if viper.GetBool("resolve-digests") || viper.GetBool("dedupe-digests") {
+		digestImages, err := resolveDigests(ctx, origin, sources)
+		if err != nil {
+			return fmt.Errorf("resolve digests: %w", err)
+		}
+
+		if viper.GetBool("resolve-digests") {
+			images = digestImages
+		}
+
+		// Images are collapsed by their digest alone, as the same image can be
+		// mirrored to (or sourced from) repositories with different names.
+		if viper.GetBool("dedupe-digests") {
+			for i, digestImage := range digestImages {
+				imageKeys[i] = digestImage[strings.LastIndex(digestImage, "@")+1:]
+			}
+		}
+	}
+
+	indexes, err := sortImageIndexes(images, viper.GetString("sort"))
+	if err != nil {
+		return fmt.Errorf("sort images: %w", err)
+	}
+
+	var sortedImages, sortedImageKeys []string
+	var sortedSources []manifest.Source
+	for _, i := range indexes {
+		sortedImages = append(sortedImages, images[i])
+		sortedImageKeys = append(sortedImageKeys, imageKeys[i])
+		sortedSources = append(sortedSources, sources[i])
+	}
+	images, imageKeys, sources = sortedImages, sortedImageKeys, sortedSources
+
+	if viper.GetBool("inspect") {
+		if err := inspectListedImages(ctx, origin, images, imageKeys, sources); err != nil {
+			return fmt.Errorf("inspect listed images: %w", err)
+		}
+	} else if err := writeListedImages(images, imageKeys, sources); err != nil {
+		return fmt.Errorf("write listed images: %w", err)
+	}
+
+	counts := countSourceKinds(sources)
+	if policy.enabled(failOnMissing) {
+		missingImages, err := findMissingImages(ctx, sources)
+		if err != nil {
+			return fmt.Errorf("find missing images: %w", err)
+		}
+
+		counts[failOnMissing] = len(missingImages)
+		summary.MissingAtTarget = len(missingImages)
+	}
+
+	if err := writeSummary(summary); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	if err := policy.check(counts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeListedImages writes the images, without the images that have the same key as an
+// earlier image, formatted with the output template when one is given.
+func writeListedImages(images []string, imageKeys []string, sources []manifest.Source) error {
+	listTemplate, err := getOutputTemplate()
+	if err != nil {
+		return fmt.Errorf("get output template: %w", err)
+	}
+
+	var lines []string
+	if listTemplate == nil {
+		lines = dedupeImages(images, imageKeys)
+	} else {
+		var templateImages []templateImage
+		for _, i := range dedupeIndexes(imageKeys) {
+			data, err := newTemplateImage(images[i], sources[i].Image(), sources[i].TargetImage())
+			if err != nil {
+				return fmt.Errorf("new template image: %w", err)
+			}
+
+			templateImages = append(templateImages, data)
+		}
+
+		lines, err = listTemplate.execute(templateImages)
+		if err != nil {
+			return fmt.Errorf("execute output template: %w", err)
+		}
+	}
+
+	if err := writeImages(lines); err != nil {
+		return fmt.Errorf("write images: %w", err)
+	}
+
+	return nil
+}
+
+// inspectListedImages writes the metadata of the images, without the images that have the same key as an earlier
+// image. The images are accessed with the auth of the source registry, or of the target registry when listing the target.
+func inspectListedImages(ctx context.Context, origin string, images []string, imageKeys []string, sources []manifest.Source) error {
+	var listedImages []string
+	var auths []string
+	for _, i := range dedupeIndexes(imageKeys) {
+		auth, err := getSourceAuth(sources[i])
 		if origin == "target" {
-			images = append(images, source.TargetImage())
-		} else {
-			images = append(images, source.Image())
+			auth, err = getTargetAuth(sources[i].Target)
+		}
+		if err != nil {
+			return fmt.Errorf("get %s auth: %w", origin, err)
 		}
+
+		listedImages = append(listedImages, images[i])
+		auths = append(auths, auth)
+	}
+
+	var scanOptions *scan.Options
+	if viper.GetBool("vulnerabilities") {
+		scanOptions = &scan.Options{Server: viper.GetString("scan-server")}
+	}
+
+	return inspectImages(ctx, listedImages, auths, scanOptions)
+}
+
+func runListClusterCommand(ctx context.Context) error {
+	cluster := images.Cluster{
+		Kubeconfig: viper.GetString("kubeconfig"),
+		Context:    viper.GetString("context"),
+		Namespaces: viper.GetStringSlice("namespaces"),
 	}
 
+	foundImages, err := images.FindImagesInCluster(cluster, images.WithContext(ctx), getRegistryHostsOption())
+	if err != nil {
+		return fmt.Errorf("find images in cluster: %w", err)
+	}
+
+	var references []string
+	for _, image := range foundImages {
+		references = append(references, image.Reference)
+	}
+
+	indexes, err := sortImageIndexes(references, viper.GetString("sort"))
+	if err != nil {
+		return fmt.Errorf("sort images: %w", err)
+	}
+
+	listTemplate, err := getOutputTemplate()
+	if err != nil {
+		return fmt.Errorf("get output template: %w", err)
+	}
+
+	var clusterImages []string
+	var templateImages []templateImage
+	for _, i := range indexes {
+		image := foundImages[i]
+		clusterImages = append(clusterImages, image.Reference)
+
+		if listTemplate != nil {
+			data, err := newTemplateImage(image.Reference, "", "")
+			if err != nil {
+				return fmt.Errorf("new template image: %w", err)
+			}
+
+			templateImages = append(templateImages, data)
+		}
+	}
+
+	if listTemplate != nil {
+		clusterImages, err = listTemplate.execute(templateImages)
+		if err != nil {
+			return fmt.Errorf("execute output template: %w", err)
+		}
+	}
+
+	if err := writeImages(clusterImages); err != nil {
+		return fmt.Errorf("write images: %w", err)
+	}
+
+	return nil
+}
+
+// writeImages writes the images to stdout, or to the output file. An existing output file is replaced,
+// unless the images are appended to it or it must not be clobbered (except when watching).
+func writeImages(images []string) error {
 	if viper.GetString("output") == "" {
 		for _, image := range images {
 			fmt.Println(image)
@@ -59,21 +417,133 @@ func runListCommand(origin string, manifestPath string) error {
 		return nil
 	}
 
-	f, err := os.Create(viper.GetString("output"))
-	if err != nil {
-		return fmt.Errorf("creating file: %w", err)
+	outputPath := viper.GetString("output")
+
+	var contents []byte
+	existing, err := ioutil.ReadFile(outputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read existing file: %w", err)
 	}
-	defer f.Close()
 
-	for _, value := range images {
-		if _, err := fmt.Fprintln(f, value); err != nil {
-			return fmt.Errorf("writing image to file: %w", err)
+	if err == nil {
+		switch {
+		case viper.GetBool("append"):
+			contents = existing
+			if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+				contents = append(contents, '\n')
+			}
+
+		case viper.GetBool("no-clobber") && !viper.GetBool("watch"):
+			return fmt.Errorf("output file %s already exists (remove --no-clobber to replace it or use --append to append to it)", outputPath)
 		}
 	}
 
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("close: %w", err)
+	for _, image := range images {
+		contents = append(contents, image+"\n"...)
+	}
+
+	if err := writeFileAtomic(outputPath, contents); err != nil {
+		return fmt.Errorf("write file: %w", err)
 	}
 
 	return nil
 }
+
+func resolveDigests(ctx context.Context, origin string, sources []manifest.Source) ([]string, error) {
+	ctx, cancel := withCommandTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	client, err := newClient()
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+
+	var images []string
+	for _, source := range sources {
+		// The digest of a source is resolved from the registry mirror that
+		// it is pulled through, if any, but the source image is listed.
+		image := source.Image()
+		pullImage := source.PullImage()
+		auth, err := getSourceAuth(source)
+		if origin == "target" {
+			image = source.TargetImage()
+			pullImage = image
+			auth, err = getTargetAuth(source.Target)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get %s auth: %w", origin, err)
+		}
+
+		// Sources that already have a digest recorded in the manifest are
+		// pinned and do not need to be resolved against the registry.
+		digest := source.Digest
+		if digest == "" || origin == "target" {
+			digest, err = client.GetDigest(ctx, pullImage, auth)
+			if err != nil {
+				return nil, fmt.Errorf("get digest: %w", err)
+			}
+		}
+
+		imageDigest, err := imageWithDigest(image, digest)
+		if err != nil {
+			return nil, fmt.Errorf("image with digest: %w", err)
+		}
+
+		images = append(images, imageDigest)
+	}
+
+	return images, nil
+}
+
+// dedupeImages returns the images without the images that have the same key as an image before them.
+func dedupeImages(images []string, keys []string) []string {
+	var dedupedImages []string
+	for _, i := range dedupeIndexes(keys) {
+		dedupedImages = append(dedupedImages, images[i])
+	}
+
+	return dedupedImages
+}
+
+// dedupeIndexes returns the indexes of the keys that are not the same as a key before them.
+func dedupeIndexes(keys []string) []int {
+	seen := make(map[string]bool)
+
+	var indexes []int
+	for i, key := range keys {
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		indexes = append(indexes, i)
+	}
+
+	return indexes
+}
+
+// getImageKey returns the key that the image is deduplicated by, which is the normalized form of the
+// reference so that references to the same image (e.g. nginx:1.25 and docker.io/library/nginx:1.25) are
+// only listed once.
+func getImageKey(image string) string {
+	normalized, err := images.NormalizeReference(image, getRegistryHostsOption())
+	if err != nil {
+		return image
+	}
+
+	return normalized
+}
+
+func imageWithDigest(image string, digest string) (string, error) {
+	parsedImage, err := images.ParseReference(image, getRegistryHostsOption())
+	if err != nil {
+		return "", fmt.Errorf("parse image: %w", err)
+	}
+
+	repository := parsedImage.Repository
+	if parsedImage.Host != "" {
+		repository = parsedImage.Host + "/" + repository
+	}
+
+	return repository + "@" + digest, nil
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestImageWithDigest(t *testing.T) {
+	testCases := []struct {
+		image    string
+		expected string
+	}{
+		{
+			image:    "busybox:1.32.0",
+			expected: "busybox@sha256:123",
+		},
+		{
+			image:    "quay.io/coreos/prometheus-operator:v0.40.0",
+			expected: "quay.io/coreos/prometheus-operator@sha256:123",
+		},
+		{
+			image:    "localhost:5000/busybox:1.32.0",
+			expected: "localhost:5000/busybox@sha256:123",
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := imageWithDigest(testCase.image, "sha256:123")
+		if err != nil {
+			t.Fatal("image with digest:", err)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf("expected image %s, actual %s", testCase.expected, actual)
+		}
+	}
+}
+
+func TestDedupeImages(t *testing.T) {
+	images := []string{"nginx:1.25", "docker.io/library/nginx:1.25", "quay.io/nginx/nginx:1.25", "busybox"}
+
+	var keys []string
+	for _, image := range images {
+		keys = append(keys, getImageKey(image))
+	}
+
+	actual := dedupeImages(images, keys)
+
+	expected := []string{"nginx:1.25", "quay.io/nginx/nginx:1.25", "busybox"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actual)
+	}
+
+	// Images that resolve to the same digest are collapsed when deduplicating by digest.
+	actual = dedupeImages(images, []string{"sha256:1", "sha256:1", "sha256:1", "sha256:2"})
+
+	expected = []string{"nginx:1.25", "busybox"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actual)
+	}
+}
+
+func TestWriteImages(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// The directory of the output file does not exist yet.
+	outputPath := filepath.Join(tempDir, "images", "images.txt")
+	viper.Set("output", outputPath)
+	defer viper.Set("output", "")
+
+	if err := writeImages([]string{"busybox:1.32.0"}); err != nil {
+		t.Fatal("write images:", err)
+	}
+
+	viper.Set("no-clobber", true)
+	if err := writeImages([]string{"nginx:1.25"}); err == nil {
+		t.Error("expected an error when the output file already exists and must not be clobbered")
+	}
+	viper.Set("no-clobber", false)
+
+	viper.Set("append", true)
+	if err := writeImages([]string{"nginx:1.25"}); err != nil {
+		t.Fatal("append images:", err)
+	}
+	viper.Set("append", false)
+
+	contents, err := ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal("read output file:", err)
+	}
+
+	expected := "busybox:1.32.0\nnginx:1.25\n"
+	if string(contents) != expected {
+		t.Errorf("expected appended output %q, actual %q", expected, contents)
+	}
+
+	if err := writeImages([]string{"alpine:3.12"}); err != nil {
+		t.Fatal("replace images:", err)
+	}
+
+	contents, err = ioutil.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal("read output file:", err)
+	}
+
+	expected = "alpine:3.12\n"
+	if string(contents) != expected {
+		t.Errorf("expected replaced output %q, actual %q", expected, contents)
+	}
+}
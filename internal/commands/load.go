@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newLoadCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "load <archive>",
+		Short: "Push the images in the manifest from an archive to the target repository",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("skip-verify", cmd.Flags().Lookup("skip-verify")); err != nil {
+				return fmt.Errorf("bind skip-verify flag: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runLoadCommand(cmd.Context(), manifestPath, args[0]); err != nil {
+				return fmt.Errorf("load: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("skip-verify", false, "Load the images without verifying the archive against its index (e.g. archives saved by older versions)")
+
+	return &cmd
+}
+
+func runLoadCommand(ctx context.Context, manifestPath string, archivePath string) error {
+	ctx, cancel := withCommandTimeout(ctx, 30*time.Minute)
+	defer cancel()
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	imageManifest, err := getManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	layoutPath, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(layoutPath)
+
+	if err := docker.ExtractArchive(archivePath, layoutPath); err != nil {
+		return fmt.Errorf("extract archive: %w", err)
+	}
+
+	if !viper.GetBool("skip-verify") {
+		bundleIndex, err := docker.VerifyBundleIndex(layoutPath)
+		if errors.Is(err, docker.ErrBundleIndexNotFound) {
+			return fmt.Errorf("archive %s does not have an index, use --skip-verify to load it anyway", archivePath)
+		}
+		if err != nil {
+			return fmt.Errorf("verify archive %s: %w", archivePath, err)
+		}
+
+		log.Infof("Verified %v image(s) and %v file(s) against the index of %s", len(bundleIndex.Images), len(bundleIndex.Files), archivePath)
+	}
+
+	for s, source := range imageManifest.Sources {
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		log.Infof("Loading %s", source.TargetImage())
+		if err := client.LoadImage(ctx, layoutPath, source.Image(), source.TargetImage(), targetAuth); err != nil {
+			return fmt.Errorf("load image %s: %w", source.Image(), err)
+		}
+
+		log.Infof("Loaded %s (%v/%v)", source.TargetImage(), s+1, len(imageManifest.Sources))
+	}
+
+	log.Infof("All images have been loaded!")
+
+	return nil
+}
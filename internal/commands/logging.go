@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// configureLogging sets the level and format of the log messages. Log messages are
+// written to stderr so that they can be separated from the output of a command.
+func configureLogging(level string, format string) error {
+	logLevel, err := log.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("parse level: %w", err)
+	}
+
+	var formatter log.Formatter
+	switch format {
+	case "text":
+		formatter = &log.TextFormatter{}
+	case "json":
+		formatter = &log.JSONFormatter{}
+	default:
+		return fmt.Errorf("unknown log format %s, expected text or json", format)
+	}
+
+	log.SetLevel(logLevel)
+	log.SetFormatter(formatter)
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestConfigureLogging(t *testing.T) {
+	defer configureLogging("info", "text")
+
+	if err := configureLogging("warn", "json"); err != nil {
+		t.Fatal("configure logging:", err)
+	}
+
+	if log.GetLevel() != log.WarnLevel {
+		t.Errorf("expected level %v, actual %v", log.WarnLevel, log.GetLevel())
+	}
+
+	if _, ok := log.StandardLogger().Formatter.(*log.JSONFormatter); !ok {
+		t.Errorf("expected json formatter, actual %T", log.StandardLogger().Formatter)
+	}
+
+	if err := configureLogging("info", "xml"); err == nil {
+		t.Error("expected error for unknown log format")
+	}
+
+	if err := configureLogging("loud", "text"); err == nil {
+		t.Error("expected error for unknown log level")
+	}
+}
@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// The operations that image lists are merged with.
+const (
+	mergeUnion     = "union"
+	mergeIntersect = "intersect"
+	mergeSubtract  = "subtract"
+)
+
+func newMergeCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "merge <path> <path>...",
+		Short: "Merge the images of image lists, image manifests or Kubernetes manifests into one deduplicated list",
+		Args:  cobra.MinimumNArgs(2),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("union", cmd.Flags().Lookup("union")); err != nil {
+				return fmt.Errorf("bind union flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("intersect", cmd.Flags().Lookup("intersect")); err != nil {
+				return fmt.Errorf("bind intersect flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("subtract", cmd.Flags().Lookup("subtract")); err != nil {
+				return fmt.Errorf("bind subtract flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			operation, err := getMergeOperation(viper.GetBool("union"), viper.GetBool("intersect"), viper.GetBool("subtract"))
+			if err != nil {
+				return fmt.Errorf("get merge operation: %w", err)
+			}
+
+			if err := runMergeCommand(cmd.Context(), operation, args); err != nil {
+				return fmt.Errorf("merge: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("union", false, "Output the images that are in any of the paths (default)")
+	cmd.Flags().Bool("intersect", false, "Output the images that are in every one of the paths")
+	cmd.Flags().Bool("subtract", false, "Output the images of the first path that are not in any of the other paths")
+	cmd.Flags().StringP("target", "t", "", "Output an image manifest with this target (e.g. host.com/repo) instead of a list of images")
+	cmd.Flags().StringP("output", "o", "", "Path to write the merged images to (defaults to stdout)")
+
+	return &cmd
+}
+
+func getMergeOperation(union bool, intersect bool, subtract bool) (string, error) {
+	var operations []string
+	if union {
+		operations = append(operations, mergeUnion)
+	}
+
+	if intersect {
+		operations = append(operations, mergeIntersect)
+	}
+
+	if subtract {
+		operations = append(operations, mergeSubtract)
+	}
+
+	if len(operations) > 1 {
+		return "", errors.New("only one of --union, --intersect or --subtract can be set")
+	}
+
+	if len(operations) == 0 {
+		return mergeUnion, nil
+	}
+
+	return operations[0], nil
+}
+
+func runMergeCommand(ctx context.Context, operation string, paths []string) error {
+	var lists [][]string
+	for _, path := range paths {
+		pathImages, err := getMergeImages(ctx, path)
+		if err != nil {
+			return fmt.Errorf("get images of %s: %w", path, err)
+		}
+
+		lists = append(lists, pathImages)
+	}
+
+	merged := mergeImageLists(operation, lists)
+
+	var contents []byte
+	if viper.GetString("target") == "" {
+		for _, image := range merged {
+			contents = append(contents, image+"\n"...)
+		}
+
+		return writeGenerated(contents)
+	}
+
+	sources, err := manifest.GetSourcesFromImages(merged, "", getRegistryHostsOption())
+	if err != nil {
+		return fmt.Errorf("get sources from images: %w", err)
+	}
+
+	targetPath := docker.RegistryPath(viper.GetString("target"))
+	imageManifest := manifest.New(targetPath.Host(getRegistryHostsOption()), targetPath.Repository(getRegistryHostsOption()))
+	for _, source := range sources {
+		source.Target = manifest.Target{}
+		imageManifest.Sources = append(imageManifest.Sources, source)
+	}
+
+	contents, err = yaml.Marshal(&imageManifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	return writeGenerated(contents)
+}
+
+// mergeImageLists returns the images of the lists that are kept by the operation, in the order that they are first
+// found in. References to the same image that are written in different ways (e.g. nginx:1.25 and
+// docker.io/library/nginx:1.25) are the same image, and the image keeps the reference that was found first.
+func mergeImageLists(operation string, lists [][]string) []string {
+	var keys []string
+	images := make(map[string]string)
+	listCounts := make(map[string]int)
+	for l, list := range lists {
+		seen := make(map[string]bool)
+		for _, image := range list {
+			key := getImageKey(image)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			listCounts[key]++
+
+			// When subtracting, only the images of the first list are
+			// kept and the images of the other lists only remove them.
+			if _, ok := images[key]; ok || (operation == mergeSubtract && l > 0) {
+				continue
+			}
+
+			keys = append(keys, key)
+			images[key] = image
+		}
+	}
+
+	var merged []string
+	for _, key := range keys {
+		if operation == mergeIntersect && listCounts[key] != len(lists) {
+			continue
+		}
+
+		if operation == mergeSubtract && listCounts[key] > 1 {
+			continue
+		}
+
+		merged = append(merged, images[key])
+	}
+
+	return merged
+}
+
+// getMergeImages returns the images of the image manifest at the path, or when the path is a file that is not YAML,
+// the images listed in the file (one per line). Otherwise, the images are found in the Kubernetes manifests at the path.
+func getMergeImages(ctx context.Context, path string) ([]string, error) {
+	fileInfo, err := os.Stat(path)
+	if err == nil && !fileInfo.IsDir() && filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml" {
+		return readImageList(path)
+	}
+
+	sources, err := getDiffSources(ctx, path, manifest.Target{})
+	if err != nil {
+		return nil, fmt.Errorf("get sources: %w", err)
+	}
+
+	var images []string
+	for _, source := range sources {
+		images = append(images, source.Image())
+	}
+
+	return images, nil
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeImageLists(t *testing.T) {
+	lists := [][]string{
+		{"nginx:1.25", "busybox:1.32.0", "quay.io/coreos/prometheus-operator:v0.40.0"},
+		{"docker.io/library/nginx:1.25", "redis:6.0"},
+		{"nginx:1.25", "busybox:1.32.0", "redis:6.0"},
+	}
+
+	testCases := []struct {
+		operation string
+		expected  []string
+	}{
+		{mergeUnion, []string{"nginx:1.25", "busybox:1.32.0", "quay.io/coreos/prometheus-operator:v0.40.0", "redis:6.0"}},
+		{mergeIntersect, []string{"nginx:1.25"}},
+		{mergeSubtract, []string{"quay.io/coreos/prometheus-operator:v0.40.0"}},
+	}
+
+	for _, testCase := range testCases {
+		actual := mergeImageLists(testCase.operation, lists)
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected %s to be %v, actual %v", testCase.operation, testCase.expected, actual)
+		}
+	}
+}
+
+func TestGetMergeOperation(t *testing.T) {
+	operation, err := getMergeOperation(false, false, false)
+	if err != nil || operation != mergeUnion {
+		t.Errorf("expected union by default, actual %s (%v)", operation, err)
+	}
+
+	if _, err := getMergeOperation(false, true, true); err == nil {
+		t.Error("expected error when more than one operation is set")
+	}
+}
@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/plexsystems/sinker/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// serveMetrics serves the metrics at /metrics in the background. Nothing is served when the address is empty.
+func serveMetrics(address string) {
+	if address == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	go func() {
+		log.Infof("Serving metrics on %s/metrics", address)
+		if err := http.ListenAndServe(address, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Unable to serve metrics: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// The types of endpoints that notifications are sent to.
+const (
+	notificationWebhook = "webhook"
+	notificationSlack   = "slack"
+	notificationTeams   = "teams"
+)
+
+// notificationConfig is an endpoint in the notifications section of the config file that a notification
+// is sent to after each run of the push and check commands.
+type notificationConfig struct {
+
+	// Type is the type of the endpoint (webhook, slack or teams), which defaults to webhook.
+	Type string `mapstructure:"type"`
+
+	// URL is the URL that the notification is posted to, such as the URL of a Slack incoming webhook.
+	URL string `mapstructure:"url"`
+
+	// On is when the notification is sent (always, success or failure), which defaults to always.
+	On string `mapstructure:"on"`
+
+	// Headers are added to the requests to webhooks (e.g. Authorization).
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// notification is the result of a run of a command, which is posted as is to webhooks.
+type notification struct {
+	Command         string     `json:"command"`
+	Succeeded       bool       `json:"succeeded"`
+	Error           string     `json:"error,omitempty"`
+	Started         time.Time  `json:"started"`
+	DurationSeconds float64    `json:"durationSeconds"`
+	Summary         runSummary `json:"summary"`
+}
+
+func newNotification(command string, started time.Time, summary runSummary, err error) notification {
+	result := notification{
+		Command:         command,
+		Succeeded:       err == nil,
+		Started:         started.UTC(),
+		DurationSeconds: time.Since(started).Round(time.Millisecond).Seconds(),
+		Summary:         summary,
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// String returns a message such as: sinker push failed after 1m30s: 12 image(s) found, 3 image(s) missing at the target
+func (n notification) String() string {
+	status := "succeeded"
+	if !n.Succeeded {
+		status = "failed"
+	}
+
+	duration := time.Duration(n.DurationSeconds * float64(time.Second)).Round(time.Second)
+	message := fmt.Sprintf("sinker %s %s after %s: %v image(s) found, %v image(s) missing at the target", n.Command, status, duration, n.Summary.ImagesFound, n.Summary.MissingAtTarget)
+	if n.Summary.BytesCopied > 0 {
+		message += ", " + formatBytes(n.Summary.BytesCopied) + " copied"
+	}
+
+	if n.Error != "" {
+		message += "\n" + n.Error
+	}
+
+	return message
+}
+
+// sendNotifications sends the result of the run of the command to each endpoint in the notifications section
+// of the config file. A notification that cannot be sent is logged, as it should not fail the command itself.
+func sendNotifications(ctx context.Context, command string, started time.Time, summary runSummary, err error) {
+	var configs []notificationConfig
+	if err := viper.UnmarshalKey("notifications", &configs); err != nil {
+		log.Warnf("Unable to read the notifications of the config: %v", err)
+		return
+	}
+
+	result := newNotification(command, started, summary, err)
+	for _, config := range configs {
+		if !config.matches(result) {
+			continue
+		}
+
+		if err := config.send(ctx, result); err != nil {
+			log.Warnf("Unable to send %s notification: %v", config.getType(), err)
+		}
+	}
+}
+
+func (c notificationConfig) getType() string {
+	if c.Type == "" {
+		return notificationWebhook
+	}
+
+	return c.Type
+}
+
+func (c notificationConfig) matches(result notification) bool {
+	switch c.On {
+	case "success":
+		return result.Succeeded
+	case "failure":
+		return !result.Succeeded
+	default:
+		return true
+	}
+}
+
+func (c notificationConfig) send(ctx context.Context, result notification) error {
+	body, err := c.getBody(result)
+	if err != nil {
+		return fmt.Errorf("get body: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range c.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("post: %w", err)
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	return nil
+}
+
+// getBody returns the body of the notification in the format of the endpoint. Slack and Microsoft
+// Teams incoming webhooks are sent a message, while other webhooks are sent the result as JSON.
+func (c notificationConfig) getBody(result notification) ([]byte, error) {
+	var body interface{}
+	switch c.getType() {
+	case notificationSlack:
+		body = map[string]string{"text": result.String()}
+
+	case notificationTeams:
+		themeColor := "2EB886"
+		if !result.Succeeded {
+			themeColor = "D00000"
+		}
+
+		body = map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    "sinker " + result.Command,
+			"themeColor": themeColor,
+			"text":       result.String(),
+		}
+
+	case notificationWebhook:
+		body = result
+
+	default:
+		return nil, fmt.Errorf("unknown notification type %s", c.Type)
+	}
+
+	contents, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return contents, nil
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendNotification(t *testing.T) {
+	var body []byte
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	summary := runSummary{ImagesFound: 12, MissingAtTarget: 3}
+	result := newNotification("push", time.Now().Add(-time.Minute), summary, errors.New("push images: unauthorized"))
+
+	webhook := notificationConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}}
+	if err := webhook.send(context.Background(), result); err != nil {
+		t.Fatal("send:", err)
+	}
+
+	var actual notification
+	if err := json.Unmarshal(body, &actual); err != nil {
+		t.Fatal("unmarshal:", err)
+	}
+
+	if actual.Command != "push" || actual.Succeeded || actual.Error != "push images: unauthorized" || actual.Summary != summary {
+		t.Errorf("unexpected notification %+v", actual)
+	}
+
+	if authorization != "Bearer token" {
+		t.Errorf("expected authorization header, actual %q", authorization)
+	}
+
+	slack := notificationConfig{Type: notificationSlack, URL: server.URL}
+	if err := slack.send(context.Background(), result); err != nil {
+		t.Fatal("send:", err)
+	}
+
+	expected := "sinker push failed after 1m0s: 12 image(s) found, 3 image(s) missing at the target"
+	if !strings.Contains(string(body), expected) {
+		t.Errorf("expected slack message to contain %q, actual %s", expected, body)
+	}
+}
+
+func TestNotificationMatches(t *testing.T) {
+	succeeded := notification{Succeeded: true}
+	failed := notification{}
+
+	if !(notificationConfig{}).matches(failed) {
+		t.Error("expected notifications to be sent on failure by default")
+	}
+
+	onFailure := notificationConfig{On: "failure"}
+	if onFailure.matches(succeeded) || !onFailure.matches(failed) {
+		t.Error("expected on failure to only match failed runs")
+	}
+
+	onSuccess := notificationConfig{On: "success"}
+	if !onSuccess.matches(succeeded) || onSuccess.matches(failed) {
+		t.Error("expected on success to only match succeeded runs")
+	}
+}
@@ -0,0 +1,330 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/hashicorp/go-version"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// outdatedImage is an image found in the resources for which a newer stable version is available.
+type outdatedImage struct {
+	image  images.Image
+	latest string
+}
+
+func (o outdatedImage) updatedReference() string {
+	return strings.TrimSuffix(o.image.Reference, ":"+o.image.Tag) + ":" + o.latest
+}
+
+func newOutdatedCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "outdated <source>...",
+		Short: "Report the images referenced by the Kubernetes resources at the sources that have newer versions",
+		Args:  cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("patch", cmd.Flags().Lookup("patch")); err != nil {
+				return fmt.Errorf("bind patch flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm", cmd.Flags().Lookup("helm")); err != nil {
+				return fmt.Errorf("bind helm flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm-values", cmd.Flags().Lookup("helm-values")); err != nil {
+				return fmt.Errorf("bind helm-values flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kustomize", cmd.Flags().Lookup("kustomize")); err != nil {
+				return fmt.Errorf("bind kustomize flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images", cmd.Flags().Lookup("env-images")); err != nil {
+				return fmt.Errorf("bind env-images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images-pattern", cmd.Flags().Lookup("env-images-pattern")); err != nil {
+				return fmt.Errorf("bind env-images-pattern flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("crd-config", cmd.Flags().Lookup("crd-config")); err != nil {
+				return fmt.Errorf("bind crd-config flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dockerfiles", cmd.Flags().Lookup("dockerfiles")); err != nil {
+				return fmt.Errorf("bind dockerfiles flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("ci-files", cmd.Flags().Lookup("ci-files")); err != nil {
+				return fmt.Errorf("bind ci-files flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("terraform", cmd.Flags().Lookup("terraform")); err != nil {
+				return fmt.Errorf("bind terraform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("skip-dirs", cmd.Flags().Lookup("skip-dirs")); err != nil {
+				return fmt.Errorf("bind skip-dirs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-gitignore", cmd.Flags().Lookup("no-gitignore")); err != nil {
+				return fmt.Errorf("bind no-gitignore flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-symlinks", cmd.Flags().Lookup("follow-symlinks")); err != nil {
+				return fmt.Errorf("bind follow-symlinks flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kinds", cmd.Flags().Lookup("kinds")); err != nil {
+				return fmt.Errorf("bind kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-kinds", cmd.Flags().Lookup("exclude-kinds")); err != nil {
+				return fmt.Errorf("bind exclude-kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("selector", cmd.Flags().Lookup("selector")); err != nil {
+				return fmt.Errorf("bind selector flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-document-size", cmd.Flags().Lookup("max-document-size")); err != nil {
+				return fmt.Errorf("bind max-document-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("warn-on-parse-error", cmd.Flags().Lookup("warn-on-parse-error")); err != nil {
+				return fmt.Errorf("bind warn-on-parse-error flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("template-defaults", cmd.Flags().Lookup("template-defaults")); err != nil {
+				return fmt.Errorf("bind template-defaults flag: %w", err)
+			}
+
+			if err := runOutdatedCommand(cmd.Context(), args); err != nil {
+				return fmt.Errorf("outdated: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("patch", false, "Print a patch that updates the outdated images in the resource files to their newest version instead")
+	cmd.Flags().Bool("helm", false, "Render Helm charts found at the source before finding images (requires helm)")
+	cmd.Flags().StringSlice("helm-values", []string{}, "Values files to use when rendering Helm charts")
+	cmd.Flags().Bool("kustomize", false, "Build kustomizations found at the source before finding images (requires kustomize)")
+	cmd.Flags().Bool("env-images", false, "Find images in the values of container environment variables")
+	cmd.Flags().String("env-images-pattern", "IMAGE", "Regular expression that environment variable names must match to be searched for images")
+	cmd.Flags().String("crd-config", "", "Path to a file that declares where images are located in custom resources")
+	cmd.Flags().Bool("dockerfiles", false, "Also find the base images in the FROM instructions of Dockerfiles")
+	cmd.Flags().Bool("ci-files", false, "Also find the images of the jobs in GitHub Actions workflows and GitLab CI files")
+	cmd.Flags().Bool("terraform", false, "Also find the images set in Terraform files by the Kubernetes and Helm providers")
+	cmd.Flags().StringSlice("skip-dirs", []string{}, "Directories to skip when finding images, by name or by path relative to the source (e.g. testdata,charts/*/tests)")
+	cmd.Flags().Bool("no-gitignore", false, "Also find images in files and directories that are ignored by .gitignore files")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symbolic links to directories when finding images")
+	cmd.Flags().StringSlice("kinds", []string{}, "Only find images in the resources of the given kinds (e.g. Deployment,CronJob)")
+	cmd.Flags().StringSlice("exclude-kinds", []string{}, "Skip the resources of the given kinds when finding images (e.g. ConfigMap)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Only find images in the resources in the given namespaces")
+	cmd.Flags().StringP("selector", "l", "", "Only find images in the resources whose labels match the selector (e.g. app.kubernetes.io/part-of=platform)")
+	cmd.Flags().String("max-document-size", "16MiB", "Maximum size of a YAML document (e.g. 64MiB), larger documents are skipped (0 does not limit the size)")
+	cmd.Flags().Bool("warn-on-parse-error", true, "Warn about YAML files that cannot be parsed, which are otherwise skipped")
+	cmd.Flags().Bool("template-defaults", false, "Find the images of unrendered Helm templates on a best-effort basis with the default values of their chart")
+
+	return &cmd
+}
+
+func runOutdatedCommand(ctx context.Context, paths []string) error {
+	ctx, cancel := withCommandTimeout(ctx, 0)
+	defer cancel()
+
+	opts, err := getAutodetectOptions(ctx)
+	if err != nil {
+		return fmt.Errorf("get autodetect options: %w", err)
+	}
+
+	foundImages, err := images.FindImagesInPaths(paths, opts...)
+	if err != nil {
+		return fmt.Errorf("find images: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	// Images in the same repository (e.g. with different tags) only need their tags listed once.
+	repositoryTags := make(map[string][]string)
+
+	var outdatedImages []outdatedImage
+	for _, image := range foundImages {
+		if image.Tag == "" || image.Digest != "" {
+			continue
+		}
+
+		currentVersion, err := version.NewSemver(image.Tag)
+		if err != nil {
+			log.Debugf("Image %s does not have a version. Skipping ...", image)
+			continue
+		}
+
+		repository := image.Host + "/" + image.Repository
+		tags, ok := repositoryTags[repository]
+		if !ok {
+			tags, err = client.GetTagsForRepository(ctx, image.Host, image.Repository)
+			if err != nil {
+				log.Warnf("Unable to list the tags of %s: %v", image, err)
+				continue
+			}
+
+			repositoryTags[repository] = tags
+		}
+
+		latest, ok := getLatestStableVersion(currentVersion, image.Tag, tags)
+		if !ok {
+			continue
+		}
+
+		outdatedImages = append(outdatedImages, outdatedImage{image: image, latest: latest})
+	}
+
+	if viper.GetBool("patch") {
+		if err := writeOutdatedPatch(os.Stdout, outdatedImages); err != nil {
+			return fmt.Errorf("write patch: %w", err)
+		}
+
+		return nil
+	}
+
+	if len(outdatedImages) == 0 {
+		log.Infof("All images are up to date!")
+		return nil
+	}
+
+	if err := writeOutdatedImages(os.Stdout, outdatedImages); err != nil {
+		return fmt.Errorf("write outdated images: %w", err)
+	}
+
+	return nil
+}
+
+// getLatestStableVersion returns the tag of the newest stable version (without a pre-release)
+// that is newer than the current version. Only tags that are written in the same way as the
+// current tag (e.g. with a v prefix) are considered, so that the updated image keeps its format.
+func getLatestStableVersion(currentVersion *version.Version, currentTag string, tags []string) (string, bool) {
+	hasPrefix := strings.HasPrefix(currentTag, "v")
+
+	var latestVersion *version.Version
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "v") != hasPrefix {
+			continue
+		}
+
+		tagVersion, err := version.NewSemver(tag)
+		if err != nil || tagVersion.Prerelease() != "" || tagVersion.Metadata() != "" {
+			continue
+		}
+
+		// Tags must have as many segments as the current tag (e.g. 1.19 is not updated to 1.20.1).
+		if strings.Count(tag, ".") != strings.Count(currentTag, ".") {
+			continue
+		}
+
+		if !tagVersion.GreaterThan(currentVersion) {
+			continue
+		}
+
+		if latestVersion == nil || tagVersion.GreaterThan(latestVersion) {
+			latestVersion = tagVersion
+		}
+	}
+
+	if latestVersion == nil {
+		return "", false
+	}
+
+	return latestVersion.Original(), true
+}
+
+func writeOutdatedImages(w io.Writer, outdatedImages []outdatedImage) error {
+	sort.Slice(outdatedImages, func(i, j int) bool {
+		return outdatedImages[i].image.Reference < outdatedImages[j].image.Reference
+	})
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, "IMAGE\tCURRENT\tLATEST"); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, outdated := range outdatedImages {
+		repository := strings.TrimSuffix(outdated.image.Reference, ":"+outdated.image.Tag)
+		if _, err := fmt.Fprintf(table, "%s\t%s\t%s\n", repository, outdated.image.Tag, outdated.latest); err != nil {
+			return fmt.Errorf("write image: %w", err)
+		}
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
+
+// writeOutdatedPatch writes a patch that updates the references to the outdated images in the files
+// they were found in. Images found in rendered resources (e.g. Helm charts) or stdin cannot be updated
+// in place, as the reference does not appear in a file as is.
+func writeOutdatedPatch(w io.Writer, outdatedImages []outdatedImage) error {
+	replacements := make(map[string]map[string]string)
+	for _, outdated := range outdatedImages {
+		for _, resource := range outdated.image.Resources {
+			info, err := os.Stat(resource.Path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+
+			if replacements[resource.Path] == nil {
+				replacements[resource.Path] = make(map[string]string)
+			}
+
+			replacements[resource.Path][outdated.image.Reference] = outdated.updatedReference()
+		}
+	}
+
+	var paths []string
+	for path := range replacements {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read file: %w", err)
+		}
+
+		patch, err := manifest.GetPatch(path, contents, manifest.ReplaceImages(contents, replacements[path]))
+		if err != nil {
+			return fmt.Errorf("get patch of %s: %w", path, err)
+		}
+
+		if _, err := io.WriteString(w, patch); err != nil {
+			return fmt.Errorf("write patch of %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
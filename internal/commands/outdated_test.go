@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestGetLatestStableVersion(t *testing.T) {
+	tags := []string{"1.19.1", "1.19.2", "1.20.0-rc.1", "v1.21.0", "1.20", "1.19.3-alpine", "latest"}
+
+	testCases := []struct {
+		current  string
+		expected string
+	}{
+		{current: "1.19.1", expected: "1.19.2"},
+		{current: "1.19", expected: "1.20"},
+		{current: "v1.20.0", expected: "v1.21.0"},
+		{current: "1.19.2", expected: ""},
+	}
+
+	for _, testCase := range testCases {
+		currentVersion, err := version.NewSemver(testCase.current)
+		if err != nil {
+			t.Fatal("new semver:", err)
+		}
+
+		actual, _ := getLatestStableVersion(currentVersion, testCase.current, tags)
+		if actual != testCase.expected {
+			t.Errorf("expected latest version of %s to be %q, actual %q", testCase.current, testCase.expected, actual)
+		}
+	}
+}
+
+func TestWriteOutdatedPatch(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	resourcePath := filepath.Join(tempDir, "deployment.yaml")
+	resource := "spec:\n  containers:\n  - name: app\n    image: busybox:1.32.0 # pinned\n"
+	if err := ioutil.WriteFile(resourcePath, []byte(resource), 0644); err != nil {
+		t.Fatal("write resource:", err)
+	}
+
+	outdatedImages := []outdatedImage{
+		{
+			image: images.Image{
+				Reference:  "busybox:1.32.0",
+				Repository: "busybox",
+				Tag:        "1.32.0",
+				Resources:  []images.Resource{{Path: resourcePath}},
+			},
+			latest: "1.33.1",
+		},
+	}
+
+	var patch bytes.Buffer
+	if err := writeOutdatedPatch(&patch, outdatedImages); err != nil {
+		t.Fatal("write outdated patch:", err)
+	}
+
+	path := filepath.ToSlash(resourcePath)
+	expected := "--- a/" + path + "\n" +
+		"+++ b/" + path + "\n" +
+		"@@ -1,4 +1,4 @@\n" +
+		" spec:\n" +
+		"   containers:\n" +
+		"   - name: app\n" +
+		"-    image: busybox:1.32.0 # pinned\n" +
+		"+    image: busybox:1.33.1 # pinned\n"
+
+	if patch.String() != expected {
+		t.Errorf("unexpected patch, expected:\n%s\nactual:\n%s", expected, patch.String())
+	}
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes the contents to the file at the given path, creating its directory when it
+// does not exist. The contents are written to a temporary file in the same directory that is then
+// renamed over the file, so that an interrupted write never leaves a partially written file behind.
+func writeFileAtomic(path string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+
+	tempFile, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	if _, err := tempFile.Write(contents); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("write: %w", err)
+	}
+
+	// Temporary files are only readable by their owner, while the
+	// file would have been readable by everyone if it was created.
+	if err := tempFile.Chmod(0644); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("chmod: %w", err)
+	}
+
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("close: %w", err)
+	}
+
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		os.Remove(tempFile.Name())
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// plannedImage is an image in the manifest and the number of bytes that pushing it transfers.
+type plannedImage struct {
+	source   string
+	target   string
+	exists   bool
+	transfer int64
+}
+
+// transferPlan is what pushing the images in the manifest transfers to the target.
+type transferPlan struct {
+	images []plannedImage
+
+	// transfer is the number of bytes of the blobs that do not exist at the target, counting
+	// each blob once, and shared is the number of bytes of the blobs of the missing images that
+	// are not transferred because they exist at the target or are shared with another image.
+	transfer int64
+	shared   int64
+	blobs    int
+}
+
+func newPlanCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "plan",
+		Short: "Estimate the size and duration of pushing the images in the manifest to the target",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("images", cmd.Flags().Lookup("images")); err != nil {
+				return fmt.Errorf("bind images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platforms", cmd.Flags().Lookup("platforms")); err != nil {
+				return fmt.Errorf("bind platforms flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("bandwidth", cmd.Flags().Lookup("bandwidth")); err != nil {
+				return fmt.Errorf("bind bandwidth flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("jobs", cmd.Flags().Lookup("jobs")); err != nil {
+				return fmt.Errorf("bind jobs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude", cmd.Flags().Lookup("exclude")); err != nil {
+				return fmt.Errorf("bind exclude flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-regex", cmd.Flags().Lookup("exclude-regex")); err != nil {
+				return fmt.Errorf("bind exclude-regex flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("source-filter", cmd.Flags().Lookup("source-filter")); err != nil {
+				return fmt.Errorf("bind source-filter flag: %w", err)
+			}
+
+			if len(viper.GetStringSlice("images")) > 0 && viper.GetString("target") == "" {
+				return errors.New("target must be specified when using the images flag")
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runPlanCommand(cmd.Context(), manifestPath); err != nil {
+				return fmt.Errorf("plan: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceP("images", "i", []string{}, "List of images to plan (e.g. host.com/repo:v1.0.0)")
+	cmd.Flags().StringP("target", "t", "", "Registry the images will be pushed to when using the images flag")
+	cmd.Flags().StringSlice("platforms", []string{}, "Platforms that will be pushed for multi-arch images (e.g. linux/amd64,linux/arm64), defaults to all platforms")
+	cmd.Flags().String("bandwidth", "", "Bandwidth available for the transfer to estimate its duration with (e.g. 50MiB/s)")
+	cmd.Flags().IntP("jobs", "j", 10, "Number of images to query at the same time")
+	cmd.Flags().StringSlice("exclude", []string{}, "Glob patterns of images to exclude (e.g. busybox:*)")
+	cmd.Flags().StringSlice("exclude-regex", []string{}, "Regular expressions of images to exclude")
+	cmd.Flags().StringSlice("source-filter", []string{}, "Only include images from the given source registries (e.g. quay.io,gcr.io)")
+
+	return &cmd
+}
+
+func runPlanCommand(ctx context.Context, manifestPath string) error {
+	ctx, cancel := withCommandTimeout(ctx, 0)
+	defer cancel()
+
+	var bandwidth int64
+	if viper.GetString("bandwidth") != "" {
+		var err error
+		bandwidth, err = docker.ParseBandwidth(viper.GetString("bandwidth"))
+		if err != nil {
+			return fmt.Errorf("parse bandwidth: %w", err)
+		}
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	sources, _, err := getImagesOrManifestSources(ctx, manifestPath)
+	if err != nil {
+		return fmt.Errorf("get sources: %w", err)
+	}
+
+	log.Infof("Querying the sizes of %v image(s) ...", len(sources))
+
+	plan, err := getTransferPlan(ctx, client, sources, viper.GetStringSlice("platforms"))
+	if err != nil {
+		return fmt.Errorf("get transfer plan: %w", err)
+	}
+
+	if err := writeTransferPlan(os.Stdout, plan, bandwidth); err != nil {
+		return fmt.Errorf("write transfer plan: %w", err)
+	}
+
+	return nil
+}
+
+// getTransferPlan returns the plan of pushing the sources to their targets. Images that exist at the target
+// are not transferred, and neither are the blobs of the missing images that exist in the images at the target.
+// Every other blob is transferred once, even when it is shared by several images.
+func getTransferPlan(ctx context.Context, client docker.Client, sources []manifest.Source, platforms []string) (transferPlan, error) {
+	plan := transferPlan{images: make([]plannedImage, len(sources))}
+	imageBlobs := make([][]docker.Blob, len(sources))
+
+	var mutex sync.Mutex
+	existingBlobs := make(map[string]bool)
+
+	query := func(i int) error {
+		source := sources[i]
+		plan.images[i] = plannedImage{source: source.Image(), target: source.TargetImage()}
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		exists, err := client.ManifestExistsAtRemote(ctx, source.TargetImage(), targetAuth)
+		if err != nil {
+			return fmt.Errorf("manifest exists at remote: %w", err)
+		}
+
+		if exists {
+			plan.images[i].exists = true
+
+			blobs, err := client.GetBlobs(ctx, source.TargetImage(), targetAuth, nil)
+			if err != nil {
+				return fmt.Errorf("get blobs of %s: %w", source.TargetImage(), err)
+			}
+
+			mutex.Lock()
+			for _, blob := range blobs {
+				existingBlobs[blob.Digest] = true
+			}
+			mutex.Unlock()
+
+			return nil
+		}
+
+		sourceAuth, err := getSourceAuth(source)
+		if err != nil {
+			return fmt.Errorf("get source auth: %w", err)
+		}
+
+		// The platforms of artifacts are not filtered when they are pushed.
+		sourcePlatforms := platforms
+		if source.IsArtifact() {
+			sourcePlatforms = nil
+		}
+
+		blobs, err := client.GetBlobs(ctx, source.PullImage(), sourceAuth, sourcePlatforms)
+		if err != nil {
+			return fmt.Errorf("get blobs of %s: %w", source.Image(), err)
+		}
+		imageBlobs[i] = blobs
+
+		return nil
+	}
+
+	if err := runJobs(ctx, viper.GetInt("jobs"), len(sources), query); err != nil {
+		return transferPlan{}, err
+	}
+
+	// The blobs are assigned to the images in the order of the manifest, so that the
+	// plan is the same every time regardless of the order the images were queried in.
+	transferredBlobs := make(map[string]bool)
+	for i := range plan.images {
+		for _, blob := range imageBlobs[i] {
+			if existingBlobs[blob.Digest] || transferredBlobs[blob.Digest] {
+				plan.shared += blob.Size
+				continue
+			}
+
+			transferredBlobs[blob.Digest] = true
+			plan.images[i].transfer += blob.Size
+			plan.transfer += blob.Size
+			plan.blobs++
+		}
+	}
+
+	return plan, nil
+}
+
+func writeTransferPlan(w io.Writer, plan transferPlan, bandwidth int64) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, "IMAGE\tTARGET\tTRANSFER"); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	var missingImages int
+	for _, image := range plan.images {
+		transfer := formatBytes(uint64(image.transfer))
+		if image.exists {
+			transfer = "exists"
+		} else {
+			missingImages++
+		}
+
+		if _, err := fmt.Fprintf(table, "%s\t%s\t%s\n", image.source, image.target, transfer); err != nil {
+			return fmt.Errorf("write image: %w", err)
+		}
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	log.Infof("%v of %v image(s) will be pushed, transferring %v blob(s) of %s (%s of shared layers and layers that exist at the target are not transferred)",
+		missingImages, len(plan.images), plan.blobs, formatBytes(uint64(plan.transfer)), formatBytes(uint64(plan.shared)))
+
+	if bandwidth > 0 {
+		duration := time.Duration(float64(plan.transfer) / float64(bandwidth) * float64(time.Second))
+		log.Infof("Estimated duration: %v at %s", duration.Round(time.Second), viper.GetString("bandwidth"))
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestGetTransferPlan(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	base, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	// The application image is built on top of the base image, so only its own layer is transferred.
+	layer, err := random.Layer(2048, "application/vnd.docker.image.rootfs.diff.tar.gzip")
+	if err != nil {
+		t.Fatal("random layer:", err)
+	}
+
+	application, err := mutate.AppendLayers(base, layer)
+	if err != nil {
+		t.Fatal("append layers:", err)
+	}
+
+	images := map[string]v1.Image{
+		host + "/source/base:1.0":        base,
+		host + "/source/application:1.0": application,
+		host + "/target/source/base:1.0": base,
+	}
+
+	for image, contents := range images {
+		reference, err := name.ParseReference(image, name.WeakValidation)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.Write(reference, contents); err != nil {
+			t.Fatal("write image:", err)
+		}
+	}
+
+	target := manifest.Target{Host: host, Repository: "target"}
+	sources := []manifest.Source{
+		{Host: host, Repository: "source/base", Tag: "1.0", Target: target},
+		{Host: host, Repository: "source/application", Tag: "1.0", Target: target},
+	}
+
+	client, err := docker.NewClient(t.Logf)
+	if err != nil {
+		t.Fatal("new client:", err)
+	}
+
+	plan, err := getTransferPlan(context.Background(), client, sources, nil)
+	if err != nil {
+		t.Fatal("get transfer plan:", err)
+	}
+
+	rawManifest, err := application.RawManifest()
+	if err != nil {
+		t.Fatal("raw manifest:", err)
+	}
+
+	applicationManifest, err := application.Manifest()
+	if err != nil {
+		t.Fatal("manifest:", err)
+	}
+
+	layerSize, err := layer.Size()
+	if err != nil {
+		t.Fatal("layer size:", err)
+	}
+
+	expected := int64(len(rawManifest)) + applicationManifest.Config.Size + layerSize
+	if plan.transfer != expected {
+		t.Errorf("expected %v bytes to be transferred, actual %v", expected, plan.transfer)
+	}
+
+	if !plan.images[0].exists || plan.images[1].exists {
+		t.Errorf("expected only the base image to exist at the target, actual %+v", plan.images)
+	}
+
+	if plan.blobs != 3 {
+		t.Errorf("expected 3 blobs to be transferred, actual %v", plan.blobs)
+	}
+}
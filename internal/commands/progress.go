@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	// progressInterval is how often the progress is logged when not attached to a terminal.
+	progressInterval = 30 * time.Second
+
+	// progressRefresh is how often the progress bar is redrawn when attached to a terminal.
+	progressRefresh = 500 * time.Millisecond
+
+	progressBarWidth = 30
+)
+
+// progress reports how many images have been completed, how many bytes have been
+// transferred and how long the remaining images are expected to take.
+type progress struct {
+	verb       string
+	total      int
+	completed  int32
+	start      time.Time
+	startBytes uint64
+}
+
+func newProgress(verb string, total int) *progress {
+	progress := progress{
+		verb:       verb,
+		total:      total,
+		start:      time.Now(),
+		startBytes: metrics.BytesTransferred.Value(),
+	}
+
+	return &progress
+}
+
+// complete records that an image has been completed.
+func (p *progress) complete() {
+	atomic.AddInt32(&p.completed, 1)
+}
+
+// run reports the progress until the returned stop function is called. When stderr is a
+// terminal, a progress bar is drawn. Otherwise, a summary line is logged periodically.
+// Nothing is reported when the output is quiet.
+func (p *progress) run(ctx context.Context) func() {
+	if viper.GetBool("quiet") {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	interactive := isTerminal(os.Stderr)
+	interval := progressInterval
+	if interactive {
+		interval = progressRefresh
+	}
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if interactive {
+					fmt.Fprintf(os.Stderr, "\r\033[K%s\n", p.bar(time.Now()))
+				} else {
+					log.Info(p.summary(time.Now()))
+				}
+				return
+
+			case now := <-ticker.C:
+				if interactive {
+					fmt.Fprintf(os.Stderr, "\r\033[K%s", p.bar(now))
+				} else {
+					log.Info(p.summary(now))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// summary returns a line such as: Pushed 3/10 image(s), 120.5 MB transferred, 2m30s remaining
+func (p *progress) summary(now time.Time) string {
+	completed := int(atomic.LoadInt32(&p.completed))
+
+	parts := []string{fmt.Sprintf("%s %v/%v image(s)", p.verb, completed, p.total)}
+	if transferred := metrics.BytesTransferred.Value() - p.startBytes; transferred > 0 {
+		parts = append(parts, formatBytes(transferred)+" transferred")
+	}
+
+	parts = append(parts, getRemaining(now.Sub(p.start), completed, p.total))
+
+	return strings.Join(parts, ", ")
+}
+
+// bar returns the summary prefixed with a bar of the completed images.
+func (p *progress) bar(now time.Time) string {
+	completed := int(atomic.LoadInt32(&p.completed))
+
+	filled := progressBarWidth
+	if p.total > 0 {
+		filled = completed * progressBarWidth / p.total
+	}
+
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", progressBarWidth-filled) + "] " + p.summary(now)
+}
+
+// getRemaining estimates the time remaining from the average time each completed image has taken.
+func getRemaining(elapsed time.Duration, completed int, total int) string {
+	if completed >= total {
+		return "completed in " + elapsed.Round(time.Second).String()
+	}
+
+	if completed == 0 {
+		return "estimating time remaining"
+	}
+
+	remaining := elapsed / time.Duration(completed) * time.Duration(total-completed)
+	return remaining.Round(time.Second).String() + " remaining"
+}
+
+func formatBytes(bytes uint64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value := float64(bytes)
+	var exponent int
+	for value >= unit && exponent < 4 {
+		value /= unit
+		exponent++
+	}
+
+	return fmt.Sprintf("%.1f %cB", value, "kMGT"[exponent-1])
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/metrics"
+)
+
+func TestProgress_Bar(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	progress := progress{
+		verb:       "Pushed",
+		total:      4,
+		start:      start,
+		startBytes: metrics.BytesTransferred.Value(),
+	}
+
+	progress.complete()
+
+	expected := "[#######.......................] Pushed 1/4 image(s), 30s remaining"
+	actual := progress.bar(start.Add(10 * time.Second))
+
+	if actual != expected {
+		t.Errorf("expected %q, actual %q", expected, actual)
+	}
+}
+
+func TestGetRemaining(t *testing.T) {
+	testCases := []struct {
+		completed int
+		expected  string
+	}{
+		{0, "estimating time remaining"},
+		{2, "1m0s remaining"},
+		{4, "completed in 1m0s"},
+	}
+
+	for _, testCase := range testCases {
+		actual := getRemaining(time.Minute, testCase.completed, 4)
+		if actual != testCase.expected {
+			t.Errorf("expected %q for %v completed, actual %q", testCase.expected, testCase.completed, actual)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	testCases := map[uint64]string{
+		512:        "512 B",
+		1500:       "1.5 kB",
+		120500000:  "120.5 MB",
+		3000000000: "3.0 GB",
+	}
+
+	for bytes, expected := range testCases {
+		if actual := formatBytes(bytes); actual != expected {
+			t.Errorf("expected %q for %v bytes, actual %q", expected, bytes, actual)
+		}
+	}
+}
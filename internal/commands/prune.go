@@ -0,0 +1,341 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// pruneNamespace is a repository of the target registry that sinker mirrors images into.
+// Every repository under the namespace is considered to be managed by sinker.
+type pruneNamespace struct {
+	host       string
+	repository string
+	auth       string
+}
+
+func (n pruneNamespace) contains(repository string) bool {
+	return repository == n.repository || strings.HasPrefix(repository, n.repository+"/")
+}
+
+// staleImage is an image at the target that is not referenced by any of the manifests.
+type staleImage struct {
+	repository string
+	tag        string
+	digest     string
+	auth       string
+}
+
+func (s staleImage) image() string {
+	return s.repository + ":" + s.tag
+}
+
+func newPruneCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "prune [manifest...]",
+		Short: "Remove the images at the target registry that are no longer referenced by the manifests",
+		Long: `Remove the images at the target registry that are no longer referenced by the manifests.
+
+The images in the repositories under the targets of the manifests (and their mappings) that are
+not referenced by any of the manifests are listed. The images are only deleted when --delete is set.`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run")); err != nil {
+				return fmt.Errorf("bind dry-run flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("delete", cmd.Flags().Lookup("delete")); err != nil {
+				return fmt.Errorf("bind delete flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("audit-log", cmd.Flags().Lookup("audit-log")); err != nil {
+				return fmt.Errorf("bind audit-log flag: %w", err)
+			}
+
+			if viper.GetBool("dry-run") && viper.GetBool("delete") {
+				return errors.New("the dry-run and delete flags cannot be used together")
+			}
+
+			manifestPaths := args
+			if len(manifestPaths) == 0 {
+				manifestPaths = []string{viper.GetString("manifest")}
+			}
+
+			if err := runPruneCommand(cmd.Context(), manifestPaths, viper.GetBool("delete")); err != nil {
+				return fmt.Errorf("prune: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "List the images that are no longer referenced without deleting them (default behavior)")
+	cmd.Flags().Bool("delete", false, "Delete the images that are no longer referenced from the target registry")
+	cmd.Flags().String("audit-log", "", "Path to an append-only JSONL log to record every image that is deleted in")
+
+	return &cmd
+}
+
+func runPruneCommand(ctx context.Context, manifestPaths []string, deleteImages bool) error {
+	ctx, cancel := withCommandTimeout(ctx, 0)
+	defer cancel()
+
+	referencedImages := make(map[string]bool)
+	var namespaces []pruneNamespace
+	for _, manifestPath := range manifestPaths {
+		imageManifest, err := getManifest(manifestPath)
+		if err != nil {
+			return fmt.Errorf("get manifest %s: %w", manifestPath, err)
+		}
+
+		// Ignored images are still referenced by the manifest, so they are never pruned.
+		imageManifest.Sources, err = expandTagSelectors(ctx, imageManifest.Sources)
+		if err != nil {
+			return fmt.Errorf("expand tag selectors: %w", err)
+		}
+
+		for _, source := range imageManifest.Sources {
+			referencedImages[source.TargetImage()] = true
+		}
+
+		manifestNamespaces, err := getPruneNamespaces(imageManifest)
+		if err != nil {
+			return fmt.Errorf("get namespaces of %s: %w", manifestPath, err)
+		}
+
+		namespaces = append(namespaces, manifestNamespaces...)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	staleImages, err := findStaleImages(ctx, client, namespaces, referencedImages)
+	if err != nil {
+		return fmt.Errorf("find stale images: %w", err)
+	}
+
+	if len(staleImages) == 0 {
+		log.Infof("No images to prune!")
+		return nil
+	}
+
+	if !deleteImages {
+		for _, stale := range staleImages {
+			log.Infof("Would delete image %s", stale.image())
+		}
+
+		log.Infof("Found %d images to prune. Run with --delete to delete them.", len(staleImages))
+		return nil
+	}
+
+	var audit *auditLog
+	if viper.GetString("audit-log") != "" {
+		audit = newAuditLog(viper.GetString("audit-log"))
+	}
+
+	// Deleting a manifest removes every tag of the manifest, so each digest only needs to be deleted once.
+	deletedDigests := make(map[string]bool)
+	for _, stale := range staleImages {
+		digestImage := stale.repository + "@" + stale.digest
+		if deletedDigests[digestImage] {
+			continue
+		}
+
+		log.Infof("Deleting image %s", stale.image())
+		deleteErr := client.DeleteImage(ctx, digestImage, stale.auth)
+		if audit != nil {
+			entry := auditEntry{Operation: auditOperationDelete, Digest: stale.digest, Target: stale.image()}
+			if deleteErr != nil {
+				entry.Error = deleteErr.Error()
+			}
+
+			if err := audit.record(entry); err != nil {
+				return fmt.Errorf("record delete %s: %w", stale.image(), err)
+			}
+		}
+
+		if deleteErr != nil {
+			return fmt.Errorf("delete image %s: %w", stale.image(), deleteErr)
+		}
+
+		deletedDigests[digestImage] = true
+	}
+
+	log.Infof("Pruned %d images", len(staleImages))
+
+	return nil
+}
+
+// getPruneNamespaces returns the repositories of the target registries that the images of the manifest
+// are mirrored into. A target without a repository would make the entire registry a candidate for
+// pruning, which is refused as the registry is likely to contain images that are not managed by sinker.
+func getPruneNamespaces(imageManifest manifest.Manifest) ([]pruneNamespace, error) {
+	targets := []manifest.Target{imageManifest.Target}
+	for _, mapping := range imageManifest.Mappings {
+		targets = append(targets, manifest.Target{
+			Host:       imageManifest.Target.Host,
+			Repository: mapping.Repository,
+			Auth:       imageManifest.Target.Auth,
+		})
+	}
+
+	for _, source := range imageManifest.Sources {
+		targets = append(targets, source.Target)
+	}
+
+	seen := make(map[string]bool)
+	var namespaces []pruneNamespace
+	for _, target := range targets {
+		repository := strings.Trim(target.Repository, "/")
+		if target.Host == "" || repository == "" {
+			return nil, fmt.Errorf("target %s/%s must have a host and a repository to be pruned", target.Host, repository)
+		}
+
+		if seen[target.Host+"/"+repository] {
+			continue
+		}
+		seen[target.Host+"/"+repository] = true
+
+		auth, err := getTargetAuth(target)
+		if err != nil {
+			return nil, fmt.Errorf("get target auth: %w", err)
+		}
+
+		namespaces = append(namespaces, pruneNamespace{
+			host:       target.Host,
+			repository: repository,
+			auth:       auth,
+		})
+	}
+
+	return namespaces, nil
+}
+
+// findStaleImages returns the images in the repositories under the namespaces that are not referenced.
+//
+// Images that share their manifest with a referenced image (e.g. another tag of the same image) are
+// kept, as deleting the manifest would also delete the referenced image. Artifacts that are attached
+// to an image by its digest (e.g. signatures) are kept for as long as the image they are attached to is.
+func findStaleImages(ctx context.Context, client docker.Client, namespaces []pruneNamespace, referencedImages map[string]bool) ([]staleImage, error) {
+	hostNamespaces := make(map[string][]pruneNamespace)
+	var hosts []string
+	for _, namespace := range namespaces {
+		if _, ok := hostNamespaces[namespace.host]; !ok {
+			hosts = append(hosts, namespace.host)
+		}
+
+		hostNamespaces[namespace.host] = append(hostNamespaces[namespace.host], namespace)
+	}
+	sort.Strings(hosts)
+
+	var staleImages []staleImage
+	for _, host := range hosts {
+		repositories, err := client.GetRepositories(ctx, host, hostNamespaces[host][0].auth)
+		if err != nil {
+			return nil, fmt.Errorf("get repositories of %s: %w", host, err)
+		}
+		sort.Strings(repositories)
+
+		for _, repository := range repositories {
+			namespace, ok := findPruneNamespace(hostNamespaces[host], repository)
+			if !ok {
+				continue
+			}
+
+			repositoryStaleImages, err := findStaleImagesInRepository(ctx, client, host+"/"+repository, namespace.auth, referencedImages)
+			if err != nil {
+				return nil, fmt.Errorf("find stale images in %s: %w", repository, err)
+			}
+
+			staleImages = append(staleImages, repositoryStaleImages...)
+		}
+	}
+
+	return staleImages, nil
+}
+
+func findPruneNamespace(namespaces []pruneNamespace, repository string) (pruneNamespace, bool) {
+	for _, namespace := range namespaces {
+		if namespace.contains(repository) {
+			return namespace, true
+		}
+	}
+
+	return pruneNamespace{}, false
+}
+
+func findStaleImagesInRepository(ctx context.Context, client docker.Client, repository string, auth string, referencedImages map[string]bool) ([]staleImage, error) {
+	tags, err := client.GetTags(ctx, repository, auth)
+	if err != nil {
+		return nil, fmt.Errorf("get tags: %w", err)
+	}
+	sort.Strings(tags)
+
+	var keptTags, staleTags, attachedTags []string
+	for _, tag := range tags {
+		switch {
+		case referencedImages[repository+":"+tag]:
+			keptTags = append(keptTags, tag)
+		case strings.HasPrefix(tag, "sha256-"):
+			attachedTags = append(attachedTags, tag)
+		default:
+			staleTags = append(staleTags, tag)
+		}
+	}
+
+	if len(staleTags) == 0 && len(attachedTags) == 0 {
+		return nil, nil
+	}
+
+	keptDigests := make(map[string]bool)
+	for _, tag := range keptTags {
+		digest, err := client.GetDigest(ctx, repository+":"+tag, auth)
+		if err != nil {
+			return nil, fmt.Errorf("get digest of %s: %w", tag, err)
+		}
+
+		keptDigests[digest] = true
+	}
+
+	var staleImages []staleImage
+	for _, tag := range staleTags {
+		digest, err := client.GetDigest(ctx, repository+":"+tag, auth)
+		if err != nil {
+			return nil, fmt.Errorf("get digest of %s: %w", tag, err)
+		}
+
+		if keptDigests[digest] {
+			log.Debugf("Image %s:%s has the same digest as a referenced image. Skipping ...", repository, tag)
+			continue
+		}
+
+		staleImages = append(staleImages, staleImage{repository: repository, tag: tag, digest: digest, auth: auth})
+	}
+
+	for _, tag := range attachedTags {
+		attachedTo := strings.Replace(strings.SplitN(tag, ".", 2)[0], "-", ":", 1)
+		if keptDigests[attachedTo] {
+			continue
+		}
+
+		digest, err := client.GetDigest(ctx, repository+":"+tag, auth)
+		if err != nil {
+			return nil, fmt.Errorf("get digest of %s: %w", tag, err)
+		}
+
+		staleImages = append(staleImages, staleImage{repository: repository, tag: tag, digest: digest, auth: auth})
+	}
+
+	return staleImages, nil
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+)
+
+func TestFindStaleImages(t *testing.T) {
+	manifests := map[string]string{
+		"mirror/busybox:1.32.0": "current",
+		"mirror/busybox:1.31.0": "old",
+		"mirror/busybox:stable": "current",
+		"mirror/busybox:1.30.0": "older",
+		"other/busybox:1.31.0":  "old",
+	}
+
+	digests := make(map[string]string)
+	for _, contents := range manifests {
+		digests[contents] = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(contents)))
+	}
+
+	// Signatures are attached to an image by the digest of the image.
+	manifests["mirror/busybox:"+strings.Replace(digests["current"], ":", "-", 1)+".sig"] = "current signature"
+	manifests["mirror/busybox:"+strings.Replace(digests["older"], ":", "-", 1)+".sig"] = "older signature"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/":
+			w.WriteHeader(http.StatusOK)
+
+		case r.URL.Path == "/v2/_catalog":
+			json.NewEncoder(w).Encode(map[string][]string{"repositories": {"mirror/busybox", "other/busybox"}})
+
+		case strings.HasSuffix(r.URL.Path, "/tags/list"):
+			repository := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/tags/list")
+
+			tags := []string{}
+			for image := range manifests {
+				if strings.HasPrefix(image, repository+":") {
+					tags = append(tags, strings.TrimPrefix(image, repository+":"))
+				}
+			}
+
+			json.NewEncoder(w).Encode(map[string]interface{}{"name": repository, "tags": tags})
+
+		case strings.Contains(r.URL.Path, "/manifests/"):
+			image := strings.Replace(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/", ":", 1)
+			contents, ok := manifests[image]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+			w.Header().Set("Docker-Content-Digest", fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(contents))))
+			w.Write([]byte(contents))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	client, err := docker.NewClient(t.Logf)
+	if err != nil {
+		t.Fatal("new client:", err)
+	}
+
+	namespaces := []pruneNamespace{{host: host, repository: "mirror"}}
+	referencedImages := map[string]bool{
+		host + "/mirror/busybox:1.32.0": true,
+	}
+
+	staleImages, err := findStaleImages(context.Background(), client, namespaces, referencedImages)
+	if err != nil {
+		t.Fatal("find stale images:", err)
+	}
+
+	var actual []string
+	for _, stale := range staleImages {
+		actual = append(actual, strings.TrimPrefix(stale.image(), host+"/"))
+	}
+
+	// The stable tag shares its digest with a referenced image and the signature of the
+	// referenced image is attached to it, so neither are stale.
+	expected := []string{
+		"mirror/busybox:1.30.0",
+		"mirror/busybox:1.31.0",
+		"mirror/busybox:" + strings.Replace(digests["older"], ":", "-", 1) + ".sig",
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected stale images %v, actual %v", expected, actual)
+	}
+}
+
+func TestPruneNamespaceContains(t *testing.T) {
+	namespace := pruneNamespace{repository: "mirror"}
+
+	if !namespace.contains("mirror/busybox") {
+		t.Error("expected repository under the namespace to be contained")
+	}
+
+	if namespace.contains("mirrors/busybox") {
+		t.Error("expected repository that only shares a prefix not to be contained")
+	}
+}
@@ -3,10 +3,12 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/plexsystems/sinker/internal/docker"
 	"github.com/plexsystems/sinker/internal/manifest"
+	imageref "github.com/plexsystems/sinker/pkg/images"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -25,13 +27,21 @@ func newPullCommand() *cobra.Command {
 				return fmt.Errorf("bind images flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("jobs", cmd.Flags().Lookup("jobs")); err != nil {
+				return fmt.Errorf("bind jobs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("rate-limit", cmd.Flags().Lookup("rate-limit")); err != nil {
+				return fmt.Errorf("bind rate-limit flag: %w", err)
+			}
+
 			var origin string
 			if len(args) > 0 {
 				origin = args[0]
 			}
 
 			manifestPath := viper.GetString("manifest")
-			if err := runPullCommand(origin, manifestPath); err != nil {
+			if err := runPullCommand(cmd.Context(), origin, manifestPath); err != nil {
 				return fmt.Errorf("pull: %w", err)
 			}
 
@@ -40,19 +50,25 @@ func newPullCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringSliceP("images", "i", []string{}, "List of images to pull (e.g. host.com/repo:v1.0.0)")
+	cmd.Flags().IntP("jobs", "j", 1, "Number of images to pull at the same time")
+	cmd.Flags().Int("rate-limit", 0, "Maximum number of images to pull from each source registry per minute (defaults to no limit)")
 
 	return &cmd
 }
 
-func runPullCommand(origin string, manifestPath string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+func runPullCommand(ctx context.Context, origin string, manifestPath string) error {
+	ctx, cancel := withCommandTimeout(ctx, 30*time.Minute)
 	defer cancel()
 
-	client, err := docker.NewClient(log.Infof)
+	client, err := newClient()
 	if err != nil {
 		return fmt.Errorf("new client: %w", err)
 	}
 
+	if viper.GetInt("rate-limit") > 0 {
+		client = client.WithRateLimiter(docker.NewRateLimiter(viper.GetInt("rate-limit")))
+	}
+
 	var images map[string]string
 	if len(viper.GetStringSlice("images")) > 0 {
 		images, err = getImagesFromCommandLine(viper.GetStringSlice("images"))
@@ -77,12 +93,34 @@ func runPullCommand(origin string, manifestPath string) error {
 		}
 	}
 
-	for image, auth := range imagesToPull {
+	var imageNames []string
+	for image := range imagesToPull {
+		imageNames = append(imageNames, image)
+	}
+
+	pullProgress := newProgress("Pulled", len(imageNames))
+
+	var pulled int32
+	pull := func(i int) error {
+		image := imageNames[i]
+
 		log.Infof("Pulling %s", image)
-		if err := client.PullImageAndWait(ctx, image, auth); err != nil {
-			return fmt.Errorf("pull image and wait: %w", err)
+		if err := client.PullImageAndWait(ctx, image, imagesToPull[image]); err != nil {
+			log.Errorf("Unable to pull %s: %v", image, err)
+			return fmt.Errorf("pull image %s: %w", image, err)
 		}
-		log.Infof("Pulled %s", image)
+
+		pullProgress.complete()
+		log.Infof("Pulled %s (%v/%v)", image, atomic.AddInt32(&pulled, 1), len(imageNames))
+		return nil
+	}
+
+	stopProgress := pullProgress.run(ctx)
+	err = runJobs(ctx, viper.GetInt("jobs"), len(imageNames), pull)
+	stopProgress()
+
+	if err != nil {
+		return fmt.Errorf("pull images: %w", err)
 	}
 
 	log.Infof("All images have been pulled!")
@@ -91,23 +129,29 @@ func runPullCommand(origin string, manifestPath string) error {
 }
 
 func getImagesFromManifest(path string, origin string) (map[string]string, error) {
-	imageManifest, err := manifest.Get(path)
+	imageManifest, err := getManifest(path)
 	if err != nil {
 		return nil, fmt.Errorf("get manifest: %w", err)
 	}
 
 	images := make(map[string]string)
 	for _, source := range imageManifest.Sources {
+		// Helm charts and other artifacts cannot be pulled by the Docker daemon.
+		if source.IsArtifact() {
+			log.Infof("Skipping %s, which is a %s", source.Image(), source.Type)
+			continue
+		}
+
 		var image string
 		var auth string
 
 		var err error
 		if origin == "target" {
 			image = source.TargetImage()
-			auth, err = source.Target.EncodedAuth()
+			auth, err = getTargetAuth(source.Target)
 		} else {
 			image = source.Image()
-			auth, err = source.EncodedAuth()
+			auth, err = getSourceAuth(source)
 		}
 		if err != nil {
 			return nil, fmt.Errorf("get %s auth: %w", origin, err)
@@ -122,9 +166,16 @@ func getImagesFromManifest(path string, origin string) (map[string]string, error
 func getImagesFromCommandLine(images []string) (map[string]string, error) {
 	imgs := make(map[string]string)
 	for _, image := range images {
-		registryPath := docker.RegistryPath(image)
+		parsedImage, err := imageref.ParseReference(image, getRegistryHostsOption())
+		if err != nil {
+			return nil, fmt.Errorf("parse image %s: %w", image, err)
+		}
+
+		source := manifest.Source{
+			Host: parsedImage.Host,
+		}
 
-		auth, err := docker.GetEncodedAuthForHost(registryPath.Host())
+		auth, err := getSourceAuth(source)
 		if err != nil {
 			return nil, fmt.Errorf("get auth: %w", err)
 		}
@@ -4,22 +4,40 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/plexsystems/sinker/internal/docker"
 	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/internal/metrics"
+	"github.com/plexsystems/sinker/internal/scan"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// expiresAfterPattern matches the times that Quay expires images after, which are a number of hours, days or weeks.
+var expiresAfterPattern = regexp.MustCompile(`^[0-9]+[hdw]$`)
+
 func newPushCommand() *cobra.Command {
+	var deprecateErr error
 	cmd := cobra.Command{
 		Use:   "push",
 		Short: "Push the images in the manifest to the target repository",
 
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if deprecateErr != nil {
+				return fmt.Errorf("deprecate dryrun flag: %w", deprecateErr)
+			}
+
+			if err := viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run")); err != nil {
+				return fmt.Errorf("bind dry-run flag: %w", err)
+			}
+
 			if err := viper.BindPFlag("dryrun", cmd.Flags().Lookup("dryrun")); err != nil {
 				return fmt.Errorf("bind dryrun flag: %w", err)
 			}
@@ -28,16 +46,263 @@ func newPushCommand() *cobra.Command {
 				return fmt.Errorf("bind images flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("images-file", cmd.Flags().Lookup("images-file")); err != nil {
+				return fmt.Errorf("bind images-file flag: %w", err)
+			}
+
 			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
 				return fmt.Errorf("bind target flag: %w", err)
 			}
 
-			if len(viper.GetStringSlice("images")) > 0 && viper.GetString("target") == "" {
-				return errors.New("target must be specified when using the images flag")
+			if err := viper.BindPFlag("destination", cmd.Flags().Lookup("destination")); err != nil {
+				return fmt.Errorf("bind destination flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platforms", cmd.Flags().Lookup("platforms")); err != nil {
+				return fmt.Errorf("bind platforms flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("jobs", cmd.Flags().Lookup("jobs")); err != nil {
+				return fmt.Errorf("bind jobs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude", cmd.Flags().Lookup("exclude")); err != nil {
+				return fmt.Errorf("bind exclude flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-regex", cmd.Flags().Lookup("exclude-regex")); err != nil {
+				return fmt.Errorf("bind exclude-regex flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("source-filter", cmd.Flags().Lookup("source-filter")); err != nil {
+				return fmt.Errorf("bind source-filter flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("copy-signatures", cmd.Flags().Lookup("copy-signatures")); err != nil {
+				return fmt.Errorf("bind copy-signatures flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("copy-sboms", cmd.Flags().Lookup("copy-sboms")); err != nil {
+				return fmt.Errorf("bind copy-sboms flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verify", cmd.Flags().Lookup("verify")); err != nil {
+				return fmt.Errorf("bind verify flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verify-key", cmd.Flags().Lookup("verify-key")); err != nil {
+				return fmt.Errorf("bind verify-key flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verify-identity", cmd.Flags().Lookup("verify-identity")); err != nil {
+				return fmt.Errorf("bind verify-identity flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verify-oidc-issuer", cmd.Flags().Lookup("verify-oidc-issuer")); err != nil {
+				return fmt.Errorf("bind verify-oidc-issuer flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("require-signed", cmd.Flags().Lookup("require-signed")); err != nil {
+				return fmt.Errorf("bind require-signed flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("scan", cmd.Flags().Lookup("scan")); err != nil {
+				return fmt.Errorf("bind scan flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("scan-severity", cmd.Flags().Lookup("scan-severity")); err != nil {
+				return fmt.Errorf("bind scan-severity flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("scan-server", cmd.Flags().Lookup("scan-server")); err != nil {
+				return fmt.Errorf("bind scan-server flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("retries", cmd.Flags().Lookup("retries")); err != nil {
+				return fmt.Errorf("bind retries flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("rate-limit", cmd.Flags().Lookup("rate-limit")); err != nil {
+				return fmt.Errorf("bind rate-limit flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("annotate", cmd.Flags().Lookup("annotate")); err != nil {
+				return fmt.Errorf("bind annotate flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("expires-after", cmd.Flags().Lookup("expires-after")); err != nil {
+				return fmt.Errorf("bind expires-after flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("harbor-labels", cmd.Flags().Lookup("harbor-labels")); err != nil {
+				return fmt.Errorf("bind harbor-labels flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verify-digests", cmd.Flags().Lookup("verify-digests")); err != nil {
+				return fmt.Errorf("bind verify-digests flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verify-pull", cmd.Flags().Lookup("verify-pull")); err != nil {
+				return fmt.Errorf("bind verify-pull flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verification-report", cmd.Flags().Lookup("verification-report")); err != nil {
+				return fmt.Errorf("bind verification-report flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verification-report-key", cmd.Flags().Lookup("verification-report-key")); err != nil {
+				return fmt.Errorf("bind verification-report-key flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-bandwidth", cmd.Flags().Lookup("max-bandwidth")); err != nil {
+				return fmt.Errorf("bind max-bandwidth flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("state-file", cmd.Flags().Lookup("state-file")); err != nil {
+				return fmt.Errorf("bind state-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("failures-file", cmd.Flags().Lookup("failures-file")); err != nil {
+				return fmt.Errorf("bind failures-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("from-failures", cmd.Flags().Lookup("from-failures")); err != nil {
+				return fmt.Errorf("bind from-failures flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("cache-file", cmd.Flags().Lookup("cache-file")); err != nil {
+				return fmt.Errorf("bind cache-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("cache-ttl", cmd.Flags().Lookup("cache-ttl")); err != nil {
+				return fmt.Errorf("bind cache-ttl flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("create-repos", cmd.Flags().Lookup("create-repos")); err != nil {
+				return fmt.Errorf("bind create-repos flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("repo-tags", cmd.Flags().Lookup("repo-tags")); err != nil {
+				return fmt.Errorf("bind repo-tags flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("repo-scan-on-push", cmd.Flags().Lookup("repo-scan-on-push")); err != nil {
+				return fmt.Errorf("bind repo-scan-on-push flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("repo-immutable-tags", cmd.Flags().Lookup("repo-immutable-tags")); err != nil {
+				return fmt.Errorf("bind repo-immutable-tags flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("create-harbor-projects", cmd.Flags().Lookup("create-harbor-projects")); err != nil {
+				return fmt.Errorf("bind create-harbor-projects flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("harbor-project-public", cmd.Flags().Lookup("harbor-project-public")); err != nil {
+				return fmt.Errorf("bind harbor-project-public flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("harbor-project-quota", cmd.Flags().Lookup("harbor-project-quota")); err != nil {
+				return fmt.Errorf("bind harbor-project-quota flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-image-size", cmd.Flags().Lookup("max-image-size")); err != nil {
+				return fmt.Errorf("bind max-image-size flag: %w", err)
+			}
+
+			if _, err := scan.AtLeast(nil, viper.GetString("scan-severity")); viper.GetBool("scan") && err != nil {
+				return fmt.Errorf("scan severity: %w", err)
+			}
+
+			if _, err := docker.ParseSize(viper.GetString("max-image-size")); viper.GetString("max-image-size") != "" && err != nil {
+				return fmt.Errorf("max image size: %w", err)
+			}
+
+			if viper.GetBool("verify") && viper.GetString("verify-key") == "" && viper.GetString("verify-identity") == "" {
+				return errors.New("verify-key or verify-identity must be specified when using the verify flag")
+			}
+
+			if isVerifyingDigests() && len(viper.GetStringSlice("platforms")) > 0 {
+				return errors.New("digests cannot be verified when using the platforms flag, as the digest of a multi-arch image changes when platforms are removed")
+			}
+
+			if viper.GetBool("annotate") && (isVerifyingDigests() || viper.GetBool("copy-signatures")) {
+				return errors.New("annotate cannot be combined with verifying digests or copying signatures, as the annotations change the digest of each image")
+			}
+
+			if viper.GetString("expires-after") != "" && !expiresAfterPattern.MatchString(viper.GetString("expires-after")) {
+				return fmt.Errorf("invalid expires-after %s, expected a number of hours, days or weeks (e.g. 12h, 30d or 2w)", viper.GetString("expires-after"))
+			}
+
+			if viper.GetString("expires-after") != "" && (isVerifyingDigests() || viper.GetBool("copy-signatures")) {
+				return errors.New("expires-after cannot be combined with verifying digests or copying signatures, as the label changes the digest of each image")
+			}
+
+			if viper.GetString("verification-report-key") != "" && viper.GetString("verification-report") == "" {
+				return errors.New("verification-report must be specified when using the verification-report-key flag")
+			}
+
+			if hasInputImages() && viper.GetString("target") == "" {
+				return errors.New("target must be specified when using the images or images-file flag")
+			}
+
+			if flag := getRegistryOnlyFlag(); viper.GetString("destination") != "" && flag != "" {
+				return fmt.Errorf("%s can only be used when pushing to a registry, not a destination", flag)
+			}
+
+			if err := viper.BindPFlag("watch", cmd.Flags().Lookup("watch")); err != nil {
+				return fmt.Errorf("bind watch flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("interactive", cmd.Flags().Lookup("interactive")); err != nil {
+				return fmt.Errorf("bind interactive flag: %w", err)
+			}
+
+			if viper.GetBool("interactive") && (!isTerminal(os.Stdin) || !isTerminal(os.Stderr)) {
+				return errors.New("interactive can only be used in a terminal")
+			}
+
+			if viper.GetBool("interactive") && (viper.GetBool("watch") || viper.GetString("images-file") == "-") {
+				return errors.New("interactive cannot be combined with watch or reading the images from stdin")
+			}
+
+			if err := viper.BindPFlag("metrics-address", cmd.Flags().Lookup("metrics-address")); err != nil {
+				return fmt.Errorf("bind metrics-address flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("manifest-key", cmd.Flags().Lookup("manifest-key")); err != nil {
+				return fmt.Errorf("bind manifest-key flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("manifest-signature", cmd.Flags().Lookup("manifest-signature")); err != nil {
+				return fmt.Errorf("bind manifest-signature flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("summary-file", cmd.Flags().Lookup("summary-file")); err != nil {
+				return fmt.Errorf("bind summary-file flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("audit-log", cmd.Flags().Lookup("audit-log")); err != nil {
+				return fmt.Errorf("bind audit-log flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("report", cmd.Flags().Lookup("report")); err != nil {
+				return fmt.Errorf("bind report flag: %w", err)
 			}
 
 			manifestPath := viper.GetString("manifest")
-			if err := runPushCommand(manifestPath); err != nil {
+			if viper.GetBool("watch") {
+				serveMetrics(viper.GetString("metrics-address"))
+
+				run := func() error { return runPushCommand(cmd.Context(), manifestPath) }
+				if err := watch(cmd.Context(), getWatchPath(manifestPath), run); err != nil {
+					return fmt.Errorf("watch: %w", err)
+				}
+
+				return nil
+			}
+
+			if err := runPushCommand(cmd.Context(), manifestPath); err != nil {
 				return fmt.Errorf("push: %w", err)
 			}
 
@@ -45,94 +310,644 @@ func newPushCommand() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().Bool("dryrun", false, "Print a list of images that would be pushed to the target")
+	cmd.Flags().Bool("dry-run", false, "Print the images that would be pushed to the target without pushing them")
+	cmd.Flags().Bool("dryrun", false, "Print the images that would be pushed to the target without pushing them")
+	deprecateErr = cmd.Flags().MarkDeprecated("dryrun", "use --dry-run instead")
 	cmd.Flags().StringSliceP("images", "i", []string{}, "List of images to push to target")
+	cmd.Flags().String("images-file", "", "Path to a file that lists the images to push to target, one per line (e.g. the output of list), or - for stdin")
 	cmd.Flags().StringP("target", "t", "", "Registry the images will be pushed to")
+	cmd.Flags().String("destination", "", "Write the images to an OCI layout (oci:<dir>), an archive (tarball:<file>) or an S3 bucket (s3://<bucket>/<prefix>) instead of the registry of each target")
+	cmd.Flags().IntP("jobs", "j", 1, "Number of images to push at the same time")
+	cmd.Flags().StringSlice("platforms", []string{}, "Platforms to push for multi-arch images (e.g. linux/amd64,linux/arm64), defaults to all platforms")
+	cmd.Flags().StringSlice("exclude", []string{}, "Glob patterns of images to exclude (e.g. busybox:*)")
+	cmd.Flags().StringSlice("exclude-regex", []string{}, "Regular expressions of images to exclude")
+	cmd.Flags().StringSlice("source-filter", []string{}, "Only include images from the given source registries (e.g. quay.io,gcr.io)")
+	cmd.Flags().Bool("copy-signatures", false, "Copy the cosign signatures and attestations of each image to the target")
+	cmd.Flags().Bool("copy-sboms", false, "Copy the SBOMs attached to each image to the target")
+	cmd.Flags().Bool("verify", false, "Verify the cosign signature of each image before pushing it (requires cosign)")
+	cmd.Flags().String("verify-key", "", "Public key to verify signatures with")
+	cmd.Flags().String("verify-identity", "", "Certificate identity to verify keyless signatures with")
+	cmd.Flags().String("verify-oidc-issuer", "", "Certificate OIDC issuer to verify keyless signatures with")
+	cmd.Flags().Bool("require-signed", false, "Refuse to push images whose signature cannot be verified against their trust policy in the manifest (requires cosign or notation)")
+	cmd.Flags().Bool("annotate", false, "Add annotations to each pushed image that record the source image, when it was pushed and the version of sinker")
+	cmd.Flags().String("expires-after", "", "Label each pushed image with quay.expires-after, so that Quay removes it after the given time (e.g. 12h, 30d or 2w)")
+	cmd.Flags().StringSlice("harbor-labels", []string{}, "Global Harbor labels to add to each pushed image (e.g. ephemeral,release-1.2)")
+	cmd.Flags().Bool("verify-digests", false, "Verify that the digest of each image at the target matches the digest of the source after pushing it")
+	cmd.Flags().Bool("verify-pull", false, "Pull the manifest of each image from the target again to verify its digest (implies verify-digests)")
+	cmd.Flags().String("verification-report", "", "Path to write a JSON report of the verified digests to (implies verify-digests)")
+	cmd.Flags().String("verification-report-key", "", "Private key to sign the verification report with (requires cosign)")
+	cmd.Flags().String("manifest-key", "", "Public key to verify the signature of the manifest with before acting on it (requires cosign)")
+	cmd.Flags().String("manifest-signature", "", "Path to the signature of the manifest (defaults to the path of the manifest with a .sig extension)")
+	cmd.Flags().String("summary-file", "", "Path to write a JSON summary of the images that were found to")
+	cmd.Flags().String("audit-log", "", "Path to an append-only JSONL log to record every image that is pushed in")
+	cmd.Flags().String("report", "", "Path to write a JSON report of the status, digests and timings of each image to")
+	cmd.Flags().Bool("interactive", false, "Select the images to push from a list of the images in the terminal before pushing them")
+	cmd.Flags().Bool("watch", false, "Watch the manifest for changes and push the images again when it changes")
+	cmd.Flags().String("metrics-address", "", "Address to serve Prometheus metrics on when watching (e.g. :9090)")
+	cmd.Flags().Int("retries", 1, "Number of times to retry pushing an image before failing, with an exponentially increasing delay")
+	cmd.Flags().String("state-file", "", "Path to a file that records pushed images so that an interrupted push can be resumed")
+	cmd.Flags().String("failures-file", "", "Path to write the images that failed to be pushed to (e.g. failed.yaml)")
+	cmd.Flags().String("from-failures", "", "Only push the images in the manifest that failed to be pushed, as written to the failures file of an earlier push")
+	cmd.Flags().String("cache-file", "", "Path to a file that caches the images that exist at the target so that they are not checked again")
+	cmd.Flags().Duration("cache-ttl", 24*time.Hour, "How long images that are not pinned to a digest are cached for")
+	cmd.Flags().Int("rate-limit", 0, "Maximum number of images to pull from each source registry per minute (defaults to no limit)")
+	cmd.Flags().String("max-bandwidth", "", "Maximum bandwidth used by all of the images that are pushed at the same time (e.g. 50MiB/s), defaults to no limit")
+	cmd.Flags().Bool("create-repos", false, "Create the ECR repositories that the images are pushed to when they do not exist")
+	cmd.Flags().StringToString("repo-tags", map[string]string{}, "Tags to add to the created ECR repositories (e.g. team=platform,env=prod)")
+	cmd.Flags().Bool("repo-scan-on-push", false, "Enable scan on push for the created ECR repositories")
+	cmd.Flags().Bool("repo-immutable-tags", false, "Make the tags of the created ECR repositories immutable")
+	cmd.Flags().Bool("create-harbor-projects", false, "Create the Harbor projects that the images are pushed to when they do not exist")
+	cmd.Flags().Bool("harbor-project-public", false, "Make the created Harbor projects public")
+	cmd.Flags().String("harbor-project-quota", "", "Storage quota of the created Harbor projects (e.g. 50GiB), defaults to the quota of Harbor")
+	cmd.Flags().String("max-image-size", "", "Maximum compressed size of an image (e.g. 5GiB), larger images are not pushed (defaults to the maxImageSize of the policy of the manifest)")
+	cmd.Flags().Bool("scan", false, "Scan each image for vulnerabilities before pushing it (requires trivy)")
+	cmd.Flags().String("scan-severity", "CRITICAL", "Images with vulnerabilities of this severity or higher are not pushed (UNKNOWN, LOW, MEDIUM, HIGH or CRITICAL)")
+	cmd.Flags().String("scan-server", "", "Address of a Trivy server to scan images with")
 
 	return &cmd
 }
 
-func runPushCommand(manifestPath string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+func runPushCommand(ctx context.Context, manifestPath string) error {
+	started := time.Now()
+
+	var summary runSummary
+	report := newRunReport("push")
+	err := pushImages(ctx, manifestPath, &summary, report)
+	sendNotifications(ctx, "push", started, summary, err)
+
+	return writeRunReport(report, err)
+}
+
+// pushImages pushes the images and records the counts of the run in the summary and the outcome of each image in
+// the report, which are filled in as far as the push got, so that they can be sent or written even on failure.
+func pushImages(ctx context.Context, manifestPath string, summary *runSummary, report *runReport) error {
+	ctx, cancel := withCommandTimeout(ctx, 30*time.Minute)
 	defer cancel()
 
-	client, err := docker.NewClient(log.Infof)
+	client, err := newClient()
 	if err != nil {
 		return fmt.Errorf("new client: %w", err)
 	}
+	client = client.WithRetries(viper.GetInt("retries"))
+
+	if viper.GetInt("rate-limit") > 0 {
+		client = client.WithRateLimiter(docker.NewRateLimiter(viper.GetInt("rate-limit")))
+	}
+
+	if viper.GetBool("annotate") {
+		client = client.WithProvenance(buildVersion)
+	}
+
+	if viper.GetString("expires-after") != "" {
+		client = client.WithConfigLabels(map[string]string{docker.QuayExpiresAfterLabel: viper.GetString("expires-after")})
+	}
+
+	target, err := docker.NewTarget(client, viper.GetString("destination"))
+	if err != nil {
+		return fmt.Errorf("new target: %w", err)
+	}
+
+	sources, sourcesSummary, err := getImagesOrManifestSources(ctx, manifestPath)
+	if err != nil {
+		return fmt.Errorf("get sources: %w", err)
+	}
+	*summary = sourcesSummary
+
+	if viper.GetString("from-failures") != "" {
+		failures, err := readPushFailures(viper.GetString("from-failures"))
+		if err != nil {
+			return fmt.Errorf("read push failures: %w", err)
+		}
+
+		sources = filterFailedSources(sources, failures)
+		log.Infof("Pushing the %v image(s) that failed to be pushed before", len(sources))
+	}
+
+	metrics.ImagesDiscovered.Add(uint64(len(sources)))
+	bytesTransferred := metrics.BytesTransferred.Value()
+
+	dryRun := viper.GetBool("dry-run") || viper.GetBool("dryrun")
+
+	var state *pushState
+	if viper.GetString("state-file") != "" {
+		state, err = readPushState(viper.GetString("state-file"))
+		if err != nil {
+			return fmt.Errorf("read push state: %w", err)
+		}
+	}
+
+	var audit *auditLog
+	if viper.GetString("audit-log") != "" {
+		audit = newAuditLog(viper.GetString("audit-log"))
+	}
 
-	sources := manifest.GetSourcesFromImages(viper.GetStringSlice("images"), viper.GetString("target"))
-	if len(sources) == 0 {
-		imageManifest, err := manifest.Get(viper.GetString("manifest"))
+	var cache *targetCache
+	if viper.GetString("cache-file") != "" {
+		cache, err = readTargetCache(viper.GetString("cache-file"), viper.GetDuration("cache-ttl"))
 		if err != nil {
-			return fmt.Errorf("get manifest: %w", err)
+			return fmt.Errorf("read target cache: %w", err)
 		}
 
-		sources = imageManifest.Sources
+		defer func() {
+			if err := cache.save(); err != nil {
+				log.Warnf("Unable to save the target cache: %v", err)
+			}
+		}()
 	}
 
 	log.Infof("Finding images that need to be pushed ...")
 
 	var sourcesToPush []manifest.Source
+	var selectableImages []selectableImage
 	for _, source := range sources {
-		exists, err := client.ImageExistsAtRemote(ctx, source.TargetImage())
+		if state != nil && state.isConfirmed(source.TargetImage()) {
+			if dryRun {
+				logImageStatus(statusSkipped, "Image %s was already pushed as %s", source.Image(), source.TargetImage())
+			}
+			report.add(source, reportStatusExists, time.Time{}, nil)
+			continue
+		}
+
+		if cache != nil && cache.exists(source) {
+			if dryRun {
+				logImageStatus(statusSkipped, "Image %s is cached as existing at the target as %s", source.Image(), source.TargetImage())
+			}
+			report.add(source, reportStatusExists, time.Time{}, nil)
+			continue
+		}
+
+		checked := time.Now()
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		exists, err := target.ImageExists(ctx, source.TargetImage(), targetAuth)
 		if err != nil {
-			return fmt.Errorf("image exists at remote: %w", err)
+			return fmt.Errorf("image exists at target: %w", err)
 		}
 
+		selectableImages = append(selectableImages, selectableImage{source: source, exists: exists, selected: !exists})
+
 		if !exists {
 			sourcesToPush = append(sourcesToPush, source)
+		} else if dryRun {
+			logImageStatus(statusSkipped, "Image %s already exists at the target as %s", source.Image(), source.TargetImage())
+		}
+
+		if exists {
+			report.add(source, reportStatusExists, checked, nil)
+		}
+
+		if exists && cache != nil {
+			cache.confirm(source)
 		}
 	}
 
-	if len(sourcesToPush) == 0 {
-		log.Infof("All images are up to date!")
-		return nil
+	metrics.ImagesMissing.Set(float64(len(sourcesToPush)))
+	summary.MissingAtTarget = len(sourcesToPush)
+
+	if viper.GetBool("interactive") && len(selectableImages) > 0 {
+		selectedSources, confirmed, err := selectImages(ctx, client, selectableImages, viper.GetStringSlice("platforms"))
+		if err != nil {
+			return fmt.Errorf("select images: %w", err)
+		}
+
+		if !confirmed {
+			for _, source := range sourcesToPush {
+				report.add(source, reportStatusSkipped, time.Time{}, nil)
+			}
+
+			log.Infof("Push was canceled, no images were pushed")
+			return nil
+		}
+
+		var selectedImages []string
+		for _, source := range selectedSources {
+			selectedImages = append(selectedImages, source.TargetImage())
+		}
+
+		for _, source := range sourcesToPush {
+			if !containsString(selectedImages, source.TargetImage()) {
+				report.add(source, reportStatusSkipped, time.Time{}, nil)
+			}
+		}
+
+		sourcesToPush = selectedSources
+		log.Infof("Selected %v of %v image(s) to push", len(sourcesToPush), len(selectableImages))
 	}
 
-	if viper.GetBool("dryrun") {
+	if dryRun {
 		for _, source := range sourcesToPush {
 			log.Infof("Image %s would be pushed as %s", source.Image(), source.TargetImage())
+			printQuiet(source.TargetImage())
+			report.add(source, reportStatusMissing, time.Time{}, nil)
+		}
+
+		log.Infof("%v image(s) would be pushed, %v image(s) already exist at the target", len(sourcesToPush), len(sources)-len(sourcesToPush))
+		if err := writeSummary(*summary); err != nil {
+			return fmt.Errorf("write summary: %w", err)
 		}
+
+		return nil
+	}
+
+	if len(sourcesToPush) == 0 {
+		if state != nil {
+			if err := state.remove(); err != nil {
+				return fmt.Errorf("remove push state: %w", err)
+			}
+		}
+
+		log.Infof("All images are up to date!")
+		if err := writeSummary(*summary); err != nil {
+			return fmt.Errorf("write summary: %w", err)
+		}
+
 		return nil
 	}
 
-	for _, source := range sourcesToPush {
-		exists, err := client.ImageExistsOnHost(ctx, source.Image())
+	verifyOptions := docker.VerifyOptions{
+		Key:      viper.GetString("verify-key"),
+		Identity: viper.GetString("verify-identity"),
+		Issuer:   viper.GetString("verify-oidc-issuer"),
+	}
+
+	var trustPolicies []manifest.TrustPolicy
+	if viper.GetBool("require-signed") {
+		trustPolicies, err = getTrustPolicies(manifestPath)
 		if err != nil {
-			return fmt.Errorf("image exists: %w", err)
+			return fmt.Errorf("get trust policies: %w", err)
 		}
+	}
 
-		if !exists {
-			sourceAuth, err := source.EncodedAuth()
+	var repositoryCreator *docker.ECRRepositoryCreator
+	if viper.GetBool("create-repos") {
+		repositoryCreator = docker.NewECRRepositoryCreator(docker.ECRRepositoryOptions{
+			Tags:          viper.GetStringMapString("repo-tags"),
+			ScanOnPush:    viper.GetBool("repo-scan-on-push"),
+			ImmutableTags: viper.GetBool("repo-immutable-tags"),
+		})
+	}
+
+	var projectCreator *docker.HarborProjectCreator
+	if viper.GetBool("create-harbor-projects") {
+		options := docker.HarborProjectOptions{Public: viper.GetBool("harbor-project-public")}
+		if viper.GetString("harbor-project-quota") != "" {
+			options.StorageLimit, err = docker.ParseSize(viper.GetString("harbor-project-quota"))
 			if err != nil {
-				return fmt.Errorf("get source auth: %w", err)
+				return fmt.Errorf("parse harbor project quota: %w", err)
 			}
+		}
 
-			log.Infof("Pulling %s", source.Image())
-			if err := client.PullImageAndWait(ctx, source.Image(), sourceAuth); err != nil {
-				return fmt.Errorf("pull image and wait: %w", err)
-			}
-			log.Infof("Pulled %s", source.Image())
+		projectCreator = docker.NewHarborProjectCreator(client, options)
+	}
 
-			if err := client.Tag(ctx, source.Image(), source.TargetImage()); err != nil {
-				return fmt.Errorf("tag image: %w", err)
-			}
+	var harborLabeler *docker.HarborLabeler
+	if len(viper.GetStringSlice("harbor-labels")) > 0 {
+		harborLabeler = docker.NewHarborLabeler(client, viper.GetStringSlice("harbor-labels"))
+	}
+
+	maxImageSize, maxImageSizeSetting, err := getMaxImageSize(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get max image size: %w", err)
+	}
+
+	var verifications verificationReport
+
+	var scanMutex sync.Mutex
+	var scanResults []scanResult
+
+	pushProgress := newProgress("Pushed", len(sourcesToPush))
+
+	// The digests of each image are recorded at its index for the report.
+	sourceDigests := make([]string, len(sourcesToPush))
+	targetDigests := make([]string, len(sourcesToPush))
+
+	var pushed int32
+	push := func(i int) error {
+		source := sourcesToPush[i]
+
+		sourceAuth, err := getSourceAuth(source)
+		if err != nil {
+			return fmt.Errorf("get source auth: %w", err)
 		}
 
-		targetAuth, err := source.Target.EncodedAuth()
+		targetAuth, err := getTargetAuth(source.Target)
 		if err != nil {
 			return fmt.Errorf("get target auth: %w", err)
 		}
 
+		// The images of each platform of an assembled source are verified and scanned, as the
+		// multi-arch image that they are assembled into does not exist at the source.
+		for _, pulledSource := range getPulledSources(source) {
+			if viper.GetBool("require-signed") {
+				if err := verifyTrust(ctx, trustPolicies, pulledSource); err != nil {
+					logImageStatus(statusFailed, "Refusing to push %s, as its signature cannot be verified: %v", pulledSource.Image(), err)
+					return fmt.Errorf("verify trust %s: %w", pulledSource.Image(), err)
+				}
+			} else if viper.GetBool("verify") {
+				if err := docker.VerifySignature(ctx, pulledSource.Image(), verifyOptions); err != nil {
+					logImageStatus(statusFailed, "Unable to verify the signature of %s: %v", pulledSource.Image(), err)
+					return fmt.Errorf("verify signature %s: %w", pulledSource.Image(), err)
+				}
+			}
+
+			if viper.GetBool("scan") && !pulledSource.IsArtifact() {
+				result, err := scanSource(ctx, pulledSource, viper.GetString("scan-severity"), scan.Options{Server: viper.GetString("scan-server")})
+				if err != nil {
+					return fmt.Errorf("scan %s: %w", pulledSource.Image(), err)
+				}
+
+				scanMutex.Lock()
+				scanResults = append(scanResults, result)
+				scanMutex.Unlock()
+
+				if result.blocked > 0 {
+					logImageStatus(statusFailed, "Image %s has %v vulnerabilities of severity %s or higher", pulledSource.Image(), result.blocked, viper.GetString("scan-severity"))
+					return fmt.Errorf("image %s did not pass the vulnerability scan", pulledSource.Image())
+				}
+			}
+		}
+
+		if maxImageSize > 0 {
+			size, err := getSourceSize(ctx, client, source, sourceAuth, viper.GetStringSlice("platforms"))
+			if err != nil {
+				return fmt.Errorf("get size of %s: %w", source.Image(), err)
+			}
+
+			if size > maxImageSize {
+				logImageStatus(statusFailed, "Refusing to push %s, as its size of %s exceeds the maximum image size of %s", source.Image(), formatBytes(uint64(size)), maxImageSizeSetting)
+				return fmt.Errorf("image %s exceeds the maximum image size", source.Image())
+			}
+		}
+
+		if repositoryCreator != nil {
+			created, err := repositoryCreator.EnsureRepository(ctx, source.TargetImage())
+			if err != nil {
+				return fmt.Errorf("ensure repository %s: %w", source.TargetImage(), err)
+			}
+
+			if created {
+				log.Infof("Created repository for %s", source.TargetImage())
+			}
+		}
+
+		if projectCreator != nil {
+			created, err := projectCreator.EnsureProject(ctx, source.TargetImage(), targetAuth)
+			if err != nil {
+				return fmt.Errorf("ensure project %s: %w", source.TargetImage(), err)
+			}
+
+			if created {
+				log.Infof("Created Harbor project for %s", source.TargetImage())
+			}
+		}
+
 		log.Infof("Pushing %s", source.TargetImage())
-		if err := client.PushImageAndWait(ctx, source.TargetImage(), targetAuth); err != nil {
-			return fmt.Errorf("push image and wait: %w", err)
+		source, sourceAuth, copyErr := copySourceWithFallbacks(ctx, target, source, sourceAuth, targetAuth)
+		if audit != nil {
+			if err := recordPush(ctx, audit, client, source, sourceAuth, copyErr); err != nil {
+				return fmt.Errorf("record push %s: %w", source.TargetImage(), err)
+			}
+		}
+
+		if copyErr != nil {
+			logImageStatus(statusFailed, "Unable to push %s: %v", source.TargetImage(), copyErr)
+			return fmt.Errorf("copy %s: %w", source.Image(), copyErr)
+		}
+
+		// An assembled source has no digest at the source to verify the multi-arch image at the target against.
+		if isVerifyingDigests() && !source.IsAssembled() {
+			verification, err := client.VerifyCopy(ctx, source.PullImage(), sourceAuth, source.TargetImage(), targetAuth, viper.GetBool("verify-pull"))
+			if err != nil {
+				return fmt.Errorf("verify %s: %w", source.TargetImage(), err)
+			}
+
+			verifications.add(verification)
+			sourceDigests[i] = verification.SourceDigest
+			targetDigests[i] = verification.TargetDigest
+
+			if !verification.Verified {
+				logImageStatus(statusFailed, "Digest %s of %s does not match digest %s of the source", verification.TargetDigest, source.TargetImage(), verification.SourceDigest)
+				return fmt.Errorf("image %s does not match the source", source.TargetImage())
+			}
+
+			log.Infof("Verified %s has digest %s", source.TargetImage(), verification.TargetDigest)
+		} else if viper.GetString("report") != "" && viper.GetString("destination") == "" {
+			digest, err := client.GetDigest(ctx, source.TargetImage(), targetAuth)
+			if err != nil {
+				log.Warnf("Unable to get the digest of %s for the report: %v", source.TargetImage(), err)
+			}
+
+			targetDigests[i] = digest
+		}
+
+		if viper.GetBool("copy-signatures") {
+			for _, pulledSource := range getPulledSources(source) {
+				signatures, err := client.CopySignaturesAndWait(ctx, pulledSource.PullImage(), sourceAuth, source.TargetImage(), targetAuth)
+				if err != nil {
+					return fmt.Errorf("copy signatures %s: %w", pulledSource.Image(), err)
+				}
+
+				for _, signature := range signatures {
+					log.Infof("Copied %s for %s", signature, source.TargetImage())
+				}
+			}
+		}
+
+		if harborLabeler != nil {
+			if err := harborLabeler.AddLabels(ctx, source.TargetImage(), targetAuth); err != nil {
+				return fmt.Errorf("add harbor labels %s: %w", source.TargetImage(), err)
+			}
+		}
+
+		if viper.GetBool("copy-sboms") {
+			for _, pulledSource := range getPulledSources(source) {
+				sboms, err := client.CopySBOMsAndWait(ctx, pulledSource.PullImage(), sourceAuth, source.TargetImage(), targetAuth)
+				if err != nil {
+					return fmt.Errorf("copy sboms %s: %w", pulledSource.Image(), err)
+				}
+
+				for _, sbom := range sboms {
+					log.Infof("Copied %s for %s", sbom, source.TargetImage())
+				}
+			}
+		}
+
+		if state != nil {
+			if err := state.confirm(source.TargetImage()); err != nil {
+				return fmt.Errorf("confirm push state: %w", err)
+			}
+		}
+
+		if cache != nil {
+			cache.confirm(source)
+		}
+
+		pushProgress.complete()
+		logImageStatus(statusSynced, "Pushed %s (%v/%v)", source.TargetImage(), atomic.AddInt32(&pushed, 1), len(sourcesToPush))
+		printQuiet(source.TargetImage())
+		return nil
+	}
+
+	// The error of each image is recorded at its index, so that the failures are written in the order of the manifest.
+	pushErrors := make([]error, len(sourcesToPush))
+	attempted := make([]bool, len(sourcesToPush))
+	pushAndRecord := func(i int) error {
+		started := time.Now()
+		attempted[i] = true
+		pushErrors[i] = push(i)
+
+		status := reportStatusPushed
+		if pushErrors[i] != nil {
+			status = reportStatusFailed
+		}
+
+		reportedImage := newReportedImage(sourcesToPush[i], status, started, pushErrors[i])
+		reportedImage.TargetDigest = targetDigests[i]
+		if sourceDigests[i] != "" {
+			reportedImage.SourceDigest = sourceDigests[i]
+		}
+		report.addImage(reportedImage)
+
+		return pushErrors[i]
+	}
+
+	stopProgress := pushProgress.run(ctx)
+	err = runJobs(ctx, viper.GetInt("jobs"), len(sourcesToPush), pushAndRecord)
+	stopProgress()
+
+	// The images that were not pushed because the push was interrupted are reported as skipped.
+	for i, source := range sourcesToPush {
+		if !attempted[i] {
+			report.add(source, reportStatusSkipped, time.Time{}, nil)
+		}
+	}
+
+	failures := newPushFailures(sourcesToPush, pushErrors)
+	if viper.GetString("failures-file") != "" {
+		if err := failures.write(viper.GetString("failures-file")); err != nil {
+			return fmt.Errorf("write push failures: %w", err)
+		}
+	}
+
+	if viper.GetBool("scan") {
+		if err := writeScanSummary(os.Stdout, scanResults); err != nil {
+			return fmt.Errorf("write scan summary: %w", err)
+		}
+	}
+
+	// The report is written even when the push failed, so that it includes the images that do not match the source.
+	if viper.GetString("verification-report") != "" {
+		if err := verifications.write(ctx, viper.GetString("verification-report"), viper.GetString("verification-report-key")); err != nil {
+			return fmt.Errorf("write verification report: %w", err)
+		}
+	}
+
+	// The summary is written even when the push failed, so that CI jobs can archive it either way.
+	summary.BytesCopied = metrics.BytesTransferred.Value() - bytesTransferred
+	if err := writeSummary(*summary); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	// The images that were pushed are kept at the target even when the push failed, such as in the archive of a tarball.
+	if err := target.Close(); err != nil {
+		return fmt.Errorf("close target: %w", err)
+	}
+
+	if ctx.Err() != nil {
+		log.Warnf("Push was interrupted after pushing %v/%v image(s)", atomic.LoadInt32(&pushed), len(sourcesToPush))
+		if state != nil {
+			log.Warnf("Run the push again with the same state file to resume")
+		}
+	}
+	if err != nil && ctx.Err() == nil && len(failures.Failed) > 0 {
+		log.Errorf("%v of %v image(s) failed to be pushed", len(failures.Failed), len(sourcesToPush))
+		return getPushExitError(fmt.Errorf("push images: %w", err), len(failures.Failed), len(sourcesToPush))
+	}
+	if err != nil {
+		return fmt.Errorf("push images: %w", err)
+	}
+
+	if state != nil {
+		if err := state.remove(); err != nil {
+			return fmt.Errorf("remove push state: %w", err)
 		}
-		log.Infof("Pushed %s", source.TargetImage())
 	}
 
 	log.Infof("All images have been pushed!")
 
 	return nil
 }
+
+// copySource copies the source to the target. Helm charts and other artifacts are copied as they are,
+// while only the selected platforms of multi-arch images are copied. Assembled sources are written as a
+// multi-arch image of each of their platform images.
+func copySource(ctx context.Context, target docker.Target, source manifest.Source, sourceAuth string, targetAuth string) error {
+	switch source.Type {
+	case manifest.SourceTypeChart:
+		options := docker.WriteOptions{Artifact: true, ConfigMediaType: docker.HelmChartConfigMediaType}
+		if err := target.WriteImage(ctx, source.PullImage(), sourceAuth, source.TargetImage(), targetAuth, options); err != nil {
+			return fmt.Errorf("copy chart: %w", err)
+		}
+
+	case manifest.SourceTypeArtifact:
+		options := docker.WriteOptions{Artifact: true}
+		if err := target.WriteImage(ctx, source.PullImage(), sourceAuth, source.TargetImage(), targetAuth, options); err != nil {
+			return fmt.Errorf("copy artifact: %w", err)
+		}
+
+	default:
+		if source.IsAssembled() {
+			options := docker.WriteOptions{PlatformImages: getPlatformImages(source)}
+			if err := target.WriteImage(ctx, source.PullImage(), sourceAuth, source.TargetImage(), targetAuth, options); err != nil {
+				return fmt.Errorf("assemble image: %w", err)
+			}
+
+			return nil
+		}
+
+		options := docker.WriteOptions{Platforms: viper.GetStringSlice("platforms")}
+		if err := target.WriteImage(ctx, source.PullImage(), sourceAuth, source.TargetImage(), targetAuth, options); err != nil {
+			return fmt.Errorf("copy image: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getPlatformImages returns the images of each platform of the assembled source, which
+// are pulled from the registry of the source, its registry mirror or its fallback.
+func getPlatformImages(source manifest.Source) []docker.PlatformImage {
+	var platformImages []docker.PlatformImage
+	for _, platformImage := range source.PlatformImages {
+		platformImages = append(platformImages, docker.PlatformImage{
+			Image:    source.PlatformSource(platformImage).PullImage(),
+			Platform: platformImage.Platform,
+		})
+	}
+
+	return platformImages
+}
+
+// getPulledSources returns the sources whose images are pulled to push the source, which are
+// the sources of each platform of an assembled source, or the source itself otherwise.
+func getPulledSources(source manifest.Source) []manifest.Source {
+	if !source.IsAssembled() {
+		return []manifest.Source{source}
+	}
+
+	var pulledSources []manifest.Source
+	for _, platformImage := range source.PlatformImages {
+		pulledSources = append(pulledSources, source.PlatformSource(platformImage))
+	}
+
+	return pulledSources
+}
+
+// isVerifyingDigests returns true when the digests of the pushed images are verified.
+func isVerifyingDigests() bool {
+	return viper.GetBool("verify-digests") || viper.GetBool("verify-pull") || viper.GetString("verification-report") != ""
+}
@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// newClient returns a Docker client that connects to the registries with the CA certificate, TLS
+// verification and insecure registries set by the flags, as well as the settings of each registry
+// in the registries section of the config file.
+func newClient() (docker.Client, error) {
+	client, err := docker.NewClient(log.Infof)
+	if err != nil {
+		return docker.Client{}, fmt.Errorf("new client: %w", err)
+	}
+
+	defaultConfig := docker.RegistryConfig{
+		CACert:                viper.GetString("ca-cert"),
+		InsecureSkipTLSVerify: viper.GetBool("insecure-skip-tls-verify"),
+		Timeout:               viper.GetDuration("registry-timeout"),
+	}
+
+	var registries map[string]docker.RegistryConfig
+	if err := viper.UnmarshalKey("registries", &registries); err != nil {
+		return docker.Client{}, fmt.Errorf("unmarshal registries: %w", err)
+	}
+
+	for _, registry := range viper.GetStringSlice("insecure") {
+		if registries == nil {
+			registries = make(map[string]docker.RegistryConfig)
+		}
+
+		config := registries[registry]
+		config.Insecure = true
+		registries[registry] = config
+	}
+
+	client, err = client.WithRegistryConfig(defaultConfig, registries)
+	if err != nil {
+		return docker.Client{}, fmt.Errorf("registry config: %w", err)
+	}
+
+	bandwidthLimiter, err := getBandwidthLimiter(registries)
+	if err != nil {
+		return docker.Client{}, fmt.Errorf("get bandwidth limiter: %w", err)
+	}
+
+	if bandwidthLimiter != nil {
+		client = client.WithBandwidthLimiter(bandwidthLimiter)
+	}
+
+	return client, nil
+}
+
+// getBandwidthLimiter returns the bandwidth limiter for the bandwidth set by the max-bandwidth flag and the
+// bandwidths of the registries in the config file, or nil when the bandwidth is not limited at all.
+func getBandwidthLimiter(registries map[string]docker.RegistryConfig) (*docker.BandwidthLimiter, error) {
+	var maxBandwidth int64
+	if viper.GetString("max-bandwidth") != "" {
+		var err error
+		maxBandwidth, err = docker.ParseBandwidth(viper.GetString("max-bandwidth"))
+		if err != nil {
+			return nil, fmt.Errorf("parse max bandwidth: %w", err)
+		}
+	}
+
+	registryBandwidths := make(map[string]int64)
+	for registry, config := range registries {
+		if config.MaxBandwidth == "" {
+			continue
+		}
+
+		bandwidth, err := docker.ParseBandwidth(config.MaxBandwidth)
+		if err != nil {
+			return nil, fmt.Errorf("parse max bandwidth of %s: %w", registry, err)
+		}
+
+		registryBandwidths[registry] = bandwidth
+	}
+
+	if maxBandwidth == 0 && len(registryBandwidths) == 0 {
+		return nil, nil
+	}
+
+	bandwidthLimiter, err := docker.NewBandwidthLimiter(maxBandwidth, registryBandwidths)
+	if err != nil {
+		return nil, fmt.Errorf("new bandwidth limiter: %w", err)
+	}
+
+	return bandwidthLimiter, nil
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/spf13/viper"
+)
+
+// registrySecrets are the Docker configs of the secrets of the registry-secrets flag, which
+// are only read from the cluster once, the first time that the auth of a registry is needed.
+var registrySecrets struct {
+	once    sync.Once
+	configs [][]byte
+	err     error
+}
+
+// getRegistrySecretAuth returns the encoded auth for the host from the first secret of the registry-secrets flag that
+// has credentials for it. When none of the secrets have credentials for the host, false is returned.
+func getRegistrySecretAuth(host string) (string, bool, error) {
+	secrets := viper.GetStringSlice("registry-secrets")
+	if len(secrets) == 0 {
+		return "", false, nil
+	}
+
+	registrySecrets.once.Do(func() {
+		registrySecrets.configs, registrySecrets.err = readRegistrySecrets(secrets)
+	})
+
+	if registrySecrets.err != nil {
+		return "", false, registrySecrets.err
+	}
+
+	return findDockerConfigAuth(registrySecrets.configs, host)
+}
+
+// readRegistrySecrets returns the Docker configs of the secrets, which are written as namespace/name,
+// or as the name of a secret in the namespace of the current kubeconfig context.
+func readRegistrySecrets(secrets []string) ([][]byte, error) {
+	cluster := images.Cluster{
+		Kubeconfig: viper.GetString("kubeconfig"),
+		Context:    viper.GetString("context"),
+	}
+
+	var configs [][]byte
+	for _, secret := range secrets {
+		var namespace string
+		name := secret
+		if separator := strings.Index(secret, "/"); separator != -1 {
+			namespace = secret[:separator]
+			name = secret[separator+1:]
+		}
+
+		dockerConfig, err := images.GetPullSecret(cluster, namespace, name)
+		if err != nil {
+			return nil, fmt.Errorf("get registry secret %s: %w", secret, err)
+		}
+
+		configs = append(configs, dockerConfig)
+	}
+
+	return configs, nil
+}
+
+func findDockerConfigAuth(configs [][]byte, host string) (string, bool, error) {
+	for _, dockerConfig := range configs {
+		auth, found, err := docker.GetEncodedAuthFromDockerConfig(dockerConfig, host)
+		if err != nil {
+			return "", false, fmt.Errorf("get encoded auth from docker config: %w", err)
+		}
+
+		if found {
+			return auth, true, nil
+		}
+	}
+
+	return "", false, nil
+}
@@ -0,0 +1,253 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newReportCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "report <source>...",
+		Short: "Report the images used by each workload found at the sources",
+		Args:  cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("helm", cmd.Flags().Lookup("helm")); err != nil {
+				return fmt.Errorf("bind helm flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm-values", cmd.Flags().Lookup("helm-values")); err != nil {
+				return fmt.Errorf("bind helm-values flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kustomize", cmd.Flags().Lookup("kustomize")); err != nil {
+				return fmt.Errorf("bind kustomize flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images", cmd.Flags().Lookup("env-images")); err != nil {
+				return fmt.Errorf("bind env-images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images-pattern", cmd.Flags().Lookup("env-images-pattern")); err != nil {
+				return fmt.Errorf("bind env-images-pattern flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("crd-config", cmd.Flags().Lookup("crd-config")); err != nil {
+				return fmt.Errorf("bind crd-config flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("deep-scan", cmd.Flags().Lookup("deep-scan")); err != nil {
+				return fmt.Errorf("bind deep-scan flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dockerfiles", cmd.Flags().Lookup("dockerfiles")); err != nil {
+				return fmt.Errorf("bind dockerfiles flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("ci-files", cmd.Flags().Lookup("ci-files")); err != nil {
+				return fmt.Errorf("bind ci-files flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("terraform", cmd.Flags().Lookup("terraform")); err != nil {
+				return fmt.Errorf("bind terraform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-sources", cmd.Flags().Lookup("follow-sources")); err != nil {
+				return fmt.Errorf("bind follow-sources flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("skip-dirs", cmd.Flags().Lookup("skip-dirs")); err != nil {
+				return fmt.Errorf("bind skip-dirs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-gitignore", cmd.Flags().Lookup("no-gitignore")); err != nil {
+				return fmt.Errorf("bind no-gitignore flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-symlinks", cmd.Flags().Lookup("follow-symlinks")); err != nil {
+				return fmt.Errorf("bind follow-symlinks flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kinds", cmd.Flags().Lookup("kinds")); err != nil {
+				return fmt.Errorf("bind kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-kinds", cmd.Flags().Lookup("exclude-kinds")); err != nil {
+				return fmt.Errorf("bind exclude-kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("selector", cmd.Flags().Lookup("selector")); err != nil {
+				return fmt.Errorf("bind selector flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-document-size", cmd.Flags().Lookup("max-document-size")); err != nil {
+				return fmt.Errorf("bind max-document-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("warn-on-parse-error", cmd.Flags().Lookup("warn-on-parse-error")); err != nil {
+				return fmt.Errorf("bind warn-on-parse-error flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("template-defaults", cmd.Flags().Lookup("template-defaults")); err != nil {
+				return fmt.Errorf("bind template-defaults flag: %w", err)
+			}
+
+			if err := runReportCommand(cmd.Context(), args); err != nil {
+				return fmt.Errorf("report: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("helm", false, "Render Helm charts found at the source before finding images (requires helm)")
+	cmd.Flags().StringSlice("helm-values", []string{}, "Values files to use when rendering Helm charts")
+	cmd.Flags().Bool("kustomize", false, "Build kustomizations found at the source before finding images (requires kustomize)")
+	cmd.Flags().Bool("env-images", false, "Find images in the values of container environment variables")
+	cmd.Flags().String("env-images-pattern", "IMAGE", "Regular expression that environment variable names must match to be searched for images")
+	cmd.Flags().String("crd-config", "", "Path to a file that declares where images are located in custom resources")
+	cmd.Flags().Bool("deep-scan", false, "Also find image references in any value of any resource (e.g. ConfigMap data), which may include false positives")
+	cmd.Flags().Bool("dockerfiles", false, "Also find the base images in the FROM instructions of Dockerfiles")
+	cmd.Flags().Bool("ci-files", false, "Also find the images of the jobs in GitHub Actions workflows and GitLab CI files")
+	cmd.Flags().Bool("terraform", false, "Also find the images set in Terraform files by the Kubernetes and Helm providers")
+	cmd.Flags().Bool("follow-sources", false, "Also find the images deployed by Argo CD Applications and Flux HelmReleases and Kustomizations by fetching their sources (requires git and helm)")
+	cmd.Flags().StringSlice("skip-dirs", []string{}, "Directories to skip when finding images, by name or by path relative to the source (e.g. testdata,charts/*/tests)")
+	cmd.Flags().Bool("no-gitignore", false, "Also find images in files and directories that are ignored by .gitignore files")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symbolic links to directories when finding images")
+	cmd.Flags().StringSlice("kinds", []string{}, "Only find images in the resources of the given kinds (e.g. Deployment,CronJob)")
+	cmd.Flags().StringSlice("exclude-kinds", []string{}, "Skip the resources of the given kinds when finding images (e.g. ConfigMap)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Only find images in the resources in the given namespaces")
+	cmd.Flags().StringP("selector", "l", "", "Only find images in the resources whose labels match the selector (e.g. app.kubernetes.io/part-of=platform)")
+	cmd.Flags().String("max-document-size", "16MiB", "Maximum size of a YAML document (e.g. 64MiB), larger documents are skipped (0 does not limit the size)")
+	cmd.Flags().Bool("warn-on-parse-error", true, "Warn about YAML files that cannot be parsed, which are otherwise skipped")
+	cmd.Flags().Bool("template-defaults", false, "Find the images of unrendered Helm templates on a best-effort basis with the default values of their chart")
+
+	return &cmd
+}
+
+func runReportCommand(ctx context.Context, paths []string) error {
+	opts, err := getAutodetectOptions(ctx)
+	if err != nil {
+		return fmt.Errorf("get autodetect options: %w", err)
+	}
+
+	foundImages, err := images.FindImagesInPaths(paths, opts...)
+	if err != nil {
+		return fmt.Errorf("find images: %w", err)
+	}
+
+	if err := writeReport(os.Stdout, foundImages); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return nil
+}
+
+// writeReport writes the images grouped by namespace and then by workload,
+// followed by the images that are used with more than one tag or digest.
+func writeReport(w io.Writer, foundImages []images.Image) error {
+	namespaces := make(map[string]map[string][]string)
+	for _, image := range foundImages {
+		for _, resource := range image.Resources {
+			namespace := resource.Namespace
+			if namespace == "" {
+				namespace = "(no namespace)"
+			}
+
+			if _, exists := namespaces[namespace]; !exists {
+				namespaces[namespace] = make(map[string][]string)
+			}
+
+			workload := resource.Kind + "/" + resource.Name
+			container := resource.Container
+			if container == "" {
+				container = "-"
+			}
+
+			reference := image.Reference
+			if resource.Heuristic {
+				reference += " (heuristic)"
+			}
+
+			namespaces[namespace][workload] = append(namespaces[namespace][workload], container+": "+reference)
+		}
+	}
+
+	var namespaceNames []string
+	for namespace := range namespaces {
+		namespaceNames = append(namespaceNames, namespace)
+	}
+	sort.Strings(namespaceNames)
+
+	for _, namespace := range namespaceNames {
+		if _, err := fmt.Fprintln(w, namespace); err != nil {
+			return fmt.Errorf("write namespace: %w", err)
+		}
+
+		workloads := namespaces[namespace]
+		var workloadNames []string
+		for workload := range workloads {
+			workloadNames = append(workloadNames, workload)
+		}
+		sort.Strings(workloadNames)
+
+		for _, workload := range workloadNames {
+			if _, err := fmt.Fprintf(w, "  %s\n", workload); err != nil {
+				return fmt.Errorf("write workload: %w", err)
+			}
+
+			for _, container := range workloads[workload] {
+				if _, err := fmt.Fprintf(w, "    %s\n", container); err != nil {
+					return fmt.Errorf("write container: %w", err)
+				}
+			}
+		}
+	}
+
+	repositories := make(map[string][]string)
+	for _, image := range foundImages {
+		repository := image.Repository
+		if image.Host != "" {
+			repository = image.Host + "/" + repository
+		}
+
+		repositories[repository] = append(repositories[repository], image.Reference)
+	}
+
+	var duplicates []string
+	for repository, references := range repositories {
+		if len(references) > 1 {
+			duplicates = append(duplicates, repository)
+		}
+	}
+	sort.Strings(duplicates)
+
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintln(w, "\nImages used with more than one tag"); err != nil {
+		return fmt.Errorf("write duplicates: %w", err)
+	}
+
+	for _, repository := range duplicates {
+		if _, err := fmt.Fprintf(w, "  %s: %s\n", repository, strings.Join(repositories[repository], ", ")); err != nil {
+			return fmt.Errorf("write duplicate: %w", err)
+		}
+	}
+
+	return nil
+}
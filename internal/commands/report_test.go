@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+func TestWriteReport(t *testing.T) {
+	foundImages := []images.Image{
+		{
+			Reference:  "busybox:1.32.0",
+			Repository: "busybox",
+			Tag:        "1.32.0",
+			Resources: []images.Resource{
+				{Kind: "Deployment", Name: "api", Namespace: "web", Container: "init"},
+				{Kind: "Pod", Name: "debug", Container: "debug"},
+			},
+		},
+		{
+			Reference:  "busybox:1.31.0",
+			Repository: "busybox",
+			Tag:        "1.31.0",
+			Resources: []images.Resource{
+				{Kind: "Deployment", Name: "api", Namespace: "web", Container: "sidecar"},
+			},
+		},
+	}
+
+	var actual bytes.Buffer
+	if err := writeReport(&actual, foundImages); err != nil {
+		t.Fatal("write report:", err)
+	}
+
+	expected := `(no namespace)
+  Pod/debug
+    debug: busybox:1.32.0
+web
+  Deployment/api
+    init: busybox:1.32.0
+    sidecar: busybox:1.31.0
+
+Images used with more than one tag
+  busybox: busybox:1.32.0, busybox:1.31.0
+`
+
+	if actual.String() != expected {
+		t.Errorf("expected report\n%s\nactual\n%s", expected, actual.String())
+	}
+}
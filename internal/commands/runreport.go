@@ -0,0 +1,173 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// runReportSchemaVersion is the version of the schema of the run report, which is increased when
+// a field is removed or changes its meaning, so that tools that read the reports can tell them apart.
+const runReportSchemaVersion = 1
+
+// The statuses of the images in a run report, which are also the statuses of the images in the output of sync.
+const (
+	reportStatusExists   = "exists"
+	reportStatusMissing  = "missing"
+	reportStatusPushed   = "pushed"
+	reportStatusVerified = "verified"
+	reportStatusSkipped  = "skipped"
+	reportStatusFailed   = "failed"
+)
+
+// runReport records the status of each image of a run of the push, check or sync command, so that
+// CI jobs can archive what happened to every image and compare the reports of different runs.
+type runReport struct {
+	mutex   sync.Mutex
+	command string
+	started time.Time
+	images  []reportedImage
+}
+
+// reportedImage is the outcome of an image in a run report.
+type reportedImage struct {
+	Source          string   `json:"source"`
+	Target          string   `json:"target"`
+	Status          string   `json:"status"`
+	Issues          []string `json:"issues,omitempty"`
+	SourceDigest    string   `json:"sourceDigest,omitempty"`
+	TargetDigest    string   `json:"targetDigest,omitempty"`
+	DurationSeconds float64  `json:"durationSeconds"`
+	Error           string   `json:"error,omitempty"`
+}
+
+func newRunReport(command string) *runReport {
+	return &runReport{
+		command: command,
+		started: time.Now(),
+	}
+}
+
+// add records the outcome of the source, which took the time since it was started.
+func (r *runReport) add(source manifest.Source, status string, started time.Time, err error) {
+	r.addImage(newReportedImage(source, status, started, err))
+}
+
+func (r *runReport) addImage(image reportedImage) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.images = append(r.images, image)
+}
+
+func newReportedImage(source manifest.Source, status string, started time.Time, err error) reportedImage {
+	image := reportedImage{
+		Source:       source.Image(),
+		Target:       source.TargetImage(),
+		Status:       status,
+		SourceDigest: source.Digest,
+	}
+
+	if !started.IsZero() {
+		image.DurationSeconds = roundSeconds(time.Since(started))
+	}
+
+	if err != nil {
+		image.Error = err.Error()
+	}
+
+	return image
+}
+
+// write writes the report to the path as JSON, along with the error that the run failed with, if any.
+// The images are sorted by their target, so that the reports of different runs can be diffed.
+func (r *runReport) write(path string, runErr error) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	sort.SliceStable(r.images, func(i, j int) bool {
+		if r.images[i].Target != r.images[j].Target {
+			return r.images[i].Target < r.images[j].Target
+		}
+
+		return r.images[i].Source < r.images[j].Source
+	})
+
+	counts := make(map[string]int)
+	for _, image := range r.images {
+		counts[image.Status]++
+	}
+
+	finished := time.Now()
+	report := struct {
+		SchemaVersion   int             `json:"schemaVersion"`
+		Command         string          `json:"command"`
+		Version         string          `json:"version"`
+		Started         time.Time       `json:"started"`
+		Finished        time.Time       `json:"finished"`
+		DurationSeconds float64         `json:"durationSeconds"`
+		Error           string          `json:"error,omitempty"`
+		Counts          map[string]int  `json:"counts"`
+		Images          []reportedImage `json:"images"`
+	}{
+		SchemaVersion:   runReportSchemaVersion,
+		Command:         r.command,
+		Version:         buildVersion,
+		Started:         r.started.UTC(),
+		Finished:        finished.UTC(),
+		DurationSeconds: roundSeconds(finished.Sub(r.started)),
+		Counts:          counts,
+		Images:          r.images,
+	}
+
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+
+	if report.Images == nil {
+		report.Images = []reportedImage{}
+	}
+
+	contents, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+
+	if err := writeFileAtomic(path, append(contents, '\n')); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return nil
+}
+
+// roundSeconds returns the duration in seconds, rounded to milliseconds.
+func roundSeconds(duration time.Duration) float64 {
+	return float64(duration.Milliseconds()) / 1000
+}
+
+// writeRunReport writes the report to the path set by the report flag, if any, and returns the error of the run.
+// A report that cannot be written fails the run, unless the run already failed, so that its error is not hidden.
+func writeRunReport(report *runReport, runErr error) error {
+	path := viper.GetString("report")
+	if path == "" {
+		return runErr
+	}
+
+	if err := report.write(path, runErr); err != nil {
+		if runErr != nil {
+			log.Warnf("Unable to write the report: %v", err)
+			return runErr
+		}
+
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return runErr
+}
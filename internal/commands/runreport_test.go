@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+)
+
+func TestRunReportWrite(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	target := manifest.Target{Host: "mycompany.com", Repository: "mirror"}
+	report := newRunReport("push")
+	report.add(manifest.Source{Repository: "redis", Tag: "6", Target: target}, reportStatusFailed, time.Now(), errors.New("unauthorized"))
+	report.add(manifest.Source{Repository: "busybox", Tag: "1.32", Digest: "sha256:abc", Target: target}, reportStatusExists, time.Time{}, nil)
+
+	path := filepath.Join(tempDir, "report.json")
+	if err := report.write(path, errors.New("push images: 1 job(s) failed")); err != nil {
+		t.Fatal("write report:", err)
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal("read report:", err)
+	}
+
+	var actual struct {
+		SchemaVersion int             `json:"schemaVersion"`
+		Command       string          `json:"command"`
+		Error         string          `json:"error"`
+		Counts        map[string]int  `json:"counts"`
+		Images        []reportedImage `json:"images"`
+	}
+	if err := json.Unmarshal(contents, &actual); err != nil {
+		t.Fatal("unmarshal report:", err)
+	}
+
+	if actual.SchemaVersion != runReportSchemaVersion || actual.Command != "push" || actual.Error == "" {
+		t.Errorf("unexpected report %s", contents)
+	}
+
+	expectedCounts := map[string]int{reportStatusExists: 1, reportStatusFailed: 1}
+	if !reflect.DeepEqual(actual.Counts, expectedCounts) {
+		t.Errorf("expected counts %v, actual %v", expectedCounts, actual.Counts)
+	}
+
+	if len(actual.Images) != 2 {
+		t.Fatalf("expected 2 images, actual %v", len(actual.Images))
+	}
+
+	// The images are sorted by their target.
+	if actual.Images[0].Target != "mycompany.com/mirror/busybox:1.32" || actual.Images[0].SourceDigest != "sha256:abc" {
+		t.Errorf("unexpected first image %+v", actual.Images[0])
+	}
+
+	if actual.Images[1].Status != reportStatusFailed || actual.Images[1].Error != "unauthorized" {
+		t.Errorf("unexpected second image %+v", actual.Images[1])
+	}
+}
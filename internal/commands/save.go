@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newSaveCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "save",
+		Short: "Save the images in the manifest to an archive",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runSaveCommand(cmd.Context(), manifestPath, viper.GetString("output")); err != nil {
+				return fmt.Errorf("save: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "images.tar.gz", "Path where the archive will be written to")
+
+	return &cmd
+}
+
+func runSaveCommand(ctx context.Context, manifestPath string, archivePath string) error {
+	ctx, cancel := withCommandTimeout(ctx, 30*time.Minute)
+	defer cancel()
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	imageManifest, err := getManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	layoutPath, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(layoutPath)
+
+	for s, source := range imageManifest.Sources {
+		sourceAuth, err := getSourceAuth(source)
+		if err != nil {
+			return fmt.Errorf("get source auth: %w", err)
+		}
+
+		log.Infof("Saving %s", source.Image())
+		if err := client.SaveImage(ctx, source.PullImage(), sourceAuth, layoutPath); err != nil {
+			return fmt.Errorf("save image %s: %w", source.Image(), err)
+		}
+
+		log.Infof("Saved %s (%v/%v)", source.Image(), s+1, len(imageManifest.Sources))
+	}
+
+	bundleIndex, err := docker.WriteBundleIndex(layoutPath)
+	if err != nil {
+		return fmt.Errorf("write bundle index: %w", err)
+	}
+
+	for _, bundleImage := range bundleIndex.Images {
+		log.Infof("Indexed %s@%s", bundleImage.Name, bundleImage.Digest)
+	}
+
+	if err := docker.WriteArchive(layoutPath, archivePath); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+
+	log.Infof("All images have been saved to %s!", archivePath)
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/internal/scan"
+)
+
+type scanResult struct {
+	image   string
+	counts  map[string]int
+	blocked int
+}
+
+func scanSource(ctx context.Context, source manifest.Source, severity string, opts scan.Options) (scanResult, error) {
+	vulnerabilities, err := scan.ScanImage(ctx, source.PullImage(), opts)
+	if err != nil {
+		return scanResult{}, fmt.Errorf("scan image: %w", err)
+	}
+
+	blocked, err := scan.AtLeast(vulnerabilities, severity)
+	if err != nil {
+		return scanResult{}, fmt.Errorf("filter vulnerabilities: %w", err)
+	}
+
+	result := scanResult{
+		image:   source.Image(),
+		counts:  scan.CountBySeverity(vulnerabilities),
+		blocked: len(blocked),
+	}
+
+	return result, nil
+}
+
+func writeScanSummary(w io.Writer, results []scanResult) error {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].image < results[j].image
+	})
+
+	severities := make([]string, len(scan.Severities))
+	for i := range scan.Severities {
+		severities[i] = scan.Severities[len(scan.Severities)-1-i]
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintf(table, "IMAGE\t%s\tRESULT\n", strings.Join(severities, "\t")); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, result := range results {
+		var counts []string
+		for _, severity := range severities {
+			counts = append(counts, fmt.Sprint(result.counts[severity]))
+		}
+
+		status := "PASSED"
+		if result.blocked > 0 {
+			status = "BLOCKED"
+		}
+
+		if _, err := fmt.Fprintf(table, "%s\t%s\t%s\n", result.image, strings.Join(counts, "\t"), status); err != nil {
+			return fmt.Errorf("write result: %w", err)
+		}
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
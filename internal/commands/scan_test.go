@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteScanSummary(t *testing.T) {
+	results := []scanResult{
+		{image: "quay.io/coreos/prometheus-operator:v0.40.0", counts: map[string]int{"HIGH": 2}},
+		{image: "busybox:1.32.0", counts: map[string]int{"CRITICAL": 1, "LOW": 3}, blocked: 1},
+	}
+
+	var actual bytes.Buffer
+	if err := writeScanSummary(&actual, results); err != nil {
+		t.Fatal("write scan summary:", err)
+	}
+
+	expected := `IMAGE                                       CRITICAL  HIGH  MEDIUM  LOW  UNKNOWN  RESULT
+busybox:1.32.0                              1         0     0       3    0        BLOCKED
+quay.io/coreos/prometheus-operator:v0.40.0  0         2     0       0    0        PASSED
+`
+
+	if actual.String() != expected {
+		t.Errorf("expected summary\n%s\nactual\n%s", expected, actual.String())
+	}
+}
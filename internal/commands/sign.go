@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newSignCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "sign",
+		Short: "Sign the image manifest so that push and check can verify it before acting on it (requires cosign)",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("key", cmd.Flags().Lookup("key")); err != nil {
+				return fmt.Errorf("bind key flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("manifest-signature", cmd.Flags().Lookup("manifest-signature")); err != nil {
+				return fmt.Errorf("bind manifest-signature flag: %w", err)
+			}
+
+			if viper.GetString("key") == "" {
+				return errors.New("key must be specified")
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runSignCommand(cmd.Context(), manifestPath); err != nil {
+				return fmt.Errorf("sign: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("key", "", "Private key to sign the manifest with (required)")
+	cmd.Flags().String("manifest-signature", "", "Path where the signature will be written to (defaults to the path of the manifest with a .sig extension)")
+
+	return &cmd
+}
+
+func runSignCommand(ctx context.Context, manifestPath string) error {
+	location := manifest.Location(manifestPath)
+	if _, err := os.Stat(location); err != nil {
+		return fmt.Errorf("stat manifest: %w", err)
+	}
+
+	signaturePath := getManifestSignaturePath(manifestPath)
+	if err := docker.SignBlob(ctx, location, viper.GetString("key"), signaturePath); err != nil {
+		return fmt.Errorf("sign manifest: %w", err)
+	}
+
+	log.Infof("Signed %s, the signature was written to %s", location, signaturePath)
+
+	return nil
+}
+
+// verifyManifestSignature verifies the signature of the manifest with the public key of the
+// manifest-key flag, so that only signed manifests determine what is pushed to the target.
+// The manifest is not verified when no key is set.
+func verifyManifestSignature(ctx context.Context, manifestPath string) error {
+	key := viper.GetString("manifest-key")
+	if key == "" {
+		return nil
+	}
+
+	location := manifest.Location(manifestPath)
+	if err := docker.VerifyBlob(ctx, location, key, getManifestSignaturePath(manifestPath)); err != nil {
+		return fmt.Errorf("verify %s: %w", location, err)
+	}
+
+	log.Infof("Verified the signature of %s", location)
+
+	return nil
+}
+
+// getManifestSignaturePath returns the path of the signature of the manifest, which is the path
+// of the manifest file with a .sig extension unless the manifest-signature flag is set.
+func getManifestSignaturePath(manifestPath string) string {
+	if viper.GetString("manifest-signature") != "" {
+		return viper.GetString("manifest-signature")
+	}
+
+	return manifest.Location(manifestPath) + ".sig"
+}
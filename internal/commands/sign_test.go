@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestGetManifestSignaturePath(t *testing.T) {
+	testCases := []struct {
+		manifestPath string
+		expected     string
+	}{
+		{manifestPath: ".", expected: ".images.yaml.sig"},
+		{manifestPath: filepath.Join("manifests", "prod.yaml"), expected: filepath.Join("manifests", "prod.yaml.sig")},
+	}
+
+	for _, testCase := range testCases {
+		actual := getManifestSignaturePath(testCase.manifestPath)
+		if actual != testCase.expected {
+			t.Errorf("expected signature path of %s to be %s, actual %s", testCase.manifestPath, testCase.expected, actual)
+		}
+	}
+
+	viper.Set("manifest-signature", "signatures/manifest.sig")
+	defer viper.Set("manifest-signature", "")
+
+	if actual := getManifestSignaturePath("."); actual != "signatures/manifest.sig" {
+		t.Errorf("expected the signature path to be set by the flag, actual %s", actual)
+	}
+}
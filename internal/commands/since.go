@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/viper"
+)
+
+// filterChangedSources returns the sources that were added or changed in the manifest since the Git ref, which
+// are the sources that are not in the manifest at the ref (e.g. the images introduced by a pull request). The
+// manifest at the ref is read with the values files at the ref, so that a source whose image is set by a variable
+// that changed is also returned. All of the sources are returned when the manifest did not exist at the ref.
+func filterChangedSources(ctx context.Context, manifestPath string, sources []manifest.Source, ref string) ([]manifest.Source, error) {
+	location := manifest.Location(manifestPath)
+	if err := verifyGitRef(ctx, filepath.Dir(location), ref); err != nil {
+		return nil, err
+	}
+
+	contents, found, err := readGitFile(ctx, location, ref)
+	if err != nil {
+		return nil, fmt.Errorf("read manifest at %s: %w", ref, err)
+	}
+
+	if !found {
+		return sources, nil
+	}
+
+	variables := make(map[string]string)
+	for _, valuesPath := range viper.GetStringSlice("manifest-values") {
+		valuesContents, found, err := readGitFile(ctx, valuesPath, ref)
+		if err != nil {
+			return nil, fmt.Errorf("read values %s at %s: %w", valuesPath, ref, err)
+		}
+
+		if !found {
+			continue
+		}
+
+		values, err := manifest.ParseVariables(valuesContents)
+		if err != nil {
+			return nil, fmt.Errorf("parse values %s at %s: %w", valuesPath, ref, err)
+		}
+
+		for name, value := range values {
+			variables[name] = value
+		}
+	}
+
+	contents, err = manifest.ExpandVariables(contents, variables)
+	if err != nil {
+		return nil, fmt.Errorf("expand variables at %s: %w", ref, err)
+	}
+
+	previousManifest, err := manifest.Parse(contents)
+	if err != nil {
+		return nil, fmt.Errorf("parse manifest at %s: %w", ref, err)
+	}
+
+	previousKeys := make(map[string]bool)
+	for _, source := range previousManifest.Sources {
+		previousKeys[getSourceKey(source)] = true
+	}
+
+	var changedSources []manifest.Source
+	for _, source := range sources {
+		if !previousKeys[getSourceKey(source)] {
+			changedSources = append(changedSources, source)
+		}
+	}
+
+	return changedSources, nil
+}
+
+// getSourceKey returns the key that identifies the images of the source, which changes when the source
+// or target image of the source changes, or when the tags that are selected by the source change.
+func getSourceKey(source manifest.Source) string {
+	return fmt.Sprintf("%s %s %s %s %s %v", source.Image(), source.TargetImage(), source.Type, source.Tags, source.TagPattern, source.Keep)
+}
+
+// verifyGitRef returns an error when the ref is not a commit of the Git repository that the directory is in.
+func verifyGitRef(ctx context.Context, dir string, ref string) error {
+	if _, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return fmt.Errorf("git ref %s not found: %w", ref, err)
+	}
+
+	return nil
+}
+
+// readGitFile returns the contents of the file at the path as of the Git ref, and whether the file existed at the ref.
+func readGitFile(ctx context.Context, path string, ref string) ([]byte, bool, error) {
+	dir, file := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	// The ./ prefix makes the path relative to the directory, rather than to the root of the repository.
+	object := ref + ":./" + file
+	if _, err := runGit(ctx, dir, "cat-file", "-e", object); err != nil {
+		return nil, false, nil
+	}
+
+	contents, err := runGit(ctx, dir, "show", object)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return contents, true, nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/spf13/viper"
+)
+
+func TestFilterChangedSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	git := func(args ...string) {
+		args = append([]string{"-c", "user.name=sinker", "-c", "user.email=sinker@example.com"}, args...)
+		if _, err := runGit(context.Background(), dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const previousManifest = `target:
+  host: mycompany.com
+sources:
+- repository: busybox
+  tag: 1.32.0
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tag: ${OPERATOR_VERSION}
+`
+
+	manifestPath := filepath.Join(dir, ".images.yaml")
+	valuesPath := filepath.Join(dir, "values.yaml")
+	if err := ioutil.WriteFile(manifestPath, []byte(previousManifest), os.ModePerm); err != nil {
+		t.Fatal("write manifest:", err)
+	}
+	if err := ioutil.WriteFile(valuesPath, []byte("OPERATOR_VERSION: v0.40.0\n"), os.ModePerm); err != nil {
+		t.Fatal("write values:", err)
+	}
+
+	git("init", "--quiet")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "initial")
+
+	viper.Set("manifest-values", []string{valuesPath})
+	defer viper.Set("manifest-values", nil)
+
+	if err := ioutil.WriteFile(manifestPath, []byte(previousManifest+"- repository: nginx\n  tag: \"1.25\"\n"), os.ModePerm); err != nil {
+		t.Fatal("write manifest:", err)
+	}
+	if err := ioutil.WriteFile(valuesPath, []byte("OPERATOR_VERSION: v0.41.0\n"), os.ModePerm); err != nil {
+		t.Fatal("write values:", err)
+	}
+
+	currentManifest, err := getManifest(dir)
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	actual, err := filterChangedSources(context.Background(), dir, currentManifest.Sources, "HEAD")
+	if err != nil {
+		t.Fatal("filter changed sources:", err)
+	}
+
+	var actualImages []string
+	for _, source := range actual {
+		actualImages = append(actualImages, source.Image())
+	}
+
+	expected := []string{
+		"quay.io/coreos/prometheus-operator:v0.41.0",
+		"nginx:1.25",
+	}
+
+	if !reflect.DeepEqual(actualImages, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actualImages)
+	}
+
+	if _, err := filterChangedSources(context.Background(), dir, currentManifest.Sources, "missing"); err == nil {
+		t.Error("expected an error for a ref that does not exist")
+	}
+}
+
+func TestFilterChangedSources_NewManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := runGit(context.Background(), dir, "init", "--quiet"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runGit(context.Background(), dir, "-c", "user.name=sinker", "-c", "user.email=sinker@example.com", "commit", "--quiet", "--allow-empty", "-m", "initial"); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := []manifest.Source{{Repository: "busybox", Tag: "1.32.0"}}
+
+	actual, err := filterChangedSources(context.Background(), dir, sources, "HEAD")
+	if err != nil {
+		t.Fatal("filter changed sources:", err)
+	}
+
+	if !reflect.DeepEqual(actual, sources) {
+		t.Errorf("expected sources %+v, actual %+v", sources, actual)
+	}
+}
@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/spf13/viper"
+)
+
+func TestSinker_PushAndCheck(t *testing.T) {
+	source := testutil.NewRegistry(t)
+	target := testutil.NewRegistry(t)
+
+	appDigest := source.PushImage(t, "org/app:1.0", 2)
+	multiDigest := source.PushIndex(t, "org/multi:1.0", "linux/amd64", "linux/arm64/v8")
+
+	dir := sinkerTempDir(t)
+	manifestPath := writeSinkerManifest(t, dir, target.Reference("mirror"), source.Reference("org/app:1.0"), source.Reference("org/multi:1.0"))
+
+	ctx := context.Background()
+	if err := runSinker(ctx, "check", "--exists", "--manifest", manifestPath); err == nil {
+		t.Fatal("expected check to fail before the images are pushed")
+	}
+
+	if err := runSinker(ctx, "push", "--manifest", manifestPath); err != nil {
+		t.Fatal("push:", err)
+	}
+
+	if err := runSinker(ctx, "check", "--exists", "--manifest", manifestPath); err != nil {
+		t.Fatal("check after push:", err)
+	}
+
+	expected := map[string]string{
+		"mirror/org/app:1.0":   appDigest,
+		"mirror/org/multi:1.0": multiDigest,
+	}
+
+	for image, expectedDigest := range expected {
+		actual, ok := target.Digest(t, image)
+		if !ok {
+			t.Errorf("expected %s to be pushed", image)
+			continue
+		}
+
+		if actual != expectedDigest {
+			t.Errorf("expected %s to have digest %s, actual %s", image, expectedDigest, actual)
+		}
+	}
+}
+
+func TestSinker_Sync(t *testing.T) {
+	source := testutil.NewRegistry(t)
+	target := testutil.NewRegistry(t)
+
+	source.PushImage(t, "org/app:1.0", 1)
+	source.PushImage(t, "org/app:2.0", 1)
+
+	dir := sinkerTempDir(t)
+	writeSinkerDeployment(t, dir, "app", source.Reference("org/app:1.0"), source.Reference("org/app:2.0"))
+
+	if err := runSinker(context.Background(), "sync", dir, "--target", target.Reference("mirror")); err != nil {
+		t.Fatal("sync:", err)
+	}
+
+	for _, image := range []string{"mirror/org/app:1.0", "mirror/org/app:2.0"} {
+		if _, ok := target.Digest(t, image); !ok {
+			t.Errorf("expected %s to be synced", image)
+		}
+	}
+
+	if _, ok := target.Digest(t, "mirror/org/app:3.0"); ok {
+		t.Error("expected an image that was not synced to not be found")
+	}
+}
+
+func TestSinker_CreateSources(t *testing.T) {
+	dir := sinkerTempDir(t)
+
+	// Only the first source would be used, so the other sources are rejected rather than ignored.
+	err := runSinker(context.Background(), "create", filepath.Join(dir, "app"), filepath.Join(dir, "web"), "--target", "mycompany.com/myteam", "--output", dir)
+	if err == nil || !strings.Contains(err.Error(), "accepts at most 1 arg(s)") {
+		t.Errorf("expected an error for more than one source, actual %v", err)
+	}
+}
+
+func TestSinker_ExitCode(t *testing.T) {
+	source := testutil.NewRegistry(t)
+	target := testutil.NewRegistry(t)
+
+	source.PushImage(t, "org/app:1.0", 1)
+
+	dir := sinkerTempDir(t)
+	manifestPath := writeSinkerManifest(t, dir, target.Host, source.Reference("org/app:1.0"), source.Reference("org/missing:1.0"))
+
+	err := runSinker(context.Background(), "push", "--manifest", manifestPath, "--retries", "0")
+	if actual := getExitCode(err); actual != exitCodePartialFailure {
+		t.Errorf("expected exit code %v when some of the images fail to push, actual %d (%v)", exitCodePartialFailure, actual, err)
+	}
+
+	if actual := getExitCode(nil); actual != 0 {
+		t.Errorf("expected exit code 0 without an error, actual %d", actual)
+	}
+}
+
+// runSinker runs sinker with the arguments (e.g. push --manifest .images.yaml) in the process of the test, as the sinker
+// binary would. The flags of previous runs are reset before each run, but the configuration of sinker is global, so
+// runSinker must not be called from parallel tests.
+func runSinker(ctx context.Context, args ...string) error {
+	viper.Reset()
+
+	cmd := NewDefaultCommand()
+	cmd.SetArgs(args)
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+
+	if err := cmd.ExecuteContext(ctx); err != nil {
+		return fmt.Errorf("run sinker %s: %w", strings.Join(args, " "), err)
+	}
+
+	return nil
+}
+
+// getExitCode returns the code that the sinker binary exits with for the error returned by runSinker.
+func getExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	return 1
+}
+
+// writeSinkerManifest writes an image manifest to the directory with the images as its sources, which are pushed to
+// the target, a host with an optional repository (e.g. 127.0.0.1:41235/mirror), and returns the path of the manifest.
+func writeSinkerManifest(t *testing.T, dir string, target string, images ...string) string {
+	sources, err := manifest.GetSourcesFromImages(images, target)
+	if err != nil {
+		t.Fatal("get sources from images:", err)
+	}
+
+	// The sources are pushed to the target of the manifest. The target is split at its first slash, as the port
+	// of a target without a repository (e.g. 127.0.0.1:41235) would be mistaken for a tag when it is parsed.
+	for s := range sources {
+		sources[s].Target = manifest.Target{}
+	}
+
+	targetTokens := strings.SplitN(target, "/", 2)
+	targetTokens = append(targetTokens, "")
+
+	imageManifest := manifest.New(targetTokens[0], targetTokens[1])
+	imageManifest.Sources = sources
+
+	if err := imageManifest.Write(dir); err != nil {
+		t.Fatal("write manifest:", err)
+	}
+
+	return manifest.Location(dir)
+}
+
+// writeSinkerDeployment writes a Kubernetes Deployment to the directory with a container for each of the images.
+func writeSinkerDeployment(t *testing.T, dir string, name string, images ...string) {
+	var containers strings.Builder
+	for i, image := range images {
+		fmt.Fprintf(&containers, "      - name: container%d\n        image: %s\n", i, image)
+	}
+
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: ` + name + `
+spec:
+  selector:
+    matchLabels:
+      app: ` + name + `
+  template:
+    metadata:
+      labels:
+        app: ` + name + `
+    spec:
+      containers:
+` + containers.String()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, name+".yaml"), []byte(deployment), os.ModePerm); err != nil {
+		t.Fatal("write deployment:", err)
+	}
+}
+
+func sinkerTempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
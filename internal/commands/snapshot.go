@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// registrySnapshot is the inventory of the images in the repositories that the images of a manifest
+// are mirrored into, so that the target can be checked without access to the target registry.
+type registrySnapshot struct {
+	Created time.Time       `json:"created"`
+	Images  []snapshotImage `json:"images"`
+
+	digests map[string]string
+}
+
+type snapshotImage struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"`
+}
+
+func newSnapshotCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "snapshot",
+		Short: "Record the tags and digests of the images at the target registry to check against offline",
+		Long: `Record the tags and digests of the images at the target registry to check against offline.
+
+Every tag of the repositories under the target of the manifest (and its mappings) is recorded
+in the snapshot, which the check command compares the manifest against with --offline.`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runSnapshotCommand(cmd.Context(), manifestPath, viper.GetString("output")); err != nil {
+				return fmt.Errorf("snapshot: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "snapshot.json", "Path where the snapshot will be written to")
+
+	return &cmd
+}
+
+func runSnapshotCommand(ctx context.Context, manifestPath string, outputPath string) error {
+	ctx, cancel := withCommandTimeout(ctx, 0)
+	defer cancel()
+
+	imageManifest, err := getManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	namespaces, err := getPruneNamespaces(imageManifest)
+	if err != nil {
+		return fmt.Errorf("get namespaces: %w", err)
+	}
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	snapshot, err := takeSnapshot(ctx, client, namespaces)
+	if err != nil {
+		return fmt.Errorf("take snapshot: %w", err)
+	}
+
+	if err := snapshot.write(outputPath); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+
+	log.Infof("Recorded %v image(s) in %s", len(snapshot.Images), outputPath)
+
+	return nil
+}
+
+// takeSnapshot returns the snapshot of every tag of the repositories under the namespaces.
+func takeSnapshot(ctx context.Context, client docker.Client, namespaces []pruneNamespace) (registrySnapshot, error) {
+	hostNamespaces := make(map[string][]pruneNamespace)
+	var hosts []string
+	for _, namespace := range namespaces {
+		if _, ok := hostNamespaces[namespace.host]; !ok {
+			hosts = append(hosts, namespace.host)
+		}
+
+		hostNamespaces[namespace.host] = append(hostNamespaces[namespace.host], namespace)
+	}
+	sort.Strings(hosts)
+
+	snapshot := registrySnapshot{Created: time.Now().UTC()}
+	for _, host := range hosts {
+		repositories, err := client.GetRepositories(ctx, host, hostNamespaces[host][0].auth)
+		if err != nil {
+			return registrySnapshot{}, fmt.Errorf("get repositories of %s: %w", host, err)
+		}
+		sort.Strings(repositories)
+
+		for _, repository := range repositories {
+			namespace, ok := findPruneNamespace(hostNamespaces[host], repository)
+			if !ok {
+				continue
+			}
+
+			tags, err := client.GetTags(ctx, host+"/"+repository, namespace.auth)
+			if err != nil {
+				return registrySnapshot{}, fmt.Errorf("get tags of %s: %w", repository, err)
+			}
+			sort.Strings(tags)
+
+			for _, tag := range tags {
+				image := host + "/" + repository + ":" + tag
+				digest, err := client.GetDigest(ctx, image, namespace.auth)
+				if err != nil {
+					return registrySnapshot{}, fmt.Errorf("get digest of %s: %w", image, err)
+				}
+
+				snapshot.Images = append(snapshot.Images, snapshotImage{Image: image, Digest: digest})
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+// readSnapshot reads the snapshot at the given path.
+func readSnapshot(path string) (registrySnapshot, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return registrySnapshot{}, fmt.Errorf("read file: %w", err)
+	}
+
+	var snapshot registrySnapshot
+	if err := json.Unmarshal(contents, &snapshot); err != nil {
+		return registrySnapshot{}, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	snapshot.digests = make(map[string]string)
+	for _, image := range snapshot.Images {
+		snapshot.digests[image.Image] = image.Digest
+	}
+
+	return snapshot, nil
+}
+
+func (s registrySnapshot) write(path string) error {
+	contents, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	if err := ioutil.WriteFile(path, append(contents, '\n'), os.ModePerm); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+
+	return nil
+}
+
+// exists returns true when the target image of the source was at the target when the snapshot was taken.
+func (s registrySnapshot) exists(source manifest.Source) bool {
+	_, ok := s.digests[source.TargetImage()]
+	return ok
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+)
+
+func TestFindMissingImagesInSnapshot(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	snapshot := registrySnapshot{
+		Created: time.Now().UTC(),
+		Images: []snapshotImage{
+			{Image: "mycompany.com/mirror/busybox:1.32.0", Digest: "sha256:abc"},
+			{Image: "mycompany.com/mirror/nginx:1.25", Digest: "sha256:def"},
+		},
+	}
+
+	snapshotPath := filepath.Join(tempDir, "snapshot.json")
+	if err := snapshot.write(snapshotPath); err != nil {
+		t.Fatal("write snapshot:", err)
+	}
+
+	target := manifest.Target{Host: "mycompany.com", Repository: "mirror"}
+	sources := []manifest.Source{
+		{Target: target, Repository: "busybox", Tag: "1.32.0"},
+		{Target: target, Repository: "busybox", Tag: "1.33.0"},
+		{Target: target, Repository: "nginx", Tag: "1.25"},
+	}
+
+	actual, err := findMissingImagesInSnapshot(snapshotPath, sources)
+	if err != nil {
+		t.Fatal("find missing images:", err)
+	}
+
+	expected := []string{"mycompany.com/mirror/busybox:1.33.0"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected missing images %v, actual %v", expected, actual)
+	}
+}
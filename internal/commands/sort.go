@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+// The orders that the images can be listed in.
+const (
+	sortRegistry = "registry"
+	sortName     = "name"
+	sortFile     = "file"
+)
+
+// sortKey is the registry, repository and tag of an image that it is sorted by.
+type sortKey struct {
+	host       string
+	repository string
+	name       string
+	tag        string
+	reference  string
+}
+
+func newSortKey(image string) sortKey {
+	key := sortKey{reference: image}
+
+	// Images are sorted by their normalized reference so that images on Docker
+	// Hub are sorted together, whether or not they include the docker.io host.
+	normalized, err := images.NormalizeReference(image, getRegistryHostsOption())
+	if err != nil {
+		return key
+	}
+
+	parsed, err := images.ParseReference(normalized, getRegistryHostsOption())
+	if err != nil {
+		return key
+	}
+
+	key.host = parsed.Host
+	key.repository = parsed.Repository
+	key.name = path.Base(parsed.Repository)
+	key.tag = parsed.Tag + "@" + parsed.Digest
+
+	return key
+}
+
+func (k sortKey) fields(order string) []string {
+	if order == sortName {
+		return []string{k.name, k.host, k.repository, k.tag, k.reference}
+	}
+
+	return []string{k.host, k.repository, k.tag, k.reference}
+}
+
+// sortImageIndexes returns the indexes of the images in the given order. The images are sorted by
+// their registry, repository and tag, by their name (the last part of their repository) and then
+// their registry, or are kept in the order that they appear in the manifest or its source files.
+func sortImageIndexes(imagesToSort []string, order string) ([]int, error) {
+	if order != sortRegistry && order != sortName && order != sortFile {
+		return nil, fmt.Errorf("unknown sort order %s (must be %s, %s or %s)", order, sortRegistry, sortName, sortFile)
+	}
+
+	indexes := make([]int, len(imagesToSort))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	if order == sortFile {
+		return indexes, nil
+	}
+
+	keys := make([][]string, len(imagesToSort))
+	for i, image := range imagesToSort {
+		keys[i] = newSortKey(image).fields(order)
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		left, right := keys[indexes[i]], keys[indexes[j]]
+		for f := range left {
+			if left[f] != right[f] {
+				return left[f] < right[f]
+			}
+		}
+
+		return false
+	})
+
+	return indexes, nil
+}
@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortImageIndexes(t *testing.T) {
+	imagesToSort := []string{
+		"quay.io/coreos/etcd:v3.4.0",
+		"nginx:1.25",
+		"mycompany.com/mirror/nginx:1.19",
+		"docker.io/library/busybox:1.32.0",
+		"nginx:1.19",
+	}
+
+	testCases := []struct {
+		order    string
+		expected []int
+	}{
+		// Images on Docker Hub are sorted together, whether or not they include the docker.io host.
+		{order: sortRegistry, expected: []int{3, 4, 1, 2, 0}},
+		{order: sortName, expected: []int{3, 0, 4, 1, 2}},
+		{order: sortFile, expected: []int{0, 1, 2, 3, 4}},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := sortImageIndexes(imagesToSort, testCase.order)
+		if err != nil {
+			t.Fatalf("sort images by %s: %v", testCase.order, err)
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected images sorted by %s to be %v, actual %v", testCase.order, testCase.expected, actual)
+		}
+	}
+
+	if _, err := sortImageIndexes(imagesToSort, "size"); err == nil {
+		t.Error("expected an error for an unknown sort order")
+	}
+}
@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// pushState records the target images that have been confirmed to exist at the target
+// so that a push that was interrupted can resume with only the images that remain.
+type pushState struct {
+	path      string
+	mutex     sync.Mutex
+	confirmed map[string]bool
+}
+
+// readPushState reads the push state at the given path. A state
+// that does not exist yet has no confirmed images.
+func readPushState(path string) (*pushState, error) {
+	state := pushState{
+		path:      path,
+		confirmed: make(map[string]bool),
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			state.confirmed[line] = true
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	return &state, nil
+}
+
+func (s *pushState) isConfirmed(image string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.confirmed[image]
+}
+
+// confirm records that the image exists at the target. Each image is written to the
+// state as soon as it is confirmed so that no progress is lost if the push is interrupted.
+func (s *pushState) confirm(image string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.confirmed[image] {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+
+	if _, err := fmt.Fprintln(f, image); err != nil {
+		f.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+
+	// The state must survive the process being stopped right after the push.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	s.confirmed[image] = true
+
+	return nil
+}
+
+// remove removes the state once every image has been pushed.
+func (s *pushState) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPushState(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	statePath := filepath.Join(tempDir, "push.state")
+
+	state, err := readPushState(statePath)
+	if err != nil {
+		t.Fatal("read push state:", err)
+	}
+
+	if err := state.confirm("host.com/repo/busybox:1.32.0"); err != nil {
+		t.Fatal("confirm:", err)
+	}
+
+	resumedState, err := readPushState(statePath)
+	if err != nil {
+		t.Fatal("read resumed push state:", err)
+	}
+
+	if !resumedState.isConfirmed("host.com/repo/busybox:1.32.0") {
+		t.Error("expected image to be confirmed after resuming")
+	}
+
+	if resumedState.isConfirmed("host.com/repo/busybox:1.31.0") {
+		t.Error("expected image to not be confirmed")
+	}
+
+	if err := resumedState.remove(); err != nil {
+		t.Fatal("remove:", err)
+	}
+
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Error("expected state to be removed")
+	}
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// imageStatus is the status of an image that is reported by the push and check commands.
+type imageStatus int
+
+const (
+	statusSynced imageStatus = iota
+	statusSkipped
+	statusFailed
+)
+
+// The ANSI escape codes of the colors of each status.
+var statusColors = map[imageStatus]string{
+	statusSynced:  "\033[32m",
+	statusSkipped: "\033[33m",
+	statusFailed:  "\033[31m",
+}
+
+const colorReset = "\033[0m"
+
+// configureOutput discards all log messages when the output is quiet, so that only the image references
+// printed by the command are written, and disables the colors of the log messages when requested.
+func configureOutput(quiet bool, noColor bool) {
+	if quiet {
+		log.SetOutput(ioutil.Discard)
+	} else {
+		log.SetOutput(os.Stderr)
+	}
+
+	if formatter, ok := log.StandardLogger().Formatter.(*log.TextFormatter); ok {
+		formatter.DisableColors = !isColorEnabled(noColor)
+	}
+}
+
+// isColorEnabled returns whether the log messages are colored, which is only the case when
+// they are written to a terminal and colors have not been disabled with the no-color flag
+// or the NO_COLOR environment variable (https://no-color.org).
+func isColorEnabled(noColor bool) bool {
+	_, noColorEnv := os.LookupEnv("NO_COLOR")
+	return !noColor && !noColorEnv && isTerminal(os.Stderr)
+}
+
+// logImageStatus logs a message about an image, which is colored by the status of the image
+// when colors are enabled. Messages about images that failed are logged as errors.
+func logImageStatus(status imageStatus, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if viper.GetString("log-format") == "text" && isColorEnabled(viper.GetBool("no-color")) {
+		message = statusColors[status] + message + colorReset
+	}
+
+	if status == statusFailed {
+		log.Error(message)
+	} else {
+		log.Info(message)
+	}
+}
+
+// printQuiet prints the reference of an image to stdout when the output is quiet, which
+// replaces the log messages with only the images that the command reports on.
+func printQuiet(image string) {
+	if viper.GetBool("quiet") {
+		fmt.Println(image)
+	}
+}
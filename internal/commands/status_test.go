@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestConfigureOutput(t *testing.T) {
+	defer configureLogging("info", "text")
+	defer configureOutput(false, false)
+
+	if err := configureLogging("info", "text"); err != nil {
+		t.Fatal("configure logging:", err)
+	}
+
+	configureOutput(true, true)
+
+	if log.StandardLogger().Out != ioutil.Discard {
+		t.Error("expected log messages to be discarded when quiet")
+	}
+
+	formatter, ok := log.StandardLogger().Formatter.(*log.TextFormatter)
+	if !ok {
+		t.Fatalf("expected text formatter, actual %T", log.StandardLogger().Formatter)
+	}
+
+	if !formatter.DisableColors {
+		t.Error("expected colors to be disabled")
+	}
+
+	configureOutput(false, false)
+
+	if log.StandardLogger().Out != os.Stderr {
+		t.Error("expected log messages to be written to stderr")
+	}
+}
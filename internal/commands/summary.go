@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// runSummary is the summary of a run of the list, push or check command, so that
+// CI jobs have a single artifact to archive with what the run found and did.
+type runSummary struct {
+	FilesScanned     int    `json:"filesScanned"`
+	ResourcesParsed  int    `json:"resourcesParsed"`
+	ImagesFound      int    `json:"imagesFound"`
+	UniqueRegistries int    `json:"uniqueRegistries"`
+	ImagesSkipped    int    `json:"imagesSkipped"`
+	MissingAtTarget  int    `json:"missingAtTarget"`
+	BytesCopied      uint64 `json:"bytesCopied"`
+}
+
+// newRunSummary returns the summary of the number of images that were found, of which only the given
+// sources were kept. The other images were skipped by the ignore section, ignore file or exclude flags.
+func newRunSummary(imagesFound int, sources []manifest.Source) runSummary {
+	registries := make(map[string]bool)
+	for _, source := range sources {
+		host := strings.ToLower(source.Host)
+		if host == "" || host == "index.docker.io" {
+			host = "docker.io"
+		}
+
+		registries[host] = true
+	}
+
+	summary := runSummary{
+		ImagesFound:      imagesFound,
+		UniqueRegistries: len(registries),
+		ImagesSkipped:    imagesFound - len(sources),
+	}
+
+	return summary
+}
+
+func (s runSummary) String() string {
+	return fmt.Sprintf("%v file(s) scanned, %v resource(s) parsed, %v image(s) found from %v registries, %v image(s) skipped, %v image(s) missing at the target, %v byte(s) copied",
+		s.FilesScanned, s.ResourcesParsed, s.ImagesFound, s.UniqueRegistries, s.ImagesSkipped, s.MissingAtTarget, s.BytesCopied)
+}
+
+// writeSummary logs the summary and, when the summary-file flag is set, writes it to the file as JSON.
+func writeSummary(summary runSummary) error {
+	log.Infof("Summary: %v", summary)
+
+	path := viper.GetString("summary-file")
+	if path == "" {
+		return nil
+	}
+
+	contents, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal summary: %w", err)
+	}
+
+	if err := ioutil.WriteFile(path, append(contents, '\n'), os.ModePerm); err != nil {
+		return fmt.Errorf("write summary: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/viper"
+)
+
+func TestNewRunSummary(t *testing.T) {
+	sources := []manifest.Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "docker.io", Repository: "library/nginx", Tag: "1.25"},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.0"},
+	}
+
+	summary := newRunSummary(5, sources)
+
+	if summary.ImagesFound != 5 {
+		t.Errorf("expected 5 images found, actual %v", summary.ImagesFound)
+	}
+
+	if summary.ImagesSkipped != 2 {
+		t.Errorf("expected 2 images skipped, actual %v", summary.ImagesSkipped)
+	}
+
+	// Images without a host are on Docker Hub, which is the same registry as docker.io.
+	if summary.UniqueRegistries != 2 {
+		t.Errorf("expected 2 unique registries, actual %v", summary.UniqueRegistries)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	summaryPath := filepath.Join(tempDir, "summary.json")
+	viper.Set("summary-file", summaryPath)
+	defer viper.Set("summary-file", "")
+
+	expected := runSummary{
+		FilesScanned:     1,
+		ResourcesParsed:  2,
+		ImagesFound:      3,
+		UniqueRegistries: 1,
+		MissingAtTarget:  1,
+		BytesCopied:      1024,
+	}
+
+	if err := writeSummary(expected); err != nil {
+		t.Fatal("write summary:", err)
+	}
+
+	contents, err := ioutil.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal("read summary:", err)
+	}
+
+	var actual runSummary
+	if err := json.Unmarshal(contents, &actual); err != nil {
+		t.Fatal("unmarshal summary:", err)
+	}
+
+	if actual != expected {
+		t.Errorf("expected summary %+v, actual %+v", expected, actual)
+	}
+}
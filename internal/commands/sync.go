@@ -0,0 +1,413 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// syncResult is the outcome of syncing the image of a source to its target.
+type syncResult struct {
+	source       manifest.Source
+	status       string
+	digest       string
+	sourceDigest string
+	duration     time.Duration
+	err          error
+}
+
+func newSyncCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "sync <source>",
+		Short: "Find the images at the source and push the images that are missing at the target",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("target", cmd.Flags().Lookup("target")); err != nil {
+				return fmt.Errorf("bind target flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dry-run", cmd.Flags().Lookup("dry-run")); err != nil {
+				return fmt.Errorf("bind dry-run flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("jobs", cmd.Flags().Lookup("jobs")); err != nil {
+				return fmt.Errorf("bind jobs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("platforms", cmd.Flags().Lookup("platforms")); err != nil {
+				return fmt.Errorf("bind platforms flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude", cmd.Flags().Lookup("exclude")); err != nil {
+				return fmt.Errorf("bind exclude flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("verify-digests", cmd.Flags().Lookup("verify-digests")); err != nil {
+				return fmt.Errorf("bind verify-digests flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("report", cmd.Flags().Lookup("report")); err != nil {
+				return fmt.Errorf("bind report flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm", cmd.Flags().Lookup("helm")); err != nil {
+				return fmt.Errorf("bind helm flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm-values", cmd.Flags().Lookup("helm-values")); err != nil {
+				return fmt.Errorf("bind helm-values flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kustomize", cmd.Flags().Lookup("kustomize")); err != nil {
+				return fmt.Errorf("bind kustomize flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images", cmd.Flags().Lookup("env-images")); err != nil {
+				return fmt.Errorf("bind env-images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images-pattern", cmd.Flags().Lookup("env-images-pattern")); err != nil {
+				return fmt.Errorf("bind env-images-pattern flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("crd-config", cmd.Flags().Lookup("crd-config")); err != nil {
+				return fmt.Errorf("bind crd-config flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("skip-dirs", cmd.Flags().Lookup("skip-dirs")); err != nil {
+				return fmt.Errorf("bind skip-dirs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-gitignore", cmd.Flags().Lookup("no-gitignore")); err != nil {
+				return fmt.Errorf("bind no-gitignore flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-symlinks", cmd.Flags().Lookup("follow-symlinks")); err != nil {
+				return fmt.Errorf("bind follow-symlinks flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kinds", cmd.Flags().Lookup("kinds")); err != nil {
+				return fmt.Errorf("bind kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-kinds", cmd.Flags().Lookup("exclude-kinds")); err != nil {
+				return fmt.Errorf("bind exclude-kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("selector", cmd.Flags().Lookup("selector")); err != nil {
+				return fmt.Errorf("bind selector flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-document-size", cmd.Flags().Lookup("max-document-size")); err != nil {
+				return fmt.Errorf("bind max-document-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("warn-on-parse-error", cmd.Flags().Lookup("warn-on-parse-error")); err != nil {
+				return fmt.Errorf("bind warn-on-parse-error flag: %w", err)
+			}
+
+			if viper.GetBool("verify-digests") && len(viper.GetStringSlice("platforms")) > 0 {
+				return errors.New("digests cannot be verified when using the platforms flag, as the digest of a multi-arch image changes when platforms are removed (use --verify-digests=false)")
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runSyncCommand(cmd.Context(), args[0], manifestPath); err != nil {
+				return fmt.Errorf("sync: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("target", "t", "", "Registry the images will be pushed to (e.g. host.com/repo), defaults to the target of the manifest")
+	cmd.Flags().Bool("dry-run", false, "Print the images that are missing at the target without pushing them")
+	cmd.Flags().IntP("jobs", "j", 4, "Number of images to check and push at the same time")
+	cmd.Flags().StringSlice("platforms", []string{}, "Platforms to push for multi-arch images (e.g. linux/amd64,linux/arm64), defaults to all platforms")
+	cmd.Flags().StringSlice("exclude", []string{}, "Glob patterns of images to exclude (e.g. busybox:*)")
+	cmd.Flags().Bool("verify-digests", true, "Verify that the digest of each pushed image at the target matches the digest of the source")
+	cmd.Flags().String("report", "", "Path to write a JSON report of the status, digests and timings of each image to")
+	cmd.Flags().Bool("helm", false, "Render Helm charts found at the source before finding images (requires helm)")
+	cmd.Flags().StringSlice("helm-values", []string{}, "Values files to use when rendering Helm charts")
+	cmd.Flags().Bool("kustomize", false, "Build kustomizations found at the source before finding images (requires kustomize)")
+	cmd.Flags().Bool("env-images", false, "Find images in the values of container environment variables")
+	cmd.Flags().String("env-images-pattern", "IMAGE", "Regular expression that environment variable names must match to be searched for images")
+	cmd.Flags().String("crd-config", "", "Path to a file that declares where images are located in custom resources")
+	cmd.Flags().StringSlice("skip-dirs", []string{}, "Directories to skip when finding images, by name or by path relative to the source (e.g. testdata,charts/*/tests)")
+	cmd.Flags().Bool("no-gitignore", false, "Also find images in files and directories that are ignored by .gitignore files")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symbolic links to directories when finding images")
+	cmd.Flags().StringSlice("kinds", []string{}, "Only find images in the resources of the given kinds (e.g. Deployment,CronJob)")
+	cmd.Flags().StringSlice("exclude-kinds", []string{}, "Skip the resources of the given kinds when finding images (e.g. ConfigMap)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Only find images in the resources in the given namespaces")
+	cmd.Flags().StringP("selector", "l", "", "Only find images in the resources whose labels match the selector (e.g. app.kubernetes.io/part-of=platform)")
+	cmd.Flags().String("max-document-size", "16MiB", "Maximum size of a YAML document (e.g. 64MiB), larger documents are skipped (0 does not limit the size)")
+	cmd.Flags().Bool("warn-on-parse-error", true, "Warn about YAML files that cannot be parsed, which are otherwise skipped")
+
+	return &cmd
+}
+
+func runSyncCommand(ctx context.Context, resourcePath string, manifestPath string) error {
+	report := newRunReport("sync")
+	results, err := syncImages(ctx, resourcePath, manifestPath)
+	for _, result := range results {
+		reportedImage := newReportedImage(result.source, result.status, time.Time{}, result.err)
+		reportedImage.DurationSeconds = roundSeconds(result.duration)
+		reportedImage.TargetDigest = result.digest
+		if result.sourceDigest != "" {
+			reportedImage.SourceDigest = result.sourceDigest
+		}
+
+		report.addImage(reportedImage)
+	}
+
+	return writeRunReport(report, err)
+}
+
+// syncImages finds the images at the source and syncs them to the target, returning the result of each image
+// as far as the sync got, so that the images that were synced are reported even when the sync failed.
+func syncImages(ctx context.Context, resourcePath string, manifestPath string) ([]syncResult, error) {
+	ctx, cancel := withCommandTimeout(ctx, 30*time.Minute)
+	defer cancel()
+
+	target, err := getSyncTarget(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("get target: %w", err)
+	}
+
+	opts, err := getAutodetectOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get autodetect options: %w", err)
+	}
+
+	log.Infof("Finding images in %s ...", resourcePath)
+	sources, err := manifest.GetImagesFromKubernetesManifests(resourcePath, target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("get images: %w", err)
+	}
+
+	for s := range sources {
+		sources[s].Target = target
+	}
+
+	sources, err = filterSources(sources, nil)
+	if err != nil {
+		return nil, fmt.Errorf("filter sources: %w", err)
+	}
+
+	log.Infof("Found %v image(s)", len(sources))
+
+	client, err := newClient()
+	if err != nil {
+		return nil, fmt.Errorf("new client: %w", err)
+	}
+
+	results, err := syncSources(ctx, client, sources, viper.GetBool("dry-run"))
+	if err != nil {
+		return nil, fmt.Errorf("sync sources: %w", err)
+	}
+
+	if err := writeSyncResults(os.Stdout, results); err != nil {
+		return nil, fmt.Errorf("write sync results: %w", err)
+	}
+
+	var failed int
+	for _, result := range results {
+		if result.status == reportStatusFailed {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		log.Errorf("%v of %v image(s) failed to be synced", failed, len(results))
+		return results, getPushExitError(fmt.Errorf("%v image(s) failed to be synced", failed), failed, len(results))
+	}
+
+	log.Infof("All images have been synced!")
+
+	return results, nil
+}
+
+// getSyncTarget returns the target set by the target flag, or the target of the manifest when
+// the flag is not set, so that a directory with a manifest can be synced without any flags.
+func getSyncTarget(manifestPath string) (manifest.Target, error) {
+	if viper.GetString("target") != "" {
+		targetPath := docker.RegistryPath(viper.GetString("target"))
+		return manifest.Target{Host: targetPath.Host(getRegistryHostsOption()), Repository: targetPath.Repository(getRegistryHostsOption())}, nil
+	}
+
+	imageManifest, err := getManifest(manifestPath)
+	if err != nil {
+		return manifest.Target{}, fmt.Errorf("target must be specified when there is no manifest: %w", err)
+	}
+
+	return imageManifest.Target, nil
+}
+
+// syncSources compares each source with its target, and pushes and verifies the images that are missing at the
+// target unless this is a dry run. A result is returned for every source, in the same order as the sources, where
+// the images that failed to be synced have the error that they failed with.
+func syncSources(ctx context.Context, client docker.Client, sources []manifest.Source, dryRun bool) ([]syncResult, error) {
+	target, err := docker.NewTarget(client, "")
+	if err != nil {
+		return nil, fmt.Errorf("new target: %w", err)
+	}
+
+	results := make([]syncResult, len(sources))
+	for s, source := range sources {
+		results[s].source = source
+	}
+
+	log.Infof("Finding images that need to be pushed ...")
+
+	var missing []int
+	diff := func(i int) error {
+		started := time.Now()
+		defer func() { results[i].duration = time.Since(started) }()
+
+		source := results[i].source
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		exists, err := target.ImageExists(ctx, source.TargetImage(), targetAuth)
+		if err != nil {
+			return fmt.Errorf("image exists at target: %w", err)
+		}
+
+		if !exists {
+			results[i].status = reportStatusMissing
+			return nil
+		}
+
+		digest, err := client.GetDigest(ctx, source.TargetImage(), targetAuth)
+		if err != nil {
+			return fmt.Errorf("get digest of %s: %w", source.TargetImage(), err)
+		}
+
+		results[i].status = reportStatusExists
+		results[i].digest = digest
+		return nil
+	}
+
+	if err := runJobs(ctx, viper.GetInt("jobs"), len(sources), diff); err != nil {
+		return nil, fmt.Errorf("compare with target: %w", err)
+	}
+
+	for r := range results {
+		if results[r].status == reportStatusMissing {
+			missing = append(missing, r)
+		}
+	}
+
+	log.Infof("%v image(s) are missing at the target, %v image(s) already exist", len(missing), len(results)-len(missing))
+	if dryRun || len(missing) == 0 {
+		return results, nil
+	}
+
+	var pushed int32
+	push := func(m int) error {
+		result := &results[missing[m]]
+
+		started := time.Now()
+		result.err = syncSource(ctx, client, target, result)
+		result.duration += time.Since(started)
+		if result.err != nil {
+			result.status = reportStatusFailed
+			logImageStatus(statusFailed, "Unable to sync %s: %v", result.source.TargetImage(), result.err)
+			return result.err
+		}
+
+		logImageStatus(statusSynced, "Pushed %s (%v/%v)", result.source.TargetImage(), atomic.AddInt32(&pushed, 1), len(missing))
+		return nil
+	}
+
+	// The images that failed are recorded in their results, so the errors of the jobs are only returned
+	// when the sync was interrupted, in which case the images that were not pushed have no result.
+	if err := runJobs(ctx, viper.GetInt("jobs"), len(missing), push); err != nil && ctx.Err() != nil {
+		return nil, fmt.Errorf("push images: %w", err)
+	}
+
+	return results, nil
+}
+
+// syncSource pushes the source of the result to the target, and verifies its digest when verifying digests.
+func syncSource(ctx context.Context, client docker.Client, target docker.Target, result *syncResult) error {
+	sourceAuth, err := getSourceAuth(result.source)
+	if err != nil {
+		return fmt.Errorf("get source auth: %w", err)
+	}
+
+	targetAuth, err := getTargetAuth(result.source.Target)
+	if err != nil {
+		return fmt.Errorf("get target auth: %w", err)
+	}
+
+	log.Infof("Pushing %s", result.source.TargetImage())
+	source, sourceAuth, err := copySourceWithFallbacks(ctx, target, result.source, sourceAuth, targetAuth)
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", result.source.Image(), err)
+	}
+
+	if !viper.GetBool("verify-digests") {
+		digest, err := client.GetDigest(ctx, source.TargetImage(), targetAuth)
+		if err != nil {
+			return fmt.Errorf("get digest of %s: %w", source.TargetImage(), err)
+		}
+
+		result.status = reportStatusPushed
+		result.digest = digest
+		return nil
+	}
+
+	verification, err := client.VerifyCopy(ctx, source.PullImage(), sourceAuth, source.TargetImage(), targetAuth, false)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", source.TargetImage(), err)
+	}
+
+	result.digest = verification.TargetDigest
+	result.sourceDigest = verification.SourceDigest
+	if !verification.Verified {
+		return fmt.Errorf("digest %s of the target does not match digest %s of the source", verification.TargetDigest, verification.SourceDigest)
+	}
+
+	result.status = reportStatusVerified
+	return nil
+}
+
+// writeSyncResults writes a table of the status and digest of each image that was synced.
+func writeSyncResults(w io.Writer, results []syncResult) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(table, "SOURCE\tTARGET\tSTATUS\tDIGEST"); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for _, result := range results {
+		if _, err := fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", result.source.Image(), result.source.TargetImage(), result.status, valueOrDash(result.digest)); err != nil {
+			return fmt.Errorf("write image: %w", err)
+		}
+	}
+
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
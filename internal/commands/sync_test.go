@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/spf13/viper"
+)
+
+func TestSyncSources(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	for _, image := range []string{"org/app:1.0", "org/db:1.0", "target/org/db:1.0"} {
+		randomImage, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		reference, err := name.ParseReference(host+"/"+image, name.WeakValidation)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.Write(reference, randomImage); err != nil {
+			t.Fatal("write image:", err)
+		}
+	}
+
+	target := manifest.Target{Host: host, Repository: "target"}
+	sources := []manifest.Source{
+		{Host: host, Repository: "org/app", Tag: "1.0", Target: target},
+		{Host: host, Repository: "org/db", Tag: "1.0", Target: target},
+		{Host: host, Repository: "org/missing", Tag: "1.0", Target: target},
+	}
+
+	client, err := docker.NewClient(t.Logf)
+	if err != nil {
+		t.Fatal("new client:", err)
+	}
+	client = client.WithRetries(0)
+
+	viper.Set("verify-digests", true)
+	defer viper.Set("verify-digests", false)
+
+	results, err := syncSources(context.Background(), client, sources, false)
+	if err != nil {
+		t.Fatal("sync sources:", err)
+	}
+
+	expected := []string{reportStatusVerified, reportStatusExists, reportStatusFailed}
+	for r, result := range results {
+		if result.status != expected[r] {
+			t.Errorf("expected %s to be %s, actual %s (%v)", result.source.Image(), expected[r], result.status, result.err)
+		}
+	}
+
+	if results[0].digest == "" || results[1].digest == "" {
+		t.Errorf("expected digests of synced and existing images, actual %q and %q", results[0].digest, results[1].digest)
+	}
+
+	var output bytes.Buffer
+	if err := writeSyncResults(&output, results); err != nil {
+		t.Fatal("write sync results:", err)
+	}
+
+	if !strings.Contains(output.String(), host+"/target/org/app:1.0") || !strings.Contains(output.String(), reportStatusFailed) {
+		t.Errorf("unexpected sync results:\n%s", output.String())
+	}
+}
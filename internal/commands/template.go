@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/spf13/viper"
+)
+
+// templateImage is the data of an image that is formatted by an output template.
+type templateImage struct {
+
+	// Image is the image as it would be listed without a template.
+	Image string
+
+	// Host, Repository, Tag and Digest are the parts of the listed image. Version is the same as the Tag.
+	Host       string
+	Repository string
+	Tag        string
+	Version    string
+	Digest     string
+
+	// Source and Target are the source and target images of the manifest. They are empty when listing a cluster.
+	Source string
+	Target string
+}
+
+var templateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    strings.ReplaceAll,
+	"trimPrefix": strings.TrimPrefix,
+	"trimSuffix": strings.TrimSuffix,
+}
+
+// outputTemplate formats the listed images with a Go template.
+type outputTemplate struct {
+	template *template.Template
+
+	// perImage is true when the template is executed for each image, rather than once for all of the images.
+	perImage bool
+}
+
+// getOutputTemplate returns the output template set by the template or template-file flag, or nil when
+// the images are not formatted with a template. The template flag is executed for each image, while the
+// template file is executed once with all of the images, so that it can include a header (e.g. of a table).
+func getOutputTemplate() (*outputTemplate, error) {
+	if viper.GetString("template") != "" && viper.GetString("template-file") != "" {
+		return nil, errors.New("template and template-file cannot be used together")
+	}
+
+	if viper.GetString("template") != "" {
+		parsedTemplate, err := template.New("template").Funcs(templateFuncs).Parse(viper.GetString("template"))
+		if err != nil {
+			return nil, fmt.Errorf("parse template: %w", err)
+		}
+
+		return &outputTemplate{template: parsedTemplate, perImage: true}, nil
+	}
+
+	if viper.GetString("template-file") != "" {
+		contents, err := ioutil.ReadFile(viper.GetString("template-file"))
+		if err != nil {
+			return nil, fmt.Errorf("read template file: %w", err)
+		}
+
+		parsedTemplate, err := template.New("template-file").Funcs(templateFuncs).Parse(string(contents))
+		if err != nil {
+			return nil, fmt.Errorf("parse template file: %w", err)
+		}
+
+		return &outputTemplate{template: parsedTemplate}, nil
+	}
+
+	return nil, nil
+}
+
+// execute returns the lines of the formatted images. A template file has access to all of the images as .Images.
+func (t outputTemplate) execute(templateImages []templateImage) ([]string, error) {
+	if !t.perImage {
+		var output bytes.Buffer
+		if err := t.template.Execute(&output, struct{ Images []templateImage }{Images: templateImages}); err != nil {
+			return nil, fmt.Errorf("execute template: %w", err)
+		}
+
+		return []string{strings.TrimSuffix(output.String(), "\n")}, nil
+	}
+
+	var lines []string
+	for _, image := range templateImages {
+		var output bytes.Buffer
+		if err := t.template.Execute(&output, image); err != nil {
+			return nil, fmt.Errorf("execute template for %s: %w", image.Image, err)
+		}
+
+		lines = append(lines, output.String())
+	}
+
+	return lines, nil
+}
+
+// newTemplateImage returns the data of the listed image for an output template.
+func newTemplateImage(image string, source string, target string) (templateImage, error) {
+	parsedImage, err := images.ParseReference(image, getRegistryHostsOption())
+	if err != nil {
+		return templateImage{}, fmt.Errorf("parse image: %w", err)
+	}
+
+	data := templateImage{
+		Image:      image,
+		Host:       parsedImage.Host,
+		Repository: parsedImage.Repository,
+		Tag:        parsedImage.Tag,
+		Version:    parsedImage.Tag,
+		Digest:     parsedImage.Digest,
+		Source:     source,
+		Target:     target,
+	}
+
+	return data, nil
+}
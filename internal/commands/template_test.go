@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestOutputTemplate(t *testing.T) {
+	defer viper.Reset()
+
+	var templateImages []templateImage
+	for _, image := range []string{"quay.io/coreos/prometheus-operator:v0.40.0", "busybox:1.32.0"} {
+		data, err := newTemplateImage(image, image, "mycompany.com/"+image)
+		if err != nil {
+			t.Fatal("new template image:", err)
+		}
+
+		templateImages = append(templateImages, data)
+	}
+
+	viper.Set("template", "skopeo copy docker://{{.Source}} docker://{{.Target}} # {{.Repository}}:{{.Version}}")
+
+	listTemplate, err := getOutputTemplate()
+	if err != nil {
+		t.Fatal("get output template:", err)
+	}
+
+	actual, err := listTemplate.execute(templateImages)
+	if err != nil {
+		t.Fatal("execute:", err)
+	}
+
+	expected := []string{
+		"skopeo copy docker://quay.io/coreos/prometheus-operator:v0.40.0 docker://mycompany.com/quay.io/coreos/prometheus-operator:v0.40.0 # coreos/prometheus-operator:v0.40.0",
+		"skopeo copy docker://busybox:1.32.0 docker://mycompany.com/busybox:1.32.0 # busybox:1.32.0",
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected output %v, actual %v", expected, actual)
+	}
+
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templateFile := filepath.Join(tempDir, "images.tmpl")
+	templateContents := "| Image | Tag |\n|---|---|\n{{range .Images}}| {{.Repository}} | {{.Tag}} |\n{{end}}"
+	if err := ioutil.WriteFile(templateFile, []byte(templateContents), 0644); err != nil {
+		t.Fatal("write template file:", err)
+	}
+
+	viper.Set("template", "")
+	viper.Set("template-file", templateFile)
+
+	listTemplate, err = getOutputTemplate()
+	if err != nil {
+		t.Fatal("get output template:", err)
+	}
+
+	actual, err = listTemplate.execute(templateImages)
+	if err != nil {
+		t.Fatal("execute:", err)
+	}
+
+	expected = []string{"| Image | Tag |\n|---|---|\n| coreos/prometheus-operator | v0.40.0 |\n| busybox | 1.32.0 |"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected output %q, actual %q", expected, actual)
+	}
+}
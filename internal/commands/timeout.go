@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// withCommandTimeout returns a copy of the context that is cancelled once the timeout set with the timeout
+// flag has passed, or the default timeout of the command when the flag is not set. Commands without a default
+// timeout pass zero, in which case the context only has a timeout when the flag is set. Every request that
+// the command sends to a registry is cancelled along with the context, so a registry that stops responding
+// cannot stall the command for longer than the timeout.
+func withCommandTimeout(ctx context.Context, defaultTimeout time.Duration) (context.Context, context.CancelFunc) {
+	timeout := viper.GetDuration("timeout")
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestWithCommandTimeout(t *testing.T) {
+	ctx, cancel := withCommandTimeout(context.Background(), 0)
+	defer cancel()
+
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline without a default timeout or the timeout flag")
+	}
+
+	viper.Set("timeout", time.Minute)
+	defer viper.Set("timeout", time.Duration(0))
+
+	ctx, cancel = withCommandTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("expected the timeout flag to take precedence over the default timeout, actual deadline %v", deadline)
+	}
+}
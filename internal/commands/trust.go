@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/viper"
+)
+
+// getTrustPolicies returns the trust policies in the manifest, followed by a policy with the key or identity
+// of the verify flags (if any), which applies to every image that none of the policies of the manifest apply to.
+// Images passed in with the images flag are not in the manifest, so only the policy of the flags applies to them.
+func getTrustPolicies(manifestPath string) ([]manifest.TrustPolicy, error) {
+	var policies []manifest.TrustPolicy
+	if len(viper.GetStringSlice("images")) == 0 {
+		imageManifest, err := getManifest(manifestPath)
+		if err != nil {
+			return nil, fmt.Errorf("get manifest: %w", err)
+		}
+
+		policies = imageManifest.Trust
+	}
+
+	if viper.GetString("verify-key") != "" || viper.GetString("verify-identity") != "" {
+		policies = append(policies, manifest.TrustPolicy{
+			Name:     "flags",
+			Key:      viper.GetString("verify-key"),
+			Identity: viper.GetString("verify-identity"),
+			Issuer:   viper.GetString("verify-oidc-issuer"),
+		})
+	}
+
+	return policies, nil
+}
+
+// verifyTrust verifies the signature of the source against the trust policy that applies to it.
+// Sources that no trust policy applies to cannot be verified, so they are not trusted either.
+func verifyTrust(ctx context.Context, policies []manifest.TrustPolicy, source manifest.Source) error {
+	policy, found, err := manifest.FindTrustPolicy(policies, source)
+	if err != nil {
+		return fmt.Errorf("find trust policy: %w", err)
+	}
+
+	if !found {
+		return fmt.Errorf("no trust policy applies to %s", source.Image())
+	}
+
+	if policy.Type == manifest.TrustNotation {
+		if err := docker.VerifyNotationSignature(ctx, source.Image()); err != nil {
+			return fmt.Errorf("trust policy %s: %w", policy.Name, err)
+		}
+
+		return nil
+	}
+
+	verifyOptions := docker.VerifyOptions{
+		Key:      policy.Key,
+		Identity: policy.Identity,
+		Issuer:   policy.Issuer,
+	}
+
+	if err := docker.VerifySignature(ctx, source.Image(), verifyOptions); err != nil {
+		return fmt.Errorf("trust policy %s: %w", policy.Name, err)
+	}
+
+	return nil
+}
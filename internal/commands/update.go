@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/plexsystems/sinker/internal/manifest"
@@ -20,6 +21,98 @@ func newUpdateCommand() *cobra.Command {
 				return fmt.Errorf("bind output flag: %w", err)
 			}
 
+			if err := viper.BindPFlag("resolve-digests", cmd.Flags().Lookup("resolve-digests")); err != nil {
+				return fmt.Errorf("bind resolve-digests flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm", cmd.Flags().Lookup("helm")); err != nil {
+				return fmt.Errorf("bind helm flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm-values", cmd.Flags().Lookup("helm-values")); err != nil {
+				return fmt.Errorf("bind helm-values flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kustomize", cmd.Flags().Lookup("kustomize")); err != nil {
+				return fmt.Errorf("bind kustomize flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images", cmd.Flags().Lookup("env-images")); err != nil {
+				return fmt.Errorf("bind env-images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images-pattern", cmd.Flags().Lookup("env-images-pattern")); err != nil {
+				return fmt.Errorf("bind env-images-pattern flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("crd-config", cmd.Flags().Lookup("crd-config")); err != nil {
+				return fmt.Errorf("bind crd-config flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("deep-scan", cmd.Flags().Lookup("deep-scan")); err != nil {
+				return fmt.Errorf("bind deep-scan flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dockerfiles", cmd.Flags().Lookup("dockerfiles")); err != nil {
+				return fmt.Errorf("bind dockerfiles flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("ci-files", cmd.Flags().Lookup("ci-files")); err != nil {
+				return fmt.Errorf("bind ci-files flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("terraform", cmd.Flags().Lookup("terraform")); err != nil {
+				return fmt.Errorf("bind terraform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-sources", cmd.Flags().Lookup("follow-sources")); err != nil {
+				return fmt.Errorf("bind follow-sources flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("skip-dirs", cmd.Flags().Lookup("skip-dirs")); err != nil {
+				return fmt.Errorf("bind skip-dirs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-gitignore", cmd.Flags().Lookup("no-gitignore")); err != nil {
+				return fmt.Errorf("bind no-gitignore flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-symlinks", cmd.Flags().Lookup("follow-symlinks")); err != nil {
+				return fmt.Errorf("bind follow-symlinks flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kinds", cmd.Flags().Lookup("kinds")); err != nil {
+				return fmt.Errorf("bind kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-kinds", cmd.Flags().Lookup("exclude-kinds")); err != nil {
+				return fmt.Errorf("bind exclude-kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("selector", cmd.Flags().Lookup("selector")); err != nil {
+				return fmt.Errorf("bind selector flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-document-size", cmd.Flags().Lookup("max-document-size")); err != nil {
+				return fmt.Errorf("bind max-document-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("warn-on-parse-error", cmd.Flags().Lookup("warn-on-parse-error")); err != nil {
+				return fmt.Errorf("bind warn-on-parse-error flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("template-defaults", cmd.Flags().Lookup("template-defaults")); err != nil {
+				return fmt.Errorf("bind template-defaults flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("strict", cmd.Flags().Lookup("strict")); err != nil {
+				return fmt.Errorf("bind strict flag: %w", err)
+			}
+
 			outputPath := viper.GetString("manifest")
 			if viper.GetString("output") != "" {
 				outputPath = viper.GetString("output")
@@ -27,7 +120,7 @@ func newUpdateCommand() *cobra.Command {
 
 			sourcePath := args[0]
 			manifestPath := viper.GetString("manifest")
-			if err := runUpdateCommand(sourcePath, manifestPath, outputPath); err != nil {
+			if err := runUpdateCommand(cmd.Context(), sourcePath, manifestPath, outputPath); err != nil {
 				return fmt.Errorf("update: %w", err)
 			}
 
@@ -36,23 +129,62 @@ func newUpdateCommand() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("output", "o", "", "Path where the updated manifest file will be written to")
+	cmd.Flags().Bool("resolve-digests", false, "Record the current digest of each image in the manifest")
+	cmd.Flags().Bool("helm", false, "Render Helm charts found at the source before finding images (requires helm)")
+	cmd.Flags().StringSlice("helm-values", []string{}, "Values files to use when rendering Helm charts")
+	cmd.Flags().Bool("kustomize", false, "Build kustomizations found at the source before finding images (requires kustomize)")
+	cmd.Flags().Bool("env-images", false, "Find images in the values of container environment variables")
+	cmd.Flags().String("env-images-pattern", "IMAGE", "Regular expression that environment variable names must match to be searched for images")
+	cmd.Flags().String("crd-config", "", "Path to a file that declares where images are located in custom resources")
+	cmd.Flags().Bool("deep-scan", false, "Also find image references in any value of any resource (e.g. ConfigMap data), which may include false positives")
+	cmd.Flags().Bool("dockerfiles", false, "Also find the base images in the FROM instructions of Dockerfiles")
+	cmd.Flags().Bool("ci-files", false, "Also find the images of the jobs in GitHub Actions workflows and GitLab CI files")
+	cmd.Flags().Bool("terraform", false, "Also find the images set in Terraform files by the Kubernetes and Helm providers")
+	cmd.Flags().Bool("follow-sources", false, "Also find the images deployed by Argo CD Applications and Flux HelmReleases and Kustomizations by fetching their sources (requires git and helm)")
+	cmd.Flags().StringSlice("skip-dirs", []string{}, "Directories to skip when finding images, by name or by path relative to the source (e.g. testdata,charts/*/tests)")
+	cmd.Flags().Bool("no-gitignore", false, "Also find images in files and directories that are ignored by .gitignore files")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symbolic links to directories when finding images")
+	cmd.Flags().StringSlice("kinds", []string{}, "Only find images in the resources of the given kinds (e.g. Deployment,CronJob)")
+	cmd.Flags().StringSlice("exclude-kinds", []string{}, "Skip the resources of the given kinds when finding images (e.g. ConfigMap)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Only find images in the resources in the given namespaces")
+	cmd.Flags().StringP("selector", "l", "", "Only find images in the resources whose labels match the selector (e.g. app.kubernetes.io/part-of=platform)")
+	cmd.Flags().String("max-document-size", "16MiB", "Maximum size of a YAML document (e.g. 64MiB), larger documents are skipped (0 does not limit the size)")
+	cmd.Flags().Bool("warn-on-parse-error", true, "Warn about YAML files that cannot be parsed, which are otherwise skipped")
+	cmd.Flags().Bool("template-defaults", false, "Find the images of unrendered Helm templates on a best-effort basis with the default values of their chart")
+	cmd.Flags().Bool("strict", false, "Fail when an image does not have a tag or digest instead of using the latest tag, or when a YAML file cannot be parsed")
 
 	return &cmd
 }
 
-func runUpdateCommand(path string, manifestPath string, outputPath string) error {
-	currentManifest, err := manifest.Get(manifestPath)
+func runUpdateCommand(ctx context.Context, path string, manifestPath string, outputPath string) error {
+	currentManifest, err := getManifest(manifestPath)
 	if err != nil {
 		return fmt.Errorf("get current manifest: %w", err)
 	}
 
-	imageManifest, err := manifest.NewWithAutodetect(currentManifest.Target.Host, currentManifest.Target.Repository, path)
+	// The manifest is written back with the references to variables that the current manifest
+	// has (e.g. a target of ${MIRROR_REGISTRY}), rather than the values of the variables.
+	templateManifest, err := manifest.GetTemplate(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get current manifest template: %w", err)
+	}
+
+	opts, err := getAutodetectOptions(ctx)
+	if err != nil {
+		return fmt.Errorf("get autodetect options: %w", err)
+	}
+
+	imageManifest, err := manifest.NewWithAutodetect(currentManifest.Target.Host, currentManifest.Target.Repository, path, opts...)
 	if err != nil {
 		return fmt.Errorf("get new manifest: %w", err)
 	}
 
+	imageManifest.Target = templateManifest.Target
+	imageManifest.Mappings = templateManifest.Mappings
+	imageManifest.Ignore = templateManifest.Ignore
+
 	for s := range imageManifest.Sources {
-		for _, currentSource := range currentManifest.Sources {
+		for c, currentSource := range currentManifest.Sources {
 			if currentSource.Host != imageManifest.Sources[s].Host {
 				continue
 			}
@@ -61,19 +193,41 @@ func runUpdateCommand(path string, manifestPath string, outputPath string) error
 				continue
 			}
 
-			// If the target host (or repository) of the source does not match the manifest
-			// target host (or repository), it has been modified by the user.
+			// If the target host, repository or auth of the source does not match the manifest
+			// target, it has been modified by the user.
 			//
-			// To preserve the current settings, set the manifest host and repository values
+			// To preserve the current settings, set the manifest host, repository and auth values
 			// to the ones present in the current manifest.
 			if currentSource.Target.Host != currentManifest.Target.Host {
-				imageManifest.Sources[s].Target.Host = currentSource.Target.Host
+				imageManifest.Sources[s].Target.Host = templateManifest.Sources[c].Target.Host
 			}
 			if currentSource.Target.Repository != currentManifest.Target.Repository {
-				imageManifest.Sources[s].Target.Repository = currentSource.Target.Repository
+				imageManifest.Sources[s].Target.Repository = templateManifest.Sources[c].Target.Repository
 			}
+			if currentSource.Target.Auth != currentManifest.Target.Auth {
+				imageManifest.Sources[s].Target.Auth = templateManifest.Sources[c].Target.Auth
+			}
+
+			// A digest that was previously pinned remains valid as long as the tag has not changed.
+			if currentSource.Tag == imageManifest.Sources[s].Tag && imageManifest.Sources[s].Digest == "" {
+				imageManifest.Sources[s].Digest = currentSource.Digest
+			}
+
+			imageManifest.Sources[s].Auth = templateManifest.Sources[c].Auth
+		}
+	}
+
+	if viper.GetBool("resolve-digests") {
+		if err := pinSourceDigests(ctx, imageManifest.Sources); err != nil {
+			return fmt.Errorf("pin source digests: %w", err)
+		}
+	}
 
-			imageManifest.Sources[s].Auth = currentSource.Auth
+	// Sources that select tags with a version constraint or pattern, as well as Helm charts and other
+	// artifacts, are not found in the resources, so they are kept as they are in the current manifest.
+	for _, templateSource := range templateManifest.Sources {
+		if templateSource.HasTagSelector() || templateSource.IsArtifact() {
+			imageManifest.Sources = append(imageManifest.Sources, templateSource)
 		}
 	}
 
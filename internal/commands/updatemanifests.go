@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newUpdateManifestsCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "update-manifests <path>",
+		Short: "Update the images in Kubernetes manifests to refer to the target registry",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("output", cmd.Flags().Lookup("output")); err != nil {
+				return fmt.Errorf("bind output flag: %w", err)
+			}
+
+			if err := runUpdateManifestsCommand(args[0], viper.GetString("output")); err != nil {
+				return fmt.Errorf("update manifests: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Directory where the updated Kubernetes manifests will be written to (defaults to updating them in place)")
+
+	return &cmd
+}
+
+func runUpdateManifestsCommand(path string, outputPath string) error {
+	imageManifest, err := getManifest(viper.GetString("manifest"))
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	rewrittenFiles, err := manifest.RewriteImages(path, outputPath, imageManifest)
+	if err != nil {
+		return fmt.Errorf("rewrite images: %w", err)
+	}
+
+	for _, rewrittenFile := range rewrittenFiles {
+		log.Infof("Updated %s", rewrittenFile)
+	}
+
+	return nil
+}
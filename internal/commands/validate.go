@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newValidateCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "validate",
+		Short: "Validate the image manifest against the schema of the manifest",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("print-schema", cmd.Flags().Lookup("print-schema")); err != nil {
+				return fmt.Errorf("bind print-schema flag: %w", err)
+			}
+
+			if viper.GetBool("print-schema") {
+				if _, err := io.WriteString(os.Stdout, manifest.Schema); err != nil {
+					return fmt.Errorf("write schema: %w", err)
+				}
+
+				return nil
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runValidateCommand(manifestPath); err != nil {
+				return fmt.Errorf("validate: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("print-schema", false, "Print the JSON schema of the manifest instead of validating the manifest")
+
+	return &cmd
+}
+
+func runValidateCommand(manifestPath string) error {
+	location := manifest.Location(manifestPath)
+	contents, err := ioutil.ReadFile(location)
+	if err != nil {
+		return fmt.Errorf("read manifest: %w", err)
+	}
+
+	variables, err := getManifestVariables()
+	if err != nil {
+		return fmt.Errorf("get manifest variables: %w", err)
+	}
+
+	contents, err = manifest.ExpandVariables(contents, variables)
+	if err != nil {
+		return fmt.Errorf("expand variables: %w", err)
+	}
+
+	problems, err := validateManifest(contents)
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintf(os.Stdout, "%s: %s\n", location, problem)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %v problem(s) in the manifest", len(problems))
+	}
+
+	log.Infof("Manifest %s is valid (schema %s)", location, manifest.SchemaVersion)
+
+	return nil
+}
+
+// validateManifest returns the problems found in the contents of the manifest. The manifest is only
+// checked for the problems that span more than one field (e.g. two sources that are flattened to the same
+// repository) once it follows the schema, as the errors of the schema are more specific.
+func validateManifest(contents []byte) ([]string, error) {
+	schemaErrors, err := manifest.ValidateSchema(contents)
+	if err != nil {
+		return nil, fmt.Errorf("validate schema: %w", err)
+	}
+
+	var problems []string
+	for _, schemaError := range schemaErrors {
+		problems = append(problems, schemaError.Error())
+	}
+
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	if _, err := manifest.Parse(contents); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems, nil
+}
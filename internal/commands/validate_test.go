@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestValidateManifest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		expected int
+	}{
+		{
+			name: "valid",
+			contents: `
+target:
+  host: mycompany.com
+sources:
+- repository: busybox
+  tag: "1.32"
+`,
+			expected: 0,
+		},
+		{
+			name: "schema",
+			contents: `
+target:
+  host: mycompany.com
+sources:
+- repository: busybox
+  tagg: "1.32"
+`,
+			expected: 1,
+		},
+		{
+			name: "flattened to the same repository",
+			contents: `
+target:
+  host: mycompany.com
+  flatten:
+    strategy: last
+sources:
+- repository: foo/busybox
+  tag: "1.32"
+- repository: bar/busybox
+  tag: "1.32"
+`,
+			expected: 1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		problems, err := validateManifest([]byte(testCase.contents))
+		if err != nil {
+			t.Fatal("validate manifest:", err)
+		}
+
+		if len(problems) != testCase.expected {
+			t.Errorf("%s: expected %v problem(s), actual %q", testCase.name, testCase.expected, problems)
+		}
+	}
+}
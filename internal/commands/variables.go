@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	"github.com/spf13/viper"
+)
+
+// getManifest returns the manifest at the path, with the references to variables replaced with the
+// values in the values files set by the manifest-values flag (or the environment variables). When
+// a variable is set in more than one values file, the value in the last file is used.
+func getManifest(path string) (manifest.Manifest, error) {
+	variables, err := getManifestVariables()
+	if err != nil {
+		return manifest.Manifest{}, err
+	}
+
+	imageManifest, err := manifest.GetWithVariables(path, variables)
+	if err != nil {
+		return manifest.Manifest{}, err
+	}
+
+	return imageManifest, nil
+}
+
+// getManifestVariables returns the variables in the values files set by the manifest-values flag.
+func getManifestVariables() (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, valuesPath := range viper.GetStringSlice("manifest-values") {
+		values, err := manifest.ReadVariables(valuesPath)
+		if err != nil {
+			return nil, fmt.Errorf("read values %s: %w", valuesPath, err)
+		}
+
+		for name, value := range values {
+			variables[name] = value
+		}
+	}
+
+	return variables, nil
+}
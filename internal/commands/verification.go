@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/plexsystems/sinker/internal/docker"
+)
+
+// verificationReport records the verifications of the images that were pushed,
+// so that the digests of the mirrored images can be audited after the push.
+type verificationReport struct {
+	mutex         sync.Mutex
+	verifications []docker.Verification
+}
+
+func (r *verificationReport) add(verification docker.Verification) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.verifications = append(r.verifications, verification)
+}
+
+// write writes the report to the path as JSON. When a key is given, the report is signed with the key and
+// the signature is written next to the report (e.g. report.json.sig), so that the report cannot be altered.
+func (r *verificationReport) write(ctx context.Context, path string, key string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	sort.Slice(r.verifications, func(i, j int) bool {
+		return r.verifications[i].Target < r.verifications[j].Target
+	})
+
+	report := struct {
+		Verifications []docker.Verification `json:"verifications"`
+	}{
+		Verifications: r.verifications,
+	}
+
+	contents, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+
+	if err := ioutil.WriteFile(path, append(contents, '\n'), os.ModePerm); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	if key == "" {
+		return nil
+	}
+
+	if err := docker.SignBlob(ctx, path, key, path+".sig"); err != nil {
+		return fmt.Errorf("sign report: %w", err)
+	}
+
+	return nil
+}
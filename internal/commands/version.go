@@ -0,0 +1,42 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// The version information is set when building a release, for example:
+//
+//	go build -ldflags "-X github.com/plexsystems/sinker/internal/commands.buildVersion=0.10.1"
+var (
+	buildVersion = "0.10.1"
+	buildCommit  = "unknown"
+	buildDate    = "unknown"
+)
+
+func newVersionCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "version",
+		Short: "Print the version, commit and build date of sinker",
+		Args:  cobra.NoArgs,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := writeVersion(os.Stdout); err != nil {
+				return fmt.Errorf("write version: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	return &cmd
+}
+
+func writeVersion(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "sinker version %s\ncommit: %s\nbuilt: %s\ngo: %s %s/%s\n", buildVersion, buildCommit, buildDate, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return err
+}
@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteVersion(t *testing.T) {
+	var output bytes.Buffer
+	if err := writeVersion(&output); err != nil {
+		t.Fatal("write version:", err)
+	}
+
+	expected := "sinker version " + buildVersion + "\ncommit: " + buildCommit + "\nbuilt: " + buildDate + "\n"
+	if !strings.HasPrefix(output.String(), expected) {
+		t.Errorf("expected version to start with %q, actual %q", expected, output.String())
+	}
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// watchDebounce is how long to wait for changes to settle before running again,
+// so that a commit or checkout that changes many files results in a single run.
+const watchDebounce = 2 * time.Second
+
+// watch runs the command once, and then again each time a YAML file in the
+// directory of the given path (or any of its subdirectories) changes.
+// Failures are logged rather than returned so that watching continues until the context is cancelled.
+func watch(ctx context.Context, path string, run func() error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("new watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	watchPath := path
+	if fileInfo, err := os.Stat(path); err == nil && !fileInfo.IsDir() {
+		watchPath = filepath.Dir(path)
+	}
+
+	if err := addWatchDirs(watcher, watchPath); err != nil {
+		return fmt.Errorf("add watch dirs: %w", err)
+	}
+
+	runAndLog := func() {
+		if err := run(); err != nil {
+			log.Errorf("Run failed: %v", err)
+		}
+
+		log.Infof("Watching %s for changes ...", watchPath)
+	}
+
+	runAndLog()
+
+	changes := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					close(changes)
+					return
+				}
+
+				if event.Op&fsnotify.Create == fsnotify.Create {
+					if fileInfo, err := os.Stat(event.Name); err == nil && fileInfo.IsDir() {
+						if err := addWatchDirs(watcher, event.Name); err != nil {
+							log.Errorf("Unable to watch %s: %v", event.Name, err)
+						}
+					}
+				}
+
+				if isYamlFile(event.Name) {
+					changes <- struct{}{}
+				}
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				log.Errorf("Watch error: %v", err)
+			}
+		}
+	}()
+
+	debounce(changes, ctx.Done(), watchDebounce, runAndLog)
+
+	return nil
+}
+
+// debounce calls run once no change has been received for the given delay.
+// It returns when the changes channel is closed or the done channel is closed.
+func debounce(changes <-chan struct{}, done <-chan struct{}, delay time.Duration, run func()) {
+	timer := time.NewTimer(delay)
+	timer.Stop()
+
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			timer.Reset(delay)
+
+		case <-timer.C:
+			run()
+
+		case <-done:
+			return
+		}
+	}
+}
+
+func addWatchDirs(watcher *fsnotify.Watcher, path string) error {
+	err := filepath.Walk(path, func(currentPath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if !fileInfo.IsDir() {
+			return nil
+		}
+
+		if fileInfo.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if err := watcher.Add(currentPath); err != nil {
+			return fmt.Errorf("add %s: %w", currentPath, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getWatchPath returns the path to watch for the manifest at the given path.
+// When no manifest is given, the manifest is in the current directory.
+func getWatchPath(manifestPath string) string {
+	if manifestPath == "" {
+		return "."
+	}
+
+	return manifestPath
+}
+
+func isYamlFile(path string) bool {
+	return filepath.Ext(path) == ".yaml" || filepath.Ext(path) == ".yml"
+}
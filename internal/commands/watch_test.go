@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDebounce(t *testing.T) {
+	changes := make(chan struct{})
+	stop := make(chan struct{})
+
+	var runs int
+	done := make(chan struct{})
+	go func() {
+		debounce(changes, stop, 50*time.Millisecond, func() { runs++ })
+		close(done)
+	}()
+
+	for i := 0; i < 5; i++ {
+		changes <- struct{}{}
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	close(changes)
+	<-done
+
+	if runs != 1 {
+		t.Errorf("expected changes to be debounced into 1 run, actual %v", runs)
+	}
+}
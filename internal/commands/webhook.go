@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/internal/webhook"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newWebhookCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "webhook",
+		Short: "Run a mutating admission webhook that rewrites the images of pods to their target images",
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("address", cmd.Flags().Lookup("address")); err != nil {
+				return fmt.Errorf("bind address flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("tls-cert", cmd.Flags().Lookup("tls-cert")); err != nil {
+				return fmt.Errorf("bind tls-cert flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("tls-key", cmd.Flags().Lookup("tls-key")); err != nil {
+				return fmt.Errorf("bind tls-key flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("sync", cmd.Flags().Lookup("sync")); err != nil {
+				return fmt.Errorf("bind sync flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("metrics-address", cmd.Flags().Lookup("metrics-address")); err != nil {
+				return fmt.Errorf("bind metrics-address flag: %w", err)
+			}
+
+			if viper.GetString("tls-cert") == "" || viper.GetString("tls-key") == "" {
+				return errors.New("tls-cert and tls-key are required")
+			}
+
+			manifestPath := viper.GetString("manifest")
+			if err := runWebhookCommand(cmd.Context(), manifestPath); err != nil {
+				return fmt.Errorf("webhook: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("address", ":8443", "Address to listen on for admission requests")
+	cmd.Flags().String("tls-cert", "", "Path to the TLS certificate to serve admission requests with")
+	cmd.Flags().String("tls-key", "", "Path to the TLS key to serve admission requests with")
+	cmd.Flags().Bool("sync", false, "Push the images that do not exist at the target registry yet when a pod is admitted, so that the pods admitted after the push are rewritten")
+	cmd.Flags().String("metrics-address", "", "Address to serve Prometheus metrics on (e.g. :9090)")
+
+	return &cmd
+}
+
+func runWebhookCommand(ctx context.Context, manifestPath string) error {
+	imageManifest, err := getManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("get manifest: %w", err)
+	}
+
+	serveMetrics(viper.GetString("metrics-address"))
+
+	client, err := newClient()
+	if err != nil {
+		return fmt.Errorf("new client: %w", err)
+	}
+
+	target, err := docker.NewTarget(client, "")
+	if err != nil {
+		return fmt.Errorf("new target: %w", err)
+	}
+
+	// The images are synced the same way that they are pushed, pulling them through the
+	// registry mirror of their source or from their fallbacks.
+	copySourceToTarget := func(ctx context.Context, source manifest.Source) error {
+		sourceAuth, err := getSourceAuth(source)
+		if err != nil {
+			return fmt.Errorf("get source auth: %w", err)
+		}
+
+		targetAuth, err := getTargetAuth(source.Target)
+		if err != nil {
+			return fmt.Errorf("get target auth: %w", err)
+		}
+
+		if _, _, err := copySourceWithFallbacks(ctx, target, source, sourceAuth, targetAuth); err != nil {
+			return fmt.Errorf("copy %s: %w", source.Image(), err)
+		}
+
+		return nil
+	}
+
+	config := webhook.Config{
+		Manifest:   imageManifest,
+		Sync:       viper.GetBool("sync"),
+		CopySource: copySourceToTarget,
+		TargetAuth: getTargetAuth,
+		Logger:     log.Infof,
+	}
+
+	// The images that are synced in the background are cancelled along with the command.
+	hook := webhook.New(ctx, client, config)
+
+	mux := http.NewServeMux()
+	mux.Handle("/mutate", hook)
+
+	server := http.Server{
+		Addr:    viper.GetString("address"),
+		Handler: mux,
+	}
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("Unable to shut down: %v", err)
+		}
+
+		if err := hook.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("Unable to stop syncing images: %v", err)
+		}
+	}()
+
+	log.Infof("Listening for admission requests on %s ...", server.Addr)
+	if err := server.ListenAndServeTLS(viper.GetString("tls-cert"), viper.GetString("tls-key")); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("listen and serve: %w", err)
+	}
+
+	<-shutdown
+
+	return nil
+}
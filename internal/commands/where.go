@@ -0,0 +1,307 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/plexsystems/sinker/pkg/images"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func newWhereCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "where <image> [<source>...]",
+		Short: "List the resources at the sources that reference an image or repository",
+		Args:  cobra.MinimumNArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := viper.BindPFlag("helm", cmd.Flags().Lookup("helm")); err != nil {
+				return fmt.Errorf("bind helm flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("helm-values", cmd.Flags().Lookup("helm-values")); err != nil {
+				return fmt.Errorf("bind helm-values flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kustomize", cmd.Flags().Lookup("kustomize")); err != nil {
+				return fmt.Errorf("bind kustomize flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images", cmd.Flags().Lookup("env-images")); err != nil {
+				return fmt.Errorf("bind env-images flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("env-images-pattern", cmd.Flags().Lookup("env-images-pattern")); err != nil {
+				return fmt.Errorf("bind env-images-pattern flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("crd-config", cmd.Flags().Lookup("crd-config")); err != nil {
+				return fmt.Errorf("bind crd-config flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("deep-scan", cmd.Flags().Lookup("deep-scan")); err != nil {
+				return fmt.Errorf("bind deep-scan flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("dockerfiles", cmd.Flags().Lookup("dockerfiles")); err != nil {
+				return fmt.Errorf("bind dockerfiles flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("ci-files", cmd.Flags().Lookup("ci-files")); err != nil {
+				return fmt.Errorf("bind ci-files flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("terraform", cmd.Flags().Lookup("terraform")); err != nil {
+				return fmt.Errorf("bind terraform flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-sources", cmd.Flags().Lookup("follow-sources")); err != nil {
+				return fmt.Errorf("bind follow-sources flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("skip-dirs", cmd.Flags().Lookup("skip-dirs")); err != nil {
+				return fmt.Errorf("bind skip-dirs flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("no-gitignore", cmd.Flags().Lookup("no-gitignore")); err != nil {
+				return fmt.Errorf("bind no-gitignore flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("follow-symlinks", cmd.Flags().Lookup("follow-symlinks")); err != nil {
+				return fmt.Errorf("bind follow-symlinks flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("kinds", cmd.Flags().Lookup("kinds")); err != nil {
+				return fmt.Errorf("bind kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("exclude-kinds", cmd.Flags().Lookup("exclude-kinds")); err != nil {
+				return fmt.Errorf("bind exclude-kinds flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("namespaces", cmd.Flags().Lookup("namespaces")); err != nil {
+				return fmt.Errorf("bind namespaces flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("selector", cmd.Flags().Lookup("selector")); err != nil {
+				return fmt.Errorf("bind selector flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("max-document-size", cmd.Flags().Lookup("max-document-size")); err != nil {
+				return fmt.Errorf("bind max-document-size flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("warn-on-parse-error", cmd.Flags().Lookup("warn-on-parse-error")); err != nil {
+				return fmt.Errorf("bind warn-on-parse-error flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("template-defaults", cmd.Flags().Lookup("template-defaults")); err != nil {
+				return fmt.Errorf("bind template-defaults flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("index", cmd.Flags().Lookup("index")); err != nil {
+				return fmt.Errorf("bind index flag: %w", err)
+			}
+
+			if err := viper.BindPFlag("format", cmd.Flags().Lookup("format")); err != nil {
+				return fmt.Errorf("bind format flag: %w", err)
+			}
+
+			if viper.GetString("index") == "" && len(args) < 2 {
+				return errors.New("at least one source must be specified when not using the index flag")
+			}
+
+			if viper.GetString("index") != "" && len(args) > 1 {
+				return errors.New("sources cannot be specified when using the index flag")
+			}
+
+			if err := runWhereCommand(cmd.Context(), args[0], args[1:]); err != nil {
+				return fmt.Errorf("where: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("index", "", "Path to the images found by an earlier find --format json to search instead of the sources, or - for stdin")
+	cmd.Flags().String("format", "text", "Format to output the images in (text or json)")
+	cmd.Flags().Bool("helm", false, "Render Helm charts found at the source before finding images (requires helm)")
+	cmd.Flags().StringSlice("helm-values", []string{}, "Values files to use when rendering Helm charts")
+	cmd.Flags().Bool("kustomize", false, "Build kustomizations found at the source before finding images (requires kustomize)")
+	cmd.Flags().Bool("env-images", false, "Find images in the values of container environment variables")
+	cmd.Flags().String("env-images-pattern", "IMAGE", "Regular expression that environment variable names must match to be searched for images")
+	cmd.Flags().String("crd-config", "", "Path to a file that declares where images are located in custom resources")
+	cmd.Flags().Bool("deep-scan", false, "Also find image references in any value of any resource (e.g. ConfigMap data), which may include false positives")
+	cmd.Flags().Bool("dockerfiles", false, "Also find the base images in the FROM instructions of Dockerfiles")
+	cmd.Flags().Bool("ci-files", false, "Also find the images of the jobs in GitHub Actions workflows and GitLab CI files")
+	cmd.Flags().Bool("terraform", false, "Also find the images set in Terraform files by the Kubernetes and Helm providers")
+	cmd.Flags().Bool("follow-sources", false, "Also find the images deployed by Argo CD Applications and Flux HelmReleases and Kustomizations by fetching their sources (requires git and helm)")
+	cmd.Flags().StringSlice("skip-dirs", []string{}, "Directories to skip when finding images, by name or by path relative to the source (e.g. testdata,charts/*/tests)")
+	cmd.Flags().Bool("no-gitignore", false, "Also find images in files and directories that are ignored by .gitignore files")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symbolic links to directories when finding images")
+	cmd.Flags().StringSlice("kinds", []string{}, "Only find images in the resources of the given kinds (e.g. Deployment,CronJob)")
+	cmd.Flags().StringSlice("exclude-kinds", []string{}, "Skip the resources of the given kinds when finding images (e.g. ConfigMap)")
+	cmd.Flags().StringSlice("namespaces", []string{}, "Only find images in the resources in the given namespaces")
+	cmd.Flags().StringP("selector", "l", "", "Only find images in the resources whose labels match the selector (e.g. app.kubernetes.io/part-of=platform)")
+	cmd.Flags().String("max-document-size", "16MiB", "Maximum size of a YAML document (e.g. 64MiB), larger documents are skipped (0 does not limit the size)")
+	cmd.Flags().Bool("warn-on-parse-error", true, "Warn about YAML files that cannot be parsed, which are otherwise skipped")
+	cmd.Flags().Bool("template-defaults", false, "Find the images of unrendered Helm templates on a best-effort basis with the default values of their chart")
+
+	return &cmd
+}
+
+func runWhereCommand(ctx context.Context, query string, paths []string) error {
+	format := viper.GetString("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("unknown format %s", format)
+	}
+
+	queryImage, err := images.ParseReference(query, getRegistryHostsOption())
+	if err != nil {
+		return fmt.Errorf("parse image: %w", err)
+	}
+
+	var foundImages []images.Image
+	if viper.GetString("index") != "" {
+		foundImages, err = readImageIndex(viper.GetString("index"))
+		if err != nil {
+			return fmt.Errorf("read image index: %w", err)
+		}
+	} else {
+		opts, err := getAutodetectOptions(ctx)
+		if err != nil {
+			return fmt.Errorf("get autodetect options: %w", err)
+		}
+
+		foundImages, err = images.FindImagesInPaths(paths, opts...)
+		if err != nil {
+			return fmt.Errorf("find images: %w", err)
+		}
+	}
+
+	matchingImages := filterReferencingImages(foundImages, queryImage)
+	if len(matchingImages) == 0 {
+		log.Infof("No resources reference %s", query)
+		return nil
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, image := range matchingImages {
+			if err := encoder.Encode(image); err != nil {
+				return fmt.Errorf("encode image: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	if err := writeReferences(os.Stdout, matchingImages); err != nil {
+		return fmt.Errorf("write references: %w", err)
+	}
+
+	return nil
+}
+
+// readImageIndex reads the images written by the find command with the json format, one image per line,
+// from the file at the path, or from stdin when the path is -.
+func readImageIndex(path string) ([]images.Image, error) {
+	reader := io.Reader(os.Stdin)
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open: %w", err)
+		}
+		defer file.Close()
+
+		reader = file
+	}
+
+	var indexedImages []images.Image
+	decoder := json.NewDecoder(reader)
+	for {
+		var image images.Image
+		if err := decoder.Decode(&image); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decode image %v: %w", len(indexedImages)+1, err)
+		}
+
+		indexedImages = append(indexedImages, image)
+	}
+
+	return indexedImages, nil
+}
+
+// filterReferencingImages returns the images that are the queried image. An image without a tag or digest in the
+// query (e.g. nginx) matches every tag and digest of its repository, and references to the same repository that
+// are written in different ways (e.g. nginx and docker.io/library/nginx) match each other.
+func filterReferencingImages(foundImages []images.Image, query images.Image) []images.Image {
+	// The tag of a query without a tag or digest defaults to latest.
+	hasTag := query.Tag != "" && (query.Tag != "latest" || query.Digest != "" || strings.HasSuffix(query.Reference, ":latest"))
+
+	var matchingImages []images.Image
+	for _, image := range foundImages {
+		if getRepositoryKey(image) != getRepositoryKey(query) {
+			continue
+		}
+
+		if query.Digest != "" && image.Digest != query.Digest {
+			continue
+		}
+
+		if hasTag && image.Tag != query.Tag {
+			continue
+		}
+
+		matchingImages = append(matchingImages, image)
+	}
+
+	return matchingImages
+}
+
+// getRepositoryKey returns the normalized repository of the image, including its host.
+func getRepositoryKey(image images.Image) string {
+	repository := image.Repository
+	if image.Host != "" {
+		repository = image.Host + "/" + repository
+	}
+
+	normalized, err := images.NormalizeReference(repository, getRegistryHostsOption())
+	if err != nil {
+		return strings.ToLower(repository)
+	}
+
+	return strings.ToLower(strings.TrimSuffix(normalized, ":latest"))
+}
+
+// writeReferences writes the location of each resource that references the images, followed by the image
+// as it is referenced, the resource and its container (e.g. app.yaml:12: nginx:1.19 (Deployment/web, container nginx)).
+func writeReferences(w io.Writer, matchingImages []images.Image) error {
+	for _, image := range matchingImages {
+		for _, resource := range image.Resources {
+			description := resource.Kind + "/" + resource.Name
+			if resource.Namespace != "" {
+				description += ", namespace " + resource.Namespace
+			}
+
+			if resource.Container != "" {
+				description += ", container " + resource.Container
+			}
+
+			if _, err := fmt.Fprintf(w, "%s: %s (%s)\n", resource.Location(), image.Reference, description); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+func TestFilterReferencingImages(t *testing.T) {
+	var foundImages []images.Image
+	for _, reference := range []string{"nginx:1.19", "docker.io/library/nginx:1.21", "nginx", "quay.io/nginx/nginx:1.19", "mycompany.com/nginx:1.19"} {
+		image, err := images.ParseReference(reference)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		foundImages = append(foundImages, image)
+	}
+
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "nginx:1.19", expected: []string{"nginx:1.19"}},
+		{query: "docker.io/library/nginx:1.19", expected: []string{"nginx:1.19"}},
+		{query: "nginx", expected: []string{"nginx:1.19", "docker.io/library/nginx:1.21", "nginx"}},
+		{query: "nginx:latest", expected: []string{"nginx"}},
+		{query: "quay.io/nginx/nginx", expected: []string{"quay.io/nginx/nginx:1.19"}},
+		{query: "busybox", expected: nil},
+	}
+
+	for _, testCase := range testCases {
+		query, err := images.ParseReference(testCase.query)
+		if err != nil {
+			t.Fatal("parse query:", err)
+		}
+
+		var actual []string
+		for _, image := range filterReferencingImages(foundImages, query) {
+			actual = append(actual, image.Reference)
+		}
+
+		if len(actual) != len(testCase.expected) {
+			t.Errorf("expected %v to match %v, actual %v", testCase.query, testCase.expected, actual)
+			continue
+		}
+
+		for i := range actual {
+			if actual[i] != testCase.expected[i] {
+				t.Errorf("expected %v to match %v, actual %v", testCase.query, testCase.expected, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestWriteReferences(t *testing.T) {
+	matchingImages := []images.Image{
+		{
+			Reference: "nginx:1.19",
+			Resources: []images.Resource{
+				{Path: "apps/web.yaml", Kind: "Deployment", Name: "web", Namespace: "frontend", Container: "nginx", Line: 21},
+				{Path: "charts/proxy", Kind: "DaemonSet", Name: "proxy"},
+			},
+		},
+	}
+
+	var output bytes.Buffer
+	if err := writeReferences(&output, matchingImages); err != nil {
+		t.Fatal("write references:", err)
+	}
+
+	expected := "apps/web.yaml:21: nginx:1.19 (Deployment/web, namespace frontend, container nginx)\n" +
+		"charts/proxy: nginx:1.19 (DaemonSet/proxy)\n"
+
+	if output.String() != expected {
+		t.Errorf("expected output %q, actual %q", expected, output.String())
+	}
+}
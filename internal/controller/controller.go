@@ -0,0 +1,222 @@
+// Package controller continuously mirrors the images of ImageSync resources to their target registries.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/internal/metrics"
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Config configures the controller.
+type Config struct {
+
+	// Namespace is the namespace to watch for ImageSyncs. When empty, all namespaces are watched.
+	Namespace string
+
+	// Interval is how often every ImageSync is reconciled.
+	Interval time.Duration
+
+	// SourceAuth returns the encoded auth of a source registry.
+	SourceAuth func(source manifest.Source) (string, error)
+
+	// TargetAuth returns the encoded auth of a target registry.
+	TargetAuth func(target manifest.Target) (string, error)
+
+	// Logger logs the progress of the controller.
+	Logger func(format string, args ...interface{})
+}
+
+// Controller reconciles ImageSync resources by mirroring their images to the target registry.
+type Controller struct {
+	kube   kubeClient
+	client docker.Client
+	config Config
+}
+
+// New returns a controller that connects to the cluster it is running in.
+func New(client docker.Client, config Config) (Controller, error) {
+	kube, err := newInClusterClient()
+	if err != nil {
+		return Controller{}, fmt.Errorf("new in cluster client: %w", err)
+	}
+
+	controller := Controller{
+		kube:   kube,
+		client: client,
+		config: config,
+	}
+
+	return controller, nil
+}
+
+// Run reconciles every ImageSync at each interval until the context is cancelled.
+func (c Controller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.reconcileAll(ctx); err != nil {
+			c.config.Logger("Unable to reconcile: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c Controller) reconcileAll(ctx context.Context) error {
+	imageSyncs, err := c.kube.listImageSyncs(ctx, c.config.Namespace)
+	if err != nil {
+		return fmt.Errorf("list image syncs: %w", err)
+	}
+
+	// An ImageSync whose status cannot be updated (e.g. because it was deleted while it was
+	// reconciled) does not stop the remaining ImageSyncs from being reconciled.
+	var missing int
+	var failedUpdates int
+	for _, imageSync := range imageSyncs {
+		imageSync.Status = c.reconcile(ctx, imageSync)
+		missing += imageSync.Status.Failed
+
+		if err := c.kube.updateStatus(ctx, imageSync); err != nil {
+			c.config.Logger("Unable to update the status of %s/%s: %v", imageSync.Namespace, imageSync.Name, err)
+			failedUpdates++
+		}
+	}
+
+	metrics.ImagesMissing.Set(float64(missing))
+
+	if failedUpdates > 0 {
+		return fmt.Errorf("unable to update the status of %d of %d image syncs", failedUpdates, len(imageSyncs))
+	}
+
+	return nil
+}
+
+// reconcile mirrors the images of the ImageSync that do not exist at the target yet.
+func (c Controller) reconcile(ctx context.Context, imageSync ImageSync) ImageSyncStatus {
+	status := ImageSyncStatus{
+		ObservedGeneration: imageSync.Generation,
+		LastSyncTime:       metav1.Now(),
+	}
+
+	sources, err := manifest.GetSourcesFromImages(imageSync.Spec.Images, imageSync.Spec.Target)
+	if err != nil {
+		status.Failed = len(imageSync.Spec.Images)
+		status.Message = fmt.Sprintf("get sources: %v", err)
+		return status
+	}
+
+	metrics.ImagesDiscovered.Add(uint64(len(sources)))
+
+	dockerConfigs, err := c.getDockerConfigs(ctx, imageSync)
+	if err != nil {
+		status.Failed = len(imageSync.Spec.Images)
+		status.Message = fmt.Sprintf("get secrets: %v", err)
+		return status
+	}
+
+	var failures []string
+	for _, source := range sources {
+		if err := c.sync(ctx, source, dockerConfigs); err != nil {
+			c.config.Logger("Unable to sync %s: %v", source.Image(), err)
+			failures = append(failures, fmt.Sprintf("%s: %v", source.Image(), err))
+			continue
+		}
+
+		status.Synced++
+	}
+
+	status.Failed = len(failures)
+	status.Message = strings.Join(failures, "; ")
+
+	return status
+}
+
+// getDockerConfigs returns the Docker configs of the secrets of the ImageSync.
+func (c Controller) getDockerConfigs(ctx context.Context, imageSync ImageSync) ([][]byte, error) {
+	var dockerConfigs [][]byte
+	for _, secret := range imageSync.Spec.Secrets {
+		contents, err := c.kube.getSecret(ctx, imageSync.Namespace, secret)
+		if err != nil {
+			return nil, fmt.Errorf("get secret %s: %w", secret, err)
+		}
+
+		dockerConfig, err := images.ParsePullSecret(contents)
+		if err != nil {
+			return nil, fmt.Errorf("parse secret %s: %w", secret, err)
+		}
+
+		dockerConfigs = append(dockerConfigs, dockerConfig)
+	}
+
+	return dockerConfigs, nil
+}
+
+// getAuth returns the encoded auth for the host from the first Docker config that has credentials for it,
+// or the auth returned by the default auth function of the config when none of them do.
+func getAuth(dockerConfigs [][]byte, host string, defaultAuth func() (string, error)) (string, error) {
+	for _, dockerConfig := range dockerConfigs {
+		auth, found, err := docker.GetEncodedAuthFromDockerConfig(dockerConfig, host)
+		if err != nil {
+			return "", fmt.Errorf("get encoded auth from docker config: %w", err)
+		}
+
+		if found {
+			return auth, nil
+		}
+	}
+
+	return defaultAuth()
+}
+
+func (c Controller) sync(ctx context.Context, source manifest.Source, dockerConfigs [][]byte) error {
+	targetAuth, err := getAuth(dockerConfigs, source.Target.Host, func() (string, error) {
+		return c.config.TargetAuth(source.Target)
+	})
+	if err != nil {
+		return fmt.Errorf("get target auth: %w", err)
+	}
+
+	exists, err := c.client.ImageExistsAtRemote(ctx, source.TargetImage(), targetAuth)
+	if err != nil {
+		return fmt.Errorf("image exists at remote: %w", err)
+	}
+
+	if exists {
+		return nil
+	}
+
+	sourceReference, err := name.ParseReference(source.Image(), name.WeakValidation)
+	if err != nil {
+		return fmt.Errorf("parse source reference: %w", err)
+	}
+
+	sourceAuth, err := getAuth(dockerConfigs, sourceReference.Context().RegistryStr(), func() (string, error) {
+		return c.config.SourceAuth(source)
+	})
+	if err != nil {
+		return fmt.Errorf("get source auth: %w", err)
+	}
+
+	c.config.Logger("Pushing %s", source.TargetImage())
+	if err := c.client.CopyImageAndWait(ctx, source.Image(), sourceAuth, source.TargetImage(), targetAuth, nil); err != nil {
+		return fmt.Errorf("copy image: %w", err)
+	}
+
+	c.config.Logger("Pushed %s", source.TargetImage())
+
+	return nil
+}
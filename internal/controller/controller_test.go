@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/internal/metrics"
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestReconcileAll(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	source, err := name.ParseReference(host+"/source/app:v1.0.0", name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(source, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	// The status of the deleted ImageSync cannot be updated, which does not stop the other ImageSync from being reconciled.
+	imageSyncs := ImageSyncList{
+		Items: []ImageSync{
+			{
+				Spec: ImageSyncSpec{
+					Target: host + "/mirror",
+					Images: []string{host + "/source/deleted:v1.0.0"},
+				},
+			},
+			{
+				Spec: ImageSyncSpec{
+					Target: host + "/mirror",
+					Images: []string{host + "/source/app:v1.0.0", host + "/source/missing:v1.0.0"},
+				},
+			},
+		},
+	}
+	imageSyncs.Items[0].Name = "deleted"
+	imageSyncs.Items[0].Namespace = "web"
+	imageSyncs.Items[1].Name = "app"
+	imageSyncs.Items[1].Namespace = "web"
+
+	var patchedStatus ImageSyncStatus
+	kubeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/sinker.plexsystems.com/v1alpha1/imagesyncs":
+			json.NewEncoder(w).Encode(imageSyncs)
+
+		case r.Method == http.MethodPatch && r.URL.Path == "/apis/sinker.plexsystems.com/v1alpha1/namespaces/web/imagesyncs/app/status":
+			body, _ := ioutil.ReadAll(r.Body)
+
+			var patch struct {
+				Status ImageSyncStatus `json:"status"`
+			}
+			if err := json.Unmarshal(body, &patch); err != nil {
+				t.Error("unmarshal patch:", err)
+			}
+
+			patchedStatus = patch.Status
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer kubeServer.Close()
+
+	client, err := docker.NewClient(t.Logf)
+	if err != nil {
+		t.Fatal("new client:", err)
+	}
+
+	noAuth := func(manifest.Source) (string, error) { return "", nil }
+	noTargetAuth := func(manifest.Target) (string, error) { return "", nil }
+
+	controller := Controller{
+		kube:   kubeClient{host: kubeServer.URL, httpClient: http.DefaultClient},
+		client: client.WithRetries(0),
+		config: Config{
+			SourceAuth: noAuth,
+			TargetAuth: noTargetAuth,
+			Logger:     t.Logf,
+		},
+	}
+
+	metrics.ImagesMissing.Set(0)
+	if err := controller.reconcileAll(context.Background()); err == nil {
+		t.Error("expected an error when the status of an image sync cannot be updated")
+	}
+
+	if metrics.ImagesMissing.Value() != 2 {
+		t.Errorf("expected 2 missing images, actual %v", metrics.ImagesMissing.Value())
+	}
+
+	if patchedStatus.Synced != 1 || patchedStatus.Failed != 1 {
+		t.Errorf("expected 1 synced and 1 failed image, actual %v synced and %v failed", patchedStatus.Synced, patchedStatus.Failed)
+	}
+
+	exists, err := client.ManifestExistsAtRemote(context.Background(), host+"/mirror/source/app:v1.0.0", "")
+	if err != nil {
+		t.Fatal("manifest exists at remote:", err)
+	}
+
+	if !exists {
+		t.Error("expected image to be mirrored to the target")
+	}
+}
+
+func TestGetDockerConfigs(t *testing.T) {
+	kubeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v1/namespaces/web/secrets/quay" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte(`{"type": "kubernetes.io/dockerconfigjson", "data": {".dockerconfigjson": "eyJhdXRocyI6eyJxdWF5LmlvIjp7InVzZXJuYW1lIjoidXNlciIsInBhc3N3b3JkIjoicGFzcyJ9fX0="}}`))
+	}))
+	defer kubeServer.Close()
+
+	controller := Controller{
+		kube: kubeClient{host: kubeServer.URL, httpClient: http.DefaultClient},
+	}
+
+	imageSync := ImageSync{Spec: ImageSyncSpec{Secrets: []string{"quay"}}}
+	imageSync.Namespace = "web"
+
+	dockerConfigs, err := controller.getDockerConfigs(context.Background(), imageSync)
+	if err != nil {
+		t.Fatal("get docker configs:", err)
+	}
+
+	defaultAuth := func() (string, error) { return "default", nil }
+
+	secretAuth, err := getAuth(dockerConfigs, "quay.io", defaultAuth)
+	if err != nil {
+		t.Fatal("get auth:", err)
+	}
+
+	expected := base64.URLEncoding.EncodeToString([]byte(`{"username":"user","password":"pass"}`))
+	if secretAuth != expected {
+		t.Errorf("expected auth %s, actual %s", expected, secretAuth)
+	}
+
+	otherAuth, err := getAuth(dockerConfigs, "docker.io", defaultAuth)
+	if err != nil {
+		t.Fatal("get auth:", err)
+	}
+
+	if otherAuth != "default" {
+		t.Errorf("expected default auth, actual %s", otherAuth)
+	}
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+)
+
+const serviceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeClient is a minimal client of the Kubernetes API for the ImageSync resource.
+type kubeClient struct {
+	host       string
+	token      string
+	httpClient *http.Client
+}
+
+// newInClusterClient returns a client that uses the service account of the pod it runs in.
+func newInClusterClient() (kubeClient, error) {
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return kubeClient{}, errors.New("not running in a cluster")
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountPath + "/token")
+	if err != nil {
+		return kubeClient{}, fmt.Errorf("read token: %w", err)
+	}
+
+	caCertificate, err := ioutil.ReadFile(serviceAccountPath + "/ca.crt")
+	if err != nil {
+		return kubeClient{}, fmt.Errorf("read ca certificate: %w", err)
+	}
+
+	certificates := x509.NewCertPool()
+	if !certificates.AppendCertsFromPEM(caCertificate) {
+		return kubeClient{}, errors.New("invalid ca certificate")
+	}
+
+	client := kubeClient{
+		host:  "https://" + net.JoinHostPort(host, port),
+		token: string(bytes.TrimSpace(token)),
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: certificates},
+			},
+		},
+	}
+
+	return client, nil
+}
+
+// listImageSyncs returns the ImageSyncs in the namespace, or in all namespaces when the namespace is empty.
+func (k kubeClient) listImageSyncs(ctx context.Context, namespace string) ([]ImageSync, error) {
+	path := "/apis/" + Group + "/" + Version + "/imagesyncs"
+	if namespace != "" {
+		path = "/apis/" + Group + "/" + Version + "/namespaces/" + namespace + "/imagesyncs"
+	}
+
+	var list ImageSyncList
+	if err := k.do(ctx, http.MethodGet, path, "", nil, &list); err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	return list.Items, nil
+}
+
+// updateStatus updates the status of the ImageSync. The status subresource must be enabled on the CRD.
+func (k kubeClient) updateStatus(ctx context.Context, imageSync ImageSync) error {
+	path := "/apis/" + Group + "/" + Version + "/namespaces/" + imageSync.Namespace + "/imagesyncs/" + imageSync.Name + "/status"
+
+	patch := struct {
+		Status ImageSyncStatus `json:"status"`
+	}{
+		Status: imageSync.Status,
+	}
+
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("marshal patch: %w", err)
+	}
+
+	if err := k.do(ctx, http.MethodPatch, path, "application/merge-patch+json", body, nil); err != nil {
+		return fmt.Errorf("patch: %w", err)
+	}
+
+	return nil
+}
+
+// getSecret returns the JSON of the Secret with the name in the namespace.
+func (k kubeClient) getSecret(ctx context.Context, namespace string, name string) ([]byte, error) {
+	var secret json.RawMessage
+	if err := k.do(ctx, http.MethodGet, "/api/v1/namespaces/"+namespace+"/secrets/"+name, "", nil, &secret); err != nil {
+		return nil, fmt.Errorf("get: %w", err)
+	}
+
+	return secret, nil
+}
+
+func (k kubeClient) do(ctx context.Context, method string, path string, contentType string, body []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, k.host+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %v: %s", resp.StatusCode, bytes.TrimSpace(contents))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.Unmarshal(contents, result); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return nil
+}
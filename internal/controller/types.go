@@ -0,0 +1,63 @@
+package controller
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Group is the API group of the ImageSync resource.
+	Group = "sinker.plexsystems.com"
+
+	// Version is the API version of the ImageSync resource.
+	Version = "v1alpha1"
+)
+
+// ImageSync is a set of images that are continuously mirrored to a target registry.
+type ImageSync struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageSyncSpec   `json:"spec"`
+	Status ImageSyncStatus `json:"status,omitempty"`
+}
+
+// ImageSyncSpec is the desired state of an ImageSync.
+type ImageSyncSpec struct {
+
+	// Target is the registry (and optionally repository) to mirror the images to (e.g. host.com/repo).
+	Target string `json:"target"`
+
+	// Images are the source images to mirror (e.g. quay.io/coreos/prometheus-operator:v0.40.0).
+	Images []string `json:"images"`
+
+	// Secrets are the names of the image pull secrets in the namespace of the ImageSync that the credentials of the
+	// source and target registries are read from. Registries without credentials in the secrets use the default auth.
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+// ImageSyncStatus is the observed state of an ImageSync.
+type ImageSyncStatus struct {
+
+	// ObservedGeneration is the generation of the ImageSync that was last reconciled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Synced is the number of images that exist at the target.
+	Synced int `json:"synced"`
+
+	// Failed is the number of images that could not be mirrored.
+	Failed int `json:"failed"`
+
+	// LastSyncTime is when the ImageSync was last reconciled.
+	LastSyncTime metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Message describes the failures of the last reconcile, if any.
+	Message string `json:"message,omitempty"`
+}
+
+// ImageSyncList is a list of ImageSync resources.
+type ImageSyncList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ImageSync `json:"items"`
+}
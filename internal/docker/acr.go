@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"time"
+)
+
+// acrHostPattern matches the hosts of Azure Container Registry registries (e.g. mycompany.azurecr.io).
+var acrHostPattern = regexp.MustCompile(`^[a-z0-9]+\.azurecr\.(?:io|cn|us|de)$`)
+
+// acrUsername is the username that authenticates to ACR with a refresh token.
+const acrUsername = "00000000-0000-0000-0000-000000000000"
+
+// azureResource is the resource that the Azure Active Directory access tokens are issued for.
+const azureResource = "https://management.azure.com/"
+
+// azureLoginURL is the endpoint of Azure Active Directory that issues access tokens.
+var azureLoginURL = "https://login.microsoftonline.com"
+
+// acrScheme is the scheme of the token exchange endpoint of the registry.
+var acrScheme = "https"
+
+func isACRHost(host string) bool {
+	return acrHostPattern.MatchString(host)
+}
+
+// getACRCredentials exchanges an Azure Active Directory access token for a refresh token of the registry. The access
+// token is issued to the service principal in the environment (AZURE_CLIENT_ID, AZURE_CLIENT_SECRET and AZURE_TENANT_ID)
+// or, when the environment does not contain a service principal, to the user that is logged in to the Azure CLI.
+func getACRCredentials(ctx context.Context, host string) (cloudCredentials, error) {
+	accessToken, tenant, err := getAzureAccessToken(ctx)
+	if err != nil {
+		return cloudCredentials{}, fmt.Errorf("get azure access token: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"tenant":       {tenant},
+		"access_token": {accessToken},
+	}
+
+	var response struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := postForm(ctx, acrScheme+"://"+host+"/oauth2/exchange", form.Encode(), nil, &response); err != nil {
+		return cloudCredentials{}, fmt.Errorf("exchange access token: %w", err)
+	}
+
+	// Refresh tokens issued by ACR are valid for three hours.
+	return cloudCredentials{
+		username: acrUsername,
+		password: response.RefreshToken,
+		expires:  time.Now().Add(3 * time.Hour),
+	}, nil
+}
+
+func getAzureAccessToken(ctx context.Context) (string, string, error) {
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	clientSecret := os.Getenv("AZURE_CLIENT_SECRET")
+	tenant := os.Getenv("AZURE_TENANT_ID")
+
+	if clientID != "" && clientSecret != "" && tenant != "" {
+		form := url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {clientID},
+			"client_secret": {clientSecret},
+			"scope":         {azureResource + ".default"},
+		}
+
+		var response struct {
+			AccessToken string `json:"access_token"`
+		}
+		if err := postForm(ctx, azureLoginURL+"/"+tenant+"/oauth2/v2.0/token", form.Encode(), nil, &response); err != nil {
+			return "", "", fmt.Errorf("get service principal token: %w", err)
+		}
+
+		return response.AccessToken, tenant, nil
+	}
+
+	output, err := exec.CommandContext(ctx, "az", "account", "get-access-token", "--resource", azureResource, "--output", "json").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("az account get-access-token: %w", err)
+	}
+
+	var response struct {
+		AccessToken string `json:"accessToken"`
+		Tenant      string `json:"tenant"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return "", "", fmt.Errorf("unmarshal access token: %w", err)
+	}
+
+	return response.AccessToken, response.Tenant, nil
+}
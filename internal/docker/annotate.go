@@ -0,0 +1,170 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// The annotations that are added to the manifests of the mirrored images to record where and when they were mirrored from.
+const (
+	AnnotationSource       = "com.plexsystems.sinker.source"
+	AnnotationSourceDigest = "com.plexsystems.sinker.source.digest"
+	AnnotationMirrored     = "com.plexsystems.sinker.mirrored"
+	AnnotationVersion      = "com.plexsystems.sinker.version"
+)
+
+// WithProvenance returns a copy of the client that adds annotations to the manifest of each image it copies,
+// recording the source image, the digest of the source image, when it was copied and the version of sinker.
+// Adding the annotations changes the digest of the manifest, so the digest of the copied image no longer
+// matches the digest of the source (or any signatures of the source).
+func (c Client) WithProvenance(version string) Client {
+	c.provenanceVersion = version
+	return c
+}
+
+func (c Client) getProvenanceAnnotations(source string, digest v1.Hash, now time.Time) map[string]string {
+	return map[string]string{
+		AnnotationSource:       source,
+		AnnotationSourceDigest: digest.String(),
+		AnnotationMirrored:     now.UTC().Format(time.RFC3339),
+		AnnotationVersion:      c.provenanceVersion,
+	}
+}
+
+// annotateManifest adds the annotations to the manifest, replacing any annotations with the same keys.
+// Fields of the manifest that are not known to this version of the client are kept as they are.
+func annotateManifest(rawManifest []byte, annotations map[string]string) ([]byte, error) {
+	var manifest map[string]json.RawMessage
+	if err := json.Unmarshal(rawManifest, &manifest); err != nil {
+		return nil, fmt.Errorf("unmarshal manifest: %w", err)
+	}
+
+	manifestAnnotations := make(map[string]string)
+	if rawAnnotations, ok := manifest["annotations"]; ok {
+		if err := json.Unmarshal(rawAnnotations, &manifestAnnotations); err != nil {
+			return nil, fmt.Errorf("unmarshal annotations: %w", err)
+		}
+	}
+
+	for key, value := range annotations {
+		manifestAnnotations[key] = value
+	}
+
+	rawAnnotations, err := json.Marshal(manifestAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("marshal annotations: %w", err)
+	}
+	manifest["annotations"] = rawAnnotations
+
+	annotatedManifest, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	return annotatedManifest, nil
+}
+
+// annotatedImage is an image whose manifest has additional annotations.
+type annotatedImage struct {
+	v1.Image
+
+	annotations map[string]string
+}
+
+func (i annotatedImage) RawManifest() ([]byte, error) {
+	rawManifest, err := i.Image.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return annotateManifest(rawManifest, i.annotations)
+}
+
+func (i annotatedImage) Manifest() (*v1.Manifest, error) {
+	rawManifest, err := i.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return v1.ParseManifest(bytes.NewReader(rawManifest))
+}
+
+func (i annotatedImage) Digest() (v1.Hash, error) {
+	rawManifest, err := i.RawManifest()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	digest, _, err := v1.SHA256(bytes.NewReader(rawManifest))
+	return digest, err
+}
+
+func (i annotatedImage) Size() (int64, error) {
+	rawManifest, err := i.RawManifest()
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(rawManifest)), nil
+}
+
+// annotatedIndex is an index whose manifest has additional annotations. The manifests of the images in
+// the index are not annotated, so that the digests of the images for each platform remain the same.
+type annotatedIndex struct {
+	index       v1.ImageIndex
+	annotations map[string]string
+}
+
+func (i annotatedIndex) MediaType() (types.MediaType, error) {
+	return i.index.MediaType()
+}
+
+func (i annotatedIndex) RawManifest() ([]byte, error) {
+	rawManifest, err := i.index.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return annotateManifest(rawManifest, i.annotations)
+}
+
+func (i annotatedIndex) IndexManifest() (*v1.IndexManifest, error) {
+	rawManifest, err := i.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return v1.ParseIndexManifest(bytes.NewReader(rawManifest))
+}
+
+func (i annotatedIndex) Digest() (v1.Hash, error) {
+	rawManifest, err := i.RawManifest()
+	if err != nil {
+		return v1.Hash{}, err
+	}
+
+	digest, _, err := v1.SHA256(bytes.NewReader(rawManifest))
+	return digest, err
+}
+
+func (i annotatedIndex) Size() (int64, error) {
+	rawManifest, err := i.RawManifest()
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(rawManifest)), nil
+}
+
+func (i annotatedIndex) Image(digest v1.Hash) (v1.Image, error) {
+	return i.index.Image(digest)
+}
+
+func (i annotatedIndex) ImageIndex(digest v1.Hash) (v1.ImageIndex, error) {
+	return i.index.ImageIndex(digest)
+}
@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestCopyImageAndWait_Provenance(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	source := host + "/source:v1.0.0"
+	sourceReference, err := name.ParseReference(source, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(sourceReference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	client := Client{
+		logInfo:  t.Logf,
+		attempts: 1,
+	}
+
+	target := host + "/target:v1.0.0"
+	if err := client.WithProvenance("1.0.0").CopyImageAndWait(context.Background(), source, "", target, "", nil); err != nil {
+		t.Fatal("copy image:", err)
+	}
+
+	targetReference, err := name.ParseReference(target, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	targetImage, err := remote.Image(targetReference)
+	if err != nil {
+		t.Fatal("get target image:", err)
+	}
+
+	manifest, err := targetImage.Manifest()
+	if err != nil {
+		t.Fatal("get manifest:", err)
+	}
+
+	sourceDigest, err := image.Digest()
+	if err != nil {
+		t.Fatal("get digest:", err)
+	}
+
+	if manifest.Annotations[AnnotationSource] != source {
+		t.Errorf("expected source annotation %s, actual %s", source, manifest.Annotations[AnnotationSource])
+	}
+
+	if manifest.Annotations[AnnotationSourceDigest] != sourceDigest.String() {
+		t.Errorf("expected source digest annotation %s, actual %s", sourceDigest, manifest.Annotations[AnnotationSourceDigest])
+	}
+
+	if manifest.Annotations[AnnotationVersion] != "1.0.0" {
+		t.Errorf("expected version annotation 1.0.0, actual %s", manifest.Annotations[AnnotationVersion])
+	}
+
+	if manifest.Annotations[AnnotationMirrored] == "" {
+		t.Error("expected mirrored annotation")
+	}
+}
+
+func TestAnnotateManifest(t *testing.T) {
+	const manifest = `{"schemaVersion":2,"subject":{"digest":"sha256:abc"},"annotations":{"foo":"bar"}}`
+
+	annotated, err := annotateManifest([]byte(manifest), map[string]string{"baz": "qux"})
+	if err != nil {
+		t.Fatal("annotate manifest:", err)
+	}
+
+	const expected = `{"annotations":{"baz":"qux","foo":"bar"},"schemaVersion":2,"subject":{"digest":"sha256:abc"}}`
+	if string(annotated) != expected {
+		t.Errorf("expected manifest %s, actual %s", expected, annotated)
+	}
+}
@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// HelmChartConfigMediaType is the media type of the config of a Helm chart that is stored in an OCI registry.
+const HelmChartConfigMediaType = "application/vnd.cncf.helm.config.v1+json"
+
+// CopyArtifactAndWait copies an OCI artifact (e.g. a Helm chart or an artifact pushed with ORAS) from the
+// source registry directly to the target registry. The manifest and blobs of the artifact are copied as they
+// are. When a config media type is given, the config of the artifact must have that media type, so that an
+// image is not mirrored as a Helm chart by mistake. Like images, failed copies are retried before failing.
+func (c Client) CopyArtifactAndWait(ctx context.Context, source string, sourceAuth string, target string, targetAuth string, configMediaType string) error {
+	copyArtifact := func() error {
+		if err := c.tryCopyArtifact(ctx, source, sourceAuth, target, targetAuth, configMediaType); err != nil {
+			return fmt.Errorf("try copy artifact: %w", err)
+		}
+
+		return nil
+	}
+
+	return c.copyAndWait(ctx, source, target, copyArtifact)
+}
+
+func (c Client) tryCopyArtifact(ctx context.Context, source string, sourceAuth string, target string, targetAuth string, configMediaType string) error {
+	if configMediaType != "" {
+		sourceReference, err := c.parseReference(source)
+		if err != nil {
+			return fmt.Errorf("parse source ref: %w", err)
+		}
+
+		sourceAuthenticator, err := getAuthenticator(sourceAuth)
+		if err != nil {
+			return fmt.Errorf("get source authenticator: %w", err)
+		}
+
+		descriptor, err := remote.Get(sourceReference, c.remoteOptions(ctx, sourceAuthenticator)...)
+		if err != nil {
+			return fmt.Errorf("get source: %w", err)
+		}
+
+		if isIndex(descriptor.MediaType) {
+			return fmt.Errorf("expected artifact with config of media type %s, found index", configMediaType)
+		}
+
+		manifest, err := v1.ParseManifest(bytes.NewReader(descriptor.Manifest))
+		if err != nil {
+			return fmt.Errorf("parse manifest: %w", err)
+		}
+
+		if manifest.Config.MediaType != types.MediaType(configMediaType) {
+			return fmt.Errorf("expected artifact with config of media type %s, found %s", configMediaType, manifest.Config.MediaType)
+		}
+	}
+
+	// The platforms of an index of artifacts are not filtered, as artifacts are not built for specific platforms.
+	if err := c.tryCopyImage(ctx, source, sourceAuth, target, targetAuth, nil); err != nil {
+		return fmt.Errorf("copy manifest: %w", err)
+	}
+
+	return nil
+}
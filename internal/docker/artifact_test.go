@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+type rawManifest struct {
+	manifest  []byte
+	mediaType types.MediaType
+}
+
+func (m rawManifest) RawManifest() ([]byte, error) {
+	return m.manifest, nil
+}
+
+func (m rawManifest) MediaType() (types.MediaType, error) {
+	return m.mediaType, nil
+}
+
+func TestCopyArtifactAndWait(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	source := host + "/charts/mychart:1.0.0"
+	sourceReference, err := name.ParseReference(source, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	var descriptors []v1.Descriptor
+	for _, mediaType := range []types.MediaType{HelmChartConfigMediaType, "application/vnd.cncf.helm.chart.content.v1.tar+gzip"} {
+		layer, err := random.Layer(1024, mediaType)
+		if err != nil {
+			t.Fatal("random layer:", err)
+		}
+
+		if err := remote.WriteLayer(sourceReference.Context(), layer); err != nil {
+			t.Fatal("write layer:", err)
+		}
+
+		digest, err := layer.Digest()
+		if err != nil {
+			t.Fatal("get digest:", err)
+		}
+
+		size, err := layer.Size()
+		if err != nil {
+			t.Fatal("get size:", err)
+		}
+
+		descriptors = append(descriptors, v1.Descriptor{MediaType: mediaType, Digest: digest, Size: size})
+	}
+
+	chartManifest := v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        descriptors[0],
+		Layers:        descriptors[1:],
+	}
+
+	manifestContents, err := json.Marshal(chartManifest)
+	if err != nil {
+		t.Fatal("marshal manifest:", err)
+	}
+
+	if err := remote.Tag(sourceReference.(name.Tag), rawManifest{manifest: manifestContents, mediaType: types.OCIManifestSchema1}); err != nil {
+		t.Fatal("write manifest:", err)
+	}
+
+	client := Client{
+		logInfo:  t.Logf,
+		attempts: 1,
+	}
+
+	target := host + "/mirror/charts/mychart:1.0.0"
+	if err := client.CopyArtifactAndWait(context.Background(), source, "", target, "", HelmChartConfigMediaType); err != nil {
+		t.Fatal("copy artifact:", err)
+	}
+
+	targetReference, err := name.ParseReference(target, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	descriptor, err := remote.Get(targetReference)
+	if err != nil {
+		t.Fatal("get target:", err)
+	}
+
+	if string(descriptor.Manifest) != string(manifestContents) {
+		t.Errorf("expected manifest %s, actual %s", manifestContents, descriptor.Manifest)
+	}
+
+	image := host + "/images/busybox:1.0.0"
+	imageReference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	randomImage, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	if err := remote.Write(imageReference, randomImage); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	if err := client.CopyArtifactAndWait(context.Background(), image, "", host+"/mirror/busybox:1.0.0", "", HelmChartConfigMediaType); err == nil {
+		t.Error("expected error when copying an image as a chart")
+	}
+}
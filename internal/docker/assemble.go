@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// PlatformImage is the image of a single platform that is assembled into a multi-arch image.
+type PlatformImage struct {
+	Image string
+
+	// Platform is the platform of the image in the form of os/arch[/variant] (e.g. linux/arm64/v8).
+	Platform string
+}
+
+// AssembleIndexAndWait assembles a multi-arch image at the target from the images of each platform, for projects
+// that publish a separate tag for each platform (e.g. 1.2.0-amd64 and 1.2.0-arm64) rather than a multi-arch image.
+// When the image of a platform is a multi-arch image itself, the image of the platform is taken from it. The index
+// is a Docker manifest list when each of the images is a Docker image, and an OCI image index otherwise.
+// Like images, failed copies are retried before failing.
+func (c Client) AssembleIndexAndWait(ctx context.Context, images []PlatformImage, sourceAuth string, target string, targetAuth string) error {
+	if len(images) == 0 {
+		return errors.New("no platform images to assemble")
+	}
+
+	assembleIndex := func() error {
+		if err := c.tryAssembleIndex(ctx, images, sourceAuth, target, targetAuth); err != nil {
+			return fmt.Errorf("try assemble index: %w", err)
+		}
+
+		return nil
+	}
+
+	return c.copyAndWait(ctx, images[0].Image, target, assembleIndex)
+}
+
+func (c Client) tryAssembleIndex(ctx context.Context, images []PlatformImage, sourceAuth string, target string, targetAuth string) error {
+	targetReference, err := c.parseReference(target)
+	if err != nil {
+		return fmt.Errorf("parse target ref: %w", err)
+	}
+
+	sourceAuthenticator, err := getAuthenticator(sourceAuth)
+	if err != nil {
+		return fmt.Errorf("get source authenticator: %w", err)
+	}
+
+	targetAuthenticator, err := getAuthenticator(targetAuth)
+	if err != nil {
+		return fmt.Errorf("get target authenticator: %w", err)
+	}
+
+	mediaType := types.DockerManifestList
+	var addendums []mutate.IndexAddendum
+	for _, platformImage := range images {
+		platform, err := ParsePlatform(platformImage.Platform)
+		if err != nil {
+			return fmt.Errorf("parse platform: %w", err)
+		}
+
+		sourceReference, err := c.parseReference(platformImage.Image)
+		if err != nil {
+			return fmt.Errorf("parse source ref: %w", err)
+		}
+
+		options := append(c.remoteOptions(ctx, sourceAuthenticator), remote.WithPlatform(platform))
+		image, err := remote.Image(sourceReference, options...)
+		if err != nil {
+			return fmt.Errorf("get image %s: %w", platformImage.Image, err)
+		}
+
+		// The platform of the image is checked, so that a tag that was published for the wrong
+		// platform is not added to the multi-arch image as the image of another platform. The config
+		// of an image does not record the variant of its platform, so only the OS and architecture are checked.
+		imagePlatform, err := getImagePlatform(image)
+		if err != nil {
+			return fmt.Errorf("get platform of %s: %w", platformImage.Image, err)
+		}
+
+		if imagePlatform.OS != platform.OS || imagePlatform.Architecture != platform.Architecture {
+			return fmt.Errorf("image %s is of platform %s, not %s", platformImage.Image, formatPlatform(imagePlatform), platformImage.Platform)
+		}
+
+		if platform.OSVersion == "" {
+			platform.OSVersion = imagePlatform.OSVersion
+		}
+
+		imageMediaType, err := image.MediaType()
+		if err != nil {
+			return fmt.Errorf("get media type of %s: %w", platformImage.Image, err)
+		}
+
+		if imageMediaType != types.DockerManifestSchema2 {
+			mediaType = types.OCIImageIndex
+		}
+
+		digest, err := image.Digest()
+		if err != nil {
+			return fmt.Errorf("get digest of %s: %w", platformImage.Image, err)
+		}
+
+		if len(c.configLabels) > 0 {
+			image, err = labelImage(image, c.configLabels)
+			if err != nil {
+				return fmt.Errorf("label image: %w", err)
+			}
+		}
+
+		if c.provenanceVersion != "" {
+			image = annotatedImage{Image: image, annotations: c.getProvenanceAnnotations(platformImage.Image, digest, time.Now())}
+		}
+
+		addendum := mutate.IndexAddendum{
+			Add:        image,
+			Descriptor: v1.Descriptor{Platform: &platform},
+		}
+
+		addendums = append(addendums, addendum)
+	}
+
+	index := mutate.AppendManifests(empty.Index, addendums...)
+	index = mutate.IndexMediaType(index, mediaType)
+
+	if c.mounts != nil {
+		index = mountableIndex{index: index, mounts: c.mounts, target: targetReference.Context()}
+	}
+
+	if err := remote.WriteIndex(targetReference, index, c.remoteOptions(ctx, targetAuthenticator)...); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+
+	if c.mounts != nil {
+		if err := c.mounts.recordIndex(targetReference.Context(), index); err != nil {
+			return fmt.Errorf("record layers: %w", err)
+		}
+	}
+
+	return nil
+}
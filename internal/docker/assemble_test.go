@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestAssembleIndexAndWait(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	architectures := map[string]string{"amd64": "linux/amd64", "arm64": "linux/arm64/v8"}
+
+	var images []PlatformImage
+	for architecture, platform := range architectures {
+		randomImage, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		configFile, err := randomImage.ConfigFile()
+		if err != nil {
+			t.Fatal("get config file:", err)
+		}
+		configFile.OS = "linux"
+		configFile.Architecture = architecture
+
+		image, err := mutate.ConfigFile(randomImage, configFile)
+		if err != nil {
+			t.Fatal("mutate config file:", err)
+		}
+
+		source := host + "/source:v1.0.0-" + architecture
+		reference, err := name.ParseReference(source, name.WeakValidation)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.Write(reference, image); err != nil {
+			t.Fatal("write image:", err)
+		}
+
+		images = append(images, PlatformImage{Image: source, Platform: platform})
+	}
+
+	client := Client{
+		logInfo: t.Logf,
+	}
+
+	target := host + "/target:v1.0.0"
+	if err := client.AssembleIndexAndWait(context.Background(), images, "", target, ""); err != nil {
+		t.Fatal("assemble index:", err)
+	}
+
+	targetReference, err := name.ParseReference(target, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	targetIndex, err := remote.Index(targetReference)
+	if err != nil {
+		t.Fatal("get target index:", err)
+	}
+
+	indexManifest, err := targetIndex.IndexManifest()
+	if err != nil {
+		t.Fatal("get index manifest:", err)
+	}
+
+	if len(indexManifest.Manifests) != len(architectures) {
+		t.Fatalf("expected %v manifests in the index, actual %v", len(architectures), len(indexManifest.Manifests))
+	}
+
+	for _, manifest := range indexManifest.Manifests {
+		if manifest.Platform == nil || architectures[manifest.Platform.Architecture] != formatPlatform(*manifest.Platform) {
+			t.Errorf("unexpected platform %+v of manifest %s", manifest.Platform, manifest.Digest)
+		}
+	}
+
+	// An image that was published for another platform than the one it is assembled as is not added to the index.
+	mismatched := []PlatformImage{{Image: images[0].Image, Platform: "linux/s390x"}}
+	if err := client.WithRetries(0).AssembleIndexAndWait(context.Background(), mismatched, "", host+"/target:mismatched", ""); err == nil {
+		t.Error("expected error when the image is of another platform")
+	}
+}
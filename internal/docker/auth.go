@@ -4,12 +4,18 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 )
 
 // GetEncodedAuthForHost returns a Base64 encoded auth for the given host.
+//
+// The credentials are found in the Docker config (e.g. from docker login). When the config does not contain
+// credentials for a registry of a supported cloud provider (ECR, GCR, Artifact Registry or ACR), the credentials
+// are requested from the cloud provider instead. When that fails as well, the registry is accessed anonymously,
+// as the images in the registry may be public.
 func GetEncodedAuthForHost(host string) (string, error) {
 	registryReference, err := name.NewRegistry(host, name.WeakValidation)
 	if err != nil {
@@ -21,6 +27,12 @@ func GetEncodedAuthForHost(host string) (string, error) {
 		return "", fmt.Errorf("resolve auth: %w", err)
 	}
 
+	if helper := GetCloudHelper(registryReference.RegistryStr()); helper != "" && isAnonymous(auth) {
+		if cloudAuth, err := GetEncodedAuthFromHelper(helper, registryReference.RegistryStr()); err == nil {
+			return cloudAuth, nil
+		}
+	}
+
 	authConfig, err := auth.Authorization()
 	if err != nil {
 		return "", fmt.Errorf("get auth: %w", err)
@@ -33,3 +45,77 @@ func GetEncodedAuthForHost(host string) (string, error) {
 
 	return base64.URLEncoding.EncodeToString(jsonAuth), nil
 }
+
+// GetEncodedBasicAuth returns a Base64 encoded auth for the given username and password.
+func GetEncodedBasicAuth(username string, password string) (string, error) {
+	authConfig := struct {
+		Username string
+		Password string
+	}{
+		Username: username,
+		Password: password,
+	}
+
+	jsonAuth, err := json.Marshal(authConfig)
+	if err != nil {
+		return "", fmt.Errorf("marshal auth: %w", err)
+	}
+
+	return base64.URLEncoding.EncodeToString(jsonAuth), nil
+}
+
+// GetEncodedAuthFromDockerConfig returns a Base64 encoded auth for the given host from the credentials in
+// the Docker config (e.g. the contents of an image pull secret). The registries in the config can be written
+// as a host (e.g. quay.io) or as a URL (e.g. https://index.docker.io/v1/). When the config does not contain
+// credentials for the host, false is returned.
+func GetEncodedAuthFromDockerConfig(dockerConfig []byte, host string) (string, bool, error) {
+	var config struct {
+		Auths map[string]struct {
+			Auth          string `json:"auth"`
+			Username      string `json:"username"`
+			Password      string `json:"password"`
+			IdentityToken string `json:"identitytoken"`
+		} `json:"auths"`
+	}
+
+	if err := json.Unmarshal(dockerConfig, &config); err != nil {
+		return "", false, fmt.Errorf("unmarshal docker config: %w", err)
+	}
+
+	registry, err := name.NewRegistry(host, name.WeakValidation)
+	if err != nil {
+		return "", false, fmt.Errorf("new registry: %w", err)
+	}
+
+	for configHost, configAuth := range config.Auths {
+		configRegistry, err := name.NewRegistry(getConfigHost(configHost), name.WeakValidation)
+		if err != nil || configRegistry.RegistryStr() != registry.RegistryStr() {
+			continue
+		}
+
+		authConfig := authn.AuthConfig{
+			Username:      configAuth.Username,
+			Password:      configAuth.Password,
+			Auth:          configAuth.Auth,
+			IdentityToken: configAuth.IdentityToken,
+		}
+
+		jsonAuth, err := json.Marshal(authConfig)
+		if err != nil {
+			return "", false, fmt.Errorf("marshal auth: %w", err)
+		}
+
+		return base64.URLEncoding.EncodeToString(jsonAuth), true, nil
+	}
+
+	return "", false, nil
+}
+
+// getConfigHost returns the host of a registry in a Docker config, which
+// can be written as a URL (e.g. https://index.docker.io/v1/).
+func getConfigHost(configHost string) string {
+	configHost = strings.TrimPrefix(configHost, "https://")
+	configHost = strings.TrimPrefix(configHost, "http://")
+
+	return strings.Split(configHost, "/")[0]
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+func TestGetEncodedAuthFromDockerConfig(t *testing.T) {
+	const dockerConfig = `{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "dXNlcjpwYXNzd29yZA=="},
+    "quay.io": {"username": "robot", "password": "token"}
+  }
+}`
+
+	testCases := []struct {
+		host             string
+		expectedFound    bool
+		expectedUsername string
+		expectedAuth     string
+	}{
+		{"docker.io", true, "", "dXNlcjpwYXNzd29yZA=="},
+		{"quay.io", true, "robot", ""},
+		{"gcr.io", false, "", ""},
+	}
+
+	for _, testCase := range testCases {
+		encodedAuth, found, err := GetEncodedAuthFromDockerConfig([]byte(dockerConfig), testCase.host)
+		if err != nil {
+			t.Fatal("get encoded auth:", err)
+		}
+
+		if found != testCase.expectedFound {
+			t.Errorf("expected found %v for %s, actual %v", testCase.expectedFound, testCase.host, found)
+			continue
+		}
+
+		if !found {
+			continue
+		}
+
+		jsonAuth, err := base64.URLEncoding.DecodeString(encodedAuth)
+		if err != nil {
+			t.Fatal("decode auth:", err)
+		}
+
+		var authConfig authn.AuthConfig
+		if err := json.Unmarshal(jsonAuth, &authConfig); err != nil {
+			t.Fatal("unmarshal auth:", err)
+		}
+
+		if authConfig.Username != testCase.expectedUsername || authConfig.Auth != testCase.expectedAuth {
+			t.Errorf("unexpected auth for %s: %+v", testCase.host, authConfig)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+var bandwidthPattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGT]i?)?B(?:/s)?$`)
+
+var bandwidthUnits = map[string]float64{
+	"":   1,
+	"K":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+}
+
+// ParseBandwidth parses a bandwidth (e.g. 50MiB/s or 10MB/s) into the number of bytes per second.
+func ParseBandwidth(bandwidth string) (int64, error) {
+	match := bandwidthPattern.FindStringSubmatch(strings.TrimSpace(bandwidth))
+	if match == nil {
+		return 0, fmt.Errorf("invalid bandwidth %s, expected a number of bytes per second (e.g. 50MiB/s)", bandwidth)
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse value: %w", err)
+	}
+
+	bytesPerSecond := int64(value * bandwidthUnits[match[2]])
+	if bytesPerSecond <= 0 {
+		return 0, fmt.Errorf("bandwidth %s must be at least one byte per second", bandwidth)
+	}
+
+	return bytesPerSecond, nil
+}
+
+var sizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGT]i?)?B$`)
+
+// ParseSize parses a size (e.g. 50GiB or 10GB) into a number of bytes.
+func ParseSize(size string) (int64, error) {
+	match := sizePattern.FindStringSubmatch(strings.TrimSpace(size))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %s, expected a number of bytes (e.g. 50GiB)", size)
+	}
+
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse value: %w", err)
+	}
+
+	bytes := int64(value * bandwidthUnits[match[2]])
+	if bytes <= 0 {
+		return 0, fmt.Errorf("size %s must be at least one byte", size)
+	}
+
+	return bytes, nil
+}
+
+// BandwidthLimiter limits the rate at which bytes are transferred to and from the registries, both in
+// total and for each registry. The limits are shared by all of the images that are copied at the same time.
+type BandwidthLimiter struct {
+	total      *bandwidthBucket
+	registries map[string]*bandwidthBucket
+}
+
+// NewBandwidthLimiter returns a bandwidth limiter that transfers at most the given number of bytes per second
+// in total, and at most the number of bytes per second of each registry (keyed by the host of the registry)
+// to and from that registry. A maximum bandwidth of zero does not limit the total bandwidth.
+func NewBandwidthLimiter(maxBandwidth int64, registryBandwidths map[string]int64) (*BandwidthLimiter, error) {
+	limiter := BandwidthLimiter{
+		registries: make(map[string]*bandwidthBucket),
+	}
+
+	if maxBandwidth > 0 {
+		limiter.total = &bandwidthBucket{bytesPerSecond: maxBandwidth}
+	}
+
+	for host, bandwidth := range registryBandwidths {
+		registry, err := name.NewRegistry(host, name.WeakValidation)
+		if err != nil {
+			return nil, fmt.Errorf("parse registry %s: %w", host, err)
+		}
+
+		limiter.registries[strings.ToLower(registry.RegistryStr())] = &bandwidthBucket{bytesPerSecond: bandwidth}
+	}
+
+	return &limiter, nil
+}
+
+// Wait blocks until the given number of bytes that were transferred to or from the registry
+// have been paid for by the bandwidth of the registry and the total bandwidth.
+func (l *BandwidthLimiter) Wait(ctx context.Context, registry string, bytes int) error {
+	wait := l.reserve(registry, bytes, time.Now())
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve reserves the bandwidth to transfer the bytes and returns how long
+// to wait until the transfer fits within the limits of the bandwidth.
+func (l *BandwidthLimiter) reserve(registry string, bytes int, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, bucket := range []*bandwidthBucket{l.total, l.registries[strings.ToLower(registry)]} {
+		if bucket == nil {
+			continue
+		}
+
+		if bucketWait := bucket.reserve(bytes, now); bucketWait > wait {
+			wait = bucketWait
+		}
+	}
+
+	return wait
+}
+
+type bandwidthBucket struct {
+	bytesPerSecond int64
+	mutex          sync.Mutex
+	next           time.Time
+}
+
+// reserve reserves the time that it takes to transfer the bytes at the bandwidth of the bucket,
+// after the bytes that were reserved before, and returns how long to wait until that time.
+func (b *bandwidthBucket) reserve(bytes int, now time.Time) time.Duration {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.next.Before(now) {
+		b.next = now
+	}
+
+	b.next = b.next.Add(time.Duration(bytes) * time.Second / time.Duration(b.bytesPerSecond))
+
+	return b.next.Sub(now)
+}
+
+// WithBandwidthLimiter returns a copy of the client that limits the bandwidth used when transferring images.
+func (c Client) WithBandwidthLimiter(bandwidthLimiter *BandwidthLimiter) Client {
+	c.bandwidthLimiter = bandwidthLimiter
+	return c
+}
+
+// bandwidthTransport limits the rate at which the bodies of the requests are uploaded and the
+// bodies of the responses are downloaded. Requests that were redirected (e.g. to the storage of
+// the registry) count towards the bandwidth of the registry that the image was requested from.
+type bandwidthTransport struct {
+	inner   http.RoundTripper
+	limiter *BandwidthLimiter
+}
+
+func (t bandwidthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	registry := getOriginalHost(req)
+
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = bandwidthReader{ReadCloser: req.Body, ctx: req.Context(), registry: registry, limiter: t.limiter}
+	}
+
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = bandwidthReader{ReadCloser: resp.Body, ctx: req.Context(), registry: registry, limiter: t.limiter}
+
+	return resp, nil
+}
+
+// getOriginalHost returns the host that the request was originally sent to before it was redirected.
+func getOriginalHost(req *http.Request) string {
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+
+	return req.URL.Host
+}
+
+type bandwidthReader struct {
+	io.ReadCloser
+	ctx      context.Context
+	registry string
+	limiter  *BandwidthLimiter
+}
+
+func (r bandwidthReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := r.limiter.Wait(r.ctx, r.registry, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBandwidth(t *testing.T) {
+	testCases := []struct {
+		bandwidth string
+		expected  int64
+	}{
+		{"50MiB/s", 50 * 1024 * 1024},
+		{"10MB/s", 10 * 1000 * 1000},
+		{"1.5KiB", 1536},
+		{"100B/s", 100},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := ParseBandwidth(testCase.bandwidth)
+		if err != nil {
+			t.Fatalf("parse bandwidth %s: %v", testCase.bandwidth, err)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf("expected %s to be %v bytes per second, actual %v", testCase.bandwidth, testCase.expected, actual)
+		}
+	}
+
+	for _, bandwidth := range []string{"", "50", "fast", "0MiB/s", "10Mb/s"} {
+		if _, err := ParseBandwidth(bandwidth); err == nil {
+			t.Errorf("expected error parsing bandwidth %q", bandwidth)
+		}
+	}
+}
+
+func TestBandwidthLimiter_Reserve(t *testing.T) {
+	limiter, err := NewBandwidthLimiter(2000, map[string]int64{"quay.io": 1000})
+	if err != nil {
+		t.Fatal("new bandwidth limiter:", err)
+	}
+
+	now := time.Now()
+
+	if wait := limiter.reserve("quay.io", 500, now); wait != 500*time.Millisecond {
+		t.Errorf("expected transfer from the registry to wait %v, actual %v", 500*time.Millisecond, wait)
+	}
+
+	// The total bandwidth is shared with the transfer from quay.io, which used a quarter of a second of it.
+	if wait := limiter.reserve("gcr.io", 1000, now); wait != 750*time.Millisecond {
+		t.Errorf("expected transfer from another registry to wait %v, actual %v", 750*time.Millisecond, wait)
+	}
+
+	if wait := limiter.reserve("quay.io", 500, now); wait != time.Second {
+		t.Errorf("expected second transfer from the registry to wait %v, actual %v", time.Second, wait)
+	}
+}
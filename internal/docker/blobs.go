@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Blob is a manifest, config or layer of an image that is transferred when the image is copied.
+type Blob struct {
+	Digest string
+	Size   int64
+}
+
+// GetBlobs returns the manifests, configs and layers of the image with their compressed sizes. The blobs
+// of a multi-arch image include the blobs of every image in its index, unless a list of platforms
+// (e.g. linux/amd64) is given to restrict them to, in the same way as copying the image does.
+func (c Client) GetBlobs(ctx context.Context, image string, auth string, platforms []string) ([]Blob, error) {
+	reference, err := c.parseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return nil, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	descriptor, err := remote.Get(reference, c.remoteOptions(ctx, authenticator)...)
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", err)
+	}
+
+	blobs := []Blob{{Digest: descriptor.Digest.String(), Size: descriptor.Size}}
+	if !isIndex(descriptor.MediaType) {
+		manifestBlobs, err := getManifestBlobs(descriptor.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("get manifest blobs: %w", err)
+		}
+
+		return append(blobs, manifestBlobs...), nil
+	}
+
+	indexManifest, err := v1.ParseIndexManifest(bytes.NewReader(descriptor.Manifest))
+	if err != nil {
+		return nil, fmt.Errorf("parse index manifest: %w", err)
+	}
+
+	requiredPlatforms, err := parsePlatforms(platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	// An image without any of the platforms is copied with all of its platforms (see filterIndex).
+	var hasRequiredPlatform bool
+	for _, manifest := range indexManifest.Manifests {
+		if manifest.Platform != nil && platformsContain(requiredPlatforms, *manifest.Platform) {
+			hasRequiredPlatform = true
+		}
+	}
+
+	for _, manifest := range indexManifest.Manifests {
+		if hasRequiredPlatform && (manifest.Platform == nil || !platformsContain(requiredPlatforms, *manifest.Platform)) {
+			continue
+		}
+
+		manifestDescriptor, err := remote.Get(reference.Context().Digest(manifest.Digest.String()), c.remoteOptions(ctx, authenticator)...)
+		if err != nil {
+			return nil, fmt.Errorf("get manifest %s: %w", manifest.Digest, err)
+		}
+
+		manifestBlobs, err := getManifestBlobs(manifestDescriptor.Manifest)
+		if err != nil {
+			return nil, fmt.Errorf("get manifest blobs of %s: %w", manifest.Digest, err)
+		}
+
+		blobs = append(blobs, Blob{Digest: manifest.Digest.String(), Size: manifest.Size})
+		blobs = append(blobs, manifestBlobs...)
+	}
+
+	return blobs, nil
+}
+
+func getManifestBlobs(contents []byte) ([]Blob, error) {
+	manifest, err := v1.ParseManifest(bytes.NewReader(contents))
+	if err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+
+	blobs := []Blob{{Digest: manifest.Config.Digest.String(), Size: manifest.Config.Size}}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, Blob{Digest: layer.Digest.String(), Size: layer.Size})
+	}
+
+	return blobs, nil
+}
@@ -0,0 +1,284 @@
+package docker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// imageNameAnnotation is the OCI annotation used to record the name of an image in an image layout.
+const imageNameAnnotation = "org.opencontainers.image.ref.name"
+
+// SaveImage saves the image from the remote registry to the OCI image layout at the specified path.
+// The image is annotated with its name so that it can be found again when it is loaded.
+func (c Client) SaveImage(ctx context.Context, image string, auth string, layoutPath string) error {
+	return c.saveImage(ctx, image, auth, layoutPath, image)
+}
+
+// saveImage saves the image from the remote registry to the OCI image layout, annotated with the given name.
+func (c Client) saveImage(ctx context.Context, image string, auth string, layoutPath string, name string) error {
+	reference, err := c.parseReference(image)
+	if err != nil {
+		return fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return fmt.Errorf("get authenticator: %w", err)
+	}
+
+	imageLayout, err := getImageLayout(layoutPath)
+	if err != nil {
+		return fmt.Errorf("get image layout: %w", err)
+	}
+
+	descriptor, err := remote.Get(reference, c.remoteOptions(ctx, authenticator)...)
+	if err != nil {
+		return fmt.Errorf("get image: %w", err)
+	}
+
+	annotations := layout.WithAnnotations(map[string]string{
+		imageNameAnnotation: name,
+	})
+
+	if !isIndex(descriptor.MediaType) {
+		remoteImage, err := descriptor.Image()
+		if err != nil {
+			return fmt.Errorf("get remote image: %w", err)
+		}
+
+		if err := imageLayout.AppendImage(remoteImage, annotations); err != nil {
+			return fmt.Errorf("append image: %w", err)
+		}
+
+		return nil
+	}
+
+	remoteIndex, err := descriptor.ImageIndex()
+	if err != nil {
+		return fmt.Errorf("get remote index: %w", err)
+	}
+
+	if err := imageLayout.AppendIndex(remoteIndex, annotations); err != nil {
+		return fmt.Errorf("append index: %w", err)
+	}
+
+	return nil
+}
+
+// LoadImage pushes the image with the given name from the OCI image layout at the
+// specified path to the target. Layouts written by push name their images after the
+// target, so the image is found by the name of the target when it is not found by its own.
+func (c Client) LoadImage(ctx context.Context, layoutPath string, image string, target string, targetAuth string) error {
+	targetReference, err := c.parseReference(target)
+	if err != nil {
+		return fmt.Errorf("parse target ref: %w", err)
+	}
+
+	targetAuthenticator, err := getAuthenticator(targetAuth)
+	if err != nil {
+		return fmt.Errorf("get target authenticator: %w", err)
+	}
+
+	layoutIndex, err := layout.ImageIndexFromPath(layoutPath)
+	if err != nil {
+		return fmt.Errorf("get layout index: %w", err)
+	}
+
+	descriptor, err := findLayoutDescriptor(layoutIndex, image, target)
+	if err != nil {
+		return fmt.Errorf("find image: %w", err)
+	}
+
+	if !isIndex(descriptor.MediaType) {
+		layoutImage, err := layoutIndex.Image(descriptor.Digest)
+		if err != nil {
+			return fmt.Errorf("get layout image: %w", err)
+		}
+
+		if err := remote.Write(targetReference, layoutImage, c.remoteOptions(ctx, targetAuthenticator)...); err != nil {
+			return fmt.Errorf("write image: %w", err)
+		}
+
+		return nil
+	}
+
+	imageIndex, err := layoutIndex.ImageIndex(descriptor.Digest)
+	if err != nil {
+		return fmt.Errorf("get layout index: %w", err)
+	}
+
+	if err := remote.WriteIndex(targetReference, imageIndex, c.remoteOptions(ctx, targetAuthenticator)...); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+
+	return nil
+}
+
+// WriteArchive writes the contents of the directory to a gzip compressed tar archive.
+func WriteArchive(dir string, archivePath string) error {
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		return fmt.Errorf("create archive: %w", err)
+	}
+	defer archive.Close()
+
+	gzipWriter := gzip.NewWriter(archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = filepath.Walk(dir, func(currentPath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		relativePath, err := filepath.Rel(dir, currentPath)
+		if err != nil {
+			return fmt.Errorf("relative path: %w", err)
+		}
+
+		if relativePath == "." {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(fileInfo, "")
+		if err != nil {
+			return fmt.Errorf("file info header: %w", err)
+		}
+		header.Name = filepath.ToSlash(relativePath)
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+
+		if fileInfo.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(currentPath)
+		if err != nil {
+			return fmt.Errorf("open file: %w", err)
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(tarWriter, file); err != nil {
+			return fmt.Errorf("copy file: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("close tar writer: %w", err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("close gzip writer: %w", err)
+	}
+
+	return nil
+}
+
+// ExtractArchive extracts the gzip compressed tar archive into the directory.
+func ExtractArchive(archivePath string, dir string) error {
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer archive.Close()
+
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return fmt.Errorf("new gzip reader: %w", err)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read archive: %w", err)
+		}
+
+		// Entries that would be written outside of the directory are not
+		// expected in an image bundle and should not be extracted.
+		filePath := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(filePath, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid archive entry %s", header.Name)
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			if err := os.MkdirAll(filePath, os.ModePerm); err != nil {
+				return fmt.Errorf("create directory: %w", err)
+			}
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+			return fmt.Errorf("create directory: %w", err)
+		}
+
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+		if err != nil {
+			return fmt.Errorf("create file: %w", err)
+		}
+
+		if _, err := io.Copy(file, tarReader); err != nil {
+			file.Close()
+			return fmt.Errorf("write file: %w", err)
+		}
+
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("close file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func getImageLayout(layoutPath string) (layout.Path, error) {
+	if imageLayout, err := layout.FromPath(layoutPath); err == nil {
+		return imageLayout, nil
+	}
+
+	imageLayout, err := layout.Write(layoutPath, empty.Index)
+	if err != nil {
+		return "", fmt.Errorf("write layout: %w", err)
+	}
+
+	return imageLayout, nil
+}
+
+// findLayoutDescriptor returns the descriptor of the first of the images that is found in the layout.
+func findLayoutDescriptor(layoutIndex v1.ImageIndex, images ...string) (v1.Descriptor, error) {
+	indexManifest, err := layoutIndex.IndexManifest()
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("get index manifest: %w", err)
+	}
+
+	for _, image := range images {
+		for _, descriptor := range indexManifest.Manifests {
+			if descriptor.Annotations[imageNameAnnotation] == image {
+				return descriptor, nil
+			}
+		}
+	}
+
+	return v1.Descriptor{}, fmt.Errorf("image %s not found in layout", images[0])
+}
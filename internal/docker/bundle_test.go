@@ -0,0 +1,172 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestSaveAndLoadImage(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	source := host + "/source:v1.0.0"
+	sourceReference, err := name.ParseReference(source, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(sourceReference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := Client{
+		logInfo: t.Logf,
+	}
+
+	savePath := filepath.Join(tempDir, "save")
+	if err := client.SaveImage(context.Background(), source, "", savePath); err != nil {
+		t.Fatal("save image:", err)
+	}
+
+	archivePath := filepath.Join(tempDir, "images.tar.gz")
+	if err := WriteArchive(savePath, archivePath); err != nil {
+		t.Fatal("write archive:", err)
+	}
+
+	loadPath := filepath.Join(tempDir, "load")
+	if err := ExtractArchive(archivePath, loadPath); err != nil {
+		t.Fatal("extract archive:", err)
+	}
+
+	target := host + "/target:v1.0.0"
+	if err := client.LoadImage(context.Background(), loadPath, source, target, ""); err != nil {
+		t.Fatal("load image:", err)
+	}
+
+	expectedDigest, err := image.Digest()
+	if err != nil {
+		t.Fatal("get digest:", err)
+	}
+
+	actualDigest, err := client.GetDigest(context.Background(), target, "")
+	if err != nil {
+		t.Fatal("get target digest:", err)
+	}
+
+	if actualDigest != expectedDigest.String() {
+		t.Errorf("expected digest %s, actual %s", expectedDigest, actualDigest)
+	}
+}
+
+func TestVerifyBundleIndex(t *testing.T) {
+	layoutPath, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(layoutPath)
+
+	imageLayout, err := layout.Write(layoutPath, empty.Index)
+	if err != nil {
+		t.Fatal("write layout:", err)
+	}
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	if err := imageLayout.AppendImage(image, layout.WithAnnotations(map[string]string{imageNameAnnotation: "example.com/image:v1.0.0"})); err != nil {
+		t.Fatal("append image:", err)
+	}
+
+	if _, err := VerifyBundleIndex(layoutPath); !errors.Is(err, ErrBundleIndexNotFound) {
+		t.Errorf("expected index not found error, actual %v", err)
+	}
+
+	if _, err := WriteBundleIndex(layoutPath); err != nil {
+		t.Fatal("write bundle index:", err)
+	}
+
+	bundleIndex, err := VerifyBundleIndex(layoutPath)
+	if err != nil {
+		t.Fatal("verify bundle index:", err)
+	}
+
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal("get digest:", err)
+	}
+
+	expectedImages := []BundleImage{{Name: "example.com/image:v1.0.0", Digest: digest.String()}}
+	if !reflect.DeepEqual(bundleIndex.Images, expectedImages) {
+		t.Errorf("expected images %v, actual %v", expectedImages, bundleIndex.Images)
+	}
+
+	layers, err := image.Layers()
+	if err != nil {
+		t.Fatal("get layers:", err)
+	}
+
+	layerDigest, err := layers[0].Digest()
+	if err != nil {
+		t.Fatal("get layer digest:", err)
+	}
+
+	layerPath := filepath.Join(layoutPath, "blobs", layerDigest.Algorithm, layerDigest.Hex)
+	layerContents, err := ioutil.ReadFile(layerPath)
+	if err != nil {
+		t.Fatal("read layer:", err)
+	}
+
+	if err := ioutil.WriteFile(layerPath, layerContents[:len(layerContents)/2], os.ModePerm); err != nil {
+		t.Fatal("truncate layer:", err)
+	}
+
+	if _, err := VerifyBundleIndex(layoutPath); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected checksum error for truncated layer, actual %v", err)
+	}
+
+	if err := os.Remove(layerPath); err != nil {
+		t.Fatal("remove layer:", err)
+	}
+
+	if _, err := VerifyBundleIndex(layoutPath); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expected missing error for removed layer, actual %v", err)
+	}
+
+	if err := ioutil.WriteFile(layerPath, layerContents, os.ModePerm); err != nil {
+		t.Fatal("restore layer:", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(layoutPath, "blobs", "extra"), []byte("extra"), os.ModePerm); err != nil {
+		t.Fatal("write extra file:", err)
+	}
+
+	if _, err := VerifyBundleIndex(layoutPath); err == nil || !strings.Contains(err.Error(), "not in the index") {
+		t.Errorf("expected error for file that is not in the index, actual %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package docker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+)
+
+// BundleIndexFile is the name of the index file in the OCI image layout of a bundle.
+const BundleIndexFile = "sinker-index.json"
+
+// bundleIndexVersion is the version of the format of the index, which is increased
+// when the index changes in a way that older versions of sinker cannot verify.
+const bundleIndexVersion = 1
+
+// ErrBundleIndexNotFound is returned when a bundle does not have an index, such as
+// the bundles that were saved by versions of sinker that did not write one.
+var ErrBundleIndexNotFound = errors.New("bundle index not found")
+
+// BundleIndex lists the images in a bundle and the checksum of each of its files,
+// so that a bundle that was tampered with or truncated in transit can be rejected.
+type BundleIndex struct {
+	Version int           `json:"version"`
+	Images  []BundleImage `json:"images"`
+	Files   []BundleFile  `json:"files"`
+}
+
+// BundleImage is an image in a bundle.
+type BundleImage struct {
+	Name   string `json:"name"`
+	Digest string `json:"digest"`
+}
+
+// BundleFile is a file in a bundle, by its path relative to the layout.
+type BundleFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// WriteBundleIndex writes the index of the images and files of the OCI image layout to the layout.
+func WriteBundleIndex(layoutPath string) (BundleIndex, error) {
+	bundleImages, err := getBundleImages(layoutPath)
+	if err != nil {
+		return BundleIndex{}, fmt.Errorf("get images: %w", err)
+	}
+
+	bundleFiles, err := getBundleFiles(layoutPath)
+	if err != nil {
+		return BundleIndex{}, fmt.Errorf("get files: %w", err)
+	}
+
+	bundleIndex := BundleIndex{
+		Version: bundleIndexVersion,
+		Images:  bundleImages,
+		Files:   bundleFiles,
+	}
+
+	contents, err := json.MarshalIndent(bundleIndex, "", "  ")
+	if err != nil {
+		return BundleIndex{}, fmt.Errorf("marshal index: %w", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(layoutPath, BundleIndexFile), contents, os.ModePerm); err != nil {
+		return BundleIndex{}, fmt.Errorf("write index: %w", err)
+	}
+
+	return bundleIndex, nil
+}
+
+// VerifyBundleIndex verifies that the OCI image layout contains exactly the images and files of its index,
+// and that the checksum of each file matches the index. The verified index is returned.
+func VerifyBundleIndex(layoutPath string) (BundleIndex, error) {
+	contents, err := ioutil.ReadFile(filepath.Join(layoutPath, BundleIndexFile))
+	if os.IsNotExist(err) {
+		return BundleIndex{}, ErrBundleIndexNotFound
+	}
+	if err != nil {
+		return BundleIndex{}, fmt.Errorf("read index: %w", err)
+	}
+
+	var bundleIndex BundleIndex
+	if err := json.Unmarshal(contents, &bundleIndex); err != nil {
+		return BundleIndex{}, fmt.Errorf("unmarshal index: %w", err)
+	}
+
+	if bundleIndex.Version > bundleIndexVersion {
+		return BundleIndex{}, fmt.Errorf("index version %v is not supported, upgrade sinker to load this bundle", bundleIndex.Version)
+	}
+
+	bundleFiles, err := getBundleFiles(layoutPath)
+	if err != nil {
+		return BundleIndex{}, fmt.Errorf("get files: %w", err)
+	}
+
+	actualFiles := make(map[string]BundleFile)
+	for _, bundleFile := range bundleFiles {
+		actualFiles[bundleFile.Path] = bundleFile
+	}
+
+	for _, expected := range bundleIndex.Files {
+		actual, ok := actualFiles[expected.Path]
+		if !ok {
+			return BundleIndex{}, fmt.Errorf("file %s is missing", expected.Path)
+		}
+
+		if actual.Size != expected.Size || actual.SHA256 != expected.SHA256 {
+			return BundleIndex{}, fmt.Errorf("checksum of file %s does not match the index", expected.Path)
+		}
+
+		delete(actualFiles, expected.Path)
+	}
+
+	for _, bundleFile := range bundleFiles {
+		if _, ok := actualFiles[bundleFile.Path]; ok {
+			return BundleIndex{}, fmt.Errorf("file %s is not in the index", bundleFile.Path)
+		}
+	}
+
+	// The layout was verified by the checksums of its files, but its images are compared
+	// as well, so that an index that was written for a different layout is not trusted.
+	bundleImages, err := getBundleImages(layoutPath)
+	if err != nil {
+		return BundleIndex{}, fmt.Errorf("get images: %w", err)
+	}
+
+	if len(bundleImages) != len(bundleIndex.Images) {
+		return BundleIndex{}, fmt.Errorf("layout has %v image(s), but the index has %v", len(bundleImages), len(bundleIndex.Images))
+	}
+
+	for i := range bundleImages {
+		if bundleImages[i] != bundleIndex.Images[i] {
+			return BundleIndex{}, fmt.Errorf("image %s@%s is not in the index", bundleImages[i].Name, bundleImages[i].Digest)
+		}
+	}
+
+	return bundleIndex, nil
+}
+
+// getBundleImages returns the name and digest of each image in the OCI image layout.
+func getBundleImages(layoutPath string) ([]BundleImage, error) {
+	layoutIndex, err := layout.ImageIndexFromPath(layoutPath)
+	if err != nil {
+		return nil, fmt.Errorf("get layout index: %w", err)
+	}
+
+	indexManifest, err := layoutIndex.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("get index manifest: %w", err)
+	}
+
+	var bundleImages []BundleImage
+	for _, descriptor := range indexManifest.Manifests {
+		bundleImage := BundleImage{
+			Name:   descriptor.Annotations[imageNameAnnotation],
+			Digest: descriptor.Digest.String(),
+		}
+
+		bundleImages = append(bundleImages, bundleImage)
+	}
+
+	return bundleImages, nil
+}
+
+// getBundleFiles returns the size and checksum of each file in the OCI image layout, other than the index.
+func getBundleFiles(layoutPath string) ([]BundleFile, error) {
+	var bundleFiles []BundleFile
+	err := filepath.Walk(layoutPath, func(currentPath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if fileInfo.IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(layoutPath, currentPath)
+		if err != nil {
+			return fmt.Errorf("relative path: %w", err)
+		}
+
+		relativePath = filepath.ToSlash(relativePath)
+		if relativePath == BundleIndexFile {
+			return nil
+		}
+
+		checksum, err := getFileChecksum(currentPath)
+		if err != nil {
+			return fmt.Errorf("get checksum of %s: %w", relativePath, err)
+		}
+
+		bundleFile := BundleFile{
+			Path:   relativePath,
+			Size:   fileInfo.Size(),
+			SHA256: checksum,
+		}
+
+		bundleFiles = append(bundleFiles, bundleFile)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return bundleFiles, nil
+}
+
+func getFileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
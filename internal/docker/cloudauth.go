@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+)
+
+// The credential helpers that are built into sinker.
+const (
+	HelperECR = "ecr"
+	HelperGCR = "gcr"
+	HelperACR = "acr"
+)
+
+// cloudAuthTimeout is how long getting the credentials for a registry from a cloud provider may take.
+const cloudAuthTimeout = 30 * time.Second
+
+// cloudAuthRefresh is how long before they expire that cached credentials are refreshed.
+const cloudAuthRefresh = 5 * time.Minute
+
+// cloudHTTPClient is the client that requests the credentials from the cloud providers.
+var cloudHTTPClient = &http.Client{Timeout: cloudAuthTimeout}
+
+// cloudCredentials are the credentials to a registry that were issued by a cloud provider.
+type cloudCredentials struct {
+	username string
+	password string
+	expires  time.Time
+}
+
+// cloudAuthCache caches the credentials of each registry, as the same registry is authenticated
+// to for every image and the cloud providers issue credentials that are valid for hours.
+var cloudAuthCache = struct {
+	sync.Mutex
+	entries map[string]cloudCredentials
+}{
+	entries: make(map[string]cloudCredentials),
+}
+
+// GetCloudHelper returns the built-in credential helper for the registry host, or
+// an empty string when the host is not a registry of a supported cloud provider.
+func GetCloudHelper(host string) string {
+	switch {
+	case isECRHost(host):
+		return HelperECR
+	case isGCRHost(host):
+		return HelperGCR
+	case isACRHost(host):
+		return HelperACR
+	}
+
+	return ""
+}
+
+// GetEncodedAuthFromHelper returns a Base64 encoded auth for the registry host using the built-in
+// credential helper of a cloud provider (ecr, gcr or acr), so that no docker login is needed.
+func GetEncodedAuthFromHelper(helper string, host string) (string, error) {
+	credentials, err := getCloudCredentials(helper, host)
+	if err != nil {
+		return "", fmt.Errorf("get %s credentials: %w", helper, err)
+	}
+
+	auth, err := GetEncodedBasicAuth(credentials.username, credentials.password)
+	if err != nil {
+		return "", fmt.Errorf("get encoded basic auth: %w", err)
+	}
+
+	return auth, nil
+}
+
+func getCloudCredentials(helper string, host string) (cloudCredentials, error) {
+	cloudAuthCache.Lock()
+	defer cloudAuthCache.Unlock()
+
+	key := helper + "/" + host
+	if cached, ok := cloudAuthCache.entries[key]; ok && time.Now().Add(cloudAuthRefresh).Before(cached.expires) {
+		return cached, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cloudAuthTimeout)
+	defer cancel()
+
+	var credentials cloudCredentials
+	var err error
+	switch helper {
+	case HelperECR:
+		credentials, err = getECRCredentials(ctx, host)
+	case HelperGCR:
+		credentials, err = getGCRCredentials(ctx)
+	case HelperACR:
+		credentials, err = getACRCredentials(ctx, host)
+	default:
+		return cloudCredentials{}, fmt.Errorf("unknown credential helper %s (expected %s, %s or %s)", helper, HelperECR, HelperGCR, HelperACR)
+	}
+	if err != nil {
+		return cloudCredentials{}, err
+	}
+
+	cloudAuthCache.entries[key] = credentials
+	return credentials, nil
+}
+
+// isAnonymous returns true when the keychain did not find any credentials for the registry.
+func isAnonymous(auth authn.Authenticator) bool {
+	if auth == authn.Anonymous {
+		return true
+	}
+
+	authConfig, err := auth.Authorization()
+	if err != nil {
+		return false
+	}
+
+	return *authConfig == authn.AuthConfig{}
+}
+
+// postForm sends a form to the URL and decodes the JSON response into the response value.
+func postForm(ctx context.Context, url string, form string, headers map[string]string, response interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(form))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	return doJSON(req, response)
+}
+
+// doJSON sends the request and decodes the JSON response into the response value.
+func doJSON(req *http.Request, response interface{}) error {
+	resp, err := cloudHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
+
+// decodeBasicAuth decodes a Base64 encoded username:password pair.
+func decodeBasicAuth(encoded string) (string, string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", fmt.Errorf("decode: %w", err)
+	}
+
+	usernamePassword := strings.SplitN(string(decoded), ":", 2)
+	if len(usernamePassword) != 2 {
+		return "", "", fmt.Errorf("missing password")
+	}
+
+	return usernamePassword[0], usernamePassword[1], nil
+}
@@ -0,0 +1,203 @@
+package docker
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetCloudHelper(t *testing.T) {
+	testCases := map[string]string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com":     HelperECR,
+		"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn": HelperECR,
+		"gcr.io":                     HelperGCR,
+		"eu.gcr.io":                  HelperGCR,
+		"us-central1-docker.pkg.dev": HelperGCR,
+		"mycompany.azurecr.io":       HelperACR,
+		"quay.io":                    "",
+		"mycompany.com":              "",
+	}
+
+	for host, expected := range testCases {
+		if actual := GetCloudHelper(host); actual != expected {
+			t.Errorf("expected helper %q for %s, actual %q", expected, host, actual)
+		}
+	}
+}
+
+// TestSignV4 uses the example request from the AWS Signature Version 4 documentation.
+func TestSignV4(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	if err != nil {
+		t.Fatal("new request:", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	credentials := awsCredentials{
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+
+	signV4(req, nil, credentials, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if actual := req.Header.Get("Authorization"); actual != expected {
+		t.Errorf("expected authorization %q, actual %q", expected, actual)
+	}
+}
+
+func TestGetECRCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken" {
+			t.Errorf("unexpected target %s", r.Header.Get("X-Amz-Target"))
+		}
+
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKIDEXAMPLE/") {
+			t.Errorf("expected request to be signed, actual authorization %q", r.Header.Get("Authorization"))
+		}
+
+		token := base64.StdEncoding.EncodeToString([]byte("AWS:secret"))
+		w.Write([]byte(`{"authorizationData":[{"authorizationToken":"` + token + `","expiresAt":1.6E9}]}`))
+	}))
+	defer server.Close()
+
+	defaultEndpoint := ecrEndpoint
+	ecrEndpoint = func(region string, domain string) string { return server.URL }
+	defer func() { ecrEndpoint = defaultEndpoint }()
+
+	setEnv(t, "AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	setEnv(t, "AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+
+	credentials, err := getECRCredentials(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com")
+	if err != nil {
+		t.Fatal("get ecr credentials:", err)
+	}
+
+	if credentials.username != "AWS" || credentials.password != "secret" || !credentials.expires.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("unexpected credentials %+v", credentials)
+	}
+}
+
+func TestGetGCPAssertion(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("generate key:", err)
+	}
+
+	encodedKey, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal("marshal key:", err)
+	}
+
+	credentials := gcpCredentialsFile{
+		Type:        "service_account",
+		ClientEmail: "sinker@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: encodedKey})),
+	}
+
+	assertion, err := getGCPAssertion(credentials, gcpTokenURL, time.Unix(1600000000, 0))
+	if err != nil {
+		t.Fatal("get assertion:", err)
+	}
+
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected a JWT with three parts, actual %v", len(parts))
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal("decode signature:", err)
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+		t.Error("verify signature:", err)
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal("decode claims:", err)
+	}
+
+	var actual map[string]interface{}
+	if err := json.Unmarshal(claims, &actual); err != nil {
+		t.Fatal("unmarshal claims:", err)
+	}
+
+	if actual["iss"] != credentials.ClientEmail || actual["aud"] != gcpTokenURL || actual["exp"] != float64(1600003600) {
+		t.Errorf("unexpected claims %v", actual)
+	}
+}
+
+func TestGetACRCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error("parse form:", err)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/tenant/oauth2/v2.0/token":
+			w.Write([]byte(`{"access_token":"aad-token"}`))
+		case "/oauth2/exchange":
+			if r.Form.Get("access_token") != "aad-token" || r.Form.Get("tenant") != "tenant" {
+				t.Errorf("unexpected exchange form %v", r.Form)
+			}
+
+			w.Write([]byte(`{"refresh_token":"acr-token"}`))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal("parse url:", err)
+	}
+
+	defaultLoginURL, defaultScheme := azureLoginURL, acrScheme
+	azureLoginURL, acrScheme = server.URL, "http"
+	defer func() { azureLoginURL, acrScheme = defaultLoginURL, defaultScheme }()
+
+	setEnv(t, "AZURE_CLIENT_ID", "client")
+	setEnv(t, "AZURE_CLIENT_SECRET", "secret")
+	setEnv(t, "AZURE_TENANT_ID", "tenant")
+
+	credentials, err := getACRCredentials(context.Background(), serverURL.Host)
+	if err != nil {
+		t.Fatal("get acr credentials:", err)
+	}
+
+	if credentials.username != acrUsername || credentials.password != "acr-token" {
+		t.Errorf("unexpected credentials %+v", credentials)
+	}
+}
+
+// setEnv sets the environment variable until the test completes.
+func setEnv(t *testing.T, key string, value string) {
+	previous, set := os.LookupEnv(key)
+	os.Setenv(key, value)
+
+	t.Cleanup(func() {
+		if set {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
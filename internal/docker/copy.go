@@ -0,0 +1,310 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/metrics"
+
+	"github.com/avast/retry-go"
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// CopyImageAndWait copies an image from the source registry directly to the target registry.
+// When the source image is a manifest list, all of the images in the manifest list are copied
+// unless a list of platforms (e.g. linux/amd64) is given to restrict the copy to. Layers that
+// were already copied to another repository of the target registry are mounted from there.
+// If an error occurs when copying an image, the copy will be attempted again with an
+// exponentially increasing delay before failing.
+func (c Client) CopyImageAndWait(ctx context.Context, source string, sourceAuth string, target string, targetAuth string, platforms []string) error {
+	copyImage := func() error {
+		if err := c.tryCopyImage(ctx, source, sourceAuth, target, targetAuth, platforms); err != nil {
+			return fmt.Errorf("try copy image: %w", err)
+		}
+
+		return nil
+	}
+
+	return c.copyAndWait(ctx, source, target, copyImage)
+}
+
+// copyAndWait copies the source to the target with the copy function, retrying the copy when it fails.
+// Each attempt waits until the maximum number of concurrent operations of the registries allows it.
+func (c Client) copyAndWait(ctx context.Context, source string, target string, copyFunc func() error) error {
+	start := time.Now()
+
+	copySource := func() error {
+		if err := c.waitForRateLimit(ctx, source); err != nil {
+			return fmt.Errorf("rate limit: %w", err)
+		}
+
+		release, err := c.acquire(ctx, source, target)
+		if err != nil {
+			return fmt.Errorf("acquire registries: %w", err)
+		}
+		defer release()
+
+		return copyFunc()
+	}
+
+	retryFunc := func(attempts uint, err error) {
+		c.logInfo("Unable to copy %v (Retrying #%v)", source, attempts+1)
+	}
+
+	if err := retry.Do(copySource, c.retryOptions(ctx, retryFunc, source, target)...); err != nil {
+		metrics.ImagesFailed.Inc()
+		return fmt.Errorf("retry: %w", err)
+	}
+
+	metrics.ImagesSynced.Inc()
+	metrics.SyncDuration.ObserveDuration(start)
+
+	return nil
+}
+
+// ParsePlatform parses a platform in the form of os/arch[/variant][:os.version] (e.g. linux/arm64/v8). The
+// version of the OS is only used by Windows images (e.g. windows/amd64:10.0.17763.1879), which are built
+// for each version of Windows.
+func ParsePlatform(platform string) (v1.Platform, error) {
+	var osVersion string
+	if separator := strings.Index(platform, ":"); separator != -1 {
+		osVersion = platform[separator+1:]
+		platform = platform[:separator]
+	}
+
+	platformTokens := strings.Split(platform, "/")
+	if len(platformTokens) < 2 || len(platformTokens) > 3 {
+		return v1.Platform{}, fmt.Errorf("invalid platform %s, expected os/arch[/variant][:os.version]", platform)
+	}
+
+	parsedPlatform := v1.Platform{
+		OS:           platformTokens[0],
+		Architecture: platformTokens[1],
+		OSVersion:    osVersion,
+	}
+
+	if len(platformTokens) == 3 {
+		parsedPlatform.Variant = platformTokens[2]
+	}
+
+	return parsedPlatform, nil
+}
+
+func (c Client) tryCopyImage(ctx context.Context, source string, sourceAuth string, target string, targetAuth string, platforms []string) error {
+	sourceReference, err := c.parseReference(source)
+	if err != nil {
+		return fmt.Errorf("parse source ref: %w", err)
+	}
+
+	targetReference, err := c.parseReference(target)
+	if err != nil {
+		return fmt.Errorf("parse target ref: %w", err)
+	}
+
+	sourceAuthenticator, err := getAuthenticator(sourceAuth)
+	if err != nil {
+		return fmt.Errorf("get source authenticator: %w", err)
+	}
+
+	targetAuthenticator, err := getAuthenticator(targetAuth)
+	if err != nil {
+		return fmt.Errorf("get target authenticator: %w", err)
+	}
+
+	descriptor, err := remote.Get(sourceReference, c.remoteOptions(ctx, sourceAuthenticator)...)
+	if err != nil {
+		return fmt.Errorf("get source: %w", err)
+	}
+
+	if !isIndex(descriptor.MediaType) {
+		image, err := descriptor.Image()
+		if err != nil {
+			return fmt.Errorf("get source image: %w", err)
+		}
+
+		if len(platforms) > 0 {
+			imagePlatform, err := getImagePlatform(image)
+			if err != nil {
+				return fmt.Errorf("get image platform: %w", err)
+			}
+
+			if err := c.warnExcludedPlatforms(source, []v1.Platform{imagePlatform}, platforms); err != nil {
+				return fmt.Errorf("check platforms: %w", err)
+			}
+		}
+
+		if len(c.configLabels) > 0 {
+			image, err = labelImage(image, c.configLabels)
+			if err != nil {
+				return fmt.Errorf("label image: %w", err)
+			}
+		}
+
+		if c.mounts != nil {
+			image = mountableImage{Image: image, mounts: c.mounts, target: targetReference.Context()}
+		}
+
+		if c.provenanceVersion != "" {
+			image = annotatedImage{Image: image, annotations: c.getProvenanceAnnotations(source, descriptor.Digest, time.Now())}
+		}
+
+		if err := remote.Write(targetReference, image, c.remoteOptions(ctx, targetAuthenticator)...); err != nil {
+			return fmt.Errorf("write image: %w", err)
+		}
+
+		if c.mounts != nil {
+			if err := c.mounts.recordImage(targetReference.Context(), image); err != nil {
+				return fmt.Errorf("record layers: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	index, err := descriptor.ImageIndex()
+	if err != nil {
+		return fmt.Errorf("get source index: %w", err)
+	}
+
+	if len(platforms) > 0 {
+		imagePlatforms, err := getIndexPlatforms(index)
+		if err != nil {
+			return fmt.Errorf("get index platforms: %w", err)
+		}
+
+		if err := c.warnExcludedPlatforms(source, imagePlatforms, platforms); err != nil {
+			return fmt.Errorf("check platforms: %w", err)
+		}
+
+		index, err = filterIndex(index, platforms)
+		if err != nil {
+			return fmt.Errorf("filter index: %w", err)
+		}
+	}
+
+	if len(c.configLabels) > 0 {
+		index, err = labelIndex(index, c.configLabels)
+		if err != nil {
+			return fmt.Errorf("label index: %w", err)
+		}
+	}
+
+	if c.mounts != nil {
+		index = mountableIndex{index: index, mounts: c.mounts, target: targetReference.Context()}
+	}
+
+	if c.provenanceVersion != "" {
+		index = annotatedIndex{index: index, annotations: c.getProvenanceAnnotations(source, descriptor.Digest, time.Now())}
+	}
+
+	if err := remote.WriteIndex(targetReference, index, c.remoteOptions(ctx, targetAuthenticator)...); err != nil {
+		return fmt.Errorf("write index: %w", err)
+	}
+
+	if c.mounts != nil {
+		if err := c.mounts.recordIndex(targetReference.Context(), index); err != nil {
+			return fmt.Errorf("record layers: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// filterIndex returns the index with only the images of the given platforms. When none of the images of the
+// index are of the given platforms, such as a Windows-only image when only linux/amd64 is given, the index is
+// returned as is, as filtering it would leave nothing to copy.
+func filterIndex(index v1.ImageIndex, platforms []string) (v1.ImageIndex, error) {
+	requiredPlatforms, err := parsePlatforms(platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("get index manifest: %w", err)
+	}
+
+	mediaType, err := index.MediaType()
+	if err != nil {
+		return nil, fmt.Errorf("get media type: %w", err)
+	}
+
+	var addendums []mutate.IndexAddendum
+	for _, manifest := range indexManifest.Manifests {
+		if manifest.Platform == nil || !platformsContain(requiredPlatforms, *manifest.Platform) {
+			continue
+		}
+
+		image, err := index.Image(manifest.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("get image %s: %w", manifest.Digest, err)
+		}
+
+		addendum := mutate.IndexAddendum{
+			Add:        image,
+			Descriptor: manifest,
+		}
+
+		addendums = append(addendums, addendum)
+	}
+
+	if len(addendums) == 0 {
+		return index, nil
+	}
+
+	filteredIndex := mutate.AppendManifests(empty.Index, addendums...)
+	filteredIndex = mutate.IndexMediaType(filteredIndex, mediaType)
+
+	return filteredIndex, nil
+}
+
+func isIndex(mediaType types.MediaType) bool {
+	return mediaType == types.OCIImageIndex || mediaType == types.DockerManifestList
+}
+
+func platformsContain(platforms []v1.Platform, platform v1.Platform) bool {
+	for _, currentPlatform := range platforms {
+		if currentPlatform.OS != platform.OS || currentPlatform.Architecture != platform.Architecture {
+			continue
+		}
+
+		if currentPlatform.Variant != "" && currentPlatform.Variant != platform.Variant {
+			continue
+		}
+
+		if currentPlatform.OSVersion != "" && currentPlatform.OSVersion != platform.OSVersion {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func getAuthenticator(encodedAuth string) (authn.Authenticator, error) {
+	if encodedAuth == "" {
+		return authn.Anonymous, nil
+	}
+
+	jsonAuth, err := base64.URLEncoding.DecodeString(encodedAuth)
+	if err != nil {
+		return nil, fmt.Errorf("decode auth: %w", err)
+	}
+
+	var authConfig authn.AuthConfig
+	if err := json.Unmarshal(jsonAuth, &authConfig); err != nil {
+		return nil, fmt.Errorf("unmarshal auth: %w", err)
+	}
+
+	return authn.FromConfig(authConfig), nil
+}
@@ -0,0 +1,185 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestCopyImageAndWait_Platforms(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	var addendums []mutate.IndexAddendum
+	for _, platform := range []string{"linux/amd64", "linux/arm64/v8"} {
+		image, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		parsedPlatform, err := ParsePlatform(platform)
+		if err != nil {
+			t.Fatal("parse platform:", err)
+		}
+
+		addendum := mutate.IndexAddendum{
+			Add: image,
+			Descriptor: v1.Descriptor{
+				Platform: &parsedPlatform,
+			},
+		}
+
+		addendums = append(addendums, addendum)
+	}
+
+	index := mutate.AppendManifests(empty.Index, addendums...)
+
+	source := host + "/source:v1.0.0"
+	sourceReference, err := name.ParseReference(source, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.WriteIndex(sourceReference, index); err != nil {
+		t.Fatal("write index:", err)
+	}
+
+	client := Client{
+		logInfo: t.Logf,
+	}
+
+	target := host + "/target:v1.0.0"
+	if err := client.CopyImageAndWait(context.Background(), source, "", target, "", []string{"linux/arm64"}); err != nil {
+		t.Fatal("copy image:", err)
+	}
+
+	targetReference, err := name.ParseReference(target, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	targetIndex, err := remote.Index(targetReference)
+	if err != nil {
+		t.Fatal("get target index:", err)
+	}
+
+	indexManifest, err := targetIndex.IndexManifest()
+	if err != nil {
+		t.Fatal("get index manifest:", err)
+	}
+
+	if len(indexManifest.Manifests) != 1 {
+		t.Fatalf("expected 1 manifest in target index, actual %v", len(indexManifest.Manifests))
+	}
+
+	if indexManifest.Manifests[0].Platform.Architecture != "arm64" {
+		t.Errorf("expected arm64 manifest, actual %s", indexManifest.Manifests[0].Platform.Architecture)
+	}
+}
+
+func TestCopyImageAndWait_WindowsPlatforms(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	var addendums []mutate.IndexAddendum
+	for _, platform := range []string{"windows/amd64:10.0.17763.1879", "windows/amd64:10.0.20348.2113"} {
+		image, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		parsedPlatform, err := ParsePlatform(platform)
+		if err != nil {
+			t.Fatal("parse platform:", err)
+		}
+
+		addendums = append(addendums, mutate.IndexAddendum{
+			Add: image,
+			Descriptor: v1.Descriptor{
+				Platform: &parsedPlatform,
+			},
+		})
+	}
+
+	source := host + "/nanoserver:v1.0.0"
+	sourceReference, err := name.ParseReference(source, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.WriteIndex(sourceReference, mutate.AppendManifests(empty.Index, addendums...)); err != nil {
+		t.Fatal("write index:", err)
+	}
+
+	var warnings []string
+	client := Client{
+		logInfo: func(format string, args ...interface{}) {
+			warnings = append(warnings, fmt.Sprintf(format, args...))
+		},
+	}
+
+	testCases := []struct {
+		platforms        []string
+		expectedVersions []string
+		expectedWarnings int
+	}{
+		{
+			platforms:        []string{"windows/amd64:10.0.20348.2113"},
+			expectedVersions: []string{"10.0.20348.2113"},
+		},
+		{
+			platforms:        []string{"linux/amd64", "windows/amd64"},
+			expectedVersions: []string{"10.0.17763.1879", "10.0.20348.2113"},
+		},
+		{
+			platforms:        []string{"linux/amd64"},
+			expectedVersions: []string{"10.0.17763.1879", "10.0.20348.2113"},
+			expectedWarnings: 1,
+		},
+	}
+
+	for i, testCase := range testCases {
+		warnings = nil
+
+		target := fmt.Sprintf("%s/target:v%v", host, i)
+		if err := client.CopyImageAndWait(context.Background(), source, "", target, "", testCase.platforms); err != nil {
+			t.Fatal("copy image:", err)
+		}
+
+		targetReference, err := name.ParseReference(target, name.WeakValidation)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		targetIndex, err := remote.Index(targetReference)
+		if err != nil {
+			t.Fatal("get target index:", err)
+		}
+
+		indexManifest, err := targetIndex.IndexManifest()
+		if err != nil {
+			t.Fatal("get index manifest:", err)
+		}
+
+		var actualVersions []string
+		for _, manifest := range indexManifest.Manifests {
+			actualVersions = append(actualVersions, manifest.Platform.OSVersion)
+		}
+
+		if !reflect.DeepEqual(actualVersions, testCase.expectedVersions) {
+			t.Errorf("platforms %v: expected versions %v, actual %v", testCase.platforms, testCase.expectedVersions, actualVersions)
+		}
+
+		if len(warnings) != testCase.expectedWarnings {
+			t.Errorf("platforms %v: expected %v warning(s), actual %q", testCase.platforms, testCase.expectedWarnings, warnings)
+		}
+	}
+}
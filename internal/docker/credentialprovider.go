@@ -0,0 +1,298 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The credential providers that are built into sinker.
+const (
+	ProviderVault             = "vault"
+	ProviderAWSSecretsManager = "aws-secrets-manager"
+)
+
+// providerCacheTTL is how long the credentials fetched from a credential provider are cached for, so that
+// the secret manager is not queried for every image, while long-running commands pick up rotated credentials.
+const providerCacheTTL = 15 * time.Minute
+
+// CredentialProvider fetches the credentials to registries from a secret manager at runtime, so that
+// they do not need to be stored in CI variables or the Docker config file.
+type CredentialProvider interface {
+
+	// GetCredentials returns the username and password stored in the secret (e.g. the path of a Vault secret).
+	GetCredentials(ctx context.Context, secret string) (string, string, error)
+}
+
+// credentialProviders are the credential providers by name. Credential providers that are not
+// registered are run as Docker credential helpers (e.g. pass runs docker-credential-pass).
+var credentialProviders = struct {
+	sync.Mutex
+	providers map[string]CredentialProvider
+}{
+	providers: map[string]CredentialProvider{
+		ProviderVault:             vaultProvider{},
+		ProviderAWSSecretsManager: awsSecretsManagerProvider{},
+	},
+}
+
+// providerAuthCache caches the credentials of each secret of each provider.
+var providerAuthCache = struct {
+	sync.Mutex
+	entries map[string]cloudCredentials
+}{
+	entries: make(map[string]cloudCredentials),
+}
+
+// RegisterCredentialProvider registers the credential provider under the name, which the auth
+// of the manifest refers to with its provider, replacing any provider with the same name.
+func RegisterCredentialProvider(name string, provider CredentialProvider) {
+	credentialProviders.Lock()
+	defer credentialProviders.Unlock()
+
+	credentialProviders.providers[name] = provider
+}
+
+// GetEncodedAuthFromProvider returns a Base64 encoded auth with the credentials in the secret of the credential provider.
+func GetEncodedAuthFromProvider(provider string, secret string) (string, error) {
+	credentials, err := getProviderCredentials(provider, secret)
+	if err != nil {
+		return "", fmt.Errorf("get %s credentials: %w", provider, err)
+	}
+
+	auth, err := GetEncodedBasicAuth(credentials.username, credentials.password)
+	if err != nil {
+		return "", fmt.Errorf("get encoded basic auth: %w", err)
+	}
+
+	return auth, nil
+}
+
+func getProviderCredentials(provider string, secret string) (cloudCredentials, error) {
+	providerAuthCache.Lock()
+	defer providerAuthCache.Unlock()
+
+	key := provider + "/" + secret
+	if cached, ok := providerAuthCache.entries[key]; ok && time.Now().Before(cached.expires) {
+		return cached, nil
+	}
+
+	credentialProviders.Lock()
+	credentialProvider, ok := credentialProviders.providers[provider]
+	credentialProviders.Unlock()
+	if !ok {
+		credentialProvider = credentialHelperProvider{helper: provider}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cloudAuthTimeout)
+	defer cancel()
+
+	username, password, err := credentialProvider.GetCredentials(ctx, secret)
+	if err != nil {
+		return cloudCredentials{}, err
+	}
+
+	credentials := cloudCredentials{
+		username: username,
+		password: password,
+		expires:  time.Now().Add(providerCacheTTL),
+	}
+
+	providerAuthCache.entries[key] = credentials
+	return credentials, nil
+}
+
+// vaultProvider reads the username and password keys of a secret of the KV secrets engine (version 1 or 2) of
+// HashiCorp Vault, at the path of the secret (e.g. secret/data/registries/quay). The address of Vault is read
+// from VAULT_ADDR, and its token from VAULT_TOKEN or the token helper file (~/.vault-token) of the Vault CLI.
+type vaultProvider struct{}
+
+func (vaultProvider) GetCredentials(ctx context.Context, secret string) (string, string, error) {
+	address := os.Getenv("VAULT_ADDR")
+	if address == "" {
+		return "", "", errors.New("VAULT_ADDR is not set")
+	}
+
+	token, err := getVaultToken()
+	if err != nil {
+		return "", "", fmt.Errorf("get vault token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(address, "/")+"/v1/"+strings.TrimPrefix(secret, "/"), nil)
+	if err != nil {
+		return "", "", fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("X-Vault-Token", token)
+	if namespace := os.Getenv("VAULT_NAMESPACE"); namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := doJSON(req, &response); err != nil {
+		return "", "", fmt.Errorf("read secret %s: %w", secret, err)
+	}
+
+	// The data of a secret of version 2 of the KV secrets engine is nested in its metadata.
+	data := response.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	username, _ := data["username"].(string)
+	password, _ := data["password"].(string)
+	if username == "" || password == "" {
+		return "", "", fmt.Errorf("secret %s does not have a username and password", secret)
+	}
+
+	return username, password, nil
+}
+
+func getVaultToken() (string, error) {
+	if token := os.Getenv("VAULT_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.New("VAULT_TOKEN is not set")
+	}
+
+	token, err := ioutil.ReadFile(filepath.Join(home, ".vault-token"))
+	if os.IsNotExist(err) {
+		return "", errors.New("VAULT_TOKEN is not set and the Vault CLI is not logged in")
+	}
+	if err != nil {
+		return "", fmt.Errorf("read token file: %w", err)
+	}
+
+	return strings.TrimSpace(string(token)), nil
+}
+
+// secretsManagerEndpoint returns the endpoint of the Secrets Manager API in the region.
+var secretsManagerEndpoint = func(region string) string {
+	return fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", region)
+}
+
+// awsSecretsManagerProvider reads the username and password keys of the JSON string of a secret of AWS
+// Secrets Manager, by its name or ARN. The secret is read in the region of its ARN, or the region of the
+// environment (AWS_REGION or AWS_DEFAULT_REGION), with the same AWS credentials as the ecr helper.
+type awsSecretsManagerProvider struct{}
+
+func (awsSecretsManagerProvider) GetCredentials(ctx context.Context, secret string) (string, string, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	// The ARN of a secret is arn:aws:secretsmanager:<region>:<account>:secret:<name>.
+	if arn := strings.Split(secret, ":"); len(arn) > 3 && arn[0] == "arn" {
+		region = arn[3]
+	}
+
+	if region == "" {
+		return "", "", errors.New("secret is not an ARN and AWS_REGION is not set")
+	}
+
+	credentials, found, err := getAWSCredentials()
+	if err != nil {
+		return "", "", fmt.Errorf("get aws credentials: %w", err)
+	}
+
+	var secretString string
+	if found {
+		secretString, err = getSecretValue(ctx, credentials, region, secret)
+		if err != nil {
+			return "", "", fmt.Errorf("get secret value: %w", err)
+		}
+	} else {
+		output, err := exec.CommandContext(ctx, "aws", "secretsmanager", "get-secret-value", "--secret-id", secret, "--region", region, "--query", "SecretString", "--output", "text").Output()
+		if err != nil {
+			return "", "", fmt.Errorf("aws secretsmanager get-secret-value: %w", err)
+		}
+
+		secretString = strings.TrimSpace(string(output))
+	}
+
+	var values struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal([]byte(secretString), &values); err != nil {
+		return "", "", fmt.Errorf("unmarshal secret %s: %w", secret, err)
+	}
+
+	if values.Username == "" || values.Password == "" {
+		return "", "", fmt.Errorf("secret %s does not have a username and password", secret)
+	}
+
+	return values.Username, values.Password, nil
+}
+
+// getSecretValue returns the string of the secret from the Secrets Manager API.
+func getSecretValue(ctx context.Context, credentials awsCredentials, region string, secret string) (string, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": secret})
+	if err != nil {
+		return "", fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, secretsManagerEndpoint(region)+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signV4(req, body, credentials, region, "secretsmanager", time.Now())
+
+	var response struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := doJSON(req, &response); err != nil {
+		return "", err
+	}
+
+	return response.SecretString, nil
+}
+
+// credentialHelperProvider runs a Docker credential helper (docker-credential-<helper>) with the secret, such as
+// the host of the registry, in the same way as Docker does. This allows any secret manager that has a Docker
+// credential helper to be used as a credential provider.
+type credentialHelperProvider struct {
+	helper string
+}
+
+func (p credentialHelperProvider) GetCredentials(ctx context.Context, secret string) (string, string, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "docker-credential-"+p.helper, "get")
+	cmd.Stdin = strings.NewReader(secret)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("docker-credential-%s: %w: %s", p.helper, err, strings.TrimSpace(stdout.String()+stderr.String()))
+	}
+
+	var credentials struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &credentials); err != nil {
+		return "", "", fmt.Errorf("unmarshal credentials: %w", err)
+	}
+
+	return credentials.Username, credentials.Secret, nil
+}
@@ -0,0 +1,121 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/quay":
+			w.Write([]byte(`{"data":{"data":{"username":"user","password":"pass"},"metadata":{"version":1}}}`))
+		case "/v1/kv/quay":
+			w.Write([]byte(`{"data":{"username":"user","password":"pass"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	setEnv(t, "VAULT_ADDR", server.URL)
+	setEnv(t, "VAULT_TOKEN", "token")
+
+	for _, secret := range []string{"secret/data/quay", "kv/quay"} {
+		username, password, err := vaultProvider{}.GetCredentials(context.Background(), secret)
+		if err != nil {
+			t.Fatalf("get credentials of %s: %v", secret, err)
+		}
+
+		if username != "user" || password != "pass" {
+			t.Errorf("unexpected credentials of %s: %s %s", secret, username, password)
+		}
+	}
+
+	if _, _, err := (vaultProvider{}).GetCredentials(context.Background(), "secret/data/missing"); err == nil {
+		t.Error("expected error for missing secret")
+	}
+}
+
+func TestAWSSecretsManagerProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			t.Errorf("unexpected target %s", r.Header.Get("X-Amz-Target"))
+		}
+
+		if !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/secretsmanager/aws4_request") {
+			t.Errorf("expected request to be signed for eu-west-1, actual authorization %q", r.Header.Get("Authorization"))
+		}
+
+		var request struct {
+			SecretId string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatal("decode request:", err)
+		}
+
+		response, _ := json.Marshal(map[string]string{"SecretString": `{"username":"user","password":"pass"}`})
+		w.Write(response)
+	}))
+	defer server.Close()
+
+	defaultEndpoint := secretsManagerEndpoint
+	secretsManagerEndpoint = func(region string) string { return server.URL }
+	defer func() { secretsManagerEndpoint = defaultEndpoint }()
+
+	setEnv(t, "AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	setEnv(t, "AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+	setEnv(t, "AWS_REGION", "us-east-1")
+
+	username, password, err := awsSecretsManagerProvider{}.GetCredentials(context.Background(), "arn:aws:secretsmanager:eu-west-1:123456789012:secret:quay")
+	if err != nil {
+		t.Fatal("get credentials:", err)
+	}
+
+	if username != "user" || password != "pass" {
+		t.Errorf("unexpected credentials %s %s", username, password)
+	}
+}
+
+type staticProvider struct {
+	calls int
+}
+
+func (p *staticProvider) GetCredentials(ctx context.Context, secret string) (string, string, error) {
+	p.calls++
+	return "user", secret, nil
+}
+
+func TestGetEncodedAuthFromProvider(t *testing.T) {
+	provider := &staticProvider{}
+	RegisterCredentialProvider("static", provider)
+
+	for i := 0; i < 2; i++ {
+		auth, err := GetEncodedAuthFromProvider("static", "pass")
+		if err != nil {
+			t.Fatal("get encoded auth from provider:", err)
+		}
+
+		expected, err := GetEncodedBasicAuth("user", "pass")
+		if err != nil {
+			t.Fatal("get encoded basic auth:", err)
+		}
+
+		if auth != expected {
+			t.Errorf("expected auth %s, actual %s", expected, auth)
+		}
+	}
+
+	if provider.calls != 1 {
+		t.Errorf("expected credentials to be cached, provider was called %v times", provider.calls)
+	}
+}
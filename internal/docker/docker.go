@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -12,20 +13,30 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/google/go-containerregistry/pkg/authn"
-	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 )
 
 // Client manages the communication with the Docker client.
 type Client struct {
-	docker  *client.Client
-	logInfo func(format string, args ...interface{})
+	docker      *client.Client
+	logInfo     func(format string, args ...interface{})
+	attempts    uint
+	rateLimiter *RateLimiter
+	mounts      *blobMounts
+	transport   http.RoundTripper
+	insecure    map[string]bool
+	limits      registryLimits
+
+	bandwidthLimiter  *BandwidthLimiter
+	provenanceVersion string
+	configLabels      map[string]string
 }
 
 // NewClient returns a Docker client configured with the given information logger.
 func NewClient(logInfo func(format string, args ...interface{})) (Client, error) {
 	retry.DefaultDelay = 5 * time.Second
+	retry.DefaultMaxJitter = time.Second
 	retry.DefaultAttempts = 2
 
 	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
@@ -36,11 +47,59 @@ func NewClient(logInfo func(format string, args ...interface{})) (Client, error)
 	client := Client{
 		docker:  dockerClient,
 		logInfo: logInfo,
+		mounts:  newBlobMounts(),
 	}
 
 	return client, nil
 }
 
+// WithRetries returns a copy of the client that attempts failed registry operations
+// the given number of times again before failing. The delay between each attempt
+// increases exponentially.
+func (c Client) WithRetries(retries int) Client {
+	if retries < 0 {
+		retries = 0
+	}
+
+	c.attempts = uint(retries) + 1
+	return c
+}
+
+// WithRateLimiter returns a copy of the client that waits for the rate limiter before pulling an image.
+func (c Client) WithRateLimiter(rateLimiter *RateLimiter) Client {
+	c.rateLimiter = rateLimiter
+	return c
+}
+
+func (c Client) waitForRateLimit(ctx context.Context, image string) error {
+	if c.rateLimiter == nil {
+		return nil
+	}
+
+	if err := c.rateLimiter.Wait(ctx, image); err != nil {
+		return fmt.Errorf("wait: %w", err)
+	}
+
+	return nil
+}
+
+// retryOptions returns the options used when retrying an operation on the given images.
+// Operations are not retried once the context has been cancelled.
+func (c Client) retryOptions(ctx context.Context, onRetry retry.OnRetryFunc, images ...string) []retry.Option {
+	retryIf := func(err error) bool {
+		return ctx.Err() == nil
+	}
+
+	opts := []retry.Option{retry.OnRetry(onRetry), retry.RetryIf(retryIf)}
+	if attempts, ok := c.getAttempts(images...); ok {
+		opts = append(opts, retry.Attempts(attempts))
+	} else if c.attempts > 0 {
+		opts = append(opts, retry.Attempts(c.attempts))
+	}
+
+	return opts
+}
+
 // PushImageAndWait pushes an image and waits for it to finish pushing.
 // If an error occurs when pushing an image, the push will be attempted again before failing.
 func (c Client) PushImageAndWait(ctx context.Context, image string, auth string) error {
@@ -56,7 +115,7 @@ func (c Client) PushImageAndWait(ctx context.Context, image string, auth string)
 		c.logInfo("Unable to push %v (Retrying #%v)", image, attempts+1)
 	}
 
-	if err := retry.Do(push, retry.OnRetry(retryFunc)); err != nil {
+	if err := retry.Do(push, c.retryOptions(ctx, retryFunc)...); err != nil {
 		return fmt.Errorf("retry: %w", err)
 	}
 
@@ -67,6 +126,10 @@ func (c Client) PushImageAndWait(ctx context.Context, image string, auth string)
 // If an error occurs when pulling an image, the pull will be attempted again before failing.
 func (c Client) PullImageAndWait(ctx context.Context, image string, auth string) error {
 	pull := func() error {
+		if err := c.waitForRateLimit(ctx, image); err != nil {
+			return fmt.Errorf("rate limit: %w", err)
+		}
+
 		if err := c.tryPullImageAndWait(ctx, image, auth); err != nil {
 			return fmt.Errorf("try pull image: %w", err)
 		}
@@ -78,7 +141,7 @@ func (c Client) PullImageAndWait(ctx context.Context, image string, auth string)
 		c.logInfo("Unable to pull %v (Retrying #%v)", image, attempts+1)
 	}
 
-	if err := retry.Do(pull, retry.OnRetry(retryFunc)); err != nil {
+	if err := retry.Do(pull, c.retryOptions(ctx, retryFunc)...); err != nil {
 		return fmt.Errorf("retry: %w", err)
 	}
 
@@ -94,6 +157,7 @@ func (c Client) ImageExistsOnHost(ctx context.Context, image string) (bool, erro
 	var images []string
 	var err error
 	if strings.Contains(image, "@") {
+		image = withoutTag(image)
 		images, err = c.GetAllDigestsOnHost(ctx)
 	} else {
 		images, err = c.GetAllImagesOnHost(ctx)
@@ -146,12 +210,12 @@ func (c Client) GetTagsForRepository(ctx context.Context, host string, repositor
 		repoPath = host + "/" + repository
 	}
 
-	repo, err := name.NewRepository(repoPath)
+	repo, err := c.newRepository(repoPath)
 	if err != nil {
 		return nil, fmt.Errorf("new repo: %w", err)
 	}
 
-	tags, err := remote.ListWithContext(ctx, repo, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	tags, err := remote.ListWithContext(ctx, repo, remote.WithAuthFromKeychain(authn.DefaultKeychain), remote.WithTransport(contextTransport{ctx: ctx, inner: c.newTransport()}))
 	if err != nil {
 		return nil, fmt.Errorf("list: %w", err)
 	}
@@ -169,23 +233,46 @@ func (c Client) Tag(ctx context.Context, sourceImage string, targetImage string)
 }
 
 // ImageExistsAtRemote returns true if the image exists at the remote registry.
-func (c Client) ImageExistsAtRemote(ctx context.Context, image string) (bool, error) {
+// Images that use the latest tag are always considered to not exist so that they are kept up to date.
+func (c Client) ImageExistsAtRemote(ctx context.Context, image string, auth string) (bool, error) {
 	if hasLatestTag(image) {
 		return false, nil
 	}
 
-	reference, err := name.ParseReference(image, name.WeakValidation)
+	exists, err := c.ManifestExistsAtRemote(ctx, image, auth)
+	if err != nil {
+		return false, fmt.Errorf("manifest exists: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ManifestExistsAtRemote returns true if the manifest of the image exists at the remote registry,
+// regardless of which tag the image uses.
+func (c Client) ManifestExistsAtRemote(ctx context.Context, image string, auth string) (bool, error) {
+	reference, err := c.parseReference(image)
 	if err != nil {
 		return false, fmt.Errorf("parse ref: %w", err)
 	}
 
-	if _, err := remote.Get(reference, remote.WithAuthFromKeychain(authn.DefaultKeychain)); err != nil {
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return false, fmt.Errorf("get authenticator: %w", err)
+	}
 
-		// If the error is a transport error, check that the error code is of type MANIFEST_UNKNOWN.
-		// This is the expected error if an image does not exist.
+	release, err := c.acquire(ctx, image)
+	if err != nil {
+		return false, fmt.Errorf("acquire registry: %w", err)
+	}
+	defer release()
+
+	if _, err := remote.Get(reference, c.remoteOptions(ctx, authenticator)...); err != nil {
+
+		// If the error is a transport error, check that the error code is of type MANIFEST_UNKNOWN
+		// or NAME_UNKNOWN. These are the expected errors if an image (or its repository) does not exist.
 		if t, exists := err.(*transport.Error); exists {
 			for _, diagnostic := range t.Errors {
-				if strings.EqualFold("MANIFEST_UNKNOWN", string(diagnostic.Code)) {
+				if strings.EqualFold("MANIFEST_UNKNOWN", string(diagnostic.Code)) || strings.EqualFold("NAME_UNKNOWN", string(diagnostic.Code)) {
 					return false, nil
 				}
 			}
@@ -199,6 +286,32 @@ func (c Client) ImageExistsAtRemote(ctx context.Context, image string) (bool, er
 	return true, nil
 }
 
+// GetDigest returns the digest of the image at the remote registry.
+func (c Client) GetDigest(ctx context.Context, image string, auth string) (string, error) {
+	reference, err := c.parseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return "", fmt.Errorf("get authenticator: %w", err)
+	}
+
+	release, err := c.acquire(ctx, image)
+	if err != nil {
+		return "", fmt.Errorf("acquire registry: %w", err)
+	}
+	defer release()
+
+	descriptor, err := remote.Get(reference, c.remoteOptions(ctx, authenticator)...)
+	if err != nil {
+		return "", fmt.Errorf("get image: %w", err)
+	}
+
+	return descriptor.Digest.String(), nil
+}
+
 type progressDetail struct {
 	Current int `json:"current"`
 	Total   int `json:"total"`
@@ -300,6 +413,22 @@ func imageExists(image string, images []string) bool {
 	return false
 }
 
+// withoutTag removes the tag from an image that is referenced by both its tag
+// and its digest (e.g. busybox:1.32.0@sha256:... becomes busybox@sha256:...).
+func withoutTag(image string) string {
+	digestTokens := strings.SplitN(image, "@", 2)
+	if len(digestTokens) != 2 {
+		return image
+	}
+
+	name := digestTokens[0]
+	if tagIndex := strings.LastIndex(name, ":"); tagIndex > strings.LastIndex(name, "/") {
+		name = name[:tagIndex]
+	}
+
+	return name + "@" + digestTokens[1]
+}
+
 func hasLatestTag(image string) bool {
 	if strings.Contains(image, ":latest") || !strings.Contains(image, ":") {
 		return true
@@ -23,3 +23,22 @@ func TestImageExists_DockerIO_WithLibrary(t *testing.T) {
 		t.Errorf("expected docker.io address to exist, but it did not.")
 	}
 }
+
+func TestWithoutTag(t *testing.T) {
+	testCases := []struct {
+		image    string
+		expected string
+	}{
+		{"busybox:1.32.0@sha256:123", "busybox@sha256:123"},
+		{"localhost:5000/busybox@sha256:123", "localhost:5000/busybox@sha256:123"},
+		{"localhost:5000/busybox:1.32.0@sha256:123", "localhost:5000/busybox@sha256:123"},
+		{"busybox:1.32.0", "busybox:1.32.0"},
+	}
+
+	for _, testCase := range testCases {
+		actual := withoutTag(testCase.image)
+		if actual != testCase.expected {
+			t.Errorf("expected image %s, actual %s", testCase.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ecrHostPattern matches the hosts of ECR registries (e.g. 123456789012.dkr.ecr.us-east-1.amazonaws.com).
+var ecrHostPattern = regexp.MustCompile(`^\d{12}\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.(amazonaws\.com(?:\.cn)?)$`)
+
+// ecrEndpoint returns the endpoint of the ECR API in the region.
+var ecrEndpoint = func(region string, domain string) string {
+	return fmt.Sprintf("https://api.ecr.%s.%s", region, domain)
+}
+
+// awsCredentials are the credentials of an AWS identity.
+type awsCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+func isECRHost(host string) bool {
+	return ecrHostPattern.MatchString(host)
+}
+
+// getECRCredentials exchanges the AWS credentials for an ECR authorization token. The credentials are read from the
+// environment (AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY) or the shared credentials file. When neither contains
+// credentials (e.g. when using an instance role or SSO), the token is requested with the AWS CLI instead.
+func getECRCredentials(ctx context.Context, host string) (cloudCredentials, error) {
+	region, domain, err := getECRRegion(host)
+	if err != nil {
+		return cloudCredentials{}, fmt.Errorf("get region: %w", err)
+	}
+
+	credentials, found, err := getAWSCredentials()
+	if err != nil {
+		return cloudCredentials{}, fmt.Errorf("get aws credentials: %w", err)
+	}
+
+	if !found {
+		password, err := exec.CommandContext(ctx, "aws", "ecr", "get-login-password", "--region", region).Output()
+		if err != nil {
+			return cloudCredentials{}, fmt.Errorf("aws ecr get-login-password: %w", err)
+		}
+
+		// Tokens issued by ECR are valid for 12 hours.
+		return cloudCredentials{
+			username: "AWS",
+			password: strings.TrimSpace(string(password)),
+			expires:  time.Now().Add(12 * time.Hour),
+		}, nil
+	}
+
+	var response struct {
+		AuthorizationData []struct {
+			AuthorizationToken string  `json:"authorizationToken"`
+			ExpiresAt          float64 `json:"expiresAt"`
+		} `json:"authorizationData"`
+	}
+	if err := ecrRequest(ctx, credentials, region, domain, "GetAuthorizationToken", struct{}{}, &response); err != nil {
+		return cloudCredentials{}, fmt.Errorf("get authorization token: %w", err)
+	}
+
+	if len(response.AuthorizationData) == 0 {
+		return cloudCredentials{}, fmt.Errorf("no authorization data returned")
+	}
+
+	username, password, err := decodeBasicAuth(response.AuthorizationData[0].AuthorizationToken)
+	if err != nil {
+		return cloudCredentials{}, fmt.Errorf("decode authorization token: %w", err)
+	}
+
+	return cloudCredentials{
+		username: username,
+		password: password,
+		expires:  time.Unix(int64(response.AuthorizationData[0].ExpiresAt), 0),
+	}, nil
+}
+
+// getECRRegion returns the region and domain of the ECR registry. When the registry is accessed through
+// a different host (e.g. a proxy), the region is read from the environment (AWS_REGION or AWS_DEFAULT_REGION).
+func getECRRegion(host string) (string, string, error) {
+	if match := ecrHostPattern.FindStringSubmatch(host); match != nil {
+		return match[1], match[2], nil
+	}
+
+	for _, variable := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(variable); region != "" {
+			return region, "amazonaws.com", nil
+		}
+	}
+
+	return "", "", fmt.Errorf("%s is not an ECR registry and AWS_REGION is not set", host)
+}
+
+// ecrRequest calls the action of the ECR API with the request as its JSON body and decodes the JSON response.
+func ecrRequest(ctx context.Context, credentials awsCredentials, region string, domain string, action string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ecrEndpoint(region, domain)+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921."+action)
+	signV4(req, body, credentials, region, "ecr", time.Now())
+
+	resp, err := cloudHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr ecrError
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Type == "" {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+
+		return apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
+
+// ecrError is an error returned by the ECR API (e.g. RepositoryNotFoundException).
+type ecrError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+func (e ecrError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Message)
+}
+
+// getAWSCredentials returns the AWS credentials from the environment or from the profile
+// (AWS_PROFILE, or default) of the shared credentials file, if any.
+func getAWSCredentials() (awsCredentials, bool, error) {
+	if os.Getenv("AWS_ACCESS_KEY_ID") != "" && os.Getenv("AWS_SECRET_ACCESS_KEY") != "" {
+		return awsCredentials{
+			accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, true, nil
+	}
+
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsFile == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, false, nil
+		}
+
+		credentialsFile = filepath.Join(home, ".aws", "credentials")
+	}
+
+	contents, err := ioutil.ReadFile(credentialsFile)
+	if os.IsNotExist(err) {
+		return awsCredentials{}, false, nil
+	}
+	if err != nil {
+		return awsCredentials{}, false, fmt.Errorf("read credentials file: %w", err)
+	}
+
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	values := getINISection(contents, profile)
+	if values["aws_access_key_id"] == "" || values["aws_secret_access_key"] == "" {
+		return awsCredentials{}, false, nil
+	}
+
+	return awsCredentials{
+		accessKeyID:     values["aws_access_key_id"],
+		secretAccessKey: values["aws_secret_access_key"],
+		sessionToken:    values["aws_session_token"],
+	}, true, nil
+}
+
+// getINISection returns the keys and values of the section of the INI file.
+func getINISection(contents []byte, section string) map[string]string {
+	values := make(map[string]string)
+
+	var inSection bool
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == section
+			continue
+		}
+
+		keyValue := strings.SplitN(line, "=", 2)
+		if inSection && len(keyValue) == 2 {
+			values[strings.TrimSpace(keyValue[0])] = strings.TrimSpace(keyValue[1])
+		}
+	}
+
+	return values
+}
+
+// signV4 signs the request with AWS Signature Version 4. All of the headers
+// that are set on the request, as well as the host, are signed.
+func signV4(req *http.Request, body []byte, credentials awsCredentials, region string, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	var headerNames []string
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		getCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashSHA256(body),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hashSHA256([]byte(canonicalRequest))}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+credentials.secretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", credentials.accessKeyID, scope, signedHeaders, signature))
+}
+
+func getCanonicalQuery(query url.Values) string {
+	var parameters []string
+	for key, values := range query {
+		for _, value := range values {
+			parameters = append(parameters, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	sort.Strings(parameters)
+
+	return strings.Join(parameters, "&")
+}
+
+// awsEscape escapes the value as required by AWS, where spaces are encoded as %20 rather than +.
+func awsEscape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+func hashSHA256(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
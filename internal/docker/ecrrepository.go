@@ -0,0 +1,218 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// ECRRepositoryOptions are the settings of the ECR repositories that are created.
+type ECRRepositoryOptions struct {
+	// Tags are the tags (key and value) that are added to the repository.
+	Tags map[string]string
+
+	// ScanOnPush enables the scanning of each image for vulnerabilities when it is pushed.
+	ScanOnPush bool
+
+	// ImmutableTags prevents the tags of the images in the repository from being overwritten.
+	ImmutableTags bool
+}
+
+// ECRRepositoryCreator creates the ECR repositories that images are pushed to when they do not exist, as ECR does
+// not create repositories on push. Each repository is only looked up once, no matter how many images are pushed to it.
+type ECRRepositoryCreator struct {
+	options ECRRepositoryOptions
+
+	mutex        sync.Mutex
+	repositories map[string]bool
+}
+
+// NewECRRepositoryCreator returns a creator that creates repositories with the given options.
+func NewECRRepositoryCreator(options ECRRepositoryOptions) *ECRRepositoryCreator {
+	return &ECRRepositoryCreator{
+		options:      options,
+		repositories: make(map[string]bool),
+	}
+}
+
+// EnsureRepository creates the repository of the image when it does not exist. Images that are not
+// pushed to an ECR registry are ignored. It returns true when the repository was created.
+func (c *ECRRepositoryCreator) EnsureRepository(ctx context.Context, image string) (bool, error) {
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return false, fmt.Errorf("parse reference: %w", err)
+	}
+
+	host := reference.Context().RegistryStr()
+	if !isECRHost(host) {
+		return false, nil
+	}
+
+	repository := reference.Context().RepositoryStr()
+	key := host + "/" + repository
+
+	// The lock is held while the repository is created so that concurrent
+	// pushes to the same repository do not try to create it more than once.
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.repositories[key] {
+		return false, nil
+	}
+
+	region, domain, err := getECRRegion(host)
+	if err != nil {
+		return false, fmt.Errorf("get region: %w", err)
+	}
+
+	registryID := strings.SplitN(host, ".", 2)[0]
+
+	created, err := createECRRepository(ctx, region, domain, registryID, repository, c.options)
+	if err != nil {
+		return false, err
+	}
+
+	c.repositories[key] = true
+	return created, nil
+}
+
+// createECRRepository creates the repository unless it already exists. The AWS credentials are read in the same way as
+// when authenticating to ECR; when none are found, the repository is looked up and created with the AWS CLI instead.
+func createECRRepository(ctx context.Context, region string, domain string, registryID string, repository string, options ECRRepositoryOptions) (bool, error) {
+	credentials, found, err := getAWSCredentials()
+	if err != nil {
+		return false, fmt.Errorf("get aws credentials: %w", err)
+	}
+
+	if !found {
+		return createECRRepositoryWithCLI(ctx, region, registryID, repository, options)
+	}
+
+	describeRequest := struct {
+		RegistryID      string   `json:"registryId"`
+		RepositoryNames []string `json:"repositoryNames"`
+	}{
+		RegistryID:      registryID,
+		RepositoryNames: []string{repository},
+	}
+
+	var describeResponse struct{}
+	err = ecrRequest(ctx, credentials, region, domain, "DescribeRepositories", describeRequest, &describeResponse)
+	if err == nil {
+		return false, nil
+	}
+	if !isECRError(err, "RepositoryNotFoundException") {
+		return false, fmt.Errorf("describe repository %s: %w", repository, err)
+	}
+
+	type tag struct {
+		Key   string `json:"Key"`
+		Value string `json:"Value"`
+	}
+
+	createRequest := struct {
+		RegistryID                 string `json:"registryId"`
+		RepositoryName             string `json:"repositoryName"`
+		ImageTagMutability         string `json:"imageTagMutability"`
+		ImageScanningConfiguration struct {
+			ScanOnPush bool `json:"scanOnPush"`
+		} `json:"imageScanningConfiguration"`
+		Tags []tag `json:"tags,omitempty"`
+	}{
+		RegistryID:         registryID,
+		RepositoryName:     repository,
+		ImageTagMutability: getECRTagMutability(options),
+	}
+	createRequest.ImageScanningConfiguration.ScanOnPush = options.ScanOnPush
+	for _, key := range getSortedKeys(options.Tags) {
+		createRequest.Tags = append(createRequest.Tags, tag{Key: key, Value: options.Tags[key]})
+	}
+
+	var createResponse struct{}
+	err = ecrRequest(ctx, credentials, region, domain, "CreateRepository", createRequest, &createResponse)
+
+	// The repository may have been created by someone else in the meantime.
+	if isECRError(err, "RepositoryAlreadyExistsException") {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("create repository %s: %w", repository, err)
+	}
+
+	return true, nil
+}
+
+func createECRRepositoryWithCLI(ctx context.Context, region string, registryID string, repository string, options ECRRepositoryOptions) (bool, error) {
+	describe := exec.CommandContext(ctx, "aws", "ecr", "describe-repositories",
+		"--region", region,
+		"--registry-id", registryID,
+		"--repository-names", repository)
+
+	output, err := describe.CombinedOutput()
+	if err == nil {
+		return false, nil
+	}
+	if !strings.Contains(string(output), "RepositoryNotFoundException") {
+		return false, fmt.Errorf("aws ecr describe-repositories: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	args := []string{"ecr", "create-repository",
+		"--region", region,
+		"--registry-id", registryID,
+		"--repository-name", repository,
+		"--image-tag-mutability", getECRTagMutability(options),
+		"--image-scanning-configuration", fmt.Sprintf("scanOnPush=%t", options.ScanOnPush),
+	}
+
+	if len(options.Tags) > 0 {
+		args = append(args, "--tags")
+		for _, key := range getSortedKeys(options.Tags) {
+			args = append(args, fmt.Sprintf("Key=%s,Value=%s", key, options.Tags[key]))
+		}
+	}
+
+	output, err = exec.CommandContext(ctx, "aws", args...).CombinedOutput()
+	if strings.Contains(string(output), "RepositoryAlreadyExistsException") {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("aws ecr create-repository: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return true, nil
+}
+
+func getECRTagMutability(options ECRRepositoryOptions) string {
+	if options.ImmutableTags {
+		return "IMMUTABLE"
+	}
+
+	return "MUTABLE"
+}
+
+// isECRError returns true when the error was returned by the ECR API with the given type.
+func isECRError(err error, errorType string) bool {
+	var apiErr ecrError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	// The type may be prefixed with its namespace (e.g. com.amazonaws.ecr#RepositoryNotFoundException).
+	return apiErr.Type == errorType || strings.HasSuffix(apiErr.Type, "#"+errorType)
+}
+
+func getSortedKeys(values map[string]string) []string {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEnsureRepository(t *testing.T) {
+	var mutex sync.Mutex
+	var actions []string
+	var createRequest map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonEC2ContainerRegistry_V20150921.")
+
+		mutex.Lock()
+		actions = append(actions, action)
+		mutex.Unlock()
+
+		switch action {
+		case "DescribeRepositories":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"RepositoryNotFoundException","message":"The repository does not exist"}`))
+
+		case "CreateRepository":
+			if err := json.NewDecoder(r.Body).Decode(&createRequest); err != nil {
+				t.Error("decode request:", err)
+			}
+			w.Write([]byte(`{"repository":{}}`))
+
+		default:
+			t.Errorf("unexpected action %s", action)
+		}
+	}))
+	defer server.Close()
+
+	defaultEndpoint := ecrEndpoint
+	ecrEndpoint = func(region string, domain string) string { return server.URL }
+	defer func() { ecrEndpoint = defaultEndpoint }()
+
+	setEnv(t, "AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	setEnv(t, "AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY")
+
+	creator := NewECRRepositoryCreator(ECRRepositoryOptions{
+		Tags:          map[string]string{"team": "platform"},
+		ScanOnPush:    true,
+		ImmutableTags: true,
+	})
+
+	created, err := creator.EnsureRepository(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/mirror/busybox:1.32.0")
+	if err != nil {
+		t.Fatal("ensure repository:", err)
+	}
+
+	if !created {
+		t.Error("expected repository to be created")
+	}
+
+	// The repository is only looked up once.
+	created, err = creator.EnsureRepository(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/mirror/busybox:1.33.0")
+	if err != nil {
+		t.Fatal("ensure repository:", err)
+	}
+
+	if created {
+		t.Error("expected repository to only be created once")
+	}
+
+	// Images that are not pushed to ECR are ignored.
+	if _, err := creator.EnsureRepository(context.Background(), "mycompany.com/mirror/busybox:1.32.0"); err != nil {
+		t.Fatal("ensure repository:", err)
+	}
+
+	if strings.Join(actions, ",") != "DescribeRepositories,CreateRepository" {
+		t.Errorf("unexpected actions %v", actions)
+	}
+
+	if createRequest["repositoryName"] != "mirror/busybox" || createRequest["registryId"] != "123456789012" {
+		t.Errorf("unexpected repository in request %v", createRequest)
+	}
+
+	if createRequest["imageTagMutability"] != "IMMUTABLE" {
+		t.Errorf("expected immutable tags, actual %v", createRequest["imageTagMutability"])
+	}
+
+	scanning, _ := createRequest["imageScanningConfiguration"].(map[string]interface{})
+	if scanning["scanOnPush"] != true {
+		t.Errorf("expected scan on push, actual %v", createRequest["imageScanningConfiguration"])
+	}
+
+	tags, _ := createRequest["tags"].([]interface{})
+	if len(tags) != 1 {
+		t.Errorf("expected one tag, actual %v", createRequest["tags"])
+	}
+}
+
+func TestIsECRError(t *testing.T) {
+	err := ecrError{Type: "com.amazonaws.ecr#RepositoryNotFoundException"}
+
+	if !isECRError(err, "RepositoryNotFoundException") {
+		t.Error("expected namespaced error type to match")
+	}
+
+	if isECRError(err, "RepositoryAlreadyExistsException") {
+		t.Error("expected different error type not to match")
+	}
+}
@@ -0,0 +1,206 @@
+package docker
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// gcpScope is the OAuth scope of the access tokens that are used to authenticate to the registries.
+const gcpScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// gcpTokenURL is the endpoint that exchanges credentials for an access token.
+var gcpTokenURL = "https://oauth2.googleapis.com/token"
+
+// gcpMetadataURL is the endpoint of the metadata server that issues access tokens to
+// the service account of the instance (e.g. on GCE, GKE or Cloud Run).
+var gcpMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// gcpCredentialsFile is an Application Default Credentials file.
+type gcpCredentialsFile struct {
+	Type string `json:"type"`
+
+	// The fields of a service account key.
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+
+	// The fields of the credentials of a user (e.g. created by gcloud auth application-default login).
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// gcpTokenResponse is an OAuth access token issued by Google.
+type gcpTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func isGCRHost(host string) bool {
+	return host == "gcr.io" || strings.HasSuffix(host, ".gcr.io") || strings.HasSuffix(host, "-docker.pkg.dev")
+}
+
+// getGCRCredentials returns the credentials to Container Registry and Artifact Registry using the
+// Application Default Credentials: the file in GOOGLE_APPLICATION_CREDENTIALS, the file created by
+// gcloud auth application-default login, or the service account of the instance from the metadata server.
+func getGCRCredentials(ctx context.Context) (cloudCredentials, error) {
+	token, err := getGCPAccessToken(ctx)
+	if err != nil {
+		return cloudCredentials{}, fmt.Errorf("get access token: %w", err)
+	}
+
+	return cloudCredentials{
+		username: "oauth2accesstoken",
+		password: token.AccessToken,
+		expires:  time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+func getGCPAccessToken(ctx context.Context) (gcpTokenResponse, error) {
+	credentialsPath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if credentialsPath == "" {
+		credentialsPath = getWellKnownGCPCredentialsPath()
+	}
+
+	contents, err := ioutil.ReadFile(credentialsPath)
+	if os.IsNotExist(err) && os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") == "" {
+		return getGCPMetadataToken(ctx)
+	}
+	if err != nil {
+		return gcpTokenResponse{}, fmt.Errorf("read credentials file: %w", err)
+	}
+
+	var credentials gcpCredentialsFile
+	if err := json.Unmarshal(contents, &credentials); err != nil {
+		return gcpTokenResponse{}, fmt.Errorf("unmarshal credentials file: %w", err)
+	}
+
+	var token gcpTokenResponse
+	switch credentials.Type {
+	case "service_account":
+		tokenURL := credentials.TokenURI
+		if tokenURL == "" {
+			tokenURL = gcpTokenURL
+		}
+
+		assertion, err := getGCPAssertion(credentials, tokenURL, time.Now())
+		if err != nil {
+			return gcpTokenResponse{}, fmt.Errorf("sign assertion: %w", err)
+		}
+
+		form := url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+		if err := postForm(ctx, tokenURL, form.Encode(), nil, &token); err != nil {
+			return gcpTokenResponse{}, fmt.Errorf("exchange service account assertion: %w", err)
+		}
+
+	case "authorized_user":
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {credentials.ClientID},
+			"client_secret": {credentials.ClientSecret},
+			"refresh_token": {credentials.RefreshToken},
+		}
+		if err := postForm(ctx, gcpTokenURL, form.Encode(), nil, &token); err != nil {
+			return gcpTokenResponse{}, fmt.Errorf("exchange refresh token: %w", err)
+		}
+
+	default:
+		return gcpTokenResponse{}, fmt.Errorf("unsupported credentials type %s", credentials.Type)
+	}
+
+	return token, nil
+}
+
+func getWellKnownGCPCredentialsPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud", "application_default_credentials.json")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+func getGCPMetadataToken(ctx context.Context) (gcpTokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataURL, nil)
+	if err != nil {
+		return gcpTokenResponse{}, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var token gcpTokenResponse
+	if err := doJSON(req, &token); err != nil {
+		return gcpTokenResponse{}, fmt.Errorf("get token from metadata server: %w", err)
+	}
+
+	return token, nil
+}
+
+// getGCPAssertion returns a JWT, signed with the private key of the service account, that is exchanged for an access token.
+func getGCPAssertion(credentials gcpCredentialsFile, audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(credentials.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("decode private key: no PEM data found")
+	}
+
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("parse private key: %w", err)
+		}
+	}
+
+	privateKey, ok := parsedKey.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("private key is not an RSA key")
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("marshal header: %w", err)
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   credentials.ClientEmail,
+		"scope": gcpScope,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("sign: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,187 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// HarborProjectOptions are the settings of the Harbor projects that are created.
+type HarborProjectOptions struct {
+	// Public allows the images in the project to be pulled without logging in.
+	Public bool
+
+	// StorageLimit is the maximum number of bytes stored in the project. Zero uses the default quota of Harbor.
+	StorageLimit int64
+}
+
+// HarborProjectCreator creates the Harbor projects that images are pushed to when they do not exist, as Harbor
+// does not create projects on push. Each project is only looked up once, no matter how many images are pushed to it.
+type HarborProjectCreator struct {
+	client  Client
+	options HarborProjectOptions
+
+	mutex    sync.Mutex
+	projects map[string]bool
+}
+
+// NewHarborProjectCreator returns a creator that creates projects with the given options. The Harbor
+// API is called with the transport of the client, so the settings of the registries also apply to it.
+func NewHarborProjectCreator(client Client, options HarborProjectOptions) *HarborProjectCreator {
+	return &HarborProjectCreator{
+		client:   client,
+		options:  options,
+		projects: make(map[string]bool),
+	}
+}
+
+// EnsureProject creates the project of the image, which is the first path component of its repository, when it
+// does not exist. The project is created with the credentials of the auth. It returns true when the project was created.
+func (c *HarborProjectCreator) EnsureProject(ctx context.Context, image string, auth string) (bool, error) {
+	reference, err := c.client.parseReference(image)
+	if err != nil {
+		return false, fmt.Errorf("parse reference: %w", err)
+	}
+
+	project := strings.SplitN(reference.Context().RepositoryStr(), "/", 2)[0]
+	key := reference.Context().RegistryStr() + "/" + project
+
+	// The lock is held while the project is created so that concurrent
+	// pushes to the same project do not try to create it more than once.
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.projects[key] {
+		return false, nil
+	}
+
+	created, err := c.createProject(ctx, reference.Context().Registry, project, auth)
+	if err != nil {
+		return false, err
+	}
+
+	c.projects[key] = true
+	return created, nil
+}
+
+// createProject creates the project unless it already exists.
+func (c *HarborProjectCreator) createProject(ctx context.Context, registry name.Registry, project string, auth string) (bool, error) {
+	endpoint := registry.Scheme() + "://" + registry.RegistryStr() + "/api/v2.0/projects"
+
+	exists, err := c.projectExists(ctx, endpoint, project, auth)
+	if err != nil {
+		return false, fmt.Errorf("head project %s: %w", project, err)
+	}
+
+	if exists {
+		return false, nil
+	}
+
+	createRequest := struct {
+		ProjectName  string            `json:"project_name"`
+		Metadata     map[string]string `json:"metadata"`
+		StorageLimit int64             `json:"storage_limit,omitempty"`
+	}{
+		ProjectName:  project,
+		Metadata:     map[string]string{"public": strconv.FormatBool(c.options.Public)},
+		StorageLimit: c.options.StorageLimit,
+	}
+
+	body, err := json.Marshal(createRequest)
+	if err != nil {
+		return false, fmt.Errorf("marshal request: %w", err)
+	}
+
+	response, err := c.client.harborRequest(ctx, http.MethodPost, endpoint, bytes.NewReader(body), auth)
+	if err != nil {
+		return false, fmt.Errorf("create project %s: %w", project, err)
+	}
+	defer response.Body.Close()
+
+	// The project may have been created by someone else in the meantime.
+	if response.StatusCode == http.StatusConflict {
+		return false, nil
+	}
+
+	if response.StatusCode != http.StatusCreated {
+		return false, fmt.Errorf("create project %s: %w", project, getHarborError(response))
+	}
+
+	return true, nil
+}
+
+func (c *HarborProjectCreator) projectExists(ctx context.Context, endpoint string, project string, auth string) (bool, error) {
+	response, err := c.client.harborRequest(ctx, http.MethodHead, endpoint+"?project_name="+url.QueryEscape(project), nil, auth)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, getHarborError(response)
+	}
+}
+
+// harborRequest sends the request to the Harbor API with the username and password of the auth.
+func (c Client) harborRequest(ctx context.Context, method string, endpoint string, body io.Reader, auth string) (*http.Response, error) {
+	request, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return nil, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	authConfig, err := authenticator.Authorization()
+	if err != nil {
+		return nil, fmt.Errorf("authorization: %w", err)
+	}
+
+	if authConfig.Username != "" {
+		request.SetBasicAuth(authConfig.Username, authConfig.Password)
+	}
+
+	httpClient := http.Client{Transport: c.transport}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+
+	return response, nil
+}
+
+// getHarborError returns the error in the response of the Harbor API.
+func getHarborError(response *http.Response) error {
+	var harborErrors struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err == nil && json.Unmarshal(body, &harborErrors) == nil && len(harborErrors.Errors) > 0 {
+		return fmt.Errorf("%s: %s", harborErrors.Errors[0].Code, harborErrors.Errors[0].Message)
+	}
+
+	return fmt.Errorf("unexpected status %s", response.Status)
+}
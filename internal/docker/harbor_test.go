@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEnsureProject(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []string
+	var createRequest map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mutex.Unlock()
+
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "Harbor12345" {
+			t.Errorf("unexpected credentials %s:%s", username, password)
+		}
+
+		switch r.Method {
+		case http.MethodHead:
+			if r.URL.Query().Get("project_name") == "existing" {
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&createRequest); err != nil {
+				t.Error("decode request:", err)
+			}
+			w.WriteHeader(http.StatusCreated)
+
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	auth, err := GetEncodedBasicAuth("admin", "Harbor12345")
+	if err != nil {
+		t.Fatal("get encoded auth:", err)
+	}
+
+	creator := NewHarborProjectCreator(Client{}, HarborProjectOptions{
+		Public:       true,
+		StorageLimit: 1024,
+	})
+
+	created, err := creator.EnsureProject(context.Background(), host+"/mirror/busybox:1.32.0", auth)
+	if err != nil {
+		t.Fatal("ensure project:", err)
+	}
+
+	if !created {
+		t.Error("expected project to be created")
+	}
+
+	// The project is only looked up once.
+	created, err = creator.EnsureProject(context.Background(), host+"/mirror/quay.io/coreos/prometheus-operator:v0.40.0", auth)
+	if err != nil {
+		t.Fatal("ensure project:", err)
+	}
+
+	if created {
+		t.Error("expected project to only be created once")
+	}
+
+	created, err = creator.EnsureProject(context.Background(), host+"/existing/busybox:1.32.0", auth)
+	if err != nil {
+		t.Fatal("ensure project:", err)
+	}
+
+	if created {
+		t.Error("expected existing project to not be created")
+	}
+
+	if strings.Join(requests, ",") != "HEAD /api/v2.0/projects,POST /api/v2.0/projects,HEAD /api/v2.0/projects" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+
+	if createRequest["project_name"] != "mirror" || createRequest["storage_limit"] != float64(1024) {
+		t.Errorf("unexpected project in request %v", createRequest)
+	}
+
+	metadata, _ := createRequest["metadata"].(map[string]interface{})
+	if metadata["public"] != "true" {
+		t.Errorf("expected public project, actual %v", createRequest["metadata"])
+	}
+}
@@ -0,0 +1,122 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// HarborLabeler adds Harbor labels to the artifacts that are pushed to Harbor, so that the mirrored images can be
+// selected by their labels, such as by a cleanup job that removes the images of a release. The labels must be global
+// labels that already exist in Harbor. The ID of each label is only looked up once for each registry.
+type HarborLabeler struct {
+	client Client
+	labels []string
+
+	mutex    sync.Mutex
+	labelIDs map[string]int64
+}
+
+// NewHarborLabeler returns a labeler that adds the labels with the given names. The Harbor API
+// is called with the transport of the client, so the settings of the registries also apply to it.
+func NewHarborLabeler(client Client, labels []string) *HarborLabeler {
+	return &HarborLabeler{
+		client:   client,
+		labels:   labels,
+		labelIDs: make(map[string]int64),
+	}
+}
+
+// AddLabels adds the labels to the artifact of the image with the credentials of the auth.
+// Labels that were already added to the artifact are left as they are.
+func (l *HarborLabeler) AddLabels(ctx context.Context, image string, auth string) error {
+	reference, err := l.client.parseReference(image)
+	if err != nil {
+		return fmt.Errorf("parse reference: %w", err)
+	}
+
+	registry := reference.Context().Registry
+	endpoint := registry.Scheme() + "://" + registry.RegistryStr() + "/api/v2.0"
+
+	repositoryTokens := strings.SplitN(reference.Context().RepositoryStr(), "/", 2)
+	if len(repositoryTokens) != 2 {
+		return fmt.Errorf("repository %s is not in a project", reference.Context().RepositoryStr())
+	}
+
+	// The name of the repository is escaped twice, as Harbor unescapes it
+	// once before matching the route (e.g. library/busybox is library%252Fbusybox).
+	artifactPath := fmt.Sprintf("/projects/%s/repositories/%s/artifacts/%s/labels", url.PathEscape(repositoryTokens[0]), url.PathEscape(url.PathEscape(repositoryTokens[1])), url.PathEscape(reference.Identifier()))
+
+	for _, label := range l.labels {
+		labelID, err := l.getLabelID(ctx, endpoint, label, auth)
+		if err != nil {
+			return fmt.Errorf("get label %s: %w", label, err)
+		}
+
+		body, err := json.Marshal(map[string]int64{"id": labelID})
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+
+		response, err := l.client.harborRequest(ctx, http.MethodPost, endpoint+artifactPath, bytes.NewReader(body), auth)
+		if err != nil {
+			return fmt.Errorf("add label %s: %w", label, err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode == http.StatusConflict {
+			continue
+		}
+
+		if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+			return fmt.Errorf("add label %s: %w", label, getHarborError(response))
+		}
+	}
+
+	return nil
+}
+
+// getLabelID returns the ID of the global label with the name.
+func (l *HarborLabeler) getLabelID(ctx context.Context, endpoint string, label string, auth string) (int64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	key := endpoint + "/" + label
+	if labelID, ok := l.labelIDs[key]; ok {
+		return labelID, nil
+	}
+
+	response, err := l.client.harborRequest(ctx, http.MethodGet, endpoint+"/labels?scope=g&name="+url.QueryEscape(label), nil, auth)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return 0, getHarborError(response)
+	}
+
+	var labels []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(&labels); err != nil {
+		return 0, fmt.Errorf("decode labels: %w", err)
+	}
+
+	// The labels are matched by a part of their name, so the label with the exact name is found among them.
+	for _, currentLabel := range labels {
+		if currentLabel.Name == label {
+			l.labelIDs[key] = currentLabel.ID
+			return currentLabel.ID, nil
+		}
+	}
+
+	return 0, fmt.Errorf("global label %s does not exist", label)
+}
@@ -0,0 +1,68 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAddLabels(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		mutex.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": 1, "name": "ephemeral-old"},
+				{"id": 2, "name": "ephemeral"},
+			})
+
+		case http.MethodPost:
+			var request map[string]int64
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Error("decode request:", err)
+			}
+
+			if request["id"] != 2 {
+				t.Errorf("expected label 2 to be added, actual %v", request["id"])
+			}
+
+			// The label was already added to the second image.
+			if strings.Contains(r.URL.Path, "prometheus") {
+				w.WriteHeader(http.StatusConflict)
+			}
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	labeler := NewHarborLabeler(Client{}, []string{"ephemeral"})
+	if err := labeler.AddLabels(context.Background(), host+"/mirror/busybox:1.32.0", ""); err != nil {
+		t.Fatal("add labels:", err)
+	}
+
+	if err := labeler.AddLabels(context.Background(), host+"/mirror/quay.io/coreos/prometheus-operator:v0.40.0", ""); err != nil {
+		t.Fatal("add labels:", err)
+	}
+
+	expected := []string{
+		"GET /api/v2.0/labels",
+		"POST /api/v2.0/projects/mirror/repositories/busybox/artifacts/1.32.0/labels",
+		"POST /api/v2.0/projects/mirror/repositories/quay.io%252Fcoreos%252Fprometheus-operator/artifacts/v0.40.0/labels",
+	}
+
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v, actual %v", expected, requests)
+	}
+}
@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// ImageMetadata is the metadata of an image that is recorded in its config.
+type ImageMetadata struct {
+	Image        string            `json:"image"`
+	Digest       string            `json:"digest"`
+	Platform     string            `json:"platform,omitempty"`
+	Created      time.Time         `json:"created"`
+	Labels       map[string]string `json:"labels,omitempty"`
+	Entrypoint   []string          `json:"entrypoint,omitempty"`
+	Cmd          []string          `json:"cmd,omitempty"`
+	ExposedPorts []string          `json:"exposedPorts,omitempty"`
+	Layers       int               `json:"layers"`
+}
+
+// InspectImage returns the metadata of the image from its config, without pulling its layers. The
+// metadata of a multi-arch image is the metadata of the image of the given platform (e.g. linux/amd64).
+func (c Client) InspectImage(ctx context.Context, image string, auth string, platform string) (ImageMetadata, error) {
+	reference, err := c.parseReference(image)
+	if err != nil {
+		return ImageMetadata{}, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return ImageMetadata{}, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	options := c.remoteOptions(ctx, authenticator)
+	if platform != "" {
+		parsedPlatform, err := ParsePlatform(platform)
+		if err != nil {
+			return ImageMetadata{}, fmt.Errorf("parse platform: %w", err)
+		}
+
+		options = append(options, remote.WithPlatform(parsedPlatform))
+	}
+
+	remoteImage, err := remote.Image(reference, options...)
+	if err != nil {
+		return ImageMetadata{}, fmt.Errorf("get image: %w", err)
+	}
+
+	digest, err := remoteImage.Digest()
+	if err != nil {
+		return ImageMetadata{}, fmt.Errorf("get digest: %w", err)
+	}
+
+	configFile, err := remoteImage.ConfigFile()
+	if err != nil {
+		return ImageMetadata{}, fmt.Errorf("get config: %w", err)
+	}
+
+	imageManifest, err := remoteImage.Manifest()
+	if err != nil {
+		return ImageMetadata{}, fmt.Errorf("get manifest: %w", err)
+	}
+
+	metadata := ImageMetadata{
+		Image:      image,
+		Digest:     digest.String(),
+		Created:    configFile.Created.Time,
+		Labels:     configFile.Config.Labels,
+		Entrypoint: configFile.Config.Entrypoint,
+		Cmd:        configFile.Config.Cmd,
+		Layers:     len(imageManifest.Layers),
+	}
+
+	if configFile.OS != "" {
+		metadata.Platform = formatPlatform(v1.Platform{OS: configFile.OS, Architecture: configFile.Architecture, OSVersion: configFile.OSVersion})
+	}
+
+	for port := range configFile.Config.ExposedPorts {
+		metadata.ExposedPorts = append(metadata.ExposedPorts, port)
+	}
+	sort.Strings(metadata.ExposedPorts)
+
+	return metadata, nil
+}
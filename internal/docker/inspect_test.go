@@ -0,0 +1,77 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestInspectImage(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	randomImage, err := random.Image(1024, 3)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	configFile, err := randomImage.ConfigFile()
+	if err != nil {
+		t.Fatal("config file:", err)
+	}
+
+	configFile.OS = "linux"
+	configFile.Architecture = "amd64"
+	configFile.Config = v1.Config{
+		Labels:       map[string]string{"org.opencontainers.image.source": "https://github.com/plexsystems/sinker"},
+		Entrypoint:   []string{"/sinker"},
+		ExposedPorts: map[string]struct{}{"9090/tcp": {}, "8080/tcp": {}},
+	}
+
+	image, err := mutate.ConfigFile(randomImage, configFile)
+	if err != nil {
+		t.Fatal("mutate config file:", err)
+	}
+
+	reference, err := name.ParseReference(host+"/sinker:v1.0.0", name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(reference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	client := Client{
+		logInfo: t.Logf,
+	}
+
+	metadata, err := client.InspectImage(context.Background(), host+"/sinker:v1.0.0", "", "")
+	if err != nil {
+		t.Fatal("inspect image:", err)
+	}
+
+	if metadata.Layers != 3 {
+		t.Errorf("expected 3 layers, actual %v", metadata.Layers)
+	}
+
+	if metadata.Platform != "linux/amd64" {
+		t.Errorf("expected platform linux/amd64, actual %v", metadata.Platform)
+	}
+
+	expectedPorts := []string{"8080/tcp", "9090/tcp"}
+	if !reflect.DeepEqual(metadata.ExposedPorts, expectedPorts) {
+		t.Errorf("expected exposed ports %v, actual %v", expectedPorts, metadata.ExposedPorts)
+	}
+
+	if !reflect.DeepEqual(metadata.Entrypoint, []string{"/sinker"}) || !reflect.DeepEqual(metadata.Labels, configFile.Config.Labels) {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+}
@@ -0,0 +1,102 @@
+package docker
+
+import (
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+// QuayExpiresAfterLabel is the label of an image that Quay expires the tag of the image after (e.g. 2w), after
+// which the garbage collection of Quay removes the image. The value is a number of hours (h), days (d) or weeks (w).
+const QuayExpiresAfterLabel = "quay.expires-after"
+
+// WithConfigLabels returns a copy of the client that adds the labels to the config of each image it copies,
+// replacing any labels with the same keys. The images of a multi-arch image are each labeled. Adding the
+// labels changes the digest of the image, so the digest of the copied image no longer matches the source.
+func (c Client) WithConfigLabels(labels map[string]string) Client {
+	c.configLabels = labels
+	return c
+}
+
+// labelImage returns the image with the labels added to its config.
+func labelImage(image v1.Image, labels map[string]string) (v1.Image, error) {
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("get config file: %w", err)
+	}
+
+	config := configFile.Config
+	config.Labels = make(map[string]string)
+	for key, value := range configFile.Config.Labels {
+		config.Labels[key] = value
+	}
+
+	for key, value := range labels {
+		config.Labels[key] = value
+	}
+
+	labeledImage, err := mutate.Config(image, config)
+	if err != nil {
+		return nil, fmt.Errorf("mutate config: %w", err)
+	}
+
+	return labeledImage, nil
+}
+
+// labelIndex returns the index with the labels added to the config of each of its images. Indexes
+// nested in the index are kept as they are, along with their platforms and annotations.
+func labelIndex(index v1.ImageIndex, labels map[string]string) (v1.ImageIndex, error) {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("get index manifest: %w", err)
+	}
+
+	mediaType, err := index.MediaType()
+	if err != nil {
+		return nil, fmt.Errorf("get media type: %w", err)
+	}
+
+	var addendums []mutate.IndexAddendum
+	for _, manifest := range indexManifest.Manifests {
+		descriptor := v1.Descriptor{
+			Platform:    manifest.Platform,
+			Annotations: manifest.Annotations,
+		}
+
+		if isIndex(manifest.MediaType) {
+			nestedIndex, err := index.ImageIndex(manifest.Digest)
+			if err != nil {
+				return nil, fmt.Errorf("get index %s: %w", manifest.Digest, err)
+			}
+
+			addendums = append(addendums, mutate.IndexAddendum{Add: nestedIndex, Descriptor: descriptor})
+			continue
+		}
+
+		image, err := index.Image(manifest.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("get image %s: %w", manifest.Digest, err)
+		}
+
+		// The attestations of the images (e.g. the provenance added by BuildKit) have an unknown platform and
+		// refer to the digest of the image that they attest, so they are kept as they are.
+		if manifest.Platform != nil && manifest.Platform.OS == "unknown" {
+			addendums = append(addendums, mutate.IndexAddendum{Add: image, Descriptor: descriptor})
+			continue
+		}
+
+		labeledImage, err := labelImage(image, labels)
+		if err != nil {
+			return nil, fmt.Errorf("label image %s: %w", manifest.Digest, err)
+		}
+
+		addendums = append(addendums, mutate.IndexAddendum{Add: labeledImage, Descriptor: descriptor})
+	}
+
+	labeledIndex := mutate.AppendManifests(empty.Index, addendums...)
+	labeledIndex = mutate.IndexMediaType(labeledIndex, mediaType)
+
+	return labeledIndex, nil
+}
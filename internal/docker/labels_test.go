@@ -0,0 +1,95 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestCopyImageAndWait_ConfigLabels(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	var addendums []mutate.IndexAddendum
+	for _, platform := range []string{"linux/amd64", "linux/arm64"} {
+		image, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		parsedPlatform, err := ParsePlatform(platform)
+		if err != nil {
+			t.Fatal("parse platform:", err)
+		}
+
+		addendums = append(addendums, mutate.IndexAddendum{
+			Add: image,
+			Descriptor: v1.Descriptor{
+				Platform: &parsedPlatform,
+			},
+		})
+	}
+
+	source := host + "/source:v1.0.0"
+	sourceReference, err := name.ParseReference(source, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.WriteIndex(sourceReference, mutate.AppendManifests(empty.Index, addendums...)); err != nil {
+		t.Fatal("write index:", err)
+	}
+
+	client := Client{logInfo: t.Logf}.WithConfigLabels(map[string]string{QuayExpiresAfterLabel: "2w"})
+
+	target := host + "/target:v1.0.0"
+	if err := client.CopyImageAndWait(context.Background(), source, "", target, "", nil); err != nil {
+		t.Fatal("copy image:", err)
+	}
+
+	targetReference, err := name.ParseReference(target, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	targetIndex, err := remote.Index(targetReference)
+	if err != nil {
+		t.Fatal("get target index:", err)
+	}
+
+	indexManifest, err := targetIndex.IndexManifest()
+	if err != nil {
+		t.Fatal("get index manifest:", err)
+	}
+
+	if len(indexManifest.Manifests) != 2 {
+		t.Fatalf("expected 2 manifests in target index, actual %v", len(indexManifest.Manifests))
+	}
+
+	for _, manifest := range indexManifest.Manifests {
+		if manifest.Platform == nil {
+			t.Fatalf("expected manifest %s to keep its platform", manifest.Digest)
+		}
+
+		image, err := targetIndex.Image(manifest.Digest)
+		if err != nil {
+			t.Fatal("get image:", err)
+		}
+
+		configFile, err := image.ConfigFile()
+		if err != nil {
+			t.Fatal("get config file:", err)
+		}
+
+		if configFile.Config.Labels[QuayExpiresAfterLabel] != "2w" {
+			t.Errorf("expected %s image to have label %s=2w, actual labels %v", manifest.Platform.Architecture, QuayExpiresAfterLabel, configFile.Config.Labels)
+		}
+	}
+}
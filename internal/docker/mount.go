@@ -0,0 +1,184 @@
+package docker
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// blobMounts records the repositories of each registry that the layers have been copied to, so that a layer
+// that is shared by several images (e.g. the layers of a common base image) is mounted from the repository it
+// was already copied to, rather than being uploaded to every repository again.
+type blobMounts struct {
+	mutex        sync.Mutex
+	repositories map[string]map[v1.Hash]name.Repository
+}
+
+func newBlobMounts() *blobMounts {
+	return &blobMounts{
+		repositories: make(map[string]map[v1.Hash]name.Repository),
+	}
+}
+
+// find returns the repository of the registry that the layer has been copied to, if any.
+func (m *blobMounts) find(registry string, digest v1.Hash) (name.Repository, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	repository, ok := m.repositories[registry][digest]
+	return repository, ok
+}
+
+func (m *blobMounts) add(repository name.Repository, digest v1.Hash) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	registry := repository.RegistryStr()
+	if m.repositories[registry] == nil {
+		m.repositories[registry] = make(map[v1.Hash]name.Repository)
+	}
+
+	if _, ok := m.repositories[registry][digest]; !ok {
+		m.repositories[registry][digest] = repository
+	}
+}
+
+// recordImage records that the layers of the image have been copied to the repository.
+func (m *blobMounts) recordImage(repository name.Repository, image v1.Image) error {
+	layers, err := image.Layers()
+	if err != nil {
+		return fmt.Errorf("get layers: %w", err)
+	}
+
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return fmt.Errorf("get digest: %w", err)
+		}
+
+		m.add(repository, digest)
+	}
+
+	return nil
+}
+
+// recordIndex records that the layers of every image in the index have been copied to the repository.
+func (m *blobMounts) recordIndex(repository name.Repository, index v1.ImageIndex) error {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return fmt.Errorf("get index manifest: %w", err)
+	}
+
+	for _, manifest := range indexManifest.Manifests {
+		if isIndex(manifest.MediaType) {
+			childIndex, err := index.ImageIndex(manifest.Digest)
+			if err != nil {
+				return fmt.Errorf("get index %s: %w", manifest.Digest, err)
+			}
+
+			if err := m.recordIndex(repository, childIndex); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		image, err := index.Image(manifest.Digest)
+		if err != nil {
+			return fmt.Errorf("get image %s: %w", manifest.Digest, err)
+		}
+
+		if err := m.recordImage(repository, image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mountableImage is an image whose layers are mounted from another repository of the target registry when
+// the layers have already been copied there. Layers that are not found at the target are uploaded as usual.
+type mountableImage struct {
+	v1.Image
+
+	mounts *blobMounts
+	target name.Repository
+}
+
+func (i mountableImage) Layers() ([]v1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	mountableLayers := make([]v1.Layer, 0, len(layers))
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, err
+		}
+
+		repository, ok := i.mounts.find(i.target.RegistryStr(), digest)
+		if !ok || repository.String() == i.target.String() {
+			mountableLayers = append(mountableLayers, layer)
+			continue
+		}
+
+		mountableLayers = append(mountableLayers, &remote.MountableLayer{
+			Layer:     layer,
+			Reference: repository.Digest(digest.String()),
+		})
+	}
+
+	return mountableLayers, nil
+}
+
+// mountableIndex is an index whose images mount their layers in the same way as a mountableImage.
+// The index is not embedded, as the ImageIndex method would conflict with the name of the field.
+type mountableIndex struct {
+	index  v1.ImageIndex
+	mounts *blobMounts
+	target name.Repository
+}
+
+func (i mountableIndex) MediaType() (types.MediaType, error) {
+	return i.index.MediaType()
+}
+
+func (i mountableIndex) Digest() (v1.Hash, error) {
+	return i.index.Digest()
+}
+
+func (i mountableIndex) Size() (int64, error) {
+	return i.index.Size()
+}
+
+func (i mountableIndex) IndexManifest() (*v1.IndexManifest, error) {
+	return i.index.IndexManifest()
+}
+
+func (i mountableIndex) RawManifest() ([]byte, error) {
+	return i.index.RawManifest()
+}
+
+func (i mountableIndex) Image(digest v1.Hash) (v1.Image, error) {
+	image, err := i.index.Image(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return mountableImage{Image: image, mounts: i.mounts, target: i.target}, nil
+}
+
+func (i mountableIndex) ImageIndex(digest v1.Hash) (v1.ImageIndex, error) {
+	index, err := i.index.ImageIndex(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return mountableIndex{index: index, mounts: i.mounts, target: i.target}, nil
+}
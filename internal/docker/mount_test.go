@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestCopyImageAndWait_MountsLayers(t *testing.T) {
+	var mutex sync.Mutex
+	var mountedFrom []string
+
+	registryHandler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// The test registry stores blobs globally, so blobs are reported as missing from
+		// the second repository to behave like a registry that stores blobs per repository.
+		if r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/v2/second/blobs/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Method == http.MethodPost && r.URL.Query().Get("from") != "" {
+			mutex.Lock()
+			mountedFrom = append(mountedFrom, r.URL.Query().Get("from"))
+			mutex.Unlock()
+		}
+
+		registryHandler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	image, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	source := host + "/source:v1.0.0"
+	sourceReference, err := name.ParseReference(source, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(sourceReference, image); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	client := Client{
+		logInfo: t.Logf,
+		mounts:  newBlobMounts(),
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), source, "", host+"/first:v1.0.0", "", nil); err != nil {
+		t.Fatal("copy image to first repository:", err)
+	}
+
+	mutex.Lock()
+	mountedFrom = nil
+	mutex.Unlock()
+
+	if err := client.CopyImageAndWait(context.Background(), source, "", host+"/second:v1.0.0", "", nil); err != nil {
+		t.Fatal("copy image to second repository:", err)
+	}
+
+	// Only the layer is mounted, the config of the image is uploaded.
+	if len(mountedFrom) != 1 || mountedFrom[0] != "first" {
+		t.Errorf("expected layer to be mounted from the first repository, actual %v", mountedFrom)
+	}
+}
@@ -0,0 +1,165 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// GetMissingPlatforms returns the platforms (e.g. linux/arm64) that the image does not provide. The platforms of a
+// multi-arch image are the platforms of the images in its index, and a single image provides only the platform of its config.
+func (c Client) GetMissingPlatforms(ctx context.Context, image string, auth string, platforms []string) ([]string, error) {
+	reference, err := c.parseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return nil, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	descriptor, err := remote.Get(reference, c.remoteOptions(ctx, authenticator)...)
+	if err != nil {
+		return nil, fmt.Errorf("get image: %w", err)
+	}
+
+	var imagePlatforms []v1.Platform
+	if isIndex(descriptor.MediaType) {
+		index, err := descriptor.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("get index: %w", err)
+		}
+
+		imagePlatforms, err = getIndexPlatforms(index)
+		if err != nil {
+			return nil, fmt.Errorf("get index platforms: %w", err)
+		}
+	} else {
+		image, err := descriptor.Image()
+		if err != nil {
+			return nil, fmt.Errorf("get image: %w", err)
+		}
+
+		imagePlatform, err := getImagePlatform(image)
+		if err != nil {
+			return nil, fmt.Errorf("get image platform: %w", err)
+		}
+
+		imagePlatforms = append(imagePlatforms, imagePlatform)
+	}
+
+	var missingPlatforms []string
+	for _, platform := range platforms {
+		requiredPlatform, err := ParsePlatform(platform)
+		if err != nil {
+			return nil, fmt.Errorf("parse platform: %w", err)
+		}
+
+		var found bool
+		for _, imagePlatform := range imagePlatforms {
+			if platformsContain([]v1.Platform{requiredPlatform}, imagePlatform) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missingPlatforms = append(missingPlatforms, platform)
+		}
+	}
+
+	return missingPlatforms, nil
+}
+
+// getIndexPlatforms returns the platforms of the images in the index. Windows images include the
+// version of Windows that they are built for, as an index can have an image for each version.
+func getIndexPlatforms(index v1.ImageIndex) ([]v1.Platform, error) {
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("get index manifest: %w", err)
+	}
+
+	var platforms []v1.Platform
+	for _, manifest := range indexManifest.Manifests {
+		if manifest.Platform != nil {
+			platforms = append(platforms, *manifest.Platform)
+		}
+	}
+
+	return platforms, nil
+}
+
+// getImagePlatform returns the platform of a single image from its config.
+func getImagePlatform(image v1.Image) (v1.Platform, error) {
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return v1.Platform{}, fmt.Errorf("get config file: %w", err)
+	}
+
+	platform := v1.Platform{
+		OS:           configFile.OS,
+		Architecture: configFile.Architecture,
+		OSVersion:    configFile.OSVersion,
+	}
+
+	return platform, nil
+}
+
+func parsePlatforms(platforms []string) ([]v1.Platform, error) {
+	var parsedPlatforms []v1.Platform
+	for _, platform := range platforms {
+		parsedPlatform, err := ParsePlatform(platform)
+		if err != nil {
+			return nil, fmt.Errorf("parse platform: %w", err)
+		}
+
+		parsedPlatforms = append(parsedPlatforms, parsedPlatform)
+	}
+
+	return parsedPlatforms, nil
+}
+
+// formatPlatform returns the platform in the form of os/arch[/variant][:os.version] (e.g. windows/amd64:10.0.17763.1879).
+func formatPlatform(platform v1.Platform) string {
+	formatted := platform.OS + "/" + platform.Architecture
+	if platform.Variant != "" {
+		formatted += "/" + platform.Variant
+	}
+
+	if platform.OSVersion != "" {
+		formatted += ":" + platform.OSVersion
+	}
+
+	return formatted
+}
+
+// warnExcludedPlatforms logs a warning when none of the platforms of the image are in the given platforms,
+// such as a Windows-only image when only linux/amd64 is given. The image is copied with all of its platforms
+// in that case, as restricting it to the given platforms would leave nothing to copy.
+func (c Client) warnExcludedPlatforms(image string, imagePlatforms []v1.Platform, platforms []string) error {
+	requiredPlatforms, err := parsePlatforms(platforms)
+	if err != nil {
+		return err
+	}
+
+	var formattedPlatforms []string
+	for _, imagePlatform := range imagePlatforms {
+		if platformsContain(requiredPlatforms, imagePlatform) {
+			return nil
+		}
+
+		formattedPlatforms = append(formattedPlatforms, formatPlatform(imagePlatform))
+	}
+
+	if len(formattedPlatforms) == 0 {
+		return nil
+	}
+
+	c.logInfo("Warning: %v only provides %v, which the platforms %v would exclude. Copying all of its platforms ...", image, strings.Join(formattedPlatforms, ", "), strings.Join(platforms, ","))
+
+	return nil
+}
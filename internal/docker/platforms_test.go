@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestGetMissingPlatforms(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	var addendums []mutate.IndexAddendum
+	for _, platform := range []string{"linux/amd64", "linux/arm64/v8"} {
+		image, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		parsedPlatform, err := ParsePlatform(platform)
+		if err != nil {
+			t.Fatal("parse platform:", err)
+		}
+
+		addendums = append(addendums, mutate.IndexAddendum{
+			Add: image,
+			Descriptor: v1.Descriptor{
+				Platform: &parsedPlatform,
+			},
+		})
+	}
+
+	image := host + "/multiarch:v1.0.0"
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.WriteIndex(reference, mutate.AppendManifests(empty.Index, addendums...)); err != nil {
+		t.Fatal("write index:", err)
+	}
+
+	client := Client{
+		logInfo: t.Logf,
+	}
+
+	actual, err := client.GetMissingPlatforms(context.Background(), image, "", []string{"linux/arm64", "linux/amd64", "linux/s390x"})
+	if err != nil {
+		t.Fatal("get missing platforms:", err)
+	}
+
+	expected := []string{"linux/s390x"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected missing platforms %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// RateLimiter limits how often images are pulled from each registry.
+type RateLimiter struct {
+	interval time.Duration
+	mutex    sync.Mutex
+	next     map[string]time.Time
+}
+
+// NewRateLimiter returns a rate limiter that allows the given number of
+// images to be pulled from each registry per minute.
+func NewRateLimiter(perMinute int) *RateLimiter {
+	return &RateLimiter{
+		interval: time.Minute / time.Duration(perMinute),
+		next:     make(map[string]time.Time),
+	}
+}
+
+// Wait blocks until the image can be pulled from its registry without exceeding the rate limit.
+func (r *RateLimiter) Wait(ctx context.Context, image string) error {
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return fmt.Errorf("parse ref: %w", err)
+	}
+
+	wait := r.reserve(reference.Context().RegistryStr(), time.Now())
+	if wait == 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve reserves the next available time to pull from the registry
+// and returns how long to wait until that time.
+func (r *RateLimiter) reserve(registry string, now time.Time) time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	next := r.next[registry]
+	if next.Before(now) {
+		next = now
+	}
+
+	r.next[registry] = next.Add(r.interval)
+
+	return next.Sub(now)
+}
+
+// getDockerHubWait returns how long to wait for a pull to become available when the
+// Docker Hub pull quota has been exhausted. Docker Hub reports the quota in the
+// RateLimit-Limit header (e.g. 100;w=21600 is 100 pulls per 6 hours) and pulls become
+// available again as they fall out of the window.
+func getDockerHubWait(header http.Header) (time.Duration, bool) {
+	limitTokens := strings.Split(header.Get("RateLimit-Limit"), ";w=")
+	if len(limitTokens) != 2 {
+		return 0, false
+	}
+
+	limit, err := strconv.Atoi(limitTokens[0])
+	if err != nil || limit <= 0 {
+		return 0, false
+	}
+
+	window, err := strconv.Atoi(limitTokens[1])
+	if err != nil || window <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(window) * time.Second / time.Duration(limit), true
+}
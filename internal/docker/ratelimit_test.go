@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Reserve(t *testing.T) {
+	rateLimiter := NewRateLimiter(60)
+	now := time.Now()
+
+	if wait := rateLimiter.reserve("quay.io", now); wait != 0 {
+		t.Errorf("expected first pull to not wait, actual %v", wait)
+	}
+
+	if wait := rateLimiter.reserve("quay.io", now); wait != time.Second {
+		t.Errorf("expected second pull to wait %v, actual %v", time.Second, wait)
+	}
+
+	if wait := rateLimiter.reserve("gcr.io", now); wait != 0 {
+		t.Errorf("expected pull from another registry to not wait, actual %v", wait)
+	}
+}
+
+func TestGetDockerHubWait(t *testing.T) {
+	header := http.Header{}
+	header.Set("RateLimit-Limit", "100;w=21600")
+	header.Set("RateLimit-Remaining", "0;w=21600")
+
+	wait, ok := getDockerHubWait(header)
+	if !ok {
+		t.Fatal("expected a wait from the rate limit headers")
+	}
+
+	if expected := 216 * time.Second; wait != expected {
+		t.Errorf("expected wait %v, actual %v", expected, wait)
+	}
+
+	if _, ok := getDockerHubWait(http.Header{}); ok {
+		t.Error("expected no wait without rate limit headers")
+	}
+}
@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// GetRepositories returns all of the repositories of the registry at the host, using the catalog
+// API of the registry. Registries that do not implement the catalog API (e.g. ECR) return an error.
+func (c Client) GetRepositories(ctx context.Context, host string, auth string) ([]string, error) {
+	registry, err := c.newRegistry(host)
+	if err != nil {
+		return nil, fmt.Errorf("new registry: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return nil, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	repositories, err := remote.Catalog(ctx, registry, c.remoteOptions(ctx, authenticator)...)
+	if err != nil {
+		return nil, fmt.Errorf("catalog: %w", err)
+	}
+
+	return repositories, nil
+}
+
+// GetTags returns all of the tags of the repository (e.g. mycompany.com/myteam/busybox).
+func (c Client) GetTags(ctx context.Context, repository string, auth string) ([]string, error) {
+	repo, err := c.newRepository(repository)
+	if err != nil {
+		return nil, fmt.Errorf("new repo: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return nil, fmt.Errorf("get authenticator: %w", err)
+	}
+
+	tags, err := remote.ListWithContext(ctx, repo, c.remoteOptions(ctx, authenticator)...)
+	if err != nil {
+		return nil, fmt.Errorf("list: %w", err)
+	}
+
+	return tags, nil
+}
+
+// DeleteImage deletes the manifest of the image from the remote registry. Most registries only
+// allow manifests to be deleted by their digest, which also removes every tag of the manifest.
+func (c Client) DeleteImage(ctx context.Context, image string, auth string) error {
+	reference, err := c.parseReference(image)
+	if err != nil {
+		return fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return fmt.Errorf("get authenticator: %w", err)
+	}
+
+	if err := remote.Delete(reference, c.remoteOptions(ctx, authenticator)...); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// RegistryCheck is the result of checking that a registry can be reached and that it accepts the credentials for it.
+type RegistryCheck struct {
+	Registry   string
+	Repository string
+
+	// Anonymous is true when the registry was accessed without credentials.
+	Anonymous bool
+
+	Reachable  bool
+	Authorized bool
+
+	// RateLimit describes the pull quota that the registry reports, such as the pull quota of Docker Hub
+	// (e.g. 98/100 pulls remaining per 6h0m0s), or is empty when the registry does not report one.
+	RateLimit string
+
+	// Err is the reason that the registry could not be reached or did not accept the credentials.
+	Err error
+}
+
+// dockerHubRateLimitRepository is the repository that Docker Hub reports the pull quota of the user for,
+// without counting the request against the quota.
+const dockerHubRateLimitRepository = "ratelimitpreview/test"
+
+// CheckRegistry checks that the registry of the repository can be reached with the settings of the client (e.g.
+// the CA certificate of the registry), and that the registry accepts the auth for pulling from the repository,
+// or for pushing to it when push is set. A repository that does not exist yet is not an error.
+func (c Client) CheckRegistry(ctx context.Context, repository string, auth string, push bool) RegistryCheck {
+	check := RegistryCheck{Repository: repository}
+
+	repo, err := c.newRepository(repository)
+	if err != nil {
+		check.Err = fmt.Errorf("new repo: %w", err)
+		return check
+	}
+
+	check.Registry = repo.RegistryStr()
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		check.Err = fmt.Errorf("get authenticator: %w", err)
+		return check
+	}
+
+	check.Anonymous = isAnonymous(authenticator)
+
+	roundTripper := contextTransport{ctx: ctx, inner: c.newTransport()}
+	if err := pingRegistry(ctx, repo.Registry, roundTripper); err != nil {
+		check.Err = err
+		return check
+	}
+
+	check.Reachable = true
+
+	scope := repo.Scope(transport.PullScope)
+	if push {
+		scope = repo.Scope(transport.PushScope)
+	}
+
+	authTransport, err := transport.New(repo.Registry, authenticator, roundTripper, []string{scope})
+	if err != nil {
+		check.Err = fmt.Errorf("authenticate: %w", err)
+		return check
+	}
+
+	response, err := registryGet(ctx, authTransport, repo.Registry, "/v2/"+repo.RepositoryStr()+"/tags/list")
+	if err != nil {
+		check.Err = fmt.Errorf("list tags: %w", err)
+		return check
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNotFound {
+		check.Err = getRegistryCheckError(response)
+		return check
+	}
+
+	check.Authorized = true
+	check.RateLimit = getRateLimit(response.Header)
+
+	if check.Registry == name.DefaultRegistry {
+		check.RateLimit = getDockerHubRateLimit(ctx, authenticator, roundTripper)
+	}
+
+	return check
+}
+
+// getDockerHubRateLimit returns the pull quota that Docker Hub reports for the auth, or an empty string when it cannot be found.
+func getDockerHubRateLimit(ctx context.Context, authenticator authn.Authenticator, roundTripper http.RoundTripper) string {
+	repo, err := name.NewRepository(dockerHubRateLimitRepository)
+	if err != nil {
+		return ""
+	}
+
+	authTransport, err := transport.New(repo.Registry, authenticator, roundTripper, []string{repo.Scope(transport.PullScope)})
+	if err != nil {
+		return ""
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, repo.Registry.Scheme()+"://"+repo.RegistryStr()+"/v2/"+repo.RepositoryStr()+"/manifests/latest", nil)
+	if err != nil {
+		return ""
+	}
+
+	response, err := authTransport.RoundTrip(request)
+	if err != nil {
+		return ""
+	}
+	response.Body.Close()
+
+	return getRateLimit(response.Header)
+}
+
+// pingRegistry returns an error when the registry cannot be reached, such as when the
+// registry does not exist or its certificate is not trusted. Any response from the
+// /v2/ endpoint, including a response that requires authentication, is a success.
+func pingRegistry(ctx context.Context, registry name.Registry, roundTripper http.RoundTripper) error {
+	response, err := registryGet(ctx, roundTripper, registry, "/v2/")
+	if err != nil {
+		return fmt.Errorf("ping: %w", err)
+	}
+	defer response.Body.Close()
+
+	io.Copy(ioutil.Discard, response.Body)
+
+	return nil
+}
+
+func registryGet(ctx context.Context, roundTripper http.RoundTripper, registry name.Registry, path string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, registry.Scheme()+"://"+registry.RegistryStr()+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	response, err := roundTripper.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// getRegistryCheckError returns the error of a response that did not accept the credentials.
+func getRegistryCheckError(response *http.Response) error {
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+
+	message := strings.TrimSpace(string(body))
+	if message == "" {
+		message = http.StatusText(response.StatusCode)
+	}
+
+	return fmt.Errorf("status %v: %s", response.StatusCode, message)
+}
+
+// getRateLimit returns the pull quota in the RateLimit-Limit and RateLimit-Remaining headers that Docker Hub
+// reports (e.g. 100;w=21600 and 98;w=21600), or an empty string when the headers are not set.
+func getRateLimit(header http.Header) string {
+	limit := strings.Split(header.Get("RateLimit-Limit"), ";w=")
+	remaining := strings.Split(header.Get("RateLimit-Remaining"), ";w=")
+	if limit[0] == "" || remaining[0] == "" {
+		return ""
+	}
+
+	rateLimit := fmt.Sprintf("%s/%s pulls remaining", remaining[0], limit[0])
+	if len(limit) == 2 {
+		if window, err := strconv.Atoi(limit[1]); err == nil {
+			rateLimit += fmt.Sprintf(" per %v", time.Duration(window)*time.Second)
+		}
+	}
+
+	return rateLimit
+}
@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+)
+
+func TestCheckRegistry(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	check := Client{logInfo: t.Logf}.CheckRegistry(context.Background(), host+"/mirror/busybox", "", true)
+	if check.Err != nil {
+		t.Fatal("check registry:", check.Err)
+	}
+
+	if !check.Reachable || !check.Authorized || !check.Anonymous {
+		t.Errorf("expected registry to be reachable and authorized anonymously, actual %+v", check)
+	}
+}
+
+func TestCheckRegistry_Unauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		if username != "user" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			http.Error(w, "invalid credentials", http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte(`{"tags":[]}`))
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	auth, err := GetEncodedBasicAuth("user", "wrong")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	check := Client{logInfo: t.Logf}.CheckRegistry(context.Background(), host+"/mirror/busybox", auth, false)
+	if !check.Reachable || check.Authorized || check.Anonymous {
+		t.Errorf("expected registry to be reachable but not authorized, actual %+v", check)
+	}
+
+	if check.Err == nil || !strings.Contains(check.Err.Error(), "invalid credentials") {
+		t.Errorf("expected error with the response of the registry, actual %v", check.Err)
+	}
+
+	auth, err = GetEncodedBasicAuth("user", "secret")
+	if err != nil {
+		t.Fatal("get encoded basic auth:", err)
+	}
+
+	check = Client{logInfo: t.Logf}.CheckRegistry(context.Background(), host+"/mirror/busybox", auth, false)
+	if !check.Authorized {
+		t.Errorf("expected registry to be authorized, actual %+v", check)
+	}
+}
+
+func TestCheckRegistry_Unreachable(t *testing.T) {
+	reg := testutil.NewRegistry(t)
+	host := reg.Host
+	reg.Close()
+
+	check := Client{logInfo: t.Logf}.CheckRegistry(context.Background(), host+"/mirror/busybox", "", false)
+	if check.Reachable || check.Err == nil {
+		t.Errorf("expected registry to be unreachable, actual %+v", check)
+	}
+}
+
+func TestGetRateLimit(t *testing.T) {
+	header := make(http.Header)
+	header.Set("RateLimit-Limit", "100;w=21600")
+	header.Set("RateLimit-Remaining", "98;w=21600")
+
+	expected := "98/100 pulls remaining per 6h0m0s"
+	if actual := getRateLimit(header); actual != expected {
+		t.Errorf("expected %q, actual %q", expected, actual)
+	}
+
+	if actual := getRateLimit(make(http.Header)); actual != "" {
+		t.Errorf("expected no rate limit, actual %q", actual)
+	}
+}
@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// RegistryConfig configures the connections to a registry (e.g. a registry that
+// is behind a corporate proxy and uses a certificate signed by a private CA).
+type RegistryConfig struct {
+	// CACert is the path to a PEM encoded CA certificate (or bundle) that is trusted in
+	// addition to the system certificates when verifying the certificate of the registry.
+	CACert string `mapstructure:"ca-cert"`
+
+	// InsecureSkipTLSVerify disables the verification of the certificate of the registry.
+	InsecureSkipTLSVerify bool `mapstructure:"insecure-skip-tls-verify"`
+
+	// Insecure connects to the registry over plain HTTP instead of HTTPS (e.g. a local registry used in development).
+	Insecure bool `mapstructure:"insecure"`
+
+	// Proxy is the URL of the proxy that the requests to the registry are sent through. When
+	// not set, the proxy is read from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string `mapstructure:"proxy"`
+
+	// MaxBandwidth is the maximum rate at which images are transferred to and from the registry (e.g. 10MiB/s).
+	MaxBandwidth string `mapstructure:"max-bandwidth"`
+
+	// MaxConcurrency is the maximum number of images that are copied to or from the registry,
+	// or checked at the registry, at the same time. Zero does not limit the concurrency.
+	MaxConcurrency int `mapstructure:"max-concurrency"`
+
+	// Timeout is how long to wait for the registry to respond to each request, after the
+	// request has been sent, before the request fails. Zero waits indefinitely.
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// Retries is the number of times that failed copies to or from the registry are attempted
+	// again, instead of the number of retries of the client.
+	Retries *int `mapstructure:"retries"`
+}
+
+func (r RegistryConfig) isEmpty() bool {
+	return r.CACert == "" && !r.InsecureSkipTLSVerify && r.Proxy == "" && r.Timeout == 0
+}
+
+// WithRegistryConfig returns a copy of the client that connects to registries with the given config. The config of
+// a registry in the registries (keyed by the host of the registry) takes precedence over the default config.
+func (c Client) WithRegistryConfig(defaultConfig RegistryConfig, registries map[string]RegistryConfig) (Client, error) {
+	transport := registryTransport{
+		registries: make(map[string]http.RoundTripper),
+	}
+
+	c.insecure = make(map[string]bool)
+
+	var err error
+	transport.defaultTransport, err = newRegistryTransport(defaultConfig)
+	if err != nil {
+		return Client{}, fmt.Errorf("new default transport: %w", err)
+	}
+
+	for host, config := range registries {
+		if config.CACert == "" {
+			config.CACert = defaultConfig.CACert
+		}
+
+		if config.Proxy == "" {
+			config.Proxy = defaultConfig.Proxy
+		}
+
+		if config.Timeout == 0 {
+			config.Timeout = defaultConfig.Timeout
+		}
+
+		config.InsecureSkipTLSVerify = config.InsecureSkipTLSVerify || defaultConfig.InsecureSkipTLSVerify
+
+		transport.registries[strings.ToLower(host)], err = newRegistryTransport(config)
+		if err != nil {
+			return Client{}, fmt.Errorf("new transport for %s: %w", host, err)
+		}
+
+		if config.Insecure {
+			c.insecure[strings.ToLower(host)] = true
+		}
+	}
+
+	c.transport = transport
+	c.limits = newRegistryLimits(registries)
+
+	return c, nil
+}
+
+// registryTransport sends each request with the transport of the registry it is sent to.
+type registryTransport struct {
+	defaultTransport http.RoundTripper
+	registries       map[string]http.RoundTripper
+}
+
+func (t registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if transport, ok := t.registries[strings.ToLower(req.URL.Host)]; ok {
+		return transport.RoundTrip(req)
+	}
+
+	return t.defaultTransport.RoundTrip(req)
+}
+
+func newRegistryTransport(config RegistryConfig) (http.RoundTripper, error) {
+	if config.isEmpty() {
+		return http.DefaultTransport, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.CACert != "" || config.InsecureSkipTLSVerify {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: config.InsecureSkipTLSVerify,
+		}
+
+		if config.CACert != "" {
+			rootCAs, err := getCertPool(config.CACert)
+			if err != nil {
+				return nil, fmt.Errorf("get cert pool: %w", err)
+			}
+
+			tlsConfig.RootCAs = rootCAs
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if config.Timeout > 0 {
+		transport.ResponseHeaderTimeout = config.Timeout
+	}
+
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy: %w", err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return transport, nil
+}
+
+// getCertPool returns the system certificates with the certificates in the file at the path added.
+func getCertPool(path string) (*x509.CertPool, error) {
+	certs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read ca cert: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(certs) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// parseReference parses the image reference. References to insecure registries are accessed over plain HTTP.
+func (c Client) parseReference(image string) (name.Reference, error) {
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.insecure[strings.ToLower(reference.Context().RegistryStr())] {
+		return reference, nil
+	}
+
+	return name.ParseReference(image, name.WeakValidation, name.Insecure)
+}
+
+// newRepository returns the repository. Repositories of insecure registries are accessed over plain HTTP.
+func (c Client) newRepository(repository string) (name.Repository, error) {
+	parsedRepository, err := name.NewRepository(repository)
+	if err != nil {
+		return name.Repository{}, err
+	}
+
+	if !c.insecure[strings.ToLower(parsedRepository.RegistryStr())] {
+		return parsedRepository, nil
+	}
+
+	return name.NewRepository(repository, name.Insecure)
+}
+
+// newRegistry returns the registry. Insecure registries are accessed over plain HTTP.
+func (c Client) newRegistry(host string) (name.Registry, error) {
+	if c.insecure[strings.ToLower(host)] {
+		return name.NewRegistry(host, name.WeakValidation, name.Insecure)
+	}
+
+	return name.NewRegistry(host, name.WeakValidation)
+}
@@ -0,0 +1,143 @@
+package docker
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRegistryConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	caCertPath := filepath.Join(tempDir, "ca.pem")
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caCertPath, caCert, 0644); err != nil {
+		t.Fatal("write ca cert:", err)
+	}
+
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	testCases := []struct {
+		name          string
+		defaultConfig RegistryConfig
+		registries    map[string]RegistryConfig
+		expectedErr   bool
+	}{
+		{
+			name:        "untrusted certificate",
+			expectedErr: true,
+		},
+		{
+			name:          "default ca cert",
+			defaultConfig: RegistryConfig{CACert: caCertPath},
+		},
+		{
+			name:       "registry ca cert",
+			registries: map[string]RegistryConfig{host: {CACert: caCertPath}},
+		},
+		{
+			name:       "registry skips verification",
+			registries: map[string]RegistryConfig{host: {InsecureSkipTLSVerify: true}},
+		},
+		{
+			name:        "other registry skips verification",
+			registries:  map[string]RegistryConfig{"mycompany.com": {InsecureSkipTLSVerify: true}},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			client, err := Client{}.WithRegistryConfig(testCase.defaultConfig, testCase.registries)
+			if err != nil {
+				t.Fatal("with registry config:", err)
+			}
+
+			resp, err := (&http.Client{Transport: client.transport}).Get(server.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			if testCase.expectedErr && err == nil {
+				t.Error("expected request to fail")
+			}
+
+			if !testCase.expectedErr && err != nil {
+				t.Error("expected request to succeed:", err)
+			}
+		})
+	}
+}
+
+func TestWithRegistryConfig_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	// The registry does not set a timeout of its own, so the default timeout applies to it.
+	defaultConfig := RegistryConfig{Timeout: 50 * time.Millisecond}
+	registries := map[string]RegistryConfig{host: {MaxConcurrency: 1}}
+
+	client, err := Client{}.WithRegistryConfig(defaultConfig, registries)
+	if err != nil {
+		t.Fatal("with registry config:", err)
+	}
+
+	resp, err := (&http.Client{Transport: client.transport}).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Error("expected request to time out")
+	}
+
+	registries[host] = RegistryConfig{Timeout: time.Second}
+	client, err = Client{}.WithRegistryConfig(defaultConfig, registries)
+	if err != nil {
+		t.Fatal("with registry config:", err)
+	}
+
+	resp, err = (&http.Client{Transport: client.transport}).Get(server.URL)
+	if err != nil {
+		t.Error("expected the timeout of the registry to take precedence:", err)
+	} else {
+		resp.Body.Close()
+	}
+}
+
+func TestParseReference_Insecure(t *testing.T) {
+	client, err := Client{}.WithRegistryConfig(RegistryConfig{}, map[string]RegistryConfig{"registry.mycompany.com": {Insecure: true}})
+	if err != nil {
+		t.Fatal("with registry config:", err)
+	}
+
+	testCases := map[string]string{
+		"registry.mycompany.com/busybox:1.32.0": "http",
+		"mycompany.com/busybox:1.32.0":          "https",
+	}
+
+	for image, expected := range testCases {
+		reference, err := client.parseReference(image)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if actual := reference.Context().Registry.Scheme(); actual != expected {
+			t.Errorf("expected scheme of %s to be %s, actual %s", image, expected, actual)
+		}
+	}
+}
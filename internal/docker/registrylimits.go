@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// registryLimits are the limits of the registries (keyed by their host) that are set in their config.
+type registryLimits struct {
+	slots   map[string]chan struct{}
+	retries map[string]int
+}
+
+func newRegistryLimits(registries map[string]RegistryConfig) registryLimits {
+	limits := registryLimits{
+		slots:   make(map[string]chan struct{}),
+		retries: make(map[string]int),
+	}
+
+	for host, config := range registries {
+		host = strings.ToLower(host)
+		if host == "docker.io" {
+			host = name.DefaultRegistry
+		}
+
+		if config.MaxConcurrency > 0 {
+			limits.slots[host] = make(chan struct{}, config.MaxConcurrency)
+		}
+
+		if config.Retries != nil && *config.Retries >= 0 {
+			limits.retries[host] = *config.Retries
+		}
+	}
+
+	return limits
+}
+
+// acquire blocks until an operation on the images can start without exceeding the maximum number of concurrent
+// operations of their registries, and returns the function that releases the registries once the operation is done.
+// The registries are acquired in the order of their hosts, so that operations on the same registries cannot deadlock.
+func (c Client) acquire(ctx context.Context, images ...string) (func(), error) {
+	var hosts []string
+	for _, image := range images {
+		host := getRegistryHost(image)
+		if _, ok := c.limits.slots[host]; ok && !containsHost(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+
+	var acquired []string
+	release := func() {
+		for _, host := range acquired {
+			<-c.limits.slots[host]
+		}
+	}
+
+	for _, host := range hosts {
+		select {
+		case c.limits.slots[host] <- struct{}{}:
+			acquired = append(acquired, host)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	return release, nil
+}
+
+// getAttempts returns the number of times an operation on the images is attempted. When the config of
+// any of their registries sets the number of retries, the largest number of retries of the registries
+// is used instead of the number of retries of the client.
+func (c Client) getAttempts(images ...string) (uint, bool) {
+	var attempts uint
+	var found bool
+	for _, image := range images {
+		retries, ok := c.limits.retries[getRegistryHost(image)]
+		if !ok {
+			continue
+		}
+
+		if !found || uint(retries)+1 > attempts {
+			attempts = uint(retries) + 1
+		}
+		found = true
+	}
+
+	return attempts, found
+}
+
+func getRegistryHost(image string) string {
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(reference.Context().RegistryStr())
+}
+
+func containsHost(hosts []string, host string) bool {
+	for _, currentHost := range hosts {
+		if currentHost == host {
+			return true
+		}
+	}
+
+	return false
+}
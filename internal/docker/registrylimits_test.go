@@ -0,0 +1,64 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	one := 1
+	client := Client{
+		limits: newRegistryLimits(map[string]RegistryConfig{
+			"registry.mycompany.com": {MaxConcurrency: 1, Retries: &one},
+			"docker.io":              {MaxConcurrency: 1},
+		}),
+	}
+
+	release, err := client.acquire(context.Background(), "registry.mycompany.com/repo:v1.0.0", "busybox:latest")
+	if err != nil {
+		t.Fatal("acquire:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.acquire(ctx, "docker.io/library/nginx:latest"); err == nil {
+		t.Error("expected acquiring a registry at its maximum concurrency to wait until the context is done")
+	}
+
+	if _, err := client.acquire(context.Background(), "quay.io/repo:v1.0.0"); err != nil {
+		t.Error("expected registries without a maximum concurrency to not be limited, actual", err)
+	}
+
+	release()
+
+	release, err = client.acquire(context.Background(), "busybox:latest")
+	if err != nil {
+		t.Fatal("acquire after release:", err)
+	}
+	release()
+}
+
+func TestGetAttempts(t *testing.T) {
+	zero := 0
+	three := 3
+	client := Client{
+		limits: newRegistryLimits(map[string]RegistryConfig{
+			"registry.mycompany.com": {Retries: &three},
+			"quay.io":                {Retries: &zero},
+		}),
+	}
+
+	if attempts, ok := client.getAttempts("quay.io/repo:v1.0.0", "registry.mycompany.com/repo:v1.0.0"); !ok || attempts != 4 {
+		t.Errorf("expected 4 attempts from the registry with the most retries, actual %v", attempts)
+	}
+
+	if attempts, ok := client.getAttempts("quay.io/repo:v1.0.0"); !ok || attempts != 1 {
+		t.Errorf("expected 1 attempt, actual %v", attempts)
+	}
+
+	if _, ok := client.getAttempts("busybox:latest"); ok {
+		t.Error("expected no attempts for registries without retries")
+	}
+}
@@ -1,6 +1,10 @@
 package docker
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
 
 // RegistryPath is a registry path for a container image.
 type RegistryPath string
@@ -17,32 +21,39 @@ func (r RegistryPath) Digest() string {
 
 // Tag returns the tag in the registry path.
 func (r RegistryPath) Tag() string {
-	if strings.Contains(string(r), "@") || !strings.Contains(string(r), ":") {
+	if strings.Contains(string(r), "@") {
 		return ""
 	}
 
-	tagTokens := strings.Split(string(r), ":")
-	return tagTokens[1]
+	// A colon before the last slash separates the host from its port, not the tag.
+	tagIndex := strings.LastIndex(string(r), ":")
+	if tagIndex < 0 || tagIndex < strings.LastIndex(string(r), "/") {
+		return ""
+	}
+
+	return string(r)[tagIndex+1:]
 }
 
-// Host returns the host in the registry path.
-func (r RegistryPath) Host() string {
+// Host returns the host in the registry path. The host is found with the same rule as the references
+// to images in resources, so the registry hosts of the options (see images.WithRegistryHosts) are hosts.
+func (r RegistryPath) Host(opts ...images.Option) string {
 	host := string(r)
 
 	if r.Tag() != "" {
 		host = strings.ReplaceAll(host, ":"+r.Tag(), "")
 	}
 
-	if !strings.Contains(host, ".") {
-		return ""
+	// A path of only a host (e.g. host.com) is the host of an empty repository.
+	if !strings.Contains(host, "/") {
+		host += "/"
 	}
 
-	hostTokens := strings.Split(string(r), "/")
-	return hostTokens[0]
+	registryHost, _ := images.SplitHost(host, opts...)
+	return registryHost
 }
 
 // Repository is the repository in the registry path.
-func (r RegistryPath) Repository() string {
+func (r RegistryPath) Repository(opts ...images.Option) string {
 	repository := string(r)
 
 	if r.Tag() != "" {
@@ -53,8 +64,8 @@ func (r RegistryPath) Repository() string {
 		repository = strings.ReplaceAll(repository, "@"+r.Digest(), "")
 	}
 
-	if r.Host() != "" {
-		repository = strings.ReplaceAll(repository, r.Host(), "")
+	if host := r.Host(opts...); host != "" {
+		repository = strings.ReplaceAll(repository, host, "")
 	}
 
 	repository = strings.TrimLeft(repository, "/")
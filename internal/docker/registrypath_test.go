@@ -1,6 +1,10 @@
 package docker
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
 
 type registryPathTest struct {
 	actualPath         RegistryPath
@@ -24,6 +28,20 @@ func TestRegistryPath_Empty(t *testing.T) {
 	verifyRegistryPath(t, test)
 }
 
+func TestRegistryPath_HostWithPort(t *testing.T) {
+	path := RegistryPath("127.0.0.1:5000/mirror")
+
+	test := registryPathTest{
+		actualPath:         path,
+		expectedHost:       "127.0.0.1:5000",
+		expectedRepository: "mirror",
+		expectedTag:        "",
+		expectedDigest:     "",
+	}
+
+	verifyRegistryPath(t, test)
+}
+
 func TestRegistryPath_Host(t *testing.T) {
 	path := RegistryPath("host.com")
 
@@ -150,6 +168,51 @@ func TestRegistryPath_Digest(t *testing.T) {
 	verifyRegistryPath(t, test)
 }
 
+func TestRegistryPath_Localhost(t *testing.T) {
+	path := RegistryPath("localhost:5000/mirror/app:v1.0.0")
+
+	test := registryPathTest{
+		actualPath:         path,
+		expectedHost:       "localhost:5000",
+		expectedRepository: "mirror/app",
+		expectedTag:        "v1.0.0",
+		expectedDigest:     "",
+	}
+
+	verifyRegistryPath(t, test)
+}
+
+func TestRegistryPath_Repository_WithDot(t *testing.T) {
+	path := RegistryPath("myteam/app.web:v1.0.0")
+
+	test := registryPathTest{
+		actualPath:         path,
+		expectedHost:       "",
+		expectedRepository: "myteam/app.web",
+		expectedTag:        "v1.0.0",
+		expectedDigest:     "",
+	}
+
+	verifyRegistryPath(t, test)
+}
+
+func TestRegistryPath_RegistryHosts(t *testing.T) {
+	path := RegistryPath("registry/mirror")
+	registryHosts := images.WithRegistryHosts("registry")
+
+	if actual := path.Host(registryHosts); actual != "registry" {
+		t.Errorf("expected host to be registry, actual %s", actual)
+	}
+
+	if actual := path.Repository(registryHosts); actual != "mirror" {
+		t.Errorf("expected repository to be mirror, actual %s", actual)
+	}
+
+	if actual := path.Host(); actual != "" {
+		t.Errorf("expected no host without the registry hosts, actual %s", actual)
+	}
+}
+
 func verifyRegistryPath(t *testing.T, test registryPathTest) {
 	if test.actualPath.Host() != test.expectedHost {
 		t.Errorf("expected host to be %s, actual %s", test.expectedHost, test.actualPath.Host())
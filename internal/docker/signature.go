@@ -0,0 +1,191 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// signatureSuffixes are the suffixes of the tags that cosign stores the
+// signatures and attestations of an image under, in the same repository as the image.
+var signatureSuffixes = []string{".sig", ".att"}
+
+// sbomSuffixes are the suffixes of the tags that SBOMs are stored under. cosign attach sbom
+// uses the .sbom suffix, and ORAS falls back to the OCI referrers tag (no suffix) for
+// registries that do not support the referrers API.
+var sbomSuffixes = []string{".sbom", ""}
+
+// CopySignaturesAndWait copies the cosign signatures and attestations of the source image
+// to the repository of the target image, and returns the tags that were copied.
+// Images that have not been signed have nothing to copy and no error is returned.
+func (c Client) CopySignaturesAndWait(ctx context.Context, source string, sourceAuth string, target string, targetAuth string) ([]string, error) {
+	copiedTags, err := c.copyAttachedTags(ctx, source, sourceAuth, target, targetAuth, signatureSuffixes)
+	if err != nil {
+		return nil, fmt.Errorf("copy signature tags: %w", err)
+	}
+
+	return copiedTags, nil
+}
+
+// CopySBOMsAndWait copies the SBOMs attached to the source image to the repository
+// of the target image, and returns the tags that were copied.
+func (c Client) CopySBOMsAndWait(ctx context.Context, source string, sourceAuth string, target string, targetAuth string) ([]string, error) {
+	copiedTags, err := c.copyAttachedTags(ctx, source, sourceAuth, target, targetAuth, sbomSuffixes)
+	if err != nil {
+		return nil, fmt.Errorf("copy sbom tags: %w", err)
+	}
+
+	return copiedTags, nil
+}
+
+// copyAttachedTags copies the artifacts that are attached to an image by being tagged
+// with the digest of the image and one of the given suffixes.
+func (c Client) copyAttachedTags(ctx context.Context, source string, sourceAuth string, target string, targetAuth string, suffixes []string) ([]string, error) {
+	digest, err := c.GetDigest(ctx, source, sourceAuth)
+	if err != nil {
+		return nil, fmt.Errorf("get digest: %w", err)
+	}
+
+	sourceRepository, err := getRepository(source)
+	if err != nil {
+		return nil, fmt.Errorf("get source repository: %w", err)
+	}
+
+	targetRepository, err := getRepository(target)
+	if err != nil {
+		return nil, fmt.Errorf("get target repository: %w", err)
+	}
+
+	var copiedTags []string
+	for _, suffix := range suffixes {
+		tag := getAttachedTag(digest, suffix)
+
+		exists, err := c.ManifestExistsAtRemote(ctx, sourceRepository+":"+tag, sourceAuth)
+		if err != nil {
+			return nil, fmt.Errorf("manifest exists at remote: %w", err)
+		}
+
+		if !exists {
+			continue
+		}
+
+		if err := c.CopyImageAndWait(ctx, sourceRepository+":"+tag, sourceAuth, targetRepository+":"+tag, targetAuth, nil); err != nil {
+			return nil, fmt.Errorf("copy %s: %w", tag, err)
+		}
+
+		copiedTags = append(copiedTags, tag)
+	}
+
+	return copiedTags, nil
+}
+
+// VerifyOptions are the options used to verify the cosign signature of an image.
+type VerifyOptions struct {
+
+	// Key is the path or URL of the public key to verify the signature with.
+	Key string
+
+	// Identity is the identity of the certificate that signed the image when
+	// keyless signing was used (e.g. an email address or workflow URL).
+	Identity string
+
+	// Issuer is the OIDC issuer of the certificate when keyless signing was used.
+	Issuer string
+}
+
+// VerifySignature verifies the cosign signature of the image. Verification
+// is performed by the cosign CLI, which must be available on the PATH.
+func VerifySignature(ctx context.Context, image string, opts VerifyOptions) error {
+	args, err := getVerifyArgs(image, opts)
+	if err != nil {
+		return fmt.Errorf("get verify args: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "cosign", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cosign verify: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return nil
+}
+
+// VerifyNotationSignature verifies the Notary v2 signature of the image against the trust policy and trust
+// store that notation has been configured with. Verification is performed by the notation CLI, which must be
+// available on the PATH.
+func VerifyNotationSignature(ctx context.Context, image string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "notation", "verify", image)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("notation verify: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return nil
+}
+
+// SignBlob signs the file at the path with the private key and writes the signature to the signature path.
+// Signing is performed by the cosign CLI, which must be available on the PATH. The password of the key
+// is read by cosign from the COSIGN_PASSWORD environment variable.
+func SignBlob(ctx context.Context, path string, key string, signaturePath string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "cosign", "sign-blob", "--key", key, "--output-signature", signaturePath, path)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cosign sign-blob: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return nil
+}
+
+// VerifyBlob verifies the signature of the file at the path, as created by SignBlob, with the public key.
+// Verification is performed by the cosign CLI, which must be available on the PATH.
+func VerifyBlob(ctx context.Context, path string, key string, signaturePath string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "cosign", "verify-blob", "--key", key, "--signature", signaturePath, path)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cosign verify-blob: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return nil
+}
+
+func getVerifyArgs(image string, opts VerifyOptions) ([]string, error) {
+	args := []string{"verify"}
+	if opts.Key != "" {
+		args = append(args, "--key", opts.Key)
+	} else if opts.Identity != "" {
+		args = append(args, "--certificate-identity", opts.Identity)
+		if opts.Issuer != "" {
+			args = append(args, "--certificate-oidc-issuer", opts.Issuer)
+		}
+	} else {
+		return nil, errors.New("a key or identity is required")
+	}
+
+	args = append(args, image)
+
+	return args, nil
+}
+
+// getAttachedTag returns the tag that artifacts attached to the given digest use
+// (e.g. sha256:abc with the suffix .sig becomes sha256-abc.sig).
+func getAttachedTag(digest string, suffix string) string {
+	return strings.Replace(digest, ":", "-", 1) + suffix
+}
+
+func getRepository(image string) (string, error) {
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return "", fmt.Errorf("parse ref: %w", err)
+	}
+
+	return reference.Context().Name(), nil
+}
@@ -0,0 +1,125 @@
+package docker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestCopySignaturesAndWait(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	image := writeRandomImage(t, host+"/source:v1.0.0")
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal("get digest:", err)
+	}
+
+	writeRandomImage(t, host+"/source:"+getAttachedTag(digest.String(), ".sig"))
+
+	client := Client{
+		logInfo: t.Logf,
+	}
+
+	actual, err := client.CopySignaturesAndWait(context.Background(), host+"/source:v1.0.0", "", host+"/target:v1.0.0", "")
+	if err != nil {
+		t.Fatal("copy signatures:", err)
+	}
+
+	expected := []string{getAttachedTag(digest.String(), ".sig")}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected copied tags %v, actual %v", expected, actual)
+	}
+
+	exists, err := client.ManifestExistsAtRemote(context.Background(), host+"/target:"+expected[0], "")
+	if err != nil {
+		t.Fatal("manifest exists at remote:", err)
+	}
+
+	if !exists {
+		t.Errorf("expected signature %s to exist at the target", expected[0])
+	}
+}
+
+func TestCopySBOMsAndWait(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	image := writeRandomImage(t, host+"/source:v1.0.0")
+	digest, err := image.Digest()
+	if err != nil {
+		t.Fatal("get digest:", err)
+	}
+
+	writeRandomImage(t, host+"/source:"+getAttachedTag(digest.String(), ".sbom"))
+	writeRandomImage(t, host+"/source:"+getAttachedTag(digest.String(), ""))
+
+	client := Client{
+		logInfo: t.Logf,
+	}
+
+	actual, err := client.CopySBOMsAndWait(context.Background(), host+"/source:v1.0.0", "", host+"/target:v1.0.0", "")
+	if err != nil {
+		t.Fatal("copy sboms:", err)
+	}
+
+	expected := []string{getAttachedTag(digest.String(), ".sbom"), getAttachedTag(digest.String(), "")}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected copied tags %v, actual %v", expected, actual)
+	}
+}
+
+func TestGetVerifyArgs(t *testing.T) {
+	testCases := []struct {
+		opts     VerifyOptions
+		expected []string
+	}{
+		{
+			opts:     VerifyOptions{Key: "cosign.pub"},
+			expected: []string{"verify", "--key", "cosign.pub", "busybox:1.32.0"},
+		},
+		{
+			opts:     VerifyOptions{Identity: "release@example.com", Issuer: "https://accounts.example.com"},
+			expected: []string{"verify", "--certificate-identity", "release@example.com", "--certificate-oidc-issuer", "https://accounts.example.com", "busybox:1.32.0"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := getVerifyArgs("busybox:1.32.0", testCase.opts)
+		if err != nil {
+			t.Fatal("get verify args:", err)
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected args %v, actual %v", testCase.expected, actual)
+		}
+	}
+
+	if _, err := getVerifyArgs("busybox:1.32.0", VerifyOptions{}); err == nil {
+		t.Error("expected an error when no key or identity is given")
+	}
+}
+
+func writeRandomImage(t *testing.T, image string) v1.Image {
+	randomImage, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	reference, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	if err := remote.Write(reference, randomImage); err != nil {
+		t.Fatal("write image:", err)
+	}
+
+	return randomImage
+}
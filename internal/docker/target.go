@@ -0,0 +1,389 @@
+package docker
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+)
+
+// Target is a destination that images are written to when they are pushed. Images are written to the
+// registry of each image by default, or to one of the destinations that can be carried into an
+// air-gapped network, where they are loaded into its registry (see NewTarget).
+type Target interface {
+	// ImageExists returns true when the image has already been written to the target.
+	ImageExists(ctx context.Context, image string, auth string) (bool, error)
+
+	// WriteImage copies the source image from its registry to the target with the name of the image.
+	WriteImage(ctx context.Context, source string, sourceAuth string, image string, auth string, options WriteOptions) error
+
+	// Close finishes writing the images to the target once all of them have been written.
+	Close() error
+}
+
+// WriteOptions are the options of an image that is written to a target.
+type WriteOptions struct {
+	// Artifact is true when the image is an OCI artifact (e.g. a Helm chart), which is copied as it is.
+	Artifact bool
+
+	// ConfigMediaType is the media type that the config of the artifact must have, or empty for any media type.
+	ConfigMediaType string
+
+	// Platforms are the platforms of a multi-arch image to write, or empty for all of them.
+	Platforms []string
+
+	// PlatformImages are the images of each platform that are assembled into a multi-arch image
+	// with the name of the image, instead of writing the source image (see AssembleIndexAndWait).
+	PlatformImages []PlatformImage
+}
+
+// NewTarget returns the target that writes the images to the destination. An empty destination is the
+// registry of each image, while the other destinations are one of the following:
+//
+//	oci:<dir>                an OCI image layout directory
+//	tarball:<file>           a gzip compressed tar archive of an OCI image layout, as written by save
+//	s3://<bucket>/<prefix>   an OCI image layout of each image in an S3 bucket (requires the aws CLI)
+//
+// The images in the layouts are named after the images they were written as, so that load can find them.
+// Only the platforms of multi-arch images that are written to a registry can be selected, and
+// multi-arch images can only be assembled from the images of their platforms in a registry.
+func NewTarget(client Client, destination string) (Target, error) {
+	switch {
+	case destination == "":
+		return registryTarget{client: client}, nil
+
+	case strings.HasPrefix(destination, "oci:") && destination != "oci:":
+		return &layoutTarget{client: client, path: strings.TrimPrefix(destination, "oci:")}, nil
+
+	case strings.HasPrefix(destination, "tarball:") && destination != "tarball:":
+		return &archiveTarget{client: client, archivePath: strings.TrimPrefix(destination, "tarball:")}, nil
+
+	case strings.HasPrefix(destination, "s3://") && strings.Trim(strings.TrimPrefix(destination, "s3://"), "/") != "":
+		return s3Target{client: client, url: strings.TrimSuffix(destination, "/")}, nil
+	}
+
+	return nil, fmt.Errorf("invalid destination %s, expected oci:<dir>, tarball:<file> or s3://<bucket>/<prefix>", destination)
+}
+
+// registryTarget writes the images to their registries.
+type registryTarget struct {
+	client Client
+}
+
+func (t registryTarget) ImageExists(ctx context.Context, image string, auth string) (bool, error) {
+	return t.client.ImageExistsAtRemote(ctx, image, auth)
+}
+
+func (t registryTarget) WriteImage(ctx context.Context, source string, sourceAuth string, image string, auth string, options WriteOptions) error {
+	if options.Artifact {
+		return t.client.CopyArtifactAndWait(ctx, source, sourceAuth, image, auth, options.ConfigMediaType)
+	}
+
+	if len(options.PlatformImages) > 0 {
+		return t.client.AssembleIndexAndWait(ctx, options.PlatformImages, sourceAuth, image, auth)
+	}
+
+	return t.client.CopyImageAndWait(ctx, source, sourceAuth, image, auth, options.Platforms)
+}
+
+func (t registryTarget) Close() error {
+	return nil
+}
+
+// layoutTarget writes the images to an OCI image layout directory. The index of the layout is rewritten
+// as each image is added to it, so the images are written one at a time.
+type layoutTarget struct {
+	client Client
+	path   string
+
+	mutex sync.Mutex
+}
+
+func (t *layoutTarget) ImageExists(ctx context.Context, image string, auth string) (bool, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if _, err := os.Stat(filepath.Join(t.path, "index.json")); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	layoutIndex, err := layout.ImageIndexFromPath(t.path)
+	if err != nil {
+		return false, fmt.Errorf("get layout index: %w", err)
+	}
+
+	indexManifest, err := layoutIndex.IndexManifest()
+	if err != nil {
+		return false, fmt.Errorf("get index manifest: %w", err)
+	}
+
+	return containsLayoutImage(indexManifest, image), nil
+}
+
+func (t *layoutTarget) WriteImage(ctx context.Context, source string, sourceAuth string, image string, auth string, options WriteOptions) error {
+	if err := validateLayoutOptions(options); err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if err := t.client.saveImage(ctx, source, sourceAuth, t.path, image); err != nil {
+		return fmt.Errorf("save image: %w", err)
+	}
+
+	return nil
+}
+
+func (t *layoutTarget) Close() error {
+	return nil
+}
+
+// archiveTarget writes the images to a gzip compressed tar archive of an OCI image layout. The images
+// of an existing archive are kept, and the archive is only written again once all of the images are written.
+type archiveTarget struct {
+	client      Client
+	archivePath string
+
+	mutex  sync.Mutex
+	images *v1.IndexManifest
+	layout *layoutTarget
+}
+
+func (t *archiveTarget) ImageExists(ctx context.Context, image string, auth string) (bool, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.images == nil {
+		indexManifest, err := readArchiveIndex(t.archivePath)
+		if err != nil {
+			return false, fmt.Errorf("read archive index: %w", err)
+		}
+
+		t.images = indexManifest
+	}
+
+	return containsLayoutImage(t.images, image), nil
+}
+
+func (t *archiveTarget) WriteImage(ctx context.Context, source string, sourceAuth string, image string, auth string, options WriteOptions) error {
+	imageLayout, err := t.getLayout()
+	if err != nil {
+		return fmt.Errorf("get layout: %w", err)
+	}
+
+	return imageLayout.WriteImage(ctx, source, sourceAuth, image, auth, options)
+}
+
+// Close writes the archive when images were written to it. The archive is written next to the
+// existing archive first, so that the existing archive is kept when it cannot be written.
+func (t *archiveTarget) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.layout == nil {
+		return nil
+	}
+	defer os.RemoveAll(t.layout.path)
+
+	if _, err := WriteBundleIndex(t.layout.path); err != nil {
+		return fmt.Errorf("write bundle index: %w", err)
+	}
+
+	partialPath := t.archivePath + ".partial"
+	if err := WriteArchive(t.layout.path, partialPath); err != nil {
+		os.Remove(partialPath)
+		return fmt.Errorf("write archive: %w", err)
+	}
+
+	if err := os.Rename(partialPath, t.archivePath); err != nil {
+		return fmt.Errorf("rename archive: %w", err)
+	}
+
+	t.layout = nil
+
+	return nil
+}
+
+// getLayout returns the layout that the images are written to before they are archived,
+// which is created along with the images of the existing archive when it is first used.
+func (t *archiveTarget) getLayout() (*layoutTarget, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.layout != nil {
+		return t.layout, nil
+	}
+
+	layoutPath, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+
+	if _, err := os.Stat(t.archivePath); err == nil {
+		if err := ExtractArchive(t.archivePath, layoutPath); err != nil {
+			os.RemoveAll(layoutPath)
+			return nil, fmt.Errorf("extract archive: %w", err)
+		}
+
+		// Archives written before bundles were indexed are still appended to, and are indexed when they are written.
+		if _, err := VerifyBundleIndex(layoutPath); err != nil && !errors.Is(err, ErrBundleIndexNotFound) {
+			os.RemoveAll(layoutPath)
+			return nil, fmt.Errorf("verify archive: %w", err)
+		}
+	}
+
+	t.layout = &layoutTarget{client: t.client, path: layoutPath}
+
+	return t.layout, nil
+}
+
+// s3Target writes each image to its own OCI image layout in an S3 bucket, under the prefix of
+// the bucket followed by the registry, repository and tag of the image (e.g. prefix/host/repo/1.0).
+type s3Target struct {
+	client Client
+	url    string
+}
+
+func (t s3Target) ImageExists(ctx context.Context, image string, auth string) (bool, error) {
+	imageURL, err := t.getImageURL(image)
+	if err != nil {
+		return false, fmt.Errorf("get image url: %w", err)
+	}
+
+	// The aws CLI exits with an error without any output when there are no objects to list.
+	output, err := exec.CommandContext(ctx, "aws", "s3", "ls", imageURL+"/index.json").CombinedOutput()
+	if err != nil && strings.TrimSpace(string(output)) == "" {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("aws s3 ls: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return true, nil
+}
+
+func (t s3Target) WriteImage(ctx context.Context, source string, sourceAuth string, image string, auth string, options WriteOptions) error {
+	if err := validateLayoutOptions(options); err != nil {
+		return err
+	}
+
+	imageURL, err := t.getImageURL(image)
+	if err != nil {
+		return fmt.Errorf("get image url: %w", err)
+	}
+
+	layoutPath, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		return fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(layoutPath)
+
+	if err := t.client.saveImage(ctx, source, sourceAuth, layoutPath, image); err != nil {
+		return fmt.Errorf("save image: %w", err)
+	}
+
+	output, err := exec.CommandContext(ctx, "aws", "s3", "sync", "--only-show-errors", layoutPath, imageURL).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("aws s3 sync: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+func (t s3Target) Close() error {
+	return nil
+}
+
+// getImageURL returns the URL of the layout of the image in the bucket. The colon of
+// a digest is replaced, so that the digest can be used as the name of a directory.
+func (t s3Target) getImageURL(image string) (string, error) {
+	reference, err := t.client.parseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("parse ref: %w", err)
+	}
+
+	identifier := strings.Replace(reference.Identifier(), ":", "-", 1)
+	imagePath := path.Join(reference.Context().RegistryStr(), reference.Context().RepositoryStr(), identifier)
+
+	return t.url + "/" + imagePath, nil
+}
+
+// containsLayoutImage returns true when the index of the layout has an image with the name.
+func containsLayoutImage(indexManifest *v1.IndexManifest, image string) bool {
+	for _, descriptor := range indexManifest.Manifests {
+		if descriptor.Annotations[imageNameAnnotation] == image {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readArchiveIndex returns the index of the layout in the archive without extracting the archive,
+// or an empty index when the archive does not exist.
+func readArchiveIndex(archivePath string) (*v1.IndexManifest, error) {
+	archive, err := os.Open(archivePath)
+	if os.IsNotExist(err) {
+		return &v1.IndexManifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer archive.Close()
+
+	gzipReader, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("new gzip reader: %w", err)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+
+		if header.Name != "index.json" {
+			continue
+		}
+
+		indexManifest, err := v1.ParseIndexManifest(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("parse index: %w", err)
+		}
+
+		return indexManifest, nil
+	}
+
+	return nil, fmt.Errorf("archive %s is not an image layout", archivePath)
+}
+
+// validateLayoutOptions returns an error when the options can only be applied when writing to a registry.
+func validateLayoutOptions(options WriteOptions) error {
+	if len(options.Platforms) > 0 {
+		return errors.New("platforms can only be selected when writing to a registry")
+	}
+
+	if len(options.PlatformImages) > 0 {
+		return errors.New("multi-arch images can only be assembled from the images of their platforms when writing to a registry")
+	}
+
+	return nil
+}
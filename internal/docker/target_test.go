@@ -0,0 +1,150 @@
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestNewTarget(t *testing.T) {
+	testCases := []struct {
+		destination string
+		valid       bool
+	}{
+		{destination: "", valid: true},
+		{destination: "oci:images", valid: true},
+		{destination: "tarball:images.tar.gz", valid: true},
+		{destination: "s3://bucket/images", valid: true},
+		{destination: "oci:", valid: false},
+		{destination: "tarball:", valid: false},
+		{destination: "s3://", valid: false},
+		{destination: "images.tar.gz", valid: false},
+	}
+
+	for _, testCase := range testCases {
+		_, err := NewTarget(Client{}, testCase.destination)
+		if testCase.valid && err != nil {
+			t.Errorf("expected destination %q to be valid, got error %v", testCase.destination, err)
+		}
+
+		if !testCase.valid && err == nil {
+			t.Errorf("expected destination %q to be invalid", testCase.destination)
+		}
+	}
+}
+
+func TestArchiveTarget(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	var sources []string
+	for _, repository := range []string{"first", "second"} {
+		image, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		source := host + "/" + repository + ":v1.0.0"
+		reference, err := name.ParseReference(source, name.WeakValidation)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.Write(reference, image); err != nil {
+			t.Fatal("write image:", err)
+		}
+
+		sources = append(sources, source)
+	}
+
+	tempDir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	client := Client{
+		logInfo: t.Logf,
+	}
+
+	archivePath := filepath.Join(tempDir, "images.tar.gz")
+	firstTarget := host + "/mirror/first:v1.0.0"
+	secondTarget := host + "/mirror/second:v1.0.0"
+
+	// Each image is written by a separate push, so that the second push adds its image to the existing archive.
+	for i, image := range []string{firstTarget, secondTarget} {
+		target, err := NewTarget(client, "tarball:"+archivePath)
+		if err != nil {
+			t.Fatal("new target:", err)
+		}
+
+		exists, err := target.ImageExists(context.Background(), image, "")
+		if err != nil {
+			t.Fatal("image exists:", err)
+		}
+
+		if exists {
+			t.Fatalf("expected image %s to not exist before it is written", image)
+		}
+
+		if err := target.WriteImage(context.Background(), sources[i], "", image, "", WriteOptions{}); err != nil {
+			t.Fatal("write image:", err)
+		}
+
+		if err := target.Close(); err != nil {
+			t.Fatal("close target:", err)
+		}
+	}
+
+	target, err := NewTarget(client, "tarball:"+archivePath)
+	if err != nil {
+		t.Fatal("new target:", err)
+	}
+	defer target.Close()
+
+	for _, image := range []string{firstTarget, secondTarget} {
+		exists, err := target.ImageExists(context.Background(), image, "")
+		if err != nil {
+			t.Fatal("image exists:", err)
+		}
+
+		if !exists {
+			t.Errorf("expected image %s to exist in the archive", image)
+		}
+	}
+
+	if err := target.WriteImage(context.Background(), sources[0], "", firstTarget, "", WriteOptions{Platforms: []string{"linux/amd64"}}); err == nil {
+		t.Error("expected error when selecting platforms of an archive")
+	}
+
+	// The images are named after their targets in the archive, so they are found by the name of the target when loaded.
+	loadPath := filepath.Join(tempDir, "load")
+	if err := ExtractArchive(archivePath, loadPath); err != nil {
+		t.Fatal("extract archive:", err)
+	}
+
+	if err := client.LoadImage(context.Background(), loadPath, sources[1], secondTarget, ""); err != nil {
+		t.Fatal("load image:", err)
+	}
+
+	expectedDigest, err := client.GetDigest(context.Background(), sources[1], "")
+	if err != nil {
+		t.Fatal("get source digest:", err)
+	}
+
+	actualDigest, err := client.GetDigest(context.Background(), secondTarget, "")
+	if err != nil {
+		t.Fatal("get target digest:", err)
+	}
+
+	if actualDigest != expectedDigest {
+		t.Errorf("expected digest %s, actual %s", expectedDigest, actualDigest)
+	}
+}
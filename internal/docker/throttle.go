@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle is the latest time that a registry asked to not be sent requests until, when it asked to wait
+// for longer than requests are retried for. It is shared by every client, like the registries themselves.
+var throttle struct {
+	sync.Mutex
+
+	until time.Time
+	host  string
+}
+
+func recordThrottle(host string, until time.Time) {
+	throttle.Lock()
+	defer throttle.Unlock()
+
+	if until.After(throttle.until) {
+		throttle.until = until
+		throttle.host = host
+	}
+}
+
+// ThrottledUntil returns the latest time that a registry asked to not be sent requests until along with the host
+// of the registry, when it asked to wait for longer than requests are retried for (e.g. a pull quota that is only
+// reset in hours). The time is zero when no registry has asked to wait for that long.
+func ThrottledUntil() (time.Time, string) {
+	throttle.Lock()
+	defer throttle.Unlock()
+
+	return throttle.until, throttle.host
+}
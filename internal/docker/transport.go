@@ -0,0 +1,177 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/metrics"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// retryAfterTransport retries requests that were rate limited (429) or rejected because
+// the registry was unavailable (503), waiting for as long as the Retry-After header asks.
+// When the Docker Hub pull quota is exhausted, requests are paused until pulls are available again.
+type retryAfterTransport struct {
+	inner    http.RoundTripper
+	attempts int
+	maxWait  time.Duration
+	logInfo  func(format string, args ...interface{})
+}
+
+func newRetryAfterTransport(inner http.RoundTripper, logInfo func(format string, args ...interface{})) http.RoundTripper {
+	if logInfo == nil {
+		logInfo = func(format string, args ...interface{}) {}
+	}
+
+	return retryAfterTransport{
+		inner:    countingTransport{inner: inner},
+		attempts: 5,
+		maxWait:  2 * time.Minute,
+		logInfo:  logInfo,
+	}
+}
+
+func (t retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.inner.RoundTrip(req)
+		if err != nil || attempt >= t.attempts {
+			return resp, err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+			return resp, nil
+		}
+
+		wait, ok := getRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if ok && wait > t.maxWait {
+			recordThrottle(req.URL.Host, time.Now().Add(wait))
+			return resp, nil
+		}
+
+		if !ok && resp.StatusCode == http.StatusTooManyRequests {
+			wait, ok = getDockerHubWait(resp.Header)
+			if ok {
+				t.logInfo("Pull quota of %s exhausted (Pausing for %v)", req.URL.Host, wait)
+			}
+		}
+
+		if !ok {
+			return resp, nil
+		}
+
+		// Requests with a body can only be sent again when the
+		// body can be recreated (e.g. the request was not streamed).
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp.Body.Close()
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// getRetryAfter returns how long to wait before retrying a request, given the value of
+// a Retry-After header. The value is either a number of seconds or an HTTP date.
+func getRetryAfter(retryAfter string, now time.Time) (time.Duration, bool) {
+	if retryAfter == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(retryAfter)
+	if err != nil {
+		return 0, false
+	}
+
+	if wait := date.Sub(now); wait > 0 {
+		return wait, true
+	}
+
+	return 0, true
+}
+
+// countingTransport records the number of bytes uploaded in the body of each request.
+type countingTransport struct {
+	inner http.RoundTripper
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = countingReader{ReadCloser: req.Body}
+	}
+
+	return t.inner.RoundTrip(req)
+}
+
+type countingReader struct {
+	io.ReadCloser
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	metrics.BytesTransferred.Add(uint64(n))
+
+	return n, err
+}
+
+// contextTransport sends every request with the given context so that in-flight
+// requests are cancelled when the context is, including the requests made by
+// libraries that do not accept a context themselves.
+type contextTransport struct {
+	ctx   context.Context
+	inner http.RoundTripper
+}
+
+func (t contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.inner.RoundTrip(req.WithContext(t.ctx))
+}
+
+// newTransport returns the transport that the requests to the registries are sent with.
+func (c Client) newTransport() http.RoundTripper {
+	transport := c.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if c.bandwidthLimiter != nil {
+		transport = bandwidthTransport{inner: transport, limiter: c.bandwidthLimiter}
+	}
+
+	return newRetryAfterTransport(transport, c.logInfo)
+}
+
+func (c Client) remoteOptions(ctx context.Context, authenticator authn.Authenticator) []remote.Option {
+	transport := contextTransport{
+		ctx:   ctx,
+		inner: c.newTransport(),
+	}
+
+	return []remote.Option{
+		remote.WithAuth(authenticator),
+		remote.WithTransport(transport),
+	}
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterTransport(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := http.Client{Transport: newRetryAfterTransport(http.DefaultTransport, t.Logf)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal("get:", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status %v, actual %v", http.StatusOK, resp.StatusCode)
+	}
+
+	if requests != 2 {
+		t.Errorf("expected 2 requests, actual %v", requests)
+	}
+}
+
+func TestRetryAfterTransport_Throttled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := http.Client{Transport: newRetryAfterTransport(http.DefaultTransport, t.Logf)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal("get:", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected status %v, actual %v", http.StatusTooManyRequests, resp.StatusCode)
+	}
+
+	until, host := ThrottledUntil()
+	if host != server.Listener.Addr().String() {
+		t.Errorf("expected %s to be throttled, actual %s", server.Listener.Addr().String(), host)
+	}
+
+	if until.Before(time.Now().Add(59 * time.Minute)) {
+		t.Errorf("expected to be throttled for an hour, actual until %v", until)
+	}
+}
+
+func TestGetRetryAfter(t *testing.T) {
+	now := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		retryAfter string
+		expected   time.Duration
+		ok         bool
+	}{
+		{retryAfter: "30", expected: 30 * time.Second, ok: true},
+		{retryAfter: "Thu, 01 Oct 2020 12:01:00 GMT", expected: time.Minute, ok: true},
+		{retryAfter: "", ok: false},
+		{retryAfter: "soon", ok: false},
+	}
+
+	for _, testCase := range testCases {
+		actual, ok := getRetryAfter(testCase.retryAfter, now)
+		if ok != testCase.ok || actual != testCase.expected {
+			t.Errorf("expected %v (%v) for %q, actual %v (%v)", testCase.expected, testCase.ok, testCase.retryAfter, actual, ok)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// manifestMediaTypes are the media types of the manifests that the registries are asked for.
+var manifestMediaTypes = []types.MediaType{
+	types.DockerManifestSchema2,
+	types.OCIManifestSchema1,
+	types.DockerManifestList,
+	types.OCIImageIndex,
+}
+
+// Verification is the result of verifying that an image was copied to the target without being changed.
+type Verification struct {
+	Source       string    `json:"source"`
+	Target       string    `json:"target"`
+	SourceDigest string    `json:"sourceDigest"`
+	TargetDigest string    `json:"targetDigest"`
+	Verified     bool      `json:"verified"`
+	Pulled       bool      `json:"pulled"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// VerifyCopy compares the digest of the source image with the digest of the target image. The digest of
+// the target is the digest that the target registry reports for the image, unless pull is set, in which
+// case the manifest of the target is pulled again and its digest is calculated from its contents.
+func (c Client) VerifyCopy(ctx context.Context, source string, sourceAuth string, target string, targetAuth string, pull bool) (Verification, error) {
+	sourceDigest, err := c.GetDigest(ctx, source, sourceAuth)
+	if err != nil {
+		return Verification{}, fmt.Errorf("get source digest: %w", err)
+	}
+
+	var targetDigest string
+	if pull {
+		targetDigest, err = c.GetDigest(ctx, target, targetAuth)
+	} else {
+		targetDigest, err = c.getReportedDigest(ctx, target, targetAuth)
+	}
+	if err != nil {
+		return Verification{}, fmt.Errorf("get target digest: %w", err)
+	}
+
+	verification := Verification{
+		Source:       source,
+		Target:       target,
+		SourceDigest: sourceDigest,
+		TargetDigest: targetDigest,
+		Verified:     sourceDigest == targetDigest,
+		Pulled:       pull,
+		Timestamp:    time.Now().UTC(),
+	}
+
+	return verification, nil
+}
+
+// getReportedDigest returns the digest that the registry reports for the image in the Docker-Content-Digest
+// header, without pulling its manifest. When the registry does not report the digest, the manifest is pulled.
+func (c Client) getReportedDigest(ctx context.Context, image string, auth string) (string, error) {
+	reference, err := c.parseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("parse ref: %w", err)
+	}
+
+	authenticator, err := getAuthenticator(auth)
+	if err != nil {
+		return "", fmt.Errorf("get authenticator: %w", err)
+	}
+
+	registry := reference.Context().Registry
+	scopes := []string{reference.Scope(transport.PullScope)}
+
+	registryTransport, err := transport.New(registry, authenticator, contextTransport{ctx: ctx, inner: c.newTransport()}, scopes)
+	if err != nil {
+		return "", fmt.Errorf("new transport: %w", err)
+	}
+
+	url := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", registry.Scheme(), registry.RegistryStr(), reference.Context().RepositoryStr(), reference.Identifier())
+	req, err := http.NewRequest(http.MethodHead, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+
+	var accept []string
+	for _, mediaType := range manifestMediaTypes {
+		accept = append(accept, string(mediaType))
+	}
+	req.Header.Set("Accept", strings.Join(accept, ","))
+
+	resp, err := (&http.Client{Transport: registryTransport}).Do(req.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("head manifest: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("head manifest: unexpected status %s", resp.Status)
+	}
+
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	digest, err := c.GetDigest(ctx, image, auth)
+	if err != nil {
+		return "", fmt.Errorf("get digest: %w", err)
+	}
+
+	return digest, nil
+}
@@ -0,0 +1,67 @@
+package docker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestVerifyCopy(t *testing.T) {
+	host := testutil.NewRegistry(t).Host
+
+	images := map[string]string{
+		"source":    host + "/source:v1.0.0",
+		"target":    host + "/target:v1.0.0",
+		"different": host + "/different:v1.0.0",
+	}
+
+	for _, repository := range []string{"source", "different"} {
+		image, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		reference, err := name.ParseReference(images[repository], name.WeakValidation)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		if err := remote.Write(reference, image); err != nil {
+			t.Fatal("write image:", err)
+		}
+	}
+
+	client := Client{
+		logInfo:  t.Logf,
+		attempts: 1,
+	}
+
+	if err := client.CopyImageAndWait(context.Background(), images["source"], "", images["target"], "", nil); err != nil {
+		t.Fatal("copy image:", err)
+	}
+
+	for _, pull := range []bool{false, true} {
+		verification, err := client.VerifyCopy(context.Background(), images["source"], "", images["target"], "", pull)
+		if err != nil {
+			t.Fatal("verify copy:", err)
+		}
+
+		if !verification.Verified {
+			t.Errorf("expected copy to be verified (pull %v), source digest %s, target digest %s", pull, verification.SourceDigest, verification.TargetDigest)
+		}
+
+		verification, err = client.VerifyCopy(context.Background(), images["source"], "", images["different"], "", pull)
+		if err != nil {
+			t.Fatal("verify copy:", err)
+		}
+
+		if verification.Verified {
+			t.Errorf("expected different image not to be verified (pull %v)", pull)
+		}
+	}
+}
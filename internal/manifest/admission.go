@@ -0,0 +1,241 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The scopes of the images that are allowed by the generated policies.
+const (
+	// AdmissionScopeImage only allows the exact images that are pushed to the target.
+	AdmissionScopeImage = "image"
+
+	// AdmissionScopeRepository allows any image of the repositories that are pushed to at the target.
+	AdmissionScopeRepository = "repository"
+
+	// AdmissionScopeRegistry allows any image of the target registries, including their repository path.
+	AdmissionScopeRegistry = "registry"
+)
+
+// AdmissionOptions are the settings of the admission policies that only allow the images pushed by sinker to run.
+type AdmissionOptions struct {
+	Name  string
+	Scope string
+}
+
+// AllowedImages are the images, repositories and registries that the sources are pushed to.
+type AllowedImages struct {
+	Images       []string `json:"images"`
+	Repositories []string `json:"repositories"`
+	Registries   []string `json:"registries"`
+}
+
+// GetAllowedImages returns the sorted images, repositories and registries that the sources are pushed to.
+func GetAllowedImages(sources []Source) AllowedImages {
+	images := make(map[string]bool)
+	repositories := make(map[string]bool)
+	registries := make(map[string]bool)
+	for _, source := range sources {
+		images[source.TargetImage()] = true
+		repositories[source.TargetRepository()] = true
+
+		registry := source.Target.Host
+		if source.Target.Repository != "" {
+			registry += "/" + source.Target.Repository
+		}
+		registries[registry] = true
+	}
+
+	return AllowedImages{
+		Images:       sortedKeys(images),
+		Repositories: sortedKeys(repositories),
+		Registries:   sortedKeys(registries),
+	}
+}
+
+func sortedKeys(values map[string]bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// kyvernoPatterns returns the wildcard patterns of the images that are allowed in the scope.
+// Images may also be pinned to their digest, so the digest of each allowed tag is allowed.
+func (a AllowedImages) kyvernoPatterns(scope string) []string {
+	var patterns []string
+	switch scope {
+	case AdmissionScopeRegistry:
+		for _, registry := range a.Registries {
+			patterns = append(patterns, registry+"/*")
+		}
+	case AdmissionScopeRepository:
+		for _, repository := range a.Repositories {
+			patterns = append(patterns, repository+":*", repository+"@*")
+		}
+	default:
+		for _, image := range a.Images {
+			patterns = append(patterns, image, image+"@*")
+		}
+	}
+
+	return patterns
+}
+
+// gatekeeperPrefixes returns the prefixes of the images that are allowed in the scope.
+func (a AllowedImages) gatekeeperPrefixes(scope string) []string {
+	var prefixes []string
+	switch scope {
+	case AdmissionScopeRegistry:
+		for _, registry := range a.Registries {
+			prefixes = append(prefixes, registry+"/")
+		}
+	case AdmissionScopeRepository:
+		for _, repository := range a.Repositories {
+			prefixes = append(prefixes, repository+":", repository+"@")
+		}
+	default:
+		prefixes = append(prefixes, a.Images...)
+	}
+
+	return prefixes
+}
+
+type kyvernoPolicy struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubernetesMetadata `yaml:"metadata"`
+	Spec       struct {
+		ValidationFailureAction string        `yaml:"validationFailureAction"`
+		Background              bool          `yaml:"background"`
+		Rules                   []kyvernoRule `yaml:"rules"`
+	} `yaml:"spec"`
+}
+
+type kyvernoRule struct {
+	Name  string `yaml:"name"`
+	Match struct {
+		Any []kyvernoResourceFilter `yaml:"any"`
+	} `yaml:"match"`
+	Validate struct {
+		Message string           `yaml:"message"`
+		Foreach []kyvernoForeach `yaml:"foreach"`
+	} `yaml:"validate"`
+}
+
+type kyvernoResourceFilter struct {
+	Resources struct {
+		Kinds []string `yaml:"kinds"`
+	} `yaml:"resources"`
+}
+
+type kyvernoForeach struct {
+	List string `yaml:"list"`
+	Deny struct {
+		Conditions struct {
+			All []kyvernoCondition `yaml:"all"`
+		} `yaml:"conditions"`
+	} `yaml:"deny"`
+}
+
+type kyvernoCondition struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Value    []string `yaml:"value"`
+}
+
+// ToKyvernoPolicy returns a Kyverno ClusterPolicy that denies pods with containers whose image is not allowed.
+func ToKyvernoPolicy(sources []Source, options AdmissionOptions) ([]byte, error) {
+	var policy kyvernoPolicy
+	policy.APIVersion = "kyverno.io/v1"
+	policy.Kind = "ClusterPolicy"
+	policy.Metadata = kubernetesMetadata{Name: options.Name}
+	policy.Spec.ValidationFailureAction = "Enforce"
+	policy.Spec.Background = true
+
+	var rule kyvernoRule
+	rule.Name = "allowed-images"
+
+	var filter kyvernoResourceFilter
+	filter.Resources.Kinds = []string{"Pod"}
+	rule.Match.Any = append(rule.Match.Any, filter)
+
+	// Every container of the pod is checked, including its init and ephemeral containers.
+	var foreach kyvernoForeach
+	foreach.List = "request.object.spec.[containers, initContainers, ephemeralContainers][]"
+	foreach.Deny.Conditions.All = []kyvernoCondition{{
+		Key:      "{{ element.image }}",
+		Operator: "AnyNotIn",
+		Value:    GetAllowedImages(sources).kyvernoPatterns(options.Scope),
+	}}
+
+	rule.Validate.Message = "Only images mirrored by sinker are allowed."
+	rule.Validate.Foreach = append(rule.Validate.Foreach, foreach)
+
+	policy.Spec.Rules = append(policy.Spec.Rules, rule)
+
+	contents, err := yaml.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return contents, nil
+}
+
+type gatekeeperConstraint struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubernetesMetadata `yaml:"metadata"`
+	Spec       struct {
+		Match struct {
+			Kinds []gatekeeperKinds `yaml:"kinds"`
+		} `yaml:"match"`
+		Parameters struct {
+			Repos []string `yaml:"repos"`
+		} `yaml:"parameters"`
+	} `yaml:"spec"`
+}
+
+type gatekeeperKinds struct {
+	APIGroups []string `yaml:"apiGroups"`
+	Kinds     []string `yaml:"kinds"`
+}
+
+// ToGatekeeperConstraint returns a constraint of the K8sAllowedRepos template of the Gatekeeper library,
+// which denies pods with containers whose image does not start with one of the allowed prefixes.
+func ToGatekeeperConstraint(sources []Source, options AdmissionOptions) ([]byte, error) {
+	var constraint gatekeeperConstraint
+	constraint.APIVersion = "constraints.gatekeeper.sh/v1beta1"
+	constraint.Kind = "K8sAllowedRepos"
+	constraint.Metadata = kubernetesMetadata{Name: options.Name}
+	constraint.Spec.Match.Kinds = []gatekeeperKinds{{APIGroups: []string{""}, Kinds: []string{"Pod"}}}
+	constraint.Spec.Parameters.Repos = GetAllowedImages(sources).gatekeeperPrefixes(options.Scope)
+
+	contents, err := yaml.Marshal(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return contents, nil
+}
+
+// ToOPAData returns a data document for OPA with the allowed images, repositories and registries,
+// so that the policies that are already in use can refer to them as data.sinker.
+func ToOPAData(sources []Source) ([]byte, error) {
+	data := map[string]AllowedImages{
+		"sinker": GetAllowedImages(sources),
+	}
+
+	contents, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return append(contents, '\n'), nil
+}
@@ -0,0 +1,95 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetAllowedImages(t *testing.T) {
+	target := Target{Host: "mycompany.com", Repository: "myteam"}
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0", Target: target},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: target},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.39.0", Target: target},
+	}
+
+	actual := GetAllowedImages(sources)
+
+	expected := AllowedImages{
+		Images: []string{
+			"mycompany.com/myteam/busybox:1.32.0",
+			"mycompany.com/myteam/coreos/prometheus-operator:v0.39.0",
+			"mycompany.com/myteam/coreos/prometheus-operator:v0.40.0",
+		},
+		Repositories: []string{
+			"mycompany.com/myteam/busybox",
+			"mycompany.com/myteam/coreos/prometheus-operator",
+		},
+		Registries: []string{"mycompany.com/myteam"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected allowed images %v, actual %v", expected, actual)
+	}
+}
+
+func TestToKyvernoPolicy(t *testing.T) {
+	target := Target{Host: "mycompany.com", Repository: "myteam"}
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0", Target: target},
+	}
+
+	testCases := []struct {
+		scope    string
+		expected string
+	}{
+		{AdmissionScopeImage, "- mycompany.com/myteam/busybox:1.32.0\n              - mycompany.com/myteam/busybox:1.32.0@*\n"},
+		{AdmissionScopeRepository, "- mycompany.com/myteam/busybox:*\n              - mycompany.com/myteam/busybox@*\n"},
+		{AdmissionScopeRegistry, "- mycompany.com/myteam/*\n"},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := ToKyvernoPolicy(sources, AdmissionOptions{Name: "sinker-allowed-images", Scope: testCase.scope})
+		if err != nil {
+			t.Fatal("to kyverno policy:", err)
+		}
+
+		if !strings.Contains(string(actual), "kind: ClusterPolicy") || !strings.Contains(string(actual), testCase.expected) {
+			t.Errorf("expected %s policy to contain\n%s\nactual\n%s", testCase.scope, testCase.expected, actual)
+		}
+	}
+}
+
+func TestToGatekeeperConstraint(t *testing.T) {
+	target := Target{Host: "mycompany.com", Repository: "myteam"}
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0", Target: target},
+	}
+
+	actual, err := ToGatekeeperConstraint(sources, AdmissionOptions{Name: "sinker-allowed-images", Scope: AdmissionScopeRepository})
+	if err != nil {
+		t.Fatal("to gatekeeper constraint:", err)
+	}
+
+	expected := `apiVersion: constraints.gatekeeper.sh/v1beta1
+kind: K8sAllowedRepos
+metadata:
+  name: sinker-allowed-images
+spec:
+  match:
+    kinds:
+    - apiGroups:
+      - ""
+      kinds:
+      - Pod
+  parameters:
+    repos:
+    - 'mycompany.com/myteam/busybox:'
+    - mycompany.com/myteam/busybox@
+`
+
+	if string(actual) != expected {
+		t.Errorf("expected constraint\n%s\nactual\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,116 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// backstageNameLength is the maximum length of the name of a Backstage entity.
+const backstageNameLength = 63
+
+// backstageNamePattern matches the characters that the name of a Backstage entity cannot have.
+var backstageNamePattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// backstageEntity is a Backstage catalog entity of kind Resource.
+type backstageEntity struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   backstageMetadata `yaml:"metadata"`
+	Spec       backstageSpec     `yaml:"spec"`
+}
+
+type backstageMetadata struct {
+	Name        string            `yaml:"name"`
+	Title       string            `yaml:"title"`
+	Annotations map[string]string `yaml:"annotations"`
+	Tags        []string          `yaml:"tags,omitempty"`
+}
+
+type backstageSpec struct {
+	Type  string `yaml:"type"`
+	Owner string `yaml:"owner"`
+}
+
+// ToBackstageEntities returns the sources as Backstage catalog entities, one Resource of type container-image per
+// image (or helm-chart and oci-artifact for charts and artifacts), which the Backstage catalog can ingest from a
+// catalog-info.yaml file. Each entity is owned by the given owner, and records its source and target images in
+// the sinker.io/source-image and sinker.io/target-image annotations.
+func ToBackstageEntities(sources []Source, owner string) ([]byte, error) {
+	if owner == "" {
+		return nil, errors.New("entities must have an owner")
+	}
+
+	var contents bytes.Buffer
+	images := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, source := range sources {
+		if images[source.Image()] {
+			continue
+		}
+		images[source.Image()] = true
+
+		// Images that only differ by their punctuation (e.g. app:v1.0 and app-v1:0) have the same name.
+		name := getBackstageName(source.Image(), names[getBackstageName(source.Image(), false)])
+		names[name] = true
+
+		entity := backstageEntity{
+			APIVersion: "backstage.io/v1alpha1",
+			Kind:       "Resource",
+			Metadata: backstageMetadata{
+				Name:  name,
+				Title: source.Image(),
+				Annotations: map[string]string{
+					"sinker.io/source-image": source.Image(),
+					"sinker.io/target-image": source.TargetImage(),
+				},
+			},
+			Spec: backstageSpec{
+				Type:  "container-image",
+				Owner: owner,
+			},
+		}
+
+		switch source.Type {
+		case SourceTypeChart:
+			entity.Spec.Type = "helm-chart"
+		case SourceTypeArtifact:
+			entity.Spec.Type = "oci-artifact"
+		}
+
+		if source.Host != "" {
+			entity.Metadata.Tags = []string{strings.Trim(backstageNamePattern.ReplaceAllString(strings.ToLower(source.Host), "-"), "-")}
+		}
+
+		document, err := yaml.Marshal(entity)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s: %w", source.Image(), err)
+		}
+
+		contents.WriteString("---\n")
+		contents.Write(document)
+	}
+
+	return contents.Bytes(), nil
+}
+
+// getBackstageName returns the name of the entity of the image, which only has lowercase letters, digits and
+// dashes. Names that are too long, or that must be unique from the name of another image, end with a hash of the image.
+func getBackstageName(image string, hashed bool) string {
+	name := strings.Trim(backstageNamePattern.ReplaceAllString(strings.ToLower(image), "-"), "-")
+	if len(name) <= backstageNameLength && !hashed {
+		return name
+	}
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(image)))[:8]
+	if len(name) > backstageNameLength-len(hash)-1 {
+		name = strings.TrimRight(name[:backstageNameLength-len(hash)-1], "-")
+	}
+
+	return name + "-" + hash
+}
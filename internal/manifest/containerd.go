@@ -0,0 +1,153 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContainerdHostsHeader is the first line of the hosts.toml files that are generated by sinker, which tells
+// them apart from the hosts.toml files that were written by hand when the generated files are kept in sync.
+const ContainerdHostsHeader = "# Generated by sinker from the image manifest, changes will be overwritten."
+
+// ContainerdHosts is the hosts.toml configuration of containerd for a source registry, which is
+// read from the directory of the registry in the config path of containerd (e.g. /etc/containerd/certs.d/quay.io).
+type ContainerdHosts struct {
+	Registry string
+	Contents []byte
+}
+
+// containerdMirror is a repository at the target that the images of a source registry are pushed to.
+type containerdMirror struct {
+	host       string
+	repository string
+	images     int
+}
+
+// ToContainerdHosts returns the hosts.toml configuration of each source registry, which makes containerd pull the images
+// of the registry from the repositories that sinker pushes them to, so that the nodes of a cluster pull from the mirror
+// without rewriting the images of the workloads. The configurations are sorted by the host of their source registry.
+//
+// containerd appends the repository of an image to the path of a mirror, so a source is only pulled from its mirror when
+// the repository of the source is pushed to the end of the repository at the target with the same tag. When the images of a
+// registry are pushed to more than one repository, each repository is a mirror of the registry, ordered by the number of images
+// pushed to it, as containerd tries the next mirror when an image is not found. The sources that cannot be pulled from their
+// mirror are returned as well, as containerd pulls them from their source registry.
+func ToContainerdHosts(sources []Source) ([]ContainerdHosts, []Source, error) {
+	mirrors := make(map[string][]*containerdMirror)
+	var unmirrored []Source
+	for _, source := range sources {
+		if source.IsArtifact() {
+			continue
+		}
+
+		host, repository, ok, err := getContainerdMirror(source)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get mirror of %s: %w", source.Image(), err)
+		}
+
+		if !ok {
+			unmirrored = append(unmirrored, source)
+			continue
+		}
+
+		registry := getCanonicalHost(source.Host)
+		mirror := findContainerdMirror(mirrors[registry], host, repository)
+		if mirror == nil {
+			mirror = &containerdMirror{host: host, repository: repository}
+			mirrors[registry] = append(mirrors[registry], mirror)
+		}
+
+		mirror.images++
+	}
+
+	registries := make([]string, 0, len(mirrors))
+	for registry := range mirrors {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+
+	var hosts []ContainerdHosts
+	for _, registry := range registries {
+		registryMirrors := mirrors[registry]
+		sort.SliceStable(registryMirrors, func(i, j int) bool {
+			return registryMirrors[i].images > registryMirrors[j].images
+		})
+
+		hosts = append(hosts, ContainerdHosts{
+			Registry: registry,
+			Contents: getContainerdHostsFile(registry, registryMirrors),
+		})
+	}
+
+	return hosts, unmirrored, nil
+}
+
+// getContainerdMirror returns the host and repository of the mirror that containerd pulls the image of the source from,
+// and false when the image is pushed to the target in a way that containerd cannot pull it from a mirror.
+func getContainerdMirror(source Source) (string, string, bool, error) {
+	targetTag, err := source.targetTag()
+	if err != nil {
+		return "", "", false, fmt.Errorf("get target tag: %w", err)
+	}
+
+	if source.Tag != "" && targetTag != source.Tag {
+		return "", "", false, nil
+	}
+
+	// containerd pulls the official images of Docker Hub from the library namespace.
+	repository := source.Repository
+	if getCanonicalHost(source.Host) == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	targetRepository := strings.TrimPrefix(source.TargetRepository(), source.Target.Host+"/")
+	if targetRepository == repository {
+		return source.Target.Host, "", true, nil
+	}
+
+	if !strings.HasSuffix(targetRepository, "/"+repository) {
+		return "", "", false, nil
+	}
+
+	return source.Target.Host, strings.TrimSuffix(targetRepository, "/"+repository), true, nil
+}
+
+func findContainerdMirror(mirrors []*containerdMirror, host string, repository string) *containerdMirror {
+	for _, mirror := range mirrors {
+		if mirror.host == host && mirror.repository == repository {
+			return mirror
+		}
+	}
+
+	return nil
+}
+
+// getContainerdHostsFile returns the hosts.toml file of the registry, which pulls from the mirrors before the registry itself.
+// The path of each mirror includes the /v2 prefix of the registry API, so that the repository at the target can be set.
+func getContainerdHostsFile(registry string, mirrors []*containerdMirror) []byte {
+	server := "https://" + registry
+	if registry == "docker.io" {
+		server = "https://registry-1.docker.io"
+	}
+
+	var contents bytes.Buffer
+	contents.WriteString(ContainerdHostsHeader + "\n")
+	contents.WriteString("server = " + strconv.Quote(server) + "\n")
+
+	for _, mirror := range mirrors {
+		url := "https://" + mirror.host + "/v2"
+		if mirror.repository != "" {
+			url += "/" + mirror.repository
+		}
+
+		contents.WriteString("\n")
+		contents.WriteString("[host." + strconv.Quote(url) + "]\n")
+		contents.WriteString(`  capabilities = ["pull", "resolve"]` + "\n")
+		contents.WriteString("  override_path = true\n")
+	}
+
+	return contents.Bytes()
+}
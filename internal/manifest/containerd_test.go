@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func TestToContainerdHosts(t *testing.T) {
+	target := Target{Host: "mycompany.com", Repository: "mirror"}
+	sources := []Source{
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.13", Target: target},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: target},
+		{Host: "quay.io", Repository: "prometheus/prometheus", Tag: "v2.26.0", Target: Target{Host: "large.mycompany.com"}},
+		{Repository: "library/busybox", Tag: "1.32.0", Target: target},
+		{Repository: "nginx", Tag: "1.21", Target: target},
+		{Host: "quay.io", Repository: "coreos/flannel", Tag: "v0.13.0", TargetTag: "stable", Target: target},
+		{Host: "ghcr.io", Repository: "myorg/charts/app", Tag: "1.0.0", Type: SourceTypeChart, Target: target},
+	}
+
+	hosts, unmirrored, err := ToContainerdHosts(sources)
+	if err != nil {
+		t.Fatal("to containerd hosts:", err)
+	}
+
+	if len(hosts) != 2 {
+		t.Fatalf("expected hosts of 2 registries, actual %d", len(hosts))
+	}
+
+	expectedDockerHub := ContainerdHostsHeader + `
+server = "https://registry-1.docker.io"
+
+[host."https://mycompany.com/v2/mirror"]
+  capabilities = ["pull", "resolve"]
+  override_path = true
+`
+
+	if hosts[0].Registry != "docker.io" || string(hosts[0].Contents) != expectedDockerHub {
+		t.Errorf("expected hosts of docker.io\n%s\nactual hosts of %s\n%s", expectedDockerHub, hosts[0].Registry, hosts[0].Contents)
+	}
+
+	expectedQuay := ContainerdHostsHeader + `
+server = "https://quay.io"
+
+[host."https://mycompany.com/v2/mirror"]
+  capabilities = ["pull", "resolve"]
+  override_path = true
+
+[host."https://large.mycompany.com/v2"]
+  capabilities = ["pull", "resolve"]
+  override_path = true
+`
+
+	if hosts[1].Registry != "quay.io" || string(hosts[1].Contents) != expectedQuay {
+		t.Errorf("expected hosts of quay.io\n%s\nactual hosts of %s\n%s", expectedQuay, hosts[1].Registry, hosts[1].Contents)
+	}
+
+	if len(unmirrored) != 2 || unmirrored[0].Repository != "nginx" || unmirrored[1].Repository != "coreos/flannel" {
+		t.Errorf("expected nginx and coreos/flannel to be unmirrored, actual %+v", unmirrored)
+	}
+}
+
+func TestToContainerdHosts_Mappings(t *testing.T) {
+	const manifestContents = `target:
+  host: mycompany.com
+mappings:
+- source: quay.io
+  repository: quay
+- source: quay.io/coreos/etcd
+  repository: etcd
+sources:
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tag: v0.40.0
+- repository: coreos/etcd
+  host: quay.io
+  tag: v3.4.13
+`
+
+	imageManifest, err := Parse([]byte(manifestContents))
+	if err != nil {
+		t.Fatal("parse manifest:", err)
+	}
+
+	hosts, unmirrored, err := ToContainerdHosts(imageManifest.Sources)
+	if err != nil {
+		t.Fatal("to containerd hosts:", err)
+	}
+
+	expected := ContainerdHostsHeader + `
+server = "https://quay.io"
+
+[host."https://mycompany.com/v2/quay"]
+  capabilities = ["pull", "resolve"]
+  override_path = true
+`
+
+	if len(hosts) != 1 || string(hosts[0].Contents) != expected {
+		t.Errorf("expected hosts\n%s\nactual\n%+v", expected, hosts)
+	}
+
+	if len(unmirrored) != 1 || unmirrored[0].Repository != "coreos/etcd" {
+		t.Errorf("expected coreos/etcd to be unmirrored, actual %+v", unmirrored)
+	}
+}
@@ -0,0 +1,440 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/plexsystems/sinker/pkg/images"
+
+	"gopkg.in/yaml.v2"
+)
+
+// The formats of image lists that can be converted to and from a manifest.
+const (
+	FormatManifest        = "manifest"
+	FormatText            = "text"
+	FormatSkopeo          = "skopeo"
+	FormatKubeImageKeeper = "kuik"
+)
+
+// ConvertFormats are the formats of image lists that can be converted to and from a manifest.
+var ConvertFormats = []string{FormatManifest, FormatText, FormatSkopeo, FormatKubeImageKeeper}
+
+// kuikAPIVersion is the API version of the CachedImage resources of kube-image-keeper.
+const kuikAPIVersion = "kuik.enix.io/v1alpha1"
+
+// cachedImage is a CachedImage resource of kube-image-keeper, which caches the source image in the registry of the cluster.
+type cachedImage struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		SourceImage string `yaml:"sourceImage"`
+		Retain      bool   `yaml:"retain,omitempty"`
+	} `yaml:"spec"`
+	Items []cachedImage `yaml:"items,omitempty"`
+}
+
+// DetectFormat returns the format of the image list with the given contents. Contents that are
+// not a manifest, a skopeo sync configuration or kube-image-keeper resources are a plain text list.
+func DetectFormat(contents []byte) string {
+	var document map[string]interface{}
+	if err := yaml.NewDecoder(bytes.NewReader(contents)).Decode(&document); err != nil {
+		return FormatText
+	}
+
+	if _, exists := document["sources"]; exists {
+		return FormatManifest
+	}
+
+	if _, exists := document["target"]; exists {
+		return FormatManifest
+	}
+
+	if apiVersion, _ := document["apiVersion"].(string); strings.HasPrefix(apiVersion, "kuik.enix.io/") {
+		return FormatKubeImageKeeper
+	}
+
+	if kind, _ := document["kind"].(string); kind == "List" || kind == "CachedImageList" {
+		return FormatKubeImageKeeper
+	}
+
+	for _, value := range document {
+		registry, ok := value.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, key := range []string{"images", "images-by-tag-regex", "images-by-semver"} {
+			if _, exists := registry[key]; exists {
+				return FormatSkopeo
+			}
+		}
+	}
+
+	return FormatText
+}
+
+// Convert converts the image list with the given contents from one format to another. The images of formats
+// that do not record where the images are pushed to are pushed to the target. The targets of the images are
+// kept when converting to a manifest or a plain text list, and are lost when converting to the other formats.
+func Convert(contents []byte, from string, to string, target Target) ([]byte, error) {
+	var manifest Manifest
+	var err error
+	switch from {
+	case FormatManifest:
+		err = yaml.Unmarshal(contents, &manifest)
+	case FormatText:
+		manifest, err = FromImageList(contents, target)
+	case FormatSkopeo:
+		manifest, err = FromSkopeoSync(contents, target)
+	case FormatKubeImageKeeper:
+		manifest, err = FromCachedImages(contents, target)
+	default:
+		return nil, fmt.Errorf("unknown format %s (must be one of %s)", from, strings.Join(ConvertFormats, ", "))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", from, err)
+	}
+
+	manifestContents, err := yaml.Marshal(&manifest)
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+
+	if to == FormatManifest {
+		return manifestContents, nil
+	}
+
+	// The manifest is parsed again so that the sources are pushed to their targets in the same way as sinker pushes them.
+	parsedManifest, err := Parse(manifestContents)
+	if err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+
+	switch to {
+	case FormatText:
+		return ToImageList(parsedManifest.Sources)
+	case FormatSkopeo:
+		return ToSkopeoSync(parsedManifest.Sources)
+	case FormatKubeImageKeeper:
+		return ToCachedImages(parsedManifest.Sources)
+	default:
+		return nil, fmt.Errorf("unknown format %s (must be one of %s)", to, strings.Join(ConvertFormats, ", "))
+	}
+}
+
+// FromImageList returns a manifest with the images of a plain text list, which has an image on each line. An image can be
+// followed by the image that it is pushed to (e.g. nginx:1.21 registry.mycompany.com/mirror/nginx:1.21), which is kept
+// as the target of the source, a mapping of the manifest or the target tag of the source. Empty lines and comments are ignored.
+//
+// The images that are not followed by the image that they are pushed to are pushed to the target. When no target is given,
+// the target of the manifest is the host of the first image that is pushed somewhere.
+func FromImageList(contents []byte, target Target) (Manifest, error) {
+	manifest := New(target.Host, target.Repository)
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) > 2 {
+			return Manifest{}, fmt.Errorf("line %d: expected an image and optionally the image it is pushed to, found %d images", line, len(fields))
+		}
+
+		source, err := getListSource(fields[0])
+		if err != nil {
+			return Manifest{}, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if len(fields) == 2 {
+			if manifest.Target.Host == "" {
+				targetImage, err := images.ParseReference(fields[1])
+				if err != nil {
+					return Manifest{}, fmt.Errorf("line %d: parse target %s: %w", line, fields[1], err)
+				}
+
+				manifest.Target.Host = targetImage.Host
+			}
+
+			if err := setListTarget(&manifest, &source, fields[1]); err != nil {
+				return Manifest{}, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+
+		manifest.Sources = append(manifest.Sources, source)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Manifest{}, fmt.Errorf("scan: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func getListSource(image string) (Source, error) {
+	parsedImage, err := images.ParseReference(image)
+	if err != nil {
+		return Source{}, fmt.Errorf("parse image %s: %w", image, err)
+	}
+
+	source := Source{
+		Host:       parsedImage.Host,
+		Repository: parsedImage.Repository,
+		Tag:        parsedImage.Tag,
+		Digest:     parsedImage.Digest,
+	}
+
+	return source, nil
+}
+
+// setListTarget sets where the source is pushed to. Images pushed to the host of the manifest target are kept as mappings
+// of the manifest when they are not pushed to the repository that sinker would push them to. Images pushed to another host
+// are kept as the target of the source, which must end with the repository of the source as sinker appends it to the target.
+func setListTarget(manifest *Manifest, source *Source, image string) error {
+	targetImage, err := images.ParseReference(image)
+	if err != nil {
+		return fmt.Errorf("parse target %s: %w", image, err)
+	}
+
+	if targetImage.Digest != "" && targetImage.Digest != source.Digest {
+		return fmt.Errorf("target %s is pinned to a different digest than %s", image, source.Image())
+	}
+
+	if targetImage.Tag != "" && targetImage.Tag != source.Tag {
+		source.TargetTag = targetImage.Tag
+	}
+
+	if targetImage.Host != manifest.Target.Host {
+		repository := strings.TrimSuffix(targetImage.Repository, source.Repository)
+		if repository == targetImage.Repository || (repository != "" && !strings.HasSuffix(repository, "/")) {
+			return fmt.Errorf("target %s does not end with the repository of %s, which only a target on %s can map it to", image, source.Image(), manifest.Target.Host)
+		}
+
+		source.Target = Target{
+			Host:       targetImage.Host,
+			Repository: strings.TrimSuffix(repository, "/"),
+		}
+
+		return nil
+	}
+
+	repository := source.Repository
+	if manifest.Target.Repository != "" {
+		repository = manifest.Target.Repository + "/" + repository
+	}
+
+	if targetImage.Repository == repository {
+		return nil
+	}
+
+	mappingSource := source.Repository
+	if source.Host != "" {
+		mappingSource = source.Host + "/" + source.Repository
+	}
+
+	for _, mapping := range manifest.Mappings {
+		if mapping.Source != mappingSource {
+			continue
+		}
+
+		if mapping.Repository != targetImage.Repository {
+			return fmt.Errorf("target %s maps %s to a different repository than %s", image, mappingSource, mapping.Repository)
+		}
+
+		return nil
+	}
+
+	manifest.Mappings = append(manifest.Mappings, Mapping{
+		Source:     mappingSource,
+		Repository: targetImage.Repository,
+	})
+
+	return nil
+}
+
+// ToImageList returns the sources as a plain text list, which has the image of each source followed by the
+// image that it is pushed to on each line. Sources without a target are listed without the image they are pushed to.
+func ToImageList(sources []Source) ([]byte, error) {
+	var list bytes.Buffer
+	for _, source := range sources {
+		if source.HasTagSelector() {
+			return nil, fmt.Errorf("source %s selects its tags, which a list of images cannot select", source.Image())
+		}
+
+		if source.Target.Host == "" {
+			list.WriteString(source.Image() + "\n")
+			continue
+		}
+
+		list.WriteString(source.Image() + " " + source.TargetImage() + "\n")
+	}
+
+	return list.Bytes(), nil
+}
+
+// skopeoSync is a skopeo sync configuration, with the registries to sync by their host.
+type skopeoSync map[string]skopeoRegistry
+
+// FromSkopeoSync returns a manifest with the images of a skopeo sync configuration, which are pushed to the target. Images
+// selected by a tag pattern or a semantic version range select their tags with the tag pattern or tags of the source.
+func FromSkopeoSync(contents []byte, target Target) (Manifest, error) {
+	var config skopeoSync
+	if err := yaml.Unmarshal(contents, &config); err != nil {
+		return Manifest{}, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	manifest := New(target.Host, target.Repository)
+	for host, registry := range config {
+		sourceHost := host
+		if sourceHost == "docker.io" {
+			sourceHost = ""
+		}
+
+		for repository, versions := range registry.Images {
+
+			// skopeo sync copies every tag of a repository without versions.
+			if len(versions) == 0 {
+				manifest.Sources = append(manifest.Sources, Source{Host: sourceHost, Repository: repository, TagPattern: ".*"})
+				continue
+			}
+
+			for _, version := range versions {
+				source := Source{Host: sourceHost, Repository: repository, Tag: version}
+				if strings.HasPrefix(version, "sha256:") {
+					source = Source{Host: sourceHost, Repository: repository, Digest: version}
+				}
+
+				manifest.Sources = append(manifest.Sources, source)
+			}
+		}
+
+		for repository, pattern := range registry.ImagesByTagRegex {
+			manifest.Sources = append(manifest.Sources, Source{Host: sourceHost, Repository: repository, TagPattern: getTagPattern(pattern)})
+		}
+
+		for repository, constraint := range registry.ImagesBySemver {
+			manifest.Sources = append(manifest.Sources, Source{Host: sourceHost, Repository: repository, Tags: constraint})
+		}
+	}
+
+	// The registries and repositories are sorted, while the versions of each repository keep their order.
+	sort.SliceStable(manifest.Sources, func(i, j int) bool {
+		if manifest.Sources[i].Host != manifest.Sources[j].Host {
+			return manifest.Sources[i].Host < manifest.Sources[j].Host
+		}
+
+		return manifest.Sources[i].Repository < manifest.Sources[j].Repository
+	})
+
+	return manifest, nil
+}
+
+// getTagPattern returns the tag pattern of a source that selects the same tags as the tag regex of skopeo sync. The tag
+// pattern of a source must match the whole tag, while a tag regex of skopeo sync matches any part of the tag unless it is anchored.
+func getTagPattern(regex string) string {
+	if strings.HasPrefix(regex, "^(?:") && strings.HasSuffix(regex, ")$") {
+		return strings.TrimSuffix(strings.TrimPrefix(regex, "^(?:"), ")$")
+	}
+
+	if strings.HasPrefix(regex, "^") {
+		regex = strings.TrimPrefix(regex, "^")
+	} else {
+		regex = ".*" + regex
+	}
+
+	if strings.HasSuffix(regex, "$") && !strings.HasSuffix(regex, `\$`) {
+		regex = strings.TrimSuffix(regex, "$")
+	} else {
+		regex += ".*"
+	}
+
+	return regex
+}
+
+// FromCachedImages returns a manifest with the source images of kube-image-keeper CachedImage resources, which are
+// pushed to the target. The resources can be separate YAML documents or the items of a List.
+func FromCachedImages(contents []byte, target Target) (Manifest, error) {
+	manifest := New(target.Host, target.Repository)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	for {
+		var resource cachedImage
+		err := decoder.Decode(&resource)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("decode: %w", err)
+		}
+
+		resources := append([]cachedImage{resource}, resource.Items...)
+		for _, resource := range resources {
+			if resource.Kind != "CachedImage" {
+				continue
+			}
+
+			source, err := getListSource(resource.Spec.SourceImage)
+			if err != nil {
+				return Manifest{}, fmt.Errorf("cached image %s: %w", resource.Metadata.Name, err)
+			}
+
+			manifest.Sources = append(manifest.Sources, source)
+		}
+	}
+
+	return manifest, nil
+}
+
+// ToCachedImages returns the sources as kube-image-keeper CachedImage resources that retain the images in the
+// cache of the cluster, so that the images are available in the same way as they are in the target registry.
+func ToCachedImages(sources []Source) ([]byte, error) {
+	var documents bytes.Buffer
+	for _, source := range sources {
+		if source.HasTagSelector() {
+			return nil, fmt.Errorf("source %s selects its tags, which kube-image-keeper cannot select", source.Image())
+		}
+
+		host := source.Host
+		if host == "" {
+			host = "docker.io"
+		}
+
+		var resource cachedImage
+		resource.APIVersion = kuikAPIVersion
+		resource.Kind = "CachedImage"
+		resource.Metadata.Name = getCachedImageName(host + "/" + strings.TrimPrefix(source.Image(), source.Host+"/"))
+		resource.Spec.SourceImage = source.Image()
+		resource.Spec.Retain = true
+
+		contents, err := yaml.Marshal(resource)
+		if err != nil {
+			return nil, fmt.Errorf("marshal: %w", err)
+		}
+
+		documents.WriteString("---\n")
+		documents.Write(contents)
+	}
+
+	return documents.Bytes(), nil
+}
+
+// getCachedImageName returns the name of the CachedImage of the image, which must be a valid name of a Kubernetes resource.
+func getCachedImageName(image string) string {
+	name := strings.Trim(harborNamePattern.ReplaceAllString(strings.ToLower(image), "-"), "-")
+	if len(name) > 253 {
+		name = strings.Trim(name[:253], "-")
+	}
+
+	return name
+}
@@ -0,0 +1,188 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	testCases := []struct {
+		contents string
+		expected string
+	}{
+		{"target:\n  host: mycompany.com\nsources:\n- repository: busybox\n", FormatManifest},
+		{"docker.io:\n  images:\n    busybox:\n    - 1.32.0\n", FormatSkopeo},
+		{"quay.io:\n  images-by-tag-regex:\n    coreos/etcd: ^v3\n", FormatSkopeo},
+		{"apiVersion: kuik.enix.io/v1alpha1\nkind: CachedImage\nspec:\n  sourceImage: busybox:1.32.0\n", FormatKubeImageKeeper},
+		{"busybox:1.32.0\nquay.io/coreos/etcd:v3.4.13\n", FormatText},
+		{"# images\nbusybox:1.32.0 mycompany.com/busybox:1.32.0\n", FormatText},
+	}
+
+	for _, testCase := range testCases {
+		if actual := DetectFormat([]byte(testCase.contents)); actual != testCase.expected {
+			t.Errorf("expected %q to be detected as %s, actual %s", testCase.contents, testCase.expected, actual)
+		}
+	}
+}
+
+func TestFromImageList(t *testing.T) {
+	const list = `# Images of the platform
+busybox:1.32.0
+quay.io/coreos/etcd:v3.4.13 mycompany.com/mirror/coreos/etcd:v3.4.13
+nginx:1.21 mycompany.com/web/nginx:stable
+quay.io/prometheus/prometheus:v2.26.0 large.mycompany.com/big/prometheus/prometheus:v2.26.0
+`
+
+	actual, err := FromImageList([]byte(list), Target{Host: "mycompany.com", Repository: "mirror"})
+	if err != nil {
+		t.Fatal("from image list:", err)
+	}
+
+	expected := Manifest{
+		Target: Target{Host: "mycompany.com", Repository: "mirror"},
+		Mappings: []Mapping{
+			{Source: "nginx", Repository: "web/nginx"},
+		},
+		Sources: []Source{
+			{Repository: "busybox", Tag: "1.32.0"},
+			{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.13"},
+			{Repository: "nginx", Tag: "1.21", TargetTag: "stable"},
+			{Host: "quay.io", Repository: "prometheus/prometheus", Tag: "v2.26.0", Target: Target{Host: "large.mycompany.com", Repository: "big"}},
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected manifest\n%+v\nactual\n%+v", expected, actual)
+	}
+}
+
+func TestFromImageList_UnmappableTarget(t *testing.T) {
+	const list = "busybox:1.32.0 other.mycompany.com/tools/box:1.32.0\n"
+
+	if _, err := FromImageList([]byte(list), Target{Host: "mycompany.com"}); err == nil {
+		t.Error("expected error for a target on another host that does not end with the repository of the source")
+	}
+}
+
+func TestFromSkopeoSync(t *testing.T) {
+	const digest = "sha256:bbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29"
+
+	const config = `quay.io:
+  images:
+    coreos/prometheus-operator:
+    - v0.40.0
+    - ` + digest + `
+  images-by-tag-regex:
+    coreos/etcd: ^v3\.4
+docker.io:
+  images:
+    busybox:
+    - 1.32.0
+`
+
+	actual, err := FromSkopeoSync([]byte(config), Target{Host: "mycompany.com"})
+	if err != nil {
+		t.Fatal("from skopeo sync:", err)
+	}
+
+	expected := []Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "quay.io", Repository: "coreos/etcd", TagPattern: `v3\.4.*`},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Digest: digest},
+	}
+
+	if !reflect.DeepEqual(actual.Sources, expected) {
+		t.Errorf("expected sources\n%+v\nactual\n%+v", expected, actual.Sources)
+	}
+}
+
+func TestFromCachedImages(t *testing.T) {
+	const resources = `apiVersion: kuik.enix.io/v1alpha1
+kind: CachedImage
+metadata:
+  name: docker.io-busybox-1.32.0
+spec:
+  sourceImage: busybox:1.32.0
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: kuik.enix.io/v1alpha1
+  kind: CachedImage
+  metadata:
+    name: quay.io-coreos-etcd-v3.4.13
+  spec:
+    sourceImage: quay.io/coreos/etcd:v3.4.13
+`
+
+	actual, err := FromCachedImages([]byte(resources), Target{Host: "mycompany.com"})
+	if err != nil {
+		t.Fatal("from cached images:", err)
+	}
+
+	expected := []Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.13"},
+	}
+
+	if !reflect.DeepEqual(actual.Sources, expected) {
+		t.Errorf("expected sources\n%+v\nactual\n%+v", expected, actual.Sources)
+	}
+}
+
+func TestConvert_ImageListRoundTrip(t *testing.T) {
+	const list = `busybox:1.32.0 mycompany.com/mirror/busybox:1.32.0
+quay.io/coreos/etcd:v3.4.13 mycompany.com/etcd:v3.4.13
+nginx:1.21 mycompany.com/mirror/nginx:stable
+quay.io/prometheus/prometheus:v2.26.0 large.mycompany.com/big/prometheus/prometheus:v2.26.0
+`
+
+	manifestContents, err := Convert([]byte(list), FormatText, FormatManifest, Target{Host: "mycompany.com", Repository: "mirror"})
+	if err != nil {
+		t.Fatal("convert to manifest:", err)
+	}
+
+	actual, err := Convert(manifestContents, FormatManifest, FormatText, Target{})
+	if err != nil {
+		t.Fatal("convert to image list:", err)
+	}
+
+	if string(actual) != list {
+		t.Errorf("expected image list\n%s\nactual\n%s\nconverted from manifest\n%s", list, actual, manifestContents)
+	}
+}
+
+func TestToCachedImages(t *testing.T) {
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.13"},
+	}
+
+	actual, err := ToCachedImages(sources)
+	if err != nil {
+		t.Fatal("to cached images:", err)
+	}
+
+	expected := `---
+apiVersion: kuik.enix.io/v1alpha1
+kind: CachedImage
+metadata:
+  name: docker-io-busybox-1-32-0
+spec:
+  sourceImage: busybox:1.32.0
+  retain: true
+---
+apiVersion: kuik.enix.io/v1alpha1
+kind: CachedImage
+metadata:
+  name: quay-io-coreos-etcd-v3-4-13
+spec:
+  sourceImage: quay.io/coreos/etcd:v3.4.13
+  retain: true
+`
+
+	if string(actual) != expected {
+		t.Errorf("expected cached images\n%s\nactual\n%s", expected, actual)
+	}
+}
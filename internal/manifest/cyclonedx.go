@@ -0,0 +1,133 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// cycloneDXBOM is a CycloneDX bill of materials with a component for each image.
+type cycloneDXBOM struct {
+	BOMFormat   string               `json:"bomFormat"`
+	SpecVersion string               `json:"specVersion"`
+	Version     int                  `json:"version"`
+	Metadata    cycloneDXMetadata    `json:"metadata"`
+	Components  []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Tools []cycloneDXTool `json:"tools"`
+}
+
+type cycloneDXTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl"`
+	Hashes     []cycloneDXHash     `json:"hashes,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ToCycloneDX returns the sources as a CycloneDX BOM in JSON with a container component for each image, which
+// dependency tracking systems (e.g. Dependency-Track) can ingest. Each component is identified by the package URL
+// of its source image, and records the image that sinker pushes it to in the sinker:target property. The version
+// of sinker that created the BOM is recorded as its tool.
+func ToCycloneDX(sources []Source, toolVersion string) ([]byte, error) {
+	bom := cycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Tools: []cycloneDXTool{{Vendor: "plexsystems", Name: "sinker", Version: toolVersion}},
+		},
+		Components: []cycloneDXComponent{},
+	}
+
+	refs := make(map[string]bool)
+	for _, source := range sources {
+		purl := getImagePURL(source)
+		if refs[purl] {
+			continue
+		}
+		refs[purl] = true
+
+		version := source.Tag
+		if version == "" {
+			version = source.Digest
+		}
+
+		component := cycloneDXComponent{
+			Type:    "container",
+			BOMRef:  purl,
+			Name:    source.Repository,
+			Version: version,
+			PURL:    purl,
+			Properties: []cycloneDXProperty{
+				{Name: "sinker:target", Value: source.TargetImage()},
+			},
+		}
+
+		if strings.HasPrefix(source.Digest, "sha256:") {
+			component.Hashes = []cycloneDXHash{{Algorithm: "SHA-256", Content: strings.TrimPrefix(source.Digest, "sha256:")}}
+		}
+
+		bom.Components = append(bom.Components, component)
+	}
+
+	// The package URLs have ampersands, which would otherwise be escaped.
+	var contents bytes.Buffer
+	encoder := json.NewEncoder(&contents)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(bom); err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+
+	return contents.Bytes(), nil
+}
+
+// getImagePURL returns the package URL of the image of the source (e.g. pkg:oci/etcd@sha256%3A...?repository_url=quay.io/coreos/etcd&tag=v3.4.13).
+// The name of the package is the last component of the repository, and its version is the digest of the image, if any.
+func getImagePURL(source Source) string {
+	host := source.Host
+	if host == "" {
+		host = "docker.io"
+	}
+
+	repository := source.Repository
+	if getCanonicalHost(host) == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	purl := "pkg:oci/" + strings.ToLower(path.Base(repository))
+	if source.Digest != "" {
+		purl += "@" + strings.Replace(source.Digest, ":", "%3A", 1)
+	}
+
+	qualifiers := "?repository_url=" + host + "/" + repository
+	if source.Tag != "" {
+		qualifiers += "&tag=" + url.QueryEscape(source.Tag)
+	}
+
+	return purl + qualifiers
+}
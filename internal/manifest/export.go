@@ -0,0 +1,104 @@
+package manifest
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// skopeoRegistry is a registry in a skopeo sync configuration.
+type skopeoRegistry struct {
+	Images           map[string][]string `yaml:"images,omitempty"`
+	ImagesByTagRegex map[string]string   `yaml:"images-by-tag-regex,omitempty"`
+	ImagesBySemver   map[string]string   `yaml:"images-by-semver,omitempty"`
+}
+
+// ToSkopeoSync returns the sources as a configuration file for skopeo sync
+// (e.g. skopeo sync --src yaml --dest docker sync.yaml host.com/repo).
+func ToSkopeoSync(sources []Source) ([]byte, error) {
+	registries := make(map[string]skopeoRegistry)
+	for _, source := range sources {
+		host := source.Host
+		if host == "" {
+			host = "docker.io"
+		}
+
+		if _, exists := registries[host]; !exists {
+			registries[host] = skopeoRegistry{
+				Images:           make(map[string][]string),
+				ImagesByTagRegex: make(map[string]string),
+				ImagesBySemver:   make(map[string]string),
+			}
+		}
+
+		// Sources that select their tags are synced by the tags that skopeo sync selects with
+		// the same pattern or version range, although skopeo does not keep a number of tags.
+		if source.TagPattern != "" {
+			registries[host].ImagesByTagRegex[source.Repository] = "^(?:" + source.TagPattern + ")$"
+			continue
+		}
+
+		if source.Tags != "" {
+			registries[host].ImagesBySemver[source.Repository] = source.Tags
+			continue
+		}
+
+		// skopeo sync selects images by either their tag or their digest. Sources
+		// that are pinned to a digest are synced by their digest.
+		version := source.Tag
+		if source.Digest != "" {
+			version = source.Digest
+		}
+
+		images := registries[host].Images
+		if version == "" {
+			images[source.Repository] = append(images[source.Repository], "latest")
+		} else {
+			images[source.Repository] = append(images[source.Repository], version)
+		}
+	}
+
+	contents, err := yaml.Marshal(registries)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return contents, nil
+}
+
+// imageSetConfiguration is the configuration used by oc-mirror.
+type imageSetConfiguration struct {
+	Kind       string `yaml:"kind"`
+	APIVersion string `yaml:"apiVersion"`
+	Mirror     struct {
+		AdditionalImages []additionalImage `yaml:"additionalImages"`
+	} `yaml:"mirror"`
+}
+
+type additionalImage struct {
+	Name string `yaml:"name"`
+}
+
+// ToImageSetConfig returns the sources as an ImageSetConfiguration for oc-mirror.
+func ToImageSetConfig(sources []Source) ([]byte, error) {
+	config := imageSetConfiguration{
+		Kind:       "ImageSetConfiguration",
+		APIVersion: "mirror.openshift.io/v1alpha2",
+	}
+
+	for _, source := range sources {
+		image := source.Image()
+		if source.Host == "" {
+			image = "docker.io/" + image
+		}
+
+		config.Mirror.AdditionalImages = append(config.Mirror.AdditionalImages, additionalImage{Name: image})
+	}
+
+	contents, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return contents, nil
+}
@@ -0,0 +1,279 @@
+package manifest
+
+import "testing"
+
+func TestToSkopeoSync(t *testing.T) {
+	const digest = "sha256:bbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29"
+
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.39.0", Digest: digest},
+	}
+
+	actual, err := ToSkopeoSync(sources)
+	if err != nil {
+		t.Fatal("to skopeo sync:", err)
+	}
+
+	expected := `docker.io:
+  images:
+    busybox:
+    - 1.32.0
+quay.io:
+  images:
+    coreos/prometheus-operator:
+    - v0.40.0
+    - ` + digest + `
+`
+
+	if string(actual) != expected {
+		t.Errorf("expected skopeo sync\n%s\nactual\n%s", expected, actual)
+	}
+}
+
+func TestToImageSetConfig(t *testing.T) {
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+	}
+
+	actual, err := ToImageSetConfig(sources)
+	if err != nil {
+		t.Fatal("to image set config:", err)
+	}
+
+	expected := `kind: ImageSetConfiguration
+apiVersion: mirror.openshift.io/v1alpha2
+mirror:
+  additionalImages:
+  - name: docker.io/busybox:1.32.0
+  - name: quay.io/coreos/prometheus-operator:v0.40.0
+`
+
+	if string(actual) != expected {
+		t.Errorf("expected image set config\n%s\nactual\n%s", expected, actual)
+	}
+}
+
+func TestToHarborReplication(t *testing.T) {
+	target := Target{Host: "harbor.mycompany.com", Repository: "mirror"}
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0", Target: target},
+		{Repository: "busybox", Tag: "1.33.0", Target: target},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0", Target: target},
+	}
+
+	actual, err := ToHarborReplication(sources)
+	if err != nil {
+		t.Fatal("to harbor replication:", err)
+	}
+
+	expected := `{
+  "registries": [
+    {
+      "name": "docker.io",
+      "type": "docker-hub",
+      "url": "https://hub.docker.com"
+    },
+    {
+      "name": "quay.io",
+      "type": "quay",
+      "url": "https://quay.io"
+    }
+  ],
+  "policies": [
+    {
+      "name": "sinker-docker-io-library-busybox",
+      "src_registry": {
+        "name": "docker.io"
+      },
+      "dest_namespace": "mirror",
+      "dest_namespace_replace_count": 1,
+      "filters": [
+        {
+          "type": "name",
+          "value": "library/busybox"
+        },
+        {
+          "type": "tag",
+          "value": "{1.32.0,1.33.0}"
+        }
+      ],
+      "trigger": {
+        "type": "manual"
+      },
+      "override": true,
+      "enabled": true
+    },
+    {
+      "name": "sinker-quay-io-coreos-prometheus-operator",
+      "src_registry": {
+        "name": "quay.io"
+      },
+      "dest_namespace": "mirror",
+      "dest_namespace_replace_count": 0,
+      "filters": [
+        {
+          "type": "name",
+          "value": "coreos/prometheus-operator"
+        },
+        {
+          "type": "tag",
+          "value": "v0.40.0"
+        }
+      ],
+      "trigger": {
+        "type": "manual"
+      },
+      "override": true,
+      "enabled": true
+    }
+  ]
+}
+`
+
+	if string(actual) != expected {
+		t.Errorf("expected harbor replication\n%s\nactual\n%s", expected, actual)
+	}
+
+	digestSources := []Source{{Repository: "busybox", Digest: "sha256:bbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29", Target: target}}
+	if _, err := ToHarborReplication(digestSources); err == nil {
+		t.Error("expected error for sources that are only pinned to a digest")
+	}
+}
+
+func TestToCycloneDX(t *testing.T) {
+	const digest = "sha256:bbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29"
+
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com"}},
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.13", Digest: digest, Target: Target{Host: "mycompany.com"}},
+		{Repository: "busybox", Tag: "1.32.0", Target: Target{Host: "mycompany.com"}},
+	}
+
+	actual, err := ToCycloneDX(sources, "v1.0.0")
+	if err != nil {
+		t.Fatal("to cyclonedx:", err)
+	}
+
+	expected := `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.4",
+  "version": 1,
+  "metadata": {
+    "tools": [
+      {
+        "vendor": "plexsystems",
+        "name": "sinker",
+        "version": "v1.0.0"
+      }
+    ]
+  },
+  "components": [
+    {
+      "type": "container",
+      "bom-ref": "pkg:oci/busybox?repository_url=docker.io/library/busybox&tag=1.32.0",
+      "name": "busybox",
+      "version": "1.32.0",
+      "purl": "pkg:oci/busybox?repository_url=docker.io/library/busybox&tag=1.32.0",
+      "properties": [
+        {
+          "name": "sinker:target",
+          "value": "mycompany.com/busybox:1.32.0"
+        }
+      ]
+    },
+    {
+      "type": "container",
+      "bom-ref": "pkg:oci/etcd@sha256%3Abbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29?repository_url=quay.io/coreos/etcd&tag=v3.4.13",
+      "name": "coreos/etcd",
+      "version": "v3.4.13",
+      "purl": "pkg:oci/etcd@sha256%3Abbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29?repository_url=quay.io/coreos/etcd&tag=v3.4.13",
+      "hashes": [
+        {
+          "alg": "SHA-256",
+          "content": "bbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29"
+        }
+      ],
+      "properties": [
+        {
+          "name": "sinker:target",
+          "value": "mycompany.com/coreos/etcd:v3.4.13"
+        }
+      ]
+    }
+  ]
+}
+`
+
+	if string(actual) != expected {
+		t.Errorf("expected bom\n%s\nactual\n%s", expected, actual)
+	}
+}
+
+func TestToBackstageEntities(t *testing.T) {
+	sources := []Source{
+		{Host: "quay.io", Repository: "coreos/etcd", Tag: "v3.4.13", Target: Target{Host: "mycompany.com"}},
+		{Host: "ghcr.io", Repository: "org/chart", Tag: "1.0.0", Type: SourceTypeChart, Target: Target{Host: "mycompany.com"}},
+		{Host: "quay.io", Repository: "coreos/etcd-v3", Tag: "4.13", Target: Target{Host: "mycompany.com"}},
+	}
+
+	actual, err := ToBackstageEntities(sources, "group:platform")
+	if err != nil {
+		t.Fatal("to backstage entities:", err)
+	}
+
+	expected := `---
+apiVersion: backstage.io/v1alpha1
+kind: Resource
+metadata:
+  name: quay-io-coreos-etcd-v3-4-13
+  title: quay.io/coreos/etcd:v3.4.13
+  annotations:
+    sinker.io/source-image: quay.io/coreos/etcd:v3.4.13
+    sinker.io/target-image: mycompany.com/coreos/etcd:v3.4.13
+  tags:
+  - quay-io
+spec:
+  type: container-image
+  owner: group:platform
+---
+apiVersion: backstage.io/v1alpha1
+kind: Resource
+metadata:
+  name: ghcr-io-org-chart-1-0-0
+  title: ghcr.io/org/chart:1.0.0
+  annotations:
+    sinker.io/source-image: ghcr.io/org/chart:1.0.0
+    sinker.io/target-image: mycompany.com/org/chart:1.0.0
+  tags:
+  - ghcr-io
+spec:
+  type: helm-chart
+  owner: group:platform
+---
+apiVersion: backstage.io/v1alpha1
+kind: Resource
+metadata:
+  name: quay-io-coreos-etcd-v3-4-13-` + getBackstageName("quay.io/coreos/etcd-v3:4.13", true)[len("quay-io-coreos-etcd-v3-4-13-"):] + `
+  title: quay.io/coreos/etcd-v3:4.13
+  annotations:
+    sinker.io/source-image: quay.io/coreos/etcd-v3:4.13
+    sinker.io/target-image: mycompany.com/coreos/etcd-v3:4.13
+  tags:
+  - quay-io
+spec:
+  type: container-image
+  owner: group:platform
+`
+
+	if string(actual) != expected {
+		t.Errorf("expected entities\n%s\nactual\n%s", expected, actual)
+	}
+
+	longName := getBackstageName("registry.mycompany.com/a/very/long/repository/path/for/an/image:v1.2.3-alpha.1", false)
+	if len(longName) > backstageNameLength {
+		t.Errorf("expected name of at most %v characters, actual %s", backstageNameLength, longName)
+	}
+}
@@ -0,0 +1,67 @@
+package manifest
+
+import (
+	"fmt"
+)
+
+// Fallback is a registry that the image of a source is pulled from when it cannot be pulled from the registry
+// of the source, such as during an outage of the registry or after the project moved to another registry.
+type Fallback struct {
+
+	// Host is the host of the fallback registry, which is the host of the source when it is not set.
+	Host string `yaml:"host,omitempty"`
+
+	// Repository is the repository at the fallback registry, which is the repository of the source when it is not set.
+	Repository string `yaml:"repository,omitempty"`
+
+	Auth Auth `yaml:"auth,omitempty"`
+}
+
+// FallbackSources returns a source for each of the fallbacks of the source, in the order that they should be tried.
+// The fallback sources pull the same tag or digest from the fallback registry, but they are pushed to the same
+// target as the source. Fallback registries are pulled from directly, rather than through a registry mirror.
+func (s Source) FallbackSources() []Source {
+	var fallbackSources []Source
+	for f := range s.Fallbacks {
+		fallback := s.Fallbacks[f]
+
+		fallbackSource := s
+		fallbackSource.Auth = fallback.Auth
+		fallbackSource.pullMirror = ""
+		fallbackSource.fallback = &fallback
+		fallbackSources = append(fallbackSources, fallbackSource)
+	}
+
+	return fallbackSources
+}
+
+// fallbackImage returns the image that is pulled from the fallback registry of the source.
+func (s Source) fallbackImage() string {
+	pulled := Source{
+		Host:       s.fallback.Host,
+		Repository: s.fallback.Repository,
+		Tag:        s.Tag,
+		Digest:     s.Digest,
+	}
+
+	if pulled.Host == "" {
+		pulled.Host = s.Host
+	}
+
+	if pulled.Repository == "" {
+		pulled.Repository = s.Repository
+	}
+
+	return pulled.Image()
+}
+
+// validateFallbacks returns an error when a fallback of the source does not refer to another registry or repository.
+func validateFallbacks(source Source) error {
+	for f, fallback := range source.Fallbacks {
+		if fallback.Host == "" && fallback.Repository == "" {
+			return fmt.Errorf("source %s: fallback %v must have a host or repository", source.Image(), f+1)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package manifest
+
+import "testing"
+
+func TestFallbackSources(t *testing.T) {
+	const contents = `target:
+  host: mycompany.com
+  repository: mirrors
+sources:
+- repository: org/app
+  host: ghcr.io
+  tag: v1.0.0
+  fallbacks:
+  - host: quay.io
+  - host: docker.io
+    repository: orgmirror/app
+    auth:
+      username: DOCKER_USERNAME
+      password: DOCKER_PASSWORD
+`
+
+	imageManifest, err := Parse([]byte(contents))
+	if err != nil {
+		t.Fatal("parse manifest:", err)
+	}
+
+	source := imageManifest.Sources[0]
+
+	mirroredSources, err := WithSourceMirrors([]Source{source}, map[string]string{"ghcr.io": "mirror.internal/ghcr.io"})
+	if err != nil {
+		t.Fatal("with source mirrors:", err)
+	}
+
+	fallbackSources := mirroredSources[0].FallbackSources()
+
+	expected := []string{
+		"quay.io/org/app:v1.0.0",
+		"docker.io/orgmirror/app:v1.0.0",
+	}
+
+	if len(fallbackSources) != len(expected) {
+		t.Fatalf("expected %v fallback sources, actual %v", len(expected), len(fallbackSources))
+	}
+
+	for f, fallbackSource := range fallbackSources {
+		if fallbackSource.PullImage() != expected[f] {
+			t.Errorf("expected fallback %v to be pulled from %s, actual %s", f+1, expected[f], fallbackSource.PullImage())
+		}
+
+		if fallbackSource.TargetImage() != source.TargetImage() {
+			t.Errorf("expected fallback %v to be pushed to %s, actual %s", f+1, source.TargetImage(), fallbackSource.TargetImage())
+		}
+
+		if fallbackSource.Image() != source.Image() {
+			t.Errorf("expected the image of fallback %v to remain %s, actual %s", f+1, source.Image(), fallbackSource.Image())
+		}
+	}
+
+	if fallbackSources[1].Auth.Username != "DOCKER_USERNAME" {
+		t.Errorf("expected the second fallback to use its own auth, actual %+v", fallbackSources[1].Auth)
+	}
+
+	if _, err := Parse([]byte("sources:\n- repository: org/app\n  fallbacks:\n  - auth:\n      helper: ecr\n")); err == nil {
+		t.Error("expected error for a fallback without a host or repository")
+	}
+}
@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// The strategies that flatten the repositories of the sources for registries that do not support
+// nested repositories (e.g. coreos/prometheus-operator under the target repository).
+const (
+	// FlattenReplace replaces the slashes of the repository with dashes (coreos-prometheus-operator).
+	FlattenReplace = "replace"
+
+	// FlattenLast keeps the last segments of the repository (prometheus-operator).
+	FlattenLast = "last"
+
+	// FlattenHash prefixes the last segment of the repository with a short hash
+	// of the host and repository of the source (1a2b3c4d-prometheus-operator).
+	FlattenHash = "hash"
+)
+
+// Flatten is how the repositories of the sources are flattened at the target.
+type Flatten struct {
+	Strategy string `yaml:"strategy,omitempty"`
+
+	// Segments is the number of segments of the repository that are
+	// kept by the last strategy, which defaults to one.
+	Segments int `yaml:"segments,omitempty"`
+}
+
+// repository returns the repository of the source at the target after it has been flattened.
+func (f Flatten) repository(source Source) string {
+	segments := strings.Split(source.Repository, "/")
+
+	switch f.Strategy {
+	case FlattenReplace:
+		return strings.Join(segments, "-")
+
+	case FlattenLast:
+		keep := f.Segments
+		if keep < 1 {
+			keep = 1
+		}
+
+		if keep < len(segments) {
+			segments = segments[len(segments)-keep:]
+		}
+
+		return strings.Join(segments, "/")
+
+	case FlattenHash:
+		// Images on Docker Hub have the same hash whether or not they
+		// include the host, or the library namespace of official images.
+		host, repository := source.Host, source.Repository
+		if host == "" || host == "docker.io" {
+			host = "docker.io"
+			if len(segments) == 1 {
+				repository = "library/" + repository
+			}
+		}
+
+		hash := sha256.Sum256([]byte(host + "/" + repository))
+		return fmt.Sprintf("%x-%s", hash[:4], segments[len(segments)-1])
+	}
+
+	return source.Repository
+}
+
+func validateFlatten(flatten Flatten) error {
+	switch flatten.Strategy {
+	case "", FlattenReplace, FlattenLast, FlattenHash:
+	default:
+		return fmt.Errorf("unknown flatten strategy %s (must be %s, %s or %s)", flatten.Strategy, FlattenReplace, FlattenLast, FlattenHash)
+	}
+
+	if flatten.Segments < 0 {
+		return fmt.Errorf("flatten segments must not be negative, actual %v", flatten.Segments)
+	}
+
+	return nil
+}
+
+// validateFlattenedRepositories returns an error when the repositories of different source repositories
+// are flattened into the same repository at the target, where their tags would overwrite each other.
+func validateFlattenedRepositories(sources []Source) error {
+	flattenedRepositories := make(map[string]string)
+	for _, source := range sources {
+		if source.Target.Flatten.Strategy == "" || source.mappedRepository != "" {
+			continue
+		}
+
+		sourceRepository := source.Host + "/" + source.Repository
+		targetRepository := source.TargetRepository()
+		if existing, ok := flattenedRepositories[targetRepository]; ok && existing != sourceRepository {
+			return fmt.Errorf("repositories %s and %s are both flattened into %s", strings.TrimPrefix(existing, "/"), strings.TrimPrefix(sourceRepository, "/"), targetRepository)
+		}
+
+		flattenedRepositories[targetRepository] = sourceRepository
+	}
+
+	return nil
+}
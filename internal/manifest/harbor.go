@@ -0,0 +1,194 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// harborReplication is a set of Harbor replication rules that pull the images in the manifest into Harbor.
+type harborReplication struct {
+	Registries []harborRegistry `json:"registries"`
+	Policies   []harborPolicy   `json:"policies"`
+}
+
+// harborRegistry is a registry endpoint that Harbor replicates images from.
+type harborRegistry struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// harborPolicy is a pull-based replication rule. The source registry is referenced by its name, as
+// Harbor refers to registry endpoints by an ID that is only known once the endpoint has been created.
+type harborPolicy struct {
+	Name                      string         `json:"name"`
+	SourceRegistry            harborRegistry `json:"src_registry"`
+	DestinationNamespace      string         `json:"dest_namespace,omitempty"`
+	DestinationNamespaceCount int            `json:"dest_namespace_replace_count"`
+	Filters                   []harborFilter `json:"filters"`
+	Trigger                   harborTrigger  `json:"trigger"`
+	Override                  bool           `json:"override"`
+	Enabled                   bool           `json:"enabled"`
+}
+
+type harborFilter struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type harborTrigger struct {
+	Type string `json:"type"`
+}
+
+var harborNamePattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ToHarborReplication returns the sources as Harbor replication rules that pull each image from its source
+// registry into the same repository that sinker pushes it to, along with the registry endpoints that the rules
+// pull from. Images of the same repository that are pushed to the same place are replicated by a single rule.
+//
+// Harbor selects images by their tag and keeps the tag at the target, so sources that are only pinned to a
+// digest or whose tag changes at the target cannot be replicated by Harbor.
+func ToHarborReplication(sources []Source) ([]byte, error) {
+	var replication harborReplication
+
+	registries := make(map[string]bool)
+	policies := make(map[string]int)
+	names := make(map[string]int)
+	for _, source := range sources {
+		host, repository := getHarborSource(source)
+
+		tag := source.Tag
+		if tag == "" && source.Digest != "" {
+			return nil, fmt.Errorf("source %s is pinned to a digest, which harbor cannot replicate", source.Image())
+		}
+		if tag == "" {
+			tag = "latest"
+		}
+
+		targetTag, err := source.targetTag()
+		if err != nil {
+			return nil, fmt.Errorf("get target tag: %w", err)
+		}
+
+		if targetTag != "" && targetTag != tag {
+			return nil, fmt.Errorf("source %s changes its tag at the target, which harbor cannot replicate", source.Image())
+		}
+
+		targetRepository := strings.TrimPrefix(source.TargetRepository(), source.Target.Host+"/")
+		namespace, count, ok := getHarborDestination(repository, targetRepository)
+		if !ok {
+			return nil, fmt.Errorf("source %s is pushed to %s, which harbor cannot replicate", source.Image(), targetRepository)
+		}
+
+		registry := getHarborRegistry(host)
+		if !registries[host] {
+			registries[host] = true
+			replication.Registries = append(replication.Registries, registry)
+		}
+
+		key := fmt.Sprintf("%s/%s:%s:%d", host, repository, namespace, count)
+		if i, exists := policies[key]; exists {
+			replication.Policies[i].Filters[1].Value = appendHarborTag(replication.Policies[i].Filters[1].Value, tag)
+			continue
+		}
+
+		name := "sinker-" + strings.Trim(harborNamePattern.ReplaceAllString(strings.ToLower(host+"-"+repository), "-"), "-")
+		names[name]++
+		if names[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, names[name])
+		}
+
+		policies[key] = len(replication.Policies)
+		replication.Policies = append(replication.Policies, harborPolicy{
+			Name:                      name,
+			SourceRegistry:            harborRegistry{Name: registry.Name},
+			DestinationNamespace:      namespace,
+			DestinationNamespaceCount: count,
+			Filters: []harborFilter{
+				{Type: "name", Value: repository},
+				{Type: "tag", Value: tag},
+			},
+			Trigger:  harborTrigger{Type: "manual"},
+			Override: true,
+			Enabled:  true,
+		})
+	}
+
+	contents, err := json.MarshalIndent(replication, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return append(contents, '\n'), nil
+}
+
+// getHarborSource returns the host of the source registry and the repository of the source as Harbor
+// refers to it, where the official images of Docker Hub are in the library namespace.
+func getHarborSource(source Source) (string, string) {
+	host := source.Host
+	if host == "" {
+		host = "docker.io"
+	}
+
+	repository := source.Repository
+	if host == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	return host, repository
+}
+
+// getHarborDestination returns the namespace and the number of leading path components of the repository
+// that Harbor replaces with the namespace, so that the repository is replicated to the target repository.
+func getHarborDestination(repository string, targetRepository string) (string, int, bool) {
+	if repository == targetRepository {
+		return "", 0, true
+	}
+
+	components := strings.Split(repository, "/")
+	for count := range components {
+		suffix := "/" + strings.Join(components[count:], "/")
+		if strings.HasSuffix(targetRepository, suffix) {
+			return strings.TrimSuffix(targetRepository, suffix), count, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// getHarborRegistry returns the registry endpoint of the host with the provider type that Harbor uses for it.
+func getHarborRegistry(host string) harborRegistry {
+	registry := harborRegistry{Name: host, Type: "docker-registry", URL: "https://" + host}
+
+	switch {
+	case host == "docker.io":
+		registry.Type = "docker-hub"
+		registry.URL = "https://hub.docker.com"
+	case host == "quay.io":
+		registry.Type = "quay"
+	case host == "ghcr.io":
+		registry.Type = "github-ghcr"
+	case host == "gcr.io" || strings.HasSuffix(host, ".gcr.io"):
+		registry.Type = "google-gcr"
+	case strings.Contains(host, ".dkr.ecr."):
+		registry.Type = "aws-ecr"
+	case strings.HasSuffix(host, ".azurecr.io"):
+		registry.Type = "azure-acr"
+	}
+
+	return registry
+}
+
+// appendHarborTag adds the tag to the tag filter, which matches several tags with the {a,b} pattern.
+func appendHarborTag(filter string, tag string) string {
+	tags := strings.Split(strings.Trim(filter, "{}"), ",")
+	for _, current := range tags {
+		if current == tag {
+			return filter
+		}
+	}
+
+	return "{" + strings.Join(append(tags, tag), ",") + "}"
+}
@@ -0,0 +1,86 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const ignoreFileName = ".sinkerignore"
+
+// GetIgnoreFile returns the patterns in the ignore file (.sinkerignore) that is located
+// next to the manifest at the specified path. When no ignore file exists, no patterns are returned.
+//
+// Each line of the ignore file is a glob pattern. Empty lines and lines starting with # are skipped.
+func GetIgnoreFile(manifestPath string) ([]string, error) {
+	ignoreFilePath := filepath.Join(filepath.Dir(getManifestLocation(manifestPath)), ignoreFileName)
+
+	contents, err := ioutil.ReadFile(ignoreFilePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read ignore file: %w", err)
+	}
+
+	var patterns []string
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		patterns = append(patterns, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan ignore file: %w", err)
+	}
+
+	return patterns, nil
+}
+
+// CompileGlob compiles a glob pattern that matches images into a regular expression.
+// A * matches any sequence of characters, including /, and a ? matches any single character.
+func CompileGlob(pattern string) (*regexp.Regexp, error) {
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.ReplaceAll(expression, `\*`, ".*")
+	expression = strings.ReplaceAll(expression, `\?`, ".")
+
+	compiled, err := regexp.Compile("^" + expression + "$")
+	if err != nil {
+		return nil, fmt.Errorf("compile glob %s: %w", pattern, err)
+	}
+
+	return compiled, nil
+}
+
+// Exclude returns the sources whose images do not match any of the given patterns.
+func Exclude(sources []Source, patterns []*regexp.Regexp) []Source {
+	var included []Source
+	for _, source := range sources {
+		if matchesAny(source.Image(), patterns) {
+			continue
+		}
+
+		included = append(included, source)
+	}
+
+	return included
+}
+
+func matchesAny(image string, patterns []*regexp.Regexp) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(image) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,58 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestExclude(t *testing.T) {
+	sources := []Source{
+		{Repository: "busybox", Tag: "latest"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "internal.example.com", Repository: "team/app", Tag: "v1.0.0"},
+	}
+
+	var patterns []*regexp.Regexp
+	for _, glob := range []string{"busybox:*", "internal.example.com/*"} {
+		pattern, err := CompileGlob(glob)
+		if err != nil {
+			t.Fatal("compile glob:", err)
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	actual := Exclude(sources, patterns)
+
+	expected := []Source{sources[1]}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected sources %+v, actual %+v", expected, actual)
+	}
+}
+
+func TestGetIgnoreFile(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	contents := []byte("# test fixtures\nbusybox:*\n\ninternal.example.com/*\n")
+	if err := ioutil.WriteFile(filepath.Join(root, ".sinkerignore"), contents, os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	actual, err := GetIgnoreFile(filepath.Join(root, ".images.yaml"))
+	if err != nil {
+		t.Fatal("get ignore file:", err)
+	}
+
+	expected := []string{"busybox:*", "internal.example.com/*"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected patterns %v, actual %v", expected, actual)
+	}
+}
@@ -1,6 +1,11 @@
 package manifest
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
 
 func TestGetSourceHostFromRepository(t *testing.T) {
 	testCases := []struct {
@@ -33,3 +38,29 @@ func TestGetSourceHostFromRepository(t *testing.T) {
 		}
 	}
 }
+
+func TestMarshalImages(t *testing.T) {
+	target := Target{
+		Host:       "mycompany.com",
+		Repository: "myteam",
+	}
+
+	foundImages := []images.Image{
+		{Host: "gcr.io", Repository: "google-containers/pause", Tag: "3.2"},
+		{Host: "localhost:5000", Repository: "busybox", Tag: "1.32.0"},
+		{Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "mycompany.com", Repository: "myteam/jimmidyson/configmap-reload", Tag: "v0.3.0"},
+	}
+
+	expected := []Source{
+		{Host: "gcr.io", Repository: "google-containers/pause", Tag: "3.2"},
+		{Host: "localhost:5000", Repository: "busybox", Tag: "1.32.0"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Repository: "jimmidyson/configmap-reload", Tag: "v0.3.0"},
+	}
+
+	actual := marshalImages(foundImages, target)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected sources %+v, actual %+v", expected, actual)
+	}
+}
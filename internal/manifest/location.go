@@ -0,0 +1,45 @@
+package manifest
+
+import "strings"
+
+// Line returns the approximate line of the manifest that the source is defined at,
+// or zero when the source was not read from a manifest (e.g. the images flag).
+func (s Source) Line() int {
+	return s.line
+}
+
+// setSourceLines sets the line of each source to the line of its repository in the sources section of the
+// manifest. When more than one source has the same repository, the sources are matched to the lines in order.
+func setSourceLines(contents []byte, sources []Source) {
+	lines := strings.Split(string(contents), "\n")
+
+	var sourcesStart int
+	for i, line := range lines {
+		if strings.HasPrefix(line, "sources:") {
+			sourcesStart = i + 1
+			break
+		}
+	}
+
+	repositoryLines := make(map[string][]int)
+	for i := sourcesStart; i < len(lines); i++ {
+		tokens := strings.Fields(lines[i])
+
+		for t := 0; t < len(tokens)-1; t++ {
+			if tokens[t] == "repository:" {
+				repository := strings.Trim(tokens[t+1], "\"'")
+				repositoryLines[repository] = append(repositoryLines[repository], i+1)
+			}
+		}
+	}
+
+	for s := range sources {
+		matchingLines := repositoryLines[sources[s].Repository]
+		if len(matchingLines) == 0 {
+			continue
+		}
+
+		sources[s].line = matchingLines[0]
+		repositoryLines[sources[s].Repository] = matchingLines[1:]
+	}
+}
@@ -1,8 +1,6 @@
 package manifest
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -10,16 +8,40 @@ import (
 	"strings"
 
 	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/pkg/images"
 
 	"gopkg.in/yaml.v2"
 )
 
 // Manifest contains all of the sources to push to a target registry.
 type Manifest struct {
-	Target  Target   `yaml:"target"`
+	Target   Target    `yaml:"target"`
+	Mappings []Mapping `yaml:"mappings,omitempty"`
+
+	// Ignore is a list of glob patterns that match source images which should be
+	// skipped by commands such as list, push and check (e.g. busybox:*).
+	Ignore []string `yaml:"ignore,omitempty"`
+
+	// Policy is enforced on the images found in resources by the lint command.
+	Policy Policy `yaml:"policy,omitempty"`
+
+	// Trust are the policies that the signatures of the source images are verified
+	// against by the push command when signatures are required.
+	Trust []TrustPolicy `yaml:"trust,omitempty"`
+
 	Sources []Source `yaml:"sources,omitempty"`
 }
 
+// Mapping remaps the repositories of the sources that match the source
+// prefix to the target repository when they are pushed to the target registry.
+//
+// For example, a mapping from quay.io/prometheus to mirrors/prometheus results in the
+// source quay.io/prometheus/prometheus being pushed to <target host>/mirrors/prometheus/prometheus.
+type Mapping struct {
+	Source     string `yaml:"source"`
+	Repository string `yaml:"repository"`
+}
+
 // New returns an empty Manifest with the target set to the
 // specified host and repository.
 func New(host string, repository string) Manifest {
@@ -37,7 +59,7 @@ func New(host string, repository string) Manifest {
 
 // NewWithAutodetect returns a manifest populated with the images found at the specified path.
 // The target of the manifest will be set to the specified host and repository.
-func NewWithAutodetect(host string, repository string, path string) (Manifest, error) {
+func NewWithAutodetect(host string, repository string, path string, opts ...images.Option) (Manifest, error) {
 	manifest := New(host, repository)
 
 	target := Target{
@@ -45,7 +67,7 @@ func NewWithAutodetect(host string, repository string, path string) (Manifest, e
 		Repository: repository,
 	}
 
-	images, err := GetImagesFromKubernetesManifests(path, target)
+	images, err := GetImagesFromKubernetesManifests(path, target, opts...)
 	if err != nil {
 		return Manifest{}, fmt.Errorf("get from kubernetes manifests: %w", err)
 	}
@@ -55,25 +77,98 @@ func NewWithAutodetect(host string, repository string, path string) (Manifest, e
 	return manifest, nil
 }
 
-// Get returns the manifest found at the specified path.
+// NewWithImages returns a manifest populated with the given images, such as the images of one of
+// the groups that were found at a path. The target of the manifest will be set to the specified host and repository.
+func NewWithImages(host string, repository string, foundImages []images.Image) Manifest {
+	manifest := New(host, repository)
+	manifest.Sources = marshalImages(foundImages, manifest.Target)
+
+	return manifest
+}
+
+// Get returns the manifest found at the specified path. References to
+// variables in the manifest are replaced with the environment variables.
 func Get(path string) (Manifest, error) {
-	manifestLocation := getManifestLocation(path)
-	manifestContents, err := ioutil.ReadFile(manifestLocation)
+	return GetWithVariables(path, nil)
+}
+
+// GetWithVariables returns the manifest found at the specified path. References to variables
+// in the manifest are replaced with the given variables, or the environment variables.
+func GetWithVariables(path string, variables map[string]string) (Manifest, error) {
+	manifestContents, err := ioutil.ReadFile(getManifestLocation(path))
 	if err != nil {
 		return Manifest{}, fmt.Errorf("reading manifest: %w", err)
 	}
 
+	manifestContents, err = ExpandVariables(manifestContents, variables)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("expand variables: %w", err)
+	}
+
+	return parseManifest(manifestContents)
+}
+
+// GetTemplate returns the manifest found at the specified path without replacing the references
+// to variables, so that the manifest can be written back without losing the references.
+func GetTemplate(path string) (Manifest, error) {
+	manifestContents, err := ioutil.ReadFile(getManifestLocation(path))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	return parseManifest(manifestContents)
+}
+
+// Parse returns the manifest with the given contents, which must not contain references to variables.
+func Parse(contents []byte) (Manifest, error) {
+	return parseManifest(contents)
+}
+
+func parseManifest(manifestContents []byte) (Manifest, error) {
 	var manifest Manifest
 	if err := yaml.Unmarshal(manifestContents, &manifest); err != nil {
 		return Manifest{}, fmt.Errorf("unmarshal manifest: %w", err)
 	}
 
-	// When a source in the manifest does not define its own target the default target
-	// should be the target defined in the manifest.
+	setSourceLines(manifestContents, manifest.Sources)
+
 	for s := range manifest.Sources {
-		if manifest.Sources[s].Target.Host == "" {
-			manifest.Sources[s].Target = manifest.Target
+		if !isSourceType(manifest.Sources[s].Type) {
+			return Manifest{}, fmt.Errorf("source %s has unknown type %s (must be one of %s)", manifest.Sources[s].Image(), manifest.Sources[s].Type, strings.Join(sourceTypes, ", "))
+		}
+
+		manifest.Sources[s].Target = getSourceTarget(manifest.Target, manifest.Sources[s].Target)
+		if err := validateTargetTag(manifest.Sources[s]); err != nil {
+			return Manifest{}, err
+		}
+
+		if err := validateFallbacks(manifest.Sources[s]); err != nil {
+			return Manifest{}, err
 		}
+
+		if err := validatePlatformImages(manifest.Sources[s]); err != nil {
+			return Manifest{}, err
+		}
+
+		if err := validateFlatten(manifest.Sources[s].Target.Flatten); err != nil {
+			return Manifest{}, fmt.Errorf("source %s: %w", manifest.Sources[s].Image(), err)
+		}
+
+		// A source that overrides the target is pushed to exactly where its target
+		// points to, so the mappings of the manifest do not apply to it.
+		if manifest.Sources[s].Target.Host != manifest.Target.Host || manifest.Sources[s].Target.Repository != manifest.Target.Repository {
+			continue
+		}
+
+		manifest.Sources[s].mappedRepository = getMappedRepository(manifest.Mappings, manifest.Sources[s])
+	}
+
+	if err := validateFlattenedRepositories(manifest.Sources); err != nil {
+		return Manifest{}, err
+	}
+
+	if err := validateTrustPolicies(manifest.Trust, manifest.Sources); err != nil {
+		return Manifest{}, err
 	}
 
 	return manifest, nil
@@ -98,6 +193,32 @@ func (m Manifest) Write(path string) error {
 type Auth struct {
 	Username string `yaml:"username,omitempty"`
 	Password string `yaml:"password,omitempty"`
+
+	// Helper is the built-in credential helper (ecr, gcr or acr) that gets the credentials from the
+	// cloud provider of the registry. Registries of the cloud providers are detected by their host,
+	// so the helper only needs to be set when the registry is accessed through a different host.
+	Helper string `yaml:"helper,omitempty"`
+
+	// Provider is the credential provider (vault, aws-secrets-manager or the name of a Docker credential helper)
+	// that fetches the credentials from a secret manager at runtime. Secret is the secret that the credentials
+	// are stored in, which defaults to the host of the registry.
+	Provider string `yaml:"provider,omitempty"`
+	Secret   string `yaml:"secret,omitempty"`
+}
+
+// encodedAuthFromProvider returns the Base64 encoded auth from the credential provider of the auth.
+func (a Auth) encodedAuthFromProvider(host string) (string, error) {
+	secret := a.Secret
+	if secret == "" {
+		secret = host
+	}
+
+	auth, err := docker.GetEncodedAuthFromProvider(a.Provider, secret)
+	if err != nil {
+		return "", fmt.Errorf("get encoded auth from provider: %w", err)
+	}
+
+	return auth, nil
 }
 
 // Target is the target registry where the images defined in
@@ -106,12 +227,20 @@ type Target struct {
 	Host       string `yaml:"host,omitempty"`
 	Repository string `yaml:"repository,omitempty"`
 	Auth       Auth   `yaml:"auth,omitempty"`
+
+	// Flatten is how the repositories of the sources are flattened for
+	// registries that do not support nested repositories.
+	Flatten Flatten `yaml:"flatten,omitempty"`
+
+	// TagTemplate is a Go template that the tag of each image at the target is created with
+	// (e.g. {{.Tag}}-mirrored), instead of using the tag of the source.
+	TagTemplate string `yaml:"tagTemplate,omitempty"`
 }
 
 // EncodedAuth returns the Base64 encoded auth for the target registry.
 func (t Target) EncodedAuth() (string, error) {
 	if t.Auth.Password != "" {
-		auth, err := getEncodedBasicAuth(os.Getenv(t.Auth.Username), os.Getenv(t.Auth.Password))
+		auth, err := docker.GetEncodedBasicAuth(os.Getenv(t.Auth.Username), os.Getenv(t.Auth.Password))
 		if err != nil {
 			return "", fmt.Errorf("get encoded auth: %w", err)
 		}
@@ -119,6 +248,19 @@ func (t Target) EncodedAuth() (string, error) {
 		return auth, nil
 	}
 
+	if t.Auth.Provider != "" {
+		return t.Auth.encodedAuthFromProvider(t.Host)
+	}
+
+	if t.Auth.Helper != "" {
+		auth, err := docker.GetEncodedAuthFromHelper(t.Auth.Helper, t.Host)
+		if err != nil {
+			return "", fmt.Errorf("get encoded auth from helper: %w", err)
+		}
+
+		return auth, nil
+	}
+
 	auth, err := docker.GetEncodedAuthForHost(t.Host)
 	if err != nil {
 		return "", fmt.Errorf("get encoded auth for host: %w", err)
@@ -135,15 +277,59 @@ type Source struct {
 	Tag        string `yaml:"tag,omitempty"`
 	Digest     string `yaml:"digest,omitempty"`
 	Auth       Auth   `yaml:"auth,omitempty"`
+
+	// TargetTag is the tag that the image is pushed to the target with, when it should
+	// be different from the tag of the source (e.g. 1.25-mirrored).
+	TargetTag string `yaml:"targetTag,omitempty"`
+
+	// Type is the type of the source, which is an image unless the source is a Helm chart
+	// or another OCI artifact (e.g. pushed with ORAS) that is mirrored between registries.
+	Type string `yaml:"type,omitempty"`
+
+	// Tags is a version constraint (e.g. ">= 1.20.0, < 1.23") that selects the tags of the repository
+	// to mirror, rather than a single tag. TagPattern selects the tags with a regular expression instead.
+	// When Keep is set, only the given number of the newest matching tags are mirrored.
+	Tags       string `yaml:"tags,omitempty"`
+	TagPattern string `yaml:"tagPattern,omitempty"`
+	Keep       int    `yaml:"keep,omitempty"`
+
+	// Trust is the name of the trust policy that the signature of the source is verified against,
+	// instead of the policy that applies to the source by the image patterns of the policies.
+	Trust string `yaml:"trust,omitempty"`
+
+	// Fallbacks are the registries that the image of the source is pulled from, in order, when it
+	// cannot be pulled from the registry of the source. The target of the source does not change.
+	Fallbacks []Fallback `yaml:"fallbacks,omitempty"`
+
+	// PlatformImages are the images of each platform of the source that are assembled into a multi-arch
+	// image at the target with the tag of the source, rather than copying the image of the source.
+	PlatformImages []PlatformImage `yaml:"platformImages,omitempty"`
+
+	// mappedRepository is the repository at the target after the
+	// mappings defined in the manifest have been applied.
+	mappedRepository string
+
+	// pullMirror is the registry mirror, including the path that the
+	// repositories are nested in, that the image of the source is pulled through.
+	pullMirror string
+
+	// fallback is the fallback that the image of the source is pulled from, if any.
+	fallback *Fallback
+
+	// line is the line of the manifest that the source is defined at.
+	line int
 }
 
-// Image returns the source image including its tag or digest.
+// Image returns the source image including its tag and digest.
+// When the source has both a tag and a digest, the digest determines which image is used.
 func (s Source) Image() string {
 	var source string
 	if s.Tag != "" {
 		source = ":" + s.Tag
-	} else if s.Digest != "" {
-		source = "@" + s.Digest
+	}
+
+	if s.Digest != "" {
+		source += "@" + s.Digest
 	}
 
 	if s.Repository != "" {
@@ -159,22 +345,38 @@ func (s Source) Image() string {
 	return source
 }
 
-// TargetImage returns the target image including its tag or digest.
+// TargetImage returns the target image including its tag, which is the digest of the
+// source when it does not have a tag, unless the tag is set by the target tag of the
+// source or the tag template of its target.
 func (s Source) TargetImage() string {
-	var target string
-	if s.Tag != "" {
-		target = ":" + s.Tag
-	} else if s.Digest != "" {
-		target = strings.ReplaceAll(s.Digest, "sha256:", "")
-		target = ":" + target
+
+	// The tag template of the manifest is validated when the manifest is read,
+	// so the tag of the source is only used when the template cannot be executed.
+	tag, err := s.targetTag()
+	if err != nil {
+		tag = s.Tag
 	}
 
-	if s.Repository != "" {
-		target = "/" + s.Repository + target
+	if tag == "" {
+		return s.TargetRepository()
 	}
 
-	if s.Target.Repository != "" {
-		target = "/" + s.Target.Repository + target
+	return s.TargetRepository() + ":" + tag
+}
+
+// TargetRepository returns the repository at the target that the image is pushed to, including the host.
+func (s Source) TargetRepository() string {
+	var target string
+	if s.mappedRepository != "" {
+		target = "/" + s.mappedRepository
+	} else {
+		if repository := s.Target.Flatten.repository(s); repository != "" {
+			target = "/" + repository
+		}
+
+		if s.Target.Repository != "" {
+			target = "/" + s.Target.Repository + target
+		}
 	}
 
 	if s.Target.Host != "" {
@@ -186,10 +388,11 @@ func (s Source) TargetImage() string {
 	return target
 }
 
-// EncodedAuth returns the Base64 encoded auth for the source registry.
+// EncodedAuth returns the Base64 encoded auth for the source registry, or for the
+// registry mirror when the image of the source is pulled through a mirror.
 func (s Source) EncodedAuth() (string, error) {
 	if s.Auth.Password != "" {
-		auth, err := getEncodedBasicAuth(os.Getenv(s.Auth.Username), os.Getenv(s.Auth.Password))
+		auth, err := docker.GetEncodedBasicAuth(os.Getenv(s.Auth.Username), os.Getenv(s.Auth.Password))
 		if err != nil {
 			return "", fmt.Errorf("get encoded auth: %w", err)
 		}
@@ -197,7 +400,20 @@ func (s Source) EncodedAuth() (string, error) {
 		return auth, nil
 	}
 
-	auth, err := docker.GetEncodedAuthForHost(s.Host)
+	if s.Auth.Provider != "" {
+		return s.Auth.encodedAuthFromProvider(s.pullHost())
+	}
+
+	if s.Auth.Helper != "" {
+		auth, err := docker.GetEncodedAuthFromHelper(s.Auth.Helper, s.pullHost())
+		if err != nil {
+			return "", fmt.Errorf("get encoded auth from helper: %w", err)
+		}
+
+		return auth, nil
+	}
+
+	auth, err := docker.GetEncodedAuthForHost(s.pullHost())
 	if err != nil {
 		return "", fmt.Errorf("get encoded auth for host: %w", err)
 	}
@@ -205,30 +421,106 @@ func (s Source) EncodedAuth() (string, error) {
 	return auth, nil
 }
 
-// GetSourcesFromImages returns the given images as sources with the specified target.
-func GetSourcesFromImages(images []string, target string) []Source {
+// GetSourcesFromImages returns the given images as sources with the specified target. The options
+// set the registry hosts that the hosts of the images and the target are found with.
+func GetSourcesFromImages(imageReferences []string, target string, opts ...images.Option) ([]Source, error) {
 	targetRegistryPath := docker.RegistryPath(target)
 	sourceTarget := Target{
-		Host:       targetRegistryPath.Host(),
-		Repository: targetRegistryPath.Repository(),
+		Host:       targetRegistryPath.Host(opts...),
+		Repository: targetRegistryPath.Repository(opts...),
 	}
 
 	var sources []Source
-	for _, image := range images {
-		registryPath := docker.RegistryPath(image)
+	for _, imageReference := range imageReferences {
+		image, err := images.ParseReference(imageReference, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("parse image %s: %w", imageReference, err)
+		}
 
 		source := Source{
-			Host:       registryPath.Host(),
+			Host:       image.Host,
 			Target:     sourceTarget,
-			Repository: registryPath.Repository(),
-			Tag:        registryPath.Tag(),
-			Digest:     registryPath.Digest(),
+			Repository: image.Repository,
+			Tag:        image.Tag,
+			Digest:     image.Digest,
 		}
 
 		sources = append(sources, source)
 	}
 
-	return sources
+	return sources, nil
+}
+
+// getSourceTarget returns the target of a source, which can override the host, repository or auth of the
+// target defined in the manifest (e.g. to push large images to a different registry). When a source does not
+// define its own host, the host and any unset repository or auth are taken from the target of the manifest.
+// The auth of the manifest target is also used when the source only overrides the repository.
+func getSourceTarget(manifestTarget Target, sourceTarget Target) Target {
+	if sourceTarget.Host != "" && sourceTarget.Host != manifestTarget.Host {
+		return sourceTarget
+	}
+
+	sourceTarget.Host = manifestTarget.Host
+	if sourceTarget.Repository == "" {
+		sourceTarget.Repository = manifestTarget.Repository
+	}
+
+	if sourceTarget.Auth == (Auth{}) {
+		sourceTarget.Auth = manifestTarget.Auth
+	}
+
+	if sourceTarget.TagTemplate == "" {
+		sourceTarget.TagTemplate = manifestTarget.TagTemplate
+	}
+
+	if sourceTarget.Flatten == (Flatten{}) {
+		sourceTarget.Flatten = manifestTarget.Flatten
+	}
+
+	return sourceTarget
+}
+
+func getMappedRepository(mappings []Mapping, source Source) string {
+	sourcePath := source.Repository
+	if source.Host != "" {
+		sourcePath = source.Host + "/" + source.Repository
+	}
+
+	// Images without a host are sourced from Docker Hub and can be matched
+	// with or without the docker.io prefix.
+	sourcePaths := []string{sourcePath}
+	if source.Host == "" {
+		sourcePaths = append(sourcePaths, "docker.io/"+source.Repository)
+	}
+
+	// When more than one mapping matches the source, the most specific mapping is used.
+	var mappedRepository string
+	var matchedLength int
+	for _, mapping := range mappings {
+		prefix := strings.TrimSuffix(mapping.Source, "/")
+
+		for _, path := range sourcePaths {
+			if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+				continue
+			}
+
+			if len(prefix) <= matchedLength {
+				continue
+			}
+
+			remainder := strings.TrimPrefix(path, prefix)
+			mappedRepository = strings.Trim(mapping.Repository+remainder, "/")
+			matchedLength = len(prefix)
+		}
+	}
+
+	return mappedRepository
+}
+
+// Location returns the path of the manifest file, which is the .images.yaml file
+// in the given directory unless the path is the path of a YAML file.
+func Location(path string) string {
+	return getManifestLocation(path)
 }
 
 func getManifestLocation(path string) string {
@@ -241,16 +533,3 @@ func getManifestLocation(path string) string {
 
 	return location
 }
-
-func getEncodedBasicAuth(username string, password string) (string, error) {
-	authConfig := Auth{
-		Username: username,
-		Password: password,
-	}
-	jsonAuth, err := json.Marshal(authConfig)
-	if err != nil {
-		return "", fmt.Errorf("marshal auth: %w", err)
-	}
-
-	return base64.URLEncoding.EncodeToString(jsonAuth), nil
-}
@@ -175,6 +175,30 @@ func TestSource_Digest(t *testing.T) {
 	}
 }
 
+func TestSource_TagAndDigest(t *testing.T) {
+	target := Target{
+		Host: "target.com",
+	}
+
+	source := Source{
+		Host:       "source.com",
+		Target:     target,
+		Repository: "repo",
+		Tag:        "v1.0.0",
+		Digest:     "sha256:123",
+	}
+
+	const expectedSource = "source.com/repo:v1.0.0@sha256:123"
+	if source.Image() != expectedSource {
+		t.Errorf("unexpected source string. expected %s, actual %s", expectedSource, source.Image())
+	}
+
+	const expectedTarget = "target.com/repo:v1.0.0"
+	if source.TargetImage() != expectedTarget {
+		t.Errorf("unexpected target string. expected %s, actual %s", expectedTarget, source.TargetImage())
+	}
+}
+
 func TestSource_AuthFromEnvironment(t *testing.T) {
 	auth := Auth{
 		Username: "ENV_USER_KEY",
@@ -210,3 +234,312 @@ func TestSource_AuthFromEnvironment(t *testing.T) {
 		t.Errorf("expected target auth %s, actual %s", expectedAuth, actualTargetAuth)
 	}
 }
+
+func TestGetMappedRepository(t *testing.T) {
+	mappings := []Mapping{
+		{
+			Source:     "quay.io/prometheus",
+			Repository: "mirrors/prometheus",
+		},
+		{
+			Source:     "quay.io/prometheus/alertmanager",
+			Repository: "mirrors",
+		},
+		{
+			Source:     "docker.io/library",
+			Repository: "hub",
+		},
+	}
+
+	testCases := []struct {
+		source   Source
+		expected string
+	}{
+		{
+			Source{Host: "quay.io", Repository: "prometheus/prometheus"},
+			"mirrors/prometheus/prometheus",
+		},
+		{
+			Source{Host: "quay.io", Repository: "prometheus/alertmanager"},
+			"mirrors",
+		},
+		{
+			Source{Repository: "library/busybox"},
+			"hub/busybox",
+		},
+		{
+			Source{Host: "quay.io", Repository: "prometheus-operator/prometheus-operator"},
+			"",
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := getMappedRepository(mappings, testCase.source)
+		if actual != testCase.expected {
+			t.Errorf("expected mapped repository %s, actual %s", testCase.expected, actual)
+		}
+	}
+}
+
+func TestParseManifest_TargetOverrides(t *testing.T) {
+	const manifestContents = `
+target:
+  host: mycompany.com
+  repository: mirrors
+  auth:
+    helper: ecr
+mappings:
+- source: quay.io
+  repository: quay
+sources:
+- repository: prometheus/prometheus
+  host: quay.io
+  tag: v2.22.0
+- repository: nvidia/cuda
+  tag: 11.0-base
+  target:
+    host: ml.mycompany.com
+    auth:
+      username: ML_USER
+      password: ML_PASSWORD
+- repository: tensorflow/tensorflow
+  tag: 2.3.0
+  target:
+    repository: large
+`
+
+	imageManifest, err := parseManifest([]byte(manifestContents))
+	if err != nil {
+		t.Fatal("parse manifest:", err)
+	}
+
+	expectedTargets := []string{
+		"mycompany.com/quay/prometheus/prometheus:v2.22.0",
+		"ml.mycompany.com/nvidia/cuda:11.0-base",
+		"mycompany.com/large/tensorflow/tensorflow:2.3.0",
+	}
+
+	for s, source := range imageManifest.Sources {
+		if source.TargetImage() != expectedTargets[s] {
+			t.Errorf("expected target %s, actual %s", expectedTargets[s], source.TargetImage())
+		}
+	}
+
+	if imageManifest.Sources[1].Target.Auth.Username != "ML_USER" {
+		t.Errorf("expected auth of the overridden host, actual %v", imageManifest.Sources[1].Target.Auth)
+	}
+
+	if imageManifest.Sources[2].Target.Auth.Helper != "ecr" {
+		t.Errorf("expected auth of the manifest target, actual %v", imageManifest.Sources[2].Target.Auth)
+	}
+}
+
+func TestParseManifest_TargetTag(t *testing.T) {
+	const manifestContents = `
+target:
+  host: mycompany.com
+  repository: mirror
+  tagTemplate: "{{.Tag}}-mirrored"
+sources:
+- repository: busybox
+  tag: 1.32.0
+- repository: nginx
+  tag: 1.25
+  targetTag: 20201101-1.25
+- repository: alpine
+  digest: sha256:123
+`
+
+	imageManifest, err := parseManifest([]byte(manifestContents))
+	if err != nil {
+		t.Fatal("parse manifest:", err)
+	}
+
+	expectedTargets := []string{
+		"mycompany.com/mirror/busybox:1.32.0-mirrored",
+		"mycompany.com/mirror/nginx:20201101-1.25",
+		"mycompany.com/mirror/alpine:123-mirrored",
+	}
+
+	for s, source := range imageManifest.Sources {
+		if source.TargetImage() != expectedTargets[s] {
+			t.Errorf("expected target %s, actual %s", expectedTargets[s], source.TargetImage())
+		}
+	}
+
+	const invalidManifestContents = `
+target:
+  host: mycompany.com
+  tagTemplate: "{{.Tag}}/mirrored"
+sources:
+- repository: busybox
+  tag: 1.32.0
+`
+
+	if _, err := parseManifest([]byte(invalidManifestContents)); err == nil {
+		t.Error("expected an error for a tag template that results in an invalid tag")
+	}
+}
+
+func TestParseManifest_Flatten(t *testing.T) {
+	testCases := []struct {
+		flatten  string
+		expected []string
+	}{
+		{
+			flatten: "strategy: replace",
+			expected: []string{
+				"mycompany.com/mirror/coreos-prometheus-operator:v0.40.0",
+				"mycompany.com/mirror/busybox:1.32.0",
+			},
+		},
+		{
+			flatten: "strategy: last",
+			expected: []string{
+				"mycompany.com/mirror/prometheus-operator:v0.40.0",
+				"mycompany.com/mirror/busybox:1.32.0",
+			},
+		},
+		{
+			flatten: "strategy: hash",
+			expected: []string{
+				"mycompany.com/mirror/037036f4-prometheus-operator:v0.40.0",
+				"mycompany.com/mirror/9d1e243a-busybox:1.32.0",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		manifestContents := `
+target:
+  host: mycompany.com
+  repository: mirror
+  flatten:
+    ` + testCase.flatten + `
+sources:
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tag: v0.40.0
+- repository: busybox
+  tag: 1.32.0
+`
+
+		imageManifest, err := parseManifest([]byte(manifestContents))
+		if err != nil {
+			t.Fatal("parse manifest:", err)
+		}
+
+		for s, source := range imageManifest.Sources {
+			if source.TargetImage() != testCase.expected[s] {
+				t.Errorf("expected target %s with %s, actual %s", testCase.expected[s], testCase.flatten, source.TargetImage())
+			}
+		}
+	}
+
+	const collidingManifestContents = `
+target:
+  host: mycompany.com
+  flatten:
+    strategy: last
+sources:
+- repository: coreos/etcd
+  host: quay.io
+  tag: v3.4.0
+- repository: etcd-development/etcd
+  host: gcr.io
+  tag: v3.4.0
+`
+
+	if _, err := parseManifest([]byte(collidingManifestContents)); err == nil {
+		t.Error("expected an error when repositories are flattened into the same repository")
+	}
+}
+
+func TestParseManifest_Trust(t *testing.T) {
+	manifestContents := `
+target:
+  host: mycompany.com
+trust:
+- name: coreos
+  images:
+  - quay.io/coreos/*
+  identity: https://github.com/coreos/prometheus-operator/.github/workflows/release.yaml@refs/heads/main
+  issuer: https://token.actions.githubusercontent.com
+- name: internal
+  type: notation
+  images:
+  - mycompany.com/*
+- name: default
+  key: cosign.pub
+sources:
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tag: v0.40.0
+- repository: myteam/application
+  host: mycompany.com
+  tag: 1.0.0
+- repository: busybox
+  tag: 1.32.0
+- repository: coreos/etcd
+  host: quay.io
+  tag: v3.4.0
+  trust: default
+`
+
+	manifest, err := parseManifest([]byte(manifestContents))
+	if err != nil {
+		t.Fatal("parse manifest:", err)
+	}
+
+	expected := []string{"coreos", "internal", "default", "default"}
+	for s, source := range manifest.Sources {
+		policy, found, err := FindTrustPolicy(manifest.Trust, source)
+		if err != nil {
+			t.Fatal("find trust policy:", err)
+		}
+
+		if !found || policy.Name != expected[s] {
+			t.Errorf("expected %s to use trust policy %s, actual %s", source.Image(), expected[s], policy.Name)
+		}
+	}
+
+	invalidManifests := []string{
+		"trust:\n- name: default\n",
+		"trust:\n- name: default\n  type: notation\n  key: cosign.pub\n",
+		"trust:\n- name: default\n  key: cosign.pub\n- name: default\n  key: other.pub\n",
+		"trust:\n- name: default\n  key: cosign.pub\nsources:\n- repository: busybox\n  trust: missing\n",
+	}
+
+	for _, invalidManifest := range invalidManifests {
+		if _, err := parseManifest([]byte(invalidManifest)); err == nil {
+			t.Errorf("expected error parsing manifest\n%s", invalidManifest)
+		}
+	}
+}
+
+func TestParseManifest_SourceLines(t *testing.T) {
+	contents := []byte(`target:
+  host: mycompany.com
+  repository: busybox
+sources:
+- repository: busybox
+  tag: 1.32.0
+- host: quay.io
+  repository: "coreos/prometheus-operator"
+  tag: v0.40.0
+- repository: busybox
+  tag: 1.33.0
+`)
+
+	imageManifest, err := parseManifest(contents)
+	if err != nil {
+		t.Fatal("parse manifest:", err)
+	}
+
+	expected := []int{5, 8, 10}
+	for s, source := range imageManifest.Sources {
+		if source.Line() != expected[s] {
+			t.Errorf("expected source %s at line %v, actual %v", source.Image(), expected[s], source.Line())
+		}
+	}
+}
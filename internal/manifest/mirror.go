@@ -0,0 +1,93 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithSourceMirrors returns the sources with the registry mirrors (e.g. pull-through caches) that their images are
+// pulled through. The mirrors are keyed by the host of the source registry, and each mirror is the host of the mirror
+// followed by an optional path that the repositories are nested in (e.g. docker.io=mirror.internal/docker.io).
+//
+// The sources keep referring to their source registry, only the image that is pulled changes.
+func WithSourceMirrors(sources []Source, mirrors map[string]string) ([]Source, error) {
+	normalizedMirrors := make(map[string]string)
+	for host, mirror := range mirrors {
+		mirror = strings.Trim(mirror, "/")
+		if mirror == "" {
+			return nil, fmt.Errorf("mirror of %s must not be empty", host)
+		}
+
+		normalizedMirrors[getCanonicalHost(host)] = mirror
+	}
+
+	mirroredSources := make([]Source, len(sources))
+	for s, source := range sources {
+		source.pullMirror = normalizedMirrors[getCanonicalHost(source.Host)]
+		mirroredSources[s] = source
+	}
+
+	return mirroredSources, nil
+}
+
+// PullImage returns the image that is pulled for the source, which is the image of the source unless
+// its registry is pulled through a mirror, or the source is pulled from a fallback registry. The official
+// images of Docker Hub are pulled from the library namespace of the mirror, in the same way as they are
+// from Docker Hub.
+func (s Source) PullImage() string {
+	if s.fallback != nil {
+		return s.fallbackImage()
+	}
+
+	if s.pullMirror == "" {
+		return s.Image()
+	}
+
+	repository := s.Repository
+	if getCanonicalHost(s.Host) == "docker.io" && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	mirrorHost, mirrorPath := splitMirror(s.pullMirror)
+
+	mirrored := s
+	mirrored.Host = mirrorHost
+	mirrored.Repository = strings.TrimLeft(mirrorPath+"/"+repository, "/")
+
+	return mirrored.Image()
+}
+
+// pullHost returns the host of the registry that the image of the source is pulled from.
+func (s Source) pullHost() string {
+	if s.fallback != nil && s.fallback.Host != "" {
+		return s.fallback.Host
+	}
+
+	if s.pullMirror == "" {
+		return s.Host
+	}
+
+	mirrorHost, _ := splitMirror(s.pullMirror)
+	return mirrorHost
+}
+
+// splitMirror returns the host of the mirror and the path that the repositories are nested in.
+func splitMirror(mirror string) (string, string) {
+	tokens := strings.SplitN(mirror, "/", 2)
+	if len(tokens) == 1 {
+		return tokens[0], ""
+	}
+
+	return tokens[0], tokens[1]
+}
+
+// getCanonicalHost returns the host with Docker Hub, which is referred to by sources without
+// a host or by docker.io, index.docker.io or registry-1.docker.io, always as docker.io.
+func getCanonicalHost(host string) string {
+	host = strings.ToLower(host)
+	if host == "" || host == "index.docker.io" || host == "registry-1.docker.io" {
+		return "docker.io"
+	}
+
+	return host
+}
@@ -0,0 +1,43 @@
+package manifest
+
+import "testing"
+
+func TestWithSourceMirrors(t *testing.T) {
+	sources := []Source{
+		{Repository: "busybox", Tag: "1.32.0"},
+		{Host: "docker.io", Repository: "bitnami/redis", Tag: "6.0.9"},
+		{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		{Host: "gcr.io", Repository: "distroless/static", Tag: "nonroot"},
+	}
+
+	mirrors := map[string]string{
+		"index.docker.io": "mirror.internal/docker.io/",
+		"quay.io":         "quay-mirror.internal:5000",
+	}
+
+	mirroredSources, err := WithSourceMirrors(sources, mirrors)
+	if err != nil {
+		t.Fatal("with source mirrors:", err)
+	}
+
+	expected := []string{
+		"mirror.internal/docker.io/library/busybox:1.32.0",
+		"mirror.internal/docker.io/bitnami/redis:6.0.9",
+		"quay-mirror.internal:5000/coreos/prometheus-operator:v0.40.0",
+		"gcr.io/distroless/static:nonroot",
+	}
+
+	for s, source := range mirroredSources {
+		if source.PullImage() != expected[s] {
+			t.Errorf("expected %s to be pulled from %s, actual %s", source.Image(), expected[s], source.PullImage())
+		}
+
+		if source.Image() != sources[s].Image() {
+			t.Errorf("expected the image of the source to remain %s, actual %s", sources[s].Image(), source.Image())
+		}
+	}
+
+	if _, err := WithSourceMirrors(sources, map[string]string{"docker.io": ""}); err == nil {
+		t.Error("expected error for empty mirror")
+	}
+}
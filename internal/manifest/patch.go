@@ -0,0 +1,94 @@
+package manifest
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// patchContext is the number of unchanged lines included around each change of a patch.
+const patchContext = 3
+
+// GetPatch returns a unified diff (that can be applied with git apply or patch -p1) of the changes from the
+// contents of the file at the path to the updated contents. Only changes that replace lines are supported,
+// so the contents and the updated contents must have the same number of lines.
+func GetPatch(path string, contents []byte, updatedContents []byte) (string, error) {
+	lines := strings.SplitAfter(string(contents), "\n")
+	updatedLines := strings.SplitAfter(string(updatedContents), "\n")
+	if len(lines) != len(updatedLines) {
+		return "", fmt.Errorf("number of lines changed from %v to %v", len(lines), len(updatedLines))
+	}
+
+	// When the file ends with a newline, the last element is empty.
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		updatedLines = updatedLines[:len(updatedLines)-1]
+	}
+
+	var changes []int
+	for i := range lines {
+		if lines[i] != updatedLines[i] {
+			changes = append(changes, i)
+		}
+	}
+
+	if len(changes) == 0 {
+		return "", nil
+	}
+
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	var patch strings.Builder
+	patch.WriteString("--- a/" + path + "\n")
+	patch.WriteString("+++ b/" + path + "\n")
+
+	for start := 0; start < len(changes); {
+
+		// Changes that are close enough for their context to overlap are part of the same hunk.
+		end := start
+		for end+1 < len(changes) && changes[end+1]-changes[end] <= 2*patchContext {
+			end++
+		}
+
+		first := max(changes[start]-patchContext, 0)
+		last := min(changes[end]+patchContext, len(lines)-1)
+
+		fmt.Fprintf(&patch, "@@ -%d,%d +%d,%d @@\n", first+1, last-first+1, first+1, last-first+1)
+		for i := first; i <= last; i++ {
+			if lines[i] == updatedLines[i] {
+				writePatchLine(&patch, " ", lines[i])
+				continue
+			}
+
+			writePatchLine(&patch, "-", lines[i])
+			writePatchLine(&patch, "+", updatedLines[i])
+		}
+
+		start = end + 1
+	}
+
+	return patch.String(), nil
+}
+
+func writePatchLine(patch *strings.Builder, prefix string, line string) {
+	patch.WriteString(prefix + line)
+	if !strings.HasSuffix(line, "\n") {
+		patch.WriteString("\n\\ No newline at end of file\n")
+	}
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
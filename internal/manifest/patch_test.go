@@ -0,0 +1,50 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetPatch(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, "line")
+	}
+
+	contents := strings.Join(lines, "\n")
+
+	lines[0] = "first"
+	lines[11] = "last"
+	updatedContents := strings.Join(lines, "\n")
+
+	patch, err := GetPatch("resources/app.yaml", []byte(contents), []byte(updatedContents))
+	if err != nil {
+		t.Fatal("get patch:", err)
+	}
+
+	expected := `--- a/resources/app.yaml
++++ b/resources/app.yaml
+@@ -1,4 +1,4 @@
+-line
++first
+ line
+ line
+ line
+@@ -9,4 +9,4 @@
+ line
+ line
+ line
+-line
+\ No newline at end of file
++last
+\ No newline at end of file
+`
+
+	if patch != expected {
+		t.Errorf("unexpected patch, expected:\n%s\nactual:\n%s", expected, patch)
+	}
+
+	if _, err := GetPatch("app.yaml", []byte("a\nb\n"), []byte("a\n")); err == nil {
+		t.Error("expected error when the number of lines changes")
+	}
+}
@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlatformImage is the image of a single platform of a source, for projects that publish a separate
+// tag for each platform (e.g. 1.2.0-amd64 and 1.2.0-arm64) rather than a multi-arch image.
+type PlatformImage struct {
+
+	// Platform is the platform of the image in the form of os/arch[/variant] (e.g. linux/arm64/v8).
+	Platform string `yaml:"platform"`
+
+	Tag    string `yaml:"tag,omitempty"`
+	Digest string `yaml:"digest,omitempty"`
+}
+
+// IsAssembled returns true when the source is assembled into a multi-arch image at the target from the
+// images of its platforms. The tag of an assembled source is the tag of the multi-arch image at the target,
+// which does not need to exist at the source.
+func (s Source) IsAssembled() bool {
+	return len(s.PlatformImages) > 0
+}
+
+// PlatformSource returns the source that pulls the image of the platform from the repository of the source.
+// It is pulled through the same registry mirror or fallback as the source, and has the same target repository.
+func (s Source) PlatformSource(platformImage PlatformImage) Source {
+	platformSource := s
+	platformSource.Tag = platformImage.Tag
+	platformSource.Digest = platformImage.Digest
+	platformSource.PlatformImages = nil
+
+	return platformSource
+}
+
+// validatePlatformImages returns an error when the images of the platforms of the source cannot be
+// assembled into a multi-arch image with the tag of the source.
+func validatePlatformImages(source Source) error {
+	if !source.IsAssembled() {
+		return nil
+	}
+
+	if source.IsArtifact() {
+		return fmt.Errorf("source %s is an artifact and cannot have platform images", source.Image())
+	}
+
+	if source.Tags != "" || source.TagPattern != "" {
+		return fmt.Errorf("source %s selects more than one tag and cannot have platform images", source.Image())
+	}
+
+	if source.Tag == "" || source.Digest != "" {
+		return fmt.Errorf("source %s must have a tag and no digest to assemble its platform images with", source.Image())
+	}
+
+	platforms := make(map[string]bool)
+	for p, platformImage := range source.PlatformImages {
+		platformTokens := strings.Split(platformImage.Platform, "/")
+		if len(platformTokens) < 2 || len(platformTokens) > 3 {
+			return fmt.Errorf("source %s: platform image %v has an invalid platform %q, expected os/arch[/variant]", source.Image(), p+1, platformImage.Platform)
+		}
+
+		if platformImage.Tag == "" && platformImage.Digest == "" {
+			return fmt.Errorf("source %s: platform image %v must have a tag or digest", source.Image(), p+1)
+		}
+
+		if platforms[platformImage.Platform] {
+			return fmt.Errorf("source %s has more than one image of platform %s", source.Image(), platformImage.Platform)
+		}
+		platforms[platformImage.Platform] = true
+	}
+
+	return nil
+}
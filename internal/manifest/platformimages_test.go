@@ -0,0 +1,92 @@
+package manifest
+
+import "testing"
+
+func TestPlatformSource(t *testing.T) {
+	const contents = `target:
+  host: mycompany.com
+  repository: mirrors
+sources:
+- repository: org/app
+  host: ghcr.io
+  tag: v1.0.0
+  platformImages:
+  - platform: linux/amd64
+    tag: v1.0.0-amd64
+  - platform: linux/arm64/v8
+    digest: sha256:8f9d1d8e0e1c1b0d36a8e5d1a6c2d4e4ee8bfc0f1b8a8e8e9f2c1a3b4d5e6f70
+`
+
+	imageManifest, err := Parse([]byte(contents))
+	if err != nil {
+		t.Fatal("parse manifest:", err)
+	}
+
+	source := imageManifest.Sources[0]
+	if !source.IsAssembled() {
+		t.Fatal("expected source to be assembled")
+	}
+
+	mirroredSources, err := WithSourceMirrors([]Source{source}, map[string]string{"ghcr.io": "mirror.internal/ghcr.io"})
+	if err != nil {
+		t.Fatal("with source mirrors:", err)
+	}
+
+	expected := []string{
+		"mirror.internal/ghcr.io/org/app:v1.0.0-amd64",
+		"mirror.internal/ghcr.io/org/app@sha256:8f9d1d8e0e1c1b0d36a8e5d1a6c2d4e4ee8bfc0f1b8a8e8e9f2c1a3b4d5e6f70",
+	}
+
+	for p, platformImage := range source.PlatformImages {
+		platformSource := mirroredSources[0].PlatformSource(platformImage)
+		if platformSource.PullImage() != expected[p] {
+			t.Errorf("expected platform image %v to be pulled from %s, actual %s", p+1, expected[p], platformSource.PullImage())
+		}
+
+		if platformSource.TargetRepository() != source.TargetRepository() {
+			t.Errorf("expected platform image %v to have target repository %s, actual %s", p+1, source.TargetRepository(), platformSource.TargetRepository())
+		}
+
+		if platformSource.IsAssembled() {
+			t.Errorf("expected platform image %v to not be assembled", p+1)
+		}
+	}
+
+	if source.TargetImage() != "mycompany.com/mirrors/org/app:v1.0.0" {
+		t.Errorf("expected the assembled image to be pushed as mycompany.com/mirrors/org/app:v1.0.0, actual %s", source.TargetImage())
+	}
+}
+
+func TestValidatePlatformImages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+	}{
+		{
+			name:     "digest",
+			contents: "sources:\n- repository: org/app\n  digest: sha256:8f9d1d8e0e1c1b0d36a8e5d1a6c2d4e4ee8bfc0f1b8a8e8e9f2c1a3b4d5e6f70\n  platformImages:\n  - platform: linux/amd64\n    tag: v1-amd64\n",
+		},
+		{
+			name:     "invalid platform",
+			contents: "sources:\n- repository: org/app\n  tag: v1\n  platformImages:\n  - platform: amd64\n    tag: v1-amd64\n",
+		},
+		{
+			name:     "no tag or digest",
+			contents: "sources:\n- repository: org/app\n  tag: v1\n  platformImages:\n  - platform: linux/amd64\n",
+		},
+		{
+			name:     "duplicate platform",
+			contents: "sources:\n- repository: org/app\n  tag: v1\n  platformImages:\n  - platform: linux/amd64\n    tag: v1-amd64\n  - platform: linux/amd64\n    tag: v1-x86\n",
+		},
+		{
+			name:     "artifact",
+			contents: "sources:\n- repository: org/chart\n  tag: v1\n  type: chart\n  platformImages:\n  - platform: linux/amd64\n    tag: v1-amd64\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		if _, err := Parse([]byte(testCase.contents)); err == nil {
+			t.Errorf("expected error for platform images with %s", testCase.name)
+		}
+	}
+}
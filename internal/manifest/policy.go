@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+// Policy is a set of rules that the images found in resources must follow.
+type Policy struct {
+
+	// ForbidLatest forbids images that use the latest tag, including images without a tag.
+	ForbidLatest bool `yaml:"forbidLatest,omitempty"`
+
+	// RequireDigest requires every image to be referenced by its digest.
+	RequireDigest bool `yaml:"requireDigest,omitempty"`
+
+	// AllowedRegistries are the registries, optionally including a repository, that
+	// images may be sourced from (e.g. quay.io or mycompany.com/myteam).
+	// Images without a host are sourced from docker.io. When empty, all registries are allowed.
+	AllowedRegistries []string `yaml:"allowedRegistries,omitempty"`
+
+	// TagPattern is a regular expression that the entire tag of each image must match (e.g. v?\d+\.\d+\.\d+).
+	// Images that are only referenced by their digest are not checked.
+	TagPattern string `yaml:"tagPattern,omitempty"`
+
+	// MaxImageSize is the maximum compressed size of an image (e.g. 5GiB). It is enforced by the push and check
+	// commands, which query the size of each image from its registry, and not by the lint command.
+	MaxImageSize string `yaml:"maxImageSize,omitempty"`
+}
+
+// Violation is a rule of the policy that an image does not follow.
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// Check returns the rules of the policy that the image does not follow.
+func (p Policy) Check(image images.Image) ([]Violation, error) {
+	var violations []Violation
+	if p.ForbidLatest && image.Tag == "latest" {
+		violations = append(violations, Violation{
+			Rule:    "forbid-latest",
+			Message: "image uses the latest tag",
+		})
+	}
+
+	if p.RequireDigest && image.Digest == "" {
+		violations = append(violations, Violation{
+			Rule:    "require-digest",
+			Message: "image is not referenced by its digest",
+		})
+	}
+
+	if len(p.AllowedRegistries) > 0 && !isAllowedRegistry(image, p.AllowedRegistries) {
+		violations = append(violations, Violation{
+			Rule:    "allowed-registries",
+			Message: fmt.Sprintf("image is not from an allowed registry (%s)", strings.Join(p.AllowedRegistries, ", ")),
+		})
+	}
+
+	if p.TagPattern != "" && image.Tag != "" {
+		tagPattern, err := regexp.Compile("^(?:" + p.TagPattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("compile tag pattern: %w", err)
+		}
+
+		if !tagPattern.MatchString(image.Tag) {
+			violations = append(violations, Violation{
+				Rule:    "tag-pattern",
+				Message: fmt.Sprintf("tag %s does not match %s", image.Tag, p.TagPattern),
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+func isAllowedRegistry(image images.Image, allowedRegistries []string) bool {
+	host := image.Host
+	if host == "" {
+		host = "docker.io"
+	}
+
+	path := host + "/" + image.Repository
+	for _, allowedRegistry := range allowedRegistries {
+		prefix := strings.TrimSuffix(allowedRegistry, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,48 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	policy := Policy{
+		ForbidLatest:      true,
+		RequireDigest:     true,
+		AllowedRegistries: []string{"quay.io/coreos", "docker.io"},
+		TagPattern:        `v\d+\.\d+\.\d+`,
+	}
+
+	testCases := []struct {
+		reference string
+		expected  []string
+	}{
+		{"quay.io/coreos/prometheus-operator:v0.40.0@sha256:0000000000000000000000000000000000000000000000000000000000000000", nil},
+		{"busybox", []string{"forbid-latest", "require-digest", "tag-pattern"}},
+		{"quay.io/prometheus/prometheus:v2.22.0", []string{"require-digest", "allowed-registries"}},
+		{"quay.io/coreos/prometheus-operator:0.40", []string{"require-digest", "tag-pattern"}},
+	}
+
+	for _, testCase := range testCases {
+		image, err := images.ParseReference(testCase.reference)
+		if err != nil {
+			t.Fatal("parse reference:", err)
+		}
+
+		violations, err := policy.Check(image)
+		if err != nil {
+			t.Fatal("check:", err)
+		}
+
+		var actual []string
+		for _, violation := range violations {
+			actual = append(actual, violation.Rule)
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected violations %v for %s, actual %v", testCase.expected, testCase.reference, actual)
+		}
+	}
+}
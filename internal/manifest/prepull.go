@@ -0,0 +1,180 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PrepullOptions are the settings of the Kubernetes resources that pre-pull images onto the nodes of a cluster.
+type PrepullOptions struct {
+	Name      string
+	Namespace string
+
+	// PullSecrets are the names of the image pull secrets that the images are pulled with.
+	PullSecrets []string
+
+	// HelperImage is the image of the static busybox binary that the DaemonSet copies into each pre-pulled image,
+	// so that the containers of images without a shell (e.g. distroless images) can also run and exit.
+	HelperImage string
+
+	// PauseImage is the image of the container that keeps the pods of the DaemonSet running once the images are pulled.
+	PauseImage string
+}
+
+type kubernetesMetadata struct {
+	Name      string            `yaml:"name,omitempty"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type localObjectReference struct {
+	Name string `yaml:"name"`
+}
+
+type prepullDaemonSet struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubernetesMetadata `yaml:"metadata"`
+	Spec       struct {
+		Selector struct {
+			MatchLabels map[string]string `yaml:"matchLabels"`
+		} `yaml:"selector"`
+		Template struct {
+			Metadata kubernetesMetadata `yaml:"metadata"`
+			Spec     prepullPodSpec     `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+type prepullPodSpec struct {
+	ImagePullSecrets []localObjectReference `yaml:"imagePullSecrets,omitempty"`
+	InitContainers   []prepullContainer     `yaml:"initContainers"`
+	Containers       []prepullContainer     `yaml:"containers"`
+	Tolerations      []prepullToleration    `yaml:"tolerations"`
+	Volumes          []prepullVolume        `yaml:"volumes"`
+}
+
+type prepullToleration struct {
+	Operator string `yaml:"operator"`
+}
+
+type prepullVolume struct {
+	Name     string   `yaml:"name"`
+	EmptyDir struct{} `yaml:"emptyDir"`
+}
+
+type prepullVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+}
+
+type prepullContainer struct {
+	Name            string               `yaml:"name"`
+	Image           string               `yaml:"image"`
+	ImagePullPolicy string               `yaml:"imagePullPolicy"`
+	Command         []string             `yaml:"command,omitempty"`
+	VolumeMounts    []prepullVolumeMount `yaml:"volumeMounts,omitempty"`
+	Resources       struct {
+		Requests map[string]string `yaml:"requests"`
+	} `yaml:"resources"`
+}
+
+// prepullPath is the path of the volume that the busybox binary is copied into.
+const prepullPath = "/prepull"
+
+// ToPrepullDaemonSet returns a DaemonSet that pulls the images onto every node of the cluster. Each image
+// is pulled by an init container that runs the busybox binary copied from the helper image and exits,
+// after which a pause container keeps the pod running without using any resources.
+func ToPrepullDaemonSet(images []string, options PrepullOptions) ([]byte, error) {
+	labels := map[string]string{"app.kubernetes.io/name": options.Name}
+
+	var daemonSet prepullDaemonSet
+	daemonSet.APIVersion = "apps/v1"
+	daemonSet.Kind = "DaemonSet"
+	daemonSet.Metadata = kubernetesMetadata{Name: options.Name, Namespace: options.Namespace, Labels: labels}
+	daemonSet.Spec.Selector.MatchLabels = labels
+	daemonSet.Spec.Template.Metadata = kubernetesMetadata{Labels: labels}
+
+	podSpec := &daemonSet.Spec.Template.Spec
+	for _, secret := range options.PullSecrets {
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, localObjectReference{Name: secret})
+	}
+
+	// Images are pulled onto every node, including nodes with taints.
+	podSpec.Tolerations = []prepullToleration{{Operator: "Exists"}}
+	podSpec.Volumes = []prepullVolume{{Name: "prepull"}}
+
+	helper := newPrepullContainer("busybox", options.HelperImage, []string{"cp", "/bin/busybox", prepullPath + "/busybox"})
+	podSpec.InitContainers = append(podSpec.InitContainers, helper)
+
+	for i, image := range images {
+		container := newPrepullContainer(fmt.Sprintf("prepull-%d", i), image, []string{prepullPath + "/busybox", "true"})
+		podSpec.InitContainers = append(podSpec.InitContainers, container)
+	}
+
+	pause := newPrepullContainer("pause", options.PauseImage, nil)
+	pause.VolumeMounts = nil
+	podSpec.Containers = append(podSpec.Containers, pause)
+
+	contents, err := yaml.Marshal(daemonSet)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return contents, nil
+}
+
+func newPrepullContainer(name string, image string, command []string) prepullContainer {
+	container := prepullContainer{
+		Name:            name,
+		Image:           image,
+		ImagePullPolicy: "IfNotPresent",
+		Command:         command,
+		VolumeMounts:    []prepullVolumeMount{{Name: "prepull", MountPath: prepullPath}},
+	}
+
+	container.Resources.Requests = map[string]string{"cpu": "1m", "memory": "8Mi"}
+
+	return container
+}
+
+type imagePullJob struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   kubernetesMetadata `yaml:"metadata"`
+	Spec       struct {
+		Image            string   `yaml:"image"`
+		PullSecrets      []string `yaml:"pullSecrets,omitempty"`
+		CompletionPolicy struct {
+			Type                    string `yaml:"type"`
+			TTLSecondsAfterFinished int    `yaml:"ttlSecondsAfterFinished"`
+		} `yaml:"completionPolicy"`
+	} `yaml:"spec"`
+}
+
+// ToImagePullJobs returns an OpenKruise ImagePullJob for each of the images, which pulls the image onto every node
+// of the cluster that runs the OpenKruise daemon. The jobs are removed once they have completed for an hour.
+func ToImagePullJobs(images []string, options PrepullOptions) ([]byte, error) {
+	var documents [][]byte
+	for i, image := range images {
+		var job imagePullJob
+		job.APIVersion = "apps.kruise.io/v1alpha1"
+		job.Kind = "ImagePullJob"
+		job.Metadata = kubernetesMetadata{Name: fmt.Sprintf("%s-%d", options.Name, i), Namespace: options.Namespace}
+		job.Spec.Image = image
+		job.Spec.PullSecrets = options.PullSecrets
+		job.Spec.CompletionPolicy.Type = "Always"
+		job.Spec.CompletionPolicy.TTLSecondsAfterFinished = 3600
+
+		contents, err := yaml.Marshal(job)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s: %w", image, err)
+		}
+
+		documents = append(documents, contents)
+	}
+
+	return bytes.Join(documents, []byte("---\n")), nil
+}
@@ -0,0 +1,76 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToPrepullDaemonSet(t *testing.T) {
+	images := []string{"mycompany.com/myteam/busybox:1.32.0", "mycompany.com/myteam/distroless:latest"}
+	options := PrepullOptions{
+		Name:        "sinker-prepull",
+		Namespace:   "kube-system",
+		PullSecrets: []string{"regcred"},
+		HelperImage: "busybox:1.36",
+		PauseImage:  "registry.k8s.io/pause:3.9",
+	}
+
+	actual, err := ToPrepullDaemonSet(images, options)
+	if err != nil {
+		t.Fatal("to prepull daemonset:", err)
+	}
+
+	expected := []string{
+		"kind: DaemonSet",
+		"  namespace: kube-system",
+		"      - name: regcred",
+		"      - name: busybox\n        image: busybox:1.36",
+		"        - cp\n        - /bin/busybox\n        - /prepull/busybox",
+		"      - name: prepull-0\n        image: mycompany.com/myteam/busybox:1.32.0",
+		"      - name: prepull-1\n        image: mycompany.com/myteam/distroless:latest",
+		"        - /prepull/busybox\n        - \"true\"",
+		"      - name: pause\n        image: registry.k8s.io/pause:3.9\n        imagePullPolicy: IfNotPresent\n        resources:",
+		"      - operator: Exists",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(string(actual), e) {
+			t.Errorf("expected daemonset to contain\n%s\nactual\n%s", e, actual)
+		}
+	}
+}
+
+func TestToImagePullJobs(t *testing.T) {
+	images := []string{"mycompany.com/myteam/busybox:1.32.0", "mycompany.com/myteam/distroless:latest"}
+	options := PrepullOptions{Name: "sinker-prepull"}
+
+	actual, err := ToImagePullJobs(images, options)
+	if err != nil {
+		t.Fatal("to image pull jobs:", err)
+	}
+
+	expected := `apiVersion: apps.kruise.io/v1alpha1
+kind: ImagePullJob
+metadata:
+  name: sinker-prepull-0
+spec:
+  image: mycompany.com/myteam/busybox:1.32.0
+  completionPolicy:
+    type: Always
+    ttlSecondsAfterFinished: 3600
+---
+apiVersion: apps.kruise.io/v1alpha1
+kind: ImagePullJob
+metadata:
+  name: sinker-prepull-1
+spec:
+  image: mycompany.com/myteam/distroless:latest
+  completionPolicy:
+    type: Always
+    ttlSecondsAfterFinished: 3600
+`
+
+	if string(actual) != expected {
+		t.Errorf("expected image pull jobs\n%s\nactual\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/plexsystems/sinker/pkg/images"
+)
+
+// imageTokenPattern matches the values in a YAML file that could be an image reference,
+// such as the value of an image field or the value of a container argument.
+var imageTokenPattern = regexp.MustCompile(`[^\s"'=,\[\]{}]+`)
+
+// RewriteImages replaces the images found in the YAML files at the specified path that are
+// sources in the manifest with their target images. When an output path is given, the rewritten
+// files are written to the output path instead of being modified in place.
+//
+// Returns the paths of the files that were written.
+func RewriteImages(path string, outputPath string, manifest Manifest) ([]string, error) {
+	files, err := images.FindFiles(path)
+	if err != nil {
+		return nil, fmt.Errorf("find files: %w", err)
+	}
+
+	var rewrittenFiles []string
+	for _, file := range files {
+		fileInfo, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("stat file: %w", err)
+		}
+
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+
+		rewrittenContents := rewriteImages(contents, manifest)
+		if outputPath == "" && string(rewrittenContents) == string(contents) {
+			continue
+		}
+
+		rewrittenFile := file
+		if outputPath != "" {
+			relativePath, err := filepath.Rel(path, file)
+			if err != nil {
+				return nil, fmt.Errorf("relative path: %w", err)
+			}
+
+			// When the path is a single file, the relative path is the current directory.
+			if relativePath == "." {
+				relativePath = filepath.Base(file)
+			}
+
+			rewrittenFile = filepath.Join(outputPath, relativePath)
+			if err := os.MkdirAll(filepath.Dir(rewrittenFile), os.ModePerm); err != nil {
+				return nil, fmt.Errorf("create output directory: %w", err)
+			}
+		}
+
+		if err := ioutil.WriteFile(rewrittenFile, rewrittenContents, fileInfo.Mode()); err != nil {
+			return nil, fmt.Errorf("write file: %w", err)
+		}
+
+		rewrittenFiles = append(rewrittenFiles, rewrittenFile)
+	}
+
+	return rewrittenFiles, nil
+}
+
+// rewriteImages replaces every value in the contents that refers to a source in the
+// manifest with its target image. Only the matched values are changed so that the
+// formatting and comments of the file are preserved.
+func rewriteImages(contents []byte, manifest Manifest) []byte {
+	return imageTokenPattern.ReplaceAllFunc(contents, func(token []byte) []byte {
+		if !images.IsReference(string(token)) {
+			return token
+		}
+
+		source, ok := manifest.FindSource(string(token))
+		if !ok {
+			return token
+		}
+
+		return []byte(source.TargetImage())
+	})
+}
+
+// ReplaceImages replaces every value in the contents that is one of the image references
+// in the replacements with its replacement, preserving the formatting and comments of the file.
+func ReplaceImages(contents []byte, replacements map[string]string) []byte {
+	return imageTokenPattern.ReplaceAllFunc(contents, func(token []byte) []byte {
+		if replacement, ok := replacements[string(token)]; ok {
+			return []byte(replacement)
+		}
+
+		return token
+	})
+}
+
+// FindSource returns the source in the manifest that refers to the given image reference.
+func (m Manifest) FindSource(reference string) (Source, bool) {
+	image, err := images.ParseReference(reference)
+	if err != nil {
+		return Source{}, false
+	}
+
+	sources := marshalImages([]images.Image{image}, m.Target)
+
+	for _, source := range m.Sources {
+		if source.Host != sources[0].Host || source.Repository != sources[0].Repository {
+			continue
+		}
+
+		if source.Tag != sources[0].Tag || source.Digest != sources[0].Digest {
+			continue
+		}
+
+		return source, true
+	}
+
+	return Source{}, false
+}
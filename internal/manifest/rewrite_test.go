@@ -0,0 +1,56 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func TestRewriteImages(t *testing.T) {
+	contents := []byte(`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  # The operator
+  - image: "quay.io/coreos/prometheus-operator:v0.40.0"
+    args:
+    - --config-reloader-image=jimmidyson/configmap-reload:v0.3.0
+  - image: busybox:1.32.0
+`)
+
+	imageManifest := Manifest{
+		Target: Target{
+			Host:       "mycompany.com",
+			Repository: "myteam",
+		},
+		Sources: []Source{
+			{
+				Host:       "quay.io",
+				Repository: "coreos/prometheus-operator",
+				Tag:        "v0.40.0",
+			},
+			{
+				Repository: "jimmidyson/configmap-reload",
+				Tag:        "v0.3.0",
+			},
+		},
+	}
+
+	for s := range imageManifest.Sources {
+		imageManifest.Sources[s].Target = imageManifest.Target
+	}
+
+	expected := `apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  # The operator
+  - image: "mycompany.com/myteam/coreos/prometheus-operator:v0.40.0"
+    args:
+    - --config-reloader-image=mycompany.com/myteam/jimmidyson/configmap-reload:v0.3.0
+  - image: busybox:1.32.0
+`
+
+	actual := string(rewriteImages(contents, imageManifest))
+	if actual != expected {
+		t.Errorf("expected rewritten contents\n%s\nactual\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,433 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SchemaVersion is the version of the schema of the image manifest. The version only
+// changes when a manifest that followed the previous schema no longer follows it.
+const SchemaVersion = "v1"
+
+// Schema is the JSON schema of the image manifest, which editors can use to validate and
+// complete manifests as they are written (e.g. with the yaml-language-server modeline).
+const Schema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "https://github.com/plexsystems/sinker/schema/manifest/v1.json",
+  "title": "sinker image manifest",
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "target": {"$ref": "#/definitions/target"},
+    "mappings": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["source", "repository"],
+        "properties": {
+          "source": {"type": "string", "description": "the host and repository prefix of the sources that are mapped (e.g. quay.io/prometheus)"},
+          "repository": {"type": "string", "description": "the repository at the target that the sources are mapped to (e.g. mirrors/prometheus)"}
+        }
+      }
+    },
+    "ignore": {
+      "type": "array",
+      "items": {"type": "string", "description": "a glob pattern of the source images to skip (e.g. busybox:*)"}
+    },
+    "policy": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "forbidLatest": {"type": "boolean"},
+        "requireDigest": {"type": "boolean"},
+        "allowedRegistries": {"type": "array", "items": {"type": "string"}},
+        "tagPattern": {"type": "string", "description": "a regular expression that the tag of each image must match"},
+        "maxImageSize": {"type": "string", "description": "the maximum compressed size of an image (e.g. 5GiB)"}
+      }
+    },
+    "trust": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string"},
+          "type": {"type": "string", "enum": ["cosign", "notation"]},
+          "images": {"type": "array", "items": {"type": "string"}},
+          "key": {"type": "string"},
+          "identity": {"type": "string"},
+          "issuer": {"type": "string"}
+        }
+      }
+    },
+    "sources": {
+      "type": "array",
+      "items": {"$ref": "#/definitions/source"}
+    }
+  },
+  "definitions": {
+    "host": {
+      "type": "string",
+      "pattern": "^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?(:[0-9]+)?$",
+      "description": "a registry host, optionally with a port (e.g. quay.io or localhost:5000)"
+    },
+    "auth": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "username": {"type": "string", "description": "the name of the environment variable that contains the username"},
+        "password": {"type": "string", "description": "the name of the environment variable that contains the password"},
+        "helper": {"type": "string", "enum": ["ecr", "gcr", "acr"]},
+        "provider": {"type": "string", "description": "the credential provider (vault, aws-secrets-manager or the name of a Docker credential helper)"},
+        "secret": {"type": "string", "description": "the secret of the credential provider that contains the credentials"}
+      }
+    },
+    "target": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "host": {"$ref": "#/definitions/host"},
+        "repository": {
+          "type": "string",
+          "pattern": "^[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$",
+          "description": "a lowercase repository path (e.g. mirrors)"
+        },
+        "auth": {"$ref": "#/definitions/auth"},
+        "flatten": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["strategy"],
+          "properties": {
+            "strategy": {"type": "string", "enum": ["replace", "last", "hash"]},
+            "segments": {"type": "integer", "minimum": 1}
+          }
+        },
+        "tagTemplate": {"type": "string", "description": "a Go template that creates the tag at the target (e.g. {{.Tag}}-mirrored)"}
+      }
+    },
+    "source": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "repository": {
+          "type": "string",
+          "pattern": "^[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$",
+          "description": "a lowercase repository path without the host or tag (e.g. coreos/prometheus-operator)"
+        },
+        "host": {"$ref": "#/definitions/host"},
+        "target": {"$ref": "#/definitions/target"},
+        "tag": {
+          "type": "string",
+          "pattern": "^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$",
+          "description": "a tag of up to 128 letters, digits, underscores, periods and dashes (e.g. v0.40.0)"
+        },
+        "digest": {
+          "type": "string",
+          "pattern": "^[a-z0-9]+([+._-][a-z0-9]+)*:[a-fA-F0-9]{32,}$",
+          "description": "a digest with its algorithm (e.g. sha256:...)"
+        },
+        "auth": {"$ref": "#/definitions/auth"},
+        "targetTag": {
+          "type": "string",
+          "pattern": "^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$",
+          "description": "a tag of up to 128 letters, digits, underscores, periods and dashes (e.g. v0.40.0-mirrored)"
+        },
+        "type": {"type": "string", "enum": ["image", "chart", "artifact"]},
+        "tags": {"type": "string", "description": "a version constraint of the tags to mirror (e.g. >= 1.20.0, < 1.23)"},
+        "tagPattern": {"type": "string", "description": "a regular expression of the tags to mirror"},
+        "keep": {"type": "integer", "minimum": 1},
+        "trust": {"type": "string", "description": "the name of a trust policy"},
+        "fallbacks": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "additionalProperties": false,
+            "properties": {
+              "host": {"$ref": "#/definitions/host"},
+              "repository": {
+                "type": "string",
+                "pattern": "^[a-z0-9]+([._-][a-z0-9]+)*(/[a-z0-9]+([._-][a-z0-9]+)*)*$",
+                "description": "a lowercase repository path without the host or tag (e.g. coreos/prometheus-operator)"
+              },
+              "auth": {"$ref": "#/definitions/auth"}
+            }
+          }
+        },
+        "platformImages": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "additionalProperties": false,
+            "required": ["platform"],
+            "properties": {
+              "platform": {"type": "string", "description": "the platform of the image (e.g. linux/arm64/v8)"},
+              "tag": {
+                "type": "string",
+                "pattern": "^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$",
+                "description": "the tag of the image of the platform (e.g. v0.40.0-arm64)"
+              },
+              "digest": {
+                "type": "string",
+                "pattern": "^[a-z0-9]+([+._-][a-z0-9]+)*:[a-fA-F0-9]{32,}$",
+                "description": "a digest with its algorithm (e.g. sha256:...)"
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+// SchemaError is a value of the manifest that does not follow the schema of the manifest.
+type SchemaError struct {
+
+	// Path is the path of the value in the manifest (e.g. sources[2].tag).
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return e.Path + ": " + e.Message
+}
+
+// schemaNode is the part of a JSON schema that the manifest schema uses.
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Description          string                 `json:"description"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Required             []string               `json:"required"`
+	Items                *schemaNode            `json:"items"`
+	Enum                 []string               `json:"enum"`
+	Pattern              string                 `json:"pattern"`
+	Minimum              *int                   `json:"minimum"`
+	Definitions          map[string]*schemaNode `json:"definitions"`
+}
+
+// ValidateSchema returns the values of the manifest that do not follow the schema of the manifest, such as
+// unknown fields and values that are not valid parts of a reference, sorted by their path in the manifest.
+// An error is only returned when the manifest is not valid YAML.
+func ValidateSchema(contents []byte) ([]SchemaError, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(contents, &value); err != nil {
+		return nil, fmt.Errorf("unmarshal manifest: %w", err)
+	}
+
+	var root schemaNode
+	if err := json.Unmarshal([]byte(Schema), &root); err != nil {
+		return nil, fmt.Errorf("unmarshal schema: %w", err)
+	}
+
+	validator := schemaValidator{definitions: root.Definitions}
+	validator.validate("", value, &root)
+
+	sort.SliceStable(validator.errors, func(i, j int) bool {
+		return validator.errors[i].Path < validator.errors[j].Path
+	})
+
+	return validator.errors, nil
+}
+
+type schemaValidator struct {
+	definitions map[string]*schemaNode
+	errors      []SchemaError
+}
+
+func (v *schemaValidator) addError(path string, format string, args ...interface{}) {
+	v.errors = append(v.errors, SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(path string, value interface{}, node *schemaNode) {
+	if node.Ref != "" {
+		node = v.definitions[strings.TrimPrefix(node.Ref, "#/definitions/")]
+	}
+
+	// Fields without a value (e.g. tag:) are the same as fields that are not set.
+	if value == nil {
+		return
+	}
+
+	switch node.Type {
+	case "object":
+		fields, ok := value.(map[interface{}]interface{})
+		if !ok {
+			v.addError(path, "must be a map, not %s", describeValue(value))
+			return
+		}
+
+		v.validateObject(path, fields, node)
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			v.addError(path, "must be a list, not %s", describeValue(value))
+			return
+		}
+
+		for i, item := range items {
+			v.validate(path+"["+strconv.Itoa(i)+"]", item, node.Items)
+		}
+
+	case "integer":
+		number, ok := value.(int)
+		if !ok {
+			v.addError(path, "must be a whole number, not %s", describeValue(value))
+			return
+		}
+
+		if node.Minimum != nil && number < *node.Minimum {
+			v.addError(path, "must be at least %v", *node.Minimum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.addError(path, "must be true or false, not %s", describeValue(value))
+		}
+
+	case "string":
+		v.validateString(path, value, node)
+	}
+}
+
+func (v *schemaValidator) validateObject(path string, fields map[interface{}]interface{}, node *schemaNode) {
+	for _, required := range node.Required {
+		if fields[required] == nil {
+			v.addError(path, "missing required field %q", required)
+		}
+	}
+
+	for key, value := range fields {
+		name := fmt.Sprint(key)
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		property, ok := node.Properties[name]
+		if ok {
+			v.validate(fieldPath, value, property)
+			continue
+		}
+
+		if node.AdditionalProperties != nil && !*node.AdditionalProperties {
+			message := fmt.Sprintf("unknown field %q", name)
+			if suggestion := suggestField(name, node.Properties); suggestion != "" {
+				message += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+
+			v.addError(path, "%s", message)
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(path string, value interface{}, node *schemaNode) {
+	switch value.(type) {
+	case map[interface{}]interface{}, []interface{}:
+		v.addError(path, "must be a single value, not %s", describeValue(value))
+		return
+	}
+
+	// Scalars that YAML parses as numbers or booleans (e.g. tag: 1.20) are read as strings by sinker, but the
+	// text of the value is not known once it has been parsed as a number, so only strings are matched.
+	text, ok := value.(string)
+	if !ok {
+		return
+	}
+
+	if len(node.Enum) > 0 && !containsString(node.Enum, text) {
+		v.addError(path, "must be one of %s, not %q", strings.Join(node.Enum, ", "), text)
+	}
+
+	if node.Pattern != "" && !regexp.MustCompile(node.Pattern).MatchString(text) {
+		v.addError(path, "%q is not %s", text, node.Description)
+	}
+}
+
+// suggestField returns the field of the properties that the unknown field is most likely a typo of,
+// which is the field with the smallest edit distance of at most two, or an empty string when there is none.
+func suggestField(name string, properties map[string]*schemaNode) string {
+	var suggestion string
+	bestDistance := 3
+	for property := range properties {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(property))
+		if distance < bestDistance || (distance == bestDistance && property < suggestion) {
+			suggestion = property
+			bestDistance = distance
+		}
+	}
+
+	return suggestion
+}
+
+// editDistance returns the Levenshtein distance between the strings.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+
+		previous = current
+	}
+
+	return previous[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func describeValue(value interface{}) string {
+	switch value.(type) {
+	case map[interface{}]interface{}:
+		return "a map"
+	case []interface{}:
+		return "a list"
+	case bool:
+		return "true or false"
+	case int, int64, uint64, float64:
+		return "a number"
+	default:
+		return "text"
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, currentValue := range values {
+		if currentValue == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+package manifest
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSchema_IsJSON(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(Schema), &schema); err != nil {
+		t.Fatal("unmarshal schema:", err)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	testCases := []struct {
+		name     string
+		contents string
+		expected []string
+	}{
+		{
+			name: "unknown field without a value",
+			contents: `
+target:
+  host: mycompany.com
+  repository: myteam
+  flatten:
+    strategy: last
+sources:
+- repository: coreos/prometheus-operator
+  host: quay.io
+  tag: v0.40.0
+- repository: busybox
+  digest: sha256:2a03a6059f21e150ae84b0973863609494aad70f0a80eaeb64bddd8d92465812
+  keep: 3
+- repository: nginx
+  tag: 1.20
+  digests:
+`,
+			expected: []string{
+				`sources[2]: unknown field "digests" (did you mean "digest"?)`,
+			},
+		},
+		{
+			name: "invalid",
+			contents: `
+target:
+  host: mycompany.com/myteam
+  flaten:
+    strategy: last
+sources:
+- repository: Coreos/Prometheus-Operator
+  tagg: v0.40.0
+- repository: busybox
+  tag: latest:1
+  type: helm
+  keep: 0
+- nginx
+unknown: true
+`,
+			expected: []string{
+				`unknown field "unknown"`,
+				`sources[0]: unknown field "tagg" (did you mean "tag"?)`,
+				`sources[0].repository: "Coreos/Prometheus-Operator" is not a lowercase repository path without the host or tag (e.g. coreos/prometheus-operator)`,
+				`sources[1].keep: must be at least 1`,
+				`sources[1].tag: "latest:1" is not a tag of up to 128 letters, digits, underscores, periods and dashes (e.g. v0.40.0)`,
+				`sources[1].type: must be one of image, chart, artifact, not "helm"`,
+				`sources[2]: must be a map, not text`,
+				`target: unknown field "flaten" (did you mean "flatten"?)`,
+				`target.host: "mycompany.com/myteam" is not a registry host, optionally with a port (e.g. quay.io or localhost:5000)`,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		schemaErrors, err := ValidateSchema([]byte(testCase.contents))
+		if err != nil {
+			t.Fatal("validate schema:", err)
+		}
+
+		var actual []string
+		for _, schemaError := range schemaErrors {
+			actual = append(actual, schemaError.Error())
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("%s: expected %q, actual %q", testCase.name, testCase.expected, actual)
+		}
+	}
+}
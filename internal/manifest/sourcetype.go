@@ -0,0 +1,31 @@
+package manifest
+
+// The types of sources that can be mirrored.
+const (
+	SourceTypeImage    = "image"
+	SourceTypeChart    = "chart"
+	SourceTypeArtifact = "artifact"
+)
+
+var sourceTypes = []string{SourceTypeImage, SourceTypeChart, SourceTypeArtifact}
+
+// IsArtifact returns true when the source is a Helm chart or another OCI artifact, rather than a
+// container image. Artifacts are copied between registries as they are, so steps that only apply
+// to container images (e.g. selecting platforms or scanning for vulnerabilities) are skipped.
+func (s Source) IsArtifact() bool {
+	return s.Type == SourceTypeChart || s.Type == SourceTypeArtifact
+}
+
+func isSourceType(sourceType string) bool {
+	if sourceType == "" {
+		return true
+	}
+
+	for _, currentType := range sourceTypes {
+		if currentType == sourceType {
+			return true
+		}
+	}
+
+	return false
+}
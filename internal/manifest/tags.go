@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/hashicorp/go-version"
+)
+
+// HasTagSelector returns true when the source selects the tags of its repository to
+// mirror with a version constraint or a pattern, rather than referencing a single tag.
+func (s Source) HasTagSelector() bool {
+	return s.Tags != "" || s.TagPattern != ""
+}
+
+// ExpandTags returns a source for each of the tags of the repository that are selected by the source.
+// The tags are sorted from oldest to newest version, and tags that are not versions are sorted before
+// the versions by name. When the source keeps a number of tags, only the newest tags are returned.
+func (s Source) ExpandTags(tags []string) ([]Source, error) {
+	var constraints version.Constraints
+	if s.Tags != "" {
+		var err error
+		constraints, err = version.NewConstraint(s.Tags)
+		if err != nil {
+			return nil, fmt.Errorf("parse tags constraint: %w", err)
+		}
+	}
+
+	var pattern *regexp.Regexp
+	if s.TagPattern != "" {
+		var err error
+		pattern, err = regexp.Compile("^(?:" + s.TagPattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("compile tag pattern: %w", err)
+		}
+	}
+
+	var matchedTags []string
+	for _, tag := range tags {
+		if pattern != nil && !pattern.MatchString(tag) {
+			continue
+		}
+
+		if constraints != nil {
+			tagVersion, err := version.NewVersion(tag)
+			if err != nil || !constraints.Check(tagVersion) {
+				continue
+			}
+		}
+
+		matchedTags = append(matchedTags, tag)
+	}
+
+	sortTags(matchedTags)
+
+	if s.Keep > 0 && len(matchedTags) > s.Keep {
+		matchedTags = matchedTags[len(matchedTags)-s.Keep:]
+	}
+
+	var sources []Source
+	for _, tag := range matchedTags {
+		source := s
+		source.Tag = tag
+		source.Digest = ""
+		source.Tags = ""
+		source.TagPattern = ""
+		source.Keep = 0
+
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+func sortTags(tags []string) {
+	sort.SliceStable(tags, func(i int, j int) bool {
+		iVersion, iErr := version.NewVersion(tags[i])
+		jVersion, jErr := version.NewVersion(tags[j])
+
+		switch {
+		case iErr != nil && jErr != nil:
+			return tags[i] < tags[j]
+		case iErr != nil:
+			return true
+		case jErr != nil:
+			return false
+		}
+
+		return iVersion.LessThan(jVersion)
+	})
+}
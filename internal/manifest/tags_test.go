@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandTags(t *testing.T) {
+	tags := []string{"1.19.4", "v1.20.0", "1.21.3", "1.21.10", "1.22.0-rc.1", "1.23.0", "latest", "1.21.3-alpine"}
+
+	testCases := []struct {
+		name     string
+		source   Source
+		expected []string
+	}{
+		{
+			name:     "version constraint",
+			source:   Source{Repository: "foo/bar", Tags: ">= 1.20.0, < 1.23"},
+			expected: []string{"v1.20.0", "1.21.3", "1.21.10"},
+		},
+		{
+			name:     "tag pattern",
+			source:   Source{Repository: "foo/bar", TagPattern: `1\.21\.\d+-alpine|latest`},
+			expected: []string{"latest", "1.21.3-alpine"},
+		},
+		{
+			name:     "keep newest tags",
+			source:   Source{Repository: "foo/bar", Tags: ">= 1.20.0", Keep: 2},
+			expected: []string{"1.21.10", "1.23.0"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sources, err := testCase.source.ExpandTags(tags)
+			if err != nil {
+				t.Fatal("expand tags:", err)
+			}
+
+			var actual []string
+			for _, source := range sources {
+				if source.HasTagSelector() {
+					t.Errorf("expected expanded source %s to not select tags", source.Image())
+				}
+
+				actual = append(actual, source.Tag)
+			}
+
+			if !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("expected tags %v, actual %v", testCase.expected, actual)
+			}
+		})
+	}
+}
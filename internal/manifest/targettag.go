@@ -0,0 +1,79 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// tagPattern matches the tags that are valid in a registry.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// targetTagData is the data that the tag template of a target is executed with.
+type targetTagData struct {
+
+	// Tag is the tag of the source, or its digest without the sha256: prefix
+	// when the source does not have a tag.
+	Tag string
+
+	// Digest is the digest of the source without the sha256: prefix, if any.
+	Digest string
+
+	// Repository is the repository of the source without its host (e.g. coreos/etcd).
+	Repository string
+}
+
+// targetTag returns the tag that the source is pushed to the target with. The tag is the target tag of
+// the source when it has one, or the result of the tag template of its target. Otherwise, the source
+// is pushed with its own tag, or the digest without the sha256: prefix when it has no tag.
+func (s Source) targetTag() (string, error) {
+	if s.TargetTag != "" {
+		return s.TargetTag, nil
+	}
+
+	data := targetTagData{
+		Tag:        s.Tag,
+		Digest:     strings.ReplaceAll(s.Digest, "sha256:", ""),
+		Repository: s.Repository,
+	}
+	if data.Tag == "" {
+		data.Tag = data.Digest
+	}
+
+	if s.Target.TagTemplate == "" || data.Tag == "" {
+		return data.Tag, nil
+	}
+
+	tagTemplate, err := template.New("tag").Option("missingkey=error").Parse(s.Target.TagTemplate)
+	if err != nil {
+		return "", fmt.Errorf("parse tag template: %w", err)
+	}
+
+	var tag bytes.Buffer
+	if err := tagTemplate.Execute(&tag, data); err != nil {
+		return "", fmt.Errorf("execute tag template: %w", err)
+	}
+
+	return tag.String(), nil
+}
+
+// validateTargetTag returns an error when the source cannot be pushed with its target tag, because the
+// tag template is invalid or results in a tag that registries do not accept.
+func validateTargetTag(source Source) error {
+	if source.TargetTag != "" && (source.Tags != "" || source.TagPattern != "") {
+		return fmt.Errorf("source %s selects more than one tag and cannot have a target tag", source.Image())
+	}
+
+	tag, err := source.targetTag()
+	if err != nil {
+		return fmt.Errorf("source %s: %w", source.Image(), err)
+	}
+
+	if tag != "" && !tagPattern.MatchString(tag) {
+		return fmt.Errorf("source %s has an invalid target tag %s", source.Image(), tag)
+	}
+
+	return nil
+}
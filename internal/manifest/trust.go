@@ -0,0 +1,114 @@
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The types of trust policies, which select the tool that verifies the signatures of the images.
+const (
+	TrustCosign   = "cosign"
+	TrustNotation = "notation"
+)
+
+// TrustPolicy is a policy that the signatures of the source images must satisfy
+// for the images to be pushed when signatures are required.
+type TrustPolicy struct {
+	Name string `yaml:"name"`
+
+	// Type is the type of signatures that are verified, either cosign (the default) or notation. Notation
+	// signatures are verified with the trust policy and trust store that notation has been configured with.
+	Type string `yaml:"type,omitempty"`
+
+	// Images are glob patterns of the source images that the policy applies to (e.g. quay.io/coreos/*).
+	// A policy without patterns applies to every image that no earlier policy applies to.
+	Images []string `yaml:"images,omitempty"`
+
+	// Key is the path or URL of the public key that cosign signatures are verified with.
+	Key string `yaml:"key,omitempty"`
+
+	// Identity and Issuer are the identity (e.g. an email address or workflow URL) and the
+	// OIDC issuer of the certificate that keyless cosign signatures are verified with.
+	Identity string `yaml:"identity,omitempty"`
+	Issuer   string `yaml:"issuer,omitempty"`
+}
+
+// FindTrustPolicy returns the trust policy of the source. A source that names a trust policy uses that policy,
+// otherwise the first policy with a pattern that matches the source is used. It returns false when no policy applies.
+func FindTrustPolicy(policies []TrustPolicy, source Source) (TrustPolicy, bool, error) {
+	if source.Trust != "" {
+		for _, policy := range policies {
+			if policy.Name == source.Trust {
+				return policy, true, nil
+			}
+		}
+
+		return TrustPolicy{}, false, fmt.Errorf("source %s uses trust policy %s, which does not exist", source.Image(), source.Trust)
+	}
+
+	for _, policy := range policies {
+		if len(policy.Images) == 0 {
+			return policy, true, nil
+		}
+
+		var patterns []*regexp.Regexp
+		for _, image := range policy.Images {
+			pattern, err := CompileGlob(image)
+			if err != nil {
+				return TrustPolicy{}, false, fmt.Errorf("trust policy %s: %w", policy.Name, err)
+			}
+
+			patterns = append(patterns, pattern)
+		}
+
+		if matchesAny(source.Image(), patterns) {
+			return policy, true, nil
+		}
+	}
+
+	return TrustPolicy{}, false, nil
+}
+
+func validateTrustPolicies(policies []TrustPolicy, sources []Source) error {
+	names := make(map[string]bool)
+	for _, policy := range policies {
+		if policy.Name == "" {
+			return fmt.Errorf("trust policies must have a name")
+		}
+
+		if names[policy.Name] {
+			return fmt.Errorf("trust policy %s is defined more than once", policy.Name)
+		}
+		names[policy.Name] = true
+
+		switch policy.Type {
+		case "", TrustCosign:
+			if policy.Key == "" && policy.Identity == "" {
+				return fmt.Errorf("trust policy %s must have a key or an identity", policy.Name)
+			}
+
+		case TrustNotation:
+			if policy.Key != "" || policy.Identity != "" || policy.Issuer != "" {
+				return fmt.Errorf("trust policy %s of type notation is verified with the trust policy of notation and cannot have a key or an identity", policy.Name)
+			}
+
+		default:
+			return fmt.Errorf("trust policy %s has unknown type %s (must be one of %s)", policy.Name, policy.Type, strings.Join([]string{TrustCosign, TrustNotation}, ", "))
+		}
+
+		for _, image := range policy.Images {
+			if _, err := CompileGlob(image); err != nil {
+				return fmt.Errorf("trust policy %s: %w", policy.Name, err)
+			}
+		}
+	}
+
+	for _, source := range sources {
+		if source.Trust != "" && !names[source.Trust] {
+			return fmt.Errorf("source %s uses trust policy %s, which does not exist", source.Image(), source.Trust)
+		}
+	}
+
+	return nil
+}
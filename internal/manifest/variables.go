@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// variablePattern matches a reference to a variable (e.g. ${MIRROR_REGISTRY}), optionally with a default
+// value that is used when the variable is not set (e.g. ${MIRROR_REGISTRY:-mycompany.com}). A reference
+// that is prefixed with an additional $ (e.g. $${MIRROR_REGISTRY}) is escaped and left as is, without the $.
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-[^}]*)?\}`)
+
+// ExpandVariables replaces the references to variables in the contents with their values, so that the same
+// manifest can be used with different registries (e.g. target: ${MIRROR_REGISTRY}/mirrors). The given
+// variables take precedence over the environment variables. Referencing a variable that is not set and
+// does not have a default value is an error, rather than silently pushing images to the wrong registry.
+func ExpandVariables(contents []byte, variables map[string]string) ([]byte, error) {
+	var missingVariables []string
+	expandedContents := variablePattern.ReplaceAllFunc(contents, func(reference []byte) []byte {
+		if strings.HasPrefix(string(reference), "$$") {
+			return reference[1:]
+		}
+
+		match := variablePattern.FindSubmatch(reference)
+		name := string(match[1])
+
+		if value, ok := variables[name]; ok {
+			return []byte(value)
+		}
+
+		if value, ok := os.LookupEnv(name); ok {
+			return []byte(value)
+		}
+
+		if len(match[2]) > 0 {
+			return match[2][len(":-"):]
+		}
+
+		missingVariables = append(missingVariables, name)
+		return reference
+	})
+
+	if len(missingVariables) > 0 {
+		return nil, fmt.Errorf("variables not set: %s", strings.Join(missingVariables, ", "))
+	}
+
+	return expandedContents, nil
+}
+
+// ReadVariables reads the variables from a values file, which is a YAML map of the names of
+// the variables to their values (e.g. MIRROR_REGISTRY: mycompany.com).
+func ReadVariables(path string) (map[string]string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	return ParseVariables(contents)
+}
+
+// ParseVariables returns the variables in the contents of a values file.
+func ParseVariables(contents []byte) (map[string]string, error) {
+	var variables map[string]string
+	if err := yaml.Unmarshal(contents, &variables); err != nil {
+		return nil, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	return variables, nil
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandVariables(t *testing.T) {
+	os.Setenv("SINKER_TEST_REGISTRY", "env.mycompany.com")
+	defer os.Unsetenv("SINKER_TEST_REGISTRY")
+
+	contents := []byte(`target:
+  host: ${SINKER_TEST_REGISTRY}
+  repository: ${SINKER_TEST_REPOSITORY:-mirrors}
+sources:
+- repository: busybox
+  tagPattern: ^1\.3[0-9]\.0$
+  target:
+    host: ${SINKER_TEST_ML_REGISTRY}
+    repository: $${NOT_EXPANDED}
+`)
+
+	actual, err := ExpandVariables(contents, map[string]string{"SINKER_TEST_ML_REGISTRY": "ml.mycompany.com"})
+	if err != nil {
+		t.Fatal("expand variables:", err)
+	}
+
+	expected := `target:
+  host: env.mycompany.com
+  repository: mirrors
+sources:
+- repository: busybox
+  tagPattern: ^1\.3[0-9]\.0$
+  target:
+    host: ml.mycompany.com
+    repository: ${NOT_EXPANDED}
+`
+	if string(actual) != expected {
+		t.Errorf("expected contents %s, actual %s", expected, actual)
+	}
+
+	if _, err := ExpandVariables([]byte("host: ${SINKER_TEST_MISSING}"), nil); err == nil {
+		t.Error("expected an error for a variable that is not set")
+	}
+}
+
+func TestGetTemplate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".images.yaml")
+	if err := ioutil.WriteFile(path, []byte("target:\n  host: ${SINKER_TEST_REGISTRY}\n"), os.ModePerm); err != nil {
+		t.Fatal("write manifest:", err)
+	}
+
+	if _, err := Get(path); err == nil {
+		t.Error("expected an error when the variable is not set")
+	}
+
+	imageManifest, err := GetWithVariables(path, map[string]string{"SINKER_TEST_REGISTRY": "mycompany.com"})
+	if err != nil {
+		t.Fatal("get with variables:", err)
+	}
+
+	if imageManifest.Target.Host != "mycompany.com" {
+		t.Errorf("expected host mycompany.com, actual %s", imageManifest.Target.Host)
+	}
+
+	template, err := GetTemplate(path)
+	if err != nil {
+		t.Fatal("get template:", err)
+	}
+
+	if template.Target.Host != "${SINKER_TEST_REGISTRY}" {
+		t.Errorf("expected host to be the variable reference, actual %s", template.Target.Host)
+	}
+}
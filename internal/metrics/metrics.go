@@ -0,0 +1,208 @@
+// Package metrics exposes the progress of the long-running modes in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ImagesDiscovered is the number of images that were found in the manifest or resources.
+	ImagesDiscovered = newCounter("sinker_images_discovered_total", "Total number of images discovered.")
+
+	// ImagesSynced is the number of images that were copied to the target registry.
+	ImagesSynced = newCounter("sinker_images_synced_total", "Total number of images synced to the target registry.")
+
+	// ImagesFailed is the number of images that could not be copied to the target registry.
+	ImagesFailed = newCounter("sinker_images_failed_total", "Total number of images that failed to sync to the target registry.")
+
+	// ImagesMissing is the number of images that did not exist at the target registry when last checked.
+	ImagesMissing = newGauge("sinker_images_missing", "Number of images that did not exist at the target registry when last checked.")
+
+	// BytesTransferred is the number of bytes uploaded to registries.
+	BytesTransferred = newCounter("sinker_bytes_transferred_total", "Total number of bytes uploaded to registries.")
+
+	// SyncDuration is how long it took to copy each image to the target registry.
+	SyncDuration = newHistogram("sinker_sync_duration_seconds", "Duration of syncing an image to the target registry.", []float64{1, 5, 15, 30, 60, 120, 300, 600})
+)
+
+type metric interface {
+	write(w io.Writer) error
+}
+
+var (
+	registryMutex sync.Mutex
+	registry      []metric
+)
+
+func register(m metric) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry = append(registry, m)
+}
+
+// Counter is a value that only increases.
+type Counter struct {
+	name  string
+	help  string
+	value uint64
+}
+
+func newCounter(name string, help string) *Counter {
+	counter := Counter{
+		name: name,
+		help: help,
+	}
+
+	register(&counter)
+	return &counter
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add increments the counter by the given value.
+func (c *Counter) Add(value uint64) {
+	atomic.AddUint64(&c.value, value)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+func (c *Counter) write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.Value())
+	return err
+}
+
+// Gauge is a value that can increase and decrease.
+type Gauge struct {
+	name string
+	help string
+	bits uint64
+}
+
+func newGauge(name string, help string) *Gauge {
+	gauge := Gauge{
+		name: name,
+		help: help,
+	}
+
+	register(&gauge)
+	return &gauge
+}
+
+// Set sets the gauge to the given value.
+func (g *Gauge) Set(value float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(value))
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+func (g *Gauge) write(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", g.name, g.help, g.name, g.name, formatFloat(g.Value()))
+	return err
+}
+
+// Histogram counts observations in cumulative buckets.
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+
+	mutex  sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func newHistogram(name string, help string, buckets []float64) *Histogram {
+	histogram := Histogram{
+		name:    name,
+		help:    help,
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+
+	register(&histogram)
+	return &histogram
+}
+
+// Observe adds a single observation to the histogram.
+func (h *Histogram) Observe(value float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for i, bucket := range h.buckets {
+		if value <= bucket {
+			h.counts[i]++
+		}
+	}
+
+	h.count++
+	h.sum += value
+}
+
+// ObserveDuration adds the time elapsed since the start, in seconds, to the histogram.
+func (h *Histogram) ObserveDuration(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name); err != nil {
+		return err
+	}
+
+	for i, bucket := range h.buckets {
+		if _, err := fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", h.name, formatFloat(bucket), h.counts[i]); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n", h.name, h.count, h.name, formatFloat(h.sum), h.name, h.count)
+	return err
+}
+
+// Write writes every metric to the writer in the Prometheus text format.
+func Write(w io.Writer) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	for _, m := range registry {
+		if err := m.write(w); err != nil {
+			return fmt.Errorf("write metric: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Handler returns a handler that serves every metric.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHistogram_Write(t *testing.T) {
+	histogram := Histogram{
+		name:    "test_duration_seconds",
+		help:    "Test duration.",
+		buckets: []float64{1, 5},
+		counts:  make([]uint64, 2),
+	}
+
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+	histogram.Observe(10)
+
+	var actual bytes.Buffer
+	if err := histogram.write(&actual); err != nil {
+		t.Fatal("write:", err)
+	}
+
+	expected := `# HELP test_duration_seconds Test duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="1"} 1
+test_duration_seconds_bucket{le="5"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 13.5
+test_duration_seconds_count 3
+`
+
+	if actual.String() != expected {
+		t.Errorf("expected %s, actual %s", expected, actual.String())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	ImagesSynced.Inc()
+	ImagesMissing.Set(2)
+
+	var actual bytes.Buffer
+	if err := Write(&actual); err != nil {
+		t.Fatal("write:", err)
+	}
+
+	expectedLines := []string{
+		"# TYPE sinker_images_synced_total counter\nsinker_images_synced_total 1\n",
+		"sinker_images_missing 2\n",
+		"# TYPE sinker_sync_duration_seconds histogram\n",
+	}
+
+	for _, expected := range expectedLines {
+		if !strings.Contains(actual.String(), expected) {
+			t.Errorf("expected metrics to contain %q, actual %s", expected, actual.String())
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package scan scans images for vulnerabilities with Trivy.
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Severities are the severities of vulnerabilities, from the least to the most severe.
+var Severities = []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// Vulnerability is a vulnerability that was found in an image.
+type Vulnerability struct {
+	ID               string `json:"VulnerabilityID"`
+	Package          string `json:"PkgName"`
+	InstalledVersion string `json:"InstalledVersion"`
+	Severity         string `json:"Severity"`
+}
+
+// Options are the options used to scan an image.
+type Options struct {
+
+	// Server is the address of a Trivy server to scan with (e.g. http://trivy:4954).
+	// When empty, the image is scanned locally.
+	Server string
+}
+
+// ScanImage scans the image for vulnerabilities and returns all of the vulnerabilities
+// that were found. Scanning is performed by the trivy CLI, which must be available on the PATH.
+func ScanImage(ctx context.Context, image string, opts Options) ([]Vulnerability, error) {
+	args := []string{"image", "--quiet", "--format", "json"}
+	if opts.Server != "" {
+		args = append(args, "--server", opts.Server)
+	}
+	args = append(args, image)
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "trivy", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("trivy: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	vulnerabilities, err := parseReport(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("parse report: %w", err)
+	}
+
+	return vulnerabilities, nil
+}
+
+// AtLeast returns the vulnerabilities that are at least as severe as the given severity.
+func AtLeast(vulnerabilities []Vulnerability, severity string) ([]Vulnerability, error) {
+	threshold := severityRank(severity)
+	if threshold < 0 {
+		return nil, fmt.Errorf("unknown severity %s", severity)
+	}
+
+	var found []Vulnerability
+	for _, vulnerability := range vulnerabilities {
+		if severityRank(vulnerability.Severity) >= threshold {
+			found = append(found, vulnerability)
+		}
+	}
+
+	return found, nil
+}
+
+// CountBySeverity returns the number of vulnerabilities of each severity.
+func CountBySeverity(vulnerabilities []Vulnerability) map[string]int {
+	counts := make(map[string]int)
+	for _, vulnerability := range vulnerabilities {
+		counts[strings.ToUpper(vulnerability.Severity)]++
+	}
+
+	return counts
+}
+
+func parseReport(contents []byte) ([]Vulnerability, error) {
+
+	// Older versions of Trivy output a list of results, while newer
+	// versions output a report object that contains the results.
+	type result struct {
+		Vulnerabilities []Vulnerability `json:"Vulnerabilities"`
+	}
+
+	var results []result
+	trimmed := bytes.TrimSpace(contents)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		if err := json.Unmarshal(trimmed, &results); err != nil {
+			return nil, fmt.Errorf("unmarshal results: %w", err)
+		}
+	} else {
+		var report struct {
+			Results []result `json:"Results"`
+		}
+		if err := json.Unmarshal(trimmed, &report); err != nil {
+			return nil, fmt.Errorf("unmarshal report: %w", err)
+		}
+
+		results = report.Results
+	}
+
+	var vulnerabilities []Vulnerability
+	for _, result := range results {
+		vulnerabilities = append(vulnerabilities, result.Vulnerabilities...)
+	}
+
+	return vulnerabilities, nil
+}
+
+func severityRank(severity string) int {
+	for rank, currentSeverity := range Severities {
+		if strings.EqualFold(currentSeverity, severity) {
+			return rank
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,54 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReport(t *testing.T) {
+	reports := []string{
+		`{"Results":[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2020-1967","PkgName":"openssl","InstalledVersion":"1.1.1d","Severity":"HIGH"}]}]}`,
+		`[{"Vulnerabilities":[{"VulnerabilityID":"CVE-2020-1967","PkgName":"openssl","InstalledVersion":"1.1.1d","Severity":"HIGH"}]}]`,
+	}
+
+	expected := []Vulnerability{
+		{ID: "CVE-2020-1967", Package: "openssl", InstalledVersion: "1.1.1d", Severity: "HIGH"},
+	}
+
+	for _, report := range reports {
+		actual, err := parseReport([]byte(report))
+		if err != nil {
+			t.Fatal("parse report:", err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected vulnerabilities %v, actual %v", expected, actual)
+		}
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	vulnerabilities := []Vulnerability{
+		{ID: "CVE-1", Severity: "LOW"},
+		{ID: "CVE-2", Severity: "HIGH"},
+		{ID: "CVE-3", Severity: "CRITICAL"},
+	}
+
+	actual, err := AtLeast(vulnerabilities, "high")
+	if err != nil {
+		t.Fatal("at least:", err)
+	}
+
+	expected := []Vulnerability{
+		{ID: "CVE-2", Severity: "HIGH"},
+		{ID: "CVE-3", Severity: "CRITICAL"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected vulnerabilities %v, actual %v", expected, actual)
+	}
+
+	if _, err := AtLeast(vulnerabilities, "SEVERE"); err == nil {
+		t.Error("expected an error for an unknown severity")
+	}
+}
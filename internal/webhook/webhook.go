@@ -0,0 +1,287 @@
+// Package webhook rewrites the images of pods to their target images as they are admitted into a cluster.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// syncTimeout is how long an on-demand sync of a single image may take.
+const syncTimeout = 30 * time.Minute
+
+// existsTimeout is how long checking that an image exists at the target may take, which is shorter
+// than the timeout of the API server for webhooks, so that the pod is still admitted when the check times out.
+const existsTimeout = 5 * time.Second
+
+// Config configures the webhook.
+type Config struct {
+
+	// Manifest contains the sources that are rewritten to their target images.
+	Manifest manifest.Manifest
+
+	// Sync mirrors the images that do not exist at the target yet when a pod is admitted. Images are only rewritten
+	// when they exist at the target, so that pods keep their source images until the images have been mirrored.
+	Sync bool
+
+	// CopySource copies the image of a source to its target when it is synced, which pulls the image through the
+	// registry mirror of the source or from its fallbacks, as push does.
+	CopySource func(ctx context.Context, source manifest.Source) error
+
+	// TargetAuth returns the encoded auth of a target registry.
+	TargetAuth func(target manifest.Target) (string, error)
+
+	// Logger logs the progress of the webhook.
+	Logger func(format string, args ...interface{})
+}
+
+// Webhook is a mutating admission webhook that rewrites the images of pods to their target images.
+type Webhook struct {
+	ctx    context.Context
+	client docker.Client
+	config Config
+
+	// syncing are the target images that are being synced in the background, which syncs waits
+	// for, and existing are the target images that are known to exist, which are not checked again.
+	mutex    sync.Mutex
+	syncing  map[string]bool
+	existing map[string]bool
+	syncs    sync.WaitGroup
+}
+
+// New returns a webhook that rewrites images using the sources in the manifest. The images that are
+// synced in the background are cancelled when the context is cancelled (e.g. when the server shuts down).
+func New(ctx context.Context, client docker.Client, config Config) *Webhook {
+	webhook := Webhook{
+		ctx:      ctx,
+		client:   client,
+		config:   config,
+		syncing:  make(map[string]bool),
+		existing: make(map[string]bool),
+	}
+
+	return &webhook
+}
+
+// Shutdown waits for the images that are being synced in the background to finish, which stop
+// when the context of the webhook is cancelled, or returns an error when the context expires first.
+func (w *Webhook) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.syncs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("wait for syncs: %w", ctx.Err())
+	}
+}
+
+type patchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value string `json:"value"`
+}
+
+// ServeHTTP handles an AdmissionReview request.
+func (w *Webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("read body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &review); err != nil || review.Request == nil {
+		http.Error(rw, "invalid admission review", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = w.admit(r.Context(), review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(review); err != nil {
+		w.config.Logger("Unable to write admission response: %v", err)
+	}
+}
+
+// admit always allows the pod so that a failure to rewrite an image never blocks a deployment.
+func (w *Webhook) admit(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	response := admissionv1.AdmissionResponse{
+		Allowed: true,
+	}
+
+	if request.Kind.Kind != "Pod" {
+		return &response
+	}
+
+	var pod corev1.Pod
+	if err := json.Unmarshal(request.Object.Raw, &pod); err != nil {
+		response.Result = &metav1.Status{Message: fmt.Sprintf("unmarshal pod: %v", err)}
+		return &response
+	}
+
+	patches := w.getPatches(ctx, pod, request.SubResource)
+	if len(patches) == 0 {
+		return &response
+	}
+
+	patch, err := json.Marshal(patches)
+	if err != nil {
+		response.Result = &metav1.Status{Message: fmt.Sprintf("marshal patch: %v", err)}
+		return &response
+	}
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	response.Patch = patch
+	response.PatchType = &patchType
+
+	return &response
+}
+
+// getPatches returns the patches that replace the images of the containers in the pod with their target images.
+// Ephemeral containers (e.g. of kubectl debug) are added to a running pod through the ephemeralcontainers
+// subresource, which can only change the ephemeral containers, so only their images are replaced.
+func (w *Webhook) getPatches(ctx context.Context, pod corev1.Pod, subResource string) []patchOperation {
+	var patches []patchOperation
+	for i, container := range pod.Spec.EphemeralContainers {
+		patches = append(patches, w.getPatch(ctx, "/spec/ephemeralContainers/"+strconv.Itoa(i)+"/image", container.Image)...)
+	}
+
+	if subResource == "ephemeralcontainers" {
+		return patches
+	}
+
+	for i, container := range pod.Spec.InitContainers {
+		patches = append(patches, w.getPatch(ctx, "/spec/initContainers/"+strconv.Itoa(i)+"/image", container.Image)...)
+	}
+
+	for i, container := range pod.Spec.Containers {
+		patches = append(patches, w.getPatch(ctx, "/spec/containers/"+strconv.Itoa(i)+"/image", container.Image)...)
+	}
+
+	return patches
+}
+
+func (w *Webhook) getPatch(ctx context.Context, path string, image string) []patchOperation {
+	source, ok := w.config.Manifest.FindSource(image)
+	if !ok || source.TargetImage() == image {
+		return nil
+	}
+
+	if !w.existsAtTarget(ctx, source) {
+		if w.config.Sync {
+			w.syncInBackground(source)
+		}
+
+		return nil
+	}
+
+	w.config.Logger("Rewriting %s to %s", image, source.TargetImage())
+
+	patch := patchOperation{
+		Op:    "replace",
+		Path:  path,
+		Value: source.TargetImage(),
+	}
+
+	return []patchOperation{patch}
+}
+
+// existsAtTarget returns true when the target image of the source exists at the target. Images that cannot be
+// checked are not rewritten, as the pod can still pull them from their source. Images that exist are remembered,
+// so that they are only checked once.
+func (w *Webhook) existsAtTarget(ctx context.Context, source manifest.Source) bool {
+	w.mutex.Lock()
+	existing := w.existing[source.TargetImage()]
+	w.mutex.Unlock()
+
+	if existing {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, existsTimeout)
+	defer cancel()
+
+	targetAuth, err := w.config.TargetAuth(source.Target)
+	if err != nil {
+		w.config.Logger("Unable to get the auth of %s, not rewriting %s: %v", source.Target.Host, source.Image(), err)
+		return false
+	}
+
+	exists, err := w.client.ImageExistsAtRemote(ctx, source.TargetImage(), targetAuth)
+	if err != nil {
+		w.config.Logger("Unable to check that %s exists, not rewriting %s: %v", source.TargetImage(), source.Image(), err)
+		return false
+	}
+
+	if !exists {
+		w.config.Logger("Image %s does not exist at the target, not rewriting %s", source.TargetImage(), source.Image())
+		return false
+	}
+
+	w.mutex.Lock()
+	w.existing[source.TargetImage()] = true
+	w.mutex.Unlock()
+
+	return true
+}
+
+// syncInBackground mirrors the source to the target without blocking the admission request, so that the pods that
+// are admitted after it has been mirrored are rewritten. A source that is already being synced is not synced again.
+func (w *Webhook) syncInBackground(source manifest.Source) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.syncing[source.TargetImage()] {
+		return
+	}
+	w.syncing[source.TargetImage()] = true
+
+	w.syncs.Add(1)
+	go func() {
+		defer w.syncs.Done()
+		defer func() {
+			w.mutex.Lock()
+			delete(w.syncing, source.TargetImage())
+			w.mutex.Unlock()
+		}()
+
+		ctx, cancel := context.WithTimeout(w.ctx, syncTimeout)
+		defer cancel()
+
+		w.config.Logger("Pushing %s", source.TargetImage())
+		if err := w.config.CopySource(ctx, source); err != nil {
+			w.config.Logger("Unable to sync %s: %v", source.Image(), err)
+			return
+		}
+
+		w.config.Logger("Pushed %s", source.TargetImage())
+
+		w.mutex.Lock()
+		w.existing[source.TargetImage()] = true
+		w.mutex.Unlock()
+	}()
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/plexsystems/sinker/internal/docker"
+	"github.com/plexsystems/sinker/internal/manifest"
+	"github.com/plexsystems/sinker/pkg/testutil"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestServeHTTP(t *testing.T) {
+	registry := testutil.NewRegistry(t)
+	registry.PushImage(t, "myteam/jimmidyson/configmap-reload:v0.3.0", 1)
+	registry.PushImage(t, "myteam/busybox:1.32.0", 1)
+
+	host := registry.Host
+
+	target := manifest.Target{
+		Host:       host,
+		Repository: "myteam",
+	}
+
+	imageManifest := manifest.Manifest{
+		Target: target,
+		Sources: []manifest.Source{
+			{Repository: "jimmidyson/configmap-reload", Tag: "v0.3.0", Target: target},
+			{Repository: "busybox", Tag: "1.32.0", Target: target},
+			{Repository: "nginx", Tag: "1.21", Target: target},
+		},
+	}
+
+	pod := corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "unknown", Image: "alpine:3.12"},
+				{Name: "reloader", Image: "jimmidyson/configmap-reload:v0.3.0"},
+				{Name: "unpushed", Image: "nginx:1.21"},
+			},
+			EphemeralContainers: []corev1.EphemeralContainer{
+				{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "busybox:1.32.0"}},
+			},
+		},
+	}
+
+	client, err := docker.NewClient(t.Logf)
+	if err != nil {
+		t.Fatal("new client:", err)
+	}
+
+	noTargetAuth := func(manifest.Target) (string, error) { return "", nil }
+	webhook := New(context.Background(), client.WithRetries(0), Config{Manifest: imageManifest, TargetAuth: noTargetAuth, Logger: t.Logf})
+
+	// Images that do not exist at the target are not rewritten, as the sync flag is not set.
+	expected := `[` +
+		`{"op":"replace","path":"/spec/ephemeralContainers/0/image","value":"` + host + `/myteam/busybox:1.32.0"},` +
+		`{"op":"replace","path":"/spec/containers/1/image","value":"` + host + `/myteam/jimmidyson/configmap-reload:v0.3.0"}` +
+		`]`
+
+	if actual := admitPod(t, webhook, pod, ""); actual != expected {
+		t.Errorf("expected patch %s, actual %s", expected, actual)
+	}
+
+	expectedEphemeral := `[{"op":"replace","path":"/spec/ephemeralContainers/0/image","value":"` + host + `/myteam/busybox:1.32.0"}]`
+	if actual := admitPod(t, webhook, pod, "ephemeralcontainers"); actual != expectedEphemeral {
+		t.Errorf("expected patch of the ephemeralcontainers subresource %s, actual %s", expectedEphemeral, actual)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	registry := testutil.NewRegistry(t)
+	registry.PushImage(t, "source/app:v1.0.0", 1)
+
+	host := registry.Host
+
+	target := manifest.Target{
+		Host:       host,
+		Repository: "mirror",
+	}
+
+	imageManifest := manifest.Manifest{
+		Target: target,
+		Sources: []manifest.Source{
+			{Host: host, Repository: "source/app", Tag: "v1.0.0", Target: target},
+		},
+	}
+
+	client, err := docker.NewClient(t.Logf)
+	if err != nil {
+		t.Fatal("new client:", err)
+	}
+	client = client.WithRetries(0)
+
+	copySource := func(ctx context.Context, source manifest.Source) error {
+		return client.CopyImageAndWait(ctx, source.PullImage(), "", source.TargetImage(), "", nil)
+	}
+
+	config := Config{
+		Manifest:   imageManifest,
+		Sync:       true,
+		CopySource: copySource,
+		TargetAuth: func(manifest.Target) (string, error) { return "", nil },
+		Logger:     t.Logf,
+	}
+
+	webhook := New(context.Background(), client, config)
+
+	pod := corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: host + "/source/app:v1.0.0"},
+			},
+		},
+	}
+
+	// Images are synced in the background when the sync flag is set, but are not rewritten until they exist at the target.
+	if actual := admitPod(t, webhook, pod, ""); actual != "" {
+		t.Errorf("expected no patch before the image is synced, actual %s", actual)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := webhook.Shutdown(ctx); err != nil {
+		t.Fatal("shutdown:", err)
+	}
+
+	exists, err := client.ImageExistsAtRemote(context.Background(), host+"/mirror/source/app:v1.0.0", "")
+	if err != nil {
+		t.Fatal("image exists at remote:", err)
+	}
+
+	if !exists {
+		t.Error("expected the image to be synced before shutdown returns")
+	}
+
+	expected := `[{"op":"replace","path":"/spec/containers/0/image","value":"` + host + `/mirror/source/app:v1.0.0"}]`
+	if actual := admitPod(t, webhook, pod, ""); actual != expected {
+		t.Errorf("expected patch %s once the image is synced, actual %s", expected, actual)
+	}
+}
+
+// admitPod sends an admission review of the pod to the webhook and returns the patch of the response.
+func admitPod(t *testing.T, webhook *Webhook, pod corev1.Pod, subResource string) string {
+	podContents, err := json.Marshal(pod)
+	if err != nil {
+		t.Fatal("marshal pod:", err)
+	}
+
+	review := admissionv1.AdmissionReview{
+		Request: &admissionv1.AdmissionRequest{
+			UID:         "1234",
+			Kind:        metav1.GroupVersionKind{Version: "v1", Kind: "Pod"},
+			SubResource: subResource,
+			Object:      runtime.RawExtension{Raw: podContents},
+		},
+	}
+
+	reviewContents, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal("marshal review:", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	webhook.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/mutate", bytes.NewReader(reviewContents)))
+
+	var actual admissionv1.AdmissionReview
+	if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil {
+		t.Fatal("unmarshal response:", err)
+	}
+
+	if actual.Response == nil || !actual.Response.Allowed || actual.Response.UID != "1234" {
+		t.Fatalf("expected pod with uid 1234 to be allowed, actual %+v", actual.Response)
+	}
+
+	return string(actual.Response.Patch)
+}
@@ -1,13 +1,50 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/plexsystems/sinker/internal/commands"
 )
 
 func main() {
-	if err := commands.NewDefaultCommand().Execute(); err != nil {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first signal cancels the running command so that it can stop cleanly,
+	// a second signal exits immediately.
+	signals := make(chan os.Signal, 1)
+	received := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		received <- <-signals
+		signal.Stop(signals)
+		cancel()
+	}()
+
+	if err := commands.NewDefaultCommand().ExecuteContext(ctx); err != nil {
+		if ctx.Err() != nil {
+			os.Exit(getSignalExitCode(<-received))
+		}
+
+		var exitErr commands.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+
 		os.Exit(1)
 	}
 }
+
+// getSignalExitCode returns the exit code of a command that was stopped by the signal, which is
+// 128 plus the number of the signal by convention (e.g. 130 for SIGINT and 143 for SIGTERM).
+func getSignalExitCode(received os.Signal) int {
+	if number, ok := received.(syscall.Signal); ok {
+		return 128 + int(number)
+	}
+
+	return 130
+}
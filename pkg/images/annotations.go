@@ -0,0 +1,54 @@
+package images
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// SkipAnnotation is the annotation that excludes all of the images of a resource
+	// when it is set to true (e.g. sinker.io/skip: "true").
+	SkipAnnotation = "sinker.io/skip"
+
+	// SourceAnnotation is the annotation that overrides the images that are found in a
+	// resource (e.g. sinker.io/source: quay.io/coreos/prometheus-operator:v0.40.0). The
+	// image of a single container can be overridden by adding the name of the container
+	// to the annotation (e.g. sinker.io/source.config-reloader).
+	SourceAnnotation = "sinker.io/source"
+)
+
+// applyAnnotations returns the images of a resource with the overrides of its annotations applied.
+// Images found by scanning the values of the resource can be skipped, but are never overridden.
+func applyAnnotations(annotations map[string]string, images []containerImage) []containerImage {
+	if skip, err := strconv.ParseBool(annotations[SkipAnnotation]); err == nil && skip {
+		return nil
+	}
+
+	// The sources in the annotations are themselves found when scanning the values
+	// of the resource, but they are not separate images of the resource.
+	sources := make(map[string]bool)
+	for name, value := range annotations {
+		if name == SourceAnnotation || strings.HasPrefix(name, SourceAnnotation+".") {
+			sources[value] = true
+		}
+	}
+
+	var overriddenImages []containerImage
+	for _, image := range images {
+		if image.heuristic && sources[image.reference] {
+			continue
+		}
+
+		if !image.heuristic {
+			if source, ok := annotations[SourceAnnotation+"."+image.container]; ok && image.container != "" {
+				image.reference = source
+			} else if source, ok := annotations[SourceAnnotation]; ok {
+				image.reference = source
+			}
+		}
+
+		overriddenImages = append(overriddenImages, image)
+	}
+
+	return overriddenImages
+}
@@ -0,0 +1,57 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyAnnotations(t *testing.T) {
+	images := []containerImage{
+		{reference: "mycompany.com/myteam/prometheus-operator:v0.40.0", container: "operator"},
+		{reference: "mycompany.com/myteam/configmap-reload:v0.4.0", container: "config-reloader"},
+		{reference: "quay.io/coreos/prometheus-operator:v0.40.0", heuristic: true},
+	}
+
+	testCases := []struct {
+		name        string
+		annotations map[string]string
+		expected    []containerImage
+	}{
+		{
+			"no annotations",
+			nil,
+			images,
+		},
+		{
+			"skip",
+			map[string]string{SkipAnnotation: "true"},
+			nil,
+		},
+		{
+			"source of a container",
+			map[string]string{
+				SourceAnnotation + ".operator": "quay.io/coreos/prometheus-operator:v0.40.0",
+				SkipAnnotation:                 "false",
+			},
+			[]containerImage{
+				{reference: "quay.io/coreos/prometheus-operator:v0.40.0", container: "operator"},
+				{reference: "mycompany.com/myteam/configmap-reload:v0.4.0", container: "config-reloader"},
+			},
+		},
+		{
+			"source of all containers",
+			map[string]string{SourceAnnotation: "quay.io/coreos/prometheus-operator:v0.40.0"},
+			[]containerImage{
+				{reference: "quay.io/coreos/prometheus-operator:v0.40.0", container: "operator"},
+				{reference: "quay.io/coreos/prometheus-operator:v0.40.0", container: "config-reloader"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := applyAnnotations(testCase.annotations, images)
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected images %+v (%s), actual %+v", testCase.expected, testCase.name, actual)
+		}
+	}
+}
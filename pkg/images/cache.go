@@ -0,0 +1,156 @@
+package images
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// cacheMutex keeps the goroutines of this process from filling the same entry of the cache at the same time.
+var cacheMutex sync.Mutex
+
+// commitPattern matches the full hash of a Git commit, which always refers to the same contents.
+var commitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// cacheKey returns the name of the entry of the cache for the given parts, such as the URL and ref of a repository.
+func cacheKey(parts ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(hash[:])[:32]
+}
+
+// getGitRepository returns the path to the directory of the repository that should be searched for images,
+// and a function that cleans up the repository once the images are found. When the options have a cache
+// directory, the repository is cloned into the cache once for each ref, and is only updated on later calls.
+func getGitRepository(repository gitRepository, o options) (string, func(), error) {
+	if o.cacheDir == "" {
+		return cloneGitRepository(o.ctx, repository)
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	dir := filepath.Join(o.cacheDir, "git", cacheKey(repository.url, repository.ref))
+	if _, err := os.Stat(dir); err == nil {
+
+		// A branch (or a moved tag) can point to a new commit, so the repository is fetched again, which
+		// only transfers the new commits. A commit always has the same contents and is used as is.
+		if commitPattern.MatchString(repository.ref) {
+			return filepath.Join(dir, filepath.FromSlash(repository.subdir)), func() {}, nil
+		}
+
+		if err := updateGitRepository(o.ctx, repository, dir); err == nil {
+			return filepath.Join(dir, filepath.FromSlash(repository.subdir)), func() {}, nil
+		}
+
+		// A repository that cannot be updated (e.g. its history was rewritten) is cloned again.
+		if err := os.RemoveAll(dir); err != nil {
+			return "", nil, fmt.Errorf("remove cached repository: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), os.ModePerm); err != nil {
+		return "", nil, fmt.Errorf("create cache dir: %w", err)
+	}
+
+	// The repository is cloned next to its entry and moved into place once the clone
+	// succeeds, so that an interrupted clone is never mistaken for a cached repository.
+	cloneDir, err := ioutil.TempDir(filepath.Dir(dir), "clone")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp dir: %w", err)
+	}
+
+	if err := gitClone(o.ctx, repository, cloneDir); err != nil {
+		os.RemoveAll(cloneDir)
+		return "", nil, err
+	}
+
+	if err := os.Rename(cloneDir, dir); err != nil {
+		os.RemoveAll(cloneDir)
+		return "", nil, fmt.Errorf("move clone into cache: %w", err)
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(repository.subdir)), func() {}, nil
+}
+
+// updateGitRepository fetches the latest commit of the ref of the cloned repository and checks it out.
+func updateGitRepository(ctx context.Context, repository gitRepository, dir string) error {
+	ref := repository.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	if _, err := execute(ctx, "git", "-C", dir, "fetch", "--quiet", "--depth", "1", "origin", ref); err != nil {
+		return fmt.Errorf("git fetch: %w", err)
+	}
+
+	if _, err := execute(ctx, "git", "-C", dir, "checkout", "--quiet", "--force", "FETCH_HEAD"); err != nil {
+		return fmt.Errorf("git checkout: %w", err)
+	}
+
+	return nil
+}
+
+// getHelmChart returns the chart to render with helm template. When the options have a cache directory and the
+// version of the chart is known, the chart is pulled into the cache once and the path to the archive is returned.
+// Otherwise, the chart is returned as is and helm fetches it from the repository every time that it is rendered.
+func getHelmChart(chart string, repository string, version string, o options) (string, error) {
+	if o.cacheDir == "" || version == "" {
+		return chart, nil
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+
+	dir := filepath.Join(o.cacheDir, "charts", cacheKey(repository, chart, version))
+	if archive, err := findChartArchive(dir); err == nil {
+		return archive, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), os.ModePerm); err != nil {
+		return "", fmt.Errorf("create cache dir: %w", err)
+	}
+
+	pullDir, err := ioutil.TempDir(filepath.Dir(dir), "pull")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+
+	args := []string{"pull", chart, "--version", version, "--destination", pullDir}
+	if repository != "" {
+		args = append(args, "--repo", repository)
+	}
+
+	if _, err := execute(o.ctx, "helm", args...); err != nil {
+		os.RemoveAll(pullDir)
+		return "", fmt.Errorf("helm pull: %w", err)
+	}
+
+	os.RemoveAll(dir)
+	if err := os.Rename(pullDir, dir); err != nil {
+		os.RemoveAll(pullDir)
+		return "", fmt.Errorf("move chart into cache: %w", err)
+	}
+
+	return findChartArchive(dir)
+}
+
+// findChartArchive returns the path to the archive of the chart that was pulled into the directory.
+func findChartArchive(dir string) (string, error) {
+	archives, err := filepath.Glob(filepath.Join(dir, "*.tgz"))
+	if err != nil {
+		return "", fmt.Errorf("glob: %w", err)
+	}
+
+	if len(archives) != 1 {
+		return "", fmt.Errorf("expected one chart archive in %s, found %v", dir, len(archives))
+	}
+
+	return archives[0], nil
+}
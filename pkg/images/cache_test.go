@@ -0,0 +1,69 @@
+package images
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetGitRepository_Cache(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	origin := filepath.Join(dir, "origin")
+	commit := func(name string) {
+		if err := ioutil.WriteFile(filepath.Join(origin, name), []byte("kind: Pod\n"), os.ModePerm); err != nil {
+			t.Fatal("write file:", err)
+		}
+
+		for _, args := range [][]string{{"add", "."}, {"-c", "user.name=sinker", "-c", "user.email=sinker@example.com", "commit", "--quiet", "-m", name}} {
+			if _, err := execute(context.Background(), "git", append([]string{"-C", origin}, args...)...); err != nil {
+				t.Fatal("git:", err)
+			}
+		}
+	}
+
+	if _, err := execute(context.Background(), "git", "init", "--quiet", origin); err != nil {
+		t.Fatal("git init:", err)
+	}
+	commit("first.yaml")
+
+	o := newOptions(WithCacheDir(filepath.Join(dir, "cache")))
+	repository := gitRepository{url: "file://" + filepath.ToSlash(origin)}
+
+	path, cleanup, err := getGitRepository(repository, o)
+	if err != nil {
+		t.Fatal("get git repository:", err)
+	}
+	cleanup()
+
+	if _, err := os.Stat(filepath.Join(path, "first.yaml")); err != nil {
+		t.Error("expected the repository to be cloned into the cache:", err)
+	}
+
+	commit("second.yaml")
+
+	cachedPath, cleanup, err := getGitRepository(repository, o)
+	if err != nil {
+		t.Fatal("get cached git repository:", err)
+	}
+	cleanup()
+
+	if cachedPath != path {
+		t.Errorf("expected the cached repository at %s, actual %s", path, cachedPath)
+	}
+
+	if _, err := os.Stat(filepath.Join(cachedPath, "second.yaml")); err != nil {
+		t.Error("expected the cached repository to be updated:", err)
+	}
+}
@@ -0,0 +1,215 @@
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kubeyaml "github.com/ghodss/yaml"
+)
+
+// The kinds of the resources that are found in CI configuration files.
+const (
+	gitHubWorkflowKind = "GitHubWorkflow"
+	gitLabCIKind       = "GitLabCI"
+)
+
+// gitLabCIKeywords are the top level keys of a .gitlab-ci.yml file that are not jobs. The
+// default section is not a job, but has an image and services in the same way as a job.
+var gitLabCIKeywords = []string{"include", "stages", "variables", "workflow", "image", "services", "cache", "before_script", "after_script"}
+
+// isGitHubWorkflow returns true when the file is a GitHub Actions workflow (e.g. .github/workflows/build.yml).
+func isGitHubWorkflow(path string) bool {
+	return strings.HasSuffix(filepath.ToSlash(filepath.Dir(path)), ".github/workflows")
+}
+
+// isGitLabCIFile returns true when the file is a GitLab CI configuration file (e.g. .gitlab-ci.yml).
+func isGitLabCIFile(path string) bool {
+	name := filepath.Base(path)
+	return name == ".gitlab-ci.yml" || name == ".gitlab-ci.yaml"
+}
+
+// getGitHubWorkflowImages returns the name of the workflow and the images of the containers and services
+// of its jobs, and of the steps that run a Docker image (e.g. uses: docker://alpine:3.12). The workflow is
+// named after the file when it does not set a name. The container of each image is the name of the job,
+// service or step that uses it.
+func getGitHubWorkflowImages(path string, yamlFile []byte) (string, []containerImage, error) {
+	var workflow struct {
+		Name string `json:"name"`
+		Jobs map[string]struct {
+			Container json.RawMessage `json:"container"`
+			Services  map[string]struct {
+				Image string `json:"image"`
+			} `json:"services"`
+			Steps []struct {
+				Name string `json:"name"`
+				Uses string `json:"uses"`
+			} `json:"steps"`
+		} `json:"jobs"`
+	}
+	if err := kubeyaml.Unmarshal(yamlFile, &workflow); err != nil {
+		return "", nil, fmt.Errorf("unmarshal workflow: %w", err)
+	}
+
+	name := workflow.Name
+	if name == "" {
+		name = filepath.Base(path)
+	}
+
+	var jobNames []string
+	for jobName := range workflow.Jobs {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	var images []containerImage
+	for _, jobName := range jobNames {
+		job := workflow.Jobs[jobName]
+
+		containerImageName, err := getCIImageName(job.Container, "image")
+		if err != nil {
+			return "", nil, fmt.Errorf("get container of job %s: %w", jobName, err)
+		}
+
+		if containerImageName != "" {
+			images = append(images, containerImage{reference: containerImageName, container: jobName})
+		}
+
+		var serviceNames []string
+		for serviceName := range job.Services {
+			serviceNames = append(serviceNames, serviceName)
+		}
+		sort.Strings(serviceNames)
+
+		for _, serviceName := range serviceNames {
+			if image := job.Services[serviceName].Image; image != "" {
+				images = append(images, containerImage{reference: image, container: serviceName})
+			}
+		}
+
+		for _, step := range job.Steps {
+			if !strings.HasPrefix(step.Uses, "docker://") {
+				continue
+			}
+
+			stepName := step.Name
+			if stepName == "" {
+				stepName = jobName
+			}
+
+			images = append(images, containerImage{reference: strings.TrimPrefix(step.Uses, "docker://"), container: stepName})
+		}
+	}
+
+	return name, images, nil
+}
+
+// getGitLabCIImages returns the images and services of the jobs in the .gitlab-ci.yml file, including the
+// default image and services. The container of each image is the name of the job that uses it, or default.
+func getGitLabCIImages(yamlFile []byte) ([]containerImage, error) {
+	type gitLabCIJob struct {
+		Image    json.RawMessage   `json:"image"`
+		Services []json.RawMessage `json:"services"`
+	}
+
+	// The default image and services can also be set at the top level,
+	// which is deprecated in favor of the default section.
+	var topLevelJob gitLabCIJob
+	if err := kubeyaml.Unmarshal(yamlFile, &topLevelJob); err != nil {
+		return nil, fmt.Errorf("unmarshal gitlab ci: %w", err)
+	}
+
+	var config map[string]json.RawMessage
+	if err := kubeyaml.Unmarshal(yamlFile, &config); err != nil {
+		return nil, fmt.Errorf("unmarshal gitlab ci: %w", err)
+	}
+
+	jobs := map[string]gitLabCIJob{}
+	for key, contents := range config {
+		if isGitLabCIKeyword(key) {
+			continue
+		}
+
+		// Keys whose values are not objects (e.g. a YAML anchor with a list) are not jobs.
+		var job gitLabCIJob
+		if err := json.Unmarshal(contents, &job); err != nil {
+			continue
+		}
+
+		jobs[key] = job
+	}
+
+	var jobNames []string
+	for jobName := range jobs {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	var images []containerImage
+	topLevelImages, err := getGitLabCIJobImages(topLevelJob.Image, topLevelJob.Services, "default")
+	if err != nil {
+		return nil, err
+	}
+	images = append(images, topLevelImages...)
+
+	for _, jobName := range jobNames {
+		jobImages, err := getGitLabCIJobImages(jobs[jobName].Image, jobs[jobName].Services, jobName)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, jobImages...)
+	}
+
+	return images, nil
+}
+
+func getGitLabCIJobImages(image json.RawMessage, services []json.RawMessage, jobName string) ([]containerImage, error) {
+	var images []containerImage
+	for _, contents := range append([]json.RawMessage{image}, services...) {
+		imageName, err := getCIImageName(contents, "name")
+		if err != nil {
+			return nil, fmt.Errorf("get image of job %s: %w", jobName, err)
+		}
+
+		if imageName != "" {
+			images = append(images, containerImage{reference: imageName, container: jobName})
+		}
+	}
+
+	return images, nil
+}
+
+// isGitLabCIKeyword returns true when the top level key of a .gitlab-ci.yml file is not a job.
+func isGitLabCIKeyword(key string) bool {
+	for _, keyword := range gitLabCIKeywords {
+		if key == keyword {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getCIImageName returns the image of a CI configuration value that is either the image itself
+// (e.g. image: node:14) or an object that has the image in the given field (e.g. name: node:14).
+func getCIImageName(contents json.RawMessage, field string) (string, error) {
+	if len(contents) == 0 || string(contents) == "null" {
+		return "", nil
+	}
+
+	var image string
+	if err := json.Unmarshal(contents, &image); err == nil {
+		return image, nil
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(contents, &object); err != nil {
+		return "", fmt.Errorf("unmarshal: %w", err)
+	}
+
+	image, _ = object[field].(string)
+	return image, nil
+}
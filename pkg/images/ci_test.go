@@ -0,0 +1,104 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsGitHubWorkflow(t *testing.T) {
+	testCases := map[string]bool{
+		".github/workflows/build.yml":         true,
+		"repo/.github/workflows/release.yaml": true,
+		".github/dependabot.yml":              false,
+		"workflows/build.yml":                 false,
+	}
+
+	for path, expected := range testCases {
+		if actual := isGitHubWorkflow(path); actual != expected {
+			t.Errorf("expected %v for %s, actual %v", expected, path, actual)
+		}
+	}
+}
+
+func TestGetGitHubWorkflowImages(t *testing.T) {
+	workflow := []byte(`name: build
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    container:
+      image: node:14.15.0
+      credentials:
+        username: ${{ github.actor }}
+    services:
+      redis:
+        image: redis:6
+    steps:
+    - uses: actions/checkout@v2
+    - name: lint
+      uses: docker://golangci/golangci-lint:v1.33.0
+  release:
+    runs-on: ubuntu-latest
+    container: golang:1.15`)
+
+	name, actual, err := getGitHubWorkflowImages(".github/workflows/build.yml", workflow)
+	if err != nil {
+		t.Fatal("get github workflow images:", err)
+	}
+
+	if name != "build" {
+		t.Errorf("expected workflow name build, actual %s", name)
+	}
+
+	expected := []containerImage{
+		{reference: "golang:1.15", container: "release"},
+		{reference: "node:14.15.0", container: "test"},
+		{reference: "redis:6", container: "redis"},
+		{reference: "golangci/golangci-lint:v1.33.0", container: "lint"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+}
+
+func TestGetGitLabCIImages(t *testing.T) {
+	gitLabCI := []byte(`image: ruby:2.7
+services:
+- postgres:13
+stages:
+- test
+variables:
+  POSTGRES_DB: test
+.docker: &docker
+  image: docker:19.03
+  services:
+  - name: docker:19.03-dind
+    alias: docker
+test:
+  stage: test
+  script:
+  - bundle exec rake test
+build:
+  <<: *docker
+  script:
+  - docker build .`)
+
+	actual, err := getGitLabCIImages(gitLabCI)
+	if err != nil {
+		t.Fatal("get gitlab ci images:", err)
+	}
+
+	expected := []containerImage{
+		{reference: "ruby:2.7", container: "default"},
+		{reference: "postgres:13", container: "default"},
+		{reference: "docker:19.03", container: ".docker"},
+		{reference: "docker:19.03-dind", container: ".docker"},
+		{reference: "docker:19.03", container: "build"},
+		{reference: "docker:19.03-dind", container: "build"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+}
@@ -0,0 +1,146 @@
+package images
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// clusterKinds are the kinds of resources that are searched for images in a cluster.
+var clusterKinds = []string{"deployments", "statefulsets", "daemonsets", "cronjobs", "pods"}
+
+// Cluster is a Kubernetes cluster to find images in.
+type Cluster struct {
+
+	// Kubeconfig is the path to the kubeconfig file used to connect to the
+	// cluster. When empty, the default kubeconfig of kubectl is used.
+	Kubeconfig string
+
+	// Context is the kubeconfig context of the cluster. When empty, the current context is used.
+	Context string
+
+	// Namespaces are the namespaces to find images in. When empty, all namespaces are searched.
+	Namespaces []string
+}
+
+// FindImagesInCluster returns all of the images used by the workloads running in the cluster.
+// The resources are retrieved with kubectl, which must be available on the PATH.
+func FindImagesInCluster(cluster Cluster, opts ...Option) ([]Image, error) {
+	o := newOptions(opts...)
+
+	documents, err := getClusterDocuments(cluster, o)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := getImagesFromYamlFiles(documents, o)
+	if err != nil {
+		return nil, fmt.Errorf("get images from yaml files: %w", err)
+	}
+
+	return images, nil
+}
+
+func getClusterDocuments(cluster Cluster, o options) ([]document, error) {
+	var documents []document
+	for _, args := range getKubectlArgs(cluster) {
+		contents, err := execute(o.ctx, "kubectl", args...)
+		if err != nil {
+			return nil, fmt.Errorf("kubectl get: %w", err)
+		}
+
+		listDocuments, err := splitResourceList(contents)
+		if err != nil {
+			return nil, fmt.Errorf("split resource list: %w", err)
+		}
+
+		documents = append(documents, listDocuments...)
+	}
+
+	return documents, nil
+}
+
+// clusterScheme is the scheme of the locations of clusters (e.g. cluster://prod/web,monitoring).
+const clusterScheme = "cluster://"
+
+// clusterSource reads the workloads running in a cluster. The location of a cluster is cluster://[context][/namespaces],
+// where the context is the kubeconfig context of the cluster (defaults to the current context) and the namespaces
+// are the comma-separated namespaces to read the workloads of (defaults to all namespaces).
+type clusterSource struct{}
+
+func (clusterSource) Match(location string) bool {
+	return strings.HasPrefix(location, clusterScheme)
+}
+
+func (s clusterSource) Files(ctx context.Context, location string) ([]File, error) {
+	documents, err := s.documents(location, getFilesOptions(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return getDocumentFiles(documents), nil
+}
+
+func (clusterSource) documents(location string, o options) ([]document, error) {
+	return getClusterDocuments(parseClusterLocation(location), o)
+}
+
+// parseClusterLocation returns the cluster at the location (e.g. cluster://prod/web,monitoring).
+func parseClusterLocation(location string) Cluster {
+	tokens := strings.SplitN(strings.TrimPrefix(location, clusterScheme), "/", 2)
+
+	cluster := Cluster{Context: tokens[0]}
+	if len(tokens) == 2 && tokens[1] != "" {
+		cluster.Namespaces = strings.Split(tokens[1], ",")
+	}
+
+	return cluster
+}
+
+func getKubectlArgs(cluster Cluster) [][]string {
+	return getKubectlArgsForKinds(cluster, clusterKinds)
+}
+
+// getKubectlArgsForKinds returns the arguments of kubectl to get the resources of the kinds in each namespace of the cluster.
+func getKubectlArgsForKinds(cluster Cluster, kinds []string) [][]string {
+	args := []string{"get", strings.Join(kinds, ","), "--output", "json"}
+	if cluster.Kubeconfig != "" {
+		args = append(args, "--kubeconfig", cluster.Kubeconfig)
+	}
+
+	if cluster.Context != "" {
+		args = append(args, "--context", cluster.Context)
+	}
+
+	if len(cluster.Namespaces) == 0 {
+		return [][]string{append(args, "--all-namespaces")}
+	}
+
+	var namespaceArgs [][]string
+	for _, namespace := range cluster.Namespaces {
+		currentArgs := append([]string{}, args...)
+		namespaceArgs = append(namespaceArgs, append(currentArgs, "--namespace", namespace))
+	}
+
+	return namespaceArgs
+}
+
+// splitResourceList splits the items of a List returned by kubectl into documents.
+// Each item is JSON, which is also valid YAML.
+func splitResourceList(contents []byte) ([]document, error) {
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+
+	if err := json.Unmarshal(contents, &list); err != nil {
+		return nil, fmt.Errorf("unmarshal list: %w", err)
+	}
+
+	var documents []document
+	for _, item := range list.Items {
+		documents = append(documents, document{path: "cluster", contents: item})
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,61 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetKubectlArgs(t *testing.T) {
+	cluster := Cluster{
+		Context:    "prod",
+		Namespaces: []string{"web", "monitoring"},
+	}
+
+	actual := getKubectlArgs(cluster)
+	expected := [][]string{
+		{"get", "deployments,statefulsets,daemonsets,cronjobs,pods", "--output", "json", "--context", "prod", "--namespace", "web"},
+		{"get", "deployments,statefulsets,daemonsets,cronjobs,pods", "--output", "json", "--context", "prod", "--namespace", "monitoring"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected args %v, actual %v", expected, actual)
+	}
+}
+
+func TestSplitResourceList(t *testing.T) {
+	list := `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {
+      "apiVersion": "v1",
+      "kind": "Pod",
+      "metadata": {"name": "busybox", "namespace": "web"},
+      "spec": {"containers": [{"name": "busybox", "image": "busybox:1.32.0"}]}
+    }
+  ]
+}`
+
+	documents, err := splitResourceList([]byte(list))
+	if err != nil {
+		t.Fatal("split resource list:", err)
+	}
+
+	actual, err := getImagesFromYamlFiles(documents, options{})
+	if err != nil {
+		t.Fatal("get images from yaml files:", err)
+	}
+
+	expected := []Image{
+		{
+			Reference:  "busybox:1.32.0",
+			Repository: "busybox",
+			Tag:        "1.32.0",
+			Resources:  []Resource{{Path: "cluster", Kind: "Pod", Name: "busybox", Namespace: "web", Container: "busybox"}},
+		},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+}
@@ -0,0 +1,91 @@
+package images
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	kubeyaml "github.com/ghodss/yaml"
+)
+
+// composeKind is the kind of the resources that are found in Docker Compose files.
+const composeKind = "Compose"
+
+// composeFilePattern matches the names of Docker Compose files, including
+// override files (e.g. docker-compose.yml, compose.yaml or docker-compose.prod.yml).
+var composeFilePattern = regexp.MustCompile(`^(docker-)?compose(\.[\w.-]+)?\.ya?ml$`)
+
+// composeVariablePattern matches the variables that Docker Compose interpolates
+// (e.g. $TAG, ${TAG}, ${TAG:-latest} or ${TAG-latest}).
+var composeVariablePattern = regexp.MustCompile(`\$(?:(\w+)|\{(\w+)(?:(:?-)([^}]*))?\})`)
+
+func isComposeFile(path string) bool {
+	return composeFilePattern.MatchString(filepath.Base(path))
+}
+
+// getComposeImages returns the name of the Compose project and the images of its services. The
+// project is named after the directory of the Compose file when the file does not set a name.
+func getComposeImages(path string, yamlFile []byte) (string, []containerImage, error) {
+	var compose struct {
+		Name     string `json:"name"`
+		Services map[string]struct {
+			Image string `json:"image"`
+		} `json:"services"`
+	}
+	if err := kubeyaml.Unmarshal(yamlFile, &compose); err != nil {
+		return "", nil, fmt.Errorf("unmarshal compose file: %w", err)
+	}
+
+	name := compose.Name
+	if name == "" {
+		absolutePath, err := filepath.Abs(path)
+		if err != nil {
+			return "", nil, fmt.Errorf("absolute path: %w", err)
+		}
+
+		name = filepath.Base(filepath.Dir(absolutePath))
+	}
+
+	var services []string
+	for service := range compose.Services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+
+	// Services that are only built from a Dockerfile do not have an image.
+	var images []containerImage
+	for _, service := range services {
+		image := interpolateComposeVariables(compose.Services[service].Image)
+		if image == "" {
+			continue
+		}
+
+		images = append(images, containerImage{reference: image, container: service})
+	}
+
+	return name, images, nil
+}
+
+// interpolateComposeVariables replaces the variables in the value with their values
+// in the environment, or their defaults when they are not set, as Docker Compose does.
+func interpolateComposeVariables(value string) string {
+	return composeVariablePattern.ReplaceAllStringFunc(value, func(variable string) string {
+		match := composeVariablePattern.FindStringSubmatch(variable)
+
+		name := match[1] + match[2]
+		operator := match[3]
+		defaultValue := match[4]
+
+		environmentValue, set := os.LookupEnv(name)
+		switch {
+		case operator == ":-" && environmentValue == "":
+			return defaultValue
+		case operator == "-" && !set:
+			return defaultValue
+		}
+
+		return environmentValue
+	})
+}
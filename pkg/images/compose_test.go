@@ -0,0 +1,55 @@
+package images
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIsComposeFile(t *testing.T) {
+	testCases := map[string]bool{
+		"edge/docker-compose.yml":      true,
+		"compose.yaml":                 true,
+		"docker-compose.override.yaml": true,
+		"deployment.yaml":              false,
+		"composer.yaml":                false,
+	}
+
+	for path, expected := range testCases {
+		if actual := isComposeFile(path); actual != expected {
+			t.Errorf("expected %v for %s, actual %v", expected, path, actual)
+		}
+	}
+}
+
+func TestGetComposeImages(t *testing.T) {
+	os.Setenv("SINKER_TEST_REGISTRY", "mycompany.com")
+	defer os.Unsetenv("SINKER_TEST_REGISTRY")
+
+	compose := []byte(`name: edge
+services:
+  web:
+    image: ${SINKER_TEST_REGISTRY}/web:${SINKER_TEST_TAG:-v1.0.0}
+  cache:
+    image: redis:6
+  worker:
+    build: ./worker`)
+
+	name, actual, err := getComposeImages("docker-compose.yml", compose)
+	if err != nil {
+		t.Fatal("get compose images:", err)
+	}
+
+	if name != "edge" {
+		t.Errorf("expected project name edge, actual %s", name)
+	}
+
+	expected := []containerImage{
+		{reference: "redis:6", container: "cache"},
+		{reference: "mycompany.com/web:v1.0.0", container: "web"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+}
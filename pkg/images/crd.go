@@ -0,0 +1,148 @@
+package images
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	kubeyaml "github.com/ghodss/yaml"
+)
+
+// CRDImagePath is the location of an image inside of a custom resource.
+type CRDImagePath struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Path is a JSONPath expression to the image (e.g. spec.image or {.spec.containers[*].image}).
+	Path string `json:"path"`
+}
+
+// GetCRDImagePaths returns the custom resource image paths defined in the file at the specified path.
+func GetCRDImagePaths(path string) ([]CRDImagePath, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+
+	var crdConfig struct {
+		CRDs []CRDImagePath `json:"crds"`
+	}
+	if err := kubeyaml.Unmarshal(contents, &crdConfig); err != nil {
+		return nil, fmt.Errorf("unmarshal crd config: %w", err)
+	}
+
+	for _, crd := range crdConfig.CRDs {
+		if crd.Kind == "" || crd.Path == "" {
+			return nil, fmt.Errorf("crd image paths require a kind and a path")
+		}
+	}
+
+	return crdConfig.CRDs, nil
+}
+
+func getCRDImagePaths(crdImagePaths []CRDImagePath, apiVersion string, kind string) []string {
+	var paths []string
+	for _, crdImagePath := range crdImagePaths {
+		if crdImagePath.Kind != kind {
+			continue
+		}
+
+		if crdImagePath.APIVersion != "" && crdImagePath.APIVersion != apiVersion {
+			continue
+		}
+
+		paths = append(paths, crdImagePath.Path)
+	}
+
+	return paths
+}
+
+func getImagesFromCustomResource(yamlFile []byte, paths []string) ([]string, error) {
+	var resource interface{}
+	if err := kubeyaml.Unmarshal(yamlFile, &resource); err != nil {
+		return nil, fmt.Errorf("unmarshal custom resource: %w", err)
+	}
+
+	var images []string
+	for _, path := range paths {
+		for _, value := range evaluatePath(resource, parsePath(path)) {
+			image, ok := value.(string)
+			if !ok || image == "" {
+				continue
+			}
+
+			images = append(images, image)
+		}
+	}
+
+	return images, nil
+}
+
+// parsePath splits a simple JSONPath expression into its segments. A segment of * selects
+// every element of a list or map. For example, {.spec.containers[*].image} is parsed into
+// spec, containers, * and image.
+func parsePath(path string) []string {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "{")
+	path = strings.TrimSuffix(path, "}")
+	path = strings.TrimPrefix(path, "$")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment == "" {
+			continue
+		}
+
+		segments = append(segments, segment)
+	}
+
+	return segments
+}
+
+func evaluatePath(value interface{}, segments []string) []interface{} {
+	if len(segments) == 0 {
+		return []interface{}{value}
+	}
+
+	segment := segments[0]
+	remaining := segments[1:]
+
+	var results []interface{}
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		if segment == "*" {
+			for _, child := range typedValue {
+				results = append(results, evaluatePath(child, remaining)...)
+			}
+
+			return results
+		}
+
+		child, exists := typedValue[segment]
+		if !exists {
+			return nil
+		}
+
+		return evaluatePath(child, remaining)
+
+	case []interface{}:
+		if segment == "*" {
+			for _, child := range typedValue {
+				results = append(results, evaluatePath(child, remaining)...)
+			}
+
+			return results
+		}
+
+		var index int
+		if _, err := fmt.Sscanf(segment, "%d", &index); err != nil || index < 0 || index >= len(typedValue) {
+			return nil
+		}
+
+		return evaluatePath(typedValue[index], remaining)
+	}
+
+	return nil
+}
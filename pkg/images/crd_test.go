@@ -0,0 +1,48 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetImagesFromCustomResource(t *testing.T) {
+	yamlFile := []byte(`
+apiVersion: elasticsearch.k8s.elastic.co/v1
+kind: Elasticsearch
+spec:
+  image: docker.elastic.co/elasticsearch/elasticsearch:7.8.0
+  nodeSets:
+  - podTemplate:
+      spec:
+        containers:
+        - image: busybox:1.32.0
+        - image: busybox:1.31.0`)
+
+	crdImagePaths := []CRDImagePath{
+		{
+			Kind: "Elasticsearch",
+			Path: "spec.image",
+		},
+		{
+			Kind: "Elasticsearch",
+			Path: "{.spec.nodeSets[*].podTemplate.spec.containers[1].image}",
+		},
+		{
+			Kind:       "Elasticsearch",
+			APIVersion: "elasticsearch.k8s.elastic.co/v1beta1",
+			Path:       "spec.nodeSets[*].podTemplate.spec.containers[0].image",
+		},
+	}
+
+	paths := getCRDImagePaths(crdImagePaths, "elasticsearch.k8s.elastic.co/v1", "Elasticsearch")
+
+	actual, err := getImagesFromCustomResource(yamlFile, paths)
+	if err != nil {
+		t.Fatal("get images from custom resource:", err)
+	}
+
+	expected := []string{"docker.elastic.co/elasticsearch/elasticsearch:7.8.0", "busybox:1.31.0"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,43 @@
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	kubeyaml "github.com/ghodss/yaml"
+)
+
+// isCrossplaneResource returns true for the Crossplane packages (Provider, Configuration and Function),
+// which are OCI images that Crossplane installs, and for the ControllerConfig resources that override
+// the image of the controller of a provider.
+func isCrossplaneResource(apiVersion string, kind string) bool {
+	if !strings.HasPrefix(apiVersion, "pkg.crossplane.io/") {
+		return false
+	}
+
+	return kind == "Provider" || kind == "Configuration" || kind == "Function" || kind == "ControllerConfig"
+}
+
+// getCrossplaneImages returns the package of a Crossplane package or the image of a ControllerConfig.
+func getCrossplaneImages(yamlFile []byte, kind string) ([]containerImage, error) {
+	var resource struct {
+		Spec struct {
+			Package string `json:"package"`
+			Image   string `json:"image"`
+		} `json:"spec"`
+	}
+	if err := kubeyaml.Unmarshal(yamlFile, &resource); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", strings.ToLower(kind), err)
+	}
+
+	image := resource.Spec.Package
+	if kind == "ControllerConfig" {
+		image = resource.Spec.Image
+	}
+
+	if image == "" {
+		return nil, nil
+	}
+
+	return []containerImage{{reference: image}}, nil
+}
@@ -0,0 +1,53 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetImagesFromYamlFile_Crossplane(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		yamlFile string
+		expected []containerImage
+	}{
+		{
+			kind: "Provider",
+			yamlFile: `
+apiVersion: pkg.crossplane.io/v1
+kind: Provider
+spec:
+  package: xpkg.upbound.io/crossplane-contrib/provider-aws:v0.33.0`,
+			expected: []containerImage{{reference: "xpkg.upbound.io/crossplane-contrib/provider-aws:v0.33.0"}},
+		},
+		{
+			kind: "Configuration",
+			yamlFile: `
+apiVersion: pkg.crossplane.io/v1
+kind: Configuration
+spec:
+  package: registry.upbound.io/xp/platform-ref-aws:v0.6.0`,
+			expected: []containerImage{{reference: "registry.upbound.io/xp/platform-ref-aws:v0.6.0"}},
+		},
+		{
+			kind: "ControllerConfig",
+			yamlFile: `
+apiVersion: pkg.crossplane.io/v1alpha1
+kind: ControllerConfig
+spec:
+  image: mycompany.com/crossplane/provider-aws-controller:v0.33.0`,
+			expected: []containerImage{{reference: "mycompany.com/crossplane/provider-aws-controller:v0.33.0"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		images, err := getImagesFromYamlFile([]byte(testCase.yamlFile), options{})
+		if err != nil {
+			t.Fatalf("get images from %s: %v", testCase.kind, err)
+		}
+
+		if !reflect.DeepEqual(images, testCase.expected) {
+			t.Errorf("expected %s images %v, actual %v", testCase.kind, testCase.expected, images)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package images
+
+import (
+	"encoding/json"
+	"sort"
+
+	kubeyaml "github.com/ghodss/yaml"
+)
+
+// getDeepScanImages returns every string value in the YAML document that looks like an image
+// reference and is not one of the known images that were already found in the document.
+func getDeepScanImages(yamlFile []byte, knownImages []containerImage) []containerImage {
+	jsonContents, err := kubeyaml.YAMLToJSON(yamlFile)
+	if err != nil {
+		return nil
+	}
+
+	var contents interface{}
+	if err := json.Unmarshal(jsonContents, &contents); err != nil {
+		return nil
+	}
+
+	found := make(map[string]bool)
+	for _, image := range knownImages {
+		found[image.reference] = true
+	}
+
+	var images []containerImage
+	for _, value := range getStringValues(contents) {
+		for _, token := range referenceTokenPattern.FindAllString(value, -1) {
+			if found[token] || !referencePattern.MatchString(token) {
+				continue
+			}
+
+			found[token] = true
+			images = append(images, containerImage{reference: token, heuristic: true})
+		}
+	}
+
+	return images
+}
+
+// getStringValues returns all of the string values in the unmarshaled JSON, excluding the keys of objects.
+func getStringValues(contents interface{}) []string {
+	var values []string
+	switch value := contents.(type) {
+	case string:
+		values = append(values, value)
+
+	case []interface{}:
+		for _, element := range value {
+			values = append(values, getStringValues(element)...)
+		}
+
+	case map[string]interface{}:
+		var keys []string
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			values = append(values, getStringValues(value[key])...)
+		}
+	}
+
+	return values
+}
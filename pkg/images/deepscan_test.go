@@ -0,0 +1,34 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDeepScanImages(t *testing.T) {
+	yamlFile := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: operator-config
+data:
+  app: web:frontend
+  config.yaml: |
+    relatedImages:
+    - image: quay.io/coreos/prometheus-operator:v0.40.0
+    - url: https://example.com/path
+  reloader: --image=jimmidyson/configmap-reload:v0.3.0
+  known: mycompany.com/known/image:v1.0.0`
+
+	knownImages := []containerImage{{reference: "mycompany.com/known/image:v1.0.0"}}
+	actual := getDeepScanImages([]byte(yamlFile), knownImages)
+
+	expected := []containerImage{
+		{reference: "quay.io/coreos/prometheus-operator:v0.40.0", heuristic: true},
+		{reference: "jimmidyson/configmap-reload:v0.3.0", heuristic: true},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+}
@@ -0,0 +1,425 @@
+package images
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	kubeyaml "github.com/ghodss/yaml"
+)
+
+// maxSourceDepth is the number of delivery resources that are followed from the original
+// resources (e.g. an Argo CD Application that deploys other Applications), which prevents
+// resources that deploy each other from being followed forever.
+const maxSourceDepth = 3
+
+// deliverySource is the source of the resources that a delivery resource (e.g. an Argo CD
+// Application or a Flux Kustomization) deploys.
+type deliverySource struct {
+
+	// gitURL is the Git URL, in the form accepted by FindImages, of the directory that
+	// contains the resources when the resources are stored in a Git repository.
+	gitURL string
+
+	// chart is the name of the chart, or the OCI reference of the chart, when the
+	// resources are rendered from a chart in a Helm repository.
+	chart      string
+	repository string
+	version    string
+
+	// values are the Helm values, as YAML, to render the chart with.
+	values []byte
+}
+
+type deliveryResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+type argoApplicationSource struct {
+	RepoURL        string `json:"repoURL"`
+	Path           string `json:"path"`
+	TargetRevision string `json:"targetRevision"`
+	Chart          string `json:"chart"`
+	Helm           struct {
+		Values string `json:"values"`
+	} `json:"helm"`
+}
+
+type argoApplication struct {
+	Spec struct {
+		Source  *argoApplicationSource  `json:"source"`
+		Sources []argoApplicationSource `json:"sources"`
+	} `json:"spec"`
+}
+
+type fluxSourceReference struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+type fluxHelmRelease struct {
+	Spec struct {
+		Chart struct {
+			Spec struct {
+				Chart     string              `json:"chart"`
+				Version   string              `json:"version"`
+				SourceRef fluxSourceReference `json:"sourceRef"`
+			} `json:"spec"`
+		} `json:"chart"`
+		Values interface{} `json:"values"`
+	} `json:"spec"`
+}
+
+type fluxKustomization struct {
+	Spec struct {
+		Path      string              `json:"path"`
+		SourceRef fluxSourceReference `json:"sourceRef"`
+	} `json:"spec"`
+}
+
+type fluxGitRepository struct {
+	Spec struct {
+		URL string `json:"url"`
+		Ref struct {
+			Branch string `json:"branch"`
+			Tag    string `json:"tag"`
+			Commit string `json:"commit"`
+		} `json:"ref"`
+	} `json:"spec"`
+}
+
+type fluxHelmRepository struct {
+	Spec struct {
+		URL  string `json:"url"`
+		Type string `json:"type"`
+	} `json:"spec"`
+}
+
+// getDeliveryImages returns the images that are deployed by the Argo CD Applications and the
+// Flux HelmReleases and Kustomizations in the documents. The Git repositories and Helm charts
+// that the resources reference are fetched to find the images in them.
+func getDeliveryImages(documents []document, o options) ([]Image, error) {
+	if o.sourceDepth >= maxSourceDepth {
+		return nil, nil
+	}
+
+	var images []Image
+	for _, document := range documents {
+		sources, err := getDeliverySources(document.contents, documents)
+		if err != nil {
+			return nil, fmt.Errorf("get delivery sources: %w", err)
+		}
+
+		for _, source := range sources {
+			sourceImages, err := getDeliverySourceImages(source, o)
+			if err != nil {
+				return nil, fmt.Errorf("get images from %s: %w", source, err)
+			}
+
+			// The resources that were fetched are removed once the images are found,
+			// so the images are reported as being deployed by the delivery resource.
+			for _, sourceImage := range sourceImages {
+				for r := range sourceImage.Resources {
+					sourceImage.Resources[r].Path = document.path
+				}
+
+				images = mergeImages(images, sourceImage)
+			}
+		}
+	}
+
+	return images, nil
+}
+
+func getDeliverySourceImages(source deliverySource, o options) ([]Image, error) {
+	// Argo CD and Flux both render any Helm charts and build any kustomizations
+	// that they find in the source, so sinker does the same.
+	sourceOptions := o
+	sourceOptions.helm = true
+	sourceOptions.helmValues = nil
+	sourceOptions.kustomize = true
+	sourceOptions.sourceDepth++
+
+	var valuesFile string
+	if len(source.values) > 0 {
+		file, err := ioutil.TempFile("", "sinker-values-*.yaml")
+		if err != nil {
+			return nil, fmt.Errorf("create values file: %w", err)
+		}
+		defer os.Remove(file.Name())
+
+		if _, err := file.Write(source.values); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("write values file: %w", err)
+		}
+
+		if err := file.Close(); err != nil {
+			return nil, fmt.Errorf("close values file: %w", err)
+		}
+
+		valuesFile = file.Name()
+		sourceOptions.helmValues = []string{valuesFile}
+	}
+
+	if source.gitURL != "" {
+		images, err := findImages(source.gitURL, sourceOptions)
+		if err != nil {
+			return nil, fmt.Errorf("find images: %w", err)
+		}
+
+		return images, nil
+	}
+
+	chart, err := getHelmChart(source.chart, source.repository, source.version, o)
+	if err != nil {
+		return nil, fmt.Errorf("get helm chart %s: %w", source.chart, err)
+	}
+
+	// A chart that was pulled into the cache is rendered from its archive.
+	args := []string{"template", chart}
+	if chart == source.chart && source.repository != "" {
+		args = append(args, "--repo", source.repository)
+	}
+	if chart == source.chart && source.version != "" {
+		args = append(args, "--version", source.version)
+	}
+	if valuesFile != "" {
+		args = append(args, "--values", valuesFile)
+	}
+
+	renderedChart, err := execute(o.ctx, "helm", args...)
+	if err != nil {
+		return nil, fmt.Errorf("helm template %s: %w", source.chart, err)
+	}
+
+	documents, err := newDocuments(source.chart, renderedChart, sourceOptions)
+	if err != nil {
+		return nil, fmt.Errorf("split rendered helm chart: %w", err)
+	}
+
+	images, err := getImagesFromYamlFiles(documents, sourceOptions)
+	if err != nil {
+		return nil, fmt.Errorf("get images from yaml files: %w", err)
+	}
+
+	return images, nil
+}
+
+// getDeliverySources returns the sources of the resources that are deployed by the delivery resource
+// in the YAML file. The sources of Flux resources are looked up in the other documents. When the source
+// of a Flux resource is not one of the documents, the resource is skipped.
+func getDeliverySources(yamlFile []byte, documents []document) ([]deliverySource, error) {
+	var resource deliveryResource
+	if err := kubeyaml.Unmarshal(yamlFile, &resource); err != nil {
+		return nil, nil
+	}
+
+	group := strings.Split(resource.APIVersion, "/")[0]
+	switch {
+	case group == "argoproj.io" && resource.Kind == "Application":
+		var application argoApplication
+		if err := kubeyaml.Unmarshal(yamlFile, &application); err != nil {
+			return nil, fmt.Errorf("unmarshal application: %w", err)
+		}
+
+		applicationSources := application.Spec.Sources
+		if application.Spec.Source != nil {
+			applicationSources = append(applicationSources, *application.Spec.Source)
+		}
+
+		var sources []deliverySource
+		for _, applicationSource := range applicationSources {
+			sources = append(sources, getArgoApplicationSource(applicationSource))
+		}
+
+		return sources, nil
+
+	case group == "helm.toolkit.fluxcd.io" && resource.Kind == "HelmRelease":
+		var helmRelease fluxHelmRelease
+		if err := kubeyaml.Unmarshal(yamlFile, &helmRelease); err != nil {
+			return nil, fmt.Errorf("unmarshal helm release: %w", err)
+		}
+
+		chartSpec := helmRelease.Spec.Chart.Spec
+		sourceContents, found := findFluxSource(documents, chartSpec.SourceRef, resource.Metadata.Namespace)
+		if !found {
+			return nil, nil
+		}
+
+		var source deliverySource
+		if helmRelease.Spec.Values != nil {
+			values, err := kubeyaml.Marshal(helmRelease.Spec.Values)
+			if err != nil {
+				return nil, fmt.Errorf("marshal values: %w", err)
+			}
+
+			source.values = values
+		}
+
+		switch chartSpec.SourceRef.Kind {
+		case "HelmRepository":
+			var helmRepository fluxHelmRepository
+			if err := kubeyaml.Unmarshal(sourceContents, &helmRepository); err != nil {
+				return nil, fmt.Errorf("unmarshal helm repository: %w", err)
+			}
+
+			source.version = chartSpec.Version
+			if helmRepository.Spec.Type == "oci" || strings.HasPrefix(helmRepository.Spec.URL, "oci://") {
+				source.chart = strings.TrimSuffix(helmRepository.Spec.URL, "/") + "/" + chartSpec.Chart
+			} else {
+				source.chart = chartSpec.Chart
+				source.repository = helmRepository.Spec.URL
+			}
+
+		case "GitRepository":
+			var gitRepository fluxGitRepository
+			if err := kubeyaml.Unmarshal(sourceContents, &gitRepository); err != nil {
+				return nil, fmt.Errorf("unmarshal git repository: %w", err)
+			}
+
+			source.gitURL = getFluxGitURL(gitRepository, chartSpec.Chart)
+		}
+
+		return []deliverySource{source}, nil
+
+	case group == "kustomize.toolkit.fluxcd.io" && resource.Kind == "Kustomization":
+		var kustomization fluxKustomization
+		if err := kubeyaml.Unmarshal(yamlFile, &kustomization); err != nil {
+			return nil, fmt.Errorf("unmarshal kustomization: %w", err)
+		}
+
+		if kustomization.Spec.SourceRef.Kind != "GitRepository" {
+			return nil, nil
+		}
+
+		sourceContents, found := findFluxSource(documents, kustomization.Spec.SourceRef, resource.Metadata.Namespace)
+		if !found {
+			return nil, nil
+		}
+
+		var gitRepository fluxGitRepository
+		if err := kubeyaml.Unmarshal(sourceContents, &gitRepository); err != nil {
+			return nil, fmt.Errorf("unmarshal git repository: %w", err)
+		}
+
+		return []deliverySource{{gitURL: getFluxGitURL(gitRepository, kustomization.Spec.Path)}}, nil
+	}
+
+	return nil, nil
+}
+
+func getArgoApplicationSource(applicationSource argoApplicationSource) deliverySource {
+	var values []byte
+	if applicationSource.Helm.Values != "" {
+		values = []byte(applicationSource.Helm.Values)
+	}
+
+	if applicationSource.Chart == "" {
+		ref := applicationSource.TargetRevision
+		if ref == "HEAD" {
+			ref = ""
+		}
+
+		return deliverySource{
+			gitURL: getGitURL(applicationSource.RepoURL, applicationSource.Path, ref),
+			values: values,
+		}
+	}
+
+	// Helm repositories that are OCI registries are referenced without a scheme.
+	if !strings.Contains(applicationSource.RepoURL, "://") {
+		return deliverySource{
+			chart:   "oci://" + strings.TrimSuffix(applicationSource.RepoURL, "/") + "/" + applicationSource.Chart,
+			version: applicationSource.TargetRevision,
+			values:  values,
+		}
+	}
+
+	return deliverySource{
+		chart:      applicationSource.Chart,
+		repository: applicationSource.RepoURL,
+		version:    applicationSource.TargetRevision,
+		values:     values,
+	}
+}
+
+// findFluxSource returns the document of the Flux source that is referenced by the source reference.
+// When the reference does not include a namespace, the source is in the namespace of the resource.
+func findFluxSource(documents []document, sourceRef fluxSourceReference, namespace string) ([]byte, bool) {
+	if sourceRef.Namespace != "" {
+		namespace = sourceRef.Namespace
+	}
+
+	for _, document := range documents {
+		var resource deliveryResource
+		if err := kubeyaml.Unmarshal(document.contents, &resource); err != nil {
+			continue
+		}
+
+		if !strings.HasPrefix(resource.APIVersion, "source.toolkit.fluxcd.io/") {
+			continue
+		}
+
+		if resource.Kind == sourceRef.Kind && resource.Metadata.Name == sourceRef.Name && resource.Metadata.Namespace == namespace {
+			return document.contents, true
+		}
+	}
+
+	return nil, false
+}
+
+func getFluxGitURL(gitRepository fluxGitRepository, path string) string {
+	ref := gitRepository.Spec.Ref.Branch
+	if gitRepository.Spec.Ref.Tag != "" {
+		ref = gitRepository.Spec.Ref.Tag
+	}
+	if gitRepository.Spec.Ref.Commit != "" {
+		ref = gitRepository.Spec.Ref.Commit
+	}
+
+	return getGitURL(gitRepository.Spec.URL, path, ref)
+}
+
+// getGitURL returns the Git URL, in the form of <repository>[//<subdir>][?ref=<ref>], of the directory in the repository.
+func getGitURL(repository string, path string, ref string) string {
+	gitURL := repository
+	if subdir := strings.Trim(strings.TrimPrefix(path, "./"), "/"); subdir != "" && subdir != "." {
+		gitURL += "//" + subdir
+	}
+
+	if ref != "" {
+		gitURL += "?ref=" + ref
+	}
+
+	return gitURL
+}
+
+func (s deliverySource) String() string {
+	if s.gitURL != "" {
+		return s.gitURL
+	}
+
+	if s.version != "" {
+		return s.chart + "@" + s.version
+	}
+
+	return s.chart
+}
+
+// mergeImages adds the image to the images, or adds the resources of the image
+// to the existing image when the image has already been found.
+func mergeImages(images []Image, image Image) []Image {
+	if i := indexOf(images, image.Reference); i >= 0 {
+		images[i].Resources = append(images[i].Resources, image.Resources...)
+		return images
+	}
+
+	return append(images, image)
+}
@@ -0,0 +1,128 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDeliverySources(t *testing.T) {
+	gitRepository := []byte(`apiVersion: source.toolkit.fluxcd.io/v1beta1
+kind: GitRepository
+metadata:
+  name: apps
+  namespace: flux-system
+spec:
+  url: https://github.com/org/apps
+  ref:
+    tag: v1.2.0`)
+
+	helmRepository := []byte(`apiVersion: source.toolkit.fluxcd.io/v1beta1
+kind: HelmRepository
+metadata:
+  name: prometheus-community
+  namespace: flux-system
+spec:
+  url: https://prometheus-community.github.io/helm-charts`)
+
+	documents := []document{
+		{path: "git.yaml", contents: gitRepository},
+		{path: "helm.yaml", contents: helmRepository},
+	}
+
+	testCases := []struct {
+		name     string
+		yamlFile string
+		expected []deliverySource
+	}{
+		{
+			"argo git source",
+			`apiVersion: argoproj.io/v1alpha1
+kind: Application
+spec:
+  source:
+    repoURL: https://github.com/org/apps.git
+    path: ./guestbook
+    targetRevision: HEAD`,
+			[]deliverySource{{gitURL: "https://github.com/org/apps.git//guestbook"}},
+		},
+		{
+			"argo helm source",
+			`apiVersion: argoproj.io/v1alpha1
+kind: Application
+spec:
+  source:
+    repoURL: https://charts.example.com
+    chart: redis
+    targetRevision: 1.0.0
+    helm:
+      values: |
+        replicas: 2`,
+			[]deliverySource{{chart: "redis", repository: "https://charts.example.com", version: "1.0.0", values: []byte("replicas: 2")}},
+		},
+		{
+			"argo oci helm source",
+			`apiVersion: argoproj.io/v1alpha1
+kind: Application
+spec:
+  source:
+    repoURL: ghcr.io/org/charts
+    chart: redis
+    targetRevision: 1.0.0`,
+			[]deliverySource{{chart: "oci://ghcr.io/org/charts/redis", version: "1.0.0"}},
+		},
+		{
+			"flux helm release",
+			`apiVersion: helm.toolkit.fluxcd.io/v2beta1
+kind: HelmRelease
+metadata:
+  namespace: monitoring
+spec:
+  chart:
+    spec:
+      chart: kube-prometheus-stack
+      version: 10.1.0
+      sourceRef:
+        kind: HelmRepository
+        name: prometheus-community
+        namespace: flux-system`,
+			[]deliverySource{{chart: "kube-prometheus-stack", repository: "https://prometheus-community.github.io/helm-charts", version: "10.1.0"}},
+		},
+		{
+			"flux kustomization",
+			`apiVersion: kustomize.toolkit.fluxcd.io/v1beta1
+kind: Kustomization
+metadata:
+  namespace: flux-system
+spec:
+  path: ./deploy/production
+  sourceRef:
+    kind: GitRepository
+    name: apps`,
+			[]deliverySource{{gitURL: "https://github.com/org/apps//deploy/production?ref=v1.2.0"}},
+		},
+		{
+			"flux kustomization with unknown source",
+			`apiVersion: kustomize.toolkit.fluxcd.io/v1beta1
+kind: Kustomization
+metadata:
+  namespace: default
+spec:
+  path: ./deploy
+  sourceRef:
+    kind: GitRepository
+    name: apps`,
+			nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := getDeliverySources([]byte(testCase.yamlFile), documents)
+		if err != nil {
+			t.Fatalf("get delivery sources for %s: %v", testCase.name, err)
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected sources %#v for %s, actual %#v", testCase.expected, testCase.name, actual)
+		}
+	}
+}
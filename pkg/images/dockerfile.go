@@ -0,0 +1,177 @@
+package images
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// dockerfileKind is the kind of the resources that are found in Dockerfiles.
+const dockerfileKind = "Dockerfile"
+
+// dockerfileArgPattern matches the build arguments that are expanded in FROM
+// instructions (e.g. $VERSION, ${VERSION}, ${VERSION:-1.15} or ${VERSION:+1.15}).
+var dockerfileArgPattern = regexp.MustCompile(`\$(?:(\w+)|\{(\w+)(?::([-+])([^}]*))?\})`)
+
+// dockerfileEscapePattern matches the parser directive that changes the escape character.
+var dockerfileEscapePattern = regexp.MustCompile("(?i)^#\\s*escape\\s*=\\s*([\\\\`])\\s*$")
+
+// isDockerfile returns true when the file is a Dockerfile (e.g. Dockerfile, Dockerfile.prod or build.Dockerfile).
+func isDockerfile(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return name == "dockerfile" || strings.HasPrefix(name, "dockerfile.") || strings.HasSuffix(name, ".dockerfile")
+}
+
+func getDockerfiles(path string, o options) ([]string, error) {
+	var dockerfiles []string
+	err := walk(path, o, func(currentFilePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if fileInfo.IsDir() || !isDockerfile(currentFilePath) {
+			return nil
+		}
+
+		dockerfiles = append(dockerfiles, currentFilePath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dockerfiles, nil
+}
+
+// getDockerfileImages returns the base images of the stages in the Dockerfile. Stages that are
+// based on an earlier stage or on scratch do not have an image. The build arguments that are
+// declared before the first stage are expanded using their default values.
+func getDockerfileImages(dockerfile []byte) []containerImage {
+	args := make(map[string]string)
+	stages := make(map[string]bool)
+	var inStage bool
+
+	var images []containerImage
+	for _, instruction := range getDockerfileInstructions(string(dockerfile)) {
+		fields := strings.Fields(instruction)
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			// Only the build arguments declared before the first stage can be used in FROM instructions.
+			if inStage {
+				continue
+			}
+
+			for _, arg := range fields[1:] {
+				nameValue := strings.SplitN(arg, "=", 2)
+				if len(nameValue) == 2 {
+					args[nameValue[0]] = strings.Trim(nameValue[1], `"'`)
+				} else {
+					args[nameValue[0]] = ""
+				}
+			}
+
+		case "FROM":
+			inStage = true
+
+			var from []string
+			for _, field := range fields[1:] {
+				if !strings.HasPrefix(field, "--") {
+					from = append(from, field)
+				}
+			}
+
+			if len(from) == 0 {
+				continue
+			}
+
+			var stage string
+			if len(from) >= 3 && strings.EqualFold(from[1], "as") {
+				stage = strings.ToLower(from[2])
+			}
+
+			image := expandDockerfileArgs(from[0], args)
+			isStage := stages[strings.ToLower(image)]
+			if stage != "" {
+				stages[stage] = true
+			}
+
+			if isStage || strings.EqualFold(image, "scratch") {
+				continue
+			}
+
+			images = append(images, containerImage{reference: image, container: stage})
+		}
+	}
+
+	return images
+}
+
+// getDockerfileInstructions returns the instructions in the Dockerfile, where instructions
+// that are continued over multiple lines are joined and comments are removed.
+func getDockerfileInstructions(dockerfile string) []string {
+	escape := `\`
+
+	var instructions []string
+	var current string
+	for _, line := range strings.Split(strings.ReplaceAll(dockerfile, "\r\n", "\n"), "\n") {
+		trimmedLine := strings.TrimSpace(line)
+
+		// Parser directives can only appear before the first instruction.
+		if len(instructions) == 0 && current == "" {
+			if match := dockerfileEscapePattern.FindStringSubmatch(trimmedLine); match != nil {
+				escape = match[1]
+				continue
+			}
+		}
+
+		if strings.HasPrefix(trimmedLine, "#") {
+			continue
+		}
+
+		if strings.HasSuffix(trimmedLine, escape) {
+			current += strings.TrimSuffix(trimmedLine, escape) + " "
+			continue
+		}
+
+		current += trimmedLine
+		if strings.TrimSpace(current) != "" {
+			instructions = append(instructions, strings.TrimSpace(current))
+		}
+
+		current = ""
+	}
+
+	if strings.TrimSpace(current) != "" {
+		instructions = append(instructions, strings.TrimSpace(current))
+	}
+
+	return instructions
+}
+
+func expandDockerfileArgs(value string, args map[string]string) string {
+	return dockerfileArgPattern.ReplaceAllStringFunc(value, func(arg string) string {
+		match := dockerfileArgPattern.FindStringSubmatch(arg)
+
+		argValue := args[match[1]+match[2]]
+		switch match[3] {
+		case "-":
+			if argValue == "" {
+				return match[4]
+			}
+		case "+":
+			if argValue != "" {
+				return match[4]
+			}
+
+			return ""
+		}
+
+		return argValue
+	})
+}
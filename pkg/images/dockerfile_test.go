@@ -0,0 +1,50 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetDockerfileImages(t *testing.T) {
+	dockerfile := []byte(`# escape=\
+ARG GO_VERSION=1.15
+ARG BASE
+
+FROM --platform=$BUILDPLATFORM golang:${GO_VERSION}-alpine AS builder
+RUN go build \
+    -o /app .
+
+FROM builder AS test
+RUN go test ./...
+
+FROM ${BASE:-gcr.io/distroless/static:nonroot}
+COPY --from=builder /app /app
+
+FROM scratch
+COPY --from=builder /app /app`)
+
+	expected := []containerImage{
+		{reference: "golang:1.15-alpine", container: "builder"},
+		{reference: "gcr.io/distroless/static:nonroot"},
+	}
+
+	actual := getDockerfileImages(dockerfile)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+}
+
+func TestIsDockerfile(t *testing.T) {
+	testCases := map[string]bool{
+		"build/Dockerfile":   true,
+		"Dockerfile.prod":    true,
+		"ci.Dockerfile":      true,
+		"dockerfile-lint.md": false,
+	}
+
+	for path, expected := range testCases {
+		if actual := isDockerfile(path); actual != expected {
+			t.Errorf("expected %v for %s, actual %v", expected, path, actual)
+		}
+	}
+}
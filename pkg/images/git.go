@@ -0,0 +1,169 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitRepository is a remote Git repository to find images in.
+type gitRepository struct {
+	url    string
+	subdir string
+	ref    string
+}
+
+// isGitURL returns true if the path refers to a remote Git repository
+// (e.g. https://github.com/org/repo//manifests?ref=v1.2.0).
+func isGitURL(path string) bool {
+	if strings.HasPrefix(path, "git::") || strings.HasPrefix(path, "git@") {
+		return true
+	}
+
+	for _, scheme := range []string{"https://", "http://", "ssh://", "git://"} {
+		if strings.HasPrefix(path, scheme) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseGitURL parses a Git URL in the form of <repository>[//<subdir>][?ref=<ref>].
+// The optional subdirectory is the directory within the repository to find images in
+// and the optional ref is the branch, tag or commit to check out.
+func parseGitURL(gitURL string) (gitRepository, error) {
+	gitURL = strings.TrimPrefix(gitURL, "git::")
+
+	var repository gitRepository
+	if queryIndex := strings.LastIndex(gitURL, "?"); queryIndex >= 0 {
+		query, err := url.ParseQuery(gitURL[queryIndex+1:])
+		if err != nil {
+			return gitRepository{}, fmt.Errorf("parse query: %w", err)
+		}
+
+		repository.ref = query.Get("ref")
+		gitURL = gitURL[:queryIndex]
+	}
+
+	// The subdirectory is separated from the repository by a double slash,
+	// which should not be confused with the double slash of the scheme.
+	searchFrom := 0
+	if schemeIndex := strings.Index(gitURL, "://"); schemeIndex >= 0 {
+		searchFrom = schemeIndex + len("://")
+	}
+
+	if subdirIndex := strings.Index(gitURL[searchFrom:], "//"); subdirIndex >= 0 {
+		subdirIndex += searchFrom
+		repository.subdir = strings.Trim(gitURL[subdirIndex+2:], "/")
+		gitURL = gitURL[:subdirIndex]
+	}
+
+	if gitURL == "" {
+		return gitRepository{}, fmt.Errorf("missing repository")
+	}
+
+	repository.url = gitURL
+
+	return repository, nil
+}
+
+// cloneGitRepository clones the repository into a temporary directory and returns the path
+// to the directory that should be searched for images. The returned cleanup function removes
+// the temporary directory.
+func cloneGitRepository(ctx context.Context, repository gitRepository) (string, func(), error) {
+	dir, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp dir: %w", err)
+	}
+
+	cleanup := func() {
+		os.RemoveAll(dir)
+	}
+
+	if err := gitClone(ctx, repository, dir); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(repository.subdir)), cleanup, nil
+}
+
+// gitClone clones the repository into the directory and checks out the ref of the repository, if any.
+func gitClone(ctx context.Context, repository gitRepository, dir string) error {
+
+	// A shallow clone is only possible when the ref is a branch or a tag. When the
+	// shallow clone fails, the ref may be a commit which requires the full history.
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if repository.ref != "" {
+		args = append(args, "--branch", repository.ref)
+	}
+	args = append(args, repository.url, dir)
+
+	if _, err := execute(ctx, "git", args...); err != nil {
+		if repository.ref == "" {
+			return fmt.Errorf("git clone: %w", err)
+		}
+
+		if _, err := execute(ctx, "git", "clone", "--quiet", repository.url, dir); err != nil {
+			return fmt.Errorf("git clone: %w", err)
+		}
+
+		if _, err := execute(ctx, "git", "-C", dir, "checkout", "--quiet", repository.ref); err != nil {
+			return fmt.Errorf("git checkout: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// gitSource reads the files of a remote Git repository, which is cloned into a temporary directory.
+type gitSource struct{}
+
+func (gitSource) Match(location string) bool {
+	return isGitURL(location)
+}
+
+func (s gitSource) Files(ctx context.Context, location string) ([]File, error) {
+	documents, err := s.documents(location, getFilesOptions(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return getDocumentFiles(documents), nil
+}
+
+func (gitSource) documents(location string, o options) ([]document, error) {
+	repository, err := parseGitURL(location)
+	if err != nil {
+		return nil, fmt.Errorf("parse git url: %w", err)
+	}
+
+	clonePath, cleanup, err := getGitRepository(repository, o)
+	if err != nil {
+		return nil, fmt.Errorf("clone git repository: %w", err)
+	}
+	defer cleanup()
+
+	documents, err := getFilesystemDocuments(clonePath, o)
+	if err != nil {
+		return nil, err
+	}
+
+	// Paths inside of a cloned repository are reported relative to the root
+	// of the repository, as the clone is removed once the images are found.
+	for i := range documents {
+		relativePath, err := filepath.Rel(clonePath, documents[i].path)
+		if err != nil {
+			return nil, fmt.Errorf("relative path: %w", err)
+		}
+
+		documents[i].path = filepath.ToSlash(relativePath)
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,44 @@
+package images
+
+import (
+	"testing"
+)
+
+func TestParseGitURL(t *testing.T) {
+	testCases := []struct {
+		gitURL   string
+		expected gitRepository
+	}{
+		{
+			"https://github.com/org/repo",
+			gitRepository{url: "https://github.com/org/repo"},
+		},
+		{
+			"https://github.com/org/repo//manifests?ref=v1.2.0",
+			gitRepository{url: "https://github.com/org/repo", subdir: "manifests", ref: "v1.2.0"},
+		},
+		{
+			"git::https://github.com/org/repo.git//deploy/base",
+			gitRepository{url: "https://github.com/org/repo.git", subdir: "deploy/base"},
+		},
+		{
+			"git@github.com:org/repo.git//manifests?ref=main",
+			gitRepository{url: "git@github.com:org/repo.git", subdir: "manifests", ref: "main"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		if !isGitURL(testCase.gitURL) {
+			t.Errorf("expected %s to be a git url", testCase.gitURL)
+		}
+
+		actual, err := parseGitURL(testCase.gitURL)
+		if err != nil {
+			t.Fatalf("parse git url %s: %v", testCase.gitURL, err)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf("expected repository %+v, actual %+v", testCase.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package images
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignoreRule is a pattern of a .gitignore file, which applies to the
+// files and directories in the directory that contains the .gitignore file.
+type gitignoreRule struct {
+	base    string
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// gitignore is the set of rules of the .gitignore files found while walking a directory.
+type gitignore struct {
+	rules []gitignoreRule
+}
+
+// load adds the rules of the .gitignore file in the directory, if any.
+func (g *gitignore) load(dir string) error {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, ".gitignore"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read .gitignore: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{base: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		pattern, err := compileGitignorePattern(line)
+		if err != nil {
+			return fmt.Errorf("compile pattern %s: %w", line, err)
+		}
+
+		rule.pattern = pattern
+		g.rules = append(g.rules, rule)
+	}
+
+	return nil
+}
+
+// ignored returns true when the file or directory is ignored. As with Git, the last rule that matches the path wins.
+func (g gitignore) ignored(path string, isDir bool) bool {
+	var ignored bool
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		relativePath, err := filepath.Rel(rule.base, path)
+		if err != nil || strings.HasPrefix(relativePath, "..") {
+			continue
+		}
+
+		if rule.pattern.MatchString(filepath.ToSlash(relativePath)) {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
+
+// compileGitignorePattern converts a .gitignore pattern to a regular expression that matches the path relative
+// to the .gitignore file. Patterns without a slash match a file or directory at any depth (e.g. *.tmp), while
+// patterns with a slash are relative to the directory of the .gitignore file (e.g. /build or docs/*.yaml).
+func compileGitignorePattern(pattern string) (*regexp.Regexp, error) {
+	var expression strings.Builder
+	expression.WriteString("^")
+
+	if !strings.Contains(pattern, "/") {
+		expression.WriteString("(?:.*/)?")
+	}
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expression.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expression.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expression.WriteString("[^/]")
+		case pattern[i] == '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				expression.WriteString(regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+
+			class := pattern[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			expression.WriteString("[" + class + "]")
+			i += end
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			expression.WriteString(regexp.QuoteMeta(string(pattern[i+1])))
+			i++
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+
+	expression.WriteString("$")
+
+	return regexp.Compile(expression.String())
+}
@@ -0,0 +1,115 @@
+package images
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isGlob returns true when the path is a glob pattern (e.g. apps/*/overlays/prod).
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// expandGlob returns the paths that match the pattern. In addition to the patterns supported by filepath.Match,
+// a ** component matches any number of directories (e.g. apps/**/overlays/prod). Paths that are inside of another
+// matching directory are not returned, as the images of a directory include the images of its subdirectories.
+func expandGlob(pattern string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+
+	var matches []string
+	if !strings.Contains(pattern, "**") {
+		var err error
+		matches, err = filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("glob: %w", err)
+		}
+	} else {
+		patternParts := strings.Split(pattern, string(filepath.Separator))
+
+		err := filepath.Walk(getGlobRoot(patternParts), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if matchGlobParts(patternParts, strings.Split(path, string(filepath.Separator))) {
+				matches = append(matches, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("walk: %w", err)
+		}
+	}
+
+	sort.Strings(matches)
+
+	var paths []string
+	for _, match := range matches {
+		if len(paths) > 0 && isInside(match, paths[len(paths)-1]) {
+			continue
+		}
+
+		paths = append(paths, match)
+	}
+
+	return paths, nil
+}
+
+// getGlobRoot returns the directory that the pattern is relative to, which is the
+// longest sequence of leading components of the pattern that are not patterns.
+func getGlobRoot(patternParts []string) string {
+	var rootParts []string
+	for _, part := range patternParts {
+		if isGlob(part) {
+			break
+		}
+
+		rootParts = append(rootParts, part)
+	}
+
+	if len(rootParts) == 0 {
+		return "."
+	}
+
+	if len(rootParts) == 1 && rootParts[0] == "" {
+		return string(filepath.Separator)
+	}
+
+	return strings.Join(rootParts, string(filepath.Separator))
+}
+
+func matchGlobParts(patternParts []string, pathParts []string) bool {
+	if len(patternParts) == 0 {
+		return len(pathParts) == 0
+	}
+
+	if patternParts[0] == "**" {
+		for i := 0; i <= len(pathParts); i++ {
+			if matchGlobParts(patternParts[1:], pathParts[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(pathParts) == 0 {
+		return false
+	}
+
+	matched, err := filepath.Match(patternParts[0], pathParts[0])
+	if err != nil || !matched {
+		return false
+	}
+
+	return matchGlobParts(patternParts[1:], pathParts[1:])
+}
+
+// isInside returns true when the path is inside of the directory.
+func isInside(path string, directory string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(directory, string(filepath.Separator))+string(filepath.Separator))
+}
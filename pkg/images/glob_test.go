@@ -0,0 +1,69 @@
+package images
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandGlob(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	directories := []string{
+		"apps/web/overlays/prod",
+		"apps/web/overlays/dev",
+		"apps/monitoring/prometheus/overlays/prod",
+		"base",
+	}
+
+	for _, directory := range directories {
+		if err := os.MkdirAll(filepath.Join(root, directory), os.ModePerm); err != nil {
+			t.Fatal("make dir:", err)
+		}
+	}
+
+	testCases := []struct {
+		pattern  string
+		expected []string
+	}{
+		{
+			pattern:  "apps/**/overlays/prod",
+			expected: []string{"apps/monitoring/prometheus/overlays/prod", "apps/web/overlays/prod"},
+		},
+		{
+			pattern:  "apps/*/overlays/*",
+			expected: []string{"apps/web/overlays/dev", "apps/web/overlays/prod"},
+		},
+		{
+			pattern:  "**/overlays",
+			expected: []string{"apps/monitoring/prometheus/overlays", "apps/web/overlays"},
+		},
+		{
+			// The subdirectories of a matching directory are already included in the directory.
+			pattern:  "apps/**",
+			expected: []string{"apps"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := expandGlob(filepath.Join(root, filepath.FromSlash(testCase.pattern)))
+		if err != nil {
+			t.Fatal("expand glob:", err)
+		}
+
+		var expected []string
+		for _, path := range testCase.expected {
+			expected = append(expected, filepath.Join(root, filepath.FromSlash(path)))
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected %s to match %v, actual %v", testCase.pattern, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func getHelmCharts(path string, o options) ([]string, error) {
+	var charts []string
+	err := walk(path, o, func(currentFilePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if !fileInfo.IsDir() {
+			return nil
+		}
+
+		if _, err := os.Stat(filepath.Join(currentFilePath, "Chart.yaml")); err != nil {
+			return nil
+		}
+
+		// Any subcharts (e.g. in the charts directory) are rendered
+		// as part of the parent chart and do not need to be visited.
+		charts = append(charts, currentFilePath)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return charts, nil
+}
+
+func renderHelmChart(ctx context.Context, chartPath string, valuesFiles []string) ([]byte, error) {
+	args := []string{"template", chartPath}
+	for _, valuesFile := range valuesFiles {
+		args = append(args, "--values", valuesFile)
+	}
+
+	renderedChart, err := execute(ctx, "helm", args...)
+	if err != nil {
+		return nil, fmt.Errorf("helm template %s: %w", chartPath, err)
+	}
+
+	return renderedChart, nil
+}
+
+// helmScheme is the scheme of the locations of charts in OCI registries (e.g. oci://ghcr.io/org/charts/app?version=1.2.0).
+const helmScheme = "oci://"
+
+// helmSource reads the resources that are rendered from a chart in an OCI registry with the values files of
+// the options. The version of the chart is set by the version query parameter (defaults to the latest version).
+type helmSource struct{}
+
+func (helmSource) Match(location string) bool {
+	return strings.HasPrefix(location, helmScheme)
+}
+
+func (s helmSource) Files(ctx context.Context, location string) ([]File, error) {
+	documents, err := s.documents(location, getFilesOptions(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return getDocumentFiles(documents), nil
+}
+
+func (helmSource) documents(location string, o options) ([]document, error) {
+	chart, version := parseHelmLocation(location)
+
+	chartPath, err := getHelmChart(chart, "", version, o)
+	if err != nil {
+		return nil, fmt.Errorf("get helm chart %s: %w", chart, err)
+	}
+
+	// A chart that was pulled into the cache is rendered from its archive.
+	args := []string{"template", chartPath}
+	if chartPath == chart && version != "" {
+		args = append(args, "--version", version)
+	}
+	for _, valuesFile := range o.helmValues {
+		args = append(args, "--values", valuesFile)
+	}
+
+	renderedChart, err := execute(o.ctx, "helm", args...)
+	if err != nil {
+		return nil, fmt.Errorf("helm template %s: %w", chart, err)
+	}
+
+	documents, err := newDocuments(chart, renderedChart, o)
+	if err != nil {
+		return nil, fmt.Errorf("split rendered helm chart: %w", err)
+	}
+
+	return documents, nil
+}
+
+// parseHelmLocation returns the chart and the version of the chart at the location.
+func parseHelmLocation(location string) (string, string) {
+	tokens := strings.SplitN(location, "?", 2)
+	if len(tokens) == 1 {
+		return location, ""
+	}
+
+	query, err := url.ParseQuery(tokens[1])
+	if err != nil {
+		return tokens[0], ""
+	}
+
+	return tokens[0], query.Get("version")
+}
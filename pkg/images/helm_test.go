@@ -0,0 +1,53 @@
+package images
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetHelmCharts(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	chart := filepath.Join(root, "chart")
+	subchart := filepath.Join(chart, "charts", "subchart")
+	manifests := filepath.Join(root, "manifests")
+
+	for _, dir := range []string{subchart, manifests} {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal("mkdir:", err)
+		}
+	}
+
+	for _, file := range []string{filepath.Join(chart, "Chart.yaml"), filepath.Join(subchart, "Chart.yaml"), filepath.Join(manifests, "deployment.yaml")} {
+		if err := ioutil.WriteFile(file, []byte{}, os.ModePerm); err != nil {
+			t.Fatal("write file:", err)
+		}
+	}
+
+	charts, err := getHelmCharts(root, newOptions())
+	if err != nil {
+		t.Fatal("get helm charts:", err)
+	}
+
+	expectedCharts := []string{chart}
+	if !reflect.DeepEqual(charts, expectedCharts) {
+		t.Errorf("expected charts %v, actual %v", expectedCharts, charts)
+	}
+
+	files, err := getYamlFiles(root, charts, newOptions())
+	if err != nil {
+		t.Fatal("get yaml files:", err)
+	}
+
+	expectedFiles := []string{filepath.Join(manifests, "deployment.yaml")}
+	if !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("expected files %v, actual %v", expectedFiles, files)
+	}
+}
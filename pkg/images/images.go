@@ -0,0 +1,620 @@
+// Package images finds the container images that are referenced by Kubernetes resources.
+//
+// Images are found in the pod specs of workloads (e.g. Deployment, StatefulSet and CronJob),
+// standalone Pods, the prometheus-operator resources, Tekton tasks and pipelines, Argo
+// workflows, the OpenShift DeploymentConfig, ImageStream and BuildConfig resources, Knative
+// Services, KEDA ScaledJobs, any custom resources that are configured with WithCRDImagePaths,
+// and the services of Docker Compose files. Helm charts and kustomizations can optionally be
+// rendered before images are found, and the base images of Dockerfiles, the images of CI jobs
+// and the images set in Terraform files can optionally be found with WithDockerfiles, WithCIFiles
+// and WithTerraform. The packages of Crossplane providers, configurations and functions are
+// found as well.
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kubeyaml "github.com/ghodss/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Image is a container image that was found in a resource.
+type Image struct {
+
+	// Reference is the reference to the image as it appears in the
+	// resource (e.g. quay.io/coreos/prometheus-operator:v0.40.0).
+	Reference string `json:"reference"`
+
+	// Host is the registry host of the image (e.g. quay.io).
+	// Images on Docker Hub that do not include a host have an empty host.
+	Host string `json:"host,omitempty"`
+
+	// Repository is the repository of the image without its host (e.g. coreos/prometheus-operator).
+	Repository string `json:"repository"`
+
+	// Tag is the tag of the image, if any (e.g. v0.40.0).
+	Tag string `json:"tag,omitempty"`
+
+	// Digest is the digest of the image, if any (e.g. sha256:...).
+	Digest string `json:"digest,omitempty"`
+
+	// Resources are the resources that the image was found in.
+	Resources []Resource `json:"resources,omitempty"`
+}
+
+// Resource is a Kubernetes resource that references an image.
+type Resource struct {
+
+	// Path is the path of the file that the resource was found in. Resources
+	// rendered from a Helm chart or kustomization have the path of the chart
+	// or kustomization, and resources read from stdin have a path of -.
+	Path string `json:"path"`
+
+	// Kind is the kind of the resource (e.g. Deployment).
+	Kind string `json:"kind"`
+
+	// Name is the name of the resource.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the resource, if any.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Container is the name of the container that uses the image, if any.
+	Container string `json:"container,omitempty"`
+
+	// Labels are the labels of the resource, if any.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Line is the approximate line of the reference to the image in the file, if it could
+	// be found. Resources rendered from a Helm chart or kustomization do not have a line.
+	Line int `json:"line,omitempty"`
+
+	// Heuristic is true when the image was found by scanning all of the values of the
+	// resource (see WithDeepScan) rather than in a known location, and may not be an image.
+	Heuristic bool `json:"heuristic,omitempty"`
+}
+
+// String returns the reference to the image.
+func (i Image) String() string {
+	return i.Reference
+}
+
+// FindImages returns all of the images found in the Kubernetes resources located
+// at the specified path. The path can either be a single file, a directory or the URL
+// of a remote Git repository (e.g. https://github.com/org/repo//manifests?ref=v1.2.0).
+//
+// When the path is -, the resources are read from stdin. The path can also be the location
+// of any other source that is registered with RegisterSource, such as a chart in an OCI
+// registry (e.g. oci://ghcr.io/org/charts/app?version=1.2.0) or a cluster (e.g. cluster://prod).
+func FindImages(path string, opts ...Option) ([]Image, error) {
+	return findImages(path, newOptions(opts...))
+}
+
+// FindImagesInPaths returns the images found at each of the paths (see FindImages). Images
+// that are referenced at more than one of the paths are only returned once, along with the
+// resources of every path that references them.
+//
+// Paths can also be glob patterns, in which case the images are found at every path that
+// matches the pattern. A ** component matches any number of directories (e.g. apps/**/prod).
+func FindImagesInPaths(paths []string, opts ...Option) ([]Image, error) {
+	paths, err := expandPaths(paths)
+	if err != nil {
+		return nil, fmt.Errorf("expand paths: %w", err)
+	}
+
+	var foundImages []Image
+	for _, path := range paths {
+		pathImages, err := FindImages(path, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("find images in %s: %w", path, err)
+		}
+
+		for _, image := range pathImages {
+			foundImages = mergeImages(foundImages, image)
+		}
+	}
+
+	return foundImages, nil
+}
+
+func findImages(location string, o options) ([]Image, error) {
+	source, err := getSource(location)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := readSource(source, location, o)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", location, err)
+	}
+
+	images, err := getImagesFromYamlFiles(documents, o)
+	if err != nil {
+		return nil, fmt.Errorf("get images from yaml files: %w", err)
+	}
+
+	return images, nil
+}
+
+// getFilesystemDocuments returns the documents of the files at the path, including the rendered documents of
+// the Helm charts and kustomizations at the path when they are rendered. The files inside of them are not read.
+func getFilesystemDocuments(path string, o options) ([]document, error) {
+	var charts []string
+	if o.helm {
+		var err error
+		charts, err = getHelmCharts(path, o)
+		if err != nil {
+			return nil, fmt.Errorf("get helm charts: %w", err)
+		}
+	}
+
+	var kustomizations []string
+	if o.kustomize {
+		var err error
+		kustomizations, err = getKustomizations(path, o)
+		if err != nil {
+			return nil, fmt.Errorf("get kustomizations: %w", err)
+		}
+	}
+
+	var excludedDirs []string
+	excludedDirs = append(excludedDirs, charts...)
+	excludedDirs = append(excludedDirs, kustomizations...)
+
+	files, err := getYamlFiles(path, excludedDirs, o)
+	if err != nil {
+		return nil, fmt.Errorf("get yaml files: %w", err)
+	}
+
+	documents, err := splitYamlFiles(files, o)
+	if err != nil {
+		return nil, fmt.Errorf("split yaml files: %w", err)
+	}
+
+	if o.dockerfiles {
+		dockerfiles, err := getDockerfiles(path, o)
+		if err != nil {
+			return nil, fmt.Errorf("get dockerfiles: %w", err)
+		}
+
+		for _, dockerfile := range dockerfiles {
+			contents, err := ioutil.ReadFile(dockerfile)
+			if err != nil {
+				return nil, fmt.Errorf("read dockerfile: %w", err)
+			}
+
+			fileDocument, err := newFileDocument(dockerfile, contents, o)
+			if err != nil {
+				return nil, err
+			}
+
+			documents = append(documents, fileDocument)
+		}
+	}
+
+	if o.terraform {
+		terraformFiles, err := getTerraformFiles(path, o)
+		if err != nil {
+			return nil, fmt.Errorf("get terraform files: %w", err)
+		}
+
+		for _, terraformFile := range terraformFiles {
+			contents, err := ioutil.ReadFile(terraformFile)
+			if err != nil {
+				return nil, fmt.Errorf("read terraform file: %w", err)
+			}
+
+			fileDocument, err := newFileDocument(terraformFile, contents, o)
+			if err != nil {
+				return nil, err
+			}
+
+			documents = append(documents, fileDocument)
+		}
+	}
+
+	for _, chart := range charts {
+		renderedChart, err := renderHelmChart(o.ctx, chart, o.helmValues)
+		if err != nil {
+			return nil, fmt.Errorf("render helm chart: %w", err)
+		}
+
+		chartDocuments, err := newDocuments(chart, renderedChart, o)
+		if err != nil {
+			return nil, fmt.Errorf("split rendered helm chart: %w", err)
+		}
+
+		documents = append(documents, chartDocuments...)
+	}
+
+	topLevelKustomizations, err := getTopLevelKustomizations(kustomizations)
+	if err != nil {
+		return nil, fmt.Errorf("get top level kustomizations: %w", err)
+	}
+
+	for _, kustomization := range topLevelKustomizations {
+		builtKustomization, err := buildKustomization(o.ctx, kustomization)
+		if err != nil {
+			return nil, fmt.Errorf("build kustomization: %w", err)
+		}
+
+		kustomizationDocuments, err := newDocuments(kustomization, builtKustomization, o)
+		if err != nil {
+			return nil, fmt.Errorf("split built kustomization: %w", err)
+		}
+
+		documents = append(documents, kustomizationDocuments...)
+	}
+
+	return documents, nil
+}
+
+// FindImagesInReader returns all of the images found in the Kubernetes resources read
+// from the reader (e.g. the output of helm template). The contents of the reader are
+// treated as a multi-document YAML file.
+func FindImagesInReader(reader io.Reader, opts ...Option) ([]Image, error) {
+	o := newOptions(opts...)
+
+	documents, err := getReaderDocuments(reader, o)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := getImagesFromYamlFiles(documents, o)
+	if err != nil {
+		return nil, fmt.Errorf("get images from yaml files: %w", err)
+	}
+
+	return images, nil
+}
+
+// document is a single YAML document and the path it was read from.
+type document struct {
+	path     string
+	contents []byte
+
+	// searched is true when the images of the document were found (see findImages), which are kept along with the
+	// metadata of the resource so that the contents of the document can be dropped once it has been searched.
+	searched   bool
+	objectMeta metav1.PartialObjectMetadata
+	images     []containerImage
+}
+
+// findImages finds the metadata of the resource in the document and the images that it references.
+func (d *document) findImages(o options) error {
+	objectMeta, yamlImages, err := getDocumentImages(*d, o)
+	if err != nil {
+		return err
+	}
+
+	d.searched = true
+	d.objectMeta = objectMeta
+	d.images = yamlImages
+	return nil
+}
+
+// newDocuments returns the documents of the rendered YAML at the path (e.g. a rendered Helm chart), which do not
+// have lines in a file. When the YAML cannot be parsed, the documents before the invalid document are returned,
+// unless the options are strict.
+func newDocuments(path string, contents []byte, o options) ([]document, error) {
+	documents, err := readDocuments(path, bytes.NewReader(contents), o)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, document := range documents {
+		for i := range document.images {
+			document.images[i].line = 0
+		}
+	}
+
+	return documents, nil
+}
+
+// newFileDocument returns the contents of a file that is not a YAML file (e.g. a Dockerfile) as a single document,
+// which is searched for images along with the lines of the images in the file, as YAML documents are when they are read.
+func newFileDocument(path string, contents []byte, o options) (document, error) {
+	fileDocument := document{path: path, contents: contents}
+	if err := fileDocument.findImages(o); err != nil {
+		return fileDocument, err
+	}
+
+	lines := newLineIndex()
+	for _, line := range bytes.SplitAfter(contents, []byte("\n")) {
+		lines.add(line)
+	}
+
+	for i := range fileDocument.images {
+		fileDocument.images[i].line = lines.find(fileDocument.images[i].reference)
+	}
+
+	if !o.keepsContents() {
+		fileDocument.contents = nil
+	}
+
+	return fileDocument, nil
+}
+
+func getImagesFromYamlFiles(documents []document, o options) ([]Image, error) {
+
+	// Finding the images in each document is independent of the other documents, so the documents
+	// that were not searched as they were read (see readDocuments) are searched in parallel. The images
+	// are merged in the order of the documents, which keeps the results the same regardless of the
+	// number of workers.
+	err := forEach(o.workers, len(documents), func(i int) error {
+		if documents[i].searched {
+			return nil
+		}
+
+		return documents[i].findImages(o)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	imageIndexes := make(map[string]int)
+	for _, document := range documents {
+		objectMeta := document.objectMeta
+
+		for _, yamlImage := range document.images {
+			resource := Resource{
+				Path:      document.path,
+				Kind:      objectMeta.Kind,
+				Name:      objectMeta.Name,
+				Namespace: objectMeta.Namespace,
+				Container: yamlImage.container,
+				Labels:    objectMeta.Labels,
+				Line:      yamlImage.line,
+				Heuristic: yamlImage.heuristic,
+			}
+
+			// Images without a tag are checked before they are merged with the images found before them, as an
+			// image without a tag (e.g. nginx) is the same image as the image with the latest tag.
+			if o.strict && !hasTagOrDigest(yamlImage.reference) {
+				if _, err := parseReference(yamlImage.reference, o); err == nil {
+					return nil, fmt.Errorf("image %s in %s does not have a tag or digest", yamlImage.reference, resource.Location())
+				}
+			}
+
+			if i, ok := imageIndexes[referenceKey(yamlImage.reference)]; ok {
+				images[i].Resources = append(images[i].Resources, resource)
+				continue
+			}
+
+			// Values that were found in places where images are commonly passed in (e.g. container arguments)
+			// are not always images. Any value that is not a valid reference is not an image.
+			image, err := parseReference(yamlImage.reference, o)
+			if err != nil {
+				continue
+			}
+
+			image.Resources = []Resource{resource}
+			imageIndexes[referenceKey(yamlImage.reference)] = len(images)
+			images = append(images, image)
+		}
+	}
+
+	if o.followSources {
+		deliveryImages, err := getDeliveryImages(documents, o)
+		if err != nil {
+			return nil, fmt.Errorf("get delivery images: %w", err)
+		}
+
+		for _, deliveryImage := range deliveryImages {
+			images = mergeImages(images, deliveryImage)
+		}
+	}
+
+	return images, nil
+}
+
+// getDocumentImages returns the metadata of the resource in the document and the images it references.
+func getDocumentImages(document document, o options) (metav1.PartialObjectMetadata, []containerImage, error) {
+	var objectMeta metav1.PartialObjectMetadata
+	if o.filtersResources() && !o.includesResource(document) {
+		return objectMeta, nil, nil
+	}
+
+	var yamlImages []containerImage
+	if isDockerfile(document.path) {
+		objectMeta.Kind = dockerfileKind
+		objectMeta.Name = filepath.Base(document.path)
+		yamlImages = getDockerfileImages(document.contents)
+	} else if isTerraformFile(document.path) {
+		objectMeta.Kind = terraformKind
+		objectMeta.Name = filepath.Base(document.path)
+		yamlImages = getTerraformImages(document.contents)
+	} else if isComposeFile(document.path) {
+		name, composeImages, err := getComposeImages(document.path, document.contents)
+		if err != nil {
+			return objectMeta, nil, fmt.Errorf("get images from compose file: %w", err)
+		}
+
+		objectMeta.Kind = composeKind
+		objectMeta.Name = name
+		yamlImages = composeImages
+	} else if o.ciFiles && isGitHubWorkflow(document.path) {
+		name, workflowImages, err := getGitHubWorkflowImages(document.path, document.contents)
+		if err != nil {
+			return objectMeta, nil, fmt.Errorf("get images from github workflow: %w", err)
+		}
+
+		objectMeta.Kind = gitHubWorkflowKind
+		objectMeta.Name = name
+		yamlImages = workflowImages
+	} else if o.ciFiles && isGitLabCIFile(document.path) {
+		gitLabCIImages, err := getGitLabCIImages(document.contents)
+		if err != nil {
+			return objectMeta, nil, fmt.Errorf("get images from gitlab ci: %w", err)
+		}
+
+		objectMeta.Kind = gitLabCIKind
+		objectMeta.Name = filepath.Base(document.path)
+		yamlImages = gitLabCIImages
+	} else {
+		kubernetesImages, err := getImagesFromYamlFile(document.contents, o)
+		if err != nil {
+			return objectMeta, nil, fmt.Errorf("get images from yaml: %w", err)
+		}
+
+		yamlImages = kubernetesImages
+	}
+
+	if o.deepScan {
+		yamlImages = append(yamlImages, getDeepScanImages(document.contents, yamlImages)...)
+	}
+
+	if len(yamlImages) == 0 {
+		return objectMeta, nil, nil
+	}
+
+	if objectMeta.Kind == "" {
+		if err := kubeyaml.Unmarshal(document.contents, &objectMeta); err != nil {
+			return objectMeta, nil, fmt.Errorf("unmarshal object metadata: %w", err)
+		}
+	}
+
+	return objectMeta, applyAnnotations(objectMeta.Annotations, yamlImages), nil
+}
+
+// FindFiles returns the paths of all of the YAML files found at the specified path.
+// The options that control which directories are searched (e.g. WithSkipDirs) apply.
+func FindFiles(path string, opts ...Option) ([]string, error) {
+	files, err := getYamlFiles(path, nil, newOptions(opts...))
+	if err != nil {
+		return nil, fmt.Errorf("get yaml files: %w", err)
+	}
+
+	return files, nil
+}
+
+func getYamlFiles(path string, excludedDirs []string, o options) ([]string, error) {
+	var files []string
+	err := walk(path, o, func(currentFilePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if fileInfo.IsDir() && containsPath(excludedDirs, currentFilePath) {
+			return filepath.SkipDir
+		}
+
+		if fileInfo.IsDir() {
+			return nil
+		}
+
+		if filepath.Ext(currentFilePath) != ".yaml" && filepath.Ext(currentFilePath) != ".yml" {
+			return nil
+		}
+
+		files = append(files, currentFilePath)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func splitYamlFiles(files []string, o options) ([]document, error) {
+	fileDocuments := make([][]document, len(files))
+	err := forEach(o.workers, len(files), func(i int) error {
+		var err error
+		fileDocuments[i], err = readFileDocuments(files[i], o)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []document
+	for _, currentDocuments := range fileDocuments {
+		documents = append(documents, currentDocuments...)
+	}
+
+	return documents, nil
+}
+
+func indexOf(images []Image, reference string) int {
+	for i, currentImage := range images {
+		if referenceKey(currentImage.Reference) == referenceKey(reference) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// referenceKey returns the key that images are merged by, so that references to the same image that are
+// written in different ways are merged into one image. The image keeps the reference that was found first.
+func referenceKey(reference string) string {
+	normalized, err := NormalizeReference(reference)
+	if err != nil {
+		return strings.ToLower(reference)
+	}
+
+	return strings.ToLower(normalized)
+}
+
+// expandPaths replaces the glob patterns in the paths with the paths that match them.
+func expandPaths(paths []string) ([]string, error) {
+	var expandedPaths []string
+	for _, path := range paths {
+		if !isFilesystemLocation(path) || !isGlob(path) {
+			expandedPaths = append(expandedPaths, path)
+			continue
+		}
+
+		pattern, err := expandHome(path)
+		if err != nil {
+			return nil, fmt.Errorf("expand home: %w", err)
+		}
+
+		matches, err := expandGlob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("expand %s: %w", path, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no paths match %s", path)
+		}
+
+		expandedPaths = append(expandedPaths, matches...)
+	}
+
+	return expandedPaths, nil
+}
+
+// expandHome replaces a leading ~ in the path with the home directory of the user, as paths
+// are not expanded when they are quoted or set in a config file rather than passed to a shell.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("get home dir: %w", err)
+	}
+
+	return filepath.Join(home, path[1:]), nil
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, currentPath := range paths {
+		if filepath.Clean(currentPath) == filepath.Clean(path) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,318 @@
+package images
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	kubeyaml "github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestFindImages_ImplicitLatest(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	pod := []byte(`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: nginx`)
+
+	if err := ioutil.WriteFile(filepath.Join(root, "pod.yaml"), pod, os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	actual, err := FindImages(root)
+	if err != nil {
+		t.Fatal("find images:", err)
+	}
+
+	expected := []Image{
+		{
+			Reference:  "nginx",
+			Repository: "nginx",
+			Tag:        "latest",
+			Resources:  []Resource{{Path: filepath.Join(root, "pod.yaml"), Kind: "Pod", Line: 5}},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+
+	if _, err := FindImages(root, WithStrict()); err == nil {
+		t.Error("expected an error for an image without a tag when strict")
+	}
+}
+
+func TestFindImages_StrictAfterLatest(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	pod := []byte(`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: nginx:latest
+  - image: nginx`)
+
+	if err := ioutil.WriteFile(filepath.Join(root, "pod.yaml"), pod, os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	// The image without a tag is the same image as the image with the latest tag, but is still an error when strict.
+	_, err = FindImages(root, WithStrict())
+	if err == nil || !strings.Contains(err.Error(), "image nginx in") {
+		t.Errorf("expected an error for the image without a tag when strict, actual %v", err)
+	}
+}
+
+func TestFindImages_ParseError(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	pods := []byte(`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: nginx:1.25
+---
+apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: busybox:1.32.0
+    command: [sh
+---
+apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: redis:7.0`)
+
+	podsPath := filepath.Join(root, "pods.yaml")
+	if err := ioutil.WriteFile(podsPath, pods, os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	var parseErrorPaths []string
+	var parseErrors []string
+	handler := func(path string, err error) {
+		parseErrorPaths = append(parseErrorPaths, path)
+		parseErrors = append(parseErrors, err.Error())
+	}
+
+	actual, err := FindImages(root, WithParseErrorHandler(handler))
+	if err != nil {
+		t.Fatal("find images:", err)
+	}
+
+	// Only the invalid document is skipped, as it ends at the next document separator.
+	var actualReferences []string
+	for _, image := range actual {
+		actualReferences = append(actualReferences, image.Reference)
+	}
+
+	expected := []string{"nginx:1.25", "redis:7.0"}
+	if !reflect.DeepEqual(actualReferences, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actualReferences)
+	}
+
+	if len(actual) == 2 && actual[1].Resources[0].Line != 18 {
+		t.Errorf("expected redis:7.0 to be on line 18, actual %v", actual[1].Resources[0].Line)
+	}
+
+	if !reflect.DeepEqual(parseErrorPaths, []string{podsPath}) {
+		t.Errorf("expected parse error for %s, actual %v", podsPath, parseErrorPaths)
+	}
+
+	if len(parseErrors) == 1 && !strings.HasPrefix(parseErrors[0], "document at line 6: ") {
+		t.Errorf("expected the parse error to have the line of the invalid document, actual %v", parseErrors[0])
+	}
+
+	if _, err := FindImages(root, WithStrict()); err == nil {
+		t.Error("expected an error for an invalid document when strict")
+	}
+}
+
+func TestFindImagesInPaths(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"web.yaml":        "apiVersion: v1\nkind: Pod\nspec:\n  containers:\n  - image: nginx:1.25",
+		"monitoring.yaml": "apiVersion: v1\nkind: Pod\nspec:\n  containers:\n  - image: docker.io/library/nginx:1.25\n  - image: busybox:1.32.0",
+	}
+
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, name), []byte(contents), os.ModePerm); err != nil {
+			t.Fatal("write file:", err)
+		}
+	}
+
+	actual, err := FindImagesInPaths([]string{filepath.Join(root, "web.yaml"), filepath.Join(root, "monitoring.yaml")})
+	if err != nil {
+		t.Fatal("find images in paths:", err)
+	}
+
+	expected := []Image{
+		{
+			Reference:  "nginx:1.25",
+			Repository: "nginx",
+			Tag:        "1.25",
+			Resources: []Resource{
+				{Path: filepath.Join(root, "web.yaml"), Kind: "Pod", Line: 5},
+				{Path: filepath.Join(root, "monitoring.yaml"), Kind: "Pod", Line: 5},
+			},
+		},
+		{
+			Reference:  "busybox:1.32.0",
+			Repository: "busybox",
+			Tag:        "1.32.0",
+			Resources:  []Resource{{Path: filepath.Join(root, "monitoring.yaml"), Kind: "Pod", Line: 6}},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home dir:", err)
+	}
+
+	actual, err := expandHome("~/manifests")
+	if err != nil {
+		t.Fatal("expand home:", err)
+	}
+
+	if expected := filepath.Join(home, "manifests"); actual != expected {
+		t.Errorf("expected path %s, actual %s", expected, actual)
+	}
+
+	actual, err = expandHome("~manifests")
+	if err != nil {
+		t.Fatal("expand home:", err)
+	}
+
+	if actual != "~manifests" {
+		t.Errorf("expected path of another user to be unchanged, actual %s", actual)
+	}
+}
+
+func TestFindImagesInReader(t *testing.T) {
+	contents := `apiVersion: v1
+kind: Pod
+metadata:
+  name: busybox
+spec:
+  containers:
+  - name: busybox
+    image: busybox:1.32.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: prometheus-operator
+  namespace: monitoring
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.32.0
+      containers:
+      - name: prometheus-operator
+        image: quay.io/coreos/prometheus-operator:v0.40.0
+`
+
+	actual, err := FindImagesInReader(strings.NewReader(contents))
+	if err != nil {
+		t.Fatal("find images in reader:", err)
+	}
+
+	expected := []Image{
+		{
+			Reference:  "busybox:1.32.0",
+			Repository: "busybox",
+			Tag:        "1.32.0",
+			Resources: []Resource{
+				{Path: "-", Kind: "Pod", Name: "busybox", Container: "busybox", Line: 8},
+				{Path: "-", Kind: "Deployment", Name: "prometheus-operator", Namespace: "monitoring", Container: "init", Line: 20},
+			},
+		},
+		{
+			Reference:  "quay.io/coreos/prometheus-operator:v0.40.0",
+			Host:       "quay.io",
+			Repository: "coreos/prometheus-operator",
+			Tag:        "v0.40.0",
+			Resources: []Resource{
+				{Path: "-", Kind: "Deployment", Name: "prometheus-operator", Namespace: "monitoring", Container: "prometheus-operator", Line: 23},
+			},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+}
+
+func TestSplitYaml(t *testing.T) {
+	contents := "---\r\n" +
+		"apiVersion: v1\r\n" +
+		"kind: ConfigMap\r\n" +
+		"data:\r\n" +
+		"  script: |\r\n" +
+		"    echo start\r\n" +
+		"    ---\r\n" +
+		"    echo end\r\n" +
+		"---\r\n" +
+		"# An empty document\r\n" +
+		"---\r\n" +
+		"apiVersion: v1\r\n" +
+		"kind: Pod\r\n"
+
+	o := newOptions()
+	o.keepContents = true
+
+	documents, err := readDocuments("-", strings.NewReader(contents), o)
+	if err != nil {
+		t.Fatal("read documents:", err)
+	}
+
+	if len(documents) != 2 {
+		t.Fatalf("expected 2 documents, actual %v", len(documents))
+	}
+
+	expected := []string{"ConfigMap", "Pod"}
+	for i, document := range documents {
+		if !strings.Contains(string(document.contents), "kind: "+expected[i]) {
+			t.Errorf("expected document %v to be a %s, actual %s", i, expected[i], document.contents)
+		}
+	}
+
+	var configMap corev1.ConfigMap
+	if err := kubeyaml.Unmarshal(documents[0].contents, &configMap); err != nil {
+		t.Fatal("unmarshal config map:", err)
+	}
+
+	if configMap.Data["script"] != "echo start\n---\necho end\n" {
+		t.Errorf("expected the block scalar to contain the document separator, actual %q", configMap.Data["script"])
+	}
+}
@@ -0,0 +1,413 @@
+package images
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	promv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	kubeyaml "github.com/ghodss/yaml"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// containerImage is an image and the name of the container that uses it.
+type containerImage struct {
+	reference string
+	container string
+
+	// heuristic is true when the image was found by scanning the values of the resource.
+	heuristic bool
+
+	// line is the line of the reference to the image in the file that it was found in, if it is known.
+	line int
+}
+
+func getImagesFromYamlFile(yamlFile []byte, o options) ([]containerImage, error) {
+
+	// If the yaml does not contain a TypeMeta, it will not be a valid
+	// Kubernetes resource and can be assumed to have no images.
+	var typeMeta metav1.TypeMeta
+	if err := kubeyaml.Unmarshal(yamlFile, &typeMeta); err != nil {
+		return nil, nil
+	}
+
+	if typeMeta.Kind == "Prometheus" {
+		prometheusImages, err := getPrometheusImages(yamlFile, o)
+		if err != nil {
+			return nil, fmt.Errorf("get prometheus images: %w", err)
+		}
+
+		return prometheusImages, nil
+	}
+
+	if typeMeta.Kind == "Alertmanager" {
+		alertmanagerImages, err := getAlertmanagerImages(yamlFile, o)
+		if err != nil {
+			return nil, fmt.Errorf("get alertmanager images: %w", err)
+		}
+
+		return alertmanagerImages, nil
+	}
+
+	if typeMeta.Kind == "ThanosRuler" {
+		thanosRulerImages, err := getThanosRulerImages(yamlFile, o)
+		if err != nil {
+			return nil, fmt.Errorf("get thanos ruler images: %w", err)
+		}
+
+		return thanosRulerImages, nil
+	}
+
+	if isTektonResource(typeMeta.APIVersion, typeMeta.Kind) {
+		tektonImages, err := getTektonImages(yamlFile, typeMeta.Kind, o)
+		if err != nil {
+			return nil, fmt.Errorf("get tekton images: %w", err)
+		}
+
+		return tektonImages, nil
+	}
+
+	if isArgoWorkflow(typeMeta.APIVersion, typeMeta.Kind) {
+		workflowImages, err := getArgoWorkflowImages(yamlFile, typeMeta.Kind, o)
+		if err != nil {
+			return nil, fmt.Errorf("get argo workflow images: %w", err)
+		}
+
+		return workflowImages, nil
+	}
+
+	if isKnativeService(typeMeta.APIVersion, typeMeta.Kind) || isKEDAScaledJob(typeMeta.APIVersion, typeMeta.Kind) {
+		podSpec, err := getServerlessPodSpec(yamlFile, typeMeta.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("get %s pod spec: %w", strings.ToLower(typeMeta.Kind), err)
+		}
+
+		return getImagesFromPodSpec(podSpec, o), nil
+	}
+
+	if isOpenShiftResource(typeMeta.APIVersion, typeMeta.Kind) {
+		openShiftImages, err := getOpenShiftImages(yamlFile, typeMeta.Kind, o)
+		if err != nil {
+			return nil, fmt.Errorf("get openshift images: %w", err)
+		}
+
+		return openShiftImages, nil
+	}
+
+	if isCrossplaneResource(typeMeta.APIVersion, typeMeta.Kind) {
+		crossplaneImages, err := getCrossplaneImages(yamlFile, typeMeta.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("get crossplane images: %w", err)
+		}
+
+		return crossplaneImages, nil
+	}
+
+	if isWorkload(typeMeta.Kind) {
+		podSpec, err := getWorkloadPodSpec(yamlFile, typeMeta.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("get %s pod spec: %w", strings.ToLower(typeMeta.Kind), err)
+		}
+
+		return getImagesFromPodSpec(podSpec, o), nil
+	}
+
+	if paths := getCRDImagePaths(o.crdPaths, typeMeta.APIVersion, typeMeta.Kind); len(paths) > 0 {
+		customResourceImages, err := getImagesFromCustomResource(yamlFile, paths)
+		if err != nil {
+			return nil, fmt.Errorf("get custom resource images: %w", err)
+		}
+
+		var images []containerImage
+		for _, customResourceImage := range customResourceImages {
+			images = append(images, containerImage{reference: customResourceImage})
+		}
+
+		return images, nil
+	}
+
+	type BaseSpec struct {
+		Template corev1.PodTemplateSpec `json:"template" protobuf:"bytes,3,opt,name=template"`
+	}
+
+	type BaseType struct {
+		Spec BaseSpec `json:"spec,omitempty" protobuf:"bytes,2,opt,name=spec"`
+	}
+
+	var contents BaseType
+	if err := kubeyaml.Unmarshal(yamlFile, &contents); err != nil {
+		return nil, nil
+	}
+
+	return getImagesFromPodSpec(contents.Spec.Template.Spec, o), nil
+}
+
+func isWorkload(kind string) bool {
+	workloads := []string{"Pod", "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job", "CronJob"}
+	for _, workload := range workloads {
+		if kind == workload {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getWorkloadPodSpec(yamlFile []byte, kind string) (corev1.PodSpec, error) {
+	switch kind {
+	case "Pod":
+		var pod corev1.Pod
+		if err := kubeyaml.Unmarshal(yamlFile, &pod); err != nil {
+			return corev1.PodSpec{}, fmt.Errorf("unmarshal pod: %w", err)
+		}
+
+		return pod.Spec, nil
+
+	case "Deployment":
+		var deployment appsv1.Deployment
+		if err := kubeyaml.Unmarshal(yamlFile, &deployment); err != nil {
+			return corev1.PodSpec{}, fmt.Errorf("unmarshal deployment: %w", err)
+		}
+
+		return deployment.Spec.Template.Spec, nil
+
+	case "StatefulSet":
+		var statefulSet appsv1.StatefulSet
+		if err := kubeyaml.Unmarshal(yamlFile, &statefulSet); err != nil {
+			return corev1.PodSpec{}, fmt.Errorf("unmarshal statefulset: %w", err)
+		}
+
+		return statefulSet.Spec.Template.Spec, nil
+
+	case "DaemonSet":
+		var daemonSet appsv1.DaemonSet
+		if err := kubeyaml.Unmarshal(yamlFile, &daemonSet); err != nil {
+			return corev1.PodSpec{}, fmt.Errorf("unmarshal daemonset: %w", err)
+		}
+
+		return daemonSet.Spec.Template.Spec, nil
+
+	case "ReplicaSet":
+		var replicaSet appsv1.ReplicaSet
+		if err := kubeyaml.Unmarshal(yamlFile, &replicaSet); err != nil {
+			return corev1.PodSpec{}, fmt.Errorf("unmarshal replicaset: %w", err)
+		}
+
+		return replicaSet.Spec.Template.Spec, nil
+
+	case "Job":
+		var job batchv1.Job
+		if err := kubeyaml.Unmarshal(yamlFile, &job); err != nil {
+			return corev1.PodSpec{}, fmt.Errorf("unmarshal job: %w", err)
+		}
+
+		return job.Spec.Template.Spec, nil
+
+	case "CronJob":
+		var cronJob batchv1beta1.CronJob
+		if err := kubeyaml.Unmarshal(yamlFile, &cronJob); err != nil {
+			return corev1.PodSpec{}, fmt.Errorf("unmarshal cronjob: %w", err)
+		}
+
+		return cronJob.Spec.JobTemplate.Spec.Template.Spec, nil
+	}
+
+	return corev1.PodSpec{}, fmt.Errorf("unknown workload %s", kind)
+}
+
+// getImagesFromPodSpec returns the images of every kind of container in the pod spec:
+// init containers, containers and ephemeral (debug) containers.
+func getImagesFromPodSpec(podSpec corev1.PodSpec, o options) []containerImage {
+	var images []containerImage
+	images = append(images, getImagesFromContainers(podSpec.InitContainers, o)...)
+	images = append(images, getImagesFromContainers(podSpec.Containers, o)...)
+	images = append(images, getImagesFromContainers(getEphemeralContainers(podSpec.EphemeralContainers), o)...)
+
+	return images
+}
+
+// getEphemeralContainers returns the ephemeral containers as containers. Ephemeral
+// containers have the same fields as containers, with the addition of a target container.
+func getEphemeralContainers(ephemeralContainers []corev1.EphemeralContainer) []corev1.Container {
+	var containers []corev1.Container
+	for _, ephemeralContainer := range ephemeralContainers {
+		containers = append(containers, corev1.Container(ephemeralContainer.EphemeralContainerCommon))
+	}
+
+	return containers
+}
+
+func getPrometheusImages(yamlFile []byte, o options) ([]containerImage, error) {
+	var prometheus promv1.Prometheus
+	if err := kubeyaml.Unmarshal(yamlFile, &prometheus); err != nil {
+		return nil, fmt.Errorf("unmarshal prometheus: %w", err)
+	}
+
+	// The sidecars and init containers are added to the pod that the operator creates. Containers
+	// with the same name as a container of the operator (e.g. config-reloader) patch that container
+	// instead, and only have an image when they replace the image that the operator chose.
+	var images []containerImage
+	images = append(images, getImagesFromContainers(prometheus.Spec.Containers, o)...)
+	images = append(images, getImagesFromContainers(prometheus.Spec.InitContainers, o)...)
+
+	prometheusImage := getOperatorImage(prometheus.Spec.Image, prometheus.Spec.BaseImage, "quay.io/prometheus/prometheus", prometheus.Spec.SHA, prometheus.Spec.Tag, prometheus.Spec.Version)
+	if prometheusImage != "" {
+		images = append(images, containerImage{reference: prometheusImage, container: "prometheus"})
+	}
+
+	if prometheus.Spec.Thanos != nil {
+		if thanosImage := getThanosSidecarImage(*prometheus.Spec.Thanos); thanosImage != "" {
+			images = append(images, containerImage{reference: thanosImage, container: "thanos-sidecar"})
+		}
+	}
+
+	return images, nil
+}
+
+// getThanosSidecarImage returns the image of the Thanos sidecar.
+func getThanosSidecarImage(thanos promv1.ThanosSpec) string {
+	var baseImage, sha, tag, version string
+	if thanos.BaseImage != nil {
+		baseImage = *thanos.BaseImage
+	}
+	if thanos.SHA != nil {
+		sha = *thanos.SHA
+	}
+	if thanos.Tag != nil {
+		tag = *thanos.Tag
+	}
+	if thanos.Version != nil {
+		version = *thanos.Version
+	}
+
+	return getOperatorImage(thanos.Image, baseImage, "quay.io/thanos/thanos", sha, tag, version)
+}
+
+// getOperatorImage returns the image that the prometheus-operator deploys for a resource. The image field
+// replaces the deprecated baseImage, sha, tag and version fields and takes precedence over them. Otherwise
+// the image is the base image (or the default base image of the operator) with the SHA, tag or version,
+// in that order of precedence. When none of them are set, the operator chooses the version and there
+// is no image to return.
+func getOperatorImage(image *string, baseImage string, defaultBaseImage string, sha string, tag string, version string) string {
+	if image != nil && *image != "" {
+		return *image
+	}
+
+	if baseImage == "" {
+		baseImage = defaultBaseImage
+	}
+
+	if sha != "" {
+		return baseImage + "@sha256:" + sha
+	}
+
+	if tag != "" {
+		return baseImage + ":" + tag
+	}
+
+	if version != "" {
+		return baseImage + ":" + version
+	}
+
+	return ""
+}
+
+func getThanosRulerImages(yamlFile []byte, o options) ([]containerImage, error) {
+	var thanosRuler promv1.ThanosRuler
+	if err := kubeyaml.Unmarshal(yamlFile, &thanosRuler); err != nil {
+		return nil, fmt.Errorf("unmarshal thanos ruler: %w", err)
+	}
+
+	var images []containerImage
+	images = append(images, getImagesFromContainers(thanosRuler.Spec.Containers, o)...)
+	images = append(images, getImagesFromContainers(thanosRuler.Spec.InitContainers, o)...)
+
+	if thanosRuler.Spec.Image != "" {
+		images = append(images, containerImage{reference: thanosRuler.Spec.Image, container: "thanos-ruler"})
+	}
+
+	return images, nil
+}
+
+func getAlertmanagerImages(yamlFile []byte, o options) ([]containerImage, error) {
+	var alertmanager promv1.Alertmanager
+	if err := kubeyaml.Unmarshal(yamlFile, &alertmanager); err != nil {
+		return nil, fmt.Errorf("unmarshal alertmanager: %w", err)
+	}
+
+	var images []containerImage
+	images = append(images, getImagesFromContainers(alertmanager.Spec.Containers, o)...)
+	images = append(images, getImagesFromContainers(alertmanager.Spec.InitContainers, o)...)
+
+	alertmanagerImage := getOperatorImage(alertmanager.Spec.Image, alertmanager.Spec.BaseImage, "quay.io/prometheus/alertmanager", alertmanager.Spec.SHA, alertmanager.Spec.Tag, alertmanager.Spec.Version)
+	if alertmanagerImage != "" {
+		images = append(images, containerImage{reference: alertmanagerImage, container: "alertmanager"})
+	}
+
+	return images, nil
+}
+
+func getImagesFromContainers(containers []corev1.Container, o options) []containerImage {
+	var images []containerImage
+	for _, container := range containers {
+
+		// The image of a container can be omitted when it is set by
+		// higher level configuration management (e.g. an admission webhook).
+		if container.Image != "" {
+			images = append(images, containerImage{reference: container.Image, container: container.Name})
+		}
+
+		for _, argImage := range getImagesFromContainerArgs(container) {
+			images = append(images, containerImage{reference: argImage, container: container.Name})
+		}
+
+		if o.envPattern != nil {
+			for _, envImage := range getImagesFromEnv(container.Env, o.envPattern) {
+				images = append(images, containerImage{reference: envImage, container: container.Name})
+			}
+		}
+	}
+
+	return images
+}
+
+// getImagesFromContainerArgs returns the images that are passed to the container in its command or
+// arguments, such as the images that an operator deploys (e.g. --prometheus-config-reloader=quay.io/...).
+// The image can be the value of a flag, a separate argument or part of a shell command.
+func getImagesFromContainerArgs(container corev1.Container) []string {
+	var args []string
+	args = append(args, container.Command...)
+	args = append(args, container.Args...)
+
+	var images []string
+	for _, arg := range args {
+		for _, token := range referenceTokenPattern.FindAllString(arg, -1) {
+			if referencePattern.MatchString(token) {
+				images = append(images, token)
+			}
+		}
+	}
+
+	return images
+}
+
+func getImagesFromEnv(env []corev1.EnvVar, namePattern *regexp.Regexp) []string {
+	var images []string
+	for _, envVar := range env {
+		if !namePattern.MatchString(envVar.Name) {
+			continue
+		}
+
+		if !IsReference(envVar.Value) {
+			continue
+		}
+
+		images = append(images, envVar.Value)
+	}
+
+	return images
+}
@@ -0,0 +1,289 @@
+package images
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetImagesFromYamlFile_Workloads(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		yamlFile string
+	}{
+		{
+			kind: "Pod",
+			yamlFile: `
+apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: busybox:1.32.0`,
+		},
+		{
+			kind: "Deployment",
+			yamlFile: `
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - image: busybox:1.32.0`,
+		},
+		{
+			kind: "Job",
+			yamlFile: `
+apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      containers:
+      - image: busybox:1.32.0`,
+		},
+		{
+			kind: "CronJob",
+			yamlFile: `
+apiVersion: batch/v1beta1
+kind: CronJob
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - image: busybox:1.32.0`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		images, err := getImagesFromYamlFile([]byte(testCase.yamlFile), options{})
+		if err != nil {
+			t.Fatalf("get images from %s: %v", testCase.kind, err)
+		}
+
+		if len(images) != 1 || images[0].reference != "busybox:1.32.0" {
+			t.Errorf("expected %s to contain image busybox:1.32.0, actual %v", testCase.kind, images)
+		}
+	}
+}
+
+func TestGetImagesFromYamlFile_PodSpecContainers(t *testing.T) {
+	yamlFile := `
+apiVersion: v1
+kind: Pod
+spec:
+  initContainers:
+  - name: init
+    image: busybox:1.32.0
+  containers:
+  - name: app
+    image: nginx:1.19.0
+  - name: configured
+  ephemeralContainers:
+  - name: debugger
+    image: alpine:3.12
+    targetContainerName: app`
+
+	images, err := getImagesFromYamlFile([]byte(yamlFile), options{})
+	if err != nil {
+		t.Fatal("get images:", err)
+	}
+
+	expected := []containerImage{
+		{reference: "busybox:1.32.0", container: "init"},
+		{reference: "nginx:1.19.0", container: "app"},
+		{reference: "alpine:3.12", container: "debugger"},
+	}
+
+	if !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected %v, actual %v", expected, images)
+	}
+}
+
+func TestGetImagesFromEnv(t *testing.T) {
+	env := []corev1.EnvVar{
+		{Name: "RELATED_IMAGE_SIDECAR", Value: "quay.io/foo/sidecar:v1.0.0"},
+		{Name: "RELATED_IMAGE_URL", Value: "https://example.com:8080"},
+		{Name: "LOG_LEVEL", Value: "debug:true"},
+		{Name: "SIDECAR_IMAGE", Value: "busybox@sha256:bbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29"},
+	}
+
+	actual := getImagesFromEnv(env, regexp.MustCompile("IMAGE"))
+	expected := []string{
+		"quay.io/foo/sidecar:v1.0.0",
+		"busybox@sha256:bbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29",
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actual)
+	}
+}
+
+func TestGetImagesFromYamlFile_Thanos(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		yamlFile string
+		expected []containerImage
+	}{
+		{
+			kind: "Prometheus",
+			yamlFile: `
+apiVersion: monitoring.coreos.com/v1
+kind: Prometheus
+spec:
+  image: quay.io/prometheus/prometheus:v2.20.0
+  thanos:
+    version: v0.14.0`,
+			expected: []containerImage{
+				{reference: "quay.io/prometheus/prometheus:v2.20.0", container: "prometheus"},
+				{reference: "quay.io/thanos/thanos:v0.14.0", container: "thanos-sidecar"},
+			},
+		},
+		{
+			kind: "ThanosRuler",
+			yamlFile: `
+apiVersion: monitoring.coreos.com/v1
+kind: ThanosRuler
+spec:
+  image: quay.io/thanos/thanos:v0.14.0`,
+			expected: []containerImage{{reference: "quay.io/thanos/thanos:v0.14.0", container: "thanos-ruler"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		images, err := getImagesFromYamlFile([]byte(testCase.yamlFile), options{})
+		if err != nil {
+			t.Fatalf("get images from %s: %v", testCase.kind, err)
+		}
+
+		if !reflect.DeepEqual(images, testCase.expected) {
+			t.Errorf("expected %s images %v, actual %v", testCase.kind, testCase.expected, images)
+		}
+	}
+}
+
+func TestGetImagesFromYamlFile_PrometheusOperator(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		yamlFile string
+		expected []containerImage
+	}{
+		{
+			kind: "Prometheus",
+			yamlFile: `
+apiVersion: monitoring.coreos.com/v1
+kind: Prometheus
+spec:
+  image: quay.io/prometheus/prometheus:v2.20.0
+  containers:
+  - name: oauth-proxy
+    image: quay.io/oauth2-proxy/oauth2-proxy:v7.4.0
+  - name: config-reloader
+    resources:
+      limits:
+        memory: 50Mi
+  initContainers:
+  - name: init-config
+    image: busybox:1.32.0`,
+			expected: []containerImage{
+				{reference: "quay.io/oauth2-proxy/oauth2-proxy:v7.4.0", container: "oauth-proxy"},
+				{reference: "busybox:1.32.0", container: "init-config"},
+				{reference: "quay.io/prometheus/prometheus:v2.20.0", container: "prometheus"},
+			},
+		},
+		{
+			kind: "Prometheus",
+			yamlFile: `
+apiVersion: monitoring.coreos.com/v1
+kind: Prometheus
+spec:
+  image: mycompany.com/prometheus/prometheus:v2.45.0
+  baseImage: quay.io/prometheus/prometheus
+  version: v2.20.0`,
+			expected: []containerImage{
+				{reference: "mycompany.com/prometheus/prometheus:v2.45.0", container: "prometheus"},
+			},
+		},
+		{
+			kind: "Prometheus",
+			yamlFile: `
+apiVersion: monitoring.coreos.com/v1
+kind: Prometheus
+spec:
+  version: v2.45.0`,
+			expected: []containerImage{
+				{reference: "quay.io/prometheus/prometheus:v2.45.0", container: "prometheus"},
+			},
+		},
+		{
+			kind: "Alertmanager",
+			yamlFile: `
+apiVersion: monitoring.coreos.com/v1
+kind: Alertmanager
+spec:
+  baseImage: mycompany.com/prometheus/alertmanager
+  version: v0.21.0
+  tag: v0.21.0-patched`,
+			expected: []containerImage{
+				{reference: "mycompany.com/prometheus/alertmanager:v0.21.0-patched", container: "alertmanager"},
+			},
+		},
+		{
+			kind: "Alertmanager",
+			yamlFile: `
+apiVersion: monitoring.coreos.com/v1
+kind: Alertmanager
+spec:
+  containers:
+  - name: alertmanager-webhook
+    image: mycompany.com/alertmanager-webhook:1.0.0`,
+			expected: []containerImage{
+				{reference: "mycompany.com/alertmanager-webhook:1.0.0", container: "alertmanager-webhook"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		images, err := getImagesFromYamlFile([]byte(testCase.yamlFile), options{})
+		if err != nil {
+			t.Fatalf("get images from %s: %v", testCase.kind, err)
+		}
+
+		if !reflect.DeepEqual(images, testCase.expected) {
+			t.Errorf("expected %s images %v, actual %v", testCase.kind, testCase.expected, images)
+		}
+	}
+}
+
+func TestGetImagesFromContainerArgs(t *testing.T) {
+	container := corev1.Container{
+		Command: []string{"/bin/sh", "-c", "crane copy quay.io/coreos/etcd:v3.4.0 mycompany.com/etcd:v3.4.0"},
+		Args: []string{
+			"--prometheus-config-reloader=quay.io/prometheus-operator/prometheus-config-reloader:v0.40.0",
+			"--config-reloader-image",
+			"jimmidyson/configmap-reload:v0.4.0",
+			"--web.listen-address=:8080",
+			"--kubelet-service=kube-system/kubelet",
+			"--alertmanager-default-base-image=quay.io/prometheus/alertmanager",
+			"--labels=app=thanos,image=quay.io/thanos/thanos:v0.14.0",
+			"--prometheus-address=localhost:9090",
+		},
+	}
+
+	expected := []string{
+		"quay.io/coreos/etcd:v3.4.0",
+		"mycompany.com/etcd:v3.4.0",
+		"quay.io/prometheus-operator/prometheus-config-reloader:v0.40.0",
+		"jimmidyson/configmap-reload:v0.4.0",
+		"quay.io/thanos/thanos:v0.14.0",
+	}
+
+	actual := getImagesFromContainerArgs(container)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actual)
+	}
+}
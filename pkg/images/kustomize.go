@@ -0,0 +1,120 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	kubeyaml "github.com/ghodss/yaml"
+)
+
+var kustomizationFileNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+type kustomization struct {
+	Resources  []string `json:"resources"`
+	Bases      []string `json:"bases"`
+	Components []string `json:"components"`
+}
+
+func getKustomizations(path string, o options) ([]string, error) {
+	var kustomizations []string
+	err := walk(path, o, func(currentFilePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if !fileInfo.IsDir() {
+			return nil
+		}
+
+		if getKustomizationFile(currentFilePath) != "" {
+			kustomizations = append(kustomizations, currentFilePath)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return kustomizations, nil
+}
+
+// getTopLevelKustomizations returns the kustomizations that are not referenced by another kustomization.
+// Kustomizations that are referenced (e.g. a base referenced by an overlay) are built as part of
+// the kustomization that references them, which ensures any overridden images are reported instead.
+func getTopLevelKustomizations(kustomizations []string) ([]string, error) {
+	var referencedDirs []string
+	for _, kustomizationDir := range kustomizations {
+		references, err := getKustomizationReferences(kustomizationDir)
+		if err != nil {
+			return nil, fmt.Errorf("get kustomization references: %w", err)
+		}
+
+		referencedDirs = append(referencedDirs, references...)
+	}
+
+	var topLevelKustomizations []string
+	for _, kustomizationDir := range kustomizations {
+		if !containsPath(referencedDirs, kustomizationDir) {
+			topLevelKustomizations = append(topLevelKustomizations, kustomizationDir)
+		}
+	}
+
+	return topLevelKustomizations, nil
+}
+
+func getKustomizationFile(dir string) string {
+	for _, fileName := range kustomizationFileNames {
+		kustomizationFile := filepath.Join(dir, fileName)
+		if _, err := os.Stat(kustomizationFile); err == nil {
+			return kustomizationFile
+		}
+	}
+
+	return ""
+}
+
+func getKustomizationReferences(dir string) ([]string, error) {
+	contents, err := ioutil.ReadFile(getKustomizationFile(dir))
+	if err != nil {
+		return nil, fmt.Errorf("read kustomization: %w", err)
+	}
+
+	var k kustomization
+	if err := kubeyaml.Unmarshal(contents, &k); err != nil {
+		return nil, fmt.Errorf("unmarshal kustomization: %w", err)
+	}
+
+	var resources []string
+	resources = append(resources, k.Resources...)
+	resources = append(resources, k.Bases...)
+	resources = append(resources, k.Components...)
+
+	// Only resources that are directories can refer to another kustomization.
+	// Files and remote resources are built as part of the kustomization itself.
+	var references []string
+	for _, resource := range resources {
+		resourcePath := filepath.Join(dir, resource)
+
+		fileInfo, err := os.Stat(resourcePath)
+		if err != nil || !fileInfo.IsDir() {
+			continue
+		}
+
+		references = append(references, resourcePath)
+	}
+
+	return references, nil
+}
+
+func buildKustomization(ctx context.Context, kustomizationPath string) ([]byte, error) {
+	builtKustomization, err := execute(ctx, "kustomize", "build", kustomizationPath)
+	if err != nil {
+		return nil, fmt.Errorf("kustomize build %s: %w", kustomizationPath, err)
+	}
+
+	return builtKustomization, nil
+}
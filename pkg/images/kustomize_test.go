@@ -0,0 +1,55 @@
+package images
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetTopLevelKustomizations(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	base := filepath.Join(root, "base")
+	overlay := filepath.Join(root, "overlays", "prod")
+
+	kustomizations := map[string]string{
+		base:    "resources:\n- deployment.yaml\n",
+		overlay: "resources:\n- ../../base\nimages:\n- name: busybox\n  newTag: 1.32.0\n",
+	}
+
+	for dir, contents := range kustomizations {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			t.Fatal("mkdir:", err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(contents), os.ModePerm); err != nil {
+			t.Fatal("write file:", err)
+		}
+	}
+
+	allKustomizations, err := getKustomizations(root, newOptions())
+	if err != nil {
+		t.Fatal("get kustomizations:", err)
+	}
+
+	expectedKustomizations := []string{base, overlay}
+	if !reflect.DeepEqual(allKustomizations, expectedKustomizations) {
+		t.Errorf("expected kustomizations %v, actual %v", expectedKustomizations, allKustomizations)
+	}
+
+	topLevelKustomizations, err := getTopLevelKustomizations(allKustomizations)
+	if err != nil {
+		t.Fatal("get top level kustomizations:", err)
+	}
+
+	expectedTopLevelKustomizations := []string{overlay}
+	if !reflect.DeepEqual(topLevelKustomizations, expectedTopLevelKustomizations) {
+		t.Errorf("expected top level kustomizations %v, actual %v", expectedTopLevelKustomizations, topLevelKustomizations)
+	}
+}
@@ -0,0 +1,95 @@
+package images
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Location returns the path of the file that the resource was found in, followed by the
+// line of the reference to the image when it is known (e.g. manifests/app.yaml:12).
+func (r Resource) Location() string {
+	if r.Line == 0 {
+		return r.Path
+	}
+
+	return r.Path + ":" + strconv.Itoa(r.Line)
+}
+
+// lineIndex indexes the lines of a file that reference images as the file is read, so that the lines of the images
+// can be found once they are found in the documents of the file, without keeping the contents of the file. The
+// documents of a file are parsed and marshaled again before images are found, so the lines are found by searching
+// the original contents of the file for the reference, which makes them approximate.
+//
+// Only the values of image fields and the values that look like a reference to an image (see IsReference) are
+// indexed, so the memory used depends on the number of references in the file rather than on its size.
+type lineIndex struct {
+
+	// line is the number of lines that were indexed, and lineStart is true when the next
+	// part of the file that is indexed is at the start of a line.
+	line      int
+	lineStart bool
+
+	imageLines  map[string][]int
+	mentions    map[string]int
+	occurrences map[string]int
+}
+
+func newLineIndex() *lineIndex {
+	return &lineIndex{
+		lineStart:   true,
+		imageLines:  make(map[string][]int),
+		mentions:    make(map[string]int),
+		occurrences: make(map[string]int),
+	}
+}
+
+// add indexes the next line of the file, which may only be part of a line that is longer than the buffer the file
+// is read with. References that are split between the parts of a line are not indexed.
+func (x *lineIndex) add(line []byte) {
+	if x.lineStart {
+		x.line++
+	}
+	x.lineStart = bytes.HasSuffix(line, []byte("\n"))
+
+	tokens := strings.FieldsFunc(string(line), func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("\"'=,[]{}", r)
+	})
+
+	for t, token := range tokens {
+		if t > 0 && tokens[t-1] == "image:" {
+			x.imageLines[token] = append(x.imageLines[token], x.line)
+			continue
+		}
+
+		// Keys (e.g. image:) are the most common tokens with a colon, which are not references.
+		if strings.HasSuffix(token, ":") || !IsReference(token) {
+			continue
+		}
+
+		if _, ok := x.mentions[token]; !ok {
+			x.mentions[token] = x.line
+		}
+	}
+}
+
+// find returns the line of the next reference to the image in the file, or zero when the reference cannot be
+// found. Each time the same image is found in the same file, the next line that references it is returned, so
+// that every resource that uses the image points to its own line. Lines that set an image field are preferred
+// over other lines that mention the reference, such as the name of a container or a container argument.
+func (x *lineIndex) find(reference string) int {
+	occurrence := x.occurrences[reference]
+	x.occurrences[reference]++
+
+	imageLines := x.imageLines[reference]
+	if len(imageLines) == 0 {
+		return x.mentions[reference]
+	}
+
+	if occurrence >= len(imageLines) {
+		return imageLines[len(imageLines)-1]
+	}
+
+	return imageLines[occurrence]
+}
@@ -0,0 +1,137 @@
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	kubeyaml "github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// openShiftObjectReference is a reference to an image in an OpenShift resource. Only references of the
+// DockerImage kind refer to an image in a registry, other kinds (e.g. ImageStreamTag) refer to image
+// streams inside of the cluster.
+type openShiftObjectReference struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// openShiftBuildStrategy is the part of a build strategy that declares the builder image.
+type openShiftBuildStrategy struct {
+	From *openShiftObjectReference `json:"from"`
+}
+
+func isOpenShiftResource(apiVersion string, kind string) bool {
+	group := strings.Split(apiVersion, "/")[0]
+
+	// Older versions of OpenShift serve their resources from the legacy v1 API without a group.
+	switch kind {
+	case "DeploymentConfig":
+		return group == "apps.openshift.io" || apiVersion == "v1"
+	case "ImageStream":
+		return group == "image.openshift.io" || apiVersion == "v1"
+	case "BuildConfig":
+		return group == "build.openshift.io" || apiVersion == "v1"
+	}
+
+	return false
+}
+
+// getOpenShiftImages returns the images of the pod template of a DeploymentConfig, the images that the tags
+// of an ImageStream are imported from, and the builder, input and output images of a BuildConfig.
+func getOpenShiftImages(yamlFile []byte, kind string, o options) ([]containerImage, error) {
+	switch kind {
+	case "DeploymentConfig":
+		var deploymentConfig struct {
+			Spec struct {
+				Template *corev1.PodTemplateSpec `json:"template"`
+			} `json:"spec"`
+		}
+		if err := kubeyaml.Unmarshal(yamlFile, &deploymentConfig); err != nil {
+			return nil, fmt.Errorf("unmarshal deployment config: %w", err)
+		}
+
+		if deploymentConfig.Spec.Template == nil {
+			return nil, nil
+		}
+
+		return getImagesFromPodSpec(deploymentConfig.Spec.Template.Spec, o), nil
+
+	case "ImageStream":
+		var imageStream struct {
+			Spec struct {
+				Tags []struct {
+					Name string                    `json:"name"`
+					From *openShiftObjectReference `json:"from"`
+				} `json:"tags"`
+			} `json:"spec"`
+		}
+		if err := kubeyaml.Unmarshal(yamlFile, &imageStream); err != nil {
+			return nil, fmt.Errorf("unmarshal image stream: %w", err)
+		}
+
+		var images []containerImage
+		for _, tag := range imageStream.Spec.Tags {
+			if reference, ok := getOpenShiftImage(tag.From); ok {
+				images = append(images, containerImage{reference: reference})
+			}
+		}
+
+		return images, nil
+
+	case "BuildConfig":
+		var buildConfig struct {
+			Spec struct {
+				Strategy struct {
+					DockerStrategy *openShiftBuildStrategy `json:"dockerStrategy"`
+					SourceStrategy *openShiftBuildStrategy `json:"sourceStrategy"`
+					CustomStrategy *openShiftBuildStrategy `json:"customStrategy"`
+				} `json:"strategy"`
+				Source struct {
+					Images []struct {
+						From *openShiftObjectReference `json:"from"`
+					} `json:"images"`
+				} `json:"source"`
+				Output struct {
+					To *openShiftObjectReference `json:"to"`
+				} `json:"output"`
+			} `json:"spec"`
+		}
+		if err := kubeyaml.Unmarshal(yamlFile, &buildConfig); err != nil {
+			return nil, fmt.Errorf("unmarshal build config: %w", err)
+		}
+
+		var references []*openShiftObjectReference
+		for _, strategy := range []*openShiftBuildStrategy{buildConfig.Spec.Strategy.DockerStrategy, buildConfig.Spec.Strategy.SourceStrategy, buildConfig.Spec.Strategy.CustomStrategy} {
+			if strategy != nil {
+				references = append(references, strategy.From)
+			}
+		}
+
+		for _, sourceImage := range buildConfig.Spec.Source.Images {
+			references = append(references, sourceImage.From)
+		}
+
+		references = append(references, buildConfig.Spec.Output.To)
+
+		var images []containerImage
+		for _, reference := range references {
+			if image, ok := getOpenShiftImage(reference); ok {
+				images = append(images, containerImage{reference: image})
+			}
+		}
+
+		return images, nil
+	}
+
+	return nil, nil
+}
+
+// getOpenShiftImage returns the image that the reference refers to, when it refers to an image in a registry.
+func getOpenShiftImage(reference *openShiftObjectReference) (string, bool) {
+	if reference == nil || reference.Kind != "DockerImage" || reference.Name == "" {
+		return "", false
+	}
+
+	return reference.Name, true
+}
@@ -0,0 +1,86 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetImagesFromYamlFile_OpenShift(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		yamlFile string
+		expected []containerImage
+	}{
+		{
+			kind: "DeploymentConfig",
+			yamlFile: `
+apiVersion: apps.openshift.io/v1
+kind: DeploymentConfig
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19`,
+			expected: []containerImage{
+				{reference: "nginx:1.19", container: "web"},
+			},
+		},
+		{
+			kind: "ImageStream",
+			yamlFile: `
+apiVersion: image.openshift.io/v1
+kind: ImageStream
+spec:
+  tags:
+  - name: "1.19"
+    from:
+      kind: DockerImage
+      name: docker.io/library/nginx:1.19
+  - name: latest
+    from:
+      kind: ImageStreamTag
+      name: "1.19"`,
+			expected: []containerImage{
+				{reference: "docker.io/library/nginx:1.19"},
+			},
+		},
+		{
+			kind: "BuildConfig",
+			yamlFile: `
+apiVersion: build.openshift.io/v1
+kind: BuildConfig
+spec:
+  source:
+    images:
+    - from:
+        kind: DockerImage
+        name: quay.io/myorg/assets:v1.0.0
+  strategy:
+    sourceStrategy:
+      from:
+        kind: DockerImage
+        name: registry.access.redhat.com/ubi8/nodejs-14:1
+  output:
+    to:
+      kind: DockerImage
+      name: quay.io/myorg/app:v1.0.0`,
+			expected: []containerImage{
+				{reference: "registry.access.redhat.com/ubi8/nodejs-14:1"},
+				{reference: "quay.io/myorg/assets:v1.0.0"},
+				{reference: "quay.io/myorg/app:v1.0.0"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := getImagesFromYamlFile([]byte(testCase.yamlFile), options{})
+		if err != nil {
+			t.Fatalf("get images from %s: %v", testCase.kind, err)
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected images %+v for %s, actual %+v", testCase.expected, testCase.kind, actual)
+		}
+	}
+}
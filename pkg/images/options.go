@@ -0,0 +1,289 @@
+package images
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Option configures how images are discovered.
+type Option func(*options)
+
+type options struct {
+	ctx            context.Context
+	helm           bool
+	helmValues     []string
+	kustomize      bool
+	envPattern     *regexp.Regexp
+	crdPaths       []CRDImagePath
+	strict         bool
+	deepScan       bool
+	dockerfiles    bool
+	ciFiles        bool
+	terraform      bool
+	followSources  bool
+	sourceDepth    int
+	workers        int
+	skipDirs       []string
+	noGitignore    bool
+	followSymlinks bool
+	cacheDir       string
+	kinds          []string
+	excludeKinds   []string
+	namespaces     []string
+	selector       labels.Selector
+	registryHosts  map[string]bool
+
+	templateDefaults bool
+	maxDocumentSize  int
+
+	// keepContents keeps the contents of the documents that are read, which are otherwise
+	// dropped once the images of each document are found (see readDocuments).
+	keepContents bool
+
+	parseErrorHandler func(path string, err error)
+}
+
+// WithHelm renders any Helm charts that are found before discovering images.
+// The given values files are passed to Helm when rendering each chart.
+func WithHelm(valuesFiles ...string) Option {
+	return func(o *options) {
+		o.helm = true
+		o.helmValues = valuesFiles
+	}
+}
+
+// WithKustomize builds any kustomizations that are found before discovering images.
+func WithKustomize() Option {
+	return func(o *options) {
+		o.kustomize = true
+	}
+}
+
+// WithEnvImages finds images in the values of container environment variables
+// whose names match the given pattern (e.g. RELATED_IMAGE_.*).
+func WithEnvImages(namePattern *regexp.Regexp) Option {
+	return func(o *options) {
+		o.envPattern = namePattern
+	}
+}
+
+// WithCRDImagePaths finds images in custom resources at the given paths.
+func WithCRDImagePaths(crdImagePaths []CRDImagePath) Option {
+	return func(o *options) {
+		o.crdPaths = crdImagePaths
+	}
+}
+
+// WithStrict returns an error when an image is found that does not have an explicit tag or digest,
+// instead of defaulting the image to the latest tag, and when a YAML document cannot be parsed, instead
+// of skipping the document.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithDeepScan also finds images in any string value of any resource that looks like an image
+// reference (e.g. in the data of a ConfigMap), including resources that are not known to contain
+// images. The resources of images that are only found this way are marked as heuristic.
+func WithDeepScan() Option {
+	return func(o *options) {
+		o.deepScan = true
+	}
+}
+
+// WithDockerfiles also finds the base images in the FROM instructions of the Dockerfiles
+// (e.g. Dockerfile, Dockerfile.prod or build.Dockerfile), such as the images that are
+// needed to build images in CI.
+func WithDockerfiles() Option {
+	return func(o *options) {
+		o.dockerfiles = true
+	}
+}
+
+// WithCIFiles also finds the images that CI jobs run in, which are the containers, services and
+// Docker steps of the jobs in GitHub Actions workflows (.github/workflows/*.yml) and the images and
+// services of the jobs in GitLab CI configuration files (.gitlab-ci.yml).
+func WithCIFiles() Option {
+	return func(o *options) {
+		o.ciFiles = true
+	}
+}
+
+// WithTerraform also finds the images that are set in Terraform files (*.tf), such as the images of the
+// containers of the resources of the Kubernetes provider and the images set by the set blocks of the
+// Helm releases of the Helm provider.
+func WithTerraform() Option {
+	return func(o *options) {
+		o.terraform = true
+	}
+}
+
+// WithFollowSources also finds the images that are deployed by Argo CD Applications and Flux
+// HelmReleases and Kustomizations, by fetching the Git repositories and Helm charts that they
+// reference. Fetching the sources requires git and helm, as well as access to the sources.
+func WithFollowSources() Option {
+	return func(o *options) {
+		o.followSources = true
+	}
+}
+
+// WithSkipDirs skips the directories that match any of the patterns when finding images. A pattern
+// can match the name of a directory (e.g. testdata) or its path relative to the path being searched
+// (e.g. charts/*/tests). The .git, vendor and node_modules directories are always skipped.
+func WithSkipDirs(patterns ...string) Option {
+	return func(o *options) {
+		o.skipDirs = patterns
+	}
+}
+
+// WithoutGitignore also finds images in the files and directories that are ignored by .gitignore files.
+func WithoutGitignore() Option {
+	return func(o *options) {
+		o.noGitignore = true
+	}
+}
+
+// WithFollowSymlinks follows symbolic links to directories when finding images. Each
+// directory is only searched once, even when it is linked to from multiple places.
+func WithFollowSymlinks() Option {
+	return func(o *options) {
+		o.followSymlinks = true
+	}
+}
+
+// WithCacheDir caches the Git repositories that are cloned and the Helm charts that are fetched to find images
+// in the directory (e.g. ~/.cache/sinker), so that they are not fetched again every time that images are found.
+// Repositories are cached for each ref and only the new commits of the ref are fetched when the repository is
+// used again. Charts are only cached when their version is known, as the version of a chart does not change.
+func WithCacheDir(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = dir
+	}
+}
+
+// WithParseErrorHandler calls the handler with the path of the YAML file of each document that cannot be parsed
+// and the error, so that the documents that are skipped because of a typo can be reported. The handler is also
+// called with an error that wraps ErrUnrenderedTemplate for each file that contains Go templates, whether or
+// not it could be parsed. The handler can be called from multiple goroutines at the same time.
+func WithParseErrorHandler(handler func(path string, err error)) Option {
+	return func(o *options) {
+		o.parseErrorHandler = handler
+	}
+}
+
+// WithTemplateDefaults finds the images of the files that contain Go templates (e.g. the templates of a Helm chart
+// that is not rendered with WithHelm) on a best-effort basis, by replacing the references to the values of the chart
+// with the default values in the values.yaml of the chart. The templates are not evaluated, so the conditionals and
+// loops of the templates are removed rather than followed, and images that are built by helper templates are missed.
+func WithTemplateDefaults() Option {
+	return func(o *options) {
+		o.templateDefaults = true
+	}
+}
+
+// WithKinds only finds the images of the resources of the given kinds (e.g. Deployment and CronJob), which are matched
+// regardless of their case. The other resources are skipped without looking for images in them, which speeds up finding
+// images in repositories with many resources that do not run images (e.g. ConfigMaps). Files that are not Kubernetes
+// resources have the kind of the file (Dockerfile, Terraform, Compose, GitHubWorkflow or GitLabCI).
+func WithKinds(kinds ...string) Option {
+	return func(o *options) {
+		o.kinds = kinds
+	}
+}
+
+// WithExcludeKinds skips the resources of the given kinds, which are matched in the same way as WithKinds.
+func WithExcludeKinds(kinds ...string) Option {
+	return func(o *options) {
+		o.excludeKinds = kinds
+	}
+}
+
+// WithNamespaces only finds the images of the resources in the given namespaces, by the namespace in their metadata.
+// Resources without a namespace (e.g. resources that are deployed with kubectl apply --namespace) and files that are
+// not Kubernetes resources are skipped.
+func WithNamespaces(namespaces ...string) Option {
+	return func(o *options) {
+		o.namespaces = namespaces
+	}
+}
+
+// WithLabelSelector only finds the images of the resources whose labels match the selector (e.g. the selector parsed
+// from app.kubernetes.io/part-of=platform by labels.Parse), which allows a team to find the images of their own components
+// in a repository that is shared with other teams. The labels of the pod templates of workloads are not matched.
+func WithLabelSelector(selector labels.Selector) Option {
+	return func(o *options) {
+		o.selector = selector
+	}
+}
+
+// WithMaxDocumentSize stops reading a YAML file at the first document that is larger than the size in bytes
+// (DefaultMaxDocumentSize by default), which is passed to the parse error handler as ErrDocumentTooLarge, or fails
+// when the options are strict. The documents are decoded one at a time, so the document is not read into memory.
+// A size of zero does not limit the size of documents.
+func WithMaxDocumentSize(size int) Option {
+	return func(o *options) {
+		o.maxDocumentSize = size
+	}
+}
+
+// WithContext stops the external tools (e.g. helm and kustomize) that are run to
+// discover images when the context is cancelled.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithRegistryHosts sets hosts that are known to be registries, so that they are the host of the references that
+// start with them even though they do not look like a host (e.g. registry/team/app, where registry is the name
+// of a host on an internal network). The hosts are compared without regard to case.
+func WithRegistryHosts(hosts ...string) Option {
+	return func(o *options) {
+		if o.registryHosts == nil {
+			o.registryHosts = make(map[string]bool)
+		}
+
+		for _, host := range hosts {
+			o.registryHosts[strings.ToLower(host)] = true
+		}
+	}
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		ctx:             context.Background(),
+		workers:         runtime.NumCPU(),
+		maxDocumentSize: DefaultMaxDocumentSize,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// keepsContents returns true when the contents of the documents are needed after their images are found,
+// which is the case when they are read as files or the sources of delivery resources are followed.
+func (o options) keepsContents() bool {
+	return o.keepContents || o.followSources
+}
+
+// handleParseError returns an error for the YAML file that cannot be parsed when strict.
+// Otherwise the error is passed to the parse error handler, if any, and nil is returned.
+func (o options) handleParseError(path string, err error) error {
+	if o.strict {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if o.parseErrorHandler != nil {
+		o.parseErrorHandler(path, err)
+	}
+
+	return nil
+}
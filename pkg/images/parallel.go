@@ -0,0 +1,47 @@
+package images
+
+import (
+	"sync"
+)
+
+// forEach calls the function once for every index from zero to count, running at most
+// the given number of calls at the same time. When any of the calls fail, the error of
+// the call with the lowest index is returned once every call has completed.
+func forEach(workers int, count int, f func(i int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	if workers > count {
+		workers = count
+	}
+
+	errs := make([]error, count)
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = f(i)
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package images
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEach(t *testing.T) {
+	var calls int32
+	err := forEach(4, 100, func(i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 10 || i == 50 {
+			return fmt.Errorf("call %v", i)
+		}
+
+		return nil
+	})
+
+	if calls != 100 {
+		t.Errorf("expected 100 calls, actual %v", calls)
+	}
+
+	if err == nil || err.Error() != "call 10" {
+		t.Errorf("expected error of the lowest index, actual %v", err)
+	}
+}
+
+func TestFindImages_Workers(t *testing.T) {
+	path := writeBenchmarkResources(t, 200)
+	defer os.RemoveAll(path)
+
+	expected, err := findImages(path, options{workers: 1})
+	if err != nil {
+		t.Fatal("find images with one worker:", err)
+	}
+
+	actual, err := findImages(path, options{workers: 8})
+	if err != nil {
+		t.Fatal("find images with eight workers:", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Error("expected the same images regardless of the number of workers")
+	}
+}
+
+func BenchmarkFindImages(b *testing.B) {
+	path := writeBenchmarkResources(b, 5000)
+	defer os.RemoveAll(path)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%v", workers), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				if _, err := findImages(path, options{workers: workers}); err != nil {
+					b.Fatal("find images:", err)
+				}
+			}
+		})
+	}
+}
+
+// writeBenchmarkResources writes the given number of files that each contain a Deployment
+// and a Service to a temporary directory, and returns the path to the directory.
+func writeBenchmarkResources(tb testing.TB, count int) string {
+	path, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		tb.Fatal("create temp dir:", err)
+	}
+
+	for i := 0; i < count; i++ {
+		contents := fmt.Sprintf(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app-%[1]v
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: mycompany.com/app-%[2]v:v1.0.%[1]v
+      - name: proxy
+        image: envoyproxy/envoy:v1.16.0
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app-%[1]v
+spec:
+  ports:
+  - port: 80
+`, i, i%50)
+
+		file := filepath.Join(path, fmt.Sprintf("app-%v.yaml", i))
+		if err := ioutil.WriteFile(file, []byte(contents), 0644); err != nil {
+			tb.Fatal("write file:", err)
+		}
+	}
+
+	return path
+}
@@ -0,0 +1,182 @@
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	kubeyaml "github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// tektonTaskSpec is the part of a Tekton task that declares the containers that run the task.
+type tektonTaskSpec struct {
+	Steps        []corev1.Container `json:"steps"`
+	Sidecars     []corev1.Container `json:"sidecars"`
+	StepTemplate *corev1.Container  `json:"stepTemplate"`
+}
+
+// tektonPipelineSpec is the part of a Tekton pipeline that declares the tasks that are embedded in the pipeline.
+type tektonPipelineSpec struct {
+	Tasks []struct {
+		TaskSpec *tektonTaskSpec `json:"taskSpec"`
+	} `json:"tasks"`
+	Finally []struct {
+		TaskSpec *tektonTaskSpec `json:"taskSpec"`
+	} `json:"finally"`
+}
+
+// argoWorkflowSpec is the part of an Argo workflow that declares the templates of the workflow.
+type argoWorkflowSpec struct {
+	Templates []struct {
+		Name           string             `json:"name"`
+		Container      *corev1.Container  `json:"container"`
+		Script         *corev1.Container  `json:"script"`
+		InitContainers []corev1.Container `json:"initContainers"`
+		Sidecars       []corev1.Container `json:"sidecars"`
+	} `json:"templates"`
+}
+
+func isTektonResource(apiVersion string, kind string) bool {
+	if !strings.HasPrefix(apiVersion, "tekton.dev/") {
+		return false
+	}
+
+	return kind == "Task" || kind == "ClusterTask" || kind == "TaskRun" || kind == "Pipeline" || kind == "PipelineRun"
+}
+
+func isArgoWorkflow(apiVersion string, kind string) bool {
+	if !strings.HasPrefix(apiVersion, "argoproj.io/") {
+		return false
+	}
+
+	return kind == "Workflow" || kind == "WorkflowTemplate" || kind == "ClusterWorkflowTemplate" || kind == "CronWorkflow"
+}
+
+// getTektonImages returns the images of the steps and sidecars of a Tekton Task or ClusterTask,
+// the tasks that are embedded in a Pipeline, and the tasks that are embedded in a TaskRun or PipelineRun.
+func getTektonImages(yamlFile []byte, kind string, o options) ([]containerImage, error) {
+	var images []containerImage
+	switch kind {
+	case "Task", "ClusterTask":
+		var task struct {
+			Spec tektonTaskSpec `json:"spec"`
+		}
+		if err := kubeyaml.Unmarshal(yamlFile, &task); err != nil {
+			return nil, fmt.Errorf("unmarshal task: %w", err)
+		}
+
+		images = getTektonTaskImages(task.Spec, o)
+
+	case "TaskRun":
+		var taskRun struct {
+			Spec struct {
+				TaskSpec *tektonTaskSpec `json:"taskSpec"`
+			} `json:"spec"`
+		}
+		if err := kubeyaml.Unmarshal(yamlFile, &taskRun); err != nil {
+			return nil, fmt.Errorf("unmarshal task run: %w", err)
+		}
+
+		if taskRun.Spec.TaskSpec != nil {
+			images = getTektonTaskImages(*taskRun.Spec.TaskSpec, o)
+		}
+
+	case "Pipeline":
+		var pipeline struct {
+			Spec tektonPipelineSpec `json:"spec"`
+		}
+		if err := kubeyaml.Unmarshal(yamlFile, &pipeline); err != nil {
+			return nil, fmt.Errorf("unmarshal pipeline: %w", err)
+		}
+
+		images = getTektonPipelineImages(pipeline.Spec, o)
+
+	case "PipelineRun":
+		var pipelineRun struct {
+			Spec struct {
+				PipelineSpec *tektonPipelineSpec `json:"pipelineSpec"`
+			} `json:"spec"`
+		}
+		if err := kubeyaml.Unmarshal(yamlFile, &pipelineRun); err != nil {
+			return nil, fmt.Errorf("unmarshal pipeline run: %w", err)
+		}
+
+		if pipelineRun.Spec.PipelineSpec != nil {
+			images = getTektonPipelineImages(*pipelineRun.Spec.PipelineSpec, o)
+		}
+	}
+
+	return images, nil
+}
+
+func getTektonPipelineImages(pipeline tektonPipelineSpec, o options) []containerImage {
+	var images []containerImage
+	for _, task := range pipeline.Tasks {
+		if task.TaskSpec != nil {
+			images = append(images, getTektonTaskImages(*task.TaskSpec, o)...)
+		}
+	}
+
+	for _, task := range pipeline.Finally {
+		if task.TaskSpec != nil {
+			images = append(images, getTektonTaskImages(*task.TaskSpec, o)...)
+		}
+	}
+
+	return images
+}
+
+func getTektonTaskImages(task tektonTaskSpec, o options) []containerImage {
+	var images []containerImage
+	if task.StepTemplate != nil {
+		images = append(images, getImagesFromContainers([]corev1.Container{*task.StepTemplate}, o)...)
+	}
+
+	images = append(images, getImagesFromContainers(task.Steps, o)...)
+	images = append(images, getImagesFromContainers(task.Sidecars, o)...)
+
+	return images
+}
+
+// getArgoWorkflowImages returns the images of the container and script templates of an Argo
+// Workflow, WorkflowTemplate, ClusterWorkflowTemplate or CronWorkflow, including their init
+// containers and sidecars. Containers without a name are named after their template.
+func getArgoWorkflowImages(yamlFile []byte, kind string, o options) ([]containerImage, error) {
+	var workflow struct {
+		Spec struct {
+			argoWorkflowSpec
+			WorkflowSpec argoWorkflowSpec `json:"workflowSpec"`
+		} `json:"spec"`
+	}
+	if err := kubeyaml.Unmarshal(yamlFile, &workflow); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %w", strings.ToLower(kind), err)
+	}
+
+	spec := workflow.Spec.argoWorkflowSpec
+	if kind == "CronWorkflow" {
+		spec = workflow.Spec.WorkflowSpec
+	}
+
+	var images []containerImage
+	for _, template := range spec.Templates {
+		var containers []corev1.Container
+		if template.Container != nil {
+			containers = append(containers, *template.Container)
+		}
+		if template.Script != nil {
+			containers = append(containers, *template.Script)
+		}
+		containers = append(containers, template.InitContainers...)
+		containers = append(containers, template.Sidecars...)
+
+		for i := range containers {
+			if containers[i].Name == "" {
+				containers[i].Name = template.Name
+			}
+		}
+
+		images = append(images, getImagesFromContainers(containers, o)...)
+	}
+
+	return images, nil
+}
@@ -0,0 +1,108 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetImagesFromYamlFile_Pipelines(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		yamlFile string
+		expected []containerImage
+	}{
+		{
+			kind: "Task",
+			yamlFile: `
+apiVersion: tekton.dev/v1beta1
+kind: Task
+spec:
+  steps:
+  - name: build
+    image: golang:1.15
+  sidecars:
+  - name: docker
+    image: docker:19.03-dind`,
+			expected: []containerImage{
+				{reference: "golang:1.15", container: "build"},
+				{reference: "docker:19.03-dind", container: "docker"},
+			},
+		},
+		{
+			kind: "Pipeline",
+			yamlFile: `
+apiVersion: tekton.dev/v1beta1
+kind: Pipeline
+spec:
+  tasks:
+  - name: lint
+    taskRef:
+      name: golangci-lint
+  - name: test
+    taskSpec:
+      steps:
+      - name: test
+        image: golang:1.15
+  finally:
+  - name: notify
+    taskSpec:
+      steps:
+      - name: notify
+        image: curlimages/curl:7.72.0`,
+			expected: []containerImage{
+				{reference: "golang:1.15", container: "test"},
+				{reference: "curlimages/curl:7.72.0", container: "notify"},
+			},
+		},
+		{
+			kind: "Workflow",
+			yamlFile: `
+apiVersion: argoproj.io/v1alpha1
+kind: Workflow
+spec:
+  templates:
+  - name: steps
+    steps:
+    - - name: hello
+        template: whalesay
+  - name: whalesay
+    container:
+      image: docker/whalesay:latest
+  - name: script
+    script:
+      image: python:3.8-alpine
+      source: print("hello")`,
+			expected: []containerImage{
+				{reference: "docker/whalesay:latest", container: "whalesay"},
+				{reference: "python:3.8-alpine", container: "script"},
+			},
+		},
+		{
+			kind: "CronWorkflow",
+			yamlFile: `
+apiVersion: argoproj.io/v1alpha1
+kind: CronWorkflow
+spec:
+  workflowSpec:
+    templates:
+    - name: backup
+      container:
+        name: main
+        image: postgres:13`,
+			expected: []containerImage{
+				{reference: "postgres:13", container: "main"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := getImagesFromYamlFile([]byte(testCase.yamlFile), options{})
+		if err != nil {
+			t.Fatalf("get images from %s: %v", testCase.kind, err)
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected images %+v for %s, actual %+v", testCase.expected, testCase.kind, actual)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+)
+
+// ParseReference parses a reference to an image (e.g. quay.io/coreos/prometheus-operator:v0.40.0)
+// into its host, repository, tag and digest.
+//
+// The repository is returned as it was written. Images on Docker Hub that do not include a
+// host are not normalized (e.g. busybox remains busybox instead of docker.io/library/busybox).
+// When the reference has neither a tag nor a digest, the tag defaults to latest.
+//
+// Only the WithRegistryHosts option is used when parsing a reference.
+func ParseReference(ref string, opts ...Option) (Image, error) {
+	return parseReference(ref, newOptions(opts...))
+}
+
+func parseReference(ref string, o options) (Image, error) {
+	parsedReference, err := reference.Parse(ref)
+	if err != nil {
+		return Image{}, fmt.Errorf("parse reference: %w", err)
+	}
+
+	named, ok := parsedReference.(reference.Named)
+	if !ok {
+		return Image{}, errors.New("reference does not contain a repository")
+	}
+
+	host, repository := splitHost(named.Name(), o)
+
+	image := Image{
+		Reference:  ref,
+		Host:       host,
+		Repository: repository,
+	}
+
+	if tagged, ok := parsedReference.(reference.Tagged); ok {
+		image.Tag = tagged.Tag()
+	}
+
+	if digested, ok := parsedReference.(reference.Digested); ok {
+		image.Digest = digested.Digest().String()
+	}
+
+	if image.Tag == "" && image.Digest == "" {
+		image.Tag = "latest"
+	}
+
+	return image, nil
+}
+
+// NormalizeReference returns the fully qualified form of the reference, in which images on Docker Hub include
+// the docker.io host and the library namespace of official images, and references without a tag or digest use
+// the latest tag (e.g. nginx becomes docker.io/library/nginx:latest). References that refer to the same image
+// in different ways (e.g. nginx:1.25 and docker.io/library/nginx:1.25) have the same normalized form.
+//
+// Only the WithRegistryHosts option is used when normalizing a reference.
+func NormalizeReference(ref string, opts ...Option) (string, error) {
+	o := newOptions(opts...)
+
+	// The registry hosts of the options do not look like a host to the reference
+	// package, which would otherwise qualify them as Docker Hub repositories.
+	if host, _ := splitHost(ref, o); o.registryHosts[strings.ToLower(host)] {
+		parsedReference, err := reference.Parse(ref)
+		if err != nil {
+			return "", fmt.Errorf("parse reference: %w", err)
+		}
+
+		named, ok := parsedReference.(reference.Named)
+		if !ok {
+			return "", errors.New("reference does not contain a repository")
+		}
+
+		return reference.TagNameOnly(named).String(), nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("parse normalized reference: %w", err)
+	}
+
+	return reference.TagNameOnly(named).String(), nil
+}
+
+// hasTagOrDigest returns true if the reference explicitly includes a tag or a digest.
+func hasTagOrDigest(ref string) bool {
+	parsedReference, err := reference.Parse(ref)
+	if err != nil {
+		return false
+	}
+
+	_, tagged := parsedReference.(reference.Tagged)
+	_, digested := parsedReference.(reference.Digested)
+
+	return tagged || digested
+}
+
+// IsReference returns true if the value looks like a reference to a container image
+// that has either a tag or a digest (e.g. quay.io/coreos/prometheus-operator:v0.40.0).
+func IsReference(value string) bool {
+	if value == "" || strings.ContainsAny(value, " \t\n") || strings.Contains(value, "://") {
+		return false
+	}
+
+	if !strings.Contains(value, ":") && !strings.Contains(value, "@") {
+		return false
+	}
+
+	if _, err := ParseReference(value); err != nil {
+		return false
+	}
+
+	return true
+}
+
+// referenceTokenPattern splits values into the tokens that could be an image reference, such as the value
+// of a container argument (e.g. --image=quay.io/coreos/prometheus-operator:v0.40.0) or a value embedded
+// in a configuration file that is stored in a ConfigMap.
+var referenceTokenPattern = regexp.MustCompile(`[^\s"'=,;\[\]{}()<>]+`)
+
+// referencePattern matches tokens that look like an image reference. To avoid most false positives
+// (e.g. an address such as localhost:9090), the reference must include a repository path and an
+// explicit tag or digest.
+var referencePattern = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9.-]*[a-zA-Z0-9])?(?::[0-9]+)?/)?[a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)+(?::[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}|@sha256:[a-f0-9]{64})$`)
+
+// SplitHost splits the name of an image (e.g. quay.io/coreos/etcd) into its host and repository,
+// where the host is empty for images on Docker Hub (see splitHost).
+//
+// Only the WithRegistryHosts option is used when splitting the name.
+func SplitHost(name string, opts ...Option) (string, string) {
+	return splitHost(name, newOptions(opts...))
+}
+
+// splitHost splits the name of an image into its host and repository.
+//
+// The first component of the name is only considered to be the host when it looks
+// like a host (e.g. quay.io or localhost:5000), which is the same rule that the Docker
+// client uses, or is one of the registry hosts of the options. Otherwise the image is
+// on Docker Hub and the host is empty.
+func splitHost(name string, o options) (string, string) {
+	nameTokens := strings.SplitN(name, "/", 2)
+	if len(nameTokens) == 1 {
+		return "", name
+	}
+
+	looksLikeHost := strings.ContainsAny(nameTokens[0], ".:") || nameTokens[0] == "localhost"
+	if !looksLikeHost && !o.registryHosts[strings.ToLower(nameTokens[0])] {
+		return "", name
+	}
+
+	return nameTokens[0], nameTokens[1]
+}
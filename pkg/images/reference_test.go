@@ -0,0 +1,126 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	const digest = "sha256:bbda10abb0b7dc57cfaab5d70ae55bd5aedfa3271686bace9818bba84cd22c29"
+
+	testCases := []struct {
+		reference string
+		expected  Image
+	}{
+		{
+			"busybox:1.32.0",
+			Image{Repository: "busybox", Tag: "1.32.0"},
+		},
+		{
+			"nginx",
+			Image{Repository: "nginx", Tag: "latest"},
+		},
+		{
+			"coreos/prometheus-operator:v0.40.0",
+			Image{Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		},
+		{
+			"quay.io/coreos/prometheus-operator:v0.40.0",
+			Image{Host: "quay.io", Repository: "coreos/prometheus-operator", Tag: "v0.40.0"},
+		},
+		{
+			"localhost:5000/foo",
+			Image{Host: "localhost:5000", Repository: "foo", Tag: "latest"},
+		},
+		{
+			"localhost/foo:v1.0.0",
+			Image{Host: "localhost", Repository: "foo", Tag: "v1.0.0"},
+		},
+		{
+			"registry.example.com/team/app@" + digest,
+			Image{Host: "registry.example.com", Repository: "team/app", Digest: digest},
+		},
+		{
+			"registry.example.com:443/app:v1.0.0@" + digest,
+			Image{Host: "registry.example.com:443", Repository: "app", Tag: "v1.0.0", Digest: digest},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := ParseReference(testCase.reference)
+		if err != nil {
+			t.Fatalf("parse reference %s: %v", testCase.reference, err)
+		}
+
+		testCase.expected.Reference = testCase.reference
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected image %+v, actual %+v", testCase.expected, actual)
+		}
+	}
+}
+
+func TestParseReference_RegistryHosts(t *testing.T) {
+	registryHosts := WithRegistryHosts("Sinker-Registry")
+
+	actual, err := ParseReference("sinker-registry/team/app:v1.0.0", registryHosts)
+	if err != nil {
+		t.Fatal("parse reference:", err)
+	}
+
+	expected := Image{Reference: "sinker-registry/team/app:v1.0.0", Host: "sinker-registry", Repository: "team/app", Tag: "v1.0.0"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected image %+v, actual %+v", expected, actual)
+	}
+
+	normalized, err := NormalizeReference("sinker-registry/team/app", registryHosts)
+	if err != nil {
+		t.Fatal("normalize reference:", err)
+	}
+
+	if normalized != "sinker-registry/team/app:latest" {
+		t.Errorf("expected the added host not to be normalized to docker hub, actual %s", normalized)
+	}
+
+	if host, _ := SplitHost("team/app", registryHosts); host != "" {
+		t.Errorf("expected a component that is not a registry host not to be a host, actual %s", host)
+	}
+
+	if host, _ := SplitHost("sinker-registry/team/app"); host != "" {
+		t.Errorf("expected the registry host to only be a host when it is passed as an option, actual %s", host)
+	}
+}
+
+func TestParseReference_Invalid(t *testing.T) {
+	references := []string{"", "http://example.com", "image:", "image@sha256:abc123"}
+
+	for _, reference := range references {
+		if _, err := ParseReference(reference); err == nil {
+			t.Errorf("expected reference %s to be invalid", reference)
+		}
+	}
+}
+
+func TestNormalizeReference(t *testing.T) {
+	testCases := []struct {
+		reference string
+		expected  string
+	}{
+		{"nginx:1.25", "docker.io/library/nginx:1.25"},
+		{"docker.io/library/nginx:1.25", "docker.io/library/nginx:1.25"},
+		{"index.docker.io/library/nginx:1.25", "docker.io/library/nginx:1.25"},
+		{"nginx", "docker.io/library/nginx:latest"},
+		{"coreos/prometheus-operator:v0.40.0", "docker.io/coreos/prometheus-operator:v0.40.0"},
+		{"quay.io/coreos/prometheus-operator:v0.40.0", "quay.io/coreos/prometheus-operator:v0.40.0"},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := NormalizeReference(testCase.reference)
+		if err != nil {
+			t.Fatal("normalize reference:", err)
+		}
+
+		if actual != testCase.expected {
+			t.Errorf("expected %s to be normalized to %s, actual %s", testCase.reference, testCase.expected, actual)
+		}
+	}
+}
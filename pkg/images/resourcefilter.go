@@ -0,0 +1,93 @@
+package images
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// resourceMetadata is the metadata of a resource that the resources to search for images are selected by.
+type resourceMetadata struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Namespace string            `yaml:"namespace"`
+		Labels    map[string]string `yaml:"labels"`
+	} `yaml:"metadata"`
+}
+
+// filtersResources returns true when only some of the resources are searched for images.
+func (o options) filtersResources() bool {
+	return len(o.kinds) > 0 || len(o.excludeKinds) > 0 || len(o.namespaces) > 0 || (o.selector != nil && !o.selector.Empty())
+}
+
+// includesResource returns true when the resource in the document is searched for images.
+func (o options) includesResource(document document) bool {
+	resource := getResourceMetadata(document, o)
+	if len(o.kinds) > 0 && !containsFold(o.kinds, resource.Kind) {
+		return false
+	}
+
+	if containsFold(o.excludeKinds, resource.Kind) {
+		return false
+	}
+
+	if len(o.namespaces) > 0 && !containsNamespace(o.namespaces, resource.Metadata.Namespace) {
+		return false
+	}
+
+	if o.selector != nil && !o.selector.Matches(labels.Set(resource.Metadata.Labels)) {
+		return false
+	}
+
+	return true
+}
+
+// getResourceMetadata returns the metadata of the resource in the document. Files that are not Kubernetes resources
+// only have the kind of the file, and documents without valid metadata have no metadata.
+func getResourceMetadata(document document, o options) resourceMetadata {
+	var resource resourceMetadata
+	switch {
+	case isDockerfile(document.path):
+		resource.Kind = dockerfileKind
+	case isTerraformFile(document.path):
+		resource.Kind = terraformKind
+	case isComposeFile(document.path):
+		resource.Kind = composeKind
+	case o.ciFiles && isGitHubWorkflow(document.path):
+		resource.Kind = gitHubWorkflowKind
+	case o.ciFiles && isGitLabCIFile(document.path):
+		resource.Kind = gitLabCIKind
+	default:
+		if err := yaml.Unmarshal(document.contents, &resource); err != nil {
+			return resourceMetadata{}
+		}
+	}
+
+	return resource
+}
+
+// containsFold returns true when the values contain the value, regardless of its case.
+func containsFold(values []string, value string) bool {
+	for _, currentValue := range values {
+		if strings.EqualFold(currentValue, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsNamespace(namespaces []string, namespace string) bool {
+	if namespace == "" {
+		return false
+	}
+
+	for _, currentNamespace := range namespaces {
+		if currentNamespace == namespace {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package images
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const filteredResources = `apiVersion: v1
+kind: Pod
+metadata:
+  name: busybox
+  namespace: tools
+  labels:
+    app.kubernetes.io/part-of: tools
+spec:
+  containers:
+  - name: busybox
+    image: busybox:1.32.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: prometheus-operator
+  namespace: monitoring
+  labels:
+    app.kubernetes.io/part-of: platform
+spec:
+  template:
+    spec:
+      containers:
+      - name: prometheus-operator
+        image: quay.io/coreos/prometheus-operator:v0.40.0
+---
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: monitoring
+  labels:
+    app.kubernetes.io/part-of: platform
+    tier: batch
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            image: quay.io/coreos/etcd:v3.4.13
+`
+
+func TestFindImagesInReader_Kinds(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{"kinds", []Option{WithKinds("deployment", "CronJob")}, []string{"quay.io/coreos/prometheus-operator:v0.40.0", "quay.io/coreos/etcd:v3.4.13"}},
+		{"exclude kinds", []Option{WithExcludeKinds("CronJob")}, []string{"busybox:1.32.0", "quay.io/coreos/prometheus-operator:v0.40.0"}},
+		{"kinds and exclude kinds", []Option{WithKinds("Pod", "CronJob"), WithExcludeKinds("pod")}, []string{"quay.io/coreos/etcd:v3.4.13"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			foundImages, err := FindImagesInReader(strings.NewReader(filteredResources), testCase.opts...)
+			if err != nil {
+				t.Fatal("find images in reader:", err)
+			}
+
+			var actual []string
+			for _, image := range foundImages {
+				actual = append(actual, image.Reference)
+			}
+
+			if !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("expected images %v, actual %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFindImagesInReader_Selectors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{"namespaces", []Option{WithNamespaces("tools")}, []string{"busybox:1.32.0"}},
+		{"equality selector", []Option{WithLabelSelector(mustParseSelector(t, "app.kubernetes.io/part-of=platform"))}, []string{"quay.io/coreos/prometheus-operator:v0.40.0", "quay.io/coreos/etcd:v3.4.13"}},
+		{"set selector", []Option{WithLabelSelector(mustParseSelector(t, "app.kubernetes.io/part-of in (platform,tools),!tier"))}, []string{"busybox:1.32.0", "quay.io/coreos/prometheus-operator:v0.40.0"}},
+		{"namespaces and selector", []Option{WithNamespaces("monitoring"), WithLabelSelector(mustParseSelector(t, "tier=batch"))}, []string{"quay.io/coreos/etcd:v3.4.13"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			foundImages, err := FindImagesInReader(strings.NewReader(filteredResources), testCase.opts...)
+			if err != nil {
+				t.Fatal("find images in reader:", err)
+			}
+
+			var actual []string
+			for _, image := range foundImages {
+				actual = append(actual, image.Reference)
+			}
+
+			if !reflect.DeepEqual(actual, testCase.expected) {
+				t.Errorf("expected images %v, actual %v", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func mustParseSelector(t *testing.T, selector string) labels.Selector {
+	parsedSelector, err := labels.Parse(selector)
+	if err != nil {
+		t.Fatal("parse selector:", err)
+	}
+
+	return parsedSelector
+}
@@ -0,0 +1,57 @@
+package images
+
+import (
+	"fmt"
+	"strings"
+
+	kubeyaml "github.com/ghodss/yaml"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// isKnativeService returns true for Knative Services and Configurations, which declare the
+// containers of their revisions in a pod template. Knative Services share their kind with
+// Kubernetes Services, so they are told apart by their API group.
+func isKnativeService(apiVersion string, kind string) bool {
+	if !strings.HasPrefix(apiVersion, "serving.knative.dev/") {
+		return false
+	}
+
+	return kind == "Service" || kind == "Configuration"
+}
+
+func isKEDAScaledJob(apiVersion string, kind string) bool {
+	if !strings.HasPrefix(apiVersion, "keda.sh/") && !strings.HasPrefix(apiVersion, "keda.k8s.io/") {
+		return false
+	}
+
+	return kind == "ScaledJob"
+}
+
+// getServerlessPodSpec returns the pod spec of the revisions of a Knative Service or Configuration,
+// or the pod spec of the jobs that are created by a KEDA ScaledJob.
+func getServerlessPodSpec(yamlFile []byte, kind string) (corev1.PodSpec, error) {
+	if kind == "ScaledJob" {
+		var scaledJob struct {
+			Spec struct {
+				JobTargetRef batchv1.JobSpec `json:"jobTargetRef"`
+			} `json:"spec"`
+		}
+		if err := kubeyaml.Unmarshal(yamlFile, &scaledJob); err != nil {
+			return corev1.PodSpec{}, fmt.Errorf("unmarshal scaled job: %w", err)
+		}
+
+		return scaledJob.Spec.JobTargetRef.Template.Spec, nil
+	}
+
+	var service struct {
+		Spec struct {
+			Template corev1.PodTemplateSpec `json:"template"`
+		} `json:"spec"`
+	}
+	if err := kubeyaml.Unmarshal(yamlFile, &service); err != nil {
+		return corev1.PodSpec{}, fmt.Errorf("unmarshal %s: %w", strings.ToLower(kind), err)
+	}
+
+	return service.Spec.Template.Spec, nil
+}
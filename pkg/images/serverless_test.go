@@ -0,0 +1,67 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetImagesFromYamlFile_Serverless(t *testing.T) {
+	testCases := []struct {
+		kind     string
+		yamlFile string
+		expected []containerImage
+	}{
+		{
+			kind: "Service",
+			yamlFile: `
+apiVersion: serving.knative.dev/v1
+kind: Service
+spec:
+  template:
+    spec:
+      containers:
+      - name: hello
+        image: gcr.io/knative-samples/helloworld-go:v1.0.0`,
+			expected: []containerImage{
+				{reference: "gcr.io/knative-samples/helloworld-go:v1.0.0", container: "hello"},
+			},
+		},
+		{
+			kind: "ScaledJob",
+			yamlFile: `
+apiVersion: keda.sh/v1alpha1
+kind: ScaledJob
+spec:
+  jobTargetRef:
+    template:
+      spec:
+        containers:
+        - name: consumer
+          image: mycompany.com/queue-consumer:v2.1.0`,
+			expected: []containerImage{
+				{reference: "mycompany.com/queue-consumer:v2.1.0", container: "consumer"},
+			},
+		},
+		{
+			kind: "Service",
+			yamlFile: `
+apiVersion: v1
+kind: Service
+spec:
+  ports:
+  - port: 80`,
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := getImagesFromYamlFile([]byte(testCase.yamlFile), options{})
+		if err != nil {
+			t.Fatalf("get images from %s: %v", testCase.kind, err)
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected images %+v for %s, actual %+v", testCase.expected, testCase.kind, actual)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// File is a file that images are found in, as it is read from a Source.
+type File struct {
+
+	// Path is the path of the file, which is reported as the path of the resources that are found in the file.
+	// Dockerfiles (e.g. Dockerfile.prod) and Terraform files (*.tf) are recognized by their path, while the
+	// contents of any other file are read as YAML documents.
+	Path     string
+	Contents []byte
+}
+
+// Source is a source of the files that images are found in, such as a directory, a Git repository or a cluster.
+// FindImages finds the images at a location in the files of the source that matches the location, and sources
+// are added with RegisterSource, so that images can be found in sources that sinker does not know about.
+type Source interface {
+
+	// Match returns true when the source reads the files at the location (e.g. a location with the scheme of the source).
+	Match(location string) bool
+
+	// Files returns the files at the location. The files are read when the images are found, and
+	// the context is cancelled when the images are no longer needed.
+	Files(ctx context.Context, location string) ([]File, error)
+}
+
+// documentSource is a source that reads the documents at a location with the options that the images are found
+// with. The built-in sources are document sources, as the options change how they are read (e.g. WithHelm).
+type documentSource interface {
+	Source
+	documents(location string, o options) ([]document, error)
+}
+
+type registeredSource struct {
+	name   string
+	source Source
+}
+
+var (
+	sourcesMutex      sync.RWMutex
+	registeredSources []registeredSource
+)
+
+func init() {
+	RegisterSource("filesystem", filesystemSource{})
+	RegisterSource("stdin", stdinSource{})
+	RegisterSource("git", gitSource{})
+	RegisterSource("helm", helmSource{})
+	RegisterSource("cluster", clusterSource{})
+}
+
+// RegisterSource registers the source with the name. A location is read from the source that was registered last
+// of the sources that match it, so a source can take over locations from the sources registered before it. A source
+// that is registered with the name of a registered source replaces it, such as to replace one of the built-in sources:
+//
+//	filesystem  the files and directories at a path, which matches every location that no other source matches
+//	stdin       the YAML documents read from stdin, when the location is -
+//	git         the files of a remote Git repository (e.g. https://github.com/org/repo//manifests?ref=v1.2.0)
+//	helm        the resources rendered from a chart in an OCI registry (e.g. oci://ghcr.io/org/charts/app?version=1.2.0)
+//	cluster     the workloads running in a cluster (e.g. cluster://prod/web,monitoring for two namespaces of the prod context)
+func RegisterSource(name string, source Source) {
+	sourcesMutex.Lock()
+	defer sourcesMutex.Unlock()
+
+	for i := range registeredSources {
+		if registeredSources[i].name == name {
+			registeredSources[i].source = source
+			return
+		}
+	}
+
+	registeredSources = append(registeredSources, registeredSource{name: name, source: source})
+}
+
+// getSource returns the source that was registered last of the sources that match the location.
+func getSource(location string) (Source, error) {
+	sourcesMutex.RLock()
+	defer sourcesMutex.RUnlock()
+
+	for i := len(registeredSources) - 1; i >= 0; i-- {
+		if registeredSources[i].source.Match(location) {
+			return registeredSources[i].source, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no source matches %s", location)
+}
+
+// isFilesystemLocation returns true when the location is read from the filesystem.
+func isFilesystemLocation(location string) bool {
+	source, err := getSource(location)
+	if err != nil {
+		return false
+	}
+
+	_, ok := source.(filesystemSource)
+	return ok
+}
+
+// readSource returns the documents at the location of the source.
+func readSource(source Source, location string, o options) ([]document, error) {
+	if source, ok := source.(documentSource); ok {
+		return source.documents(location, o)
+	}
+
+	files, err := source.Files(o.ctx, location)
+	if err != nil {
+		return nil, fmt.Errorf("get files: %w", err)
+	}
+
+	var documents []document
+	for _, file := range files {
+		if isDockerfile(file.Path) || isTerraformFile(file.Path) {
+			fileDocument, err := newFileDocument(file.Path, file.Contents, o)
+			if err != nil {
+				return nil, err
+			}
+
+			documents = append(documents, fileDocument)
+			continue
+		}
+
+		fileDocuments, err := readDocuments(file.Path, bytes.NewReader(file.Contents), o)
+		if err != nil {
+			return nil, fmt.Errorf("split yaml file %s: %w", file.Path, err)
+		}
+
+		documents = append(documents, fileDocuments...)
+	}
+
+	return documents, nil
+}
+
+// getFilesOptions returns the options that the built-in sources read their documents with to implement Source,
+// which keep the contents of the documents after they are searched for images.
+func getFilesOptions(ctx context.Context) options {
+	o := newOptions(WithContext(ctx))
+	o.keepContents = true
+
+	return o
+}
+
+// getDocumentFiles returns the documents as the files of a source, for the built-in sources to implement Source.
+func getDocumentFiles(documents []document) []File {
+	var files []File
+	for _, document := range documents {
+		files = append(files, File{Path: document.path, Contents: document.contents})
+	}
+
+	return files
+}
+
+// filesystemSource reads the files and directories at a path.
+type filesystemSource struct{}
+
+func (filesystemSource) Match(location string) bool {
+	return true
+}
+
+func (s filesystemSource) Files(ctx context.Context, location string) ([]File, error) {
+	documents, err := s.documents(location, getFilesOptions(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return getDocumentFiles(documents), nil
+}
+
+func (filesystemSource) documents(location string, o options) ([]document, error) {
+	path, err := expandHome(location)
+	if err != nil {
+		return nil, fmt.Errorf("expand home: %w", err)
+	}
+
+	return getFilesystemDocuments(path, o)
+}
+
+// stdinSource reads the YAML documents from stdin.
+type stdinSource struct{}
+
+func (stdinSource) Match(location string) bool {
+	return location == "-"
+}
+
+func (s stdinSource) Files(ctx context.Context, location string) ([]File, error) {
+	documents, err := s.documents(location, getFilesOptions(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return getDocumentFiles(documents), nil
+}
+
+func (stdinSource) documents(location string, o options) ([]document, error) {
+	documents, err := getReaderDocuments(os.Stdin, o)
+	if err != nil {
+		return nil, fmt.Errorf("read stdin: %w", err)
+	}
+
+	return documents, nil
+}
+
+// getReaderDocuments returns the documents of the multi-document YAML file read from the reader.
+func getReaderDocuments(reader io.Reader, o options) ([]document, error) {
+	documents, err := readDocuments("-", reader, o)
+	if err != nil {
+		return nil, fmt.Errorf("split yaml: %w", err)
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,120 @@
+package images
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memorySource is a source of the files in memory, which is registered the same way as a source of another package.
+type memorySource map[string][]File
+
+func (s memorySource) Match(location string) bool {
+	return strings.HasPrefix(location, "memory://")
+}
+
+func (s memorySource) Files(ctx context.Context, location string) ([]File, error) {
+	return s[strings.TrimPrefix(location, "memory://")], nil
+}
+
+func TestRegisterSource(t *testing.T) {
+	deployment := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: quay.io/org/app:v1.0.0
+`
+
+	RegisterSource("memory", memorySource{
+		"apps": {
+			{Path: "apps/app.yaml", Contents: []byte(deployment)},
+			{Path: "apps/Dockerfile", Contents: []byte("FROM golang:1.15 AS build\n")},
+		},
+	})
+
+	actual, err := FindImagesInPaths([]string{"memory://apps"}, WithDockerfiles())
+	if err != nil {
+		t.Fatal("find images:", err)
+	}
+
+	var references []string
+	for _, image := range actual {
+		references = append(references, image.Reference)
+	}
+
+	expected := []string{"quay.io/org/app:v1.0.0", "golang:1.15"}
+	if !reflect.DeepEqual(references, expected) {
+		t.Errorf("expected images %v, actual %v", expected, references)
+	}
+
+	if actual[0].Resources[0].Path != "apps/app.yaml" || actual[0].Resources[0].Line != 10 {
+		t.Errorf("expected image to be found at line 10 of apps/app.yaml, actual %+v", actual[0].Resources[0])
+	}
+
+	// Registering a source with the same name replaces the source.
+	RegisterSource("memory", memorySource{})
+
+	actual, err = FindImages("memory://apps")
+	if err != nil {
+		t.Fatal("find images:", err)
+	}
+
+	if len(actual) != 0 {
+		t.Errorf("expected no images from the replaced source, actual %v", actual)
+	}
+}
+
+func TestGetSource(t *testing.T) {
+	testCases := map[string]Source{
+		"manifests/prod":                         filesystemSource{},
+		"-":                                      stdinSource{},
+		"https://github.com/org/repo//manifests": gitSource{},
+		"oci://ghcr.io/org/charts/app?version=1.2.0":    helmSource{},
+		"cluster://prod/web,monitoring":                 clusterSource{},
+		"git@github.com:org/repo.git//manifests?ref=v1": gitSource{},
+	}
+
+	for location, expected := range testCases {
+		actual, err := getSource(location)
+		if err != nil {
+			t.Fatalf("get source %s: %v", location, err)
+		}
+
+		if reflect.TypeOf(actual) != reflect.TypeOf(expected) {
+			t.Errorf("expected %s to be read from %T, actual %T", location, expected, actual)
+		}
+	}
+}
+
+func TestParseClusterLocation(t *testing.T) {
+	testCases := map[string]Cluster{
+		"cluster://":                    {},
+		"cluster://prod":                {Context: "prod"},
+		"cluster://prod/web,monitoring": {Context: "prod", Namespaces: []string{"web", "monitoring"}},
+		"cluster:///web":                {Namespaces: []string{"web"}},
+	}
+
+	for location, expected := range testCases {
+		if actual := parseClusterLocation(location); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected cluster %+v for %s, actual %+v", expected, location, actual)
+		}
+	}
+}
+
+func TestParseHelmLocation(t *testing.T) {
+	chart, version := parseHelmLocation("oci://ghcr.io/org/charts/app?version=1.2.0")
+	if chart != "oci://ghcr.io/org/charts/app" || version != "1.2.0" {
+		t.Errorf("expected chart oci://ghcr.io/org/charts/app with version 1.2.0, actual %s with version %s", chart, version)
+	}
+
+	chart, version = parseHelmLocation("oci://ghcr.io/org/charts/app")
+	if chart != "oci://ghcr.io/org/charts/app" || version != "" {
+		t.Errorf("expected chart oci://ghcr.io/org/charts/app without a version, actual %s with version %s", chart, version)
+	}
+}
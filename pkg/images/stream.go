@@ -0,0 +1,201 @@
+package images
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WalkFunc is the function called by Walk for each image that is found.
+type WalkFunc func(image Image) error
+
+// Walk calls walkFn for each image found at the path (see FindImages), as each file is parsed, rather than
+// finding every image before returning them. Only the documents of the current file are held in memory,
+// which keeps the memory used constant when finding the images of very large trees.
+//
+// The images of each file, and of each Helm chart and kustomization, are passed to walkFn once they are found.
+// An image that is referenced in more than one file is passed to walkFn once for each file, with the resources
+// of that file. The sources of Flux and Argo CD resources are not followed, as they can be declared in other files.
+//
+// Only paths and the URLs of remote Git repositories can be walked, as the other sources (see RegisterSource)
+// read all of their files at once. When walkFn returns an error, the walk stops and the error is returned as is.
+func Walk(path string, walkFn WalkFunc, opts ...Option) error {
+	o := newOptions(opts...)
+
+	path, err := expandHome(path)
+	if err != nil {
+		return fmt.Errorf("expand home: %w", err)
+	}
+
+	var gitRoot string
+	if isGitURL(path) {
+		repository, err := parseGitURL(path)
+		if err != nil {
+			return fmt.Errorf("parse git url: %w", err)
+		}
+
+		clonePath, cleanup, err := getGitRepository(repository, o)
+		if err != nil {
+			return fmt.Errorf("clone git repository: %w", err)
+		}
+		defer cleanup()
+
+		gitRoot = clonePath
+		path = clonePath
+	}
+
+	// The images of the charts and kustomizations are only found once they are rendered, and
+	// they are passed to walkFn as each one is rendered. The files inside of them are not walked.
+	var charts []string
+	if o.helm {
+		charts, err = getHelmCharts(path, o)
+		if err != nil {
+			return fmt.Errorf("get helm charts: %w", err)
+		}
+	}
+
+	var kustomizations []string
+	if o.kustomize {
+		kustomizations, err = getKustomizations(path, o)
+		if err != nil {
+			return fmt.Errorf("get kustomizations: %w", err)
+		}
+	}
+
+	var excludedDirs []string
+	excludedDirs = append(excludedDirs, charts...)
+	excludedDirs = append(excludedDirs, kustomizations...)
+
+	err = walk(path, o, func(currentFilePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if fileInfo.IsDir() && containsPath(excludedDirs, currentFilePath) {
+			return filepath.SkipDir
+		}
+
+		if fileInfo.IsDir() {
+			return nil
+		}
+
+		documents, err := readFileDocuments(currentFilePath, o)
+		if err != nil {
+			return err
+		}
+
+		return walkDocuments(documents, gitRoot, o, walkFn)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, chart := range charts {
+		renderedChart, err := renderHelmChart(o.ctx, chart, o.helmValues)
+		if err != nil {
+			return fmt.Errorf("render helm chart: %w", err)
+		}
+
+		documents, err := newDocuments(chart, renderedChart, o)
+		if err != nil {
+			return fmt.Errorf("split rendered helm chart: %w", err)
+		}
+
+		if err := walkDocuments(documents, gitRoot, o, walkFn); err != nil {
+			return err
+		}
+	}
+
+	topLevelKustomizations, err := getTopLevelKustomizations(kustomizations)
+	if err != nil {
+		return fmt.Errorf("get top level kustomizations: %w", err)
+	}
+
+	for _, kustomization := range topLevelKustomizations {
+		builtKustomization, err := buildKustomization(o.ctx, kustomization)
+		if err != nil {
+			return fmt.Errorf("build kustomization: %w", err)
+		}
+
+		documents, err := newDocuments(kustomization, builtKustomization, o)
+		if err != nil {
+			return fmt.Errorf("split built kustomization: %w", err)
+		}
+
+		if err := walkDocuments(documents, gitRoot, o, walkFn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readFileDocuments returns the documents of the file at the path, or no documents
+// when the file is not one of the kinds of files that images are found in.
+func readFileDocuments(path string, o options) ([]document, error) {
+	isYaml := filepath.Ext(path) == ".yaml" || filepath.Ext(path) == ".yml"
+	isOther := (o.dockerfiles && isDockerfile(path)) || (o.terraform && isTerraformFile(path))
+	if !isYaml && !isOther {
+		return nil, nil
+	}
+
+	if isOther {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+
+		fileDocument, err := newFileDocument(path, contents, o)
+		if err != nil {
+			return nil, err
+		}
+
+		return []document{fileDocument}, nil
+	}
+
+	// YAML files are read one document at a time, so that large files (e.g. bundles of
+	// CustomResourceDefinitions) are not read into memory at once before they are split.
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	documents, err := readDocuments(path, file, o)
+	if err != nil {
+		return nil, fmt.Errorf("split yaml file %s: %w", path, err)
+	}
+
+	return documents, nil
+}
+
+// walkDocuments calls walkFn for each image found in the documents. The errors of walkFn are returned as is.
+func walkDocuments(documents []document, gitRoot string, o options, walkFn WalkFunc) error {
+	if gitRoot != "" {
+		for i := range documents {
+			relativePath, err := filepath.Rel(gitRoot, documents[i].path)
+			if err != nil {
+				return fmt.Errorf("relative path: %w", err)
+			}
+
+			documents[i].path = filepath.ToSlash(relativePath)
+		}
+	}
+
+	// The sources of delivery resources are only followed by FindImages.
+	o.followSources = false
+
+	images, err := getImagesFromYamlFiles(documents, o)
+	if err != nil {
+		return fmt.Errorf("get images from yaml files: %w", err)
+	}
+
+	for _, image := range images {
+		if err := walkFn(image); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package images
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"a.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: a\nspec:\n  containers:\n  - name: app\n    image: busybox:1.32.0\n",
+		"b.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: b\nspec:\n  containers:\n  - name: app\n    image: busybox:1.32.0\n  - name: sidecar\n    image: nginx:1.19\n",
+	}
+
+	for path, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(root, path), []byte(contents), os.ModePerm); err != nil {
+			t.Fatal("write file:", err)
+		}
+	}
+
+	var actual []string
+	err = Walk(root, func(image Image) error {
+		for _, resource := range image.Resources {
+			actual = append(actual, image.Reference+" "+resource.Name+" "+filepath.Base(resource.Location()))
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal("walk:", err)
+	}
+
+	expected := []string{
+		"busybox:1.32.0 a a.yaml:8",
+		"busybox:1.32.0 b b.yaml:8",
+		"nginx:1.19 b b.yaml:10",
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+
+	stop := errors.New("stop")
+	var walked int
+	err = Walk(root, func(image Image) error {
+		walked++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("expected the error of the walk function, actual %v", err)
+	}
+
+	if walked != 1 {
+		t.Errorf("expected the walk to stop after the first image, actual %v images", walked)
+	}
+}
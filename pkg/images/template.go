@@ -0,0 +1,227 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ErrUnrenderedTemplate is passed to the parse error handler (see WithParseErrorHandler) for the files that
+// contain Go template syntax, such as the templates of a Helm chart that was not rendered. The images of these
+// files may be missing or incomplete, unless the templates are rendered with WithHelm or WithTemplateDefaults.
+var ErrUnrenderedTemplate = errors.New("file contains Go templates that were not rendered")
+
+var (
+	// templateActionPattern matches the actions of Go templates (e.g. {{ .Values.image.tag }} or {{- if .Values.enabled }}).
+	templateActionPattern = regexp.MustCompile(`\{\{-?\s*(.*?)\s*-?\}\}`)
+
+	// goTemplateActionPattern matches the contents of the actions that are only used by Go templates, so that the
+	// placeholders of other tools that use the same delimiters (e.g. {{inputs.parameters.image}} of Argo Workflows
+	// and ${{ matrix.image }} of GitHub Actions) are not mistaken for them.
+	goTemplateActionPattern = regexp.MustCompile(`^(\.|\$|/\*|(if|else|end|range|with|define|template|block|include|toYaml|tpl|default|quote|required|printf)\b)`)
+)
+
+// templateData is the data that the templates of a file are rendered with on a best-effort basis.
+type templateData struct {
+	values map[interface{}]interface{}
+	chart  map[interface{}]interface{}
+}
+
+// isGoTemplate returns true when the contents contain the actions of Go templates.
+func isGoTemplate(contents []byte) bool {
+	for _, match := range templateActionPattern.FindAllSubmatch(contents, -1) {
+		if goTemplateActionPattern.Match(match[1]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// renderTemplateDefaults replaces the actions of the Go templates in the contents on a best-effort basis, without
+// evaluating them. References to values (e.g. .Values.image.tag) are replaced with the default values of the chart
+// that the file is in, along with the values of the chart (e.g. .Chart.AppVersion) and literal strings. Every other
+// action is removed, along with the lines that only contain actions, which keeps both branches of conditionals.
+func renderTemplateDefaults(contents []byte, data templateData) []byte {
+	var lines []string
+	for _, line := range strings.Split(string(contents), "\n") {
+		if !templateActionPattern.MatchString(line) {
+			lines = append(lines, line)
+			continue
+		}
+
+		rendered := templateActionPattern.ReplaceAllStringFunc(line, func(action string) string {
+			return resolveTemplateAction(templateActionPattern.FindStringSubmatch(action)[1], data)
+		})
+
+		onlyActions := strings.TrimSpace(templateActionPattern.ReplaceAllString(line, "")) == ""
+		if onlyActions && strings.TrimSpace(rendered) == "" {
+			continue
+		}
+
+		lines = append(lines, rendered)
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// resolveTemplateAction returns the value of a pipeline of commands (e.g. .Values.image.tag | default "1.0"),
+// or an empty string when the value cannot be known without evaluating the template.
+func resolveTemplateAction(action string, data templateData) string {
+	var value string
+	for i, command := range strings.Split(action, "|") {
+		fields := strings.Fields(command)
+		if i > 0 {
+			fields = append(fields, strconv.Quote(value))
+		}
+
+		value = resolveTemplateCommand(fields, data)
+	}
+
+	return value
+}
+
+// resolveTemplateCommand returns the value of the command, where the last of the fields is the value that was
+// piped to the command, if any. Only the functions that are commonly used to set images are supported.
+func resolveTemplateCommand(fields []string, data templateData) string {
+	if len(fields) == 0 {
+		return ""
+	}
+
+	name := fields[0]
+	args := fields[1:]
+	if len(args) == 0 {
+		return resolveTemplateOperand(name, data)
+	}
+
+	switch name {
+	case "default":
+		if value := resolveTemplateOperand(args[len(args)-1], data); value != "" && len(args) > 1 {
+			return value
+		}
+
+		return resolveTemplateOperand(args[0], data)
+
+	case "coalesce":
+		for _, arg := range args {
+			if value := resolveTemplateOperand(arg, data); value != "" {
+				return value
+			}
+		}
+
+	case "quote":
+		return strconv.Quote(resolveTemplateOperand(args[len(args)-1], data))
+
+	case "squote":
+		return "'" + resolveTemplateOperand(args[len(args)-1], data) + "'"
+
+	case "toString", "trim", "required":
+		return strings.TrimSpace(resolveTemplateOperand(args[len(args)-1], data))
+
+	case "lower":
+		return strings.ToLower(resolveTemplateOperand(args[len(args)-1], data))
+	}
+
+	return ""
+}
+
+// resolveTemplateOperand returns the value of a literal string or number, or of a reference to a value.
+// The release is named release-name in the default namespace, which is what helm template uses by default.
+func resolveTemplateOperand(operand string, data templateData) string {
+	operand = strings.TrimPrefix(operand, "$")
+
+	if value, err := strconv.Unquote(operand); err == nil {
+		return value
+	}
+
+	if _, err := strconv.ParseFloat(operand, 64); err == nil {
+		return operand
+	}
+
+	switch {
+	case strings.HasPrefix(operand, ".Values."):
+		return lookupTemplateValue(data.values, strings.Split(strings.TrimPrefix(operand, ".Values."), "."))
+	case strings.HasPrefix(operand, ".Chart."):
+		return lookupTemplateValue(data.chart, []string{lowerFirst(strings.TrimPrefix(operand, ".Chart."))})
+	case operand == ".Release.Name":
+		return "release-name"
+	case operand == ".Release.Namespace":
+		return "default"
+	}
+
+	return ""
+}
+
+// lookupTemplateValue returns the value at the path of keys, or an empty string when the
+// value does not exist or is not a scalar (e.g. a list of values).
+func lookupTemplateValue(values map[interface{}]interface{}, keys []string) string {
+	var value interface{} = values
+	for _, key := range keys {
+		currentValues, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return ""
+		}
+
+		value = currentValues[key]
+	}
+
+	switch value.(type) {
+	case string, int, float64, bool:
+		return fmt.Sprint(value)
+	}
+
+	return ""
+}
+
+// getTemplateData returns the default values of the Helm chart that the file is in, which is the closest
+// directory above the file that has a Chart.yaml. Files that are not in a chart have no values.
+func getTemplateData(path string) templateData {
+	data := templateData{
+		values: make(map[interface{}]interface{}),
+		chart:  make(map[interface{}]interface{}),
+	}
+
+	dir := filepath.Dir(path)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "Chart.yaml")); err == nil {
+			readTemplateValues(filepath.Join(dir, "Chart.yaml"), data.chart)
+			readTemplateValues(filepath.Join(dir, "values.yaml"), data.values)
+			return data
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return data
+		}
+
+		dir = parent
+	}
+}
+
+// readTemplateValues reads the values of the YAML file into the values. A file that cannot
+// be read leaves the values empty, as the templates are only rendered on a best-effort basis.
+func readTemplateValues(path string, values map[interface{}]interface{}) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	yaml.Unmarshal(contents, &values)
+}
+
+// lowerFirst lowers the first letter of the field of the chart (e.g. AppVersion),
+// as the keys of the Chart.yaml file start with a lowercase letter (e.g. appVersion).
+func lowerFirst(field string) string {
+	if field == "" {
+		return field
+	}
+
+	return strings.ToLower(field[:1]) + field[1:]
+}
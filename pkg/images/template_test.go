@@ -0,0 +1,131 @@
+package images
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const templatedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ include "app.fullname" . }}
+  labels:
+    {{- include "app.labels" . | nindent 4 }}
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "{{ .Values.image.repository }}:{{ .Values.image.tag | default .Chart.AppVersion }}"
+      {{- if .Values.sidecar.enabled }}
+      - name: sidecar
+        image: {{ .Values.sidecar.image | quote }}
+      {{- end }}
+`
+
+func TestFindImages_Templates(t *testing.T) {
+	chartPath, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("create temp dir:", err)
+	}
+	defer os.RemoveAll(chartPath)
+
+	files := map[string]string{
+		"Chart.yaml":                "apiVersion: v2\nname: app\nversion: 0.1.0\nappVersion: v1.2.0\n",
+		"values.yaml":               "image:\n  repository: quay.io/org/app\n  tag: \"\"\nsidecar:\n  enabled: false\n  image: busybox:1.32.0\n",
+		"templates/deployment.yaml": templatedDeployment,
+	}
+
+	for name, contents := range files {
+		if err := os.MkdirAll(filepath.Join(chartPath, filepath.Dir(name)), os.ModePerm); err != nil {
+			t.Fatal("create directory:", err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(chartPath, name), []byte(contents), 0644); err != nil {
+			t.Fatal("write file:", err)
+		}
+	}
+
+	var mutex sync.Mutex
+	var templateErrors []error
+	handler := WithParseErrorHandler(func(path string, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		templateErrors = append(templateErrors, err)
+	})
+
+	if _, err := FindImages(chartPath, handler); err != nil {
+		t.Fatal("find images:", err)
+	}
+
+	if len(templateErrors) != 1 || !errors.Is(templateErrors[0], ErrUnrenderedTemplate) {
+		t.Errorf("expected the templates to be reported once, actual %v", templateErrors)
+	}
+
+	actual, err := FindImages(chartPath, WithTemplateDefaults())
+	if err != nil {
+		t.Fatal("find images with template defaults:", err)
+	}
+
+	var references []string
+	for _, image := range actual {
+		references = append(references, image.Reference)
+	}
+
+	expected := []string{"quay.io/org/app:v1.2.0", "busybox:1.32.0"}
+	if !reflect.DeepEqual(references, expected) {
+		t.Errorf("expected images %v, actual %v", expected, references)
+	}
+}
+
+func TestIsGoTemplate(t *testing.T) {
+	testCases := []struct {
+		contents string
+		expected bool
+	}{
+		{contents: "image: {{ .Values.image }}", expected: true},
+		{contents: "{{- if .Values.enabled }}", expected: true},
+		{contents: "image: {{inputs.parameters.image}}", expected: false},
+		{contents: "image: ${{ matrix.image }}", expected: false},
+		{contents: "image: busybox:1.32.0", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		if actual := isGoTemplate([]byte(testCase.contents)); actual != testCase.expected {
+			t.Errorf("expected %q to be a template %v, actual %v", testCase.contents, testCase.expected, actual)
+		}
+	}
+}
+
+func TestFindImagesInReader_Anchors(t *testing.T) {
+	contents := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - &app
+        name: app
+        image: quay.io/org/app:v1.0.0
+      - <<: *app
+        name: worker
+`
+
+	actual, err := FindImagesInReader(strings.NewReader(contents))
+	if err != nil {
+		t.Fatal("find images:", err)
+	}
+
+	if len(actual) != 1 || len(actual[0].Resources) != 2 || actual[0].Resources[1].Container != "worker" {
+		t.Errorf("expected the image of the alias to be found in both containers, actual %+v", actual)
+	}
+}
@@ -0,0 +1,94 @@
+package images
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// terraformKind is the kind of the resources that are found in Terraform files.
+const terraformKind = "Terraform"
+
+// terraformBlockPattern matches the headers of the resource and data blocks of a Terraform file
+// (e.g. resource "kubernetes_deployment" "nginx" {), which the images inside of them belong to.
+var terraformBlockPattern = regexp.MustCompile(`(?m)^\s*(resource|data)\s+"([^"]+)"\s+"([^"]+)"\s*\{`)
+
+// terraformImagePattern matches the image attributes of the Kubernetes provider (e.g. image = "nginx:1.25"
+// in the container block of a kubernetes_deployment), and of other providers that use the same name.
+var terraformImagePattern = regexp.MustCompile(`(?m)^\s*image\s*=\s*"([^"]+)"`)
+
+// terraformHelmSetPattern matches the set blocks of a helm_release that set an image (e.g. name = "image"
+// or name = "sidecar.image" followed by value = "busybox:1.32.0").
+var terraformHelmSetPattern = regexp.MustCompile(`set(?:_string)?\s*\{\s*name\s*=\s*"((?:[^"]*\.)?image)"\s*value\s*=\s*"([^"]+)"\s*\}`)
+
+func isTerraformFile(path string) bool {
+	return filepath.Ext(path) == ".tf"
+}
+
+func getTerraformFiles(path string, o options) ([]string, error) {
+	var terraformFiles []string
+	err := walk(path, o, func(currentFilePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("walk path: %w", err)
+		}
+
+		if fileInfo.IsDir() || !isTerraformFile(currentFilePath) {
+			return nil
+		}
+
+		terraformFiles = append(terraformFiles, currentFilePath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return terraformFiles, nil
+}
+
+// getTerraformImages returns the images that are set by the attributes named image and by the set blocks
+// of Helm releases in the Terraform file. The container of each image is the address of the resource that
+// it is set in (e.g. kubernetes_deployment.nginx). Values that are interpolated (e.g. "nginx:${var.tag}")
+// are returned as well, but are not valid references until Terraform evaluates them and are skipped.
+func getTerraformImages(terraformFile []byte) []containerImage {
+	blocks := terraformBlockPattern.FindAllSubmatchIndex(terraformFile, -1)
+
+	type terraformImage struct {
+		offset    int
+		reference string
+	}
+
+	var terraformImages []terraformImage
+	for _, match := range terraformImagePattern.FindAllSubmatchIndex(terraformFile, -1) {
+		terraformImages = append(terraformImages, terraformImage{offset: match[0], reference: string(terraformFile[match[2]:match[3]])})
+	}
+
+	for _, match := range terraformHelmSetPattern.FindAllSubmatchIndex(terraformFile, -1) {
+		terraformImages = append(terraformImages, terraformImage{offset: match[0], reference: string(terraformFile[match[4]:match[5]])})
+	}
+
+	sort.Slice(terraformImages, func(i, j int) bool {
+		return terraformImages[i].offset < terraformImages[j].offset
+	})
+
+	var images []containerImage
+	for _, image := range terraformImages {
+		var address string
+		for _, block := range blocks {
+			if block[0] > image.offset {
+				break
+			}
+
+			address = string(terraformFile[block[4]:block[5]]) + "." + string(terraformFile[block[6]:block[7]])
+			if string(terraformFile[block[2]:block[3]]) == "data" {
+				address = "data." + address
+			}
+		}
+
+		images = append(images, containerImage{reference: image.reference, container: address})
+	}
+
+	return images
+}
@@ -0,0 +1,60 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetTerraformImages(t *testing.T) {
+	terraformFile := []byte(`variable "tag" {
+  default = "1.25"
+}
+
+resource "kubernetes_deployment" "nginx" {
+  metadata {
+    name = "nginx"
+  }
+
+  spec {
+    template {
+      spec {
+        container {
+          name  = "nginx"
+          image = "nginx:1.25"
+        }
+
+        container {
+          name  = "exporter"
+          image = "nginx/nginx-prometheus-exporter:${var.tag}"
+        }
+      }
+    }
+  }
+}
+
+resource "helm_release" "redis" {
+  name  = "redis"
+  chart = "redis"
+
+  set {
+    name  = "image.tag"
+    value = "6.0.9"
+  }
+
+  set {
+    name  = "metrics.image"
+    value = "oliver006/redis_exporter:v1.13.1"
+  }
+}`)
+
+	expected := []containerImage{
+		{reference: "nginx:1.25", container: "kubernetes_deployment.nginx"},
+		{reference: "nginx/nginx-prometheus-exporter:${var.tag}", container: "kubernetes_deployment.nginx"},
+		{reference: "oliver006/redis_exporter:v1.13.1", container: "helm_release.redis"},
+	}
+
+	actual := getTerraformImages(terraformFile)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %+v, actual %+v", expected, actual)
+	}
+}
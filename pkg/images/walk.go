@@ -0,0 +1,137 @@
+package images
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// defaultSkipDirs are the names of the directories that are never searched for
+// resources, as they only contain source control metadata or dependencies.
+var defaultSkipDirs = []string{".git", "vendor", "node_modules"}
+
+type walker struct {
+	options   options
+	gitignore gitignore
+	visited   map[string]bool
+	walkFn    filepath.WalkFunc
+}
+
+// walk walks the file tree at the path in the same order as filepath.Walk. Directories that are
+// skipped by default (e.g. .git and node_modules) or by the options are not walked, nor are the
+// files and directories that are ignored by .gitignore files unless the .gitignore files are
+// disabled. Symbolic links to directories are only followed when enabled, and each directory
+// is only walked once, so that links to parent directories do not cause a cycle.
+func walk(path string, o options, walkFn filepath.WalkFunc) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return walkFn(path, nil, err)
+	}
+
+	w := walker{
+		options: o,
+		visited: make(map[string]bool),
+		walkFn:  walkFn,
+	}
+
+	err = w.walk(path, path, info)
+	if err == filepath.SkipDir {
+		return nil
+	}
+
+	return err
+}
+
+func (w *walker) walk(root string, path string, info os.FileInfo) error {
+	if path != root && w.skipped(root, path, info) {
+		return nil
+	}
+
+	if err := w.walkFn(path, info, nil); err != nil {
+		if info.IsDir() && err == filepath.SkipDir {
+			return nil
+		}
+
+		return err
+	}
+
+	if !info.IsDir() {
+		return nil
+	}
+
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return w.walkFn(path, info, err)
+	}
+
+	if w.visited[realPath] {
+		return nil
+	}
+	w.visited[realPath] = true
+
+	if !w.options.noGitignore {
+		if err := w.gitignore.load(path); err != nil {
+			return w.walkFn(path, info, fmt.Errorf("load gitignore: %w", err))
+		}
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return w.walkFn(path, info, err)
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+		if entry.Mode()&os.ModeSymlink != 0 && w.options.followSymlinks {
+			target, err := os.Stat(entryPath)
+			if err != nil {
+				return w.walkFn(entryPath, entry, err)
+			}
+
+			entry = target
+		}
+
+		if err := w.walk(root, entryPath, entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// skipped returns true when the file or directory should not be walked.
+func (w *walker) skipped(root string, path string, info os.FileInfo) bool {
+	if !w.options.noGitignore && w.gitignore.ignored(path, info.IsDir()) {
+		return true
+	}
+
+	if !info.IsDir() {
+		return false
+	}
+
+	for _, skipDir := range defaultSkipDirs {
+		if info.Name() == skipDir {
+			return true
+		}
+	}
+
+	relativePath, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	// Directories can be skipped by their name (e.g. testdata) or by their
+	// path relative to the path being walked (e.g. charts/*/templates).
+	for _, skipDir := range w.options.skipDirs {
+		if matched, _ := filepath.Match(skipDir, info.Name()); matched {
+			return true
+		}
+
+		if matched, _ := filepath.Match(filepath.Clean(skipDir), relativePath); matched {
+			return true
+		}
+	}
+
+	return false
+}
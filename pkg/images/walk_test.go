@@ -0,0 +1,122 @@
+package images
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetYamlFiles_Walk(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		".gitignore":                  "rendered/\n*.local.yaml\n!keep.local.yaml\n",
+		"app/deployment.yaml":         "",
+		"app/dev.local.yaml":          "",
+		"app/keep.local.yaml":         "",
+		"app/.gitignore":              "/generated.yaml\n",
+		"app/generated.yaml":          "",
+		"rendered/deployment.yaml":    "",
+		"vendor/module/fixture.yaml":  "",
+		"node_modules/pkg/chart.yaml": "",
+		"testdata/fixture.yaml":       "",
+	}
+
+	for path, contents := range files {
+		fullPath := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), os.ModePerm); err != nil {
+			t.Fatal("mkdir:", err)
+		}
+
+		if err := ioutil.WriteFile(fullPath, []byte(contents), os.ModePerm); err != nil {
+			t.Fatal("write file:", err)
+		}
+	}
+
+	// A link to the parent directory would cause a cycle if it were followed more than once.
+	if err := os.Symlink(root, filepath.Join(root, "app", "parent")); err != nil {
+		t.Fatal("symlink:", err)
+	}
+
+	testCases := []struct {
+		name     string
+		options  options
+		expected []string
+	}{
+		{
+			"default",
+			options{},
+			[]string{"app/deployment.yaml", "app/keep.local.yaml", "testdata/fixture.yaml"},
+		},
+		{
+			"skip dirs",
+			options{skipDirs: []string{"testdata"}},
+			[]string{"app/deployment.yaml", "app/keep.local.yaml"},
+		},
+		{
+			"without gitignore",
+			options{noGitignore: true, skipDirs: []string{"testdata"}},
+			[]string{"app/deployment.yaml", "app/dev.local.yaml", "app/generated.yaml", "app/keep.local.yaml", "rendered/deployment.yaml"},
+		},
+		{
+			"follow symlinks",
+			options{followSymlinks: true},
+			[]string{"app/deployment.yaml", "app/keep.local.yaml", "testdata/fixture.yaml"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		yamlFiles, err := getYamlFiles(root, nil, testCase.options)
+		if err != nil {
+			t.Fatalf("get yaml files (%s): %v", testCase.name, err)
+		}
+
+		var actual []string
+		for _, yamlFile := range yamlFiles {
+			relativePath, err := filepath.Rel(root, yamlFile)
+			if err != nil {
+				t.Fatal("relative path:", err)
+			}
+
+			actual = append(actual, filepath.ToSlash(relativePath))
+		}
+
+		if !reflect.DeepEqual(actual, testCase.expected) {
+			t.Errorf("expected files %v (%s), actual %v", testCase.expected, testCase.name, actual)
+		}
+	}
+}
+
+func TestCompileGitignorePattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		path    string
+		matched bool
+	}{
+		{"*.tmp", "a/b/c.tmp", true},
+		{"/build", "build", true},
+		{"/build", "src/build", false},
+		{"docs/*.yaml", "docs/a.yaml", true},
+		{"docs/*.yaml", "docs/a/b.yaml", false},
+		{"**/fixtures", "a/b/fixtures", true},
+		{"charts/**/tests", "charts/app/sub/tests", true},
+		{"file[0-9].yaml", "file1.yaml", true},
+	}
+
+	for _, testCase := range testCases {
+		pattern, err := compileGitignorePattern(testCase.pattern)
+		if err != nil {
+			t.Fatalf("compile %s: %v", testCase.pattern, err)
+		}
+
+		if actual := pattern.MatchString(testCase.path); actual != testCase.matched {
+			t.Errorf("expected %s to match %s: %v, actual %v", testCase.pattern, testCase.path, testCase.matched, actual)
+		}
+	}
+}
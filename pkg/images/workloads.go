@@ -0,0 +1,265 @@
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Workload is a workload running in a cluster with the images that it uses.
+type Workload struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Images    []string
+
+	// PullSecrets are the names of the image pull secrets in the namespace of the workload that the nodes use
+	// to pull its images, which are the pull secrets of the workload followed by those of its service account.
+	PullSecrets []string
+}
+
+// FindWorkloadsInCluster returns the workloads running in the cluster. Pods that are owned by another resource
+// (e.g. the pods of a Deployment) are not returned, as the resource that owns them is returned instead.
+// The resources are retrieved with kubectl, which must be available on the PATH.
+func FindWorkloadsInCluster(cluster Cluster, opts ...Option) ([]Workload, error) {
+	o := newOptions(opts...)
+
+	var documents []document
+	for _, args := range getKubectlArgsForKinds(cluster, append(append([]string{}, clusterKinds...), "serviceaccounts")) {
+		contents, err := execute(o.ctx, "kubectl", args...)
+		if err != nil {
+			return nil, fmt.Errorf("kubectl get: %w", err)
+		}
+
+		listDocuments, err := splitResourceList(contents)
+		if err != nil {
+			return nil, fmt.Errorf("split resource list: %w", err)
+		}
+
+		documents = append(documents, listDocuments...)
+	}
+
+	workloads, err := getWorkloads(documents, o)
+	if err != nil {
+		return nil, fmt.Errorf("get workloads: %w", err)
+	}
+
+	return workloads, nil
+}
+
+type clusterResource struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name            string            `json:"name"`
+		Namespace       string            `json:"namespace"`
+		OwnerReferences []json.RawMessage `json:"ownerReferences"`
+	} `json:"metadata"`
+
+	// ImagePullSecrets are the pull secrets of a service account.
+	ImagePullSecrets []struct {
+		Name string `json:"name"`
+	} `json:"imagePullSecrets"`
+}
+
+func getWorkloads(documents []document, o options) ([]Workload, error) {
+	serviceAccountSecrets := make(map[string][]string)
+	for _, document := range documents {
+		var resource clusterResource
+		if err := json.Unmarshal(document.contents, &resource); err != nil {
+			return nil, fmt.Errorf("unmarshal resource: %w", err)
+		}
+
+		if resource.Kind != "ServiceAccount" {
+			continue
+		}
+
+		for _, secret := range resource.ImagePullSecrets {
+			key := resource.Metadata.Namespace + "/" + resource.Metadata.Name
+			serviceAccountSecrets[key] = append(serviceAccountSecrets[key], secret.Name)
+		}
+	}
+
+	var workloads []Workload
+	for _, document := range documents {
+		var resource clusterResource
+		if err := json.Unmarshal(document.contents, &resource); err != nil {
+			return nil, fmt.Errorf("unmarshal resource: %w", err)
+		}
+
+		if !isWorkload(resource.Kind) || len(resource.Metadata.OwnerReferences) > 0 {
+			continue
+		}
+
+		podSpec, err := getWorkloadPodSpec(document.contents, resource.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("get pod spec of %s %s: %w", resource.Kind, resource.Metadata.Name, err)
+		}
+
+		workload := Workload{
+			Kind:      resource.Kind,
+			Namespace: resource.Metadata.Namespace,
+			Name:      resource.Metadata.Name,
+		}
+
+		for _, image := range getImagesFromPodSpec(podSpec, o) {
+			workload.Images = append(workload.Images, image.reference)
+		}
+
+		for _, secret := range podSpec.ImagePullSecrets {
+			workload.PullSecrets = append(workload.PullSecrets, secret.Name)
+		}
+
+		serviceAccount := podSpec.ServiceAccountName
+		if serviceAccount == "" {
+			serviceAccount = "default"
+		}
+
+		workload.PullSecrets = append(workload.PullSecrets, serviceAccountSecrets[workload.Namespace+"/"+serviceAccount]...)
+
+		workloads = append(workloads, workload)
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].Namespace != workloads[j].Namespace {
+			return workloads[i].Namespace < workloads[j].Namespace
+		}
+
+		if workloads[i].Kind != workloads[j].Kind {
+			return workloads[i].Kind < workloads[j].Kind
+		}
+
+		return workloads[i].Name < workloads[j].Name
+	})
+
+	return workloads, nil
+}
+
+// FindPullSecretsInCluster returns the Docker configs of the image pull secrets in the cluster, keyed by the
+// namespace and name of the secret (e.g. default/regcred). Secrets of the legacy kubernetes.io/dockercfg type
+// are returned in the same format as the kubernetes.io/dockerconfigjson type, with the registries under auths.
+func FindPullSecretsInCluster(cluster Cluster, opts ...Option) (map[string][]byte, error) {
+	o := newOptions(opts...)
+
+	pullSecrets := make(map[string][]byte)
+	for _, args := range getKubectlArgsForKinds(cluster, []string{"secrets"}) {
+		contents, err := execute(o.ctx, "kubectl", args...)
+		if err != nil {
+			return nil, fmt.Errorf("kubectl get: %w", err)
+		}
+
+		if err := addPullSecrets(pullSecrets, contents); err != nil {
+			return nil, fmt.Errorf("add pull secrets: %w", err)
+		}
+	}
+
+	return pullSecrets, nil
+}
+
+// GetPullSecret returns the Docker config of the image pull secret with the name in the namespace of the cluster, in the
+// same format as FindPullSecretsInCluster. When the namespace is empty, the namespace of the kubeconfig context is used.
+// The secret is retrieved with kubectl, which must be available on the PATH.
+func GetPullSecret(cluster Cluster, namespace string, name string, opts ...Option) ([]byte, error) {
+	o := newOptions(opts...)
+
+	args := []string{"get", "secret", name, "--output", "json"}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+
+	if cluster.Kubeconfig != "" {
+		args = append(args, "--kubeconfig", cluster.Kubeconfig)
+	}
+
+	if cluster.Context != "" {
+		args = append(args, "--context", cluster.Context)
+	}
+
+	contents, err := execute(o.ctx, "kubectl", args...)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl get: %w", err)
+	}
+
+	dockerConfig, err := ParsePullSecret(contents)
+	if err != nil {
+		return nil, fmt.Errorf("parse secret %s/%s: %w", namespace, name, err)
+	}
+
+	return dockerConfig, nil
+}
+
+// ParsePullSecret returns the Docker config of the image pull secret, which is the JSON of a Secret
+// resource of the kubernetes.io/dockerconfigjson or the legacy kubernetes.io/dockercfg type.
+func ParsePullSecret(contents []byte) ([]byte, error) {
+	var secret pullSecret
+	if err := json.Unmarshal(contents, &secret); err != nil {
+		return nil, fmt.Errorf("unmarshal secret: %w", err)
+	}
+
+	dockerConfig, err := secret.dockerConfig()
+	if err != nil {
+		return nil, fmt.Errorf("get docker config: %w", err)
+	}
+
+	if dockerConfig == nil {
+		return nil, fmt.Errorf("secret of type %s is not an image pull secret", secret.Type)
+	}
+
+	return dockerConfig, nil
+}
+
+type pullSecret struct {
+	Type     string `json:"type"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Data map[string][]byte `json:"data"`
+}
+
+// dockerConfig returns the Docker config of the secret, or nil when the secret is not an image pull secret.
+// The config of the legacy kubernetes.io/dockercfg type is returned with the registries under auths.
+func (s pullSecret) dockerConfig() ([]byte, error) {
+	switch s.Type {
+	case "kubernetes.io/dockerconfigjson":
+		return s.Data[".dockerconfigjson"], nil
+
+	case "kubernetes.io/dockercfg":
+		if len(s.Data[".dockercfg"]) == 0 {
+			return nil, nil
+		}
+
+		dockerConfig, err := json.Marshal(map[string]json.RawMessage{"auths": s.Data[".dockercfg"]})
+		if err != nil {
+			return nil, fmt.Errorf("marshal docker config: %w", err)
+		}
+
+		return dockerConfig, nil
+	}
+
+	return nil, nil
+}
+
+func addPullSecrets(pullSecrets map[string][]byte, contents []byte) error {
+	var list struct {
+		Items []pullSecret `json:"items"`
+	}
+
+	if err := json.Unmarshal(contents, &list); err != nil {
+		return fmt.Errorf("unmarshal list: %w", err)
+	}
+
+	for _, secret := range list.Items {
+		dockerConfig, err := secret.dockerConfig()
+		if err != nil {
+			return fmt.Errorf("get docker config of %s: %w", secret.Metadata.Name, err)
+		}
+
+		if dockerConfig == nil {
+			continue
+		}
+
+		pullSecrets[secret.Metadata.Namespace+"/"+secret.Metadata.Name] = dockerConfig
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package images
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetWorkloads(t *testing.T) {
+	list := `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {
+      "apiVersion": "apps/v1",
+      "kind": "Deployment",
+      "metadata": {"name": "web", "namespace": "default"},
+      "spec": {"template": {"spec": {
+        "serviceAccountName": "web",
+        "imagePullSecrets": [{"name": "quay"}],
+        "containers": [{"name": "web", "image": "quay.io/mycompany/web:1.0.0"}]
+      }}}
+    },
+    {
+      "apiVersion": "v1",
+      "kind": "Pod",
+      "metadata": {"name": "web-abc", "namespace": "default", "ownerReferences": [{"kind": "ReplicaSet", "name": "web-123"}]},
+      "spec": {"containers": [{"name": "web", "image": "quay.io/mycompany/web:1.0.0"}]}
+    },
+    {
+      "apiVersion": "v1",
+      "kind": "Pod",
+      "metadata": {"name": "debug", "namespace": "default"},
+      "spec": {"containers": [{"name": "busybox", "image": "busybox:1.32.0"}]}
+    },
+    {
+      "apiVersion": "v1",
+      "kind": "ServiceAccount",
+      "metadata": {"name": "web", "namespace": "default"},
+      "imagePullSecrets": [{"name": "dockerhub"}]
+    },
+    {
+      "apiVersion": "v1",
+      "kind": "ServiceAccount",
+      "metadata": {"name": "default", "namespace": "default"},
+      "imagePullSecrets": [{"name": "mirror"}]
+    }
+  ]
+}`
+
+	documents, err := splitResourceList([]byte(list))
+	if err != nil {
+		t.Fatal("split resource list:", err)
+	}
+
+	actual, err := getWorkloads(documents, options{})
+	if err != nil {
+		t.Fatal("get workloads:", err)
+	}
+
+	expected := []Workload{
+		{Kind: "Deployment", Namespace: "default", Name: "web", Images: []string{"quay.io/mycompany/web:1.0.0"}, PullSecrets: []string{"quay", "dockerhub"}},
+		{Kind: "Pod", Namespace: "default", Name: "debug", Images: []string{"busybox:1.32.0"}, PullSecrets: []string{"mirror"}},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected workloads %+v, actual %+v", expected, actual)
+	}
+}
+
+func TestAddPullSecrets(t *testing.T) {
+	list := `{
+  "items": [
+    {
+      "type": "kubernetes.io/dockerconfigjson",
+      "metadata": {"name": "quay", "namespace": "default"},
+      "data": {".dockerconfigjson": "eyJhdXRocyI6e319"}
+    },
+    {
+      "type": "kubernetes.io/dockercfg",
+      "metadata": {"name": "legacy", "namespace": "default"},
+      "data": {".dockercfg": "eyJxdWF5LmlvIjp7fX0="}
+    },
+    {
+      "type": "Opaque",
+      "metadata": {"name": "password", "namespace": "default"},
+      "data": {"password": "c2VjcmV0"}
+    }
+  ]
+}`
+
+	pullSecrets := make(map[string][]byte)
+	if err := addPullSecrets(pullSecrets, []byte(list)); err != nil {
+		t.Fatal("add pull secrets:", err)
+	}
+
+	expected := map[string][]byte{
+		"default/quay":   []byte(`{"auths":{}}`),
+		"default/legacy": []byte(`{"auths":{"quay.io":{}}}`),
+	}
+
+	if !reflect.DeepEqual(pullSecrets, expected) {
+		t.Errorf("expected pull secrets %s, actual %s", expected, pullSecrets)
+	}
+}
+
+func TestParsePullSecret(t *testing.T) {
+	secret := `{"type": "kubernetes.io/dockerconfigjson", "metadata": {"name": "quay"}, "data": {".dockerconfigjson": "eyJhdXRocyI6e319"}}`
+
+	actual, err := ParsePullSecret([]byte(secret))
+	if err != nil {
+		t.Fatal("parse pull secret:", err)
+	}
+
+	if string(actual) != `{"auths":{}}` {
+		t.Errorf("expected docker config %s, actual %s", `{"auths":{}}`, actual)
+	}
+
+	if _, err := ParsePullSecret([]byte(`{"type": "Opaque", "data": {"password": "c2VjcmV0"}}`)); err == nil {
+		t.Error("expected an error for a secret that is not an image pull secret")
+	}
+}
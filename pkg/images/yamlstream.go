@@ -0,0 +1,306 @@
+package images
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultMaxDocumentSize is the maximum size of a YAML document in bytes, unless it is set with WithMaxDocumentSize.
+// Resources are rarely larger than a few megabytes, even the CustomResourceDefinitions with the largest schemas.
+const DefaultMaxDocumentSize = 16 << 20
+
+// ErrDocumentTooLarge is passed to the parse error handler (see WithParseErrorHandler) for each YAML document that is
+// larger than the maximum size of a document (see WithMaxDocumentSize). The document is skipped, along with the rest
+// of the file, as the rest of the document would have to be read to find the separator that ends it.
+var ErrDocumentTooLarge = errors.New("document is larger than the maximum size of a document")
+
+// yamlStreamBufferSize is the size of the buffer that YAML files are read with. Lines that are longer than the
+// buffer are read in parts, so that a file with a single long line (e.g. minified JSON) is not read at once.
+const yamlStreamBufferSize = 4096
+
+// yamlStream is the reader of a YAML file that the documents of the file are decoded from. The file is read a
+// line at a time, so that the lines of the images can be indexed as the file is read (see lineIndex), the Go
+// templates of each line can be rendered (see WithTemplateDefaults) and the size of each document is limited.
+//
+// The stream ends at each document separator (---), so that each document is decoded on its own and a document
+// that cannot be parsed does not stop the documents that follow it from being decoded. A separator at the start
+// of a line always starts a new document, even in the middle of a block scalar, so it cannot be part of a document.
+type yamlStream struct {
+	path    string
+	reader  *bufio.Reader
+	lines   *lineIndex
+	pending []byte
+
+	// line is the number of the line that was read last, and documentLine the line that the current document
+	// starts on. midLine is set when only part of a line that is longer than the buffer was read.
+	line         int
+	documentLine int
+	midLine      bool
+
+	// separator is the separator that ended the last document, which is the first line of the next document.
+	separator   []byte
+	content     bool
+	documentEnd bool
+	eof         bool
+
+	templateDefaults bool
+	templateData     *templateData
+	templated        bool
+
+	documentSize    int
+	maxDocumentSize int
+	tooLarge        bool
+	err             error
+}
+
+func newYamlStream(path string, reader io.Reader, o options) *yamlStream {
+	return &yamlStream{
+		path:             path,
+		reader:           bufio.NewReaderSize(reader, yamlStreamBufferSize),
+		lines:            newLineIndex(),
+		templateDefaults: o.templateDefaults,
+		maxDocumentSize:  o.maxDocumentSize,
+	}
+}
+
+// Read reads the next part of the current document, one line at a time.
+func (s *yamlStream) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		line, err := s.nextLine()
+		if err != nil {
+			return 0, err
+		}
+
+		s.pending = line
+	}
+
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+
+	s.documentSize += n
+	if s.maxDocumentSize > 0 && s.documentSize > s.maxDocumentSize {
+		s.tooLarge = true
+		return n, ErrDocumentTooLarge
+	}
+
+	return n, nil
+}
+
+// nextDocument starts reading the next document of the file, and returns false when there are no more documents.
+func (s *yamlStream) nextDocument() bool {
+	if s.eof || s.err != nil {
+		return false
+	}
+
+	s.pending = nil
+	s.content = false
+	s.documentEnd = false
+	s.documentSize = 0
+	s.documentLine = s.line + 1
+	if s.separator != nil {
+		s.documentLine = s.line
+	}
+
+	return true
+}
+
+// skipDocument skips the rest of the current document, such as a document that cannot be parsed.
+func (s *yamlStream) skipDocument() {
+	s.pending = nil
+	for {
+		if _, err := s.nextLine(); err != nil {
+			return
+		}
+	}
+}
+
+// nextLine returns the next line of the current document, or io.EOF at the end of the document.
+func (s *yamlStream) nextLine() ([]byte, error) {
+	if s.documentEnd {
+		return nil, io.EOF
+	}
+
+	line := s.separator
+	s.separator = nil
+
+	if line == nil {
+		lineStart := !s.midLine
+
+		var err error
+		line, err = s.readLine()
+		if err == io.EOF {
+			s.eof = true
+			s.documentEnd = true
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Directives (e.g. %YAML 1.2) are part of the document that follows them.
+		if lineStart && isDocumentSeparator(line) && s.content {
+			s.separator = line
+			s.documentEnd = true
+			return nil, io.EOF
+		}
+
+		if !lineStart || !bytes.HasPrefix(line, []byte("%")) {
+			s.content = true
+		}
+	}
+
+	return line, nil
+}
+
+// readLine returns the next line of the file, which may only be part of a line that is longer than the buffer,
+// after it is indexed and its Go templates are rendered. Lines that only contain template actions are removed.
+func (s *yamlStream) readLine() ([]byte, error) {
+	line, err := s.reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		err = nil
+	}
+
+	if err != nil && err != io.EOF {
+		s.err = err
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, io.EOF
+	}
+
+	if !s.midLine {
+		s.line++
+	}
+	s.midLine = line[len(line)-1] != '\n'
+
+	// The line is only valid until the next read, so it is copied before it is returned.
+	line = append([]byte(nil), line...)
+	s.lines.add(line)
+
+	if !isGoTemplate(line) {
+		return line, nil
+	}
+
+	if !s.templateDefaults {
+		s.templated = true
+		return line, nil
+	}
+
+	if s.templateData == nil {
+		templateData := getTemplateData(s.path)
+		s.templateData = &templateData
+	}
+
+	return renderTemplateDefaults(line, *s.templateData), nil
+}
+
+// isDocumentSeparator returns true when the line starts a new document (e.g. --- or --- !!map).
+func isDocumentSeparator(line []byte) bool {
+	if !bytes.HasPrefix(line, []byte("---")) {
+		return false
+	}
+
+	return len(line) == 3 || bytes.ContainsAny(line[3:4], " \t\r\n")
+}
+
+// readDocuments returns the documents of the multi-document YAML file read from the reader, which are decoded one
+// at a time. The images of each document are found as soon as it is decoded (see document.findImages), after which
+// its contents are dropped, unless they are needed by the options, along with the documents that have no images.
+// This keeps the memory used low when reading large files, such as bundles of CustomResourceDefinitions.
+//
+// A document that cannot be parsed is skipped, unless the options are strict, and the documents that follow it are
+// still decoded. A document that is larger than the maximum size of a document is skipped along with the rest of
+// the file instead (see ErrDocumentTooLarge).
+func readDocuments(path string, reader io.Reader, o options) ([]document, error) {
+	stream := newYamlStream(path, reader, o)
+
+	var documents []document
+	var reportedTemplate bool
+	for stream.nextDocument() {
+		decoder := yaml.NewDecoder(stream)
+
+		for {
+			var contents interface{}
+			err := decoder.Decode(&contents)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				if stream.err != nil {
+					return nil, fmt.Errorf("read: %w", stream.err)
+				}
+
+				if stream.tooLarge {
+					if err := o.handleParseError(path, ErrDocumentTooLarge); err != nil {
+						return nil, err
+					}
+
+					return documents, nil
+				}
+
+				// The lines of the errors are counted from the start of the document.
+				err = fmt.Errorf("document at line %v: %w", stream.documentLine, err)
+
+				// Files with templates are reported once, whether or not they could be parsed, as the images of
+				// the documents that could be parsed may be incomplete (e.g. {{ .Values.image }}).
+				if stream.templated && !reportedTemplate {
+					err = fmt.Errorf("%w: %v", ErrUnrenderedTemplate, err)
+					reportedTemplate = true
+				}
+
+				if err := o.handleParseError(path, err); err != nil {
+					return nil, err
+				}
+
+				stream.skipDocument()
+				break
+			}
+
+			// Empty documents (e.g. a document that only contains comments) have no images.
+			if contents == nil {
+				continue
+			}
+
+			yamlDocument, err := yaml.Marshal(contents)
+			if err != nil {
+				return nil, fmt.Errorf("marshal document: %w", err)
+			}
+
+			current := document{path: path, contents: yamlDocument}
+			if err := current.findImages(o); err != nil {
+				return nil, err
+			}
+
+			for i := range current.images {
+				current.images[i].line = stream.lines.find(current.images[i].reference)
+			}
+
+			if o.keepsContents() {
+				documents = append(documents, current)
+				continue
+			}
+
+			if len(current.images) > 0 {
+				current.contents = nil
+				documents = append(documents, current)
+			}
+		}
+	}
+
+	if stream.err != nil {
+		return nil, fmt.Errorf("read: %w", stream.err)
+	}
+
+	if stream.templated && !reportedTemplate && o.parseErrorHandler != nil {
+		o.parseErrorHandler(path, ErrUnrenderedTemplate)
+	}
+
+	return documents, nil
+}
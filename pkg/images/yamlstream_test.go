@@ -0,0 +1,224 @@
+package images
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadDocuments_Lines(t *testing.T) {
+	contents := "# A comment before the first document\n" +
+		"apiVersion: v1\n" +
+		"kind: Pod\n" +
+		"metadata:\n" +
+		"  name: first\n" +
+		"spec:\n" +
+		"  containers:\n" +
+		"  - image: busybox:1.32.0\n" +
+		"...\n" +
+		"---\n" +
+		"apiVersion: v1\n" +
+		"kind: ConfigMap\n" +
+		"data:\n" +
+		"  schema: " + strings.Repeat("x", 200) + "\n" +
+		"---\n" +
+		"apiVersion: v1\n" +
+		"kind: Pod\n" +
+		"metadata:\n" +
+		"  name: second\n" +
+		"spec:\n" +
+		"  containers:\n" +
+		"  - image: busybox:1.32.0\n" +
+		"  - image: nginx:1.25\n"
+
+	documents, err := readDocuments("-", strings.NewReader(contents), newOptions())
+	if err != nil {
+		t.Fatal("read documents:", err)
+	}
+
+	// The ConfigMap does not have any images, so it is dropped once it is searched.
+	if len(documents) != 2 {
+		t.Fatalf("expected 2 documents, actual %v", len(documents))
+	}
+
+	var actual []string
+	for _, document := range documents {
+		if document.contents != nil {
+			t.Errorf("expected the contents of %s to be dropped", document.objectMeta.Name)
+		}
+
+		for _, image := range document.images {
+			actual = append(actual, fmt.Sprintf("%s:%s:%d", document.objectMeta.Name, image.reference, image.line))
+		}
+	}
+
+	expected := []string{"first:busybox:1.32.0:8", "second:busybox:1.32.0:22", "second:nginx:1.25:23"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected images %v, actual %v", expected, actual)
+	}
+}
+
+func TestFindImages_MaxDocumentSize(t *testing.T) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		t.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	// The schema of the CustomResourceDefinition is on a single line that is longer than the buffer that files are read with.
+	pods := []byte(`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: nginx:1.24
+    command: [sh
+---
+apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: redis:7.0
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: large
+  annotations:
+    schema: "` + strings.Repeat("x", 2*yamlStreamBufferSize) + `"
+---
+apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: nginx:1.25
+`)
+
+	podsPath := filepath.Join(root, "pods.yaml")
+	if err := ioutil.WriteFile(podsPath, pods, os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	app := []byte(`apiVersion: v1
+kind: Pod
+spec:
+  containers:
+  - image: busybox:1.32.0
+`)
+
+	appPath := filepath.Join(root, "app.yaml")
+	if err := ioutil.WriteFile(appPath, app, os.ModePerm); err != nil {
+		t.Fatal("write file:", err)
+	}
+
+	var parseErrors []error
+	handler := func(path string, err error) {
+		parseErrors = append(parseErrors, err)
+	}
+
+	actual, err := FindImages(root, WithParseErrorHandler(handler), WithMaxDocumentSize(yamlStreamBufferSize))
+	if err != nil {
+		t.Fatal("find images:", err)
+	}
+
+	// Unlike the invalid document, which is skipped on its own, the rest of the file of the large document is skipped,
+	// but not the documents before it or the other files.
+	if len(actual) != 2 || actual[0].Reference != "busybox:1.32.0" || actual[1].Reference != "redis:7.0" {
+		t.Fatalf("expected the images of the other file and before the large document, actual %+v", actual)
+	}
+
+	if actual[0].Resources[0].Line != 5 {
+		t.Errorf("expected the image to be on line 5, actual %v", actual[0].Resources[0].Line)
+	}
+
+	if len(parseErrors) != 2 || errors.Is(parseErrors[0], ErrDocumentTooLarge) || !errors.Is(parseErrors[1], ErrDocumentTooLarge) {
+		t.Errorf("expected a parse error and a document too large error, actual %v", parseErrors)
+	}
+
+	large := strings.SplitN(string(pods), "---\n", 3)[2]
+	if _, err := readDocuments(podsPath, strings.NewReader(large), newOptions(WithStrict(), WithMaxDocumentSize(yamlStreamBufferSize))); !errors.Is(err, ErrDocumentTooLarge) {
+		t.Errorf("expected a document too large error when strict, actual %v", err)
+	}
+}
+
+// BenchmarkFindImages_LargeFile finds the images of a bundle of CustomResourceDefinitions with large schemas, which
+// is the kind of file that is only read one document at a time to keep the memory used low.
+func BenchmarkFindImages_LargeFile(b *testing.B) {
+	root, err := ioutil.TempDir("", "sinker")
+	if err != nil {
+		b.Fatal("temp dir:", err)
+	}
+	defer os.RemoveAll(root)
+
+	var bundle strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&bundle, "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: crd%d\nspec:\n  versions:\n", i)
+		for v := 0; v < 100; v++ {
+			fmt.Fprintf(&bundle, "  - name: v%d\n    schema:\n      description: %s\n", v, strings.Repeat("x", 200))
+		}
+	}
+
+	bundle.WriteString("---\napiVersion: v1\nkind: Pod\nspec:\n  containers:\n  - image: nginx:1.25\n")
+	if err := ioutil.WriteFile(filepath.Join(root, "bundle.yaml"), []byte(bundle.String()), os.ModePerm); err != nil {
+		b.Fatal("write file:", err)
+	}
+
+	benchmarkPeakHeap(b, func() error {
+		_, err := FindImages(root)
+		return err
+	})
+}
+
+// BenchmarkFindImages_TestData finds the images of the example and test manifests of the repository.
+func BenchmarkFindImages_TestData(b *testing.B) {
+	paths := []string{filepath.Join("..", "..", "example"), filepath.Join("..", "..", "test")}
+
+	benchmarkPeakHeap(b, func() error {
+		_, err := FindImagesInPaths(paths)
+		return err
+	})
+}
+
+// benchmarkPeakHeap runs the function b.N times and reports the largest heap in use while it runs, as the bytes that
+// are allocated by each run (-benchmem) do not show how much of the files are held in memory at once.
+func benchmarkPeakHeap(b *testing.B, run func() error) {
+	done := make(chan struct{})
+	peak := make(chan uint64)
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		var stats runtime.MemStats
+		var maxHeap uint64
+		for {
+			select {
+			case <-done:
+				peak <- maxHeap
+				return
+			case <-ticker.C:
+				runtime.ReadMemStats(&stats)
+				if stats.HeapInuse > maxHeap {
+					maxHeap = stats.HeapInuse
+				}
+			}
+		}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := run(); err != nil {
+			b.Fatal("find images:", err)
+		}
+	}
+	b.StopTimer()
+
+	close(done)
+	b.ReportMetric(float64(<-peak), "peak-heap-B")
+}
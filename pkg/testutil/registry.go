@@ -0,0 +1,183 @@
+// Package testutil provides an OCI registry that runs in the process of a test, so that the images that sinker pushes,
+// checks and syncs can be tested without a registry or network access. The registry is used both by the tests of sinker
+// and by the tests of tools that embed sinker or run it as part of their own pipelines.
+package testutil
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// Registry is an OCI registry that stores its images in memory, served over plain HTTP on a loopback address, which
+// sinker connects to without the --insecure flag. The images of the registry are lost when the registry is closed.
+type Registry struct {
+	// Host is the host of the registry, including its port (e.g. 127.0.0.1:41235).
+	Host string
+
+	server *httptest.Server
+}
+
+// NewRegistry starts an empty registry, which is closed when the test and its subtests complete.
+func NewRegistry(t testing.TB) *Registry {
+	t.Helper()
+
+	// The registry logs every request, which would drown out the logs of the test.
+	server := httptest.NewServer(registry.New(registry.Logger(log.New(ioutil.Discard, "", 0))))
+	t.Cleanup(server.Close)
+
+	return &Registry{
+		Host:   strings.TrimPrefix(server.URL, "http://"),
+		server: server,
+	}
+}
+
+// Close stops the registry before the end of the test (e.g. to test what happens when a registry is unavailable).
+func (r *Registry) Close() {
+	r.server.Close()
+}
+
+// Reference returns the reference to the image in the registry, where the image is a repository with a tag or digest
+// (e.g. coreos/etcd:v3.4.13 is returned as 127.0.0.1:41235/coreos/etcd:v3.4.13).
+func (r *Registry) Reference(image string) string {
+	return r.Host + "/" + image
+}
+
+// PushImage pushes an image with random layers of the given number to the registry, where the image is a repository
+// with a tag (e.g. coreos/etcd:v3.4.13), and returns the digest of its manifest.
+func (r *Registry) PushImage(t testing.TB, image string, layers int) string {
+	t.Helper()
+
+	randomImage, err := random.Image(1024, int64(layers))
+	if err != nil {
+		t.Fatal("random image:", err)
+	}
+
+	reference := r.parseReference(t, image)
+	if err := remote.Write(reference, randomImage); err != nil {
+		t.Fatalf("write image %s: %v", reference, err)
+	}
+
+	digest, err := randomImage.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	return digest.String()
+}
+
+// PushIndex pushes a multi-platform image to the registry with a random image for each of the platforms (e.g. linux/amd64
+// or linux/arm64/v8), where the image is a repository with a tag, and returns the digest of its index.
+func (r *Registry) PushIndex(t testing.TB, image string, platforms ...string) string {
+	t.Helper()
+
+	var addendums []mutate.IndexAddendum
+	for _, platform := range platforms {
+		platformImage, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatal("random image:", err)
+		}
+
+		parsedPlatform, err := parsePlatform(platform)
+		if err != nil {
+			t.Fatalf("parse platform %s: %v", platform, err)
+		}
+
+		addendums = append(addendums, mutate.IndexAddendum{
+			Add: platformImage,
+			Descriptor: v1.Descriptor{
+				Platform: &parsedPlatform,
+			},
+		})
+	}
+
+	index := mutate.AppendManifests(empty.Index, addendums...)
+
+	reference := r.parseReference(t, image)
+	if err := remote.WriteIndex(reference, index); err != nil {
+		t.Fatalf("write index %s: %v", reference, err)
+	}
+
+	digest, err := index.Digest()
+	if err != nil {
+		t.Fatal("digest:", err)
+	}
+
+	return digest.String()
+}
+
+// Digest returns the digest of the image in the registry, where the image is a repository with a tag or digest,
+// and false when the registry does not have the image (e.g. to assert that sinker pushed or skipped the image).
+func (r *Registry) Digest(t testing.TB, image string) (string, bool) {
+	t.Helper()
+
+	descriptor, err := remote.Get(r.parseReference(t, image))
+	if isNotFound(err) {
+		return "", false
+	}
+
+	if err != nil {
+		t.Fatalf("get digest of %s: %v", image, err)
+	}
+
+	return descriptor.Digest.String(), true
+}
+
+func (r *Registry) parseReference(t testing.TB, image string) name.Reference {
+	t.Helper()
+
+	reference, err := name.ParseReference(r.Reference(image), name.WeakValidation)
+	if err != nil {
+		t.Fatalf("parse reference %s: %v", image, err)
+	}
+
+	return reference
+}
+
+// parsePlatform parses a platform in the form os/arch[/variant] (e.g. linux/arm64/v8).
+func parsePlatform(platform string) (v1.Platform, error) {
+	platformTokens := strings.Split(platform, "/")
+	if len(platformTokens) < 2 || len(platformTokens) > 3 {
+		return v1.Platform{}, fmt.Errorf("invalid platform %s, expected os/arch[/variant]", platform)
+	}
+
+	parsedPlatform := v1.Platform{
+		OS:           platformTokens[0],
+		Architecture: platformTokens[1],
+	}
+
+	if len(platformTokens) == 3 {
+		parsedPlatform.Variant = platformTokens[2]
+	}
+
+	return parsedPlatform, nil
+}
+
+func isNotFound(err error) bool {
+	transportErr, ok := err.(*transport.Error)
+	if !ok {
+		return false
+	}
+
+	for _, diagnostic := range transportErr.Errors {
+		switch diagnostic.Code {
+		case transport.ManifestUnknownErrorCode, transport.NameUnknownErrorCode:
+			return true
+		}
+	}
+
+	return transportErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+func TestPushImage(t *testing.T) {
+	registry := NewRegistry(t)
+
+	digest := registry.PushImage(t, "org/app:1.0", 2)
+
+	actual, ok := registry.Digest(t, "org/app:1.0")
+	if !ok {
+		t.Fatal("expected org/app:1.0 to be pushed")
+	}
+
+	if actual != digest {
+		t.Errorf("expected org/app:1.0 to have digest %s, actual %s", digest, actual)
+	}
+
+	if _, ok := registry.Digest(t, "org/app@"+digest); !ok {
+		t.Error("expected org/app to be found by its digest")
+	}
+
+	if _, ok := registry.Digest(t, "org/app:2.0"); ok {
+		t.Error("expected an image that was not pushed to not be found")
+	}
+}
+
+func TestPushIndex(t *testing.T) {
+	registry := NewRegistry(t)
+
+	digest := registry.PushIndex(t, "org/multi:1.0", "linux/amd64", "linux/arm64/v8")
+
+	index, err := remote.Index(registry.parseReference(t, "org/multi:1.0"))
+	if err != nil {
+		t.Fatal("get index:", err)
+	}
+
+	indexManifest, err := index.IndexManifest()
+	if err != nil {
+		t.Fatal("index manifest:", err)
+	}
+
+	var actual []string
+	for _, descriptor := range indexManifest.Manifests {
+		actual = append(actual, descriptor.Platform.OS+"/"+descriptor.Platform.Architecture+descriptor.Platform.Variant)
+	}
+
+	if len(actual) != 2 || actual[0] != "linux/amd64" || actual[1] != "linux/arm64v8" {
+		t.Errorf("expected the platforms linux/amd64 and linux/arm64 v8, actual %v", actual)
+	}
+
+	if actual, _ := registry.Digest(t, "org/multi:1.0"); actual != digest {
+		t.Errorf("expected the index to have digest %s, actual %s", digest, actual)
+	}
+}
+
+func TestClose(t *testing.T) {
+	registry := NewRegistry(t)
+	registry.Close()
+
+	if _, err := remote.Get(registry.parseReference(t, "org/app:1.0")); err == nil {
+		t.Error("expected an error when the registry is closed")
+	}
+}